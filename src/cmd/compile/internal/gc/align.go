@@ -156,6 +156,18 @@ func widstruct(errtype *types.Type, t *types.Type, o int64, flag int) int64 {
 	return o
 }
 
+// isAtomicAlign64 reports whether t is sync/atomic.align64,
+// a struct type that has 8-byte alignment on all systems.
+func isAtomicAlign64(t *types.Type) bool {
+	if t.Sym == nil || t.Sym.Name != "align64" {
+		return false
+	}
+	if t.Sym.Pkg == localpkg {
+		return myimportpath == "sync/atomic"
+	}
+	return t.Sym.Pkg.Path == "sync/atomic"
+}
+
 // dowidth calculates and stores the size and alignment for t.
 // If sizeCalculationDisabled is set, and the size/alignment
 // have not already been calculated, it calls Fatal.
@@ -342,6 +354,11 @@ func dowidth(t *types.Type) {
 			Fatalf("dowidth fn struct %v", t)
 		}
 		w = widstruct(t, t, 0, 1)
+		if isAtomicAlign64(t) {
+			// Make the 64-bit types of sync/atomic
+			// 64-bit aligned on 32-bit systems too.
+			t.Align = 8
+		}
 
 	// make fake type to check later to
 	// trigger function argument computation.
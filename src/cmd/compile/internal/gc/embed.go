@@ -0,0 +1,309 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cmd/compile/internal/types"
+	"cmd/internal/obj"
+	"cmd/internal/src"
+)
+
+// embedCfg is the configuration for //go:embed directives,
+// read from the file named by the -embedcfg flag.
+// The go command resolves the patterns; the compiler only
+// checks that each directive's patterns were resolved.
+var embedCfg struct {
+	Patterns map[string][]string // pattern -> files, relative to the package directory
+	Files    map[string]string   // file -> path on disk
+}
+
+func readEmbedCfg(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Fatalf("-embedcfg: %v", err)
+	}
+	if err := json.Unmarshal(data, &embedCfg); err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+	if embedCfg.Patterns == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Patterns", file)
+	}
+	if embedCfg.Files == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Files", file)
+	}
+}
+
+// pragmaEmbed records a //go:embed directive.
+type pragmaEmbed struct {
+	pos      src.Pos
+	patterns []string
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the glob patterns.
+// It accepts unquoted space-separated patterns as well as double-quoted and back-quoted Go strings.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var path string
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			path = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			path = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					path = q
+					args = args[i+1:]
+					break Switch
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, path)
+	}
+	return list, nil
+}
+
+// An embedVar is a package-level variable initialized
+// by one or more //go:embed directives.
+type embedVar struct {
+	n     *Node
+	files []string // embedded files, in pattern order
+}
+
+// embedlist lists the variables to be initialized by initEmbed.
+var embedlist []embedVar
+
+// varEmbed checks the //go:embed directives preceding a var declaration
+// and records the variable for initialization once its type is known.
+func varEmbed(p *noder, names []*Node, typ *Node, exprs []*Node, embeds []pragmaEmbed) {
+	pos := embeds[0].pos
+	if !p.importedEmbed {
+		yyerrorpos(pos, "go:embed only allowed in Go files that import \"embed\"")
+		return
+	}
+	if dclcontext != PEXTERN {
+		yyerrorpos(pos, "go:embed cannot apply to var inside func")
+		return
+	}
+	if len(names) > 1 {
+		yyerrorpos(pos, "go:embed cannot apply to multiple vars")
+		return
+	}
+	if len(exprs) > 0 {
+		yyerrorpos(pos, "go:embed cannot apply to var with initializer")
+		return
+	}
+	if typ == nil {
+		// Should not happen, since len(exprs) == 0 now.
+		yyerrorpos(pos, "go:embed cannot apply to var without type")
+		return
+	}
+	if embedCfg.Patterns == nil {
+		yyerrorpos(pos, "invalid go:embed: build system did not supply embed configuration")
+		return
+	}
+
+	var files []string
+	have := make(map[string]bool)
+	for _, e := range embeds {
+		for _, pattern := range e.patterns {
+			list, ok := embedCfg.Patterns[pattern]
+			if !ok {
+				yyerrorpos(e.pos, "invalid go:embed: build system did not map pattern: %s", pattern)
+			}
+			for _, file := range list {
+				if embedCfg.Files[file] == "" {
+					yyerrorpos(e.pos, "invalid go:embed: build system did not map file: %s", file)
+					continue
+				}
+				if !have[file] {
+					have[file] = true
+					files = append(files, file)
+				}
+			}
+		}
+	}
+
+	embedlist = append(embedlist, embedVar{n: names[0], files: files})
+}
+
+const (
+	embedUnknown = iota
+	embedBytes
+	embedString
+	embedFiles
+)
+
+// embedKind determines the kind of embedding variable.
+func embedKind(typ *types.Type) int {
+	if typ.Sym != nil && typ.Sym.Name == "FS" && (typ.Sym.Pkg.Path == "embed" || (typ.Sym.Pkg == localpkg && myimportpath == "embed")) {
+		return embedFiles
+	}
+	if typ.Etype == types.TSTRING {
+		return embedString
+	}
+	if typ.Etype == types.TSLICE && typ.Elem().Etype == types.TUINT8 {
+		return embedBytes
+	}
+	return embedUnknown
+}
+
+// embedFileNameSplit splits name into the directory and element
+// used to order the files of an embed.FS.
+// It must match split in package embed.
+func embedFileNameSplit(name string) (dir, elem string, isDir bool) {
+	if name[len(name)-1] == '/' {
+		isDir = true
+		name = name[:len(name)-1]
+	}
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return ".", name, isDir
+	}
+	return name[:i], name[i+1:], isDir
+}
+
+// embedFileLess implements the sort order for a list of embedded files.
+// See the comment inside ../../../../embed/embed.go's FS struct.
+func embedFileLess(x, y string) bool {
+	xdir, xelem, _ := embedFileNameSplit(x)
+	ydir, yelem, _ := embedFileNameSplit(y)
+	return xdir < ydir || xdir == ydir && xelem < yelem
+}
+
+// embedFileList returns the sorted list of files for an embed.FS,
+// including an entry (with a trailing slash) for every directory
+// containing an embedded file.
+func embedFileList(v *embedVar) []string {
+	have := make(map[string]bool)
+	var list []string
+	for _, file := range v.files {
+		list = append(list, file)
+		for dir := path.Dir(file); dir != "." && !have[dir]; dir = path.Dir(dir) {
+			have[dir] = true
+			list = append(list, dir+"/")
+		}
+	}
+	obj.SortSlice(list, func(i, j int) bool {
+		return embedFileLess(list[i], list[j])
+	})
+	return list
+}
+
+// initEmbed emits the data for the embedded variable v.
+func initEmbed(v *embedVar) {
+	n := v.n
+	kind := embedKind(n.Type)
+	if kind == embedUnknown {
+		yyerrorl(n.Pos, "go:embed cannot apply to var of type %v", n.Type)
+		return
+	}
+
+	sym := n.Sym.Linksym()
+	switch kind {
+	case embedString, embedBytes:
+		if len(v.files) != 1 {
+			yyerrorl(n.Pos, "invalid go:embed: multiple files for type %v", n.Type)
+			return
+		}
+		data, err := ioutil.ReadFile(embedCfg.Files[v.files[0]])
+		if err != nil {
+			yyerrorl(n.Pos, "embed %s: %v", v.files[0], err)
+			return
+		}
+		if kind == embedString {
+			off := dsymptr(sym, 0, stringsym(n.Pos, string(data)), 0)
+			duintptr(sym, off, uint64(len(data)))
+		} else {
+			// Unlike a string, the []byte is writable,
+			// so it gets its own copy of the data.
+			slicebytes(n, string(data), len(data))
+		}
+
+	case embedFiles:
+		files := embedFileList(v)
+		slicedata := Ctxt.Lookup(sym.Name + ".files")
+		// The []file pointed at by FS.files, laid out just past the slice header.
+		off := dsymptr(slicedata, 0, slicedata, 3*Widthptr)
+		off = duintptr(slicedata, off, uint64(len(files)))
+		off = duintptr(slicedata, off, uint64(len(files)))
+
+		// Package embed's file type is
+		//	name string
+		//	data string
+		//	hash [16]byte
+		// Emit one of these per file in the set.
+		const hashSize = 16
+		for _, file := range files {
+			off = dsymptr(slicedata, off, stringsym(n.Pos, file), 0)
+			off = duintptr(slicedata, off, uint64(len(file)))
+			if strings.HasSuffix(file, "/") {
+				// Directories have no data and no hash.
+				off = duintptr(slicedata, off, 0)
+				off = duintptr(slicedata, off, 0)
+				off += hashSize
+				continue
+			}
+			data, err := ioutil.ReadFile(embedCfg.Files[file])
+			if err != nil {
+				yyerrorl(n.Pos, "embed %s: %v", file, err)
+				return
+			}
+			sum := sha256.Sum256(data)
+			off = dsymptr(slicedata, off, stringsym(n.Pos, string(data)), 0)
+			off = duintptr(slicedata, off, uint64(len(data)))
+			off = int(slicedata.WriteBytes(Ctxt, int64(off), sum[:hashSize]))
+		}
+		ggloblsym(slicedata, int32(off), obj.RODATA|obj.LOCAL)
+		dsymptr(sym, 0, slicedata, 0)
+	}
+}
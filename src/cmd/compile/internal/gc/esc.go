@@ -679,6 +679,18 @@ func (e *EscState) esc(n *Node, parent *Node) {
 		e.escassignSinkWhy(n, n, "too large for stack") // TODO category: tooLarge
 	}
 
+	// Stack frames are only register-aligned, so variables that need
+	// more, such as the 64-bit types of sync/atomic on 32-bit systems,
+	// are allocated on the heap, which aligns them.
+	if n.Esc != EscHeap && n.Type != nil && tooAlignedForStack(n) {
+		if Debug['m'] > 2 {
+			Warnl(n.Pos, "%v is too aligned for stack", n)
+		}
+		n.Esc = EscHeap
+		addrescapes(n)
+		e.escassignSinkWhy(n, n, "too aligned for stack")
+	}
+
 	e.esc(n.Left, n)
 
 	if n.Op == ORANGE {
@@ -1227,6 +1239,25 @@ func (e *EscState) escassign(dst, src *Node, step *EscStep) {
 	lineno = lno
 }
 
+// tooAlignedForStack reports whether the variable or allocation n
+// needs more alignment than a stack frame provides.
+func tooAlignedForStack(n *Node) bool {
+	var t *types.Type
+	switch n.Op {
+	case ONAME:
+		if n.Class() != PAUTO {
+			return false
+		}
+		t = n.Type
+	case ONEW, OPTRLIT, OMAKESLICE, OSLICELIT:
+		t = n.Type.Elem()
+	default:
+		return false
+	}
+	dowidth(t)
+	return int(t.Align) > Widthreg
+}
+
 // Common case for escapes is 16 bits 000000000xxxEEEE
 // where commonest cases for xxx encoding in-to-out pointer
 //  flow are 000, 001, 010, 011  and EEEE is computed Esc bits.
@@ -89,6 +89,15 @@ func typecheckinl(fn *Node) {
 	lineno = lno
 }
 
+const (
+	inlineMaxBudget = 80
+
+	// inlineHotMaxBudget is the budget of functions that are the
+	// target of a hot call in the -pgoprofile profile. Calls to them
+	// that exceed inlineMaxBudget are inlined only at hot call sites.
+	inlineHotMaxBudget = 2000
+)
+
 // Caninl determines whether fn is inlineable.
 // If so, caninl saves fn->nbody in fn->inl and substitutes it with a copy.
 // fn and ->nbody will already have been typechecked.
@@ -169,7 +178,13 @@ func caninl(fn *Node) {
 	}
 	defer n.Func.SetInlinabilityChecked(true)
 
-	const maxBudget = 80
+	maxBudget := int32(inlineMaxBudget)
+	if pgoHotCallee(n) {
+		maxBudget = inlineHotMaxBudget
+		if Debug['m'] > 1 {
+			fmt.Printf("%v: hot function %v: inlining budget %d\n", fn.Line(), n, maxBudget)
+		}
+	}
 	visitor := hairyVisitor{budget: maxBudget}
 	if visitor.visitList(fn.Nbody) {
 		reason = visitor.reason
@@ -626,6 +641,9 @@ func inlnode(n *Node) *Node {
 		}
 
 		n = mkinlcall(n, asNode(n.Left.Type.FuncType().Nname), n.Isddd())
+
+	case OCALLINTER:
+		n = pgoDevirtualize(n)
 	}
 
 	lineno = lno
@@ -773,6 +791,13 @@ func mkinlcall1(n, fn *Node, isddd bool) *Node {
 		return n
 	}
 
+	if fn.Func.InlCost > inlineMaxBudget && !pgoHotCallSite(n) {
+		if Debug['m'] > 1 {
+			fmt.Printf("%v: cannot inline call to %v: cost %d exceeds budget %d at cold call site\n", n.Line(), fn, fn.Func.InlCost, inlineMaxBudget)
+		}
+		return n
+	}
+
 	if Debug_typecheckinl == 0 {
 		typecheckinl(fn)
 	}
@@ -80,11 +80,13 @@ func absFilename(name string) string {
 
 // noder transforms package syntax's AST into a Node tree.
 type noder struct {
-	file       *syntax.File
-	linknames  []linkname
-	pragcgobuf string
-	err        chan syntax.Error
-	scope      ScopeID
+	file          *syntax.File
+	linknames     []linkname
+	embeds        []pragmaEmbed
+	importedEmbed bool
+	pragcgobuf    string
+	err           chan syntax.Error
+	scope         ScopeID
 }
 
 func (p *noder) funchdr(n *Node) ScopeID {
@@ -158,6 +160,10 @@ func (p *noder) node() {
 
 	xtop = append(xtop, p.decls(p.file.DeclList)...)
 
+	for _, e := range p.embeds {
+		yyerrorpos(e.pos, "misplaced go:embed directive")
+	}
+
 	for _, n := range p.linknames {
 		if imported_unsafe {
 			lookup(n.local).Linkname = n.remote
@@ -176,12 +182,18 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 
 	for _, decl := range decls {
 		p.lineno(decl)
+		embeds := p.takeEmbeds(decl.Pos())
+		if _, ok := decl.(*syntax.VarDecl); !ok {
+			for _, e := range embeds {
+				yyerrorpos(e.pos, "misplaced go:embed directive")
+			}
+		}
 		switch decl := decl.(type) {
 		case *syntax.ImportDecl:
 			p.importDecl(decl)
 
 		case *syntax.VarDecl:
-			l = append(l, p.varDecl(decl)...)
+			l = append(l, p.varDecl(decl, embeds)...)
 
 		case *syntax.ConstDecl:
 			l = append(l, p.constDecl(decl, &cs)...)
@@ -202,6 +214,9 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 
 func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	val := p.basicLit(imp.Path)
+	if path, ok := val.U.(string); ok && path == "embed" {
+		p.importedEmbed = true
+	}
 	ipkg := importfile(&val)
 
 	if ipkg == nil {
@@ -243,7 +258,7 @@ func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	my.Block = 1 // at top level
 }
 
-func (p *noder) varDecl(decl *syntax.VarDecl) []*Node {
+func (p *noder) varDecl(decl *syntax.VarDecl, embeds []pragmaEmbed) []*Node {
 	names := p.declNames(decl.NameList)
 	typ := p.typeExprOrNil(decl.Type)
 
@@ -252,10 +267,26 @@ func (p *noder) varDecl(decl *syntax.VarDecl) []*Node {
 		exprs = p.exprList(decl.Values)
 	}
 
+	if len(embeds) > 0 {
+		varEmbed(p, names, typ, exprs, embeds)
+	}
+
 	p.lineno(decl)
 	return variter(names, typ, exprs)
 }
 
+// takeEmbeds removes and returns the //go:embed directives
+// that appear in the source before pos.
+func (p *noder) takeEmbeds(pos src.Pos) []pragmaEmbed {
+	i := 0
+	for i < len(p.embeds) && p.embeds[i].pos.Before(pos) {
+		i++
+	}
+	embeds := p.embeds[:i]
+	p.embeds = p.embeds[i:]
+	return embeds
+}
+
 // constState tracks state between constant specifiers within a
 // declaration group. This state is kept separate from noder so nested
 // constant declarations are handled correctly (e.g., issue 15550).
@@ -1345,6 +1376,18 @@ func (p *noder) pragma(pos src.Pos, text string) syntax.Pragma {
 		}
 		p.linknames = append(p.linknames, linkname{pos, f[1], f[2]})
 
+	case text == "go:embed", strings.HasPrefix(text, "go:embed "):
+		patterns, err := parseGoEmbed(text[len("go:embed"):])
+		if err != nil {
+			p.error(syntax.Error{Pos: pos, Msg: err.Error()})
+			break
+		}
+		if len(patterns) == 0 {
+			p.error(syntax.Error{Pos: pos, Msg: "usage: //go:embed pattern..."})
+			break
+		}
+		p.embeds = append(p.embeds, pragmaEmbed{pos, patterns})
+
 	case strings.HasPrefix(text, "go:cgo_"):
 		p.pragcgobuf += p.pragcgo(pos, text)
 		fallthrough // because of //go:cgo_unsafe_args
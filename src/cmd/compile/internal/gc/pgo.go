@@ -0,0 +1,237 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"cmd/compile/internal/pgo"
+	"cmd/compile/internal/types"
+	"cmd/internal/objabi"
+)
+
+// Profile-guided optimization.
+//
+// With -pgoprofile, the compiler reads a CPU profile of the program
+// being built and uses its hot call edges in two ways:
+//
+// Functions that are the target of a hot call are inlinable with a
+// larger budget, inlineHotMaxBudget, but calls to them are inlined
+// only at hot call sites if their cost exceeds inlineMaxBudget.
+//
+// Interface method calls at hot call sites whose hot callee is a
+// method of a known concrete type are devirtualized by testing for
+// that type, so that the method can be called directly and inlined.
+
+// pgoHotThreshold is the percentage of the total weight of the call
+// edges of the profile that the hot edges account for.
+const pgoHotThreshold = 99
+
+var (
+	pgoprofile string       // -pgoprofile flag
+	pgoProfile *pgo.Profile // profile read from pgoprofile
+)
+
+func readPGOProfile(file string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("-pgoprofile: %v", err)
+	}
+	defer f.Close()
+	p, err := pgo.Read(f, pgoHotThreshold)
+	if err != nil {
+		log.Fatalf("-pgoprofile: %s: %v", file, err)
+	}
+	pgoProfile = p
+}
+
+// pgoLinkName returns the symbol name, as recorded in profiles,
+// of the linker symbol name, in which the local package is "".
+func pgoLinkName(name string) string {
+	if strings.HasPrefix(name, `"".`) {
+		return objabi.PathToPrefix(myimportpath) + name[len(`""`):]
+	}
+	return name
+}
+
+// pgoHotCallee reports whether fn is the target of a hot call edge.
+func pgoHotCallee(fn *Node) bool {
+	return pgoProfile != nil && pgoProfile.IsHotCallee(pgoLinkName(fn.Sym.LinksymName()))
+}
+
+// pgoCallSite returns the call site of the call n in Curfn,
+// or in the function inlined into Curfn that n belongs to.
+func pgoCallSite(n *Node) pgo.CallSite {
+	pos := Ctxt.PosTable.Pos(n.Pos)
+	caller := Curfn.Func.Nname.Sym.LinksymName()
+	if b := pos.Base(); b != nil && b.InliningIndex() >= 0 {
+		caller = Ctxt.InlTree.InlinedFunction(b.InliningIndex()).Name
+	}
+	return pgo.CallSite{Caller: pgoLinkName(caller), Line: int(pos.Line())}
+}
+
+// pgoHotCallSite reports whether the call n is the source of a hot call edge.
+func pgoHotCallSite(n *Node) bool {
+	return pgoProfile != nil && pgoProfile.IsHotCallSite(pgoCallSite(n))
+}
+
+// pgoDevirtualize rewrites the interface method call n, if the
+// profile shows a hot call from it to a method of concrete type T, into
+//
+//	if t, ok := recv.(T); ok {
+//		results = t.M(args)
+//	} else {
+//		results = recv.M(args)
+//	}
+//
+// with the direct call inlined if possible. It returns the rewritten
+// call as an OINLCALL, or n if the call cannot be devirtualized.
+func pgoDevirtualize(n *Node) *Node {
+	if pgoProfile == nil || n.NoInline() || n.Left.Op != ODOTINTER {
+		return n
+	}
+	if n.List.Len() == 1 && n.List.First().Type != nil && n.List.First().Type.IsFuncArgStruct() {
+		// Arguments are the results of a call, as in x.M(f()).
+		return n
+	}
+	sel := n.Left
+	var typ *types.Type
+	for _, e := range pgoProfile.HotCallees(pgoCallSite(n)) {
+		if typ = pgoMethodType(e.Callee, sel); typ != nil {
+			break
+		}
+	}
+	if typ == nil {
+		return n
+	}
+	if Debug['m'] != 0 {
+		fmt.Printf("%v: PGO devirtualizing %v to %v\n", n.Line(), sel, typ)
+	}
+
+	// Evaluate the receiver and arguments once, in order.
+	init := n.Ninit.Slice()
+	tmp := func(t *types.Type, val *Node) *Node {
+		v := devirtvar(t)
+		init = append(init, nod(ODCL, v, nil))
+		if val != nil {
+			init = append(init, typecheck(nod(OAS, v, val), Etop))
+		}
+		return v
+	}
+	recv := tmp(sel.Left.Type, sel.Left)
+	var args []*Node
+	for _, a := range n.List.Slice() {
+		args = append(args, tmp(a.Type, a))
+	}
+	var rets []*Node
+	for _, f := range sel.Type.Results().Fields().Slice() {
+		rets = append(rets, tmp(f.Type, nil))
+	}
+
+	// call returns the call of the method on recv, assigning the results.
+	// Each call and the OINLCALL get their own lists of arguments and
+	// results, since later passes may edit them in place.
+	call := func(recv *Node, noinline bool) *Node {
+		c := nod(OCALL, nodSym(OXDOT, recv, sel.Sym), nil)
+		c.List.Set(append([]*Node(nil), args...))
+		c.SetIsddd(n.Isddd())
+		c.SetNoInline(noinline)
+		var as *Node
+		switch len(rets) {
+		case 0:
+			return typecheck(c, Etop)
+		case 1:
+			as = nod(OAS, rets[0], c)
+		default:
+			as = nod(OAS2, nil, nil)
+			as.List.Set(append([]*Node(nil), rets...))
+			as.Rlist.Set1(c)
+		}
+		return typecheck(as, Etop)
+	}
+
+	concrete := tmp(typ, nil)
+	ok := tmp(types.Types[TBOOL], nil)
+	as := nod(OAS2, nil, nil)
+	as.List.Set2(concrete, ok)
+	as.Rlist.Set1(nod(ODOTTYPE, recv, typenod(typ)))
+	init = append(init, typecheck(as, Etop))
+
+	// The fallback call is marked so that it is left
+	// alone if the inliner comes across it again.
+	nif := nod(OIF, ok, nil)
+	nif.SetLikely(true)
+	nif.Nbody.Set1(call(concrete, false))
+	nif.Rlist.Set1(call(recv, true))
+	inlnodelist(nif.Nbody)
+	for _, s := range nif.Nbody.Slice() {
+		if s.Op == OINLCALL {
+			inlconv2stmt(s)
+		}
+	}
+	init = append(init, typecheck(nif, Etop))
+
+	r := nod(OINLCALL, nil, nil)
+	r.Ninit.Set(init)
+	r.Rlist.Set(append([]*Node(nil), rets...))
+	r.Type = n.Type
+	r.SetTypecheck(1)
+	return r
+}
+
+// pgoMethodType returns the concrete type whose method is the named
+// callee, if the type is declared in the local package or a directly
+// imported one and implements the interface of the method selector sel.
+func pgoMethodType(callee string, sel *Node) *types.Type {
+	path, name, ptr, method, ok := pgo.SplitMethodName(callee)
+	if !ok || method != sel.Sym.Name {
+		return nil
+	}
+	var pkg *types.Pkg
+	if path == objabi.PathToPrefix(myimportpath) {
+		pkg = localpkg
+	} else {
+		for _, p := range types.ImportedPkgList() {
+			if p.Prefix == path {
+				pkg = p
+				break
+			}
+		}
+	}
+	if pkg == nil || pkg.Syms[name] == nil {
+		return nil
+	}
+	d := asNode(pkg.Syms[name].Def)
+	if d == nil || d.Op != OTYPE || d.Type == nil || d.Type.IsInterface() {
+		return nil
+	}
+	// Interface calls to a value method go through the wrapper with
+	// a pointer receiver, so the profile names (*T).M even if the
+	// interface holds a T. Assume it does if T has the method.
+	var missing, have *types.Field
+	var ptrRecv int
+	if implements(d.Type, sel.Left.Type, &missing, &have, &ptrRecv) {
+		return d.Type
+	}
+	if t := types.NewPtr(d.Type); ptr && implements(t, sel.Left.Type, &missing, &have, &ptrRecv) {
+		return t
+	}
+	return nil
+}
+
+// Synthesize a variable for the receiver, arguments or
+// results of a devirtualized call.
+func devirtvar(t *types.Type) *Node {
+	n := newname(lookupN("~dv", len(Curfn.Func.Dcl)))
+	n.Type = t
+	n.SetClass(PAUTO)
+	n.Name.SetUsed(true)
+	n.Name.Curfn = Curfn
+	Curfn.Func.Dcl = append(Curfn.Func.Dcl, n)
+	return n
+}
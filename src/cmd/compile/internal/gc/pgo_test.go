@@ -0,0 +1,113 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"internal/testenv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+// writePGOProfile writes a CPU profile for testdata/pgo/pgo.go
+// to file, with a sample of the given weight for each stack.
+func writePGOProfile(t *testing.T, file string, stacks map[int64][]string, lines map[string]int64) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10000000,
+	}
+	funcs := make(map[string]*profile.Function)
+	for weight, stack := range stacks {
+		s := &profile.Sample{Value: []int64{weight, weight * p.Period}}
+		for _, name := range stack {
+			fn := funcs[name]
+			if fn == nil {
+				fn = &profile.Function{ID: uint64(len(funcs) + 1), Name: name, Filename: "pgo.go"}
+				funcs[name] = fn
+				p.Function = append(p.Function, fn)
+			}
+			loc := &profile.Location{ID: uint64(len(p.Location) + 1), Line: []profile.Line{{Function: fn, Line: lines[name]}}}
+			p.Location = append(p.Location, loc)
+			s.Location = append(s.Location, loc)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPGO(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+
+	dir, err := ioutil.TempDir("", "TestPGO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Sampled lines: the call sites in hot and cold, and the
+	// first lines of the bodies of big and add.Add.
+	lines := map[string]int64{
+		"pgo.hot":        35,
+		"pgo.cold":       39,
+		"pgo.big":        17,
+		"pgo.(*add).Add": 14,
+	}
+	prof := filepath.Join(dir, "pgo.pprof")
+	writePGOProfile(t, prof, map[int64][]string{
+		100: {"pgo.big", "pgo.hot"},
+		99:  {"pgo.(*add).Add", "pgo.hot"},
+		1:   {"pgo.big", "pgo.cold"},
+	}, lines)
+
+	compile := func(args ...string) string {
+		args = append([]string{"tool", "compile", "-p", "pgo", "-o", filepath.Join(dir, "pgo.o"), "-m", "-m"}, args...)
+		out, err := exec.Command(testenv.GoToolPath(t), append(args, filepath.Join("testdata", "pgo", "pgo.go"))...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+
+	tests := []struct {
+		pgo  bool
+		want string
+	}{
+		{false, `pgo.go:16:6: cannot inline big: function too complex`},
+		{true, `pgo.go:16:6: can inline big`},
+		{true, `pgo.go:35:\d+: inlining call to big`},
+		{true, `pgo.go:39:\d+: cannot inline call to big: cost \d+ exceeds budget 80 at cold call site`},
+		{true, `pgo.go:35:\d+: PGO devirtualizing a.Add to add`},
+		{true, `pgo.go:35:\d+: inlining call to add.Add`},
+	}
+	out := compile()
+	outPGO := compile("-pgoprofile", prof)
+	for _, tt := range tests {
+		o := out
+		if tt.pgo {
+			o = outPGO
+		}
+		if !regexp.MustCompile(tt.want).MatchString(o) {
+			t.Errorf("compile output (pgo=%v) does not match %q:\n%s", tt.pgo, tt.want, o)
+		}
+	}
+	if regexp.MustCompile(`inlining call to big|PGO`).MatchString(out) {
+		t.Errorf("compile output without profile shows PGO optimizations:\n%s", out)
+	}
+}
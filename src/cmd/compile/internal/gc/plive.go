@@ -129,9 +129,40 @@ type Liveness struct {
 	// An array with a bit vector for each safe point tracking live variables.
 	livevars []bvec
 
+	// unsafePoints bit i is set if Value ID i is not a safe point
+	// for asynchronous preemption.
+	unsafePoints bvec
+
+	// allUnsafe indicates that no point in the function is safe
+	// for asynchronous preemption.
+	allUnsafe bool
+
 	cache progeffectscache
 }
 
+// LivenessMap records the liveness information genssa needs: the stack
+// map index of each call and the values at which the function may not
+// be asynchronously preempted.
+type LivenessMap struct {
+	// stackMapIndex maps from safe points (i.e., CALLs) to their
+	// index within the stack maps.
+	stackMapIndex map[*ssa.Value]int
+
+	// unsafePoints bit i is set if Value ID i is not a safe point
+	// for asynchronous preemption.
+	unsafePoints bvec
+
+	// allUnsafe indicates that no point in the function is safe
+	// for asynchronous preemption.
+	allUnsafe bool
+}
+
+// isUnsafe reports whether v is not a safe point for asynchronous
+// preemption.
+func (m LivenessMap) isUnsafe(v *ssa.Value) bool {
+	return m.allUnsafe || m.unsafePoints.Get(int32(v.ID))
+}
+
 type progeffectscache struct {
 	textavarinit []int32
 	retuevar     []int32
@@ -452,6 +483,102 @@ func issafepoint(v *ssa.Value) bool {
 	return v.Op.IsCall()
 }
 
+// markUnsafePoints finds the places in the function where it would be
+// unsafe to stop the goroutine asynchronously, that is, at a point
+// other than a call, and scan its stack.
+func (lv *Liveness) markUnsafePoints() {
+	if compiling_runtime || lv.fn.Func.Pragma&Nosplit != 0 {
+		// The runtime assumes it is only preempted at calls,
+		// and nosplit functions may not have room on the
+		// stack for the preemption handler.
+		lv.allUnsafe = true
+		return
+	}
+
+	lv.unsafePoints = bvalloc(int32(lv.f.NumValues()))
+
+	// Mark write barrier blocks unsafe. Between the load of
+	// writeBarrier.enabled and the store it guards, the GC must
+	// not change phase.
+	for _, b := range lv.f.Blocks {
+		if len(b.Succs) != 2 {
+			continue
+		}
+		start := -1
+		for i, v := range b.Values {
+			if v.Aux == writeBarrier || (len(v.Args) > 0 && v.Args[0].Aux == writeBarrier) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			continue
+		}
+		for _, v := range b.Values[start:] {
+			lv.unsafePoints.Set(int32(v.ID))
+		}
+		for _, e := range b.Succs {
+			for _, v := range e.Block().Values {
+				lv.unsafePoints.Set(int32(v.ID))
+			}
+		}
+	}
+
+	// Mark everything from a uintptr-to-pointer conversion back to
+	// the preceding call unsafe. Until the conversion, the uintptr
+	// may hold the only reference to an object, which the garbage
+	// collector cannot see. At a call, the uintptr must already be
+	// dead or the program is not valid.
+	flooded := bvalloc(int32(lv.f.NumBlocks()))
+	var flood func(b *ssa.Block, vi int)
+	flood = func(b *ssa.Block, vi int) {
+		if vi == len(b.Values) {
+			// Flooding from the end of the block.
+			if flooded.Get(int32(b.ID)) {
+				return
+			}
+			flooded.Set(int32(b.ID))
+		}
+		for i := vi - 1; i >= 0; i-- {
+			v := b.Values[i]
+			if v.Op.IsCall() {
+				return
+			}
+			lv.unsafePoints.Set(int32(v.ID))
+		}
+		for _, e := range b.Preds {
+			p := e.Block()
+			flood(p, len(p.Values))
+		}
+	}
+	for _, b := range lv.f.Blocks {
+		for i, v := range b.Values {
+			if isUintptrToPointer(v) {
+				lv.unsafePoints.Set(int32(v.ID))
+				flood(b, i)
+			}
+		}
+	}
+}
+
+// isUintptrToPointer reports whether v converts a uintptr to a pointer.
+func isUintptrToPointer(v *ssa.Value) bool {
+	switch v.Op {
+	case ssa.OpConvert,
+		ssa.Op386MOVLconvert,
+		ssa.OpAMD64MOVQconvert,
+		ssa.OpAMD64MOVLconvert,
+		ssa.OpARMMOVWconvert,
+		ssa.OpARM64MOVDconvert,
+		ssa.OpMIPSMOVWconvert,
+		ssa.OpMIPS64MOVVconvert,
+		ssa.OpPPC64MOVDconvert,
+		ssa.OpS390XMOVDconvert:
+		return v.Type.IsPtrShaped()
+	}
+	return false
+}
+
 // Initializes the sets for solving the live variables. Visits all the
 // instructions in each basic block to summarizes the information at each basic
 // block
@@ -1248,12 +1375,13 @@ func (lv *Liveness) emit(argssym, livesym *obj.LSym) {
 // pointer variables in the function and emits a runtime data
 // structure read by the garbage collector.
 // Returns a map from GC safe points to their corresponding stack map index.
-func liveness(e *ssafn, f *ssa.Func) map[*ssa.Value]int {
+func liveness(e *ssafn, f *ssa.Func) LivenessMap {
 	// Construct the global liveness state.
 	vars, idx := getvariables(e.curfn)
 	lv := newliveness(e.curfn, f, vars, idx, e.stkptrsize)
 
 	// Run the dataflow framework.
+	lv.markUnsafePoints()
 	lv.prologue()
 	lv.solve()
 	lv.epilogue()
@@ -1267,5 +1395,9 @@ func liveness(e *ssafn, f *ssa.Func) map[*ssa.Value]int {
 	if ls := e.curfn.Func.lsym; ls != nil {
 		lv.emit(&ls.Func.GCArgs, &ls.Func.GCLocals)
 	}
-	return lv.stackMapIndex
+	return LivenessMap{
+		stackMapIndex: lv.stackMapIndex,
+		unsafePoints:  lv.unsafePoints,
+		allUnsafe:     lv.allUnsafe,
+	}
 }
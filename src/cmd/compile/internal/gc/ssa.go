@@ -4582,9 +4582,14 @@ type SSAGenState struct {
 
 	maxarg int64 // largest frame size for arguments to calls made by the function
 
-	// Map from GC safe points to stack map index, generated by
-	// liveness analysis.
-	stackMapIndex map[*ssa.Value]int
+	// Map from GC safe points to stack map index and the unsafe
+	// points for asynchronous preemption, generated by liveness
+	// analysis.
+	livenessMap LivenessMap
+
+	// unsafe is the value of the last PCDATA $PCDATA_UnsafePoint
+	// emitted.
+	unsafe bool
 }
 
 // Prog appends a new Prog.
@@ -4631,11 +4636,14 @@ func genssa(f *ssa.Func, pp *Progs) {
 
 	e := f.Frontend().(*ssafn)
 
-	s.stackMapIndex = liveness(e, f)
+	s.livenessMap = liveness(e, f)
 
 	// Remember where each block starts.
 	s.bstart = make([]*obj.Prog, f.NumBlocks())
 	s.pp = pp
+	if s.livenessMap.allUnsafe {
+		s.emitUnsafePoint(true)
+	}
 	var progToValue map[*obj.Prog]*ssa.Value
 	var progToBlock map[*obj.Prog]*ssa.Block
 	var valueToProgAfter []*obj.Prog // The first Prog following computation of a value v; v is visible at this point.
@@ -4667,6 +4675,9 @@ func genssa(f *ssa.Func, pp *Progs) {
 		for _, v := range b.Values {
 			x := s.pp.next
 			s.DebugFriendlySetPosFrom(v)
+			if u := s.livenessMap.isUnsafe(v); u != s.unsafe {
+				s.emitUnsafePoint(u)
+			}
 			switch v.Op {
 			case ssa.OpInitMem:
 				// memory arg needs no code
@@ -5093,8 +5104,21 @@ func (s *SSAGenState) AddrScratch(a *obj.Addr) {
 	a.Offset = s.ScratchFpMem.Xoffset
 }
 
+// emitUnsafePoint emits a PCDATA marking the following instructions
+// safe or unsafe for asynchronous preemption.
+func (s *SSAGenState) emitUnsafePoint(unsafe bool) {
+	p := s.Prog(obj.APCDATA)
+	Addrconst(&p.From, objabi.PCDATA_UnsafePoint)
+	if unsafe {
+		Addrconst(&p.To, objabi.PCDATA_UnsafePointUnsafe)
+	} else {
+		Addrconst(&p.To, objabi.PCDATA_UnsafePointSafe)
+	}
+	s.unsafe = unsafe
+}
+
 func (s *SSAGenState) Call(v *ssa.Value) *obj.Prog {
-	idx, ok := s.stackMapIndex[v]
+	idx, ok := s.livenessMap.stackMapIndex[v]
 	if !ok {
 		Fatalf("missing stack map index for %v", v.LongString())
 	}
@@ -0,0 +1,40 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgo is compiled with a profile, written by TestPGO,
+// in which big is called from hot and add.Add is called from
+// the interface method call in hot.
+package pgo
+
+type Adder interface{ Add(a, b int) int }
+
+type add struct{}
+
+func (add) Add(a, b int) int { return a + b }
+
+func big(x int) int {
+	x = x*3 + 1
+	x = x ^ (x >> 3)
+	x = x*5 + 7
+	x = x ^ (x >> 5)
+	x = x*7 + 11
+	x = x ^ (x >> 7)
+	x = x*11 + 13
+	x = x ^ (x >> 11)
+	x = x*13 + 17
+	x = x ^ (x >> 13)
+	x = x*17 + 19
+	x = x ^ (x >> 17)
+	x = x*19 + 23
+	x = x ^ (x >> 19)
+	return x
+}
+
+func hot(a Adder, x int) int {
+	return a.Add(x, big(x)) // line 35
+}
+
+func cold(x int) int {
+	return big(x) // line 39
+}
@@ -0,0 +1,247 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgo reads the CPU profiles used by the compiler for
+// profile-guided optimization. It summarizes a profile as the
+// weighted edges of the call graph of the profiled program,
+// from call sites to the functions they call, and identifies
+// the hot ones.
+//
+// Functions are identified by their symbol names, as recorded in
+// the profile: the import path of the package, a dot, and the name
+// of the function, such as "bytes.(*Buffer).Write" or "main.f.func1".
+// Call sites are identified by the calling function and the line
+// number of the call.
+package pgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// A CallSite identifies a call in the source code.
+type CallSite struct {
+	Caller string // symbol name of the calling function
+	Line   int    // line number of the call
+}
+
+// A CallEdge is an edge of the call graph, from a call site
+// to a function it called, weighted by the samples in which
+// the call was on the stack.
+type CallEdge struct {
+	CallSite
+	Callee string
+	Weight int64
+}
+
+// A Profile is the call graph summary of a CPU profile.
+type Profile struct {
+	TotalWeight int64
+	Edges       []*CallEdge // hottest first
+	HotEdges    int         // number of hot edges, at the start of Edges
+
+	hotCallSites map[CallSite]bool
+	hotCallees   map[string]bool
+	siteEdges    map[CallSite][]*CallEdge
+}
+
+// Read reads a CPU profile in the gzip-compressed or uncompressed
+// protocol buffer format written by runtime/pprof. The hot edges
+// of the profile are the hottest ones that together account for at
+// least threshold percent of the total weight of the edges.
+func Read(r io.Reader, threshold float64) (*Profile, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+	pp, err := parseProto(data)
+	if err != nil {
+		return nil, err
+	}
+	return newProfile(pp, threshold)
+}
+
+// valueIndex returns the index of the sample value to use as weight:
+// CPU time if the profile records it, the last value otherwise.
+func (p *protoProfile) valueIndex() (int, error) {
+	if len(p.sampleTypes) == 0 {
+		return 0, errors.New("profile has no sample types")
+	}
+	for i, vt := range p.sampleTypes {
+		if p.str(vt.typ) == "cpu" {
+			return i, nil
+		}
+	}
+	return len(p.sampleTypes) - 1, nil
+}
+
+func newProfile(pp *protoProfile, threshold float64) (*Profile, error) {
+	vi, err := pp.valueIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	type edgeKey struct {
+		site   CallSite
+		callee string
+	}
+	edges := make(map[edgeKey]*CallEdge)
+	type frame struct {
+		fn   string
+		line int
+	}
+	var stack []frame
+	for _, s := range pp.samples {
+		if vi >= len(s.values) || s.values[vi] <= 0 {
+			continue
+		}
+		w := s.values[vi]
+
+		// Expand the locations, leaf first, into frames.
+		// The lines of a location list inlined calls,
+		// also innermost first.
+		stack = stack[:0]
+		for _, id := range s.locations {
+			for _, l := range pp.locations[id] {
+				name, ok := pp.functions[l.function]
+				if !ok {
+					return nil, errMalformed
+				}
+				stack = append(stack, frame{pp.str(name), int(l.line)})
+			}
+		}
+		for i := 0; i+1 < len(stack); i++ {
+			callee, caller := stack[i], stack[i+1]
+			k := edgeKey{CallSite{caller.fn, caller.line}, callee.fn}
+			e := edges[k]
+			if e == nil {
+				e = &CallEdge{CallSite: k.site, Callee: k.callee}
+				edges[k] = e
+			}
+			e.Weight += w
+		}
+	}
+
+	p := &Profile{
+		hotCallSites: make(map[CallSite]bool),
+		hotCallees:   make(map[string]bool),
+		siteEdges:    make(map[CallSite][]*CallEdge),
+	}
+	for _, e := range edges {
+		p.Edges = append(p.Edges, e)
+		p.TotalWeight += e.Weight
+	}
+	sort.Sort(byWeight(p.Edges))
+
+	var cum int64
+	for _, e := range p.Edges {
+		if float64(cum) >= threshold/100*float64(p.TotalWeight) {
+			break
+		}
+		cum += e.Weight
+		p.HotEdges++
+		p.hotCallSites[e.CallSite] = true
+		p.hotCallees[e.Callee] = true
+		p.siteEdges[e.CallSite] = append(p.siteEdges[e.CallSite], e)
+	}
+	return p, nil
+}
+
+// IsHotCallSite reports whether the call site is the source of a hot edge.
+func (p *Profile) IsHotCallSite(site CallSite) bool {
+	return p.hotCallSites[site]
+}
+
+// IsHotCallee reports whether the named function is the target of a hot edge.
+func (p *Profile) IsHotCallee(fn string) bool {
+	return p.hotCallees[fn]
+}
+
+// HotCallees returns the hot edges from the call site, hottest first.
+func (p *Profile) HotCallees(site CallSite) []*CallEdge {
+	return p.siteEdges[site]
+}
+
+// SplitMethodName splits the symbol name of a method, such as
+// "bytes.(*Buffer).Write" or "time.Time.String", into the import
+// path of the package, the name of the receiver's base type, whether
+// the receiver is a pointer, and the name of the method.
+// It reports ok == false if name is not the name of a method.
+//
+// As in symbol names, the import path is escaped as by
+// objabi.PathToPrefix, so that its last element contains no dots.
+func SplitMethodName(name string) (pkgPath, typeName string, ptr bool, method string, ok bool) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", "", false, "", false
+	}
+	pkgPath, rest := name[:slash+1+dot], name[slash+1+dot+1:]
+	if strings.HasPrefix(rest, "(*") {
+		end := strings.Index(rest, ").")
+		if end < 0 {
+			return "", "", false, "", false
+		}
+		typeName, method, ptr = rest[2:end], rest[end+2:], true
+	} else {
+		i := strings.Index(rest, ".")
+		if i < 0 {
+			return "", "", false, "", false
+		}
+		typeName, method = rest[:i], rest[i+1:]
+	}
+	if typeName == "" || method == "" || strings.Contains(method, ".") || isClosureName(method) {
+		// Closures, such as "pkg.f.func1" or "pkg.T.M.func1", are not methods.
+		return "", "", false, "", false
+	}
+	return pkgPath, typeName, ptr, method, true
+}
+
+// isClosureName reports whether name is of the form funcN,
+// the name given by the compiler to the Nth closure of a function.
+func isClosureName(name string) bool {
+	if !strings.HasPrefix(name, "func") || len(name) == len("func") {
+		return false
+	}
+	for _, c := range name[len("func"):] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// byWeight sorts edges by decreasing weight,
+// then by call site and callee for determinism.
+type byWeight []*CallEdge
+
+func (x byWeight) Len() int      { return len(x) }
+func (x byWeight) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byWeight) Less(i, j int) bool {
+	a, b := x[i], x[j]
+	if a.Weight != b.Weight {
+		return a.Weight > b.Weight
+	}
+	if a.Caller != b.Caller {
+		return a.Caller < b.Caller
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Callee < b.Callee
+}
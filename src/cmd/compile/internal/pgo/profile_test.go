@@ -0,0 +1,194 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+// protoWriter encodes a profile in the protocol buffer format,
+// for the fields read by parseProto.
+type protoWriter struct {
+	buf     []byte
+	strings map[string]int
+	strtab  []string
+}
+
+func (w *protoWriter) varint(x uint64) {
+	for x >= 0x80 {
+		w.buf = append(w.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	w.buf = append(w.buf, byte(x))
+}
+
+func (w *protoWriter) uint(field int, x uint64) {
+	w.varint(uint64(field)<<3 | 0)
+	w.varint(x)
+}
+
+// msg encodes a length-delimited field whose contents are written by f.
+func (w *protoWriter) msg(field int, f func()) {
+	outer := w.buf
+	w.buf = nil
+	f()
+	inner := w.buf
+	w.buf = outer
+	w.varint(uint64(field)<<3 | 2)
+	w.varint(uint64(len(inner)))
+	w.buf = append(w.buf, inner...)
+}
+
+func (w *protoWriter) str(s string) uint64 {
+	if w.strings == nil {
+		w.strings = map[string]int{"": 0}
+		w.strtab = []string{""}
+	}
+	i, ok := w.strings[s]
+	if !ok {
+		i = len(w.strtab)
+		w.strings[s] = i
+		w.strtab = append(w.strtab, s)
+	}
+	return uint64(i)
+}
+
+type testFrame struct {
+	fn   string
+	line int
+}
+
+// encodeProfile returns a CPU profile with a sample for each stack,
+// given leaf first, of the given weight. Each frame gets its own
+// location, except that a stack element holding several frames is
+// a location with inlined calls.
+func encodeProfile(samples map[int64][][]testFrame) []byte {
+	w := new(protoWriter)
+	w.msg(1, func() { w.uint(1, w.str("samples")); w.uint(2, w.str("count")) })
+	w.msg(1, func() { w.uint(1, w.str("cpu")); w.uint(2, w.str("nanoseconds")) })
+	funcs := make(map[string]uint64)
+	var locs [][]testFrame
+	for weight, stack := range samples {
+		var ids []uint64
+		for _, loc := range stack {
+			locs = append(locs, loc)
+			ids = append(ids, uint64(len(locs)))
+			for _, f := range loc {
+				if funcs[f.fn] == 0 {
+					funcs[f.fn] = uint64(len(funcs) + 1)
+				}
+			}
+		}
+		w.msg(2, func() {
+			for _, id := range ids {
+				w.uint(1, id)
+			}
+			// Values packed, as runtime/pprof writes them.
+			w.msg(2, func() { w.varint(uint64(weight)); w.varint(uint64(weight * 10000000)) })
+		})
+	}
+	for i, loc := range locs {
+		w.msg(4, func() {
+			w.uint(1, uint64(i+1))
+			for _, f := range loc {
+				w.msg(4, func() { w.uint(1, funcs[f.fn]); w.uint(2, uint64(f.line)) })
+			}
+		})
+	}
+	for name, id := range funcs {
+		w.msg(5, func() { w.uint(1, id); w.uint(2, w.str(name)) })
+	}
+	for _, s := range w.strtab {
+		w.varint(6<<3 | 2)
+		w.varint(uint64(len(s)))
+		w.buf = append(w.buf, s...)
+	}
+	return w.buf
+}
+
+func TestRead(t *testing.T) {
+	data := encodeProfile(map[int64][][]testFrame{
+		// main.main calls main.hot at line 10, which calls
+		// the method (*main.T).M at line 20 through an interface.
+		90: {{{"main.(*T).M", 30}}, {{"main.hot", 20}}, {{"main.main", 10}}},
+		// main.cold was inlined into main.main at line 11.
+		9: {{{"runtime.memmove", 1}}, {{"main.cold", 40}, {"main.main", 11}}},
+		1: {{{"main.rare", 50}}, {{"main.main", 12}}},
+	})
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(data)
+	zw.Close()
+
+	for _, input := range [][]byte{data, gz.Bytes()} {
+		p, err := Read(bytes.NewReader(input), 95)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Weights come from the cpu sample value.
+		if want := int64(2*90+2*9+1) * 10000000; p.TotalWeight != want {
+			t.Errorf("TotalWeight = %d, want %d", p.TotalWeight, want)
+		}
+		if len(p.Edges) != 5 || p.HotEdges != 4 {
+			t.Errorf("got %d edges, %d hot, want 5, 4", len(p.Edges), p.HotEdges)
+		}
+		if !p.IsHotCallSite(CallSite{"main.hot", 20}) || !p.IsHotCallee("main.(*T).M") {
+			t.Errorf("call from main.hot to main.(*T).M not hot")
+		}
+		if !p.IsHotCallSite(CallSite{"main.main", 11}) || !p.IsHotCallee("main.cold") {
+			t.Errorf("inlined call from main.main to main.cold not hot")
+		}
+		if p.IsHotCallSite(CallSite{"main.main", 12}) || p.IsHotCallee("main.rare") {
+			t.Errorf("call from main.main to main.rare is hot")
+		}
+		if e := p.HotCallees(CallSite{"main.hot", 20}); len(e) != 1 || e[0].Callee != "main.(*T).M" {
+			t.Errorf("HotCallees(main.hot:20) = %v", e)
+		}
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	data := encodeProfile(map[int64][][]testFrame{
+		1: {{{"main.f", 2}}, {{"main.main", 1}}},
+	})
+	for i := 0; i < len(data); i++ {
+		// Truncated data is malformed, or at least lacks
+		// its string table, which comes last.
+		if _, err := Read(bytes.NewReader(data[:i]), 99); err == nil {
+			t.Errorf("Read accepted profile truncated to %d bytes", i)
+		}
+	}
+	if _, err := Read(bytes.NewReader([]byte("not a profile")), 99); err == nil {
+		t.Errorf("Read accepted text")
+	}
+}
+
+func TestSplitMethodName(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg, typ string
+		ptr      bool
+		method   string
+		ok       bool
+	}{
+		{"bytes.(*Buffer).Write", "bytes", "Buffer", true, "Write", true},
+		{"time.Time.String", "time", "Time", false, "String", true},
+		{"gopkg.in/yaml%2ev2.(*parser).parse", "gopkg.in/yaml%2ev2", "parser", true, "parse", true},
+		{"example.com/a.b/c.T.m", "example.com/a.b/c", "T", false, "m", true},
+		{"main.main", "", "", false, "", false},
+		{"main.main.func1", "", "", false, "", false},
+		{"main.T.M.func1", "", "", false, "", false},
+		{"runtime", "", "", false, "", false},
+	}
+	for _, tt := range tests {
+		pkg, typ, ptr, method, ok := SplitMethodName(tt.name)
+		if pkg != tt.pkg || typ != tt.typ || ptr != tt.ptr || method != tt.method || ok != tt.ok {
+			t.Errorf("SplitMethodName(%q) = %q, %q, %v, %q, %v", tt.name, pkg, typ, ptr, method, ok)
+		}
+	}
+}
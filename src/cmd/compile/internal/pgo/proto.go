@@ -0,0 +1,277 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// This file decodes the subset of the profile.proto format,
+// as written by runtime/pprof, that is needed to reconstruct
+// the call stacks of the samples:
+//
+//	message Profile {
+//		repeated ValueType sample_type = 1;
+//		repeated Sample sample = 2;
+//		repeated Location location = 4;
+//		repeated Function function = 5;
+//		repeated string string_table = 6;
+//	}
+//	message ValueType { int64 type = 1; int64 unit = 2; }
+//	message Sample { repeated uint64 location_id = 1; repeated int64 value = 2; }
+//	message Location { uint64 id = 1; repeated Line line = 4; }
+//	message Line { uint64 function_id = 1; int64 line = 2; }
+//	message Function { uint64 id = 1; int64 name = 2; }
+//
+// All other fields are skipped.
+
+type protoProfile struct {
+	sampleTypes []protoValueType
+	samples     []protoSample
+	locations   map[uint64][]protoLine
+	functions   map[uint64]int64 // function id to name string index
+	strings     []string
+}
+
+type protoValueType struct {
+	typ, unit int64
+}
+
+type protoSample struct {
+	locations []uint64
+	values    []int64
+}
+
+type protoLine struct {
+	function uint64
+	line     int64
+}
+
+var errMalformed = errors.New("malformed profile")
+
+// protoBuffer is a protocol buffer message being decoded.
+type protoBuffer struct {
+	data []byte
+	err  error
+}
+
+func (b *protoBuffer) fail() {
+	if b.err == nil {
+		b.err = errMalformed
+	}
+	b.data = nil
+}
+
+func (b *protoBuffer) varint() uint64 {
+	var x uint64
+	for i := uint(0); i < 10 && i < uint(len(b.data)); i++ {
+		c := b.data[i]
+		x |= uint64(c&0x7F) << (7 * i)
+		if c < 0x80 {
+			b.data = b.data[i+1:]
+			return x
+		}
+	}
+	b.fail()
+	return 0
+}
+
+// next decodes the key of the next field, and its contents if it is
+// length-delimited. It returns ok == false at the end of the message.
+func (b *protoBuffer) next() (field int, wire int, v uint64, msg []byte, ok bool) {
+	if len(b.data) == 0 || b.err != nil {
+		return 0, 0, 0, nil, false
+	}
+	key := b.varint()
+	field, wire = int(key>>3), int(key&7)
+	switch wire {
+	case 0:
+		v = b.varint()
+	case 1:
+		if len(b.data) < 8 {
+			b.fail()
+			break
+		}
+		b.data = b.data[8:]
+	case 2:
+		n := b.varint()
+		if n > uint64(len(b.data)) {
+			b.fail()
+			break
+		}
+		msg, b.data = b.data[:n], b.data[n:]
+	case 5:
+		if len(b.data) < 4 {
+			b.fail()
+			break
+		}
+		b.data = b.data[4:]
+	default:
+		b.fail()
+	}
+	return field, wire, v, msg, b.err == nil
+}
+
+// repeated decodes a repeated integer field, packed or not.
+func (b *protoBuffer) repeated(wire int, v uint64, msg []byte, list []uint64) []uint64 {
+	if wire == 0 {
+		return append(list, v)
+	}
+	if wire != 2 {
+		b.fail()
+		return list
+	}
+	packed := protoBuffer{data: msg}
+	for len(packed.data) > 0 && packed.err == nil {
+		list = append(list, packed.varint())
+	}
+	if packed.err != nil {
+		b.fail()
+	}
+	return list
+}
+
+func parseProto(data []byte) (*protoProfile, error) {
+	p := &protoProfile{
+		locations: make(map[uint64][]protoLine),
+		functions: make(map[uint64]int64),
+	}
+	b := protoBuffer{data: data}
+	for {
+		field, wire, _, msg, ok := b.next()
+		if !ok {
+			break
+		}
+		if wire != 2 {
+			continue
+		}
+		switch field {
+		case 1:
+			var vt protoValueType
+			m := protoBuffer{data: msg}
+			for {
+				field, _, v, _, ok := m.next()
+				if !ok {
+					break
+				}
+				switch field {
+				case 1:
+					vt.typ = int64(v)
+				case 2:
+					vt.unit = int64(v)
+				}
+			}
+			if m.err != nil {
+				return nil, m.err
+			}
+			p.sampleTypes = append(p.sampleTypes, vt)
+		case 2:
+			var s protoSample
+			var values []uint64
+			m := protoBuffer{data: msg}
+			for {
+				field, wire, v, msg, ok := m.next()
+				if !ok {
+					break
+				}
+				switch field {
+				case 1:
+					s.locations = m.repeated(wire, v, msg, s.locations)
+				case 2:
+					values = m.repeated(wire, v, msg, values)
+				}
+			}
+			if m.err != nil {
+				return nil, m.err
+			}
+			for _, v := range values {
+				s.values = append(s.values, int64(v))
+			}
+			p.samples = append(p.samples, s)
+		case 4:
+			var id uint64
+			var lines []protoLine
+			m := protoBuffer{data: msg}
+			for {
+				field, wire, v, msg, ok := m.next()
+				if !ok {
+					break
+				}
+				switch {
+				case field == 1:
+					id = v
+				case field == 4 && wire == 2:
+					var l protoLine
+					lm := protoBuffer{data: msg}
+					for {
+						field, _, v, _, ok := lm.next()
+						if !ok {
+							break
+						}
+						switch field {
+						case 1:
+							l.function = v
+						case 2:
+							l.line = int64(v)
+						}
+					}
+					if lm.err != nil {
+						return nil, lm.err
+					}
+					lines = append(lines, l)
+				}
+			}
+			if m.err != nil {
+				return nil, m.err
+			}
+			p.locations[id] = lines
+		case 5:
+			var id uint64
+			var name int64
+			m := protoBuffer{data: msg}
+			for {
+				field, _, v, _, ok := m.next()
+				if !ok {
+					break
+				}
+				switch field {
+				case 1:
+					id = v
+				case 2:
+					name = int64(v)
+				}
+			}
+			if m.err != nil {
+				return nil, m.err
+			}
+			p.functions[id] = name
+		case 6:
+			p.strings = append(p.strings, string(msg))
+		}
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(p.strings) == 0 || p.strings[0] != "" {
+		return nil, fmt.Errorf("malformed profile: missing string table")
+	}
+	for _, name := range p.functions {
+		if name < 0 || name >= int64(len(p.strings)) {
+			return nil, fmt.Errorf("malformed profile: bad function name index %d", name)
+		}
+	}
+	for _, vt := range p.sampleTypes {
+		if vt.typ < 0 || vt.typ >= int64(len(p.strings)) {
+			return nil, fmt.Errorf("malformed profile: bad sample type index %d", vt.typ)
+		}
+	}
+	return p, nil
+}
+
+// str returns the string with index i in the string table.
+func (p *protoProfile) str(i int64) string {
+	return p.strings[i]
+}
@@ -0,0 +1,448 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"internal/coverage"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cmd/internal/objabi"
+)
+
+const usageMessage = `usage: go tool covdata <command> -i=dir1,dir2,... [-o=output]
+
+The commands are:
+
+	merge      merge the data of the input directories
+	           into a single pair of files in the -o directory
+	subtract   keep the counts of the first input directory for the code
+	           that is not covered in any of the other input directories
+	intersect  keep the counts of the input directories for the code
+	           that is covered in all of them
+	textfmt    merge the data of the input directories and write it
+	           to the -o file, or standard output, as a text profile
+
+Run 'go tool covdata <command> -help' for the flags of a command.
+`
+
+func usage() {
+	fmt.Fprint(os.Stderr, usageMessage)
+	os.Exit(2)
+}
+
+// A command is a covdata subcommand. It combines the profiles read
+// from its input directories into one, written to a directory or,
+// for textfmt, to a text profile.
+type command struct {
+	name      string
+	minInputs int
+	text      bool
+	combine   func([]*profile) (*profile, error)
+}
+
+var commands = []*command{
+	{name: "merge", minInputs: 1, combine: mergeProfiles},
+	{name: "subtract", minInputs: 2, combine: subtractProfiles},
+	{name: "intersect", minInputs: 2, combine: intersectProfiles},
+	{name: "textfmt", minInputs: 1, text: true, combine: mergeProfiles},
+}
+
+func main() {
+	objabi.AddVersionFlag()
+	flag.Usage = usage
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("covdata: ")
+
+	if flag.NArg() == 0 {
+		usage()
+	}
+	var cmd *command
+	for _, c := range commands {
+		if c.name == flag.Arg(0) {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "covdata: unknown command %q\n", flag.Arg(0))
+		usage()
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	in := fs.String("i", "", "comma-separated list of input directories")
+	outHelp := "output directory"
+	if cmd.text {
+		outHelp = "output file; default: standard output"
+	}
+	out := fs.String("o", "", outHelp)
+	fs.Parse(flag.Args()[1:])
+	if fs.NArg() != 0 {
+		log.Fatalf("%s: unexpected arguments: %s", cmd.name, strings.Join(fs.Args(), " "))
+	}
+	if *in == "" {
+		log.Fatalf("%s: missing -i flag", cmd.name)
+	}
+	if *out == "" && !cmd.text {
+		log.Fatalf("%s: missing -o flag", cmd.name)
+	}
+	dirs := strings.Split(*in, ",")
+	if len(dirs) < cmd.minInputs {
+		log.Fatalf("%s: requires at least %d input directories", cmd.name, cmd.minInputs)
+	}
+
+	var profiles []*profile
+	for _, dir := range dirs {
+		p, err := readDir(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		profiles = append(profiles, p)
+	}
+	result, err := cmd.combine(profiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cmd.text {
+		err = writeTextFile(*out, result)
+	} else {
+		err = writeDir(*out, result)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// A profile holds coverage counts by source file and basic block.
+// Counts recorded for the same block, by any binary in any run,
+// are combined.
+type profile struct {
+	mode  string
+	files map[string]*fileData // by file name
+	pkgs  map[string][]string  // file names by package path
+}
+
+// fileData holds the blocks of a source file and their counts.
+type fileData struct {
+	blocks []coverage.Block
+	counts []uint32
+	index  map[coverage.Block]int
+}
+
+func newProfile(mode string) *profile {
+	return &profile{
+		mode:  mode,
+		files: make(map[string]*fileData),
+		pkgs:  make(map[string][]string),
+	}
+}
+
+// file returns the data for the named file of package pkgPath,
+// creating it if needed.
+func (p *profile) file(pkgPath, name string) *fileData {
+	f := p.files[name]
+	if f == nil {
+		f = &fileData{index: make(map[coverage.Block]int)}
+		p.files[name] = f
+		p.pkgs[pkgPath] = append(p.pkgs[pkgPath], name)
+	}
+	return f
+}
+
+// add combines count into the count of block b.
+func (f *fileData) add(mode string, b coverage.Block, count uint32) {
+	i, ok := f.index[b]
+	if !ok {
+		i = len(f.blocks)
+		f.index[b] = i
+		f.blocks = append(f.blocks, b)
+		f.counts = append(f.counts, 0)
+	}
+	f.counts[i] = combine(mode, f.counts[i], count)
+}
+
+// count returns the count of block b in the named file.
+func (p *profile) count(name string, b coverage.Block) uint32 {
+	if f := p.files[name]; f != nil {
+		if i, ok := f.index[b]; ok {
+			return f.counts[i]
+		}
+	}
+	return 0
+}
+
+// combine returns the count of a block executed x times in one
+// run and y times in another.
+func combine(mode string, x, y uint32) uint32 {
+	if mode == "set" {
+		if x != 0 || y != 0 {
+			return 1
+		}
+		return 0
+	}
+	if x+y < x {
+		return 1<<32 - 1
+	}
+	return x + y
+}
+
+// addProfile adds the counts of q to those of p.
+func (p *profile) addProfile(q *profile) error {
+	if p.mode != q.mode {
+		return fmt.Errorf("inconsistent coverage modes %q and %q", p.mode, q.mode)
+	}
+	for pkgPath, names := range q.pkgs {
+		for _, name := range names {
+			qf := q.files[name]
+			f := p.file(pkgPath, name)
+			for i, b := range qf.blocks {
+				f.add(p.mode, b, qf.counts[i])
+			}
+		}
+	}
+	return nil
+}
+
+// addMeta adds the counts of a counter data file, or of none
+// if c is nil, for the program described by meta-data m.
+func (p *profile) addMeta(m *coverage.Meta, c *coverage.Counters) {
+	k := 0
+	for _, pkg := range m.Packages {
+		for _, file := range pkg.Files {
+			f := p.file(pkg.Path, file.Name)
+			for i, b := range file.Blocks {
+				var n uint32
+				if c != nil {
+					n = c.Counts[k][i]
+				}
+				f.add(p.mode, b, n)
+			}
+			k++
+		}
+	}
+}
+
+// readDir reads the coverage data files in dir. Programs for which
+// the directory holds a meta-data file but no counter data contribute
+// their blocks with zero counts.
+func readDir(dir string) (*profile, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	metas := make(map[coverage.Hash]*coverage.Meta)
+	var counterFiles []string
+	for _, info := range infos {
+		h, isMeta, isCounter := coverage.ParseFileName(info.Name())
+		name := filepath.Join(dir, info.Name())
+		switch {
+		case isMeta:
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			m, err := coverage.DecodeMeta(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			if coverage.HashMeta(data) != h {
+				return nil, fmt.Errorf("%s: meta-data does not match its hash", name)
+			}
+			metas[h] = m
+		case isCounter:
+			counterFiles = append(counterFiles, name)
+		}
+	}
+	if len(metas) == 0 {
+		return nil, fmt.Errorf("no coverage meta-data files in %s", dir)
+	}
+
+	var p *profile
+	for _, m := range metas {
+		if p == nil {
+			p = newProfile(m.Mode)
+		} else if p.mode != m.Mode {
+			return nil, fmt.Errorf("%s: inconsistent coverage modes %q and %q", dir, p.mode, m.Mode)
+		}
+		p.addMeta(m, nil)
+	}
+	for _, name := range counterFiles {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		c, err := coverage.DecodeCounters(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		m := metas[c.MetaHash]
+		if m == nil {
+			return nil, fmt.Errorf("%s: no meta-data file for counter data", name)
+		}
+		if err := c.Check(m); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		p.addMeta(m, c)
+	}
+	return p, nil
+}
+
+func mergeProfiles(profiles []*profile) (*profile, error) {
+	p := newProfile(profiles[0].mode)
+	for _, q := range profiles {
+		if err := p.addProfile(q); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// subtractProfiles keeps the counts of the first profile
+// for the blocks not covered in any of the others.
+func subtractProfiles(profiles []*profile) (*profile, error) {
+	p, err := mergeProfiles(profiles[:1])
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range profiles[1:] {
+		if q.mode != p.mode {
+			return nil, fmt.Errorf("inconsistent coverage modes %q and %q", p.mode, q.mode)
+		}
+		for name, f := range p.files {
+			for i, b := range f.blocks {
+				if q.count(name, b) != 0 {
+					f.counts[i] = 0
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// intersectProfiles combines the counts of the profiles
+// for the blocks covered in all of them.
+func intersectProfiles(profiles []*profile) (*profile, error) {
+	p, err := mergeProfiles(profiles)
+	if err != nil {
+		return nil, err
+	}
+	for name, f := range p.files {
+		for i, b := range f.blocks {
+			for _, q := range profiles {
+				if q.count(name, b) == 0 {
+					f.counts[i] = 0
+					break
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// sortedPkgs returns the package paths of p in order.
+func (p *profile) sortedPkgs() []string {
+	var paths []string
+	for path := range p.pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// sortedBlocks returns the indexes of the blocks of f
+// in order of position.
+func (f *fileData) sortedBlocks() []int {
+	idx := make([]int, len(f.blocks))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		bi, bj := f.blocks[idx[i]], f.blocks[idx[j]]
+		if bi.StartLine != bj.StartLine {
+			return bi.StartLine < bj.StartLine
+		}
+		return bi.StartCol < bj.StartCol
+	})
+	return idx
+}
+
+// writeText writes p in the text profile format.
+func writeText(w io.Writer, p *profile) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", p.mode)
+	for _, path := range p.sortedPkgs() {
+		names := append([]string(nil), p.pkgs[path]...)
+		sort.Strings(names)
+		for _, name := range names {
+			f := p.files[name]
+			for _, i := range f.sortedBlocks() {
+				b := f.blocks[i]
+				fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n", name, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, f.counts[i])
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// writeTextFile writes p in the text profile format to the named file,
+// or to standard output if name is empty.
+func writeTextFile(name string, p *profile) error {
+	if name == "" {
+		return writeText(os.Stdout, p)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = writeText(f, p)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// writeDir writes p to dir as a meta-data file
+// and a counter data file.
+func writeDir(dir string, p *profile) error {
+	m := &coverage.Meta{Mode: p.mode}
+	c := new(coverage.Counters)
+	for _, path := range p.sortedPkgs() {
+		pkg := coverage.Package{Path: path}
+		names := append([]string(nil), p.pkgs[path]...)
+		sort.Strings(names)
+		for _, name := range names {
+			f := p.files[name]
+			file := coverage.File{Name: name}
+			var counts []uint32
+			for _, i := range f.sortedBlocks() {
+				file.Blocks = append(file.Blocks, f.blocks[i])
+				counts = append(counts, f.counts[i])
+			}
+			pkg.Files = append(pkg.Files, file)
+			c.Counts = append(c.Counts, counts)
+		}
+		m.Packages = append(m.Packages, pkg)
+	}
+	data := m.Encode()
+	c.MetaHash = coverage.HashMeta(data)
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, coverage.MetaFileName(c.MetaHash)), data, 0666); err != nil {
+		return err
+	}
+	name := coverage.CounterFileName(c.MetaHash, os.Getpid(), time.Now().UnixNano())
+	return ioutil.WriteFile(filepath.Join(dir, name), c.Encode(), 0666)
+}
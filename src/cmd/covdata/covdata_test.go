@@ -0,0 +1,177 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"internal/coverage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	blockA1 = coverage.Block{StartLine: 3, StartCol: 14, EndLine: 5, EndCol: 2, NumStmt: 1}
+	blockA2 = coverage.Block{StartLine: 7, StartCol: 14, EndLine: 9, EndCol: 2, NumStmt: 2}
+	blockB1 = coverage.Block{StartLine: 4, StartCol: 10, EndLine: 6, EndCol: 2, NumStmt: 3}
+)
+
+// Two builds of a program, the second of which also instruments package b.
+var (
+	metaA = &coverage.Meta{
+		Mode: "count",
+		Packages: []coverage.Package{
+			{Path: "p/a", Files: []coverage.File{{Name: "p/a/a.go", Blocks: []coverage.Block{blockA1, blockA2}}}},
+		},
+	}
+	metaAB = &coverage.Meta{
+		Mode: "count",
+		Packages: []coverage.Package{
+			{Path: "p/b", Files: []coverage.File{{Name: "p/b/b.go", Blocks: []coverage.Block{blockB1}}}},
+			{Path: "p/a", Files: []coverage.File{{Name: "p/a/a.go", Blocks: []coverage.Block{blockA2, blockA1}}}},
+		},
+	}
+)
+
+// writeRun writes to dir the data of a run of the program with
+// meta-data m that recorded the given counts.
+func writeRun(t *testing.T, dir string, pid int, m *coverage.Meta, counts ...[]uint32) {
+	data := m.Encode()
+	h := coverage.HashMeta(data)
+	if err := ioutil.WriteFile(filepath.Join(dir, coverage.MetaFileName(h)), data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	c := &coverage.Counters{MetaHash: h, Counts: counts}
+	if err := ioutil.WriteFile(filepath.Join(dir, coverage.CounterFileName(h, pid, 1)), c.Encode(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func textProfile(t *testing.T, p *profile) string {
+	var buf bytes.Buffer
+	if err := writeText(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCommands(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "covdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir1 := filepath.Join(tmp, "1")
+	dir2 := filepath.Join(tmp, "2")
+	os.Mkdir(dir1, 0777)
+	os.Mkdir(dir2, 0777)
+	writeRun(t, dir1, 1, metaA, []uint32{1, 0})
+	writeRun(t, dir1, 2, metaA, []uint32{2, 0})
+	writeRun(t, dir2, 3, metaAB, []uint32{5}, []uint32{0, 4})
+	// A program that was built but never ran.
+	os.Mkdir(filepath.Join(tmp, "3"), 0777)
+	data := (&coverage.Meta{Mode: "count", Packages: []coverage.Package{
+		{Path: "p/c", Files: []coverage.File{{Name: "p/c/c.go", Blocks: []coverage.Block{blockB1}}}},
+	}}).Encode()
+	if err := ioutil.WriteFile(filepath.Join(tmp, "3", coverage.MetaFileName(coverage.HashMeta(data))), data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var profiles []*profile
+	for _, dir := range []string{dir1, dir2, filepath.Join(tmp, "3")} {
+		p, err := readDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	tests := []struct {
+		name    string
+		combine func([]*profile) (*profile, error)
+		inputs  []*profile
+		want    string
+	}{
+		{"merge", mergeProfiles, profiles, `mode: count
+p/a/a.go:3.14,5.2 1 7
+p/a/a.go:7.14,9.2 2 0
+p/b/b.go:4.10,6.2 3 5
+p/c/c.go:4.10,6.2 3 0
+`},
+		{"subtract", subtractProfiles, profiles[1:2], `mode: count
+p/a/a.go:3.14,5.2 1 4
+p/a/a.go:7.14,9.2 2 0
+p/b/b.go:4.10,6.2 3 5
+`},
+		{"subtract", subtractProfiles, profiles[:2], `mode: count
+p/a/a.go:3.14,5.2 1 0
+p/a/a.go:7.14,9.2 2 0
+`},
+		{"subtract", subtractProfiles, []*profile{profiles[1], profiles[0]}, `mode: count
+p/a/a.go:3.14,5.2 1 0
+p/a/a.go:7.14,9.2 2 0
+p/b/b.go:4.10,6.2 3 5
+`},
+		{"intersect", intersectProfiles, profiles[:2], `mode: count
+p/a/a.go:3.14,5.2 1 7
+p/a/a.go:7.14,9.2 2 0
+p/b/b.go:4.10,6.2 3 0
+`},
+	}
+	for _, tt := range tests {
+		p, err := tt.combine(tt.inputs)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := textProfile(t, p); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	// Data written by merge reads back the same.
+	merged, err := mergeProfiles(profiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(tmp, "out")
+	if err := writeDir(out, merged); err != nil {
+		t.Fatal(err)
+	}
+	reread, err := readDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := textProfile(t, reread), textProfile(t, merged); got != want {
+		t.Errorf("merged data reads back as\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestModeMismatch(t *testing.T) {
+	p, q := newProfile("set"), newProfile("count")
+	if _, err := mergeProfiles([]*profile{p, q}); err == nil {
+		t.Errorf("merge of set and count profiles succeeded")
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		mode string
+		x, y uint32
+		want uint32
+	}{
+		{"set", 0, 0, 0},
+		{"set", 1, 0, 1},
+		{"set", 1, 1, 1},
+		{"count", 2, 3, 5},
+		{"atomic", 1<<32 - 2, 3, 1<<32 - 1},
+	}
+	for _, tt := range tests {
+		if got := combine(tt.mode, tt.x, tt.y); got != tt.want {
+			t.Errorf("combine(%q, %d, %d) = %d, want %d", tt.mode, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
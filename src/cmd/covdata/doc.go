@@ -0,0 +1,40 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Covdata is a program for manipulating the coverage data files written
+by programs built with 'go build -cover'.
+
+Such a program writes, when it exits, a meta-data file describing its
+instrumented source code and a counter data file holding the execution
+counts of the run into the directory named by $GOCOVERDIR. Covdata
+reads the files in one or more such directories and combines the
+counts recorded for the same source code, whichever binary or run
+they come from.
+
+Usage:
+
+	go tool covdata <command> -i=dir1,dir2,... [-o=output]
+
+The commands are:
+
+	merge      merge the data of the input directories
+	           into a single pair of files in the -o directory
+	subtract   keep the counts of the first input directory for the code
+	           that is not covered in any of the other input directories
+	intersect  keep the counts of the input directories for the code
+	           that is covered in all of them
+	textfmt    merge the data of the input directories and write it to
+	           the -o file, or standard output, in the text profile format
+	           produced by 'go test -coverprofile' and read by 'go tool cover'
+
+For example, to view the coverage of an integration test as HTML:
+
+	go build -cover -o myserver ./cmd/myserver
+	mkdir covdata
+	GOCOVERDIR=covdata ./run-integration-tests.sh
+	go tool covdata textfmt -i=covdata -o=profile.txt
+	go tool cover -html=profile.txt
+*/
+package main
@@ -23,7 +23,8 @@
 // 	generate    generate Go files by processing source
 // 	get         download and install packages and dependencies
 // 	install     compile and install packages and dependencies
-// 	list        list packages
+// 	list        list packages or modules
+// 	mod         module maintenance
 // 	run         compile and run Go program
 // 	test        test packages
 // 	tool        run specified go tool
@@ -38,11 +39,16 @@
 // 	buildmode   description of build modes
 // 	filetype    file types
 // 	gopath      GOPATH environment variable
+// 	gopath-get  legacy GOPATH go get
+// 	goproxy     module proxy protocol
 // 	environment environment variables
 // 	importpath  import path syntax
+// 	modules     modules, module versions, and more
+// 	module-get  module-aware go get
 // 	packages    description of package lists
 // 	testflag    description of testing flags
 // 	testfunc    description of testing functions
+// 	go.mod      the go.mod file
 //
 // Use "go help [topic]" for more information about that topic.
 //
@@ -126,6 +132,15 @@
 // 	-linkshared
 // 		link against shared libraries previously created with
 // 		-buildmode=shared.
+// 	-mod mode
+// 		module download mode to use: readonly or vendor.
+// 		See 'go help modules' for more.
+// 	-pgo file
+// 		use the CPU profile in file, as written by runtime/pprof,
+// 		for profile-guided optimization: the compiler inlines more
+// 		aggressively at the hot call sites of the profile and
+// 		devirtualizes hot interface method calls.
+// 		Only supported by the gc compiler.
 // 	-pkgdir dir
 // 		install and load all packages from dir instead of the usual locations.
 // 		For example, when building with a non-standard configuration,
@@ -155,6 +170,28 @@
 // only for package fmt, while 'go build -gcflags=all=-S fmt'
 // prints the disassembly for fmt and all its dependencies.
 //
+// The build, install and run commands also accept flags that build
+// programs instrumented to record the coverage of their source code:
+//
+// 	-cover
+// 		enable code coverage instrumentation.
+// 	-covermode set,count,atomic
+// 		the mode of coverage analysis, as for 'go test'.
+// 		The default is "set", or "atomic" if -race is enabled.
+// 		Sets -cover.
+// 	-coverpkg pattern1,pattern2,pattern3
+// 		instrument the packages, among those named on the command line
+// 		and their dependencies, that match the patterns. The default is
+// 		to instrument only the packages named on the command line.
+// 		Sets -cover.
+//
+// When an instrumented program exits, by returning from main.main or by
+// calling os.Exit, it writes its coverage data to the directory named by
+// the GOCOVERDIR environment variable. Use 'go tool covdata' to merge the
+// data of several runs and to convert it to a profile for 'go tool cover'.
+// Package runtime/coverage lets long-running programs write the data
+// at other times.
+//
 // For more about specifying packages, see 'go help packages'.
 // For more about where packages and binaries are installed,
 // run 'go help gopath'.
@@ -174,7 +211,7 @@
 //
 // Usage:
 //
-// 	go clean [-i] [-r] [-n] [-x] [-cache] [-testcache] [build flags] [packages]
+// 	go clean [-i] [-r] [-n] [-x] [-cache] [-testcache] [-modcache] [build flags] [packages]
 //
 // Clean removes object files from package source directories.
 // The go command builds most objects in a temporary directory,
@@ -217,6 +254,10 @@
 // The -testcache flag causes clean to expire all test results in the
 // go build cache.
 //
+// The -modcache flag causes clean to remove the entire module
+// download cache, including unpacked source code of versioned
+// dependencies.
+//
 // For more about build flags, see 'go help build'.
 //
 // For more about specifying packages, see 'go help packages'.
@@ -555,6 +596,12 @@
 // For more about how 'go get' finds source code to
 // download, see 'go help importpath'.
 //
+// This text describes the behavior of get when using GOPATH
+// to manage source code and dependencies.
+// If instead the go command is running in module-aware mode,
+// the details of get's flags and effects change, as does 'go help get'.
+// See 'go help modules' and 'go help module-get'.
+//
 // See also: go build, go install, go clean.
 //
 //
@@ -574,13 +621,16 @@
 // See also: go build, go get, go clean.
 //
 //
-// List packages
+// List packages or modules
 //
 // Usage:
 //
-// 	go list [-e] [-f format] [-json] [build flags] [packages]
+// 	go list [-e] [-f format] [-json] [-m] [-u] [-versions] [build flags] [packages]
 //
-// List lists the packages named by the import paths, one per line.
+// List lists the named packages, one per line.
+// The most commonly-used flags are -f and -json, which control the form
+// of the output printed for each package. Other list flags, documented below,
+// control more specific details.
 //
 // The default output shows the package import path:
 //
@@ -608,6 +658,7 @@
 //         Root          string // Go root or Go path dir containing this package
 //         ConflictDir   string // this directory shadows Dir in $GOPATH
 //         BinaryOnly    bool   // binary-only package: cannot be recompiled from sources
+//         Module        *Module // info about package's containing module, if any (can be nil)
 //
 //         // Source files
 //         GoFiles        []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
@@ -625,6 +676,14 @@
 //         TestGoFiles    []string // _test.go files in package
 //         XTestGoFiles   []string // _test.go files outside package
 //
+//         // Embedded files
+//         EmbedPatterns      []string // //go:embed patterns
+//         EmbedFiles         []string // files matched by EmbedPatterns
+//         TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+//         TestEmbedFiles     []string // files matched by TestEmbedPatterns
+//         XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+//         XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
+//
 //         // Cgo directives
 //         CgoCFLAGS    []string // cgo: flags for C compiler
 //         CgoCPPFLAGS  []string // cgo: flags for C preprocessor
@@ -692,10 +751,246 @@
 // a non-nil Error field; other information may or may not be missing
 // (zeroed).
 //
+// The -m flag causes list to list modules instead of packages.
+//
+// When listing modules, the -f flag still specifies a format template
+// applied to a Go struct, but now a Module struct:
+//
+//     type Module struct {
+//         Path     string       // module path
+//         Version  string       // module version
+//         Versions []string     // available module versions (with -versions)
+//         Replace  *Module      // replaced by this module
+//         Update   *Module      // available update, if any (with -u)
+//         Time     *time.Time   // time version was created
+//         Main     bool         // is this the main module?
+//         Indirect bool         // is this module only an indirect dependency of main module?
+//         Dir      string       // directory holding files for this module, if any
+//         GoMod    string       // path to go.mod file for this module, if any
+//         Error    *ModuleError // error loading module
+//     }
+//
+//     type ModuleError struct {
+//         Err string // the error itself
+//     }
+//
+// The default output is to print the module path and then
+// information about the version and replacement if any.
+// For example, 'go list -m all' might print:
+//
+//     my/main/module
+//     golang.org/x/text v0.3.0 => /tmp/text
+//     rsc.io/pdf v0.1.1
+//
+// The -u flag adds information about available upgrades.
+// When the latest version of a given module is newer than
+// the current one, list -u sets the Module's Update field
+// to information about the newer module, and the default
+// output shows the newer version in brackets after the
+// current version. For example, 'go list -m -u all' might print:
+//
+//     my/main/module
+//     golang.org/x/text v0.3.0 [v0.4.0] => /tmp/text
+//     rsc.io/pdf v0.1.1 [v0.1.2]
+//
+// The -versions flag causes list to set the Module's Versions field
+// to a list of all known versions of that module, ordered according
+// to semantic versioning, earliest to latest. The flag also changes
+// the default output format to display the module path followed by the
+// space-separated version list.
+//
+// The arguments to list -m are interpreted as a list of modules, not packages.
+// The main module is the module containing the current directory.
+// The active modules are the main module and its dependencies.
+// With no arguments, list -m shows the main module.
+// With arguments, list -m shows the modules specified by the arguments.
+// Any of the active modules can be specified by its module path.
+// The special pattern "all" specifies all the active modules, first the main
+// module and then dependencies sorted by module path.
+// A pattern containing "..." specifies the active modules whose
+// module paths match the pattern.
+// A query of the form path@version specifies the result of that query,
+// which is not limited to active modules.
+// See 'go help modules' for more about module queries.
+//
 // For more about build flags, see 'go help build'.
 //
 // For more about specifying packages, see 'go help packages'.
 //
+// For more about modules, see 'go help modules'.
+//
+//
+// Module maintenance
+//
+// Go mod provides access to operations on modules.
+//
+// Note that support for modules is built into all the go commands,
+// not just 'go mod'. For example, day-to-day adding, removing, upgrading,
+// and downgrading of dependencies should be done using 'go get'.
+// See 'go help modules' for an overview of module functionality.
+//
+// Usage:
+//
+// 	go mod <command> [arguments]
+//
+// The commands are:
+//
+// 	download    download modules to local cache
+// 	graph       print module requirement graph
+// 	init        initialize new module in current directory
+// 	tidy        add missing and remove unused modules
+// 	vendor      make vendored copy of dependencies
+// 	verify      verify dependencies have expected content
+// 	why         explain why packages or modules are needed
+//
+// Use "go help mod <command>" for more information about a command.
+//
+//
+// Download modules to local cache
+//
+// Usage:
+//
+// 	go mod download [-json] [modules]
+//
+// Download downloads the named modules, which can be module patterns selecting
+// dependencies of the main module or module queries of the form path@version.
+// With no arguments, download applies to all dependencies of the main module.
+//
+// The go command will automatically download modules as needed during ordinary
+// execution. The "go mod download" command is useful mainly for pre-filling
+// the local cache or to compute the answers for a module proxy.
+//
+// By default, download reports errors to standard error but is otherwise silent.
+// The -json flag causes download to print a sequence of JSON objects
+// to standard output, describing each downloaded module (or failure),
+// corresponding to this Go struct:
+//
+//     type Module struct {
+//         Path     string // module path
+//         Version  string // module version
+//         Error    string // error loading module
+//         Info     string // absolute path to cached .info file
+//         GoMod    string // absolute path to cached .mod file
+//         Zip      string // absolute path to cached .zip file
+//         Dir      string // absolute path to cached source root directory
+//         Sum      string // checksum for path, version (as in go.sum)
+//         GoModSum string // checksum for go.mod (as in go.sum)
+//     }
+//
+// See 'go help modules' for more about module queries.
+//
+//
+// Print module requirement graph
+//
+// Usage:
+//
+// 	go mod graph
+//
+// Graph prints the module requirement graph (with replacements applied)
+// in text form. Each line in the output has two space-separated fields: a module
+// and one of its requirements. Each module is identified as a string of the form
+// path@version, except for the main module, which has no @version suffix.
+//
+//
+// Initialize new module in current directory
+//
+// Usage:
+//
+// 	go mod init [module]
+//
+// Init initializes and writes a new go.mod to the current directory,
+// in effect creating a new module rooted at the current directory.
+// The file go.mod must not already exist.
+// If possible, init will guess the module path from import comments
+// (see 'go help importpath') or from the location of the directory
+// within GOPATH/src.
+//
+// Example:
+//
+// 	go mod init example.com/m
+//
+//
+// Add missing and remove unused modules
+//
+// Usage:
+//
+// 	go mod tidy [-v]
+//
+// Tidy makes sure go.mod matches the source code in the module.
+// It adds any missing modules necessary to build the current module's
+// packages and dependencies, and it removes unused modules that
+// don't provide any relevant packages. It also adds any missing entries
+// to go.sum and removes any unnecessary ones.
+//
+// The -v flag causes tidy to print information about removed modules
+// to standard error.
+//
+//
+// Make vendored copy of dependencies
+//
+// Usage:
+//
+// 	go mod vendor [-v]
+//
+// Vendor resets the main module's vendor directory to include all packages
+// needed to build and test all the main module's packages.
+// It does not include test code for vendored packages.
+//
+// The list of vendored modules and packages is recorded in
+// vendor/modules.txt, which the -mod=vendor build flag consults.
+//
+// The -v flag causes vendor to print the names of vendored
+// modules and packages to standard error.
+//
+//
+// Verify dependencies have expected content
+//
+// Usage:
+//
+// 	go mod verify
+//
+// Verify checks that the dependencies of the current module,
+// which are stored in a local downloaded source cache, have not been
+// modified since being downloaded. If all the modules are unmodified,
+// verify prints "all modules verified." Otherwise it reports which
+// modules have been changed and causes 'go mod' to exit with a
+// non-zero status.
+//
+//
+// Explain why packages or modules are needed
+//
+// Usage:
+//
+// 	go mod why [-m] packages...
+//
+// Why shows a shortest path in the import graph from the main module to
+// each of the listed packages. If the -m flag is given, why treats the
+// arguments as a list of modules and finds a path to any package in each
+// of the modules.
+//
+// Why queries the graph of packages matched by "go list all", which
+// includes the tests of the main module's packages.
+//
+// The output is a sequence of stanzas, one for each package or module
+// name on the command line, separated by blank lines. Each stanza begins
+// with a comment line "# package" or "# module" giving the target
+// package or module. Subsequent lines give a path through the import
+// graph, one package per line. If the package or module is not
+// referenced from the main module, the stanza will display a single
+// parenthesized note indicating that fact.
+//
+// For example:
+//
+// 	$ go mod why golang.org/x/text/language golang.org/x/text/encoding
+// 	# golang.org/x/text/language
+// 	example.com/quote
+// 	example.com/sampler
+// 	golang.org/x/text/language
+//
+// 	# golang.org/x/text/encoding
+// 	(main module does not need package golang.org/x/text/encoding)
+// 	$
+//
 //
 // Compile and run Go program
 //
@@ -739,8 +1034,8 @@
 //
 // 'Go test' recompiles each package along with any files with names matching
 // the file pattern "*_test.go".
-// These additional files can contain test functions, benchmark functions, and
-// example functions. See 'go help testfunc' for more.
+// These additional files can contain test functions, benchmark functions, fuzz
+// targets, and example functions. See 'go help testfunc' for more.
 // Each listed package causes the execution of a separate test binary.
 // Files whose names begin with "_" (including "_test.go") or "." are ignored.
 //
@@ -814,6 +1109,8 @@
 //
 // 	-json
 // 	    Convert test output to JSON suitable for automated processing.
+// 	    The output of building the tests, including build and vet
+// 	    errors, is also reported as JSON events, on standard output.
 // 	    See 'go doc test2json' for the encoding details.
 //
 // 	-o file
@@ -857,7 +1154,7 @@
 //
 // Usage:
 //
-// 	go vet [-n] [-x] [build flags] [vet flags] [packages]
+// 	go vet [-n] [-x] [-vettool prog] [build flags] [vet flags] [packages]
 //
 // Vet runs the Go vet command on the packages named by the import paths.
 //
@@ -867,6 +1164,14 @@
 // The -n flag prints commands that would be executed.
 // The -x flag prints commands as they are executed.
 //
+// The -vettool=prog flag selects a different analysis tool with alternative
+// or additional checks, such as one built with the go/analysis/unitchecker
+// or go/analysis/multichecker package. The flags accepted by go vet are then
+// those reported by the tool. For example:
+//
+// 	go build -o mychecker ./cmd/mychecker
+// 	go vet -vettool=$(pwd)/mychecker ./...
+//
 // The build flags supported by go vet are those that control package resolution
 // and execution, such as -n, -x, -v, -tags, and -toolexec.
 // For more about these flags, see 'go help build'.
@@ -1133,6 +1438,129 @@
 // See https://golang.org/s/go15vendor for details.
 //
 //
+// Legacy GOPATH go get
+//
+// The 'go get' command changes behavior depending on whether the
+// go command is running in module-aware mode or legacy GOPATH mode.
+// This help text, accessible as 'go help gopath-get' even in module-aware mode,
+// describes 'go get' as it operates in legacy GOPATH mode.
+//
+// Usage: get [-d] [-f] [-fix] [-insecure] [-t] [-u] [build flags] [packages]
+//
+// Get downloads the packages named by the import paths, along with their
+// dependencies. It then installs the named packages, like 'go install'.
+//
+// The -d flag instructs get to stop after downloading the packages; that is,
+// it instructs get not to install the packages.
+//
+// The -f flag, valid only when -u is set, forces get -u not to verify that
+// each package has been checked out from the source control repository
+// implied by its import path. This can be useful if the source is a local fork
+// of the original.
+//
+// The -fix flag instructs get to run the fix tool on the downloaded packages
+// before resolving dependencies or building the code.
+//
+// The -insecure flag permits fetching from repositories and resolving
+// custom domains using insecure schemes such as HTTP. Use with caution.
+//
+// The -t flag instructs get to also download the packages required to build
+// the tests for the specified packages.
+//
+// The -u flag instructs get to use the network to update the named packages
+// and their dependencies. By default, get uses the network to check out
+// missing packages but does not use it to look for updates to existing packages.
+//
+// The -v flag enables verbose progress and debug output.
+//
+// Get also accepts build flags to control the installation. See 'go help build'.
+//
+// When checking out a new package, get creates the target directory
+// GOPATH/src/<import-path>. If the GOPATH contains multiple entries,
+// get uses the first one. For more details see: 'go help gopath'.
+//
+// When checking out or updating a package, get looks for a branch or tag
+// that matches the locally installed version of Go. The most important
+// rule is that if the local installation is running version "go1", get
+// searches for a branch or tag named "go1". If no such version exists
+// it retrieves the default branch of the package.
+//
+// When go get checks out or updates a Git repository,
+// it also updates any git submodules referenced by the repository.
+//
+// Get never checks out or updates code stored in vendor directories.
+//
+// For more about specifying packages, see 'go help packages'.
+//
+// For more about how 'go get' finds source code to
+// download, see 'go help importpath'.
+//
+// This text describes the behavior of get when using GOPATH
+// to manage source code and dependencies.
+// If instead the go command is running in module-aware mode,
+// the details of get's flags and effects change, as does 'go help get'.
+// See 'go help modules' and 'go help module-get'.
+//
+// See also: go build, go install, go clean.
+//
+//
+// Module proxy protocol
+//
+// The go command downloads modules from a module proxy named by the
+// GOPROXY environment variable. GOPROXY is the URL of the proxy:
+// an http://, https://, or file:// URL. Setting GOPROXY to "off"
+// disallows downloading modules from any source; in that case only
+// modules already in the module cache can be used.
+//
+// A module proxy is a web server, or a directory tree, that responds to
+// GET requests for URLs of a specified form. The requests have no query
+// parameters, so even a site serving from a fixed file system
+// (including a file:/// URL) can be a module proxy.
+//
+// The GET requests sent to a Go module proxy are:
+//
+// GET $GOPROXY/<module>/@v/list returns a list of all known versions of the
+// given module, one per line.
+//
+// GET $GOPROXY/<module>/@v/<version>.info returns JSON-formatted metadata
+// about that version of the given module.
+//
+// GET $GOPROXY/<module>/@v/<version>.mod returns the go.mod file
+// for that version of the given module.
+//
+// GET $GOPROXY/<module>/@v/<version>.zip returns the zip archive
+// for that version of the given module.
+//
+// To avoid problems when serving from case-sensitive file systems,
+// the <module> and <version> elements are case-encoded, replacing every
+// uppercase letter with an exclamation mark followed by the corresponding
+// lower-case letter: github.com/Azure encodes as github.com/!azure.
+//
+// The JSON-formatted metadata about a given module corresponds to
+// this Go data structure, which may be expanded in the future:
+//
+//     type Info struct {
+//         Version string    // version string
+//         Time    time.Time // commit time
+//     }
+//
+// The zip archive for a specific version of a given module is a
+// standard zip file that contains the file tree corresponding
+// to the module's source code and related files. The archive uses
+// slash-separated paths, and every file path in the archive must
+// begin with <module>@<version>/, where the module and version are
+// substituted directly, not case-encoded. The root of the module
+// file tree corresponds to the <module>@<version>/ prefix in the
+// archive.
+//
+// The go command stores the info, mod, and zip files it downloads
+// in its local cache, $GOPATH/pkg/mod/cache/download.
+// The cache layout is the same as the proxy URL space, so
+// serving $GOPATH/pkg/mod/cache/download at (or copying it to)
+// https://example.com/proxy would let other users access those
+// cached module versions with GOPROXY=https://example.com/proxy.
+//
+//
 // Environment variables
 //
 // The go command, and the tools it invokes, examine a few different
@@ -1154,6 +1582,11 @@
 // 		Examples are linux, darwin, windows, netbsd.
 // 	GOPATH
 // 		For more details see: 'go help gopath'.
+// 	GOPROXY
+// 		URL of Go module proxy. See 'go help goproxy'.
+// 	GO111MODULE
+// 		Controls whether module mode is used: on, off, or auto.
+// 		See 'go help modules'.
 // 	GORACE
 // 		Options for the race detector.
 // 		See https://golang.org/doc/articles/race_detector.html.
@@ -1165,6 +1598,14 @@
 // 	GOCACHE
 // 		The directory where the go command will store
 // 		cached information for reuse in future builds.
+// 	GOCACHEPROG
+// 		A command line, split at spaces, running a helper program
+// 		to which the go command delegates build cache lookups and
+// 		writes, such as to share a cache between machines.
+// 		The GOCACHE directory remains in use as a local cache.
+// 	GOCOVERDIR
+// 		The directory into which programs built with -cover
+// 		write their coverage data. See 'go help build'.
 //
 // Environment variables for use with cgo:
 //
@@ -1393,6 +1834,382 @@
 // See https://golang.org/s/go14customimport for details.
 //
 //
+// Modules, module versions, and more
+//
+// A module is a collection of related Go packages.
+// Modules are the unit of source code interchange and versioning.
+// The go command has direct support for working with modules,
+// including recording and resolving dependencies on other modules.
+// Modules replace the old GOPATH-based approach to specifying
+// which source files are used in a given build.
+//
+// Preliminary module support
+//
+// The go command can be run in module-aware mode or in GOPATH mode.
+// The GO111MODULE environment variable selects between them.
+//
+// If GO111MODULE=off, the go command never uses module support.
+// It looks in vendor directories and GOPATH to find dependencies,
+// as it always has.
+//
+// If GO111MODULE=on, the go command requires the use of modules,
+// never consulting GOPATH to find dependencies. We refer to this as
+// the command being module-aware or running in "module-aware mode".
+//
+// If GO111MODULE=auto or is unset, the go command enables or disables
+// module support based on the current directory. Module support is
+// enabled only when the current directory is outside GOPATH/src and
+// itself contains a go.mod file or is below a directory containing
+// a go.mod file.
+//
+// In module-aware mode, GOPATH no longer defines the meaning of imports
+// during a build, but it still stores downloaded dependencies (in
+// GOPATH/pkg/mod) and installed commands (in GOPATH/bin, unless GOBIN
+// is set).
+//
+// Defining a module
+//
+// A module is defined by a tree of Go source files with a go.mod file
+// in the tree's root directory. The directory containing the go.mod file
+// is called the module root. Typically the module root will also
+// correspond to a source code repository root (but in general it need
+// not). The module is the set of all Go packages in the module root and
+// its subdirectories, but excluding subtrees with their own go.mod files.
+//
+// The "module path" is the import path prefix corresponding to the
+// module root. The go.mod file defines the module path and lists the
+// specific versions of other modules that should be used when resolving
+// imports during a build, by giving their module paths and versions.
+//
+// For example, this go.mod declares that the directory containing it
+// is the root of the module with path example.com/m, and it also
+// declares that the module depends on specific versions of
+// golang.org/x/text and gopkg.in/yaml.v2:
+//
+// 	module example.com/m
+//
+// 	require (
+// 		golang.org/x/text v0.3.0
+// 		gopkg.in/yaml.v2 v2.1.0
+// 	)
+//
+// The go.mod file can also specify replacements and excluded versions
+// that only apply when building the module directly; they are ignored
+// when the module is incorporated into a larger build.
+// For more about the go.mod file, see 'go help go.mod'.
+//
+// To start a new module, simply create a go.mod file in the root of the
+// module's directory tree, containing only a module statement.
+// The 'go mod init' command can be used to do this:
+//
+// 	go mod init example.com/m
+//
+// The main module and the build list
+//
+// The "main module" is the module containing the directory where the
+// go command is run. The go command finds the module root by looking
+// for a go.mod in the current directory, or else the current directory's
+// parent directory, or else the parent's parent directory, and so on.
+//
+// The main module's go.mod file defines the precise set of packages
+// available for use by the go command, through require, replace, and
+// exclude statements. Dependency modules, found by following require
+// statements, also contribute to the definition of that set of packages,
+// but only through their go.mod files' require statements: any replace
+// and exclude statements in dependency modules are ignored.
+//
+// The set of modules providing packages to builds is called the
+// "build list". The build list initially contains only the main module.
+// Then the go command adds to the list the exact module versions
+// required by modules already on the list, recursively, until there
+// is nothing left to add to the list. If multiple versions of a
+// particular module are added to the list, then at the end only the
+// latest version (according to semantic version ordering) is kept
+// for use in the build. This algorithm is called minimal version
+// selection.
+//
+// The 'go list' command provides information about the main module
+// and the build list. For example:
+//
+// 	go list -m              # print path of main module
+// 	go list -m all          # print build list
+//
+// Maintaining module requirements
+//
+// The go.mod file is meant to be readable and editable by both
+// programmers and tools. The go command itself automatically updates
+// the go.mod file to maintain a standard formatting and the accuracy
+// of require statements.
+//
+// Any go command that finds an unfamiliar import will look up the
+// module containing that import and add the latest version of that
+// module to go.mod automatically. In most cases, therefore, it suffices
+// to add an import to source code and run 'go build', 'go test', or
+// even 'go list': as part of analyzing the package, the go command will
+// discover and resolve the import and update the go.mod file.
+//
+// Any go command can determine that a module requirement is missing
+// and must be added, even when considering only a single package
+// from the module. On the other hand, determining that a module
+// requirement is no longer necessary and can be deleted requires a
+// full view of all packages in the module, across all possible build
+// configurations (architectures, operating systems, build tags, and
+// so on). The 'go mod tidy' command builds that view and then adds
+// any missing module requirements and removes unnecessary ones.
+//
+// As part of maintaining the require statements in go.mod, the go
+// command tracks which ones provide packages imported directly by the
+// current module and which ones provide packages only used indirectly
+// by other module dependencies. Requirements needed only for indirect
+// uses are marked with a "// indirect" comment in the go.mod file.
+//
+// Because the module graph defines the meaning of import statements,
+// any commands that load packages also use and therefore update go.mod,
+// including go build, go get, go install, go list, go test, go mod graph,
+// go mod tidy, and go mod why.
+//
+// The -mod=readonly flag disables the automatic updating of go.mod:
+// any command that would need to change go.mod fails instead.
+//
+// The 'go get' command updates go.mod to change the module versions
+// used in a build. An upgrade of one module may imply upgrading others,
+// because the new version may require newer versions of its dependencies.
+// See 'go help module-get' for details.
+//
+// Module queries
+//
+// The go command accepts a "module query" in place of a module version
+// on the command line, as in 'go get path@query'.
+//
+// A fully-specified semantic version, such as "v1.2.3",
+// evaluates to that specific version.
+//
+// A semantic version prefix, such as "v1" or "v1.2",
+// evaluates to the latest available tagged version with that prefix.
+//
+// A semantic version comparison, such as "<v1.2.3" or ">=v1.5.6",
+// evaluates to the available tagged version nearest to the comparison target
+// (the latest version for < and <=, the earliest version for > and >=).
+//
+// The string "latest" matches the latest available tagged version.
+//
+// All queries prefer release versions to pre-release versions.
+// For example, "<v1.2.3" will prefer to return "v1.2.2"
+// instead of "v1.2.3-pre1", even though "v1.2.3-pre1" is nearer
+// to the comparison target.
+//
+// Module versions disallowed by exclude statements in the
+// main module's go.mod are considered unavailable and cannot
+// be returned by queries.
+//
+// For example, these commands are all valid:
+//
+// 	go get github.com/gorilla/mux@latest    # same (@latest is default for 'go get')
+// 	go get github.com/gorilla/mux@v1.6.2    # records v1.6.2
+// 	go get github.com/gorilla/mux@v1        # records latest v1.x.x
+// 	go get github.com/gorilla/mux@'<v1.6.2' # records v1.6.1
+//
+// Module compatibility and semantic versioning
+//
+// The go command requires that modules use semantic versions and
+// expects that the versions accurately describe compatibility:
+// it assumes that v1.5.4 is a backwards-compatible replacement
+// for v1.5.3, v1.4.0, and even v1.0.0. More generally the go command
+// expects that packages follow the "import compatibility rule",
+// which says:
+//
+// "If an old package and a new package have the same import path,
+// the new package must be backwards compatible with the old package."
+//
+// Because the go command assumes the import compatibility rule,
+// a module definition can only set the minimum required version
+// of one of its dependencies: it cannot set a maximum or exclude
+// selected versions. Still, the import compatibility rule is not a
+// guarantee: it may be that v1.5.4 is buggy and not a backwards-compatible
+// replacement for v1.5.3. Because of this, the go command never updates
+// from an older version to a newer version of a module unasked.
+//
+// In semantic versioning, changing the major version number indicates
+// a lack of backwards compatibility with earlier versions. To preserve
+// import compatibility, the go command requires that modules with major
+// version v2 or later use a module path with that major version as the
+// final element. For example, version v2.0.0 of example.com/m must
+// instead use module path example.com/m/v2, and packages in that module
+// would use that path as their import path prefix, as in example.com/m/v2/sub/pkg.
+// Including the major version number in the module path and import paths
+// in this way is called "semantic import versioning".
+//
+// As a special case, for historical reasons, module paths beginning with
+// gopkg.in/ continue to use the conventions established on that system:
+// the major version is always present, and it is preceded by a dot
+// instead of a slash: gopkg.in/yaml.v1 and gopkg.in/yaml.v2, not
+// gopkg.in/yaml and gopkg.in/yaml/v2.
+//
+// Module downloading
+//
+// The go command downloads modules from the module proxy named by the
+// GOPROXY environment variable, which may be a file:// URL naming a
+// local directory tree. Fetching modules directly from version control
+// systems is not supported. See 'go help goproxy' for details about the
+// proxy protocol and the layout of the module cache.
+//
+// Downloaded modules are kept, read-only, in the module cache,
+// GOPATH/pkg/mod. Because the cache uses the same layout as a proxy,
+// setting GOPROXY=off allows builds to proceed offline using only
+// modules already in the cache, and a copy of the cache can itself
+// serve as a file:// proxy for other machines.
+//
+// Module authentication using go.sum
+//
+// The go command tries to authenticate every downloaded module,
+// checking that the bits downloaded for a specific module version today
+// match bits downloaded yesterday. This ensures repeatable builds
+// and detects introduction of unexpected changes, malicious or not.
+//
+// In each module's root, alongside go.mod, the go command maintains
+// a file named go.sum containing the cryptographic checksums of the
+// module's dependencies.
+//
+// The form of each line in go.sum is three fields:
+//
+// 	<module> <version>[/go.mod] <hash>
+//
+// Each known module version results in two lines in the go.sum file.
+// The first line gives the hash of the module version's file tree.
+// The second line appends "/go.mod" to the version and gives the hash
+// of only the module version's (possibly synthesized) go.mod file.
+// The go.mod-only hash allows downloading and authenticating a
+// module version's go.mod file, which is needed to compute the
+// dependency graph, without also downloading all the module's source code.
+//
+// The hash begins with an algorithm prefix of the form "h<N>:".
+// The only defined algorithm prefix is "h1:", which uses SHA-256.
+//
+// If a go.sum line does not match the downloaded module or go.mod file,
+// the go command reports a "checksum mismatch" error and stops.
+// The 'go mod verify' command checks that the cached copies of module
+// downloads still match both their recorded checksums and the entries
+// in go.sum.
+//
+// Modules and vendoring
+//
+// When using modules, the go command completely ignores vendor directories.
+//
+// By default, the go command satisfies dependencies by downloading modules
+// from their sources and using those downloaded copies (after verification,
+// as described in the previous section). To allow interoperation with older
+// versions of Go, or to ensure that all files used for a build are stored
+// together in a single file tree, 'go mod vendor' creates a directory named
+// vendor in the root directory of the main module and stores there all the
+// packages from dependency modules that are needed to support builds and
+// tests of packages in the main module.
+//
+// To build using the main module's top-level vendor directory to satisfy
+// dependencies (disabling use of the usual network sources and local
+// caches), use 'go build -mod=vendor'. Note that only the main module's
+// top-level vendor directory is used; vendor directories in other locations
+// are still ignored.
+//
+//
+// Module-aware go get
+//
+// The 'go get' command changes behavior depending on whether the
+// go command is running in module-aware mode or legacy GOPATH mode.
+// This help text, accessible as 'go help module-get' even in legacy GOPATH mode,
+// describes 'go get' as it operates in module-aware mode.
+//
+// Usage: get [-d] [-m] [-u] [-v] [-insecure] [build flags] [packages]
+//
+// Get resolves and adds dependencies to the current development module
+// and then builds and installs them.
+//
+// The first step is to resolve which dependencies to add.
+//
+// For each named package or package pattern, get must decide which version of
+// the corresponding module to use. By default, get chooses the latest tagged
+// release version, such as v0.4.5 or v1.2.3. If there are no tagged release
+// versions, get chooses the latest tagged prerelease version, such as
+// v0.0.1-pre1. If there are no tagged versions at all, get reports an error.
+//
+// This default version selection can be overridden by adding an @version
+// suffix to the package argument, as in 'go get golang.org/x/text@v0.3.0'.
+// The version suffix can also be a version prefix or comparison:
+// 'go get golang.org/x/text@v0.3' and 'go get golang.org/x/text@<v0.3.1'
+// are both valid. See 'go help modules' for the full query syntax.
+//
+// If a module under consideration is already a dependency of the current
+// development module, then get will update the required version.
+// Specifying a version earlier than the current required version is valid
+// only if no other module in the build list requires a later version.
+// The version suffix @none indicates that the dependency should be removed
+// entirely, which is likewise valid only if no other module requires it.
+//
+// Although get defaults to using the latest version of the module containing
+// a named package, it does not use the latest version of that module's
+// dependencies. Instead it prefers to use the specific dependency versions
+// requested by that module. For example, if the latest A requires module
+// B v1.2.3, while B v1.2.4 and v1.3.1 are also available, then 'go get A'
+// will use the latest A but then use B v1.2.3, as requested by A. (If there
+// are competing requirements for a particular module, then 'go get' resolves
+// those requirements by taking the maximum requested version.)
+//
+// The -u flag instructs get to update dependencies to use newer minor or
+// patch releases when available. Continuing the previous example,
+// 'go get -u A' will use the latest A with B v1.3.1 (not B v1.2.3).
+// With no package arguments, 'go get -u' updates every module in the
+// build list to its latest version.
+//
+// In general, adding a new dependency may require upgrading
+// existing dependencies to keep a working build, and 'go get' does
+// this automatically. Similarly, upgrading one dependency may
+// require upgrading other dependencies, and 'go get' does
+// this automatically as well.
+//
+// The -m flag instructs get to stop here, after resolving, upgrading,
+// and downgrading modules and updating go.mod. When using -m,
+// each specified package path must be a module path as well,
+// not the import path of a package below the module root.
+//
+// The -insecure flag is accepted for compatibility with GOPATH mode
+// and has no effect: modules are always fetched from the proxy
+// named by GOPROXY. See 'go help goproxy'.
+//
+// The second step is to download (if needed), build, and install
+// the named packages.
+//
+// If an argument names a module but not a package (because there is no
+// Go source code in the module's root directory), then the install step
+// is skipped for that argument, instead of causing a build failure.
+// For example 'go get golang.org/x/perf' succeeds even though there
+// is no code corresponding to that import path.
+//
+// Package patterns are allowed but are expanded only against the modules
+// already in the build list: a pattern cannot add a new module, and it cannot
+// be combined with an @version suffix.
+//
+// The -d flag instructs get to download the source code needed to build
+// the named packages, including downloading necessary dependencies,
+// but not to build and install them.
+//
+// With no package arguments, 'go get' applies to the main module,
+// and to the Go package in the current directory, if any. In particular,
+// 'go get -u' updates all the dependencies of the main module.
+// With no package arguments and also without -u,
+// 'go get' is not much more than 'go install', and 'go get -d' not much
+// more than 'go list'.
+//
+// For more about modules, see 'go help modules'.
+//
+// For more about specifying packages, see 'go help packages'.
+//
+// This text describes the behavior of get using modules to manage source
+// code and dependencies. If instead the go command is running in GOPATH
+// mode, the details of get's flags and effects change, as does 'go help get'.
+// See 'go help modules' and 'go help gopath-get'.
+//
+// See also: go build, go install, go clean, go mod.
+//
+//
 // Description of package lists
 //
 // Many commands apply to a set of packages:
@@ -1543,10 +2360,30 @@
 // 	-failfast
 // 	    Do not start new tests after the first test failure.
 //
+// 	-fuzz regexp
+// 	    Run the fuzz target matching the regular expression. When specified,
+// 	    the command line argument must match exactly one package, and regexp
+// 	    must match exactly one fuzz target within that package. After tests,
+// 	    fuzz targets' seed corpora, and examples have completed, the fuzz
+// 	    target is run with inputs generated by mutating its seed corpus,
+// 	    guided by coverage instrumentation of the package under test.
+// 	    Inputs that expand coverage are kept in the build cache.
+// 	    An input that makes the fuzz target fail is written to
+// 	    testdata/fuzz/FuzzXxx, where later runs of go test replay it.
+// 	    See 'go help testfunc' for details.
+//
+// 	-fuzztime t
+// 	    Run enough iterations of the fuzz target to take t, specified as
+// 	    a time.Duration (for example, -fuzztime 1h30s), or run exactly
+// 	    n iterations when written as nx (for example, -fuzztime 1000x).
+// 	    The default is to run until an input fails or go test is
+// 	    interrupted.
+//
 // 	-list regexp
-// 	    List tests, benchmarks, or examples matching the regular expression.
-// 	    No tests, benchmarks or examples will be run. This will only
-// 	    list top-level tests. No subtest or subbenchmarks will be shown.
+// 	    List tests, benchmarks, fuzz targets, or examples matching the
+// 	    regular expression. No tests, benchmarks, fuzz targets, or examples
+// 	    will be run. This will only list top-level tests. No subtest or
+// 	    subbenchmarks will be shown.
 //
 // 	-parallel n
 // 	    Allow parallel execution of test functions that call t.Parallel.
@@ -1580,7 +2417,8 @@
 //
 // 	-v
 // 	    Verbose output: log all tests as they are run. Also print all
-// 	    text from Log and Logf calls even if the test succeeds.
+// 	    text from Log and Logf calls, as they are made, even if the
+// 	    test succeeds, and the attributes set with Attr.
 //
 // 	-vet list
 // 	    Configure the invocation of "go vet" during "go test"
@@ -1704,8 +2542,8 @@
 //
 // Description of testing functions
 //
-// The 'go test' command expects to find test, benchmark, and example functions
-// in the "*_test.go" files corresponding to the package under test.
+// The 'go test' command expects to find test, benchmark, fuzz target, and example
+// functions in the "*_test.go" files corresponding to the package under test.
 //
 // A test function is one named TestXXX (where XXX is any alphanumeric string
 // not starting with a lower case letter) and should have the signature,
@@ -1716,6 +2554,16 @@
 //
 // 	func BenchmarkXXX(b *testing.B) { ... }
 //
+// A fuzz target is one named FuzzXXX and should have the signature,
+//
+// 	func FuzzXXX(f *testing.F) { ... }
+//
+// The fuzz target adds seed inputs with f.Add and passes the function to
+// fuzz to f.Fuzz. Without the -fuzz flag, go test calls that function once
+// for each seed input, including those stored in files in the directory
+// testdata/fuzz/FuzzXXX. With -fuzz, go test also calls it with new inputs
+// derived from the seed inputs, as described in 'go help testflag'.
+//
 // An example function is similar to a test function but, instead of using
 // *testing.T to report success or failure, prints output to os.Stdout.
 // If the last comment in the function starts with "Output:" then the output
@@ -1760,4 +2608,74 @@
 // See the documentation of the testing package for more information.
 //
 //
+// The go.mod file
+//
+// A module version is defined by a tree of source files, with a go.mod
+// file in its root. When the go command is run, it looks in the current
+// directory and then successive parent directories to find the go.mod
+// marking the root of the main (current) module.
+//
+// The go.mod file itself is line-oriented, with // comments but
+// no /* */ comments. Each line holds a single directive, made up of a
+// verb followed by arguments. For example:
+//
+// 	module my/thing
+// 	require other/thing v1.0.2
+// 	require new/thing/v2 v2.3.4
+// 	exclude old/thing v1.2.3
+// 	replace bad/thing v1.4.5 => good/thing v1.4.5
+//
+// The verbs are module, to define the module path; require, to require
+// a particular module at a given version or later; exclude, to exclude
+// a particular module version from use; and replace, to replace a module
+// version with a different module version. Exclude and replace apply only
+// in the main module's go.mod and are ignored in dependencies.
+// See 'go help modules' for details.
+//
+// The leading verb can be factored out of adjacent lines to create a block,
+// like in Go imports:
+//
+// 	require (
+// 		new/thing v2.3.4
+// 		old/thing v1.2.3
+// 	)
+//
+// The go.mod file is designed both to be edited directly and to be
+// easily updated by tools. Go commands that find or add requirements
+// rewrite go.mod in a standard format, preserving comments.
+//
+// The go command automatically updates go.mod each time it uses the
+// module graph, to make sure go.mod always accurately reflects reality
+// and is properly formatted. For example, consider this go.mod file:
+//
+// 	module M
+//
+// 	require (
+// 		A v1.0.0
+// 		B v1.0.0
+// 		C v1.0.0
+// 		D v1.2.3
+// 	)
+//
+// 	exclude D v1.2.3
+//
+// The update modifies requirements to respect exclusions, so the
+// requirement on the excluded D v1.2.3 is updated to use the next
+// available version of D, perhaps D v1.2.4 or D v1.3.0.
+//
+// The update removes redundant or misleading requirements.
+// For example, if A v1.0.0 itself requires B v1.2.0 and C v1.0.0,
+// then go.mod's requirement of B v1.0.0 is misleading (superseded by
+// A's need for v1.2.0), and its requirement of C v1.0.0 is redundant
+// (implied by A's need for the same version), so both will be removed.
+// If module M contains packages that directly import packages from B or
+// C, then the requirements will be kept but updated to the actual
+// versions being used.
+//
+// Because the module graph defines the meaning of import statements,
+// any commands that load packages also use and therefore update go.mod,
+// including go build, go get, go install, go list, go test, go mod graph,
+// go mod tidy, and go mod why.
+//
+//
 package main
@@ -23,6 +23,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/google/pprof/profile"
 )
 
 var (
@@ -172,6 +174,8 @@ func TestMain(m *testing.M) {
 	os.Unsetenv("GOBIN")
 	os.Unsetenv("GOPATH")
 	os.Unsetenv("GIT_ALLOW_PROTOCOL")
+	os.Unsetenv("GO111MODULE")
+	os.Unsetenv("GOPROXY")
 	if home, ccacheDir := os.Getenv("HOME"), os.Getenv("CCACHE_DIR"); home != "" && ccacheDir == "" {
 		// On some systems the default C compiler is ccache.
 		// Setting HOME to a non-existent directory will break
@@ -2397,6 +2401,133 @@ func TestCoverageRuns(t *testing.T) {
 	checkCoverage(tg, data)
 }
 
+func TestBuildCover(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/p/p.go", `package p
+
+func Sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+`)
+	tg.tempFile("src/prog/main.go", `package main
+
+import (
+	"os"
+	"p"
+	"strconv"
+)
+
+func main() {
+	n, _ := strconv.Atoi(os.Args[1])
+	if p.Sign(n) < 0 {
+		os.Exit(0)
+	}
+}
+`)
+	tg.tempDir("covdata")
+	tg.setenv("GOPATH", tg.path("."))
+	prog := tg.path("prog" + exeSuffix)
+	tg.run("build", "-cover", "-coverpkg=p,prog", "-o", prog, "prog")
+
+	// The program writes its coverage data when main returns
+	// and when it calls os.Exit.
+	for _, arg := range []string{"1", "-1"} {
+		cmd := exec.Command(prog, arg)
+		cmd.Env = append(os.Environ(), "GOCOVERDIR="+tg.path("covdata"))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("running instrumented program: %v\n%s", err, out)
+		}
+	}
+	out, err := exec.Command(prog, "1").CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "GOCOVERDIR not set") {
+		t.Errorf("instrumented program without GOCOVERDIR: %v\n%s", err, out)
+	}
+
+	tg.run("tool", "covdata", "textfmt", "-i="+tg.path("covdata"))
+	tg.grepStdout(`^mode: set$`, "covdata textfmt did not report set mode")
+	tg.grepStdout(`^p/p\.go:4\.11,6\.3 1 1$`, "covdata textfmt did not report coverage of run with negative argument")
+	tg.grepStdout(`^p/p\.go:7\.2,7\.10 1 1$`, "covdata textfmt did not report coverage of run with positive argument")
+	tg.grepStdout(`^prog/main\.go:`, "covdata textfmt did not report coverage of main package")
+}
+
+// writeCallProfile writes to file a CPU profile with
+// a single sample, of a call from caller at line to callee.
+func writeCallProfile(t *testing.T, file, caller string, line int64, callee string) {
+	from := &profile.Function{ID: 1, Name: caller}
+	to := &profile.Function{ID: 2, Name: callee}
+	loc := []*profile.Location{
+		{ID: 1, Line: []profile.Line{{Function: to, Line: 1}}},
+		{ID: 2, Line: []profile.Line{{Function: from, Line: line}}},
+	}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		Sample:     []*profile.Sample{{Location: loc, Value: []int64{1e9}}},
+		Location:   loc,
+		Function:   []*profile.Function{from, to},
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := p.Write(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildPGO(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/p/p.go", `package p
+
+func Hot(x int) int {
+	return big(x)
+}
+
+func big(x int) int {
+	x = x*3 + 1
+	x = x ^ (x >> 3)
+	x = x*5 + 7
+	x = x ^ (x >> 5)
+	x = x*7 + 11
+	x = x ^ (x >> 7)
+	x = x*11 + 13
+	x = x ^ (x >> 11)
+	x = x*13 + 17
+	x = x ^ (x >> 13)
+	x = x*17 + 19
+	x = x ^ (x >> 17)
+	x = x*19 + 23
+	x = x ^ (x >> 19)
+	return x
+}
+`)
+	tg.setenv("GOPATH", tg.path("."))
+	prof := tg.path("cpu.pprof")
+
+	writeCallProfile(t, prof, "p.Hot", 4, "p.big")
+	tg.run("build", "-gcflags=-m", "-pgo="+prof, "p")
+	tg.grepStderr(`p.go:4:\d+: inlining call to big`, "big not inlined at hot call site")
+
+	tg.run("build", "-gcflags=-m", "p")
+	tg.grepStderrNot(`inlining call to big`, "big inlined without -pgo")
+
+	// A profile in which the call is not hot, in the same file,
+	// must not reuse the cached result of the first build.
+	writeCallProfile(t, prof, "p.Hot", 4, "p.other")
+	tg.run("build", "-gcflags=-m", "-pgo="+prof, "p")
+	tg.grepStderrNot(`inlining call to big`, "big inlined with new profile")
+
+	tg.runFail("build", "-pgo="+tg.path("missing.pprof"), "p")
+	tg.grepStderr(`-pgo: .*missing\.pprof`, "missing profile not reported")
+}
+
 // Check that coverage analysis uses set mode.
 // Also check that coverage profiles merge correctly.
 func TestCoverageUsesSetMode(t *testing.T) {
@@ -3147,6 +3278,50 @@ func TestGoVetWithFlagsOff(t *testing.T) {
 	tg.run("vet", "-printf=false", "vetpkg")
 }
 
+func TestGoVetWithVetTool(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/checker/main.go", `package main
+
+import (
+	"go/analysis/multichecker"
+	"go/analysis/passes/printf"
+)
+
+func main() { multichecker.Main(printf.Analyzer) }
+`)
+	tg.tempFile("src/w/w.go", `package w
+
+import "fmt"
+
+func Logf(format string, args ...interface{}) { fmt.Printf(format, args...) }
+`)
+	tg.tempFile("src/p/p.go", `package p
+
+import "w"
+
+func F() { w.Logf("%d", "x") }
+`)
+	tg.setenv("GOPATH", tg.path("."))
+	checker := tg.path("checker" + exeSuffix)
+	tg.run("build", "-o", checker, "checker")
+
+	// The vet tool learns from package w that w.Logf is
+	// a printf wrapper, and checks the call in package p.
+	tg.runFail("vet", "-vettool="+checker, "p")
+	tg.grepStderr(`p\.go:5:.*Logf format %d has arg "x" of wrong type string`, "go vet -vettool did not check call to printf wrapper in another package")
+
+	// The tool's flags are accepted by go vet.
+	tg.run("vet", "-vettool="+checker, "-printf=false", "p")
+	tg.runFail("vet", "-vettool="+checker, "-printf.funcs=Warnf", "p")
+	tg.grepStderr("Logf format", "go vet -vettool with tool flag did not run printf check")
+
+	// Flags unknown to the tool are rejected.
+	tg.runFail("vet", "-vettool="+checker, "-bogus", "p")
+	tg.grepStderr(`flag "-bogus" not defined`, "go vet -vettool accepted unknown flag")
+}
+
 // Issue 9767, 19769.
 func TestGoGetDotSlashDownload(t *testing.T) {
 	testenv.MustHaveExternalNetwork(t)
@@ -4649,15 +4824,15 @@ func TestTestRegexps(t *testing.T) {
 	//	BenchmarkXX is run but only with N=1, once
 	//	BenchmarkX/Y is run in full, twice
 	want := `=== RUN   TestX
-=== RUN   TestX/Y
 	x_test.go:6: LOG: X running
-    	x_test.go:8: LOG: Y running
+=== RUN   TestX/Y
+	x_test.go:8: LOG: Y running
 === RUN   TestXX
 	z_test.go:10: LOG: XX running
 === RUN   TestX
-=== RUN   TestX/Y
 	x_test.go:6: LOG: X running
-    	x_test.go:8: LOG: Y running
+=== RUN   TestX/Y
+	x_test.go:8: LOG: Y running
 === RUN   TestXX
 	z_test.go:10: LOG: XX running
 --- BENCH: BenchmarkX/Y
@@ -4699,10 +4874,134 @@ func TestListTests(t *testing.T) {
 
 	t.Run("Test", testWith("Test", "TestSimple"))
 	t.Run("Bench", testWith("Benchmark", "BenchmarkSimple"))
+	t.Run("Fuzz", testWith("Fuzz", "FuzzSimple"))
 	t.Run("Example1", testWith("Example", "ExampleSimple"))
 	t.Run("Example2", testWith("Example", "ExampleWithEmptyOutput"))
 }
 
+func TestGoTestFuzz(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.makeTempdir()
+	tg.setenv("GOPATH", tg.tempdir)
+	tg.setenv("GOCACHE", tg.path("cache"))
+	tg.tempFile("src/fz/fz.go", `package fz
+
+func Check(s string, n int) {
+	if len(s) > 3 && s[0] == 'F' {
+		if s[1] == 'U' {
+			if s[2] == 'Z' {
+				if n > 10 {
+					panic("boom")
+				}
+			}
+		}
+	}
+}
+`)
+	tg.tempFile("src/fz/fz_test.go", `package fz
+
+import "testing"
+
+func FuzzCheck(f *testing.F) {
+	f.Add("hello", 1)
+	f.Fuzz(func(t *testing.T, s string, n int) {
+		Check(s, n)
+	})
+}
+`)
+	tg.cd(tg.path("src/fz"))
+
+	// Without -fuzz, only the seed corpus is run.
+	tg.run("test", "-v", ".")
+	tg.grepStdout(`--- PASS: FuzzCheck/seed#0`, "did not run seed corpus entry")
+
+	tg.runFail("test", "-fuzz=FuzzCheck", ".", "errors")
+	tg.grepStderr("cannot use -fuzz flag with multiple packages", "did not reject -fuzz with multiple packages")
+
+	// A short run finds nothing.
+	tg.run("test", "-fuzz=FuzzCheck", "-fuzztime=10x", ".")
+	tg.grepStdout(`fuzz: elapsed: .*execs: 10 `, "did not stop after 10 inputs")
+	tg.mustNotExist(tg.path("src/fz/testdata/fuzz"))
+
+	// The coverage of the nested conditions guides the fuzzer to the panic.
+	tg.runFail("test", "-fuzz=FuzzCheck", "-fuzztime=60s", ".")
+	tg.grepStdout(`panic: boom`, "did not report panic")
+	tg.grepStdout(`Failing input written to testdata/fuzz/FuzzCheck/`, "did not write failing input")
+	files, err := ioutil.ReadDir(tg.path("src/fz/testdata/fuzz/FuzzCheck"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("found %d files in testdata/fuzz/FuzzCheck, want 1", len(files))
+	}
+	name := files[0].Name()
+	tg.grepStdout(`go test -run=FuzzCheck/`+name, "did not print command to re-run failing input")
+
+	// Without -fuzz, the failing input is now part of the seed corpus.
+	tg.runFail("test", "-v", "-run=FuzzCheck/"+name, ".")
+	tg.grepStdout(`=== RUN   FuzzCheck/`+name, "did not replay failing input")
+	tg.grepStdout(`panic: boom`, "failing input did not fail")
+	tg.grepStdoutNot(`seed#0`, "-run did not select failing input alone")
+}
+
+func TestGoTestFuzzCrash(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.makeTempdir()
+	tg.setenv("GOPATH", tg.tempdir)
+	tg.setenv("GOCACHE", tg.path("cache"))
+	tg.tempFile("src/fz/fz.go", `package fz
+
+import "os"
+
+func Check(s string) {
+	if len(s) > 2 && s[0] == 'F' {
+		if s[1] == 'U' {
+			if s[2] == 'Z' {
+				os.Exit(3)
+			}
+		}
+	}
+}
+`)
+	tg.tempFile("src/fz/fz_test.go", `package fz
+
+import "testing"
+
+func FuzzExit(f *testing.F) {
+	f.Add("hello")
+	f.Fuzz(func(t *testing.T, s string) {
+		Check(s)
+	})
+}
+`)
+	tg.cd(tg.path("src/fz"))
+
+	// The input that makes the fuzzing process exit is still recorded.
+	tg.runFail("test", "-fuzz=FuzzExit", "-fuzztime=60s", ".")
+	tg.grepStdout(`fuzzing process terminated unexpectedly`, "did not report unexpected exit")
+	tg.grepStdout(`Failing input written to testdata/fuzz/FuzzExit/`, "did not write crashing input")
+	files, err := ioutil.ReadDir(tg.path("src/fz/testdata/fuzz/FuzzExit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("found %d files in testdata/fuzz/FuzzExit, want 1", len(files))
+	}
+	data, err := ioutil.ReadFile(tg.path("src/fz/testdata/fuzz/FuzzExit/" + files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `string("FUZ`) {
+		t.Errorf("crashing input is %q, want a string beginning with FUZ", data)
+	}
+
+	// Replaying the input exits the test binary.
+	tg.runFail("test", "-run=FuzzExit/"+files[0].Name(), ".")
+	tg.grepStdout(`exit status 3`, "failing input did not exit")
+}
+
 func TestBuildmodePIE(t *testing.T) {
 	if runtime.Compiler == "gccgo" {
 		t.Skipf("skipping test because buildmode=pie is not supported on gccgo")
@@ -4975,6 +5274,43 @@ func TestCacheCoverage(t *testing.T) {
 	tg.run("test", "-cover", "math", "strings")
 }
 
+func TestGoCacheProg(t *testing.T) {
+	if strings.Contains(os.Getenv("GODEBUG"), "gocacheverify") {
+		t.Skip("GODEBUG gocacheverify")
+	}
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.makeTempdir()
+	prog := tg.path("cacheprog" + exeSuffix)
+	tg.run("build", "-o", prog, "testdata/cacheprog.go")
+	tg.setenv("GOPATH", filepath.Join(tg.pwd(), "testdata"))
+	tg.setenv("GOCACHEPROG", prog+" "+tg.path("shared"))
+
+	// complex/w is a trivial non-main package.
+	tg.setenv("GOCACHE", tg.path("c1"))
+	tg.run("build", "-x", "complex/w")
+	tg.grepStderr(`[\\/]compile|gccgo`, "did not run compiler")
+	files, err := filepath.Glob(tg.path("shared/a-*"))
+	tg.must(err)
+	if len(files) == 0 {
+		t.Fatal("no entries written to GOCACHEPROG helper")
+	}
+
+	// An empty local cache gets the package from the helper.
+	tg.setenv("GOCACHE", tg.path("c2"))
+	tg.run("build", "-x", "complex/w")
+	tg.grepStderrNot(`[\\/]compile|gccgo`, "ran compiler with entry in GOCACHEPROG helper")
+
+	// And it is now in the local cache too.
+	tg.setenv("GOCACHEPROG", "")
+	tg.run("build", "-x", "complex/w")
+	tg.grepStderrNot(`[\\/]compile|gccgo`, "ran compiler with entry copied from GOCACHEPROG helper")
+
+	tg.setenv("GOCACHEPROG", tg.path("nonexistent"))
+	tg.runFail("build", "complex/w")
+	tg.grepStderr("starting \\$GOCACHEPROG", "did not report missing GOCACHEPROG helper")
+}
+
 func TestIssue22588(t *testing.T) {
 	// Don't get confused by stderr coming from tools.
 	tg := testgo(t)
@@ -5408,6 +5744,50 @@ func TestGoTestJSON(t *testing.T) {
 	tg.grepStdout(`\{"Action":"pass","Package":"errors"\}`, "did not see final pass")
 }
 
+func TestGoTestJSONEvents(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.makeTempdir()
+	tg.setenv("GOCACHE", tg.tempdir)
+	tg.tempFile("src/bad/bad.go", "package bad\n\nfunc F() int { return undefined }\n")
+	tg.tempFile("src/bad/bad_test.go", "package bad\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F() }\n")
+	tg.tempFile("src/good/good_test.go", `package good
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAttr(t *testing.T) {
+	t.Attr("issue", "1234")
+	fmt.Println("=== RUN   TestFake")
+}
+
+func BenchmarkAlloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = fmt.Sprint(i)
+	}
+}
+`)
+	tg.setenv("GOPATH", tg.path("."))
+
+	tg.runFail("test", "-json", "bad")
+	tg.grepStdout(`"Action":"build-output","ImportPath":"bad","Output":"# bad\\n"`, "did not see build output")
+	tg.grepStdout(`"Action":"build-error","ImportPath":"bad","File":"[^"]*bad.go","Line":3,"Column":\d+,"Output":"[^"]*undefined: undefined`, "did not see build error")
+	tg.grepStdout(`"Action":"build-fail","ImportPath":"bad"`, "did not see build-fail")
+	tg.grepStdout(`"Action":"fail","Package":"bad",.*"FailedBuild":"bad"`, "did not see fail with FailedBuild")
+	tg.grepStdoutNot(`^# bad`, "saw build output outside JSON events")
+
+	tg.run("test", "-json", "-bench=.", "-benchtime=1ms", "good")
+	tg.grepStdout(`"Action":"attr","Package":"good","Test":"TestAttr","Key":"issue","Value":"1234"`, "did not see attr")
+	tg.grepStdout(`"Action":"output","Package":"good","Test":"TestAttr","Output":"=== RUN   TestFake\\n"`, "did not see fake framing line as output")
+	tg.grepStdoutNot(`"Action":"run","Package":"good","Test":"TestFake"`, "saw fake framing line as event")
+	tg.grepStdout(`"Action":"bench","Package":"good","Test":"BenchmarkAlloc[^"]*","Iterations":\d+,"Metrics":\{"B/op":\d+,"allocs/op":\d+,"ns/op":[\d.]+\}`, "did not see bench result")
+	tg.grepStdoutNot("\x16", "saw framing marker in output")
+}
+
 func TestFailFast(t *testing.T) {
 	tg := testgo(t)
 	defer tg.cleanup()
@@ -5498,3 +5878,54 @@ func init() {}
 	tg.run("build", "-o", tg.path("a.exe"), "a")
 	tg.run("test", "a")
 }
+
+func TestEmbed(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.makeTempdir()
+	tg.setenv("GOPATH", tg.path("."))
+	tg.setenv("GOCACHE", tg.path("cache"))
+	tg.tempFile("src/p/p.go", `package main
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+//go:embed x.txt
+var x string
+
+func main() { fmt.Println(x) }
+`)
+	tg.tempFile("src/p/x.txt", "hello")
+	tg.run("run", tg.path("src/p/p.go"))
+	tg.grepStdout("^hello$", "did not print embedded file")
+	tg.run("list", "-f={{.EmbedPatterns}} {{.EmbedFiles}}", "p")
+	tg.grepStdout(`^\[x\.txt\] \[x\.txt\]$`, "go list did not report embedded files")
+
+	// Changing an embedded file must invalidate the cached build.
+	tg.tempFile("src/p/x.txt", "goodbye")
+	tg.run("run", tg.path("src/p/p.go"))
+	tg.grepStdout("^goodbye$", "did not rebuild after embedded file changed")
+
+	tg.tempFile("src/q/q.go", `package q
+
+import _ "embed"
+
+//go:embed missing*
+var s string
+`)
+	tg.runFail("build", "q")
+	tg.grepStderr(`q\.go:5:12: pattern missing\*: no matching files found`, "did not report pattern with no matches")
+
+	tg.tempFile("src/q/q.go", `package q
+
+import _ "embed"
+
+//go:embed ../x.txt
+var s string
+`)
+	tg.runFail("build", "q")
+	tg.grepStderr(`pattern \.\./x\.txt: invalid pattern syntax`, "did not report invalid pattern")
+}
@@ -28,11 +28,13 @@ type ActionID [HashSize]byte
 // An OutputID is a cache output key, the hash of an output of a computation.
 type OutputID [HashSize]byte
 
-// A Cache is a package cache, backed by a file system directory tree.
+// A Cache is a package cache, backed by a file system directory tree
+// and optionally by a GOCACHEPROG helper program.
 type Cache struct {
-	dir string
-	log *os.File
-	now func() time.Time
+	dir  string
+	log  *os.File
+	now  func() time.Time
+	prog *progCache // GOCACHEPROG helper, or nil
 }
 
 // Open opens and returns the cache in the given directory.
@@ -73,6 +75,15 @@ func Open(dir string) (*Cache, error) {
 	return c, nil
 }
 
+// Close closes the connection to the GOCACHEPROG helper, if any,
+// waiting for it to exit.
+func (c *Cache) Close() error {
+	if c.prog == nil {
+		return nil
+	}
+	return c.prog.close()
+}
+
 // fileName returns the name of the file corresponding to the given id.
 func (c *Cache) fileName(id [HashSize]byte, key string) string {
 	return filepath.Join(c.dir, fmt.Sprintf("%02x", id[0]), fmt.Sprintf("%x", id)+"-"+key)
@@ -123,11 +134,17 @@ func initEnv() {
 // returning the corresponding output ID and file size, if any.
 // Note that finding an output ID does not guarantee that the
 // saved file for that output ID is still available.
+// Entries missing from the cache directory are looked up
+// with the GOCACHEPROG helper, if any.
 func (c *Cache) Get(id ActionID) (Entry, error) {
 	if verify {
 		return Entry{}, errMissing
 	}
-	return c.get(id)
+	entry, err := c.get(id)
+	if err != nil && c.prog != nil {
+		return c.getProg(id)
+	}
+	return entry, err
 }
 
 type Entry struct {
@@ -365,7 +382,16 @@ func (c *Cache) put(id ActionID, file io.ReadSeeker, allowVerify bool) (OutputID
 	}
 
 	// Add to cache index.
-	return out, size, c.putIndexEntry(id, out, size, allowVerify)
+	if err := c.putIndexEntry(id, out, size, allowVerify); err != nil {
+		return out, size, err
+	}
+
+	if c.prog != nil {
+		if err := c.prog.put(id, out, size, file); err != nil {
+			return out, size, err
+		}
+	}
+	return out, size, nil
 }
 
 // PutBytes stores the given bytes in the cache as the output for the action ID.
@@ -51,6 +51,18 @@ func initDefaultCache() {
 	if err != nil {
 		base.Fatalf("initializing cache in $GOCACHE: %s", err)
 	}
+	if prog := os.Getenv("GOCACHEPROG"); prog != "" {
+		p, err := startProg(prog)
+		if err != nil {
+			base.Fatalf("starting $GOCACHEPROG: %s", err)
+		}
+		c.prog = p
+		base.AtExit(func() {
+			if err := c.Close(); err != nil {
+				base.Errorf("%s", err)
+			}
+		})
+	}
 	defaultCache = c
 }
 
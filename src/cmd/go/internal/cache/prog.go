@@ -0,0 +1,341 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the go command's side of the GOCACHEPROG protocol,
+// by which the build cache delegates to a helper program, such as one
+// implementing a cache shared by many machines.
+//
+// The go command starts the helper, running the command line in
+// $GOCACHEPROG, and sends it requests on its standard input,
+// reading responses from its standard output. Both are streams of
+// JSON values: ProgRequest and ProgResponse, one per line.
+// The helper first writes a response with ID 0, listing in
+// KnownCommands the commands it supports. It may answer requests in
+// any order, since each response carries the ID of its request.
+//
+// A "get" request asks for the entry for ActionID. The helper
+// responds with Miss set, or with the OutputID, Size and Time of
+// the entry, and in DiskPath the absolute name of a file holding
+// the output, which the go command copies into its local cache.
+//
+// A "put" request stores an entry for ActionID, of an output with
+// OutputID and BodySize. If BodySize is not zero, the request is
+// followed by the contents of the output, as a JSON string holding
+// its standard base64 encoding, on a line of its own.
+//
+// A "close" request asks the helper to finish any pending work.
+// After responding to it, the helper should exit when its standard
+// input is closed.
+//
+// The local cache directory is consulted before the helper, and
+// stores everything the go command gets from or puts to the helper.
+
+// A ProgCmd is a command in a ProgRequest.
+type ProgCmd string
+
+const (
+	ProgGet   ProgCmd = "get"
+	ProgPut   ProgCmd = "put"
+	ProgClose ProgCmd = "close"
+)
+
+// A ProgRequest is a request sent to a GOCACHEPROG helper.
+type ProgRequest struct {
+	// ID identifies the request, so that the response can be
+	// matched to it. IDs are positive and never reused.
+	ID int64
+
+	Command ProgCmd
+
+	// ActionID is the cache key of a "get" or "put" request.
+	ActionID []byte `json:",omitempty"`
+
+	// OutputID is the hash of the output of a "put" request.
+	OutputID []byte `json:",omitempty"`
+
+	// BodySize is the size of the output of a "put" request.
+	BodySize int64 `json:",omitempty"`
+}
+
+// A ProgResponse is a response from a GOCACHEPROG helper.
+type ProgResponse struct {
+	ID  int64  // ID of the request; 0 for the initial response
+	Err string `json:",omitempty"` // if not empty, the request failed
+
+	// KnownCommands lists the commands the helper supports.
+	// It is set only in the initial response.
+	KnownCommands []ProgCmd `json:",omitempty"`
+
+	// For "get" requests, Miss reports that there is no entry
+	// for the action ID; otherwise the other fields describe it.
+	Miss     bool       `json:",omitempty"`
+	OutputID []byte     `json:",omitempty"`
+	Size     int64      `json:",omitempty"`
+	Time     *time.Time `json:",omitempty"`
+	DiskPath string     `json:",omitempty"`
+}
+
+// A progCache is a connection to a running GOCACHEPROG helper.
+type progCache struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	can   map[ProgCmd]bool
+
+	writeMu  sync.Mutex // serializes writes of requests
+	w        *bufio.Writer
+	writeErr error // first write error, failing all later requests
+
+	mu       sync.Mutex // guards the following
+	nextID   int64
+	inFlight map[int64]chan<- *ProgResponse
+	readErr  error // error that ended readLoop, failing all later requests
+
+	readDone chan bool // closed when readLoop returns
+}
+
+// startProg starts the helper program run by the command line prog,
+// and reads its initial response.
+func startProg(prog string) (*progCache, error) {
+	args := strings.Fields(prog)
+	if len(args) == 0 {
+		return nil, errors.New("no command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p, err := newProgCache(stdin, stdout)
+	if err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	p.cmd = cmd
+	return p, nil
+}
+
+// newProgCache reads the initial response of a helper from stdout
+// and returns a progCache sending it requests on stdin.
+func newProgCache(stdin io.WriteCloser, stdout io.Reader) (*progCache, error) {
+	dec := json.NewDecoder(bufio.NewReader(stdout))
+	var res ProgResponse
+	if err := dec.Decode(&res); err != nil || res.ID != 0 {
+		if err == nil {
+			err = fmt.Errorf("initial response has ID %d", res.ID)
+		}
+		return nil, fmt.Errorf("reading initial response: %v", err)
+	}
+	p := &progCache{
+		stdin:    stdin,
+		can:      make(map[ProgCmd]bool),
+		w:        bufio.NewWriter(stdin),
+		inFlight: make(map[int64]chan<- *ProgResponse),
+		readDone: make(chan bool),
+	}
+	for _, c := range res.KnownCommands {
+		p.can[c] = true
+	}
+	go p.readLoop(dec)
+	return p, nil
+}
+
+// readLoop reads responses and hands them to the requests waiting for them.
+func (p *progCache) readLoop(dec *json.Decoder) {
+	defer close(p.readDone)
+	for {
+		res := new(ProgResponse)
+		err := dec.Decode(res)
+		p.mu.Lock()
+		if err == nil {
+			ch, ok := p.inFlight[res.ID]
+			if ok {
+				delete(p.inFlight, res.ID)
+				p.mu.Unlock()
+				ch <- res
+				continue
+			}
+			err = fmt.Errorf("response to unknown request %d", res.ID)
+		} else if err == io.EOF {
+			err = errors.New("helper exited")
+		}
+		p.readErr = fmt.Errorf("GOCACHEPROG: %v", err)
+		for id, ch := range p.inFlight {
+			delete(p.inFlight, id)
+			close(ch)
+		}
+		p.mu.Unlock()
+		return
+	}
+}
+
+// send sends the request, followed by the contents of body if
+// req.BodySize is not zero, and waits for the response.
+func (p *progCache) send(req *ProgRequest, body io.Reader) (*ProgResponse, error) {
+	ch := make(chan *ProgResponse, 1)
+	p.mu.Lock()
+	if p.readErr != nil {
+		p.mu.Unlock()
+		return nil, p.readErr
+	}
+	p.nextID++
+	req.ID = p.nextID
+	p.inFlight[req.ID] = ch
+	p.mu.Unlock()
+
+	if err := p.write(req, body); err != nil {
+		p.mu.Lock()
+		delete(p.inFlight, req.ID)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("GOCACHEPROG: %v", err)
+	}
+	res, ok := <-ch
+	if !ok {
+		p.mu.Lock()
+		err := p.readErr
+		p.mu.Unlock()
+		return nil, err
+	}
+	if res.Err != "" {
+		return nil, fmt.Errorf("GOCACHEPROG: %s %x: %s", req.Command, req.ActionID, res.Err)
+	}
+	return res, nil
+}
+
+// write writes the request, followed by its body, to the helper.
+// The body is streamed after the request, so a failure part way
+// through leaves the helper with a malformed request. After any
+// error, write therefore fails every later request too.
+func (p *progCache) write(req *ProgRequest, body io.Reader) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if p.writeErr != nil {
+		return p.writeErr
+	}
+	if err := p.writeRequest(req, body); err != nil {
+		p.writeErr = fmt.Errorf("connection broken by earlier error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (p *progCache) writeRequest(req *ProgRequest, body io.Reader) error {
+	if err := json.NewEncoder(p.w).Encode(req); err != nil {
+		return err
+	}
+	if req.BodySize > 0 {
+		p.w.WriteByte('"')
+		enc := base64.NewEncoder(base64.StdEncoding, p.w)
+		n, err := io.Copy(enc, body)
+		if err != nil {
+			return err
+		}
+		if n != req.BodySize {
+			return fmt.Errorf("body is %d bytes, want %d", n, req.BodySize)
+		}
+		enc.Close()
+		p.w.WriteString("\"\n")
+	}
+	return p.w.Flush()
+}
+
+// get looks up the action ID with the helper.
+// It returns errMissing if the helper has no entry for it.
+func (p *progCache) get(id ActionID) (*ProgResponse, error) {
+	if !p.can[ProgGet] {
+		return nil, errMissing
+	}
+	res, err := p.send(&ProgRequest{Command: ProgGet, ActionID: id[:]}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.Miss {
+		return nil, errMissing
+	}
+	if len(res.OutputID) != HashSize || res.DiskPath == "" {
+		return nil, fmt.Errorf("GOCACHEPROG: get %x: incomplete response", id)
+	}
+	return res, nil
+}
+
+// put stores the output of the action ID, read from file, with the helper.
+func (p *progCache) put(id ActionID, out OutputID, size int64, file io.ReadSeeker) error {
+	if !p.can[ProgPut] {
+		return nil
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return err
+	}
+	_, err := p.send(&ProgRequest{Command: ProgPut, ActionID: id[:], OutputID: out[:], BodySize: size}, file)
+	return err
+}
+
+// close asks the helper to finish and waits for it to exit.
+func (p *progCache) close() error {
+	var err error
+	if p.can[ProgClose] {
+		_, err = p.send(&ProgRequest{Command: ProgClose}, nil)
+	}
+	p.stdin.Close()
+	<-p.readDone
+	if werr := p.cmd.Wait(); err == nil && werr != nil {
+		err = fmt.Errorf("GOCACHEPROG: %v", werr)
+	}
+	return err
+}
+
+// getProg looks up the action ID with the helper and, if it has an
+// entry, copies the output into the local cache before returning it.
+// It returns errMissing only if the helper reports a miss; any other
+// failure is returned as is.
+func (c *Cache) getProg(id ActionID) (Entry, error) {
+	res, err := c.prog.get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	var out OutputID
+	copy(out[:], res.OutputID)
+	f, err := os.Open(res.DiskPath)
+	if err != nil {
+		return Entry{}, fmt.Errorf("GOCACHEPROG: get %x: %v", id, err)
+	}
+	defer f.Close()
+	// copyFile checks that the contents match the output ID.
+	if err := c.copyFile(f, out, res.Size); err != nil {
+		return Entry{}, fmt.Errorf("GOCACHEPROG: get %x: %v", id, err)
+	}
+	if err := c.putIndexEntry(id, out, res.Size, false); err != nil {
+		return Entry{}, err
+	}
+	tm := c.now()
+	if res.Time != nil {
+		tm = *res.Time
+	}
+	return Entry{out, res.Size, tm}, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// startTestProg returns a progCache connected to a fake helper,
+// running in a goroutine, that answers each request with the
+// response returned by respond.
+func startTestProg(t *testing.T, respond func(*ProgRequest) *ProgResponse) *progCache {
+	reqR, reqW := io.Pipe()
+	resR, resW := io.Pipe()
+	go func() {
+		enc := json.NewEncoder(resW)
+		enc.Encode(&ProgResponse{KnownCommands: []ProgCmd{ProgGet, ProgPut}})
+		dec := json.NewDecoder(reqR)
+		for {
+			req := new(ProgRequest)
+			err := dec.Decode(req)
+			if err == nil && req.BodySize > 0 {
+				var body []byte
+				err = dec.Decode(&body)
+			}
+			if err != nil {
+				resW.CloseWithError(err)
+				return
+			}
+			res := respond(req)
+			res.ID = req.ID
+			enc.Encode(res)
+		}
+	}()
+	p, err := newProgCache(reqW, resR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProgGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cachetest-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := []byte("output data")
+	out := sha256.Sum256(data)
+	file := filepath.Join(dir, "output")
+	if err := ioutil.WriteFile(file, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	cdir := filepath.Join(dir, "cache")
+	if err := os.Mkdir(cdir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(cdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.prog = startTestProg(t, func(req *ProgRequest) *ProgResponse {
+		switch req.ActionID[0] {
+		case 1:
+			return &ProgResponse{Miss: true}
+		case 2:
+			return &ProgResponse{Err: "helper failed"}
+		case 3:
+			return &ProgResponse{OutputID: out[:], Size: int64(len(data)), DiskPath: filepath.Join(dir, "missing")}
+		case 4:
+			return &ProgResponse{OutputID: out[:]}
+		}
+		return &ProgResponse{OutputID: out[:], Size: int64(len(data)), DiskPath: file}
+	})
+
+	for _, tt := range []struct {
+		id   int
+		want string // "" for a hit, "miss" for errMissing, or part of the error
+	}{
+		{1, "miss"},
+		{2, "helper failed"},
+		{3, "missing"},
+		{4, "incomplete response"},
+		{5, ""},
+	} {
+		_, err := c.Get(ActionID{byte(tt.id)})
+		switch {
+		case tt.want == "":
+			if err != nil {
+				t.Errorf("Get(%d): %v", tt.id, err)
+			}
+		case tt.want == "miss":
+			if err != errMissing {
+				t.Errorf("Get(%d) = %v, want errMissing", tt.id, err)
+			}
+		case err == nil || err == errMissing || !strings.Contains(err.Error(), tt.want):
+			t.Errorf("Get(%d) = %v, want error containing %q", tt.id, err, tt.want)
+		}
+	}
+
+	// The hit is now in the local cache.
+	entry, err := c.get(ActionID{5})
+	if err != nil || entry.OutputID != out || entry.Size != int64(len(data)) {
+		t.Errorf("local get after hit = %+v, %v; want output %x", entry, err, out)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestProgWriteErrorBreaksConnection(t *testing.T) {
+	var requests int32
+	p := startTestProg(t, func(req *ProgRequest) *ProgResponse {
+		atomic.AddInt32(&requests, 1)
+		return &ProgResponse{Miss: true}
+	})
+
+	body := io.MultiReader(strings.NewReader("partial"), errReader{})
+	if err := p.put(ActionID{1}, OutputID{}, 100, readSeeker{body}); err == nil || !strings.Contains(err.Error(), "read failed") {
+		t.Fatalf("put with failing body = %v, want read failure", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.get(ActionID{2}); err == nil || err == errMissing || !strings.Contains(err.Error(), "broken") {
+			t.Errorf("get after failed put = %v, want broken connection", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("helper answered %d requests, want 0", n)
+	}
+}
+
+// readSeeker adds a no-op Seek method to an io.Reader.
+type readSeeker struct {
+	io.Reader
+}
+
+func (readSeeker) Seek(int64, int) (int64, error) { return 0, nil }
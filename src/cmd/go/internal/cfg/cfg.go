@@ -21,12 +21,17 @@ var (
 	BuildA                 bool   // -a flag
 	BuildBuildmode         string // -buildmode flag
 	BuildContext           = build.Default
+	BuildCover             bool               // -cover flag
+	BuildCoverMode         string             // -covermode flag
+	BuildCoverPkg          []string           // -coverpkg flag
 	BuildI                 bool               // -i flag
 	BuildLinkshared        bool               // -linkshared flag
+	BuildMod               string             // -mod flag
 	BuildMSan              bool               // -msan flag
 	BuildN                 bool               // -n flag
 	BuildO                 string             // -o flag
 	BuildP                 = runtime.NumCPU() // -p flag
+	BuildPGO               string             // -pgo flag
 	BuildPkgdir            string             // -pkgdir flag
 	BuildRace              bool               // -race flag
 	BuildToolexec          []string           // -toolexec flag
@@ -37,6 +42,8 @@ var (
 	BuildWork              bool // -work flag
 	BuildX                 bool // -x flag
 
+	ModulesEnabled bool // whether the go command is running in module-aware mode
+
 	CmdName string // "build", "install", "list", etc.
 
 	DebugActiongraph string // -debug-actiongraph flag (undocumented, unstable)
@@ -0,0 +1,99 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dirhash
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func h(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func htop(k string, s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return k + ":" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestHash1(t *testing.T) {
+	files := []string{"xyz", "abc"}
+	open := func(file string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("data for " + file)), nil
+	}
+	want := htop("h1", fmt.Sprintf("%s  %s\n%s  %s\n", h("data for abc"), "abc", h("data for xyz"), "xyz"))
+	out, err := Hash1(files, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != want {
+		t.Errorf("Hash1(...) = %s, want %s", out, want)
+	}
+
+	_, err = Hash1([]string{"xyz", "a\nbc"}, open)
+	if err == nil {
+		t.Error("Hash1: expected error on newline in filenames")
+	}
+}
+
+func TestHashDirAndZip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dirhash-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "xyz"), []byte("data for xyz"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "abc"), []byte("data for abc"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	want := htop("h1", fmt.Sprintf("%s  %s\n%s  %s\n", h("data for abc"), "prefix/sub/abc", h("data for xyz"), "prefix/xyz"))
+	out, err := HashDir(dir, "prefix", Hash1)
+	if err != nil {
+		t.Fatalf("HashDir: %v", err)
+	}
+	if out != want {
+		t.Errorf("HashDir(...) = %s, want %s", out, want)
+	}
+
+	zipfile := filepath.Join(dir, "x.zip")
+	f, err := os.Create(zipfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for _, name := range []string{"prefix/xyz", "prefix/sub/abc"} {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(w, "data for %s", name[strings.LastIndex(name, "/")+1:])
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out, err = HashZip(zipfile, Hash1)
+	if err != nil {
+		t.Fatalf("HashZip: %v", err)
+	}
+	if out != want {
+		t.Errorf("HashZip(...) = %s, want %s", out, want)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 
@@ -16,6 +17,7 @@ import (
 	"cmd/go/internal/cache"
 	"cmd/go/internal/cfg"
 	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/work"
 )
 
@@ -51,11 +53,13 @@ func MkEnv() []cfg.EnvVar {
 		{Name: "GOARCH", Value: cfg.Goarch},
 		{Name: "GOBIN", Value: cfg.GOBIN},
 		{Name: "GOCACHE", Value: cache.DefaultDir()},
+		{Name: "GOCACHEPROG", Value: os.Getenv("GOCACHEPROG")},
 		{Name: "GOEXE", Value: cfg.ExeSuffix},
 		{Name: "GOHOSTARCH", Value: runtime.GOARCH},
 		{Name: "GOHOSTOS", Value: runtime.GOOS},
 		{Name: "GOOS", Value: cfg.Goos},
 		{Name: "GOPATH", Value: cfg.BuildContext.GOPATH},
+		{Name: "GOPROXY", Value: os.Getenv("GOPROXY")},
 		{Name: "GORACE", Value: os.Getenv("GORACE")},
 		{Name: "GOROOT", Value: cfg.GOROOT},
 		{Name: "GOTMPDIR", Value: os.Getenv("GOTMPDIR")},
@@ -115,8 +119,13 @@ func ExtraEnvVars() []cfg.EnvVar {
 	b.Init()
 	cppflags, cflags, cxxflags, fflags, ldflags := b.CFlags(&load.Package{})
 	cmd := b.GccCmd(".", "")
+	gomod := ""
+	if modload.HasModRoot() {
+		gomod = filepath.Join(modload.ModRoot(), "go.mod")
+	}
 	return []cfg.EnvVar{
 		// Note: Update the switch in runEnv below when adding to this list.
+		{Name: "GOMOD", Value: gomod},
 		{Name: "CGO_CFLAGS", Value: strings.Join(cflags, " ")},
 		{Name: "CGO_CPPFLAGS", Value: strings.Join(cppflags, " ")},
 		{Name: "CGO_CXXFLAGS", Value: strings.Join(cxxflags, " ")},
@@ -144,7 +153,8 @@ func runEnv(cmd *base.Command, args []string) {
 				"CGO_FFLAGS",
 				"CGO_LDFLAGS",
 				"PKG_CONFIG",
-				"GOGCCFLAGS":
+				"GOGCCFLAGS",
+				"GOMOD":
 				needExtra = true
 			}
 		}
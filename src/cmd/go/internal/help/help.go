@@ -26,15 +26,10 @@ func Help(args []string) {
 		// not exit 2: succeeded at 'go help'.
 		return
 	}
-	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, "usage: go help command\n\nToo many arguments given.\n")
-		os.Exit(2) // failed at 'go help'
-	}
-
 	arg := args[0]
 
 	// 'go help documentation' generates doc.go.
-	if arg == "documentation" {
+	if len(args) == 1 && arg == "documentation" {
 		fmt.Println("// Copyright 2011 The Go Authors. All rights reserved.")
 		fmt.Println("// Use of this source code is governed by a BSD-style")
 		fmt.Println("// license that can be found in the LICENSE file.")
@@ -45,21 +40,41 @@ func Help(args []string) {
 		buf := new(bytes.Buffer)
 		PrintUsage(buf)
 		usage := &base.Command{Long: buf.String()}
-		tmpl(&commentWriter{W: os.Stdout}, documentationTemplate, append([]*base.Command{usage}, base.Commands...))
+		cmds := []*base.Command{usage}
+		for _, cmd := range base.Commands {
+			cmds = append(cmds, cmd)
+			cmds = append(cmds, cmd.Commands...)
+		}
+		tmpl(&commentWriter{W: os.Stdout}, documentationTemplate, cmds)
 		fmt.Println("package main")
 		return
 	}
 
-	for _, cmd := range base.Commands {
-		if cmd.Name() == arg {
-			tmpl(os.Stdout, helpTemplate, cmd)
-			// not exit 2: succeeded at 'go help cmd'.
-			return
+	cmds := base.Commands
+	var cmd *base.Command
+Args:
+	for i, arg := range args {
+		for _, c := range cmds {
+			if c.Name() == arg {
+				cmd = c
+				cmds = c.Commands
+				continue Args
+			}
+		}
+		helpSuccess := "go help"
+		if i > 0 {
+			helpSuccess = "go help " + strings.Join(args[:i], " ")
 		}
+		fmt.Fprintf(os.Stderr, "Unknown help topic %#q. Run '%s'.\n", strings.Join(args, " "), helpSuccess)
+		os.Exit(2) // failed at 'go help cmd'
 	}
 
-	fmt.Fprintf(os.Stderr, "Unknown help topic %#q. Run 'go help'.\n", arg)
-	os.Exit(2) // failed at 'go help cmd'
+	if len(cmd.Commands) > 0 {
+		PrintSubcommandUsage(os.Stdout, cmd)
+	} else {
+		tmpl(os.Stdout, helpTemplate, cmd)
+	}
+	// not exit 2: succeeded at 'go help cmd'.
 }
 
 var usageTemplate = `Go is a tool for managing Go source code.
@@ -69,19 +84,32 @@ Usage:
 	go command [arguments]
 
 The commands are:
-{{range .}}{{if .Runnable}}
+{{range .}}{{if or .Runnable .Commands}}
 	{{.Name | printf "%-11s"}} {{.Short}}{{end}}{{end}}
 
 Use "go help [command]" for more information about a command.
 
 Additional help topics:
-{{range .}}{{if not .Runnable}}
+{{range .}}{{if not (or .Runnable .Commands)}}
 	{{.Name | printf "%-11s"}} {{.Short}}{{end}}{{end}}
 
 Use "go help [topic]" for more information about that topic.
 
 `
 
+var subcommandUsageTemplate = `{{.Long | trim}}
+
+Usage:
+
+	go {{.LongName}} <command> [arguments]
+
+The commands are:
+{{range .Commands}}
+	{{.Name | printf "%-11s"}} {{.Short}}{{end}}
+
+Use "go help {{.LongName}} <command>" for more information about a command.
+`
+
 var helpTemplate = `{{if .Runnable}}usage: go {{.UsageLine}}
 
 {{end}}{{.Long | trim}}
@@ -89,14 +117,16 @@ var helpTemplate = `{{if .Runnable}}usage: go {{.UsageLine}}
 
 var documentationTemplate = `{{range .}}{{if .Short}}{{.Short | capitalize}}
 
-{{end}}{{if .Runnable}}Usage:
+{{end}}{{if .Commands}}` + subcommandUsageTemplate + `
+
+{{else}}{{if .Runnable}}Usage:
 
 	go {{.UsageLine}}
 
 {{end}}{{.Long | trim}}
 
 
-{{end}}`
+{{end}}{{end}}`
 
 // commentWriter writes a Go comment to the underlying io.Writer,
 // using line comment form (//).
@@ -176,3 +206,11 @@ func PrintUsage(w io.Writer) {
 	tmpl(bw, usageTemplate, base.Commands)
 	bw.Flush()
 }
+
+// PrintSubcommandUsage prints the usage message for cmd,
+// a command such as 'go mod' that has subcommands.
+func PrintSubcommandUsage(w io.Writer, cmd *base.Command) {
+	bw := bufio.NewWriter(w)
+	tmpl(bw, subcommandUsageTemplate, cmd)
+	bw.Flush()
+}
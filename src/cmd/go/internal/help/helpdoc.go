@@ -466,6 +466,11 @@ General-purpose environment variables:
 		Examples are linux, darwin, windows, netbsd.
 	GOPATH
 		For more details see: 'go help gopath'.
+	GOPROXY
+		URL of Go module proxy. See 'go help goproxy'.
+	GO111MODULE
+		Controls whether module mode is used: on, off, or auto.
+		See 'go help modules'.
 	GORACE
 		Options for the race detector.
 		See https://golang.org/doc/articles/race_detector.html.
@@ -477,6 +482,14 @@ General-purpose environment variables:
 	GOCACHE
 		The directory where the go command will store
 		cached information for reuse in future builds.
+	GOCACHEPROG
+		A command line, split at spaces, running a helper program
+		to which the go command delegates build cache lookups and
+		writes, such as to share a cache between machines.
+		The GOCACHE directory remains in use as a local cache.
+	GOCOVERDIR
+		The directory into which programs built with -cover
+		write their coverage data. See 'go help build'.
 
 Environment variables for use with cgo:
 
@@ -19,9 +19,20 @@ import (
 
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
+	"cmd/go/internal/modinfo"
 	"cmd/go/internal/str"
 )
 
+var (
+	// module hooks; nil if module use is disabled
+	ModBinDir            func() string                                       // return effective bin directory
+	ModLookup            func(path string) (dir, realPath string, err error) // lookup effective meaning of import
+	ModPackageModuleInfo func(path string) *modinfo.ModulePublic             // return module info for Package struct
+	ModImportPaths       func(args []string) []string                        // expand import paths
+	ModImportFromFiles   func([]string)                                      // update go.mod to add modules for imports in these files
+	ModDirImportPath     func(string) string                                 // return effective import path for directory
+)
+
 var IgnoreImports bool // control whether we ignore imports in packages
 
 // A Package describes a single package found in a directory.
@@ -47,6 +58,8 @@ type PackagePublic struct {
 	ConflictDir   string `json:",omitempty"` // Dir is hidden by this other directory
 	BinaryOnly    bool   `json:",omitempty"` // package cannot be recompiled
 
+	Module *modinfo.ModulePublic `json:",omitempty"` // info about package's module, if any
+
 	// Stale and StaleReason remain here *only* for the list command.
 	// They are only initialized in preparation for list execution.
 	// The regular build determines staleness on the fly during action execution.
@@ -67,6 +80,10 @@ type PackagePublic struct {
 	SwigCXXFiles   []string `json:",omitempty"` // .swigcxx files
 	SysoFiles      []string `json:",omitempty"` // .syso system object files added to package
 
+	// Embedded files
+	EmbedPatterns []string `json:",omitempty"` // //go:embed patterns
+	EmbedFiles    []string `json:",omitempty"` // files matched by EmbedPatterns
+
 	// Cgo directives
 	CgoCFLAGS    []string `json:",omitempty"` // cgo: flags for C compiler
 	CgoCPPFLAGS  []string `json:",omitempty"` // cgo: flags for C preprocessor
@@ -85,10 +102,14 @@ type PackagePublic struct {
 	DepsErrors []*PackageError `json:",omitempty"` // errors loading dependencies
 
 	// Test information
-	TestGoFiles  []string `json:",omitempty"` // _test.go files in package
-	TestImports  []string `json:",omitempty"` // imports from TestGoFiles
-	XTestGoFiles []string `json:",omitempty"` // _test.go files outside package
-	XTestImports []string `json:",omitempty"` // imports from XTestGoFiles
+	TestGoFiles        []string `json:",omitempty"` // _test.go files in package
+	TestImports        []string `json:",omitempty"` // imports from TestGoFiles
+	TestEmbedPatterns  []string `json:",omitempty"` // //go:embed patterns
+	TestEmbedFiles     []string `json:",omitempty"` // files matched by TestEmbedPatterns
+	XTestGoFiles       []string `json:",omitempty"` // _test.go files outside package
+	XTestImports       []string `json:",omitempty"` // imports from XTestGoFiles
+	XTestEmbedPatterns []string `json:",omitempty"` // //go:embed patterns
+	XTestEmbedFiles    []string `json:",omitempty"` // files matched by XTestEmbedPatterns
 }
 
 type PackageInternal struct {
@@ -104,6 +125,7 @@ type PackageInternal struct {
 	ExeName      string               // desired name for temporary executable
 	CoverMode    string               // preprocess Go source files with the coverage tool in this mode
 	CoverVars    map[string]*CoverVar // variables created by coverage analysis
+	Embed        map[string][]string  // //go:embed pattern -> list of matching files
 	OmitDebug    bool                 // tell linker not to write debug information
 	GobinSubdir  bool                 // install target would be subdir of GOBIN
 
@@ -172,6 +194,40 @@ type CoverVar struct {
 	Var  string // name of count struct
 }
 
+// DeclareCoverVars attaches the required cover variables names
+// to the files, to be used when annotating the files.
+func DeclareCoverVars(importPath string, files ...string) map[string]*CoverVar {
+	coverVars := make(map[string]*CoverVar)
+	coverIndex := 0
+	for _, file := range files {
+		if base.IsTestFile(file) {
+			continue
+		}
+		coverVars[file] = &CoverVar{
+			File: filepath.Join(importPath, file),
+			Var:  fmt.Sprintf("GoCover_%d", coverIndex),
+		}
+		coverIndex++
+	}
+	return coverVars
+}
+
+// EnsureImport ensures that package p imports the named package.
+func EnsureImport(p *Package, pkg string) {
+	for _, d := range p.Internal.Imports {
+		if d.Name == pkg {
+			return
+		}
+	}
+
+	p1 := LoadPackage(pkg, &ImportStack{})
+	if p1.Error != nil {
+		base.Fatalf("load %s: %v", pkg, p1.Error)
+	}
+
+	p.Internal.Imports = append(p.Internal.Imports, p1)
+}
+
 func (p *Package) copyBuild(pp *build.Package) {
 	p.Internal.Build = pp
 
@@ -220,6 +276,9 @@ func (p *Package) copyBuild(pp *build.Package) {
 	p.TestImports = pp.TestImports
 	p.XTestGoFiles = pp.XTestGoFiles
 	p.XTestImports = pp.XTestImports
+	p.EmbedPatterns = pp.EmbedPatterns
+	p.TestEmbedPatterns = pp.TestEmbedPatterns
+	p.XTestEmbedPatterns = pp.XTestEmbedPatterns
 	if IgnoreImports {
 		p.Imports = nil
 		p.TestImports = nil
@@ -384,8 +443,18 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 	origPath := path
 	isLocal := build.IsLocalImport(path)
 	var debugDeprecatedImportcfgDir string
+	var modDir string
+	var modErr error
 	if isLocal {
 		importPath = dirToImportPath(filepath.Join(srcDir, path))
+	} else if cfg.ModulesEnabled && (parent == nil || !parent.Standard) {
+		// Standard packages resolve their imports (including the
+		// vendored copies in GOROOT/src/vendor) the usual way.
+		var p string
+		modDir, p, modErr = ModLookup(path)
+		if modErr == nil {
+			importPath = p
+		}
 	} else if DebugDeprecatedImportcfg.enabled {
 		if d, i := DebugDeprecatedImportcfg.lookup(parent, path); d != "" {
 			debugDeprecatedImportcfgDir = d
@@ -416,6 +485,11 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 		var err error
 		if debugDeprecatedImportcfgDir != "" {
 			bp, err = cfg.BuildContext.ImportDir(debugDeprecatedImportcfgDir, 0)
+		} else if modDir != "" {
+			bp, err = cfg.BuildContext.ImportDir(modDir, 0)
+		} else if modErr != nil {
+			bp = new(build.Package)
+			err = fmt.Errorf("unknown import path %q: %v", importPath, modErr)
 		} else {
 			buildMode := build.ImportComment
 			if mode&UseVendor == 0 || path != origPath {
@@ -427,8 +501,10 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 		bp.ImportPath = importPath
 		if cfg.GOBIN != "" {
 			bp.BinDir = cfg.GOBIN
+		} else if cfg.ModulesEnabled && !bp.Goroot {
+			bp.BinDir = ModBinDir()
 		}
-		if debugDeprecatedImportcfgDir == "" && err == nil && !isLocal && bp.ImportComment != "" && bp.ImportComment != path &&
+		if debugDeprecatedImportcfgDir == "" && modDir == "" && err == nil && !isLocal && bp.ImportComment != "" && bp.ImportComment != path &&
 			!strings.Contains(path, "/vendor/") && !strings.HasPrefix(path, "vendor/") {
 			err = fmt.Errorf("code in directory %s expects import %q", bp.Dir, bp.ImportComment)
 		}
@@ -447,7 +523,7 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 	}
 
 	// Checked on every import because the rules depend on the code doing the importing.
-	if perr := disallowInternal(srcDir, p, stk); perr != p {
+	if perr := disallowInternal(srcDir, parent, p, stk); perr != p {
 		return setErrorPos(perr, importPos)
 	}
 	if mode&UseVendor != 0 {
@@ -623,10 +699,11 @@ func reusePackage(p *Package, stk *ImportStack) *Package {
 	return p
 }
 
-// disallowInternal checks that srcDir is allowed to import p.
+// disallowInternal checks that srcDir (containing package importer, if non-nil)
+// is allowed to import p.
 // If the import is allowed, disallowInternal returns the original package p.
 // If not, it returns a new package containing just an appropriate error.
-func disallowInternal(srcDir string, p *Package, stk *ImportStack) *Package {
+func disallowInternal(srcDir string, importer *Package, p *Package, stk *ImportStack) *Package {
 	// golang.org/s/go14internal:
 	// An import of a path containing the element “internal”
 	// is disallowed if the importing code is outside the tree
@@ -669,16 +746,35 @@ func disallowInternal(srcDir string, p *Package, stk *ImportStack) *Package {
 	if i > 0 {
 		i-- // rewind over slash in ".../internal"
 	}
-	parent := p.Dir[:i+len(p.Dir)-len(p.ImportPath)]
-	if hasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
-		return p
-	}
 
-	// Look for symlinks before reporting error.
-	srcDir = expandPath(srcDir)
-	parent = expandPath(parent)
-	if hasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
-		return p
+	if p.Module == nil {
+		parent := p.Dir[:i+len(p.Dir)-len(p.ImportPath)]
+		if hasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
+			return p
+		}
+
+		// Look for symlinks before reporting error.
+		srcDir = expandPath(srcDir)
+		parent = expandPath(parent)
+		if hasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
+			return p
+		}
+	} else {
+		// p is in a module, whose directory need not end in its import path,
+		// so decide based on the importer's import path instead.
+		if importer == nil {
+			return p
+		}
+		importerPath := importer.ImportPath
+		if importer.Internal.CmdlineFiles {
+			// The importer is a list of command-line files.
+			// Use the import path of the directory containing them.
+			importerPath = ModDirImportPath(importer.Dir)
+		}
+		parentOfInternal := p.ImportPath[:i]
+		if hasPathPrefix(importerPath, parentOfInternal) {
+			return p
+		}
 	}
 
 	// Internal is present, and srcDir is outside parent's tree. Not allowed.
@@ -835,6 +931,20 @@ func InstallTargetDir(p *Package) TargetDir {
 	return ToBin
 }
 
+// isMajorVersionElem reports whether the import path element
+// elem is a major version suffix like v2.
+func isMajorVersionElem(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] < '1' || '9' < elem[1] {
+		return false
+	}
+	for i := 2; i < len(elem); i++ {
+		if c := elem[i]; c < '0' || '9' < c {
+			return false
+		}
+	}
+	return true
+}
+
 var cgoExclude = map[string]bool{
 	"runtime/cgo": true,
 }
@@ -866,6 +976,9 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 	// so it is important that the first load can fill in CmdlinePkg correctly.
 	// Hence the call to an explicit matching check here.
 	p.Internal.CmdlinePkg = isCmdlinePkg(p)
+	if cfg.ModulesEnabled && !p.Internal.Local && !p.Standard {
+		p.Module = ModPackageModuleInfo(p.ImportPath)
+	}
 
 	p.Internal.Asmflags = BuildAsmflags.For(p)
 	p.Internal.Gcflags = BuildGcflags.For(p)
@@ -908,6 +1021,15 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 			return
 		}
 		_, elem := filepath.Split(p.Dir)
+		if p.Module != nil {
+			// A module directory may end in @version,
+			// so name the command after its import path instead.
+			// For example.com/cmd/v2, use cmd, not v2.
+			_, elem = pathpkg.Split(p.ImportPath)
+			if isMajorVersionElem(elem) {
+				_, elem = pathpkg.Split(pathpkg.Dir(p.ImportPath))
+			}
+		}
 		full := cfg.BuildContext.GOOS + "_" + cfg.BuildContext.GOARCH + "/" + elem
 		if cfg.BuildContext.GOOS != base.ToolGOOS || cfg.BuildContext.GOARCH != base.ToolGOARCH {
 			// Install cross-compiled binaries to subdirectories of bin.
@@ -934,6 +1056,10 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 		// Local import turned into absolute path.
 		// No permanent install target.
 		p.Target = ""
+	} else if p.Module != nil {
+		// Packages in modules are not installed;
+		// their compiled forms live only in the build cache.
+		p.Target = ""
 	} else {
 		p.Target = p.Internal.Build.PkgObj
 		if cfg.BuildLinkshared {
@@ -1140,6 +1266,29 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 		setError(fmt.Sprintf("case-insensitive import collision: %q and %q", p.ImportPath, other))
 		return
 	}
+
+	// Resolve //go:embed patterns to the files they embed.
+	// The test patterns are resolved only for reporting by go list;
+	// go test resolves them again when it builds the test packages.
+	setEmbedError := func(err error, pos map[string][]token.Position) {
+		setError(err.Error())
+		if e, ok := err.(*EmbedError); ok {
+			setErrorPos(p, pos[e.Pattern])
+		}
+	}
+	var err1 error
+	if p.EmbedFiles, p.Internal.Embed, err1 = p.ResolveEmbed(p.EmbedPatterns); err1 != nil {
+		setEmbedError(err1, p.Internal.Build.EmbedPatternPos)
+		return
+	}
+	if p.TestEmbedFiles, _, err1 = p.ResolveEmbed(p.TestEmbedPatterns); err1 != nil {
+		setEmbedError(err1, p.Internal.Build.TestEmbedPatternPos)
+		return
+	}
+	if p.XTestEmbedFiles, _, err1 = p.ResolveEmbed(p.XTestEmbedPatterns); err1 != nil {
+		setEmbedError(err1, p.Internal.Build.XTestEmbedPatternPos)
+		return
+	}
 }
 
 // LinkerDeps returns the list of linker-induced dependencies for main package p.
@@ -1329,9 +1478,17 @@ func LoadPackage(arg string, stk *ImportStack) *Package {
 	// referring to io/ioutil rather than a hypothetical import of
 	// "./ioutil".
 	if build.IsLocalImport(arg) {
-		bp, _ := cfg.BuildContext.ImportDir(filepath.Join(base.Cwd, arg), build.FindOnly)
-		if bp.ImportPath != "" && bp.ImportPath != "." {
-			arg = bp.ImportPath
+		if cfg.ModulesEnabled {
+			// In module mode, a directory in the main module
+			// or one of its dependencies is loaded by its import path.
+			if path := ModDirImportPath(filepath.Join(base.Cwd, arg)); path != "" && path != "." && !build.IsLocalImport(path) {
+				arg = path
+			}
+		} else {
+			bp, _ := cfg.BuildContext.ImportDir(filepath.Join(base.Cwd, arg), build.FindOnly)
+			if bp.ImportPath != "" && bp.ImportPath != "." {
+				arg = bp.ImportPath
+			}
 		}
 	}
 
@@ -1367,7 +1524,14 @@ func PackagesAndErrors(args []string) []*Package {
 		return []*Package{GoFilesPackage(args)}
 	}
 
-	args = ImportPaths(args)
+	if cfg.ModulesEnabled {
+		if cmdlineMatchers == nil {
+			SetCmdlinePatterns(args)
+		}
+		args = ModImportPaths(args)
+	} else {
+		args = ImportPaths(args)
+	}
 	var (
 		pkgs    []*Package
 		stk     ImportStack
@@ -1444,6 +1608,10 @@ func GoFilesPackage(gofiles []string) *Package {
 		}
 	}
 
+	if cfg.ModulesEnabled {
+		ModImportFromFiles(gofiles)
+	}
+
 	var stk ImportStack
 	ctxt := cfg.BuildContext
 	ctxt.UseAllFiles = true
@@ -1503,6 +1671,8 @@ func GoFilesPackage(gofiles []string) *Package {
 		}
 		if cfg.GOBIN != "" {
 			pkg.Target = filepath.Join(cfg.GOBIN, exe)
+		} else if cfg.ModulesEnabled {
+			pkg.Target = filepath.Join(ModBinDir(), exe)
 		}
 	}
 
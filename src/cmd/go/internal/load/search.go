@@ -383,3 +383,16 @@ func ImportPathsNoDotExpansion(args []string) []string {
 func IsMetaPackage(name string) bool {
 	return name == "std" || name == "cmd" || name == "all"
 }
+
+// MatchPattern(pattern)(name) reports whether
+// name matches pattern, using the syntax described
+// in the comment on matchPattern.
+func MatchPattern(pattern string) func(name string) bool {
+	return matchPattern(pattern)
+}
+
+// TreeCanMatchPattern(pattern)(name) reports whether
+// name or children of name can possibly match pattern.
+func TreeCanMatchPattern(pattern string) func(name string) bool {
+	return treeCanMatchPattern(pattern)
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// go mod vendor
+
+package modcmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/module"
+)
+
+var cmdVendor = &base.Command{
+	UsageLine: "mod vendor [-v]",
+	Short:     "make vendored copy of dependencies",
+	Long: `
+Vendor resets the main module's vendor directory to include all packages
+needed to build and test all the main module's packages.
+It does not include test code for vendored packages.
+
+The list of vendored modules and packages is recorded in
+vendor/modules.txt, which the -mod=vendor build flag consults.
+
+The -v flag causes vendor to print the names of vendored
+modules and packages to standard error.
+	`,
+}
+
+func init() {
+	cmdVendor.Run = runVendor // break init cycle
+	cmdVendor.Flag.BoolVar(&cfg.BuildV, "v", false, "")
+}
+
+func runVendor(cmd *base.Command, args []string) {
+	if len(args) != 0 {
+		base.Fatalf("go mod vendor: vendor takes no arguments")
+	}
+	pkgs := modload.LoadALL()
+
+	vdir := filepath.Join(modload.ModRoot(), "vendor")
+	if err := os.RemoveAll(vdir); err != nil {
+		base.Fatalf("go mod vendor: %v", err)
+	}
+
+	modpkgs := make(map[module.Version][]string)
+	for _, pkg := range pkgs {
+		m := modload.PackageModule(pkg)
+		if m.Path == "" || m == modload.Target {
+			continue
+		}
+		modpkgs[m] = append(modpkgs[m], pkg)
+	}
+
+	var buf bytes.Buffer
+	for _, m := range modload.BuildList()[1:] {
+		pkgs := modpkgs[m]
+		if len(pkgs) == 0 {
+			continue
+		}
+		repl := ""
+		if r := modload.Replacement(m); r.Path != "" {
+			repl = " => " + r.Path
+			if r.Version != "" {
+				repl += " " + r.Version
+			}
+		}
+		line := fmt.Sprintf("# %s %s%s\n", m.Path, m.Version, repl)
+		buf.WriteString(line)
+		if cfg.BuildV {
+			os.Stderr.WriteString(line)
+		}
+		sort.Strings(pkgs)
+		for _, pkg := range pkgs {
+			fmt.Fprintf(&buf, "%s\n", pkg)
+			if cfg.BuildV {
+				fmt.Fprintf(os.Stderr, "%s\n", pkg)
+			}
+			vendorPkg(vdir, pkg)
+		}
+	}
+	if buf.Len() == 0 {
+		fmt.Fprintf(os.Stderr, "go: no dependencies to vendor\n")
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(vdir, "modules.txt"), buf.Bytes(), 0666); err != nil {
+		base.Fatalf("go mod vendor: %v", err)
+	}
+}
+
+// vendorPkg copies the non-test files of the package
+// with the given import path into the vendor directory.
+func vendorPkg(vdir, pkg string) {
+	src, _, err := modload.Lookup(pkg)
+	if err != nil {
+		base.Fatalf("go mod vendor: %v", err)
+	}
+	copyDir(filepath.Join(vdir, pkg), src, matchNonTest)
+}
+
+// matchNonTest reports whether the file should be vendored:
+// test files are omitted.
+func matchNonTest(info os.FileInfo) bool {
+	return !strings.HasSuffix(info.Name(), "_test.go")
+}
+
+// copyDir copies the regular files in src matching match into dst,
+// creating dst if necessary. It does not descend into subdirectories.
+func copyDir(dst, src string, match func(os.FileInfo) bool) {
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		base.Fatalf("go mod vendor: %v", err)
+	}
+	if err := os.MkdirAll(dst, 0777); err != nil {
+		base.Fatalf("go mod vendor: %v", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !file.Mode().IsRegular() || !match(file) {
+			continue
+		}
+		r, err := os.Open(filepath.Join(src, file.Name()))
+		if err != nil {
+			base.Fatalf("go mod vendor: %v", err)
+		}
+		w, err := os.Create(filepath.Join(dst, file.Name()))
+		if err != nil {
+			base.Fatalf("go mod vendor: %v", err)
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			base.Fatalf("go mod vendor: %v", err)
+		}
+		r.Close()
+		if err := w.Close(); err != nil {
+			base.Fatalf("go mod vendor: %v", err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// PkgMod is the root of the module cache, $GOPATH/pkg/mod.
+// It is set by package modload during initialization.
+var PkgMod string
+
+// CacheDir returns the directory holding downloaded
+// info, mod, and zip files, $GOPATH/pkg/mod/cache/download.
+func CacheDir() string {
+	return filepath.Join(PkgMod, "cache/download")
+}
+
+// CachePath returns the name of the file in the download cache
+// holding the given suffix ("info", "mod", "zip", or "ziphash")
+// for the module version m.
+func CachePath(m module.Version, suffix string) (string, error) {
+	dir, err := cacheDir(m.Path)
+	if err != nil {
+		return "", err
+	}
+	if !semver.IsValid(m.Version) {
+		return "", fmt.Errorf("non-semver module version %q", m.Version)
+	}
+	if module.CanonicalVersion(m.Version) != m.Version {
+		return "", fmt.Errorf("non-canonical module version %q", m.Version)
+	}
+	encVer, err := module.EncodeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, encVer+"."+suffix), nil
+}
+
+// DownloadDir returns the directory to which m should be extracted,
+// $GOPATH/pkg/mod/<module>@<version>.
+func DownloadDir(m module.Version) (string, error) {
+	if PkgMod == "" {
+		return "", fmt.Errorf("internal error: modfetch.PkgMod not set")
+	}
+	enc, err := module.EncodePath(m.Path)
+	if err != nil {
+		return "", err
+	}
+	if !semver.IsValid(m.Version) {
+		return "", fmt.Errorf("non-semver module version %q", m.Version)
+	}
+	if module.CanonicalVersion(m.Version) != m.Version {
+		return "", fmt.Errorf("non-canonical module version %q", m.Version)
+	}
+	encVer, err := module.EncodeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(PkgMod, enc+"@"+encVer), nil
+}
+
+func cacheDir(path string) (string, error) {
+	if PkgMod == "" {
+		return "", fmt.Errorf("internal error: modfetch.PkgMod not set")
+	}
+	enc, err := module.EncodePath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(CacheDir(), enc, "@v"), nil
+}
+
+var lookupCache = make(map[string]Repo)
+
+// Lookup returns the module with the given module path.
+// Lookups consult the module cache first and the proxy
+// named by $GOPROXY only for information not already cached.
+func Lookup(path string) Repo {
+	if r := lookupCache[path]; r != nil {
+		return r
+	}
+	r := &cachingRepo{path: path}
+	r.proxy, r.proxyErr = newProxyRepo(proxyURL, path)
+	lookupCache[path] = r
+	return r
+}
+
+// A cachingRepo is a Repo that records the results
+// of proxy lookups in the download cache.
+type cachingRepo struct {
+	path     string
+	proxy    Repo
+	proxyErr error // reason proxy is nil
+}
+
+func (r *cachingRepo) ModulePath() string {
+	return r.path
+}
+
+func (r *cachingRepo) Versions(prefix string) ([]string, error) {
+	if r.proxy != nil {
+		return r.proxy.Versions(prefix)
+	}
+	if r.proxyErr != errProxyOff {
+		return nil, r.proxyErr
+	}
+	// Offline: report the versions already in the cache.
+	return r.cachedVersions(prefix)
+}
+
+// cachedVersions returns the versions recorded in the cache's list file
+// or having info files in the cache.
+func (r *cachingRepo) cachedVersions(prefix string) ([]string, error) {
+	dir, err := cacheDir(r.path)
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]bool)
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "list"))
+	for _, v := range strings.Fields(string(data)) {
+		have[v] = true
+	}
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		if name := fi.Name(); strings.HasSuffix(name, ".info") {
+			if v, err := module.DecodeVersion(strings.TrimSuffix(name, ".info")); err == nil {
+				have[v] = true
+			}
+		}
+	}
+	var list []string
+	for v := range have {
+		if semver.IsValid(v) && strings.HasPrefix(v, prefix) {
+			list = append(list, v)
+		}
+	}
+	SortVersions(list)
+	return list, nil
+}
+
+func (r *cachingRepo) Stat(rev string) (*RevInfo, error) {
+	file, err := CachePath(module.Version{Path: r.path, Version: rev}, "info")
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		info := new(RevInfo)
+		if err := json.Unmarshal(data, info); err == nil && info.Version == rev {
+			return info, nil
+		}
+	}
+	if r.proxy == nil {
+		return nil, fmt.Errorf("%s@%s: %v", r.path, rev, r.proxyErr)
+	}
+	info, err := r.proxy.Stat(rev)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeInfo(file, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (r *cachingRepo) Latest() (*RevInfo, error) {
+	if r.proxy != nil {
+		info, err := r.proxy.Latest()
+		if err != nil {
+			return nil, err
+		}
+		file, err := CachePath(module.Version{Path: r.path, Version: info.Version}, "info")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeInfo(file, info); err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+	list, err := r.Versions("")
+	if err != nil {
+		return nil, err
+	}
+	if v := LatestVersion(list); v != "" {
+		return r.Stat(v)
+	}
+	return nil, fmt.Errorf("no versions of %s in module cache", r.path)
+}
+
+func (r *cachingRepo) GoMod(version string) ([]byte, error) {
+	file, err := CachePath(module.Version{Path: r.path, Version: version}, "mod")
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		return data, nil
+	}
+	if r.proxy == nil {
+		return nil, fmt.Errorf("%s@%s: %v", r.path, version, r.proxyErr)
+	}
+	data, err := r.proxy.GoMod(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeDiskCache(file, data); err != nil {
+		return nil, err
+	}
+	if err := addCachedVersion(r.path, version); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *cachingRepo) Zip(version, tmpdir string) (string, error) {
+	if r.proxy == nil {
+		return "", fmt.Errorf("%s@%s: %v", r.path, version, r.proxyErr)
+	}
+	return r.proxy.Zip(version, tmpdir)
+}
+
+func writeInfo(file string, info *RevInfo) error {
+	js, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return writeDiskCache(file, js)
+}
+
+// writeDiskCache writes data to file atomically,
+// so that concurrent go commands never see a partial file.
+func writeDiskCache(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// addCachedVersion records version in the cache's list file for path,
+// so that the download cache can itself be served as a module proxy.
+func addCachedVersion(path, version string) error {
+	dir, err := cacheDir(path)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, "list")
+	old, _ := ioutil.ReadFile(file)
+	for _, line := range strings.Split(string(old), "\n") {
+		if line == version {
+			return nil
+		}
+	}
+	var buf bytes.Buffer
+	buf.Write(old)
+	if len(old) > 0 && old[len(old)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(version + "\n")
+	return writeDiskCache(file, buf.Bytes())
+}
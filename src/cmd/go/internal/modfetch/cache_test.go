@@ -0,0 +1,94 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+)
+
+func TestCachePath(t *testing.T) {
+	defer func(old string) { PkgMod = old }(PkgMod)
+	PkgMod = filepath.FromSlash("/gopath/pkg/mod")
+
+	for _, tt := range []struct {
+		m      module.Version
+		suffix string
+		want   string // or error substring, if err is set
+		err    bool
+	}{
+		{module.Version{Path: "example.com/m", Version: "v1.0.0"}, "info", "cache/download/example.com/m/@v/v1.0.0.info", false},
+		{module.Version{Path: "example.com/M", Version: "v1.0.0-RC"}, "mod", "cache/download/example.com/!m/@v/v1.0.0-!r!c.mod", false},
+		{module.Version{Path: "example.com/m", Version: pseudoAfter}, "zip", "cache/download/example.com/m/@v/" + pseudoAfter + ".zip", false},
+		{module.Version{Path: "example.com/m", Version: "v1.0"}, "info", "non-canonical module version", true},
+		{module.Version{Path: "example.com/m", Version: "v1.0.0+build"}, "info", "non-canonical module version", true},
+		{module.Version{Path: "example.com/m", Version: "master"}, "info", "non-semver module version", true},
+	} {
+		got, err := CachePath(tt.m, tt.suffix)
+		if tt.err {
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CachePath(%v, %q) = %q, %v; want error containing %q", tt.m, tt.suffix, got, err, tt.want)
+			}
+			continue
+		}
+		want := filepath.Join(PkgMod, filepath.FromSlash(tt.want))
+		if err != nil || got != want {
+			t.Errorf("CachePath(%v, %q) = %q, %v; want %q", tt.m, tt.suffix, got, err, want)
+		}
+	}
+}
+
+func TestCachingRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modfetch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { PkgMod = old }(PkgMod)
+	PkgMod = filepath.Join(dir, "pkg/mod")
+
+	url := writeProxy(t, filepath.Join(dir, "proxy"), "example.com/m", "v1.0.0", pseudoAfter, "v1.1.0")
+	proxy, err := newProxyRepo(url, "example.com/m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	online := &cachingRepo{path: "example.com/m", proxy: proxy}
+	if _, err := online.Stat(pseudoAfter); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := online.GoMod("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Offline, only the versions fetched above are known:
+	// the one with an info file and the one added to the list file.
+	offline := &cachingRepo{path: "example.com/m", proxyErr: errProxyOff}
+	list, err := offline.Versions("")
+	if want := []string{"v1.0.0", pseudoAfter}; err != nil || !reflect.DeepEqual(list, want) {
+		t.Errorf("offline Versions = %v, %v; want %v", list, err, want)
+	}
+	if info, err := offline.Stat(pseudoAfter); err != nil || info.Version != pseudoAfter {
+		t.Errorf("offline Stat(%s) = %+v, %v", pseudoAfter, info, err)
+	}
+	if data, err := offline.GoMod("v1.0.0"); err != nil || !strings.Contains(string(data), "v1.0.0") {
+		t.Errorf("offline GoMod(v1.0.0) = %q, %v", data, err)
+	}
+	if _, err := offline.GoMod("v1.1.0"); err == nil || !strings.Contains(err.Error(), errProxyOff.Error()) {
+		t.Errorf("offline GoMod of uncached version = %v, want %v", err, errProxyOff)
+	}
+
+	// Without a proxy configured at all, lookups fail
+	// rather than silently using the cache.
+	unset := &cachingRepo{path: "example.com/m", proxyErr: errNoProxy}
+	if _, err := unset.Versions(""); err != errNoProxy {
+		t.Errorf("Versions with GOPROXY unset = %v, want %v", err, errNoProxy)
+	}
+}
@@ -0,0 +1,430 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfetch downloads modules from a module proxy
+// into the module cache and verifies them against go.sum.
+package modfetch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/dirhash"
+	"cmd/go/internal/module"
+)
+
+// Download downloads the specific module version to the
+// local download cache and returns the name of the directory
+// corresponding to the root of the module's file tree.
+func Download(mod module.Version) (dir string, err error) {
+	if PkgMod == "" {
+		// Do not download to current directory.
+		return "", fmt.Errorf("missing modfetch.PkgMod")
+	}
+
+	dir, err = DownloadDir(mod)
+	if err != nil {
+		return "", err
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) == 0 {
+		zipfile, err := CachePath(mod, "zip")
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(zipfile); err != nil {
+			if err := downloadZip(mod, zipfile); err != nil {
+				return "", err
+			}
+		}
+		if cfg.BuildV {
+			fmt.Fprintf(os.Stderr, "go: extracting %s %s\n", mod.Path, mod.Version)
+		}
+		if err := extract(mod, dir, zipfile); err != nil {
+			return "", err
+		}
+	}
+	if err := checkMod(mod); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func downloadZip(mod module.Version, target string) error {
+	repo := Lookup(mod.Path)
+	if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+	if cfg.BuildV || !QuietLookup {
+		fmt.Fprintf(os.Stderr, "go: downloading %s %s\n", mod.Path, mod.Version)
+	}
+	tmpfile, err := repo.Zip(mod.Version, filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpfile)
+
+	// Double-check zip file looks OK before trusting it.
+	if err := checkZip(mod, tmpfile); err != nil {
+		return err
+	}
+
+	hash, err := dirhash.HashZip(tmpfile, dirhash.DefaultHash)
+	if err != nil {
+		return err
+	}
+	if err := checkModSum(mod, hash); err != nil {
+		return err
+	}
+	if err := writeDiskCache(target+"hash", []byte(hash)); err != nil {
+		return err
+	}
+	return os.Rename(tmpfile, target)
+}
+
+// extract unpacks zipfile into dir, leaving the
+// resulting files and directories read-only.
+func extract(mod module.Version, dir, zipfile string) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0777); err != nil {
+		return err
+	}
+	tmpdir, err := ioutil.TempDir(parent, filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return err
+	}
+	prefix := mod.Path + "@" + mod.Version + "/"
+	if err := Unzip(tmpdir, zipfile, prefix); err != nil {
+		RemoveAll(tmpdir)
+		return fmt.Errorf("unzip %v: %v", zipfile, err)
+	}
+	makeReadOnly(tmpdir)
+	os.Remove(dir) // in case of an empty directory left by an earlier failure
+	if err := os.Rename(tmpdir, dir); err != nil {
+		RemoveAll(tmpdir)
+		if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+			// Another go command extracted the module concurrently.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// makeReadOnly makes the files and directories in the tree
+// rooted at dir read-only, so that builds cannot modify
+// the contents of the module cache by accident.
+func makeReadOnly(dir string) {
+	var dirs []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		os.Chmod(path, info.Mode()&^0222)
+		return nil
+	})
+	// Make directories read-only last, deepest first,
+	// so that we can still change their contents while walking.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if info, err := os.Stat(dirs[i]); err == nil {
+			os.Chmod(dirs[i], info.Mode()&^0222)
+		}
+	}
+}
+
+// RemoveAll removes a directory written by Download or Unzip, first applying
+// any permission changes needed to do so.
+func RemoveAll(dir string) error {
+	// Module cache has 0555 directories; make them writable in order to remove content.
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // ignore errors walking in file system
+		}
+		if info.IsDir() {
+			os.Chmod(path, 0777)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
+// QuietLookup suppresses the "go: downloading" messages
+// printed when fetching modules.
+var QuietLookup bool
+
+// GoModFile returns the path to the cached go.mod file for the given module
+// version, downloading it if necessary and verifying it against go.sum.
+func GoModFile(path, version string) (string, error) {
+	mod := module.Version{Path: path, Version: version}
+	if _, err := GoMod(path, version); err != nil {
+		return "", err
+	}
+	return CachePath(mod, "mod")
+}
+
+// GoMod returns the go.mod file for the given module version,
+// downloading it if necessary and verifying it against go.sum.
+func GoMod(path, version string) ([]byte, error) {
+	data, err := Lookup(path).GoMod(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGoMod(path, version, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GoModSum returns the go.sum entry for the module version's go.mod file.
+// (That is, it returns the entry listed in go.sum as "path version/go.mod".)
+func GoModSum(path, version string) (string, error) {
+	data, err := GoMod(path, version)
+	if err != nil {
+		return "", err
+	}
+	return goModSum(data)
+}
+
+func goModSum(data []byte) (string, error) {
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+// checkGoMod checks the given module's go.mod checksum;
+// data is the go.mod content.
+func checkGoMod(path, version string, data []byte) error {
+	h, err := goModSum(data)
+	if err != nil {
+		return fmt.Errorf("verifying %s %s go.mod: %v", path, version, err)
+	}
+	return checkModSum(module.Version{Path: path, Version: version + "/go.mod"}, h)
+}
+
+// checkMod checks the given module's checksum.
+func checkMod(mod module.Version) error {
+	ziphash, err := CachePath(mod, "ziphash")
+	if err != nil {
+		return fmt.Errorf("verifying %s@%s: %v", mod.Path, mod.Version, err)
+	}
+	data, err := ioutil.ReadFile(ziphash)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("verifying %s@%s: %v", mod.Path, mod.Version, err)
+		}
+		// The hash was lost; recompute it from the zip file.
+		zipfile, err := CachePath(mod, "zip")
+		if err != nil {
+			return err
+		}
+		h, err := dirhash.HashZip(zipfile, dirhash.DefaultHash)
+		if err != nil {
+			return fmt.Errorf("verifying %s@%s: %v", mod.Path, mod.Version, err)
+		}
+		data = []byte(h)
+		if err := writeDiskCache(ziphash, data); err != nil {
+			return err
+		}
+	}
+	h := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(h, "h1:") {
+		return fmt.Errorf("verifying %s@%s: unexpected ziphash: %q", mod.Path, mod.Version, h)
+	}
+	return checkModSum(mod, h)
+}
+
+// checkZip checks that the zip file for mod is well-formed:
+// every file must be in the directory path@version/,
+// named by a valid file path, and unique up to case folding.
+func checkZip(mod module.Version, zipfile string) error {
+	return checkZipFile(zipfile, mod.Path+"@"+mod.Version+"/")
+}
+
+// The go.sum state for the main module.
+var goSum struct {
+	file      string                      // name of go.sum file, if any
+	m         map[module.Version][]string // content of go.sum file
+	checked   map[modSum]bool             // sums actually checked during execution
+	dirty     bool                        // whether we added any new sums to m
+	overwrite bool                        // whether to overwrite go.sum instead of merging with it
+	enabled   bool                        // whether to use go.sum at all
+	loaded    bool
+}
+
+type modSum struct {
+	mod module.Version
+	sum string
+}
+
+// InitGoSum sets the name of the go.sum file to use
+// to record and check module checksums.
+// If InitGoSum is never called, checksums are not recorded.
+func InitGoSum(file string) {
+	goSum.file = file
+	goSum.enabled = true
+}
+
+// initGoSum loads the go.sum file, if needed.
+// It reports whether go.sum is in use.
+func initGoSum() bool {
+	if !goSum.enabled {
+		return false
+	}
+	if goSum.loaded {
+		return true
+	}
+	goSum.loaded = true
+	goSum.m = make(map[module.Version][]string)
+	goSum.checked = make(map[modSum]bool)
+	data, err := ioutil.ReadFile(goSum.file)
+	if err != nil && !os.IsNotExist(err) {
+		base.Fatalf("go: %v", err)
+	}
+	readGoSum(goSum.file, data)
+	return true
+}
+
+// readGoSum parses data, which is the content of file,
+// and adds it to goSum.m.
+func readGoSum(file string, data []byte) {
+	lineno := 0
+	for len(data) > 0 {
+		var line []byte
+		lineno++
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			line, data = data, nil
+		} else {
+			line, data = data[:i], data[i+1:]
+		}
+		f := strings.Fields(string(line))
+		if len(f) == 0 {
+			// blank line; skip it
+			continue
+		}
+		if len(f) != 3 {
+			base.Fatalf("go: malformed go.sum:\n%s:%d: wrong number of fields %v", file, lineno, len(f))
+		}
+		mod := module.Version{Path: f[0], Version: f[1]}
+		goSum.m[mod] = append(goSum.m[mod], f[2])
+	}
+}
+
+// checkModSum checks that the recorded checksum for mod is h.
+// If go.sum has no entry for mod, checkModSum records h.
+func checkModSum(mod module.Version, h string) error {
+	if !initGoSum() {
+		return nil
+	}
+	if goSum.checked[modSum{mod, h}] {
+		return nil
+	}
+	sums := goSum.m[mod]
+	if len(sums) > 0 {
+		for _, vh := range sums {
+			if h == vh {
+				goSum.checked[modSum{mod, h}] = true
+				return nil
+			}
+		}
+		return fmt.Errorf("verifying %s@%s: checksum mismatch\n\tdownloaded: %v\n\tgo.sum:     %v", mod.Path, mod.Version, h, strings.Join(sums, ", "))
+	}
+	goSum.m[mod] = append(goSum.m[mod], h)
+	goSum.checked[modSum{mod, h}] = true
+	goSum.dirty = true
+	return nil
+}
+
+// Sum returns the checksum for the downloaded copy of the given module,
+// if present in the download cache.
+func Sum(mod module.Version) string {
+	if PkgMod == "" {
+		// Do not use current directory.
+		return ""
+	}
+	ziphash, err := CachePath(mod, "ziphash")
+	if err != nil {
+		return ""
+	}
+	data, err := ioutil.ReadFile(ziphash)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// WriteGoSum writes the go.sum file if it needs to be updated.
+func WriteGoSum() {
+	if !goSum.enabled || !goSum.dirty {
+		return
+	}
+
+	// Merge in any lines added to go.sum by another go command
+	// since we read it, so that we never drop checksums.
+	// TrimGoSum asks to drop lines instead.
+	if data, err := ioutil.ReadFile(goSum.file); err == nil && !goSum.overwrite {
+		saved := goSum.m
+		goSum.m = make(map[module.Version][]string)
+		readGoSum(goSum.file, data)
+		for mod, sums := range saved {
+		Sums:
+			for _, sum := range sums {
+				for _, have := range goSum.m[mod] {
+					if have == sum {
+						continue Sums
+					}
+				}
+				goSum.m[mod] = append(goSum.m[mod], sum)
+			}
+		}
+	}
+
+	var mods []module.Version
+	for m := range goSum.m {
+		mods = append(mods, m)
+	}
+	module.Sort(mods)
+	var buf bytes.Buffer
+	for _, m := range mods {
+		list := goSum.m[m]
+		sort.Strings(list)
+		for _, h := range list {
+			fmt.Fprintf(&buf, "%s %s %s\n", m.Path, m.Version, h)
+		}
+	}
+
+	if err := ioutil.WriteFile(goSum.file, buf.Bytes(), 0666); err != nil {
+		base.Fatalf("go: writing go.sum: %v", err)
+	}
+	goSum.dirty = false
+	goSum.overwrite = false
+}
+
+// TrimGoSum trims go.sum to contain only the modules for which keep[m] is true.
+func TrimGoSum(keep map[module.Version]bool) {
+	if !initGoSum() {
+		return
+	}
+	for m := range goSum.m {
+		// If we're keeping x@v we also keep x@v/go.mod.
+		// Map x@v/go.mod back to x@v for the keep lookup.
+		noGoMod := module.Version{Path: m.Path, Version: strings.TrimSuffix(m.Version, "/go.mod")}
+		if !keep[m] && !keep[noGoMod] {
+			delete(goSum.m, m)
+			goSum.dirty = true
+			goSum.overwrite = true
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/web"
+)
+
+var HelpGoproxy = &base.Command{
+	UsageLine: "goproxy",
+	Short:     "module proxy protocol",
+	Long: `
+The go command downloads modules from a module proxy named by the
+GOPROXY environment variable. GOPROXY is the URL of the proxy:
+an http://, https://, or file:// URL. Setting GOPROXY to "off"
+disallows downloading modules from any source; in that case only
+modules already in the module cache can be used.
+
+A module proxy is a web server, or a directory tree, that responds to
+GET requests for URLs of a specified form. The requests have no query
+parameters, so even a site serving from a fixed file system
+(including a file:/// URL) can be a module proxy.
+
+The GET requests sent to a Go module proxy are:
+
+GET $GOPROXY/<module>/@v/list returns a list of all known versions of the
+given module, one per line.
+
+GET $GOPROXY/<module>/@v/<version>.info returns JSON-formatted metadata
+about that version of the given module.
+
+GET $GOPROXY/<module>/@v/<version>.mod returns the go.mod file
+for that version of the given module.
+
+GET $GOPROXY/<module>/@v/<version>.zip returns the zip archive
+for that version of the given module.
+
+To avoid problems when serving from case-sensitive file systems,
+the <module> and <version> elements are case-encoded, replacing every
+uppercase letter with an exclamation mark followed by the corresponding
+lower-case letter: github.com/Azure encodes as github.com/!azure.
+
+The JSON-formatted metadata about a given module corresponds to
+this Go data structure, which may be expanded in the future:
+
+    type Info struct {
+        Version string    // version string
+        Time    time.Time // commit time
+    }
+
+The zip archive for a specific version of a given module is a
+standard zip file that contains the file tree corresponding
+to the module's source code and related files. The archive uses
+slash-separated paths, and every file path in the archive must
+begin with <module>@<version>/, where the module and version are
+substituted directly, not case-encoded. The root of the module
+file tree corresponds to the <module>@<version>/ prefix in the
+archive.
+
+The go command stores the info, mod, and zip files it downloads
+in its local cache, $GOPATH/pkg/mod/cache/download.
+The cache layout is the same as the proxy URL space, so
+serving $GOPATH/pkg/mod/cache/download at (or copying it to)
+https://example.com/proxy would let other users access those
+cached module versions with GOPROXY=https://example.com/proxy.
+`,
+}
+
+// proxyURL is the value of $GOPROXY.
+var proxyURL = os.Getenv("GOPROXY")
+
+// errProxyOff is returned by lookups when GOPROXY=off.
+var errProxyOff = errors.New("module lookup disabled by GOPROXY=off")
+
+// errNoProxy is returned by lookups when GOPROXY is unset.
+var errNoProxy = errors.New("cannot download modules: GOPROXY is not set (see 'go help goproxy')")
+
+// A proxyRepo is a Repo served by a module proxy.
+type proxyRepo struct {
+	url  string
+	path string
+}
+
+func newProxyRepo(baseURL, path string) (Repo, error) {
+	switch baseURL {
+	case "off":
+		return nil, errProxyOff
+	case "":
+		return nil, errNoProxy
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file", "http", "https":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL scheme (must be http, https, or file): %s", baseURL)
+	}
+	enc, err := module.EncodePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &proxyRepo{strings.TrimSuffix(baseURL, "/") + "/" + enc, path}, nil
+}
+
+func (p *proxyRepo) ModulePath() string {
+	return p.path
+}
+
+// get fetches the named file relative to the proxy URL for the module.
+func (p *proxyRepo) get(name string) ([]byte, error) {
+	target := p.url + "/" + name
+	if strings.HasPrefix(target, "file://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		file := u.Path
+		if runtime.GOOS == "windows" {
+			// file:///C:/x is parsed as Path "/C:/x".
+			file = strings.TrimPrefix(file, "/")
+		}
+		data, err := ioutil.ReadFile(filepath.FromSlash(file))
+		if os.IsNotExist(err) {
+			return nil, &notExistError{target}
+		}
+		return data, err
+	}
+	data, err := web.Get(target)
+	if herr, ok := err.(*web.HTTPError); ok && (herr.StatusCode == 404 || herr.StatusCode == 410) {
+		return nil, &notExistError{target}
+	}
+	return data, err
+}
+
+// A notExistError reports that a proxy does not have the requested file.
+type notExistError struct {
+	url string
+}
+
+func (e *notExistError) Error() string {
+	return fmt.Sprintf("%s: not found", e.url)
+}
+
+func (p *proxyRepo) Versions(prefix string) ([]string, error) {
+	data, err := p.get("@v/list")
+	if err != nil {
+		if _, ok := err.(*notExistError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []string
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) >= 1 && semver.IsValid(f[0]) && strings.HasPrefix(f[0], prefix) {
+			list = append(list, f[0])
+		}
+	}
+	SortVersions(list)
+	return list, nil
+}
+
+func (p *proxyRepo) Latest() (*RevInfo, error) {
+	list, err := p.Versions("")
+	if err != nil {
+		return nil, err
+	}
+	if v := LatestVersion(list); v != "" {
+		return p.Stat(v)
+	}
+	return nil, fmt.Errorf("no versions of %s available from proxy", p.path)
+}
+
+func (p *proxyRepo) Stat(rev string) (*RevInfo, error) {
+	encRev, err := module.EncodeVersion(rev)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.get("@v/" + encRev + ".info")
+	if err != nil {
+		return nil, err
+	}
+	info := new(RevInfo)
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("%s@%s: invalid info file: %v", p.path, rev, err)
+	}
+	if info.Version != rev {
+		return nil, fmt.Errorf("%s@%s: info file reports version %q", p.path, rev, info.Version)
+	}
+	return info, nil
+}
+
+func (p *proxyRepo) GoMod(version string) ([]byte, error) {
+	encVer, err := module.EncodeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return p.get("@v/" + encVer + ".mod")
+}
+
+func (p *proxyRepo) Zip(version string, tmpdir string) (tmpfile string, err error) {
+	encVer, err := module.EncodeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	data, err := p.get("@v/" + encVer + ".zip")
+	if err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(tmpdir, "go-proxy-download-")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// A RevInfo describes a single revision of a module.
+type RevInfo struct {
+	Version string    // version string
+	Time    time.Time // commit time
+}
+
+// A Repo represents a repository storing all versions of a single module.
+type Repo interface {
+	// ModulePath returns the module path.
+	ModulePath() string
+
+	// Versions lists all known versions with the given prefix,
+	// sorted in semantic version order.
+	Versions(prefix string) (tags []string, err error)
+
+	// Stat returns information about the given version.
+	Stat(rev string) (*RevInfo, error)
+
+	// Latest returns the latest version.
+	Latest() (*RevInfo, error)
+
+	// GoMod returns the go.mod file for the given version.
+	GoMod(version string) (data []byte, err error)
+
+	// Zip downloads a zip file for the given version
+	// to a new file in a given temporary directory.
+	// It returns the name of the new file.
+	// The caller should remove the file when finished with it.
+	Zip(version, tmpdir string) (tmpfile string, err error)
+}
+
+// SortVersions sorts list in semantic version order.
+func SortVersions(list []string) {
+	mods := make([]module.Version, len(list))
+	for i, v := range list {
+		mods[i] = module.Version{Version: v}
+	}
+	module.Sort(mods)
+	for i, m := range mods {
+		list[i] = m.Version
+	}
+}
+
+// LatestVersion returns the latest version in list,
+// preferring release versions to pre-release versions.
+// It returns the empty string if list is empty.
+func LatestVersion(list []string) string {
+	latest := ""
+	for _, v := range list {
+		if semver.Prerelease(v) == "" && (latest == "" || semver.Compare(v, latest) > 0) {
+			latest = v
+		}
+	}
+	if latest != "" {
+		return latest
+	}
+	for _, v := range list {
+		if latest == "" || semver.Compare(v, latest) > 0 {
+			latest = v
+		}
+	}
+	return latest
+}
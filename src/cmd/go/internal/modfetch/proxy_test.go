@@ -0,0 +1,177 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Pseudo-versions name untagged commits. They are prereleases:
+// v0.0.0-timestamp-commit sorts before any tagged version, and
+// vX.Y.(Z+1)-0.timestamp-commit sorts after vX.Y.Z but before
+// vX.Y.(Z+1).
+const (
+	pseudoBase  = "v0.0.0-20180101000000-0123456789ab"
+	pseudoAfter = "v1.2.4-0.20180601000000-ba9876543210"
+)
+
+var sortVersionsTests = []struct {
+	in   []string
+	want []string
+}{
+	{
+		[]string{"v1.10.0", "v1.2.0", "v1.9.0"},
+		[]string{"v1.2.0", "v1.9.0", "v1.10.0"},
+	},
+	{
+		[]string{"v1.0.0", "v1.0.0-rc.1", "v0.9.0", "v1.0.0-beta"},
+		[]string{"v0.9.0", "v1.0.0-beta", "v1.0.0-rc.1", "v1.0.0"},
+	},
+	{
+		[]string{"v1.2.4", pseudoAfter, "v1.2.3", pseudoBase},
+		[]string{pseudoBase, "v1.2.3", pseudoAfter, "v1.2.4"},
+	},
+	{
+		[]string{"v2.0.0+incompatible", "v1.5.0"},
+		[]string{"v1.5.0", "v2.0.0+incompatible"},
+	},
+}
+
+func TestSortVersions(t *testing.T) {
+	for _, tt := range sortVersionsTests {
+		list := append([]string(nil), tt.in...)
+		SortVersions(list)
+		if !reflect.DeepEqual(list, tt.want) {
+			t.Errorf("SortVersions(%v) = %v, want %v", tt.in, list, tt.want)
+		}
+	}
+}
+
+var latestVersionTests = []struct {
+	in   []string
+	want string
+}{
+	{nil, ""},
+	{[]string{"v1.0.0", "v1.1.0", "v0.9.0"}, "v1.1.0"},
+	{[]string{"v1.0.0", "v1.1.0-beta"}, "v1.0.0"},
+	{[]string{"v1.1.0-beta", "v1.1.0-alpha"}, "v1.1.0-beta"},
+	// A pseudo-version is a prerelease, so a tagged release wins
+	// even if the pseudo-version is newer...
+	{[]string{"v1.2.3", pseudoAfter}, "v1.2.3"},
+	// ...but without releases, the newest pseudo-version wins.
+	{[]string{pseudoBase, pseudoAfter}, pseudoAfter},
+}
+
+func TestLatestVersion(t *testing.T) {
+	for _, tt := range latestVersionTests {
+		if got := LatestVersion(tt.in); got != tt.want {
+			t.Errorf("LatestVersion(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// writeProxy writes the files of a module proxy serving the
+// module path at the given versions to dir, and returns the
+// file:// URL of the proxy.
+func writeProxy(t *testing.T, dir, encPath string, versions ...string) string {
+	vdir := filepath.Join(dir, encPath, "@v")
+	if err := os.MkdirAll(vdir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	var list []string
+	for _, v := range versions {
+		list = append(list, v)
+		info := `{"Version":"` + v + `","Time":"2018-01-01T00:00:00Z"}`
+		if err := ioutil.WriteFile(filepath.Join(vdir, v+".info"), []byte(info), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(vdir, v+".mod"), []byte("module example.com/M\n// "+v+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The list is unsorted and includes a version that is not semver.
+	list = append(list, "master")
+	if err := ioutil.WriteFile(filepath.Join(vdir, "list"), []byte(strings.Join(list, "\n")+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+func TestProxyRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modfetch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Uppercase letters in the module path are case-encoded.
+	url := writeProxy(t, dir, "example.com/!m", "v1.1.0", pseudoBase, "v1.0.0", "v1.2.0-beta")
+	repo, err := newProxyRepo(url, "example.com/M")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{pseudoBase, "v1.0.0", "v1.1.0", "v1.2.0-beta"}},
+		{"v1.1", []string{"v1.1.0"}},
+		{"v2", nil},
+	} {
+		list, err := repo.Versions(tt.prefix)
+		if err != nil || !reflect.DeepEqual(list, tt.want) {
+			t.Errorf("Versions(%q) = %v, %v; want %v", tt.prefix, list, err, tt.want)
+		}
+	}
+
+	latest, err := repo.Latest()
+	if err != nil || latest.Version != "v1.1.0" {
+		t.Errorf("Latest() = %+v, %v; want v1.1.0", latest, err)
+	}
+	info, err := repo.Stat(pseudoBase)
+	if err != nil || info.Version != pseudoBase || info.Time.Year() != 2018 {
+		t.Errorf("Stat(%s) = %+v, %v", pseudoBase, info, err)
+	}
+	if _, err := repo.Stat("v9.9.9"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Stat of missing version = %v, want not found error", err)
+	}
+	data, err := repo.GoMod("v1.0.0")
+	if err != nil || !strings.Contains(string(data), "v1.0.0") {
+		t.Errorf("GoMod(v1.0.0) = %q, %v", data, err)
+	}
+
+	// A module the proxy does not have has no versions.
+	other, err := newProxyRepo(url, "example.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, err := other.Versions(""); list != nil || err != nil {
+		t.Errorf("Versions of unknown module = %v, %v; want nil, nil", list, err)
+	}
+}
+
+func TestNewProxyRepoErrors(t *testing.T) {
+	for _, tt := range []struct {
+		url, path string
+		err       error
+		contains  string
+	}{
+		{"off", "example.com/m", errProxyOff, ""},
+		{"", "example.com/m", errNoProxy, ""},
+		{"ftp://example.com/proxy", "example.com/m", nil, "invalid proxy URL scheme"},
+		{"https://example.com/proxy", "example.com/m\x00", nil, "invalid"},
+	} {
+		_, err := newProxyRepo(tt.url, tt.path)
+		if tt.err != nil && err != tt.err || tt.err == nil && (err == nil || !strings.Contains(err.Error(), tt.contains)) {
+			t.Errorf("newProxyRepo(%q, %q) = %v, want %v %q", tt.url, tt.path, err, tt.err, tt.contains)
+		}
+	}
+}
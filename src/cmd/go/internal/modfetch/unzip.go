@@ -0,0 +1,121 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/str"
+)
+
+// maxSize is the maximum total uncompressed size of a module zip file.
+const maxSize = 500 << 20
+
+// checkZipFile checks that every file in zipfile is named
+// prefix followed by a valid file path, that no two names are equal
+// up to case folding, and that the total size is reasonable.
+func checkZipFile(zipfile, prefix string) error {
+	z, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+
+	foldPath := make(map[string]string)
+	var size int64
+	for _, zf := range z.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			return fmt.Errorf("unexpected file name %s", zf.Name)
+		}
+		name := zf.Name[len(prefix):]
+		if name == "" {
+			continue
+		}
+		isDir := strings.HasSuffix(name, "/")
+		if isDir {
+			name = name[:len(name)-1]
+		}
+		if path.Clean(name) != name {
+			return fmt.Errorf("non-canonical file name %s", zf.Name)
+		}
+		if err := module.CheckFilePath(name); err != nil {
+			return err
+		}
+		fold := str.ToFold(name)
+		if other := foldPath[fold]; other != "" {
+			return fmt.Errorf("case-insensitive file name collision: %q and %q", other, name)
+		}
+		foldPath[fold] = name
+		if fold != name {
+			// The directories of name must not collide either.
+			for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+				if other := foldPath[str.ToFold(dir)]; other != "" && other != dir {
+					return fmt.Errorf("case-insensitive file name collision: %q and %q", other, dir)
+				}
+			}
+		}
+		s := int64(zf.UncompressedSize64)
+		if s < 0 || maxSize-size < s {
+			return fmt.Errorf("module source tree too big")
+		}
+		size += s
+	}
+	return nil
+}
+
+// Unzip extracts the files in zipfile whose names begin with prefix
+// into the directory dir, dropping the prefix.
+// The zip file must have been checked by checkZipFile.
+func Unzip(dir, zipfile, prefix string) error {
+	if err := checkZipFile(zipfile, prefix); err != nil {
+		return err
+	}
+	z, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+
+	for _, zf := range z.File {
+		name := zf.Name[len(prefix):]
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return err
+		}
+		w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+		if err != nil {
+			return fmt.Errorf("unzip %v: %v", zipfile, err)
+		}
+		r, err := zf.Open()
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("unzip %v: %v", zipfile, err)
+		}
+		lr := &io.LimitedReader{R: r, N: int64(zf.UncompressedSize64) + 1}
+		_, err = io.Copy(w, lr)
+		r.Close()
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("unzip %v: %v", zipfile, err)
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("unzip %v: %v", zipfile, err)
+		}
+		if lr.N <= 0 {
+			return fmt.Errorf("uncompressed size of file %s is larger than declared size (%d bytes)", zf.Name, zf.UncompressedSize64)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,618 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfile implements parsing and formatting for go.mod files.
+//
+// A go.mod file is a sequence of statements, one per line.
+// Each statement begins with a verb (module, require, exclude, or replace)
+// followed by arguments. Statements with the same verb may be grouped
+// into a parenthesized block:
+//
+//	module example.com/m
+//
+//	require (
+//		example.com/a v1.2.3
+//		example.com/b v0.1.0 // indirect
+//	)
+//
+//	exclude example.com/a v1.2.2
+//
+//	replace example.com/b v0.1.0 => ../b
+//
+// Comments begin with // and run to the end of the line.
+// Comments on their own lines are kept with the statement that follows them;
+// a comment at the end of a line is kept with the statement on that line.
+// Format rewrites the file in canonical form, grouping statements by verb.
+package modfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// A File is the parsed, interpreted form of a go.mod file.
+type File struct {
+	Module  *Module
+	Require []*Require
+	Exclude []*Exclude
+	Replace []*Replace
+
+	// Trailing holds comment lines that follow the last statement.
+	Trailing []string
+}
+
+// Comments holds the comments attached to a single statement.
+type Comments struct {
+	Before []string // whole-line comments preceding the statement, without the leading //
+	Suffix string   // end-of-line comment, without the leading //
+}
+
+// A Module is the module statement.
+type Module struct {
+	Mod module.Version
+	Comments
+}
+
+// A Require is a single requirement statement.
+type Require struct {
+	Mod      module.Version
+	Indirect bool // has "// indirect" comment
+	Comments
+}
+
+// An Exclude is a single exclude statement.
+type Exclude struct {
+	Mod module.Version
+	Comments
+}
+
+// A Replace is a single replace statement.
+// If New.Version is empty, New.Path is a directory
+// (relative to the directory holding the go.mod file, or absolute)
+// holding the replacement module.
+type Replace struct {
+	Old module.Version
+	New module.Version
+	Comments
+}
+
+// An Error describes a problem at a specific line of a go.mod file.
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+// An ErrorList is a list of errors found while parsing a go.mod file.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	var buf bytes.Buffer
+	for i, e := range l {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(e.Error())
+	}
+	return buf.String()
+}
+
+// Parse parses the data, reported in errors as being from file,
+// into a File struct.
+func Parse(file string, data []byte) (*File, error) {
+	p := &parser{file: file, f: new(File)}
+	p.parse(data)
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+	return p.f, nil
+}
+
+// ModulePath returns the module path from the go.mod file text.
+// If it cannot find a module path, it returns an empty string.
+// It is tolerant of unrelated problems in the go.mod file.
+func ModulePath(data []byte) string {
+	for len(data) > 0 {
+		line := data
+		data = nil
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, data = line[:i], line[i+1:]
+		}
+		if i := bytes.Index(line, []byte("//")); i >= 0 {
+			line = line[:i]
+		}
+		fields, err := splitFields(string(line))
+		if err != nil || len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+type parser struct {
+	file    string
+	f       *File
+	errs    ErrorList
+	pending []string // comment lines not yet attached to a statement
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) {
+	p.errs = append(p.errs, &Error{File: p.file, Line: line, Err: fmt.Errorf(format, args...)})
+}
+
+func (p *parser) parse(data []byte) {
+	lines := strings.Split(string(data), "\n")
+	block := "" // verb of the enclosing block, if any
+	blockLine := 0
+	for i, text := range lines {
+		lineno := i + 1
+		text, comment, hasComment := cutComment(text)
+		fields, err := splitFields(text)
+		if err != nil {
+			p.errorf(lineno, "%v", err)
+			continue
+		}
+		if len(fields) == 0 {
+			if hasComment {
+				p.pending = append(p.pending, comment)
+			}
+			continue
+		}
+		if block != "" {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = ""
+				continue
+			}
+			p.stmt(lineno, block, fields, comment)
+			continue
+		}
+		verb := fields[0]
+		if len(fields) == 2 && fields[1] == "(" {
+			switch verb {
+			case "require", "exclude", "replace":
+				block = verb
+				blockLine = lineno
+			default:
+				p.errorf(lineno, "unknown block type: %s", verb)
+			}
+			continue
+		}
+		p.stmt(lineno, verb, fields[1:], comment)
+	}
+	if block != "" {
+		p.errorf(blockLine, "unterminated %s block", block)
+	}
+	if p.f.Module == nil && len(p.errs) == 0 && len(p.f.Require)+len(p.f.Exclude)+len(p.f.Replace) > 0 {
+		p.errorf(1, "no module statement")
+	}
+	p.f.Trailing = p.pending
+	p.pending = nil
+}
+
+// stmt records the statement with the given verb and arguments.
+func (p *parser) stmt(line int, verb string, args []string, comment string) {
+	c := Comments{Before: p.pending, Suffix: comment}
+	p.pending = nil
+	switch verb {
+	default:
+		p.errorf(line, "unknown directive: %s", verb)
+
+	case "module":
+		if p.f.Module != nil {
+			p.errorf(line, "repeated module statement")
+			return
+		}
+		if len(args) != 1 {
+			p.errorf(line, "usage: module module/path")
+			return
+		}
+		p.f.Module = &Module{Mod: module.Version{Path: args[0]}, Comments: c}
+
+	case "require", "exclude":
+		if len(args) != 2 {
+			p.errorf(line, "usage: %s module/path v1.2.3", verb)
+			return
+		}
+		v, err := checkVersion(args[0], args[1])
+		if err != nil {
+			p.errorf(line, "%v", err)
+			return
+		}
+		mod := module.Version{Path: args[0], Version: v}
+		if verb == "require" {
+			r := &Require{Mod: mod, Comments: c}
+			if isIndirect(c.Suffix) {
+				r.Indirect = true
+				r.Suffix = ""
+			}
+			p.f.Require = append(p.f.Require, r)
+		} else {
+			p.f.Exclude = append(p.f.Exclude, &Exclude{Mod: mod, Comments: c})
+		}
+
+	case "replace":
+		arrow := 2
+		if len(args) >= 2 && args[1] == "=>" {
+			arrow = 1
+		}
+		if len(args) < arrow+2 || len(args) > arrow+3 || args[arrow] != "=>" {
+			p.errorf(line, "usage: %s module/path [v1.2.3] => other/module v1.4\n\t or %s module/path [v1.2.3] => ../local/directory", verb, verb)
+			return
+		}
+		var old module.Version
+		old.Path = args[0]
+		if err := module.CheckImportPath(old.Path); err != nil {
+			p.errorf(line, "invalid module path: %v", err)
+			return
+		}
+		if arrow == 2 {
+			v, err := checkVersion(args[0], args[1])
+			if err != nil {
+				p.errorf(line, "%v", err)
+				return
+			}
+			old.Version = v
+		}
+		var new module.Version
+		new.Path = args[arrow+1]
+		if len(args) == arrow+2 {
+			if !IsDirectoryPath(new.Path) {
+				p.errorf(line, "replacement module without version must be directory path (rooted or starting with ./ or ../)")
+				return
+			}
+			if filepath.Separator == '/' && strings.Contains(new.Path, `\`) {
+				p.errorf(line, "replacement directory appears to be Windows path (on a non-windows system)")
+				return
+			}
+		} else {
+			v, err := checkVersion(new.Path, args[arrow+2])
+			if err != nil {
+				p.errorf(line, "%v", err)
+				return
+			}
+			if IsDirectoryPath(new.Path) {
+				p.errorf(line, "replacement module directory path %q cannot have version", new.Path)
+				return
+			}
+			new.Version = v
+		}
+		p.f.Replace = append(p.f.Replace, &Replace{Old: old, New: new, Comments: c})
+	}
+}
+
+// checkVersion checks that vers is a valid canonical semantic version
+// for the module path.
+func checkVersion(path, vers string) (string, error) {
+	if !semver.IsValid(vers) {
+		return "", fmt.Errorf("invalid module version %q: must be of the form v1.2.3", vers)
+	}
+	if cv := module.CanonicalVersion(vers); cv != vers {
+		return "", fmt.Errorf("invalid module version %q: must be in canonical form %q", vers, cv)
+	}
+	if _, pathMajor, ok := module.SplitPathVersion(path); ok && !module.MatchPathMajor(vers, pathMajor) {
+		return "", fmt.Errorf("invalid module version %q: does not match module path %s", vers, path)
+	}
+	return vers, nil
+}
+
+// IsDirectoryPath reports whether the given path should be interpreted
+// as a directory path. Just like on the go command line, relative paths
+// and rooted paths are directory paths; the rest are module paths.
+func IsDirectoryPath(ns string) bool {
+	// Because go.mod files can move from one system to another,
+	// we check all known path syntaxes, both Unix and Windows.
+	return strings.HasPrefix(ns, "./") || strings.HasPrefix(ns, "../") || strings.HasPrefix(ns, "/") ||
+		strings.HasPrefix(ns, `.\`) || strings.HasPrefix(ns, `..\`) || strings.HasPrefix(ns, `\`) ||
+		len(ns) >= 2 && ('A' <= ns[0] && ns[0] <= 'Z' || 'a' <= ns[0] && ns[0] <= 'z') && ns[1] == ':'
+}
+
+// isIndirect reports whether the end-of-line comment marks
+// a requirement as indirect.
+func isIndirect(comment string) bool {
+	return strings.TrimSpace(comment) == "indirect"
+}
+
+// cutComment splits line into the text before any // comment
+// and the comment itself.
+func cutComment(line string) (text, comment string, ok bool) {
+	inQuote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote != 0:
+			if c == '\\' && inQuote == '"' {
+				i++
+			} else if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '`':
+			inQuote = c
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return line[:i], strings.TrimRightFunc(line[i+2:], unicode.IsSpace), true
+		}
+	}
+	return line, "", false
+}
+
+// splitFields splits text into white-space separated fields,
+// unquoting any Go-quoted strings.
+func splitFields(text string) ([]string, error) {
+	var fields []string
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return fields, nil
+		}
+		switch text[0] {
+		case '(', ')':
+			fields = append(fields, text[:1])
+			text = text[1:]
+			continue
+		case '"', '`':
+			end := -1
+			for i := 1; i < len(text); i++ {
+				if text[i] == '\\' && text[0] == '"' {
+					i++
+					continue
+				}
+				if text[i] == text[0] {
+					end = i
+					break
+				}
+			}
+			if end < 0 {
+				return nil, errors.New("unterminated quoted string")
+			}
+			s, err := strconv.Unquote(text[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string: %v", err)
+			}
+			fields = append(fields, s)
+			text = text[end+1:]
+			continue
+		}
+		i := strings.IndexFunc(text, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || r == '`'
+		})
+		if i < 0 {
+			i = len(text)
+		}
+		fields = append(fields, text[:i])
+		text = text[i:]
+	}
+}
+
+// AddModuleStmt sets the module path of the file.
+func (f *File) AddModuleStmt(path string) {
+	if f.Module == nil {
+		f.Module = new(Module)
+	}
+	f.Module.Mod.Path = path
+}
+
+// AddRequire adds a requirement on path at version vers,
+// replacing any existing requirement on path.
+func (f *File) AddRequire(path, vers string) {
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			r.Mod.Version = vers
+			r.Indirect = false
+			f.dropDuplicateRequire(path, r)
+			return
+		}
+	}
+	f.Require = append(f.Require, &Require{Mod: module.Version{Path: path, Version: vers}})
+}
+
+func (f *File) dropDuplicateRequire(path string, keep *Require) {
+	var list []*Require
+	for _, r := range f.Require {
+		if r.Mod.Path != path || r == keep {
+			list = append(list, r)
+		}
+	}
+	f.Require = list
+}
+
+// DropRequire removes all requirements on path.
+func (f *File) DropRequire(path string) {
+	f.dropDuplicateRequire(path, nil)
+}
+
+// SetRequire replaces the requirement list with req,
+// keeping the comments of requirements on modules that remain.
+func (f *File) SetRequire(req []*Require) {
+	old := make(map[string]*Require)
+	for _, r := range f.Require {
+		old[r.Mod.Path] = r
+	}
+	var list []*Require
+	for _, r := range req {
+		nr := &Require{Mod: r.Mod, Indirect: r.Indirect}
+		if o := old[r.Mod.Path]; o != nil {
+			nr.Comments = o.Comments
+		}
+		list = append(list, nr)
+	}
+	f.Require = list
+}
+
+// AddExclude adds an exclusion of path at version vers.
+func (f *File) AddExclude(path, vers string) error {
+	v, err := checkVersion(path, vers)
+	if err != nil {
+		return err
+	}
+	for _, x := range f.Exclude {
+		if x.Mod.Path == path && x.Mod.Version == v {
+			return nil
+		}
+	}
+	f.Exclude = append(f.Exclude, &Exclude{Mod: module.Version{Path: path, Version: v}})
+	return nil
+}
+
+// AddReplace adds a replacement of oldPath@oldVers by newPath@newVers,
+// replacing any existing replacement of the same module version.
+func (f *File) AddReplace(oldPath, oldVers, newPath, newVers string) {
+	old := module.Version{Path: oldPath, Version: oldVers}
+	new := module.Version{Path: newPath, Version: newVers}
+	for _, r := range f.Replace {
+		if r.Old == old {
+			r.New = new
+			return
+		}
+	}
+	f.Replace = append(f.Replace, &Replace{Old: old, New: new})
+}
+
+// SortBlocks sorts the require, exclude, and replace statements
+// by module path and version, removing exact duplicates.
+func (f *File) SortBlocks() {
+	sort.SliceStable(f.Require, func(i, j int) bool {
+		return lessVersion(f.Require[i].Mod, f.Require[j].Mod)
+	})
+	sort.SliceStable(f.Exclude, func(i, j int) bool {
+		return lessVersion(f.Exclude[i].Mod, f.Exclude[j].Mod)
+	})
+	sort.SliceStable(f.Replace, func(i, j int) bool {
+		return lessVersion(f.Replace[i].Old, f.Replace[j].Old)
+	})
+	var xs []*Exclude
+	for i, x := range f.Exclude {
+		if i == 0 || x.Mod != f.Exclude[i-1].Mod {
+			xs = append(xs, x)
+		}
+	}
+	f.Exclude = xs
+}
+
+func lessVersion(x, y module.Version) bool {
+	if x.Path != y.Path {
+		return x.Path < y.Path
+	}
+	return semver.Compare(x.Version, y.Version) < 0
+}
+
+// Format returns the go.mod file text for f.
+// Statements are grouped by verb: module, then require, exclude, and replace.
+// A verb with more than one statement is written as a parenthesized block.
+func (f *File) Format() ([]byte, error) {
+	var buf bytes.Buffer
+	if f.Module != nil {
+		writeBefore(&buf, "", f.Module.Before)
+		buf.WriteString("module " + quote(f.Module.Mod.Path))
+		writeSuffix(&buf, f.Module.Suffix)
+	}
+
+	var req [][]string
+	var reqc []Comments
+	for _, r := range f.Require {
+		req = append(req, []string{quote(r.Mod.Path), r.Mod.Version})
+		c := r.Comments
+		if r.Indirect {
+			c.Suffix = "indirect"
+		}
+		reqc = append(reqc, c)
+	}
+	writeBlock(&buf, "require", req, reqc)
+
+	var exc [][]string
+	var excc []Comments
+	for _, x := range f.Exclude {
+		exc = append(exc, []string{quote(x.Mod.Path), x.Mod.Version})
+		excc = append(excc, x.Comments)
+	}
+	writeBlock(&buf, "exclude", exc, excc)
+
+	var rep [][]string
+	var repc []Comments
+	for _, r := range f.Replace {
+		args := []string{quote(r.Old.Path)}
+		if r.Old.Version != "" {
+			args = append(args, r.Old.Version)
+		}
+		args = append(args, "=>", quote(r.New.Path))
+		if r.New.Version != "" {
+			args = append(args, r.New.Version)
+		}
+		rep = append(rep, args)
+		repc = append(repc, r.Comments)
+	}
+	writeBlock(&buf, "replace", rep, repc)
+
+	if len(f.Trailing) > 0 {
+		buf.WriteString("\n")
+		writeBefore(&buf, "", f.Trailing)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeBlock(buf *bytes.Buffer, verb string, lines [][]string, comments []Comments) {
+	if len(lines) == 0 {
+		return
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	if len(lines) == 1 {
+		writeBefore(buf, "", comments[0].Before)
+		buf.WriteString(verb + " " + strings.Join(lines[0], " "))
+		writeSuffix(buf, comments[0].Suffix)
+		return
+	}
+	buf.WriteString(verb + " (\n")
+	for i, line := range lines {
+		writeBefore(buf, "\t", comments[i].Before)
+		buf.WriteString("\t" + strings.Join(line, " "))
+		writeSuffix(buf, comments[i].Suffix)
+	}
+	buf.WriteString(")\n")
+}
+
+func writeBefore(buf *bytes.Buffer, indent string, comments []string) {
+	for _, c := range comments {
+		buf.WriteString(indent + "//" + c + "\n")
+	}
+}
+
+func writeSuffix(buf *bytes.Buffer, comment string) {
+	if comment != "" {
+		if !strings.HasPrefix(comment, " ") {
+			comment = " " + comment
+		}
+		buf.WriteString(" //" + comment)
+	}
+	buf.WriteString("\n")
+}
+
+// quote returns s, quoted if necessary to be read back as a single field.
+func quote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || r == '`' || r == '\\' {
+			return strconv.Quote(s)
+		}
+	}
+	if strings.Contains(s, "//") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,209 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var formatTests = []struct {
+	in, out string
+}{
+	{
+		`module x.y/z`,
+		"module x.y/z\n",
+	},
+	{
+		`
+// Leading comment.
+module "x.y/z" // trailing
+
+require x.y/a v1.0.0
+require (
+	// About b.
+	x.y/b v0.1.0 // indirect
+	x.y/c v2.0.0+incompatible
+)
+exclude x.y/a v0.9.0
+replace x.y/b v0.1.0 => ../b
+replace x.y/c => x.y/d v1.2.3
+// The end.
+`,
+		`// Leading comment.
+module x.y/z // trailing
+
+require (
+	x.y/a v1.0.0
+	// About b.
+	x.y/b v0.1.0 // indirect
+	x.y/c v2.0.0+incompatible
+)
+
+exclude x.y/a v0.9.0
+
+replace (
+	x.y/b v0.1.0 => ../b
+	x.y/c => x.y/d v1.2.3
+)
+
+// The end.
+`,
+	},
+	{
+		"module \"x.y/with space\"\n",
+		"module \"x.y/with space\"\n",
+	},
+	{
+		// Pseudo-versions are kept as written.
+		"module x.y/z\nrequire x.y/b v0.0.0-20180101000000-0123456789ab\nrequire x.y/a v1.2.4-0.20180601000000-ba9876543210\n",
+		`module x.y/z
+
+require (
+	x.y/b v0.0.0-20180101000000-0123456789ab
+	x.y/a v1.2.4-0.20180601000000-ba9876543210
+)
+`,
+	},
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range formatTests {
+		f, err := Parse("in", []byte(tt.in))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		out, err := f.Format()
+		if err != nil {
+			t.Errorf("Format: %v", err)
+			continue
+		}
+		if string(out) != tt.out {
+			t.Errorf("Format(%q):\nhave:\n%s\nwant:\n%s", tt.in, out, tt.out)
+			continue
+		}
+		f2, err := Parse("out", out)
+		if err != nil {
+			t.Errorf("re-Parse: %v", err)
+			continue
+		}
+		out2, _ := f2.Format()
+		if string(out2) != string(out) {
+			t.Errorf("Format not idempotent:\nhave:\n%s\nwant:\n%s", out2, out)
+		}
+	}
+}
+
+// TestRoundTrip checks that formatting a parsed file and parsing
+// the result again yields the same statements.
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range formatTests {
+		f, err := Parse("in", []byte(tt.in))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		out, err := f.Format()
+		if err != nil {
+			t.Errorf("Format: %v", err)
+			continue
+		}
+		f2, err := Parse("out", out)
+		if err != nil {
+			t.Errorf("re-Parse(%q): %v", out, err)
+			continue
+		}
+		for _, x := range []struct {
+			name       string
+			have, want interface{}
+		}{
+			{"Module", f2.Module, f.Module},
+			{"Require", f2.Require, f.Require},
+			{"Exclude", f2.Exclude, f.Exclude},
+			{"Replace", f2.Replace, f.Replace},
+		} {
+			if !reflect.DeepEqual(x.have, x.want) {
+				t.Errorf("round trip of %q changed %s:\nhave %s\nwant %s", tt.in, x.name, dump(x.have), dump(x.want))
+			}
+		}
+	}
+}
+
+func dump(x interface{}) string {
+	js, _ := json.Marshal(x)
+	return string(js)
+}
+
+var parseErrorTests = []struct {
+	in  string
+	err string
+}{
+	{"module", "in:1: usage: module module/path"},
+	{"module x.y/z\nmodule x.y/w", "in:2: repeated module statement"},
+	{"module x.y/z\nrequire x.y/a 1.0.0", `in:2: invalid module version "1.0.0"`},
+	{"module x.y/z\nrequire x.y/a v1.0", `must be in canonical form "v1.0.0"`},
+	{"module x.y/z\nrequire x.y/a/v2 v1.0.0", "does not match module path"},
+	{"module x.y/z\nrequire (\nx.y/a v1.0.0\n", "in:2: unterminated require block"},
+	{"module x.y/z\nreplace x.y/a => x.y/b", "must be directory path"},
+	{"module x.y/z\nfrobnicate x.y/a", "unknown directive: frobnicate"},
+	{"require x.y/a v1.0.0", "no module statement"},
+	{`module "x.y/z`, "unterminated quoted string"},
+	{"module x.y/z\nrequire x.y/a v0.0.0-20180101000000-0123456789ab+build", "must be in canonical form"},
+	{"module x.y/z\nrequire x.y/a/v2 v0.0.0-20180101000000-0123456789ab", "does not match module path"},
+}
+
+func TestParseError(t *testing.T) {
+	for _, tt := range parseErrorTests {
+		_, err := Parse("in", []byte(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q): error %v, want %q", tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	f, err := Parse("in", []byte("module x.y/z\n\nrequire (\n\tx.y/b v1.0.0 // keep\n\tx.y/a v1.0.0 // indirect\n)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.AddRequire("x.y/a", "v1.1.0")
+	f.AddRequire("x.y/c", "v0.1.0")
+	f.DropRequire("x.y/b")
+	f.AddReplace("x.y/c", "", "../c", "")
+	if err := f.AddExclude("x.y/a", "v1.0.5"); err != nil {
+		t.Fatal(err)
+	}
+	f.SortBlocks()
+	out, _ := f.Format()
+	want := `module x.y/z
+
+require (
+	x.y/a v1.1.0
+	x.y/c v0.1.0
+)
+
+exclude x.y/a v1.0.5
+
+replace x.y/c => ../c
+`
+	if string(out) != want {
+		t.Errorf("have:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for _, tt := range []struct{ in, out string }{
+		{"module x.y/z\n", "x.y/z"},
+		{"// comment\nmodule \"x.y/z\" // c\nrequire a b\n", "x.y/z"},
+		{"require x.y/a v1.0.0\n", ""},
+	} {
+		if out := ModulePath([]byte(tt.in)); out != tt.out {
+			t.Errorf("ModulePath(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
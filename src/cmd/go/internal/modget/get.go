@@ -0,0 +1,431 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modget implements the module-aware ``go get'' command.
+package modget
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/work"
+)
+
+var CmdGet = &base.Command{
+	UsageLine: "get [-d] [-m] [-u] [-v] [-insecure] [build flags] [packages]",
+	Short:     "add dependencies to current module and install them",
+	Long: `
+Get resolves and adds dependencies to the current development module
+and then builds and installs them.
+
+The first step is to resolve which dependencies to add.
+
+For each named package or package pattern, get must decide which version of
+the corresponding module to use. By default, get chooses the latest tagged
+release version, such as v0.4.5 or v1.2.3. If there are no tagged release
+versions, get chooses the latest tagged prerelease version, such as
+v0.0.1-pre1. If there are no tagged versions at all, get reports an error.
+
+This default version selection can be overridden by adding an @version
+suffix to the package argument, as in 'go get golang.org/x/text@v0.3.0'.
+The version suffix can also be a version prefix or comparison:
+'go get golang.org/x/text@v0.3' and 'go get golang.org/x/text@<v0.3.1'
+are both valid. See 'go help modules' for the full query syntax.
+
+If a module under consideration is already a dependency of the current
+development module, then get will update the required version.
+Specifying a version earlier than the current required version is valid
+only if no other module in the build list requires a later version.
+The version suffix @none indicates that the dependency should be removed
+entirely, which is likewise valid only if no other module requires it.
+
+Although get defaults to using the latest version of the module containing
+a named package, it does not use the latest version of that module's
+dependencies. Instead it prefers to use the specific dependency versions
+requested by that module. For example, if the latest A requires module
+B v1.2.3, while B v1.2.4 and v1.3.1 are also available, then 'go get A'
+will use the latest A but then use B v1.2.3, as requested by A. (If there
+are competing requirements for a particular module, then 'go get' resolves
+those requirements by taking the maximum requested version.)
+
+The -u flag instructs get to update dependencies to use newer minor or
+patch releases when available. Continuing the previous example,
+'go get -u A' will use the latest A with B v1.3.1 (not B v1.2.3).
+With no package arguments, 'go get -u' updates every module in the
+build list to its latest version.
+
+In general, adding a new dependency may require upgrading
+existing dependencies to keep a working build, and 'go get' does
+this automatically. Similarly, upgrading one dependency may
+require upgrading other dependencies, and 'go get' does
+this automatically as well.
+
+The -m flag instructs get to stop here, after resolving, upgrading,
+and downgrading modules and updating go.mod. When using -m,
+each specified package path must be a module path as well,
+not the import path of a package below the module root.
+
+The -insecure flag is accepted for compatibility with GOPATH mode
+and has no effect: modules are always fetched from the proxy
+named by GOPROXY. See 'go help goproxy'.
+
+The second step is to download (if needed), build, and install
+the named packages.
+
+If an argument names a module but not a package (because there is no
+Go source code in the module's root directory), then the install step
+is skipped for that argument, instead of causing a build failure.
+For example 'go get golang.org/x/perf' succeeds even though there
+is no code corresponding to that import path.
+
+Package patterns are allowed but are expanded only against the modules
+already in the build list: a pattern cannot add a new module, and it cannot
+be combined with an @version suffix.
+
+The -d flag instructs get to download the source code needed to build
+the named packages, including downloading necessary dependencies,
+but not to build and install them.
+
+With no package arguments, 'go get' applies to the main module,
+and to the Go package in the current directory, if any. In particular,
+'go get -u' updates all the dependencies of the main module.
+With no package arguments and also without -u,
+'go get' is not much more than 'go install', and 'go get -d' not much
+more than 'go list'.
+
+For more about modules, see 'go help modules'.
+
+For more about specifying packages, see 'go help packages'.
+
+This text describes the behavior of get using modules to manage source
+code and dependencies. If instead the go command is running in GOPATH
+mode, the details of get's flags and effects change, as does 'go help get'.
+See 'go help modules' and 'go help gopath-get'.
+
+See also: go build, go install, go clean, go mod.
+	`,
+}
+
+// HelpModuleGet makes the module-aware get help text
+// available even when modules are not enabled.
+var HelpModuleGet = &base.Command{
+	UsageLine: "module-get",
+	Short:     "module-aware go get",
+	Long: `
+The 'go get' command changes behavior depending on whether the
+go command is running in module-aware mode or legacy GOPATH mode.
+This help text, accessible as 'go help module-get' even in legacy GOPATH mode,
+describes 'go get' as it operates in module-aware mode.
+
+Usage: ` + CmdGet.UsageLine + `
+` + CmdGet.Long,
+}
+
+var (
+	getD   = CmdGet.Flag.Bool("d", false, "")
+	getF   = CmdGet.Flag.Bool("f", false, "")
+	getFix = CmdGet.Flag.Bool("fix", false, "")
+	getM   = CmdGet.Flag.Bool("m", false, "")
+	getT   = CmdGet.Flag.Bool("t", false, "")
+	getU   = CmdGet.Flag.Bool("u", false, "")
+	_      = CmdGet.Flag.Bool("insecure", false, "")
+	// -v is cfg.BuildV
+)
+
+func init() {
+	work.AddBuildFlags(CmdGet)
+	CmdGet.Run = runGet // break init loop
+}
+
+// A query describes a command-line argument and the module version
+// it resolves to.
+type query struct {
+	arg  string         // the original argument
+	path string         // the import path or module path
+	vers string         // the version query
+	m    module.Version // the resolved module
+	pkg  bool           // path names a package, not just a module
+}
+
+func runGet(cmd *base.Command, args []string) {
+	work.BuildInit()
+	if *getF {
+		fmt.Fprintf(os.Stderr, "go get: -f flag is a no-op when using modules\n")
+	}
+	if *getFix {
+		fmt.Fprintf(os.Stderr, "go get: -fix flag is a no-op when using modules\n")
+	}
+	if *getT {
+		fmt.Fprintf(os.Stderr, "go get: -t flag is a no-op when using modules\n")
+	}
+	if *getM && *getD {
+		base.Fatalf("go get: cannot use -m with -d")
+	}
+
+	modload.LoadBuildList()
+
+	// Parse the arguments and resolve each to a module version.
+	var queries []*query
+	var install []string // packages to install
+	for _, arg := range args {
+		path, vers := arg, "latest"
+		if i := strings.Index(arg, "@"); i >= 0 {
+			path, vers = arg[:i], arg[i+1:]
+			if vers == "" {
+				base.Errorf("go get %s: missing version after @", arg)
+				continue
+			}
+		}
+
+		if build.IsLocalImport(path) {
+			if path != arg {
+				base.Errorf("go get %s: cannot use path@version syntax for a local directory", arg)
+				continue
+			}
+			// A local package or pattern is in the main module:
+			// there is no module to upgrade, only packages to install.
+			install = append(install, path)
+			continue
+		}
+		if strings.Contains(path, "...") {
+			if path != arg {
+				base.Errorf("go get %s: cannot use path@version syntax with a package pattern", arg)
+				continue
+			}
+			install = append(install, path)
+			continue
+		}
+		if path == modload.Target.Path {
+			if vers != "latest" {
+				base.Errorf("go get %s: cannot change the version of the main module", arg)
+			}
+			install = append(install, path)
+			continue
+		}
+		if err := module.CheckPath(path); err != nil && *getM {
+			base.Errorf("go get %s: %v", arg, err)
+			continue
+		}
+		queries = append(queries, &query{arg: arg, path: path, vers: vers})
+	}
+	base.ExitIfErrors()
+
+	for _, q := range queries {
+		if q.vers == "none" {
+			q.m = module.Version{Path: q.path, Version: "none"}
+			continue
+		}
+		// First try the argument as a module path.
+		info, err := modload.Query(q.path, q.vers, modload.Allowed)
+		if err == nil {
+			q.m = module.Version{Path: q.path, Version: info.Version}
+			q.pkg = hasPackage(q.m, q.path)
+			continue
+		}
+		if *getM {
+			base.Errorf("go get %s: %v", q.arg, err)
+			continue
+		}
+		// Otherwise the argument is a package in some module.
+		m, _, err := modload.QueryPackage(q.path, q.vers, modload.Allowed)
+		if err != nil {
+			base.Errorf("go get %s: %v", q.arg, err)
+			continue
+		}
+		q.m = m
+		q.pkg = true
+	}
+	base.ExitIfErrors()
+
+	// Decide on the new requirements of the main module:
+	// the requested versions, plus the latest versions
+	// of the modules to update with -u.
+	want := make(map[string]string) // module path → version
+	for _, q := range queries {
+		if old, ok := want[q.m.Path]; ok && old != q.m.Version {
+			base.Fatalf("go get: conflicting versions for module %s: %s and %s", q.m.Path, old, q.m.Version)
+		}
+		want[q.m.Path] = q.m.Version
+	}
+	if *getU {
+		for _, path := range upgradePaths(queries) {
+			if _, ok := want[path]; ok {
+				continue
+			}
+			info, err := modload.Query(path, "latest", modload.Allowed)
+			if err != nil {
+				base.Errorf("go get: upgrading %s: %v", path, err)
+				continue
+			}
+			want[path] = info.Version
+		}
+		base.ExitIfErrors()
+	}
+
+	// Apply the new requirements and recompute the build list.
+	reqs := newReqs(modload.BuildList()[1:], want, queries, *getU)
+	modload.SetBuildList(append([]module.Version{modload.Target}, reqs...))
+	modload.ReloadBuildList()
+
+	// Check that the selected versions are the requested ones.
+	selected := make(map[string]string)
+	for _, m := range modload.BuildList() {
+		selected[m.Path] = m.Version
+	}
+	for _, q := range queries {
+		v, ok := selected[q.m.Path]
+		switch {
+		case q.m.Version == "none" && ok:
+			base.Errorf("go get %s: module %s is still required by other modules", q.arg, q.m.Path)
+		case q.m.Version != "none" && v != q.m.Version:
+			base.Errorf("go get %s: module %s is required at %s by other modules; cannot use %s", q.arg, q.m.Path, v, q.m.Version)
+		}
+	}
+	base.ExitIfErrors()
+
+	modload.WriteGoMod()
+	if *getM {
+		return
+	}
+
+	// Install (or with -d, just download and load) the named packages.
+	for _, q := range queries {
+		if q.pkg {
+			install = append(install, q.path)
+		}
+	}
+	if len(args) == 0 {
+		install = []string{"."}
+		if dir, _ := os.Getwd(); !hasGoFilesIn(dir) {
+			install = nil
+		}
+	}
+	if len(install) == 0 {
+		return
+	}
+	if *getD {
+		// Loading the packages downloads their modules and
+		// any modules needed for their imports.
+		load.PackagesAndErrors(install)
+		modload.WriteGoMod()
+		return
+	}
+	work.InstallPackages(install, true)
+}
+
+// newReqs returns the new requirements of the main module, given its
+// current build list (excluding the main module itself) and the wanted
+// version of each module to add, change or, with version "none", drop.
+// When upgrading with -u, a module that was not itself queried
+// is never downgraded. Modules new to the build list are added
+// in path order.
+func newReqs(list []module.Version, want map[string]string, queries []*query, upgrade bool) []module.Version {
+	added := make(map[string]bool)
+	for path := range want {
+		added[path] = true
+	}
+	var reqs []module.Version
+	for _, m := range list {
+		if v, ok := want[m.Path]; ok {
+			delete(added, m.Path)
+			if v == "none" {
+				continue
+			}
+			if upgrade && semver.Compare(v, m.Version) < 0 && !isQueried(queries, m.Path) {
+				// Never let -u downgrade a module.
+				v = m.Version
+			}
+			m.Version = v
+		}
+		reqs = append(reqs, m)
+	}
+	var paths []string
+	for path := range added {
+		if want[path] != "none" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		reqs = append(reqs, module.Version{Path: path, Version: want[path]})
+	}
+	return reqs
+}
+
+// hasPackage reports whether the module m has a package with import path path.
+func hasPackage(m module.Version, path string) bool {
+	_, ok, err := modload.ModulePackageDir(m, path)
+	return err == nil && ok
+}
+
+// hasGoFilesIn reports whether dir contains any .go files.
+func hasGoFilesIn(dir string) bool {
+	bp, err := cfg.BuildContext.ImportDir(dir, 0)
+	if err != nil {
+		_, noGo := err.(*build.NoGoError)
+		return !noGo && bp != nil && len(bp.InvalidGoFiles) > 0
+	}
+	return true
+}
+
+// upgradePaths returns the module paths that -u should upgrade:
+// with no queries, every module in the build list;
+// otherwise, the modules required, directly or indirectly,
+// by the queried modules.
+func upgradePaths(queries []*query) []string {
+	list := modload.BuildList()
+	if len(queries) == 0 {
+		var paths []string
+		for _, m := range list[1:] {
+			paths = append(paths, m.Path)
+		}
+		return paths
+	}
+
+	reqs := modload.Reqs()
+	seen := make(map[module.Version]bool)
+	var paths []string
+	var walk func(module.Version)
+	walk = func(m module.Version) {
+		if seen[m] {
+			return
+		}
+		seen[m] = true
+		deps, err := reqs.Required(m)
+		if err != nil {
+			base.Errorf("go get: %v", err)
+			return
+		}
+		for _, r := range deps {
+			if !seen[r] {
+				paths = append(paths, r.Path)
+				walk(r)
+			}
+		}
+	}
+	for _, q := range queries {
+		if q.m.Version != "none" {
+			walk(q.m)
+		}
+	}
+	return paths
+}
+
+func isQueried(queries []*query, path string) bool {
+	for _, q := range queries {
+		if q.m.Path == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modget
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+)
+
+const pseudoAfter = "v1.2.4-0.20180601000000-ba9876543210"
+
+var newReqsTests = []struct {
+	name    string
+	list    string // current build list, "path@version ..."
+	want    map[string]string
+	queried string // paths named on the command line
+	upgrade bool
+	reqs    string
+}{
+	{
+		name: "add",
+		list: "a@v1.0.0",
+		want: map[string]string{"c": "v1.0.0", "b": "v1.1.0"},
+		reqs: "a@v1.0.0 b@v1.1.0 c@v1.0.0",
+	},
+	{
+		name:    "downgrade",
+		list:    "a@v1.0.0 b@v1.2.0",
+		want:    map[string]string{"b": "v1.1.0"},
+		queried: "b",
+		reqs:    "a@v1.0.0 b@v1.1.0",
+	},
+	{
+		name:    "downgrade with -u",
+		list:    "a@v1.0.0 b@v1.2.0",
+		want:    map[string]string{"b": "v1.1.0"},
+		queried: "b",
+		upgrade: true,
+		reqs:    "a@v1.0.0 b@v1.1.0",
+	},
+	{
+		name:    "-u never downgrades",
+		list:    "a@v1.0.0 b@v1.2.0",
+		want:    map[string]string{"a": "v1.1.0", "b": "v1.1.0"},
+		queried: "a",
+		upgrade: true,
+		reqs:    "a@v1.1.0 b@v1.2.0",
+	},
+	{
+		name:    "-u keeps newer pseudo-version",
+		list:    "a@" + pseudoAfter,
+		want:    map[string]string{"a": "v1.2.3"},
+		upgrade: true,
+		reqs:    "a@" + pseudoAfter,
+	},
+	{
+		name:    "-u upgrades pseudo-version",
+		list:    "a@" + pseudoAfter,
+		want:    map[string]string{"a": "v1.2.4"},
+		upgrade: true,
+		reqs:    "a@v1.2.4",
+	},
+	{
+		name:    "pseudo-version",
+		list:    "a@v1.2.4",
+		want:    map[string]string{"a": pseudoAfter},
+		queried: "a",
+		reqs:    "a@" + pseudoAfter,
+	},
+	{
+		name:    "none",
+		list:    "a@v1.0.0 b@v1.2.0",
+		want:    map[string]string{"a": "none", "c": "none"},
+		queried: "a c",
+		reqs:    "b@v1.2.0",
+	},
+}
+
+func parseList(s string) []module.Version {
+	var list []module.Version
+	for _, f := range strings.Fields(s) {
+		i := strings.Index(f, "@")
+		list = append(list, module.Version{Path: f[:i], Version: f[i+1:]})
+	}
+	return list
+}
+
+func TestNewReqs(t *testing.T) {
+	for _, tt := range newReqsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []*query
+			for _, path := range strings.Fields(tt.queried) {
+				queries = append(queries, &query{m: module.Version{Path: path, Version: tt.want[path]}})
+			}
+			reqs := newReqs(parseList(tt.list), tt.want, queries, tt.upgrade)
+			if want := parseList(tt.reqs); !reflect.DeepEqual(reqs, want) {
+				t.Errorf("newReqs = %v, want %v", reqs, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/modinfo"
+	"cmd/go/internal/module"
+	"cmd/go/internal/mvs"
+	"cmd/go/internal/semver"
+)
+
+// buildList is the list of modules to use for building packages.
+// InitMod sets it to the main module followed by its direct requirements;
+// ReloadBuildList replaces that with the result of MVS.
+var buildList []module.Version
+
+// LoadBuildList loads and returns the build list from go.mod.
+// The loading of the build list happens automatically in ImportPaths:
+// LoadBuildList need only be called if ImportPaths is not
+// (typically in commands that care about the module but
+// no particular package).
+func LoadBuildList() []module.Version {
+	InitMod()
+	if !buildListLoaded {
+		ReloadBuildList()
+	}
+	return buildList
+}
+
+// buildListLoaded reports whether buildList holds the result of
+// MVS rather than just the requirements listed in go.mod.
+var buildListLoaded bool
+
+// ReloadBuildList recomputes the build list from the requirements
+// of the main module, using the current buildList as the
+// main module's requirements.
+func ReloadBuildList() []module.Version {
+	InitMod()
+	if cfg.BuildMod == "vendor" {
+		readVendorList()
+		buildList = append([]module.Version{Target}, vendorList...)
+		buildListLoaded = true
+		return buildList
+	}
+	list, err := mvs.BuildList(Target, Reqs())
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	buildList = list
+	buildListLoaded = true
+	return buildList
+}
+
+// BuildList returns the module build list,
+// typically constructed by a previous call to
+// LoadBuildList or ImportPaths.
+// The caller must not modify the returned list.
+func BuildList() []module.Version {
+	return buildList
+}
+
+// SetBuildList sets the module build list.
+// The caller is responsible for ensuring that the list is valid.
+// SetBuildList does not retain a reference to the original list.
+func SetBuildList(list []module.Version) {
+	buildList = append([]module.Version{}, list...)
+	buildListLoaded = true
+}
+
+// Reqs returns the current module requirement graph.
+// Future calls to SetBuildList do not affect the operation
+// of the returned Reqs.
+func Reqs() mvs.Reqs {
+	r := &mvsReqs{
+		buildList: buildList,
+		cache:     make(map[module.Version][]module.Version),
+	}
+	return r
+}
+
+// directPaths returns the set of module paths providing packages
+// imported directly by the main module, as far as is known:
+// the result of the last package load if there was one,
+// and otherwise the requirements not marked "// indirect" in go.mod.
+func directPaths() map[string]bool {
+	if loaded != nil {
+		return loaded.direct
+	}
+	direct := make(map[string]bool)
+	if modFile != nil {
+		for _, r := range modFile.Require {
+			if !r.Indirect {
+				direct[r.Mod.Path] = true
+			}
+		}
+	}
+	return direct
+}
+
+// MinReqs returns a Reqs with minimal dependencies of Target,
+// as will be written to go.mod.
+func MinReqs() mvs.Reqs {
+	isDirect := directPaths()
+	var direct []string
+	for _, m := range buildList[1:] {
+		if isDirect[m.Path] {
+			direct = append(direct, m.Path)
+		}
+	}
+	min, err := mvs.Req(Target, buildList, direct, Reqs())
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	return &mvsReqs{buildList: append([]module.Version{Target}, min...)}
+}
+
+// An mvsReqs implements mvs.Reqs for module semantic versions,
+// with any exclusions or replacements applied internally.
+type mvsReqs struct {
+	buildList []module.Version
+	cache     map[module.Version][]module.Version
+}
+
+func (r *mvsReqs) Required(mod module.Version) ([]module.Version, error) {
+	if mod.Version == "none" {
+		return nil, nil
+	}
+	if list, ok := r.cache[mod]; ok {
+		return list, nil
+	}
+
+	var list []module.Version
+	if mod == Target {
+		// The main module's requirements are the rest of the build list.
+		list = append(list, r.buildList[1:]...)
+	} else {
+		var err error
+		list, err = r.required(mod)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Apply exclusions, moving excluded versions up to the
+	// next version that is allowed.
+	for i, m := range list {
+		for excluded[m] {
+			next, err := r.next(m)
+			if err != nil {
+				return nil, err
+			}
+			if next.Version == "none" {
+				return nil, fmt.Errorf("%s requires %s, but all later versions are excluded", mod, m)
+			}
+			m = next
+		}
+		list[i] = m
+	}
+	if r.cache != nil {
+		r.cache[mod] = list
+	}
+	return list, nil
+}
+
+// required returns the requirements listed in the go.mod file
+// for mod, after applying any replacement.
+func (r *mvsReqs) required(mod module.Version) ([]module.Version, error) {
+	origPath := mod.Path
+	var data []byte
+	if repl := Replacement(mod); repl.Path != "" {
+		if repl.Version == "" {
+			dir := repl.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(ModRoot(), dir)
+			}
+			gomod := filepath.Join(dir, "go.mod")
+			var err error
+			data, err = ioutil.ReadFile(gomod)
+			if os.IsNotExist(err) {
+				// A replacement directory without a go.mod
+				// has no requirements.
+				return nil, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %v", base.ShortPath(gomod), err)
+			}
+			f, err := modfile.Parse(gomod, data)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %v", base.ShortPath(gomod), err)
+			}
+			return requireList(f), nil
+		}
+		mod = repl
+	}
+
+	data, err := modfetch.GoMod(mod.Path, mod.Version)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse("go.mod", data)
+	if err != nil {
+		return nil, fmt.Errorf("%s@%s: parsing go.mod: %v", mod.Path, mod.Version, err)
+	}
+	if f.Module == nil {
+		return nil, fmt.Errorf("%s@%s: parsing go.mod: missing module line", mod.Path, mod.Version)
+	}
+	if mpath := f.Module.Mod.Path; mpath != origPath && mpath != mod.Path {
+		return nil, fmt.Errorf("%s@%s: parsing go.mod: unexpected module path %q", mod.Path, mod.Version, mpath)
+	}
+	return requireList(f), nil
+}
+
+func requireList(f *modfile.File) []module.Version {
+	var list []module.Version
+	for _, r := range f.Require {
+		list = append(list, r.Mod)
+	}
+	return list
+}
+
+func (*mvsReqs) Max(v1, v2 string) string {
+	if v1 != "" && semver.Compare(v1, v2) == -1 {
+		return v2
+	}
+	return v1
+}
+
+// next returns the next version of m.Path after m.Version.
+// It returns "none" as the version if there is no next version.
+func (*mvsReqs) next(m module.Version) (module.Version, error) {
+	list, err := modfetch.Lookup(m.Path).Versions("")
+	if err != nil {
+		return module.Version{}, err
+	}
+	i := 0
+	for ; i < len(list) && semver.Compare(list[i], m.Version) <= 0; i++ {
+	}
+	if i < len(list) {
+		return module.Version{Path: m.Path, Version: list[i]}, nil
+	}
+	return module.Version{Path: m.Path, Version: "none"}, nil
+}
+
+// Replacement returns the replacement for mod, if any, from go.mod.
+// If there is no replacement for mod, Replacement returns
+// a module.Version with Path == "".
+func Replacement(mod module.Version) module.Version {
+	if modFile == nil {
+		// Happens during testing.
+		return module.Version{}
+	}
+
+	var found *modfile.Replace
+	for _, r := range modFile.Replace {
+		if r.Old.Path == mod.Path && (r.Old.Version == "" || r.Old.Version == mod.Version) {
+			found = r // keep going
+		}
+	}
+	if found == nil {
+		return module.Version{}
+	}
+	return found.New
+}
+
+// PackageModuleInfo returns information about the module
+// providing the package named by the import path.
+func PackageModuleInfo(pkgpath string) *modinfo.ModulePublic {
+	if isStandardImportPath(pkgpath) || !Enabled() {
+		return nil
+	}
+	m := findModule(pkgpath, pkgpath)
+	if m.Path == "" {
+		return nil
+	}
+	return moduleInfo(m, true)
+}
+
+// ModuleInfo returns information about the module with the given path
+// in the build list, or an error-bearing ModulePublic if there is none.
+func ModuleInfo(path string) *modinfo.ModulePublic {
+	if !Enabled() {
+		return nil
+	}
+
+	for _, m := range BuildList() {
+		if m.Path == path {
+			return moduleInfo(m, true)
+		}
+	}
+
+	return &modinfo.ModulePublic{
+		Path: path,
+		Error: &modinfo.ModuleError{
+			Err: "module not in current build",
+		},
+	}
+}
+
+func moduleInfo(m module.Version, fromBuildList bool) *modinfo.ModulePublic {
+	if m == Target {
+		info := &modinfo.ModulePublic{
+			Path:    m.Path,
+			Version: m.Version,
+			Main:    true,
+		}
+		if HasModRoot() {
+			info.Dir = ModRoot()
+			info.GoMod = filepath.Join(info.Dir, "go.mod")
+		}
+		return info
+	}
+
+	info := &modinfo.ModulePublic{
+		Path:     m.Path,
+		Version:  m.Version,
+		Indirect: fromBuildList && !directPaths()[m.Path],
+	}
+
+	if cfg.BuildMod == "vendor" {
+		info.Dir = filepath.Join(ModRoot(), "vendor", m.Path)
+		return info
+	}
+
+	// complete fills in the extra fields in m.
+	complete := func(m *modinfo.ModulePublic) {
+		if m.Version != "" {
+			if q, err := modfetch.Lookup(m.Path).Stat(m.Version); err != nil {
+				m.Error = &modinfo.ModuleError{Err: err.Error()}
+			} else {
+				m.Version = q.Version
+				m.Time = &q.Time
+			}
+
+			mod := module.Version{Path: m.Path, Version: m.Version}
+			if gomod, err := modfetch.CachePath(mod, "mod"); err == nil {
+				if info, err := os.Stat(gomod); err == nil && info.Mode().IsRegular() {
+					m.GoMod = gomod
+				}
+			}
+			if dir, err := modfetch.DownloadDir(mod); err == nil {
+				if info, err := os.Stat(dir); err == nil && info.IsDir() {
+					m.Dir = dir
+				}
+			}
+		}
+	}
+
+	if !fromBuildList {
+		complete(info)
+		return info
+	}
+
+	r := Replacement(m)
+	if r.Path == "" {
+		complete(info)
+		return info
+	}
+
+	// Don't hit the network to fill in extra data for replaced modules:
+	// the Dir and GoMod fields come from the replacement anyway.
+	info.Replace = &modinfo.ModulePublic{
+		Path:    r.Path,
+		Version: r.Version,
+	}
+	if r.Version == "" {
+		if filepath.IsAbs(r.Path) {
+			info.Replace.Dir = r.Path
+		} else {
+			info.Replace.Dir = filepath.Join(ModRoot(), r.Path)
+		}
+		info.Replace.GoMod = filepath.Join(info.Replace.Dir, "go.mod")
+	} else {
+		complete(info.Replace)
+	}
+	info.Dir = info.Replace.Dir
+	info.GoMod = info.Replace.GoMod
+	return info
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"reflect"
+	"testing"
+
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/module"
+)
+
+var maxTests = []struct {
+	v1, v2 string
+	want   string
+}{
+	{"v1.0.0", "v1.1.0", "v1.1.0"},
+	{"v1.1.0", "v1.0.0", "v1.1.0"},
+	{"v1.1.0-beta", "v1.1.0", "v1.1.0"},
+	{"none", "v1.0.0", "v1.0.0"},
+	{"v1.0.0", "none", "v1.0.0"},
+	// The empty version, that of the main module, is the maximum.
+	{"", "v1.0.0", ""},
+	{pseudoBase, "v0.1.0", "v0.1.0"},
+	{"v1.2.3", pseudoAfter, pseudoAfter},
+	{pseudoAfter, "v1.2.4", "v1.2.4"},
+}
+
+func TestMvsReqsMax(t *testing.T) {
+	r := new(mvsReqs)
+	for _, tt := range maxTests {
+		if got := r.Max(tt.v1, tt.v2); got != tt.want {
+			t.Errorf("Max(%q, %q) = %q, want %q", tt.v1, tt.v2, got, tt.want)
+		}
+	}
+}
+
+func TestRequireList(t *testing.T) {
+	f, err := modfile.Parse("go.mod", []byte(`
+		module example.com/m
+		require (
+			example.com/a v1.0.0
+			example.com/b `+pseudoAfter+`
+		)
+		exclude example.com/a v1.1.0
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []module.Version{
+		{Path: "example.com/a", Version: "v1.0.0"},
+		{Path: "example.com/b", Version: pseudoAfter},
+	}
+	if list := requireList(f); !reflect.DeepEqual(list, want) {
+		t.Errorf("requireList = %v, want %v", list, want)
+	}
+
+	// Dropping a module with "none" leaves it without requirements.
+	if list, err := new(mvsReqs).Required(module.Version{Path: "example.com/a", Version: "none"}); list != nil || err != nil {
+		t.Errorf("Required(none) = %v, %v; want nil, nil", list, err)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import "cmd/go/internal/base"
+
+var HelpModules = &base.Command{
+	UsageLine: "modules",
+	Short:     "modules, module versions, and more",
+	Long: `
+A module is a collection of related Go packages.
+Modules are the unit of source code interchange and versioning.
+The go command has direct support for working with modules,
+including recording and resolving dependencies on other modules.
+Modules replace the old GOPATH-based approach to specifying
+which source files are used in a given build.
+
+Preliminary module support
+
+The go command can be run in module-aware mode or in GOPATH mode.
+The GO111MODULE environment variable selects between them.
+
+If GO111MODULE=off, the go command never uses module support.
+It looks in vendor directories and GOPATH to find dependencies,
+as it always has.
+
+If GO111MODULE=on, the go command requires the use of modules,
+never consulting GOPATH to find dependencies. We refer to this as
+the command being module-aware or running in "module-aware mode".
+
+If GO111MODULE=auto or is unset, the go command enables or disables
+module support based on the current directory. Module support is
+enabled only when the current directory is outside GOPATH/src and
+itself contains a go.mod file or is below a directory containing
+a go.mod file.
+
+In module-aware mode, GOPATH no longer defines the meaning of imports
+during a build, but it still stores downloaded dependencies (in
+GOPATH/pkg/mod) and installed commands (in GOPATH/bin, unless GOBIN
+is set).
+
+Defining a module
+
+A module is defined by a tree of Go source files with a go.mod file
+in the tree's root directory. The directory containing the go.mod file
+is called the module root. Typically the module root will also
+correspond to a source code repository root (but in general it need
+not). The module is the set of all Go packages in the module root and
+its subdirectories, but excluding subtrees with their own go.mod files.
+
+The "module path" is the import path prefix corresponding to the
+module root. The go.mod file defines the module path and lists the
+specific versions of other modules that should be used when resolving
+imports during a build, by giving their module paths and versions.
+
+For example, this go.mod declares that the directory containing it
+is the root of the module with path example.com/m, and it also
+declares that the module depends on specific versions of
+golang.org/x/text and gopkg.in/yaml.v2:
+
+	module example.com/m
+
+	require (
+		golang.org/x/text v0.3.0
+		gopkg.in/yaml.v2 v2.1.0
+	)
+
+The go.mod file can also specify replacements and excluded versions
+that only apply when building the module directly; they are ignored
+when the module is incorporated into a larger build.
+For more about the go.mod file, see 'go help go.mod'.
+
+To start a new module, simply create a go.mod file in the root of the
+module's directory tree, containing only a module statement.
+The 'go mod init' command can be used to do this:
+
+	go mod init example.com/m
+
+The main module and the build list
+
+The "main module" is the module containing the directory where the
+go command is run. The go command finds the module root by looking
+for a go.mod in the current directory, or else the current directory's
+parent directory, or else the parent's parent directory, and so on.
+
+The main module's go.mod file defines the precise set of packages
+available for use by the go command, through require, replace, and
+exclude statements. Dependency modules, found by following require
+statements, also contribute to the definition of that set of packages,
+but only through their go.mod files' require statements: any replace
+and exclude statements in dependency modules are ignored.
+
+The set of modules providing packages to builds is called the
+"build list". The build list initially contains only the main module.
+Then the go command adds to the list the exact module versions
+required by modules already on the list, recursively, until there
+is nothing left to add to the list. If multiple versions of a
+particular module are added to the list, then at the end only the
+latest version (according to semantic version ordering) is kept
+for use in the build. This algorithm is called minimal version
+selection.
+
+The 'go list' command provides information about the main module
+and the build list. For example:
+
+	go list -m              # print path of main module
+	go list -m all          # print build list
+
+Maintaining module requirements
+
+The go.mod file is meant to be readable and editable by both
+programmers and tools. The go command itself automatically updates
+the go.mod file to maintain a standard formatting and the accuracy
+of require statements.
+
+Any go command that finds an unfamiliar import will look up the
+module containing that import and add the latest version of that
+module to go.mod automatically. In most cases, therefore, it suffices
+to add an import to source code and run 'go build', 'go test', or
+even 'go list': as part of analyzing the package, the go command will
+discover and resolve the import and update the go.mod file.
+
+Any go command can determine that a module requirement is missing
+and must be added, even when considering only a single package
+from the module. On the other hand, determining that a module
+requirement is no longer necessary and can be deleted requires a
+full view of all packages in the module, across all possible build
+configurations (architectures, operating systems, build tags, and
+so on). The 'go mod tidy' command builds that view and then adds
+any missing module requirements and removes unnecessary ones.
+
+As part of maintaining the require statements in go.mod, the go
+command tracks which ones provide packages imported directly by the
+current module and which ones provide packages only used indirectly
+by other module dependencies. Requirements needed only for indirect
+uses are marked with a "// indirect" comment in the go.mod file.
+
+Because the module graph defines the meaning of import statements,
+any commands that load packages also use and therefore update go.mod,
+including go build, go get, go install, go list, go test, go mod graph,
+go mod tidy, and go mod why.
+
+The -mod=readonly flag disables the automatic updating of go.mod:
+any command that would need to change go.mod fails instead.
+
+The 'go get' command updates go.mod to change the module versions
+used in a build. An upgrade of one module may imply upgrading others,
+because the new version may require newer versions of its dependencies.
+See 'go help module-get' for details.
+
+Module queries
+
+The go command accepts a "module query" in place of a module version
+on the command line, as in 'go get path@query'.
+
+A fully-specified semantic version, such as "v1.2.3",
+evaluates to that specific version.
+
+A semantic version prefix, such as "v1" or "v1.2",
+evaluates to the latest available tagged version with that prefix.
+
+A semantic version comparison, such as "<v1.2.3" or ">=v1.5.6",
+evaluates to the available tagged version nearest to the comparison target
+(the latest version for < and <=, the earliest version for > and >=).
+
+The string "latest" matches the latest available tagged version.
+
+All queries prefer release versions to pre-release versions.
+For example, "<v1.2.3" will prefer to return "v1.2.2"
+instead of "v1.2.3-pre1", even though "v1.2.3-pre1" is nearer
+to the comparison target.
+
+Module versions disallowed by exclude statements in the
+main module's go.mod are considered unavailable and cannot
+be returned by queries.
+
+For example, these commands are all valid:
+
+	go get github.com/gorilla/mux@latest    # same (@latest is default for 'go get')
+	go get github.com/gorilla/mux@v1.6.2    # records v1.6.2
+	go get github.com/gorilla/mux@v1        # records latest v1.x.x
+	go get github.com/gorilla/mux@'<v1.6.2' # records v1.6.1
+
+Module compatibility and semantic versioning
+
+The go command requires that modules use semantic versions and
+expects that the versions accurately describe compatibility:
+it assumes that v1.5.4 is a backwards-compatible replacement
+for v1.5.3, v1.4.0, and even v1.0.0. More generally the go command
+expects that packages follow the "import compatibility rule",
+which says:
+
+"If an old package and a new package have the same import path,
+the new package must be backwards compatible with the old package."
+
+Because the go command assumes the import compatibility rule,
+a module definition can only set the minimum required version
+of one of its dependencies: it cannot set a maximum or exclude
+selected versions. Still, the import compatibility rule is not a
+guarantee: it may be that v1.5.4 is buggy and not a backwards-compatible
+replacement for v1.5.3. Because of this, the go command never updates
+from an older version to a newer version of a module unasked.
+
+In semantic versioning, changing the major version number indicates
+a lack of backwards compatibility with earlier versions. To preserve
+import compatibility, the go command requires that modules with major
+version v2 or later use a module path with that major version as the
+final element. For example, version v2.0.0 of example.com/m must
+instead use module path example.com/m/v2, and packages in that module
+would use that path as their import path prefix, as in example.com/m/v2/sub/pkg.
+Including the major version number in the module path and import paths
+in this way is called "semantic import versioning".
+
+As a special case, for historical reasons, module paths beginning with
+gopkg.in/ continue to use the conventions established on that system:
+the major version is always present, and it is preceded by a dot
+instead of a slash: gopkg.in/yaml.v1 and gopkg.in/yaml.v2, not
+gopkg.in/yaml and gopkg.in/yaml/v2.
+
+Module downloading
+
+The go command downloads modules from the module proxy named by the
+GOPROXY environment variable, which may be a file:// URL naming a
+local directory tree. Fetching modules directly from version control
+systems is not supported. See 'go help goproxy' for details about the
+proxy protocol and the layout of the module cache.
+
+Downloaded modules are kept, read-only, in the module cache,
+GOPATH/pkg/mod. Because the cache uses the same layout as a proxy,
+setting GOPROXY=off allows builds to proceed offline using only
+modules already in the cache, and a copy of the cache can itself
+serve as a file:// proxy for other machines.
+
+Module authentication using go.sum
+
+The go command tries to authenticate every downloaded module,
+checking that the bits downloaded for a specific module version today
+match bits downloaded yesterday. This ensures repeatable builds
+and detects introduction of unexpected changes, malicious or not.
+
+In each module's root, alongside go.mod, the go command maintains
+a file named go.sum containing the cryptographic checksums of the
+module's dependencies.
+
+The form of each line in go.sum is three fields:
+
+	<module> <version>[/go.mod] <hash>
+
+Each known module version results in two lines in the go.sum file.
+The first line gives the hash of the module version's file tree.
+The second line appends "/go.mod" to the version and gives the hash
+of only the module version's (possibly synthesized) go.mod file.
+The go.mod-only hash allows downloading and authenticating a
+module version's go.mod file, which is needed to compute the
+dependency graph, without also downloading all the module's source code.
+
+The hash begins with an algorithm prefix of the form "h<N>:".
+The only defined algorithm prefix is "h1:", which uses SHA-256.
+
+If a go.sum line does not match the downloaded module or go.mod file,
+the go command reports a "checksum mismatch" error and stops.
+The 'go mod verify' command checks that the cached copies of module
+downloads still match both their recorded checksums and the entries
+in go.sum.
+
+Modules and vendoring
+
+When using modules, the go command completely ignores vendor directories.
+
+By default, the go command satisfies dependencies by downloading modules
+from their sources and using those downloaded copies (after verification,
+as described in the previous section). To allow interoperation with older
+versions of Go, or to ensure that all files used for a build are stored
+together in a single file tree, 'go mod vendor' creates a directory named
+vendor in the root directory of the main module and stores there all the
+packages from dependency modules that are needed to support builds and
+tests of packages in the main module.
+
+To build using the main module's top-level vendor directory to satisfy
+dependencies (disabling use of the usual network sources and local
+caches), use 'go build -mod=vendor'. Note that only the main module's
+top-level vendor directory is used; vendor directories in other locations
+are still ignored.
+	`,
+}
+
+var HelpGoMod = &base.Command{
+	UsageLine: "go.mod",
+	Short:     "the go.mod file",
+	Long: `
+A module version is defined by a tree of source files, with a go.mod
+file in its root. When the go command is run, it looks in the current
+directory and then successive parent directories to find the go.mod
+marking the root of the main (current) module.
+
+The go.mod file itself is line-oriented, with // comments but
+no /* */ comments. Each line holds a single directive, made up of a
+verb followed by arguments. For example:
+
+	module my/thing
+	require other/thing v1.0.2
+	require new/thing/v2 v2.3.4
+	exclude old/thing v1.2.3
+	replace bad/thing v1.4.5 => good/thing v1.4.5
+
+The verbs are module, to define the module path; require, to require
+a particular module at a given version or later; exclude, to exclude
+a particular module version from use; and replace, to replace a module
+version with a different module version. Exclude and replace apply only
+in the main module's go.mod and are ignored in dependencies.
+See 'go help modules' for details.
+
+The leading verb can be factored out of adjacent lines to create a block,
+like in Go imports:
+
+	require (
+		new/thing v2.3.4
+		old/thing v1.2.3
+	)
+
+The go.mod file is designed both to be edited directly and to be
+easily updated by tools. Go commands that find or add requirements
+rewrite go.mod in a standard format, preserving comments.
+
+The go command automatically updates go.mod each time it uses the
+module graph, to make sure go.mod always accurately reflects reality
+and is properly formatted. For example, consider this go.mod file:
+
+	module M
+
+	require (
+		A v1.0.0
+		B v1.0.0
+		C v1.0.0
+		D v1.2.3
+	)
+
+	exclude D v1.2.3
+
+The update modifies requirements to respect exclusions, so the
+requirement on the excluded D v1.2.3 is updated to use the next
+available version of D, perhaps D v1.2.4 or D v1.3.0.
+
+The update removes redundant or misleading requirements.
+For example, if A v1.0.0 itself requires B v1.2.0 and C v1.0.0,
+then go.mod's requirement of B v1.0.0 is misleading (superseded by
+A's need for v1.2.0), and its requirement of C v1.0.0 is redundant
+(implied by A's need for the same version), so both will be removed.
+If module M contains packages that directly import packages from B or
+C, then the requirements will be kept but updated to the actual
+versions being used.
+
+Because the module graph defines the meaning of import statements,
+any commands that load packages also use and therefore update go.mod,
+including go build, go get, go install, go list, go test, go mod graph,
+go mod tidy, and go mod why.
+	`,
+}
@@ -0,0 +1,302 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// An ImportMissingError reports that no module in the build list
+// provides the package with the given import path.
+type ImportMissingError struct {
+	ImportPath string
+	Module     module.Version // module that would provide it, if known
+}
+
+func (e *ImportMissingError) Error() string {
+	if e.Module.Path == "" {
+		return "cannot find module providing package " + e.ImportPath
+	}
+	return "missing module for import: " + e.Module.Path + "@" + e.Module.Version + " provides " + e.ImportPath
+}
+
+// Import finds the module and directory in the build list
+// containing the package with the given import path.
+// The answer must be unique: Import returns an error
+// if multiple modules attempt to provide the same package.
+// Import can return a module with an empty m.Path, for packages in the standard library.
+// Import can return an empty directory string, for fake packages like "C" and "unsafe".
+//
+// If the package cannot be found in the current build list,
+// Import returns an ImportMissingError as the error.
+// If Import can identify a module that could be added to supply the package,
+// the ImportMissingError records that module.
+func Import(path string) (m module.Version, dir string, err error) {
+	if strings.Contains(path, "@") {
+		return module.Version{}, "", fmt.Errorf("import path should not have @version")
+	}
+	if build.IsLocalImport(path) {
+		return module.Version{}, "", fmt.Errorf("relative import not supported")
+	}
+	if path == "C" || path == "unsafe" {
+		// There's no directory for import "C" or import "unsafe".
+		return module.Version{}, "", nil
+	}
+
+	// Is the package in the standard library?
+	if isStandardImportPath(path) {
+		dir := filepath.Join(cfg.GOROOTsrc, path)
+		if _, err := os.Stat(dir); err == nil {
+			return module.Version{}, dir, nil
+		}
+		return module.Version{}, "", fmt.Errorf("package %s is not in GOROOT (%s)", path, dir)
+	}
+
+	if !HasModRoot() {
+		return module.Version{}, "", fmt.Errorf("cannot find module providing package %s: working directory is not part of a module", path)
+	}
+
+	// -mod=vendor is special.
+	// Everything must be in the main module or the main module's vendor directory.
+	if cfg.BuildMod == "vendor" {
+		mainDir, mainOK := dirInModule(path, Target.Path, ModRoot(), true)
+		vendorDir, vendorOK := dirInModule(path, "", filepath.Join(ModRoot(), "vendor"), false)
+		if mainOK && vendorOK {
+			return module.Version{}, "", fmt.Errorf("ambiguous import: found %s in multiple directories:\n\t%s\n\t%s", path, mainDir, vendorDir)
+		}
+		// Prefer to return main directory if there is one,
+		// Note that we're not checking that the package exists.
+		// We'll leave that for load.
+		if !vendorOK && mainDir != "" {
+			return Target, mainDir, nil
+		}
+		readVendorList()
+		return vendorMap[path], vendorDir, nil
+	}
+
+	// Check each module on the build list.
+	var dirs []string
+	var mods []module.Version
+	for _, m := range buildList {
+		if !maybeInModule(path, m.Path) {
+			// Avoid possibly downloading irrelevant modules.
+			continue
+		}
+		root, isLocal, err := fetch(m)
+		if err != nil {
+			return module.Version{}, "", err
+		}
+		dir, ok := dirInModule(path, m.Path, root, isLocal)
+		if ok {
+			mods = append(mods, m)
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(mods) == 1 {
+		return mods[0], dirs[0], nil
+	}
+	if len(mods) > 0 {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "ambiguous import: found %s in multiple modules:", path)
+		for i, m := range mods {
+			fmt.Fprintf(&buf, "\n\t%s", m.Path)
+			if m.Version != "" {
+				fmt.Fprintf(&buf, " %s", m.Version)
+			}
+			fmt.Fprintf(&buf, " (%s)", dirs[i])
+		}
+		return module.Version{}, "", errors.New(buf.String())
+	}
+
+	// Not on build list.
+
+	// Look up the module containing the package, for addition to the build list,
+	// trying each prefix of the import path from longest to shortest.
+	if cfg.BuildMod == "readonly" {
+		return module.Version{}, "", fmt.Errorf("import lookup disabled by -mod=%s", cfg.BuildMod)
+	}
+
+	m, _, err = QueryPackage(path, "latest", Allowed)
+	if err == nil {
+		return module.Version{}, "", &ImportMissingError{ImportPath: path, Module: m}
+	}
+	return module.Version{}, "", &ImportMissingError{ImportPath: path}
+}
+
+// maybeInModule reports whether, syntactically,
+// a package with the given import path could be supplied
+// by a module with the given module path (mpath).
+func maybeInModule(path, mpath string) bool {
+	return mpath == path ||
+		len(path) > len(mpath) && path[len(mpath)] == '/' && path[:len(mpath)] == mpath
+}
+
+var haveGoModCache = make(map[string]bool) // dir → bool
+
+// dirInModule locates the directory that would hold the package named by the given path,
+// if it were in the module with module path mpath and root mdir.
+// If path is syntactically not within mpath,
+// or if mdir is a local file tree (isLocal == true) and the directory
+// that would hold path is in a sub-module (covered by a go.mod below mdir),
+// dirInModule returns "", false.
+//
+// Otherwise, dirInModule returns the name of the directory where
+// Go source files would be expected, along with a boolean indicating
+// whether there are in fact Go source files in that directory.
+func dirInModule(path, mpath, mdir string, isLocal bool) (dir string, haveGoFiles bool) {
+	// Determine where to expect the package.
+	if path == mpath {
+		dir = mdir
+	} else if mpath == "" { // vendor directory
+		dir = filepath.Join(mdir, path)
+	} else if len(path) > len(mpath) && path[len(mpath)] == '/' && path[:len(mpath)] == mpath {
+		dir = filepath.Join(mdir, path[len(mpath)+1:])
+	} else {
+		return "", false
+	}
+
+	// Check that there aren't other modules in the way.
+	// This check is unnecessary inside the module cache
+	// and important to skip in the vendor directory,
+	// where all the module trees have been overlaid.
+	// So we only check local module trees
+	// (the main module, and any directory trees pointed at by replace directives).
+	if isLocal {
+		for d := dir; d != mdir && len(d) > len(mdir); {
+			haveGoMod, ok := haveGoModCache[d]
+			if !ok {
+				fi, err := os.Stat(filepath.Join(d, "go.mod"))
+				haveGoMod = err == nil && !fi.IsDir()
+				haveGoModCache[d] = haveGoMod
+			}
+			if haveGoMod {
+				return "", false
+			}
+			parent := filepath.Dir(d)
+			if parent == d {
+				// Break the loop, as otherwise we'd loop
+				// forever if d=="." and mdir=="".
+				break
+			}
+			d = parent
+		}
+	}
+
+	// Now committed to returning dir (not "").
+
+	// Are there Go source files in the directory?
+	// We don't care about build tags, not even "+build ignore".
+	// We're just looking for a plausible directory.
+	haveGoFiles = hasGoFiles(dir)
+	return dir, haveGoFiles
+}
+
+// hasGoFiles reports whether dir contains any .go files.
+func hasGoFiles(dir string) bool {
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch returns the directory holding the source tree for module m,
+// downloading it if necessary. isLocal reports whether the tree is
+// the main module or a local directory named by a replace directive.
+func fetch(m module.Version) (dir string, isLocal bool, err error) {
+	if m == Target {
+		return ModRoot(), true, nil
+	}
+	if r := Replacement(m); r.Path != "" {
+		if r.Version == "" {
+			dir = r.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(ModRoot(), dir)
+			}
+			return dir, true, nil
+		}
+		m = r
+	}
+
+	dir, err = modfetch.Download(m)
+	return dir, false, err
+}
+
+// isStandardImportPath reports whether path names a package
+// in the standard library: its first element contains no dot
+// and it is not part of the main module, whose path need not
+// contain a dot either.
+func isStandardImportPath(path string) bool {
+	i := strings.Index(path, "/")
+	if i < 0 {
+		i = len(path)
+	}
+	if strings.Contains(path[:i], ".") {
+		return false
+	}
+	return Target.Path == "" || !maybeInModule(path, Target.Path)
+}
+
+var (
+	vendorOnce bool
+	vendorList []module.Version          // modules that contribute packages to the build, in order of appearance
+	vendorMap  map[string]module.Version // package → module
+)
+
+// readVendorList reads the list of vendored modules from vendor/modules.txt.
+func readVendorList() {
+	if vendorOnce {
+		return
+	}
+	vendorOnce = true
+	vendorList = nil
+	vendorMap = make(map[string]module.Version)
+	data, _ := ioutil.ReadFile(filepath.Join(ModRoot(), "vendor/modules.txt"))
+	var m module.Version
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "# ") {
+			f := strings.Fields(line)
+			m = module.Version{}
+			if len(f) >= 3 && semver.IsValid(f[2]) {
+				m = module.Version{Path: f[1], Version: f[2]}
+				vendorList = append(vendorList, m)
+			}
+		} else if m.Path != "" {
+			f := strings.Fields(line)
+			if len(f) == 1 {
+				vendorMap[f[0]] = m
+			}
+		}
+	}
+}
+
+// ModulePackageDir returns the directory that would hold the package
+// with the given import path in module m, downloading m if needed,
+// along with whether that directory contains any Go source files.
+func ModulePackageDir(m module.Version, path string) (dir string, ok bool, err error) {
+	root, isLocal, err := fetch(m)
+	if err != nil {
+		return "", false, err
+	}
+	dir, ok = dirInModule(path, m.Path, root, isLocal)
+	return dir, ok, nil
+}
@@ -0,0 +1,459 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modload implements the module-aware parts of the go command:
+// locating the main module, computing the build list, and
+// resolving import paths to the modules that provide them.
+package modload
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/module"
+)
+
+var (
+	cwd            string
+	MustUseModules bool // set by GO111MODULE=on
+	initialized    bool
+	inGOPATH       bool // running in GOPATH/src
+
+	modRoot     string
+	modFile     *modfile.File
+	modFileData []byte
+	excluded    map[module.Version]bool
+	Target      module.Version
+
+	gopath string
+
+	CmdModInit   bool   // running 'go mod init'
+	CmdModModule string // module argument for 'go mod init'
+)
+
+// Init determines whether module mode is enabled, locates the root
+// of the current module (if any), and sets up the hooks in package load
+// that make the rest of the go command module-aware.
+func Init() {
+	if initialized {
+		return
+	}
+	initialized = true
+
+	env := os.Getenv("GO111MODULE")
+	switch env {
+	default:
+		base.Fatalf("go: unknown environment setting GO111MODULE=%s", env)
+	case "", "auto":
+		// Decide below.
+	case "on":
+		MustUseModules = true
+	case "off":
+		if CmdModInit {
+			die()
+		}
+		return
+	}
+
+	var err error
+	cwd, err = os.Getwd()
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+
+	for _, gopath := range filepath.SplitList(cfg.BuildContext.GOPATH) {
+		if gopath == "" {
+			continue
+		}
+		if inDir(cwd, filepath.Join(gopath, "src")) != "" {
+			inGOPATH = true
+			break
+		}
+	}
+	if inGOPATH && !MustUseModules {
+		if CmdModInit {
+			die() // Don't init a module that we're just going to ignore.
+		}
+		// No automatic enabling in GOPATH.
+		return
+	}
+
+	if CmdModInit {
+		// Running 'go mod init': go.mod will be created in current directory.
+		modRoot = cwd
+	} else {
+		modRoot = FindModuleRoot(cwd)
+		if modRoot == "" {
+			if !MustUseModules {
+				// GO111MODULE is 'auto' (or unset), and we can't find a module root.
+				// Stay in GOPATH mode.
+				return
+			}
+		} else if inDir(modRoot, os.TempDir()) == "." {
+			// If you create /tmp/go.mod for experimenting,
+			// then any tests that create work directories under /tmp
+			// will find it and get modules when they're not expecting them.
+			// It's a bit of a peculiar thing to disallow but quite mysterious
+			// when it happens.
+			modRoot = ""
+			fmt.Fprintf(os.Stderr, "go: warning: ignoring go.mod in system temp root %v\n", os.TempDir())
+			if !MustUseModules {
+				return
+			}
+		}
+	}
+
+	cfg.ModulesEnabled = true
+	load.ModBinDir = BinDir
+	load.ModLookup = Lookup
+	load.ModPackageModuleInfo = PackageModuleInfo
+	load.ModImportPaths = ImportPaths
+	load.ModImportFromFiles = ImportFromFiles
+	load.ModDirImportPath = DirImportPath
+
+	list := filepath.SplitList(cfg.BuildContext.GOPATH)
+	if len(list) == 0 || list[0] == "" {
+		base.Fatalf("missing $GOPATH")
+	}
+	gopath = list[0]
+	if _, err := os.Stat(filepath.Join(gopath, "go.mod")); err == nil {
+		base.Fatalf("$GOPATH/go.mod exists but should not")
+	}
+	modfetch.PkgMod = filepath.Join(gopath, "pkg/mod")
+
+	if modRoot != "" {
+		modfetch.InitGoSum(filepath.Join(modRoot, "go.sum"))
+	}
+}
+
+// Enabled reports whether modules are enabled.
+// If modules must be enabled but there is no main module,
+// Enabled returns true and the first use of module information
+// reports the error (usually through InitMod).
+func Enabled() bool {
+	Init()
+	return cfg.ModulesEnabled
+}
+
+// HasModRoot reports whether a main module is present.
+func HasModRoot() bool {
+	Init()
+	return modRoot != ""
+}
+
+// ModRoot returns the root of the main module.
+// It calls base.Fatalf if there is no main module.
+func ModRoot() string {
+	if !HasModRoot() {
+		die()
+	}
+	return modRoot
+}
+
+// ModFile returns the parsed go.mod file.
+//
+// Note that after calling ImportPaths or LoadBuildList,
+// the require statements in the modfile.File are no longer
+// the source of truth and will be ignored: edits made directly
+// will be lost at the next call to WriteGoMod.
+// To make permanent changes to the require statements
+// in go.mod, edit it before calling ImportPaths or LoadBuildList.
+func ModFile() *modfile.File {
+	InitMod()
+	return modFile
+}
+
+// BinDir returns the directory in which 'go install' places
+// commands when GOBIN is unset.
+func BinDir() string {
+	Init()
+	return filepath.Join(gopath, "bin")
+}
+
+func die() {
+	if os.Getenv("GO111MODULE") == "off" {
+		base.Fatalf("go: modules disabled by GO111MODULE=off; see 'go help modules'")
+	}
+	if inGOPATH && !MustUseModules {
+		base.Fatalf("go: modules disabled inside GOPATH/src by GO111MODULE=auto; see 'go help modules'")
+	}
+	base.Fatalf("go: cannot find main module; see 'go help modules'")
+}
+
+// InitMod sets Target and, if there is a main module,
+// parses the initial build list from its go.mod file,
+// creating and populating that file if needed.
+func InitMod() {
+	if len(buildList) > 0 {
+		return
+	}
+
+	Init()
+	if modRoot == "" {
+		die()
+	}
+
+	switch cfg.BuildMod {
+	case "", "readonly", "vendor":
+		// ok
+	default:
+		base.Fatalf("-mod=%s not supported (can be '', 'readonly', or 'vendor')", cfg.BuildMod)
+	}
+
+	if CmdModInit {
+		// Running go mod init: create a new go.mod.
+		newModFile()
+		modFileToBuildList()
+		WriteGoMod()
+		return
+	}
+
+	gomod := filepath.Join(modRoot, "go.mod")
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+
+	f, err := modfile.Parse(gomod, data)
+	if err != nil {
+		// Errors returned by modfile.Parse begin with file:line.
+		base.Fatalf("go: %v", err)
+	}
+	modFile = f
+	modFileData = data
+
+	if f.Module == nil {
+		// No module statement. Must add module path.
+		path, err := FindModulePath(modRoot)
+		if err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		f.AddModuleStmt(path)
+	}
+	if err := module.CheckImportPath(f.Module.Mod.Path); err != nil {
+		base.Fatalf("go: invalid module path in %s: %v", gomod, err)
+	}
+
+	excluded = make(map[module.Version]bool)
+	for _, x := range f.Exclude {
+		excluded[x.Mod] = true
+	}
+	modFileToBuildList()
+	WriteGoMod()
+}
+
+// modFileToBuildList initializes buildList from the modFile.
+func modFileToBuildList() {
+	Target = modFile.Module.Mod
+	list := []module.Version{Target}
+	for _, r := range modFile.Require {
+		list = append(list, r.Mod)
+	}
+	buildList = list
+}
+
+// Allowed reports whether module m is allowed (not excluded) by the main module's go.mod.
+func Allowed(m module.Version) bool {
+	return !excluded[m]
+}
+
+func newModFile() {
+	if modFile == nil {
+		path, err := FindModulePath(modRoot)
+		if err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "go: creating new go.mod: module %s\n", path)
+		modFile = new(modfile.File)
+		modFile.AddModuleStmt(path)
+	}
+	excluded = make(map[module.Version]bool)
+}
+
+// FindModuleRoot finds the module root by looking for a go.mod file
+// in dir or any of its parents.
+// It returns the empty string if there is no such file.
+func FindModuleRoot(dir string) string {
+	dir = filepath.Clean(dir)
+
+	// Look for enclosing go.mod.
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			return dir
+		}
+		d := filepath.Dir(dir)
+		if d == dir {
+			break
+		}
+		dir = d
+	}
+	return ""
+}
+
+// FindModulePath returns the module path to use for a new module
+// rooted at dir: the argument to 'go mod init', if any,
+// the path implied by dir's location in $GOPATH/src,
+// or the import comment on the package in dir.
+func FindModulePath(dir string) (string, error) {
+	if CmdModModule != "" {
+		// Running go mod init x/y/z; return x/y/z.
+		return CmdModModule, nil
+	}
+
+	// Cast about for import comments,
+	// first in top-level directory, then in subdirectories.
+	if path, ok := importComment(dir); ok {
+		return path, nil
+	}
+
+	// Look for path in GOPATH.
+	for _, gpdir := range filepath.SplitList(cfg.BuildContext.GOPATH) {
+		if gpdir == "" {
+			continue
+		}
+		if rel := inDir(dir, filepath.Join(gpdir, "src")); rel != "" && rel != "." {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot determine module path for source directory %s (outside GOPATH, no import comments)", dir)
+}
+
+// importComment returns the import comment on the package in dir,
+// or failing that, the import path implied by the comment on a
+// package in one of dir's immediate subdirectories.
+func importComment(dir string) (string, bool) {
+	if bp, err := cfg.BuildContext.ImportDir(dir, build.ImportComment); err == nil && bp.ImportComment != "" {
+		return bp.ImportComment, true
+	}
+	infos, _ := ioutil.ReadDir(dir)
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_") || info.Name() == "testdata" {
+			continue
+		}
+		bp, err := cfg.BuildContext.ImportDir(filepath.Join(dir, info.Name()), build.ImportComment)
+		if err == nil && bp.ImportComment != "" && path.Base(bp.ImportComment) == info.Name() {
+			return path.Dir(bp.ImportComment), true
+		}
+	}
+	return "", false
+}
+
+// WriteGoMod writes the current build list back to go.mod,
+// along with any go.sum updates.
+func WriteGoMod() {
+	if modFile == nil || cfg.BuildMod == "vendor" {
+		return
+	}
+
+	if buildListLoaded {
+		reqs := MinReqs()
+		min, err := reqs.Required(Target)
+		if err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		direct := directPaths()
+		var list []*modfile.Require
+		for _, m := range min {
+			list = append(list, &modfile.Require{
+				Mod:      m,
+				Indirect: !direct[m.Path],
+			})
+		}
+		modFile.SetRequire(list)
+	}
+
+	new, err := modFile.Format()
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	if !bytes.Equal(new, modFileData) {
+		if cfg.BuildMod == "readonly" {
+			base.Fatalf("go: updates to go.mod needed, disabled by -mod=readonly")
+		}
+		if err := ioutil.WriteFile(filepath.Join(modRoot, "go.mod"), new, 0666); err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		modFileData = new
+	}
+	modfetch.WriteGoSum()
+}
+
+// inDir checks whether path is in the file tree rooted at dir.
+// If so, inDir returns an equivalent path relative to dir.
+// If not, inDir returns an empty string.
+// inDir makes some effort to succeed even in the presence of symbolic links.
+func inDir(path, dir string) string {
+	if rel := inDirLex(path, dir); rel != "" {
+		return rel
+	}
+	xpath, err := filepath.EvalSymlinks(path)
+	if err != nil || xpath == path {
+		xpath = ""
+	} else {
+		if rel := inDirLex(xpath, dir); rel != "" {
+			return rel
+		}
+	}
+
+	xdir, err := filepath.EvalSymlinks(dir)
+	if err == nil && xdir != dir {
+		if rel := inDirLex(path, xdir); rel != "" {
+			return rel
+		}
+		if xpath != "" {
+			if rel := inDirLex(xpath, xdir); rel != "" {
+				return rel
+			}
+		}
+	}
+	return ""
+}
+
+// inDirLex is like inDir but only checks the lexical form of the file names.
+// It does not consider symbolic links.
+func inDirLex(path, dir string) string {
+	pv := strings.ToUpper(filepath.VolumeName(path))
+	dv := strings.ToUpper(filepath.VolumeName(dir))
+	path = path[len(pv):]
+	dir = dir[len(dv):]
+	switch {
+	default:
+		return ""
+	case pv != dv:
+		return ""
+	case len(path) == len(dir):
+		if path == dir {
+			return "."
+		}
+		return ""
+	case dir == "":
+		return path
+	case len(path) > len(dir):
+		if dir[len(dir)-1] == filepath.Separator {
+			if path[:len(dir)] == dir {
+				return path[len(dir):]
+			}
+			return ""
+		}
+		if path[len(dir)] == filepath.Separator && path[:len(dir)] == dir {
+			if len(path) == len(dir)+1 {
+				return "."
+			}
+			return path[len(dir)+1:]
+		}
+		return ""
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modinfo"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// ListModules returns information about the modules named by args,
+// as reported by 'go list -m'. With no arguments it returns the main module.
+// If listU is set, each result records the latest available update.
+// If listVersions is set, each result records the list of known versions.
+func ListModules(args []string, listU, listVersions bool) []*modinfo.ModulePublic {
+	mods := listModules(args)
+	if listU || listVersions {
+		for _, m := range mods {
+			if listU {
+				addUpdate(m)
+				if m.Replace != nil {
+					addUpdate(m.Replace)
+				}
+			}
+			if listVersions {
+				addVersions(m)
+				if m.Replace != nil {
+					addVersions(m.Replace)
+				}
+			}
+		}
+	}
+	return mods
+}
+
+func listModules(args []string) []*modinfo.ModulePublic {
+	LoadBuildList()
+	if len(args) == 0 {
+		return []*modinfo.ModulePublic{moduleInfo(buildList[0], true)}
+	}
+
+	var mods []*modinfo.ModulePublic
+	matchedBuildList := make([]bool, len(buildList))
+	for _, arg := range args {
+		if strings.Contains(arg, `\`) {
+			base.Fatalf("go: module paths never use backslash")
+		}
+		if build.IsLocalImport(arg) {
+			base.Fatalf("go: cannot use relative path %s to specify module", arg)
+		}
+		if i := strings.Index(arg, "@"); i >= 0 {
+			path, vers := arg[:i], arg[i+1:]
+			info, err := Query(path, vers, nil)
+			if err != nil {
+				mods = append(mods, &modinfo.ModulePublic{
+					Path:    path,
+					Version: vers,
+					Error:   &modinfo.ModuleError{Err: err.Error()},
+				})
+				continue
+			}
+			mods = append(mods, moduleInfo(module.Version{Path: path, Version: info.Version}, false))
+			continue
+		}
+
+		// Module path or pattern.
+		var match func(string) bool
+		literal := false
+		if arg == "all" {
+			match = func(string) bool { return true }
+		} else if strings.Contains(arg, "...") {
+			match = load.MatchPattern(arg)
+		} else {
+			match = func(p string) bool { return arg == p }
+			literal = true
+		}
+		matched := false
+		for i, m := range buildList {
+			if match(m.Path) {
+				matched = true
+				if !matchedBuildList[i] {
+					matchedBuildList[i] = true
+					mods = append(mods, moduleInfo(m, true))
+				}
+			}
+		}
+		if !matched {
+			if literal {
+				mods = append(mods, &modinfo.ModulePublic{
+					Path:  arg,
+					Error: &modinfo.ModuleError{Err: fmt.Sprintf("module %q is not a known dependency", arg)},
+				})
+			} else {
+				fmt.Fprintf(os.Stderr, "warning: pattern %q matched no module dependencies\n", arg)
+			}
+		}
+	}
+	return mods
+}
+
+// addUpdate fills in m.Update if an update is available.
+func addUpdate(m *modinfo.ModulePublic) {
+	if m.Version == "" {
+		return
+	}
+	if info, err := Query(m.Path, "latest", Allowed); err == nil && semver.Compare(info.Version, m.Version) > 0 {
+		m.Update = &modinfo.ModulePublic{
+			Path:    m.Path,
+			Version: info.Version,
+			Time:    &info.Time,
+		}
+	}
+}
+
+// addVersions fills in m.Versions with the list of known versions.
+func addVersions(m *modinfo.ModulePublic) {
+	versions, err := modfetch.Lookup(m.Path).Versions("")
+	if err != nil {
+		return
+	}
+	for _, v := range versions {
+		if Allowed(module.Version{Path: m.Path, Version: v}) {
+			m.Versions = append(m.Versions, v)
+		}
+	}
+}
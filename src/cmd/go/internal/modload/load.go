@@ -0,0 +1,761 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/module"
+)
+
+// loaded is the most recently-used package loader.
+// It holds details about individual packages.
+var loaded *loader
+
+// ImportPaths returns the set of packages matching the args (patterns),
+// adding modules to the build list as needed to satisfy new imports.
+func ImportPaths(patterns []string) []string {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	type match struct {
+		pattern string
+		pkgs    []string
+		done    bool // pkgs does not depend on the build list
+	}
+	var matches []*match
+	for _, pattern := range cleanPatterns(patterns) {
+		matches = append(matches, &match{pattern: pattern})
+	}
+
+	loaded = newLoader()
+	updateMatches := func(iterating bool) {
+		for _, m := range matches {
+			if m.done {
+				continue
+			}
+			switch {
+			case build.IsLocalImport(m.pattern) || filepath.IsAbs(m.pattern):
+				// Evaluate list of file system directories on first iteration.
+				m.pkgs = localPackages(m.pattern)
+				m.done = true
+
+			case m.pattern == "std" || m.pattern == "cmd":
+				m.pkgs = load.MatchPackages(m.pattern)
+				m.done = true
+
+			case m.pattern == "all":
+				loaded.testAll = true
+				if iterating {
+					// Enumerate the packages in the main module.
+					// We'll load the dependencies as we find them.
+					m.pkgs = TargetPackages()
+				} else {
+					// Starting with the packages in the main module,
+					// enumerate the full list of "all".
+					m.pkgs = loaded.computePatternAll()
+				}
+
+			case strings.Contains(m.pattern, "..."):
+				m.pkgs = matchPackages(m.pattern, buildList)
+
+			default:
+				m.pkgs = []string{m.pattern}
+				m.done = true
+			}
+		}
+	}
+
+	loaded.load(func() []string {
+		var roots []string
+		updateMatches(true)
+		for _, m := range matches {
+			roots = append(roots, m.pkgs...)
+		}
+		return roots
+	})
+
+	// One last pass to finalize wildcards.
+	updateMatches(false)
+
+	checkAliases()
+
+	WriteGoMod()
+
+	var final []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if len(m.pkgs) == 0 && (m.pattern == "all" || strings.Contains(m.pattern, "...")) {
+			fmt.Fprintf(os.Stderr, "warning: %q matched no packages\n", m.pattern)
+		}
+		for _, pkg := range m.pkgs {
+			if !seen[pkg] {
+				seen[pkg] = true
+				final = append(final, pkg)
+			}
+		}
+	}
+	return final
+}
+
+// cleanPatterns puts the patterns into canonical form,
+// preserving a leading ./ as load.ImportPathsNoDotExpansion does.
+func cleanPatterns(patterns []string) []string {
+	var out []string
+	for _, a := range patterns {
+		// Arguments are supposed to be import paths, but
+		// as a courtesy to Windows developers, rewrite \ to /
+		// in command-line arguments. Handles .\... and so on.
+		if filepath.Separator == '\\' {
+			a = strings.Replace(a, `\`, `/`, -1)
+		}
+
+		// Put argument in canonical form, but preserve leading ./.
+		if strings.HasPrefix(a, "./") {
+			a = "./" + path.Clean(a)
+			if a == "./." {
+				a = "."
+			}
+		} else {
+			a = path.Clean(a)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// localPackages returns the import paths of the packages in the
+// directories named by the file system pattern.
+func localPackages(pattern string) []string {
+	var dirs []string
+	if strings.Contains(pattern, "...") {
+		for _, pkg := range load.MatchPackagesInFS(pattern) {
+			dir := filepath.Join(cwd, pkg)
+			if HasModRoot() && inDir(dir, filepath.Join(ModRoot(), "vendor")) != "" {
+				// Vendor directories are not used in module mode.
+				continue
+			}
+			if HasModRoot() && FindModuleRoot(dir) != ModRoot() {
+				// Skip packages in nested modules.
+				continue
+			}
+			dirs = append(dirs, dir)
+		}
+	} else {
+		dir := pattern
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cwd, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+
+	var pkgs []string
+	for _, dir := range dirs {
+		pkg := DirImportPath(dir)
+		if pkg == "." || HasModRoot() && inDir(dir, ModRoot()) != "" && FindModuleRoot(dir) != ModRoot() {
+			base.Errorf("go: directory %s outside available modules", base.ShortPath(dir))
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// DirImportPath returns the effective import path for dir,
+// provided it is within the main module or the standard library,
+// or else returns ".".
+func DirImportPath(dir string) string {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	} else {
+		dir = filepath.Clean(dir)
+	}
+
+	if HasModRoot() {
+		InitMod()
+		if dir == modRoot {
+			return Target.Path
+		}
+		if strings.HasPrefix(dir, modRoot+string(filepath.Separator)) {
+			suffix := filepath.ToSlash(dir[len(modRoot):])
+			if strings.HasPrefix(suffix, "/vendor/") {
+				// A vendored copy is named by its original import path.
+				return strings.TrimPrefix(suffix, "/vendor/")
+			}
+			return Target.Path + suffix
+		}
+	}
+	if rel := inDir(dir, cfg.GOROOTsrc); rel != "" && rel != "." {
+		return filepath.ToSlash(rel)
+	}
+	return "."
+}
+
+// ImportFromFiles adds modules to the build list as needed
+// to satisfy the imports in the named Go source files.
+func ImportFromFiles(gofiles []string) {
+	imports, testImports, err := scanFiles(gofiles, nil)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+
+	loaded = newLoader()
+	loaded.fromFiles = true
+	loaded.load(func() []string {
+		var roots []string
+		roots = append(roots, imports...)
+		roots = append(roots, testImports...)
+		return roots
+	})
+	WriteGoMod()
+}
+
+// LoadALL returns the set of all packages in the current build list,
+// considering the files for all build tags.
+func LoadALL() []string {
+	InitMod()
+	loaded = newLoader()
+	loaded.isALL = true
+	loaded.allTags = true
+	loaded.testAll = true
+	all := TargetPackages()
+	loaded.load(func() []string { return all })
+	checkAliases()
+	WriteGoMod()
+
+	var paths []string
+	for _, pkg := range loaded.pkgs {
+		if pkg.err != nil && !isStandardImportPath(pkg.path) {
+			base.Errorf("%s: %v", pkg.stackText(), pkg.err)
+			continue
+		}
+		paths = append(paths, pkg.path)
+	}
+	base.ExitIfErrors()
+	return paths
+}
+
+// Lookup returns the source directory, import path, and any loading error for
+// the package at path.
+// Lookup requires that one of the Load functions in this package has already
+// been called.
+func Lookup(path string) (dir, realPath string, err error) {
+	if loaded != nil {
+		if pkg, ok := loaded.pkgCache[path]; ok {
+			return pkg.dir, pkg.path, pkg.err
+		}
+	}
+
+	// The loader did not see this import: for example, it is
+	// a test import of a non-root package, or a dependency
+	// injected by the build like runtime/cgo.
+	// Resolve it directly, without adding modules to the build list.
+	if HasModRoot() {
+		LoadBuildList()
+	}
+	_, dir, err = Import(path)
+	return dir, path, err
+}
+
+// PackageModule returns the module providing the package named by the import path.
+func PackageModule(path string) module.Version {
+	if loaded == nil {
+		return module.Version{}
+	}
+	pkg, ok := loaded.pkgCache[path]
+	if !ok {
+		return module.Version{}
+	}
+	return pkg.mod
+}
+
+// findModule returns the module containing the package at path,
+// needed to build the package at target.
+func findModule(target, path string) module.Version {
+	if loaded != nil {
+		if pkg, ok := loaded.pkgCache[path]; ok {
+			return pkg.mod
+		}
+	}
+	if path == "." {
+		return buildList[0]
+	}
+	m, _, err := Import(path)
+	if err != nil {
+		base.Fatalf("go: cannot find module for path %v (needed by %v): %v", path, target, err)
+	}
+	return m
+}
+
+// Why returns the "go mod why" output stanza for the given package,
+// without the leading # comment.
+// The package graph must have been loaded already, usually by LoadALL.
+// If there is no reason for the package to be in the current build,
+// Why returns an empty string.
+func Why(path string) string {
+	pkg, ok := loaded.pkgCache[path]
+	if !ok {
+		return ""
+	}
+	return pkg.why()
+}
+
+// WhyDepth returns the number of steps in the Why listing.
+// If there is no reason for the package to be in the current build,
+// WhyDepth returns 0.
+func WhyDepth(path string) int {
+	n := 0
+	pkg, _ := loaded.pkgCache[path]
+	for p := pkg; p != nil; p = p.stack {
+		n++
+	}
+	return n
+}
+
+// A loader manages the process of loading information about
+// the required packages for a particular build,
+// checking that the packages are available in the module set,
+// and updating the module set if needed.
+// Loading is an iterative process: try to load all the needed packages,
+// but if imports are missing, try to resolve those imports, and repeat.
+//
+// Although most of the loading state is maintained in the loader struct,
+// one key piece - the build list - is a global, so that it can be modified
+// separate from the loading operation, such as during "go get"
+// upgrades/downgrades or in "go mod" operations.
+type loader struct {
+	allTags   bool // scan files for all build tags, as 'go mod tidy' and 'go mod vendor' do
+	testRoots bool // include tests for roots
+	testAll   bool // include tests for all packages in the main module
+	isALL     bool // the loaded packages are "all": the import graph is complete
+	fromFiles bool // the roots are the imports of Go files named on the command line
+
+	// reset on each iteration
+	roots    []*loadPkg
+	pkgs     []*loadPkg
+	work     []*loadPkg          // packages still to process, in breadth-first order
+	pkgCache map[string]*loadPkg // import path → package
+
+	// computed at end of iterations
+	direct map[string]bool // modules providing packages imported directly by main module
+}
+
+// newLoader returns a new loader.
+// Tests of root packages are loaded by the commands that build them.
+func newLoader() *loader {
+	ld := new(loader)
+	ld.testRoots = cfg.CmdName == "test" || cfg.CmdName == "vet"
+	return ld
+}
+
+func (ld *loader) reset() {
+	ld.roots = nil
+	ld.pkgs = nil
+	ld.work = nil
+	ld.pkgCache = make(map[string]*loadPkg)
+}
+
+// A loadPkg records information about a single loaded package.
+type loadPkg struct {
+	path        string         // import path
+	mod         module.Version // module providing package
+	dir         string         // directory containing source code
+	imports     []*loadPkg     // packages imported by this one
+	err         error          // error loading package
+	stack       *loadPkg       // package importing this one in minimal import stack for this pkg
+	test        *loadPkg       // package with test imports, if we need test
+	testOf      *loadPkg       // package being tested, if this is a test pseudo-package
+	testImports []string       // test-only imports, saved for use by pkg.test.
+}
+
+// load attempts to load the build graph needed to process a set of root packages.
+// The set of root packages is defined by the roots function.
+// It is called each time the build list changes, so that
+// patterns can be re-evaluated against the new build list.
+func (ld *loader) load(roots func() []string) {
+	if HasModRoot() {
+		LoadBuildList()
+	}
+
+	added := make(map[string]bool)
+	for {
+		ld.reset()
+		for _, path := range roots() {
+			root := ld.pkg(path, nil)
+			ld.roots = append(ld.roots, root)
+		}
+		ld.doWork()
+
+		numAdded := 0
+		haveMod := make(map[module.Version]bool)
+		for _, m := range buildList {
+			haveMod[m] = true
+		}
+		for _, pkg := range ld.pkgs {
+			if err, ok := pkg.err.(*ImportMissingError); ok && err.Module.Path != "" {
+				if added[pkg.path] {
+					base.Fatalf("go: %s: looping trying to add package", pkg.stackText())
+				}
+				added[pkg.path] = true
+				numAdded++
+				if !haveMod[err.Module] {
+					fmt.Fprintf(os.Stderr, "go: finding %s %s\n", err.Module.Path, err.Module.Version)
+					haveMod[err.Module] = true
+					buildList = append(buildList, err.Module)
+				}
+				continue
+			}
+		}
+		if numAdded == 0 {
+			break
+		}
+
+		// Recompute buildList with all our additions.
+		ReloadBuildList()
+	}
+
+	// Mark the modules providing the imports of main-module packages as direct.
+	// Go files named on the command line count as part of the main module.
+	ld.direct = make(map[string]bool)
+	if ld.fromFiles {
+		for _, root := range ld.roots {
+			if root.mod.Path != "" {
+				ld.direct[root.mod.Path] = true
+			}
+		}
+	}
+	for _, pkg := range ld.pkgs {
+		if pkg.mod == Target {
+			for _, dep := range pkg.imports {
+				if dep.mod.Path != "" {
+					ld.direct[dep.mod.Path] = true
+				}
+			}
+		}
+	}
+	for _, pkg := range ld.testPkgs() {
+		if pkg.testOf.mod == Target {
+			for _, dep := range pkg.imports {
+				if dep.mod.Path != "" {
+					ld.direct[dep.mod.Path] = true
+				}
+			}
+		}
+	}
+
+	// Mix in direct markings (really, lack of indirect markings)
+	// from go.mod, unless we scanned the whole module
+	// and can therefore be sure we know better than go.mod.
+	if !ld.isALL && modFile != nil {
+		for _, r := range modFile.Require {
+			if !r.Indirect {
+				ld.direct[r.Mod.Path] = true
+			}
+		}
+	}
+}
+
+// testPkgs returns the test pseudo-packages created during loading.
+func (ld *loader) testPkgs() []*loadPkg {
+	var tests []*loadPkg
+	for _, pkg := range ld.pkgs {
+		if pkg.test != nil {
+			tests = append(tests, pkg.test)
+		}
+	}
+	return tests
+}
+
+// pkg returns the *loadPkg for path, creating and queuing it if needed.
+// If the package is new, importer becomes the last element of its
+// minimal import stack.
+func (ld *loader) pkg(path string, importer *loadPkg) *loadPkg {
+	if pkg := ld.pkgCache[path]; pkg != nil {
+		return pkg
+	}
+	pkg := &loadPkg{
+		path:  path,
+		stack: importer,
+	}
+	ld.pkgCache[path] = pkg
+	ld.pkgs = append(ld.pkgs, pkg)
+	ld.work = append(ld.work, pkg)
+	return pkg
+}
+
+// doWork processes the queued packages in breadth-first order,
+// so that each package's stack is a minimal import stack.
+func (ld *loader) doWork() {
+	for i := 0; i < len(ld.work); i++ {
+		ld.doPkg(ld.work[i])
+	}
+	ld.work = nil
+}
+
+// doPkg processes a package on the work queue.
+func (ld *loader) doPkg(pkg *loadPkg) {
+	if pkg.testOf != nil {
+		// Test pseudo-package: its imports are the test imports
+		// of the package being tested.
+		for _, path := range pkg.testOf.testImports {
+			pkg.imports = append(pkg.imports, ld.pkg(path, pkg))
+		}
+		return
+	}
+
+	pkg.mod, pkg.dir, pkg.err = Import(pkg.path)
+	if pkg.dir == "" || isStandardImportPath(pkg.path) {
+		// Standard library packages import only other
+		// standard library packages, so there is no need
+		// to follow their imports.
+		return
+	}
+
+	var tags map[string]bool
+	if ld.allTags {
+		tags = anyTags
+	}
+	imports, testImports, err := scanDir(pkg.dir, tags)
+	if err != nil {
+		pkg.err = err
+		return
+	}
+	for _, path := range imports {
+		pkg.imports = append(pkg.imports, ld.pkg(path, pkg))
+	}
+
+	isRoot := false
+	for _, r := range ld.roots {
+		if r == pkg {
+			isRoot = true
+			break
+		}
+	}
+	if len(testImports) > 0 && (ld.testRoots && isRoot || ld.testAll && pkg.mod == Target) {
+		for _, path := range testImports {
+			if path != pkg.path {
+				pkg.testImports = append(pkg.testImports, path)
+			}
+		}
+		pkg.test = &loadPkg{
+			path:   pkg.path,
+			mod:    pkg.mod,
+			dir:    pkg.dir,
+			stack:  pkg,
+			testOf: pkg,
+		}
+		ld.work = append(ld.work, pkg.test)
+	}
+}
+
+// computePatternAll returns the list of packages matching pattern "all",
+// starting with a list of the import paths for the packages in the main module.
+func (ld *loader) computePatternAll() []string {
+	var all []string
+	for _, pkg := range ld.pkgs {
+		if pkg.err == nil && !isStandardImportPath(pkg.path) {
+			all = append(all, pkg.path)
+		}
+	}
+	sort.Strings(all)
+	return all
+}
+
+// checkAliases reports an error if a module path is used both
+// as itself and as the replacement for another module:
+// the aliasing behavior would be too subtle to rely on.
+func checkAliases() {
+	if modFile == nil {
+		return
+	}
+	used := make(map[string]module.Version)
+	for _, m := range buildList {
+		if m != Target {
+			used[m.Path] = m
+		}
+	}
+	for _, m := range buildList {
+		r := Replacement(m)
+		if r.Path == "" || r.Version == "" {
+			continue
+		}
+		if other, ok := used[r.Path]; ok && other != m {
+			base.Fatalf("go: %s@%s used for two different module paths (%s and %s)", r.Path, r.Version, other.Path, m.Path)
+		}
+	}
+}
+
+// displayPath returns the import path of pkg,
+// with a .test suffix for test pseudo-packages.
+func (pkg *loadPkg) displayPath() string {
+	if pkg.testOf != nil {
+		return pkg.path + ".test"
+	}
+	return pkg.path
+}
+
+// stackText builds the import stack text to use when
+// reporting an error in pkg. It has the general form
+//
+//	import root ->
+//		import other ->
+//		import other2 ->
+//		import pkg
+//
+func (pkg *loadPkg) stackText() string {
+	var stack []*loadPkg
+	for p := pkg.stack; p != nil; p = p.stack {
+		stack = append(stack, p)
+	}
+
+	var buf strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		p := stack[i]
+		if p.testOf != nil {
+			fmt.Fprintf(&buf, "test ->\n\t")
+		} else {
+			fmt.Fprintf(&buf, "import %q ->\n\t", p.path)
+		}
+	}
+	fmt.Fprintf(&buf, "import %q", pkg.path)
+	return buf.String()
+}
+
+// why returns the text to use in "go mod why" output about the given package.
+// It is less ornate than the stackText but contains the same information.
+func (pkg *loadPkg) why() string {
+	var stack []*loadPkg
+	for p := pkg; p != nil; p = p.stack {
+		stack = append(stack, p)
+	}
+
+	var buf strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		buf.WriteString(stack[i].displayPath())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// anyTags is a special tags map that satisfies nearly all build tag expressions.
+// Only "ignore" and malformed build tag requirements are considered false.
+var anyTags = map[string]bool{"*": true}
+
+// scanDir returns the imports and test imports of the Go package in dir.
+// If tags is nil, scanDir applies the current build context,
+// as the go command does when building. If tags is anyTags,
+// scanDir considers the files for all build tags except "ignore".
+func scanDir(dir string, tags map[string]bool) (imports, testImports []string, err error) {
+	if tags == nil {
+		bp, err := cfg.BuildContext.ImportDir(dir, 0)
+		if err != nil {
+			if _, noGo := err.(*build.NoGoError); noGo {
+				return nil, nil, nil
+			}
+			// Use whatever imports could be found; the
+			// error will be reported when the package is loaded.
+		}
+		if bp == nil {
+			return nil, nil, nil
+		}
+		testImports = append(testImports, bp.TestImports...)
+		testImports = append(testImports, bp.XTestImports...)
+		return bp.Imports, testImports, nil
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.Mode().IsRegular() && !strings.HasPrefix(name, "_") && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return scanFiles(files, tags)
+}
+
+// scanFiles returns the imports and test imports
+// in the named Go source files. Files with a +build ignore
+// line are skipped when tags is anyTags.
+func scanFiles(files []string, tags map[string]bool) (imports, testImports []string, err error) {
+	var imp, testImp []string
+	fset := token.NewFileSet()
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, name, nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tags != nil && isIgnored(f.Comments, f.Package) {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: invalid import path %s", name, spec.Path.Value)
+			}
+			if path == "C" {
+				continue
+			}
+			if strings.HasSuffix(name, "_test.go") {
+				testImp = append(testImp, path)
+			} else {
+				imp = append(imp, path)
+			}
+		}
+	}
+	return dedup(imp), dedup(testImp), nil
+}
+
+// isIgnored reports whether the comments before the package
+// clause include a "+build ignore" constraint.
+func isIgnored(comments []*ast.CommentGroup, pkg token.Pos) bool {
+	for _, g := range comments {
+		if g.Pos() >= pkg {
+			break
+		}
+		for _, c := range g.List {
+			line := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(line, "+build") {
+				continue
+			}
+			for _, tag := range strings.Fields(line[len("+build"):]) {
+				if tag == "ignore" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// dedup returns the sorted list of distinct strings in list.
+func dedup(list []string) []string {
+	sort.Strings(list)
+	out := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
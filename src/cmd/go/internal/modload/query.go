@@ -0,0 +1,220 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	pathpkg "path"
+	"strings"
+
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// Query looks up a version of a given module given a version query string.
+// The module must be a complete module path.
+// The version must take one of the following forms:
+//
+//	- the literal string "latest", denoting the latest available, allowed tagged version,
+//	  with non-prereleases preferred over prereleases.
+//	- v1, denoting the latest available tagged version v1.x.x.
+//	- v1.2, denoting the latest available tagged version v1.2.x.
+//	- v1.2.3, a semantic version string denoting that tagged version.
+//	- <v1.2.3, <=v1.2.3, >v1.2.3, >=v1.2.3,
+//	  denoting the version closest to the target and satisfying the given operator,
+//	  with non-prereleases preferred over prereleases.
+//
+// If the allowed function is non-nil, Query excludes any versions for which allowed returns false.
+func Query(path, query string, allowed func(module.Version) bool) (*modfetch.RevInfo, error) {
+	if allowed == nil {
+		allowed = func(module.Version) bool { return true }
+	}
+
+	// Parse query to detect parse errors (and possibly handle query)
+	// before any network I/O.
+	badVersion := func(v string) (*modfetch.RevInfo, error) {
+		return nil, fmt.Errorf("invalid semantic version %q in range %q", v, query)
+	}
+	var ok func(module.Version) bool
+	var prefix string
+	var preferOlder bool
+	switch {
+	case query == "latest":
+		ok = allowed
+
+	case strings.HasPrefix(query, "<="):
+		v := query[len("<="):]
+		if !semver.IsValid(v) {
+			return badVersion(v)
+		}
+		if isSemverPrefix(v) {
+			// Refuse to say whether <=v1.2 allows v1.2.3 (remember, @v1.2 might mean v1.2.3).
+			return nil, fmt.Errorf("ambiguous semantic version %q in range %q", v, query)
+		}
+		ok = func(m module.Version) bool {
+			return semver.Compare(m.Version, v) <= 0 && allowed(m)
+		}
+
+	case strings.HasPrefix(query, "<"):
+		v := query[len("<"):]
+		if !semver.IsValid(v) {
+			return badVersion(v)
+		}
+		ok = func(m module.Version) bool {
+			return semver.Compare(m.Version, v) < 0 && allowed(m)
+		}
+
+	case strings.HasPrefix(query, ">="):
+		v := query[len(">="):]
+		if !semver.IsValid(v) {
+			return badVersion(v)
+		}
+		ok = func(m module.Version) bool {
+			return semver.Compare(m.Version, v) >= 0 && allowed(m)
+		}
+		preferOlder = true
+
+	case strings.HasPrefix(query, ">"):
+		v := query[len(">"):]
+		if !semver.IsValid(v) {
+			return badVersion(v)
+		}
+		if isSemverPrefix(v) {
+			// Refuse to say whether >v1.2 allows v1.2.3 (remember, @v1.2 might mean v1.2.3).
+			return nil, fmt.Errorf("ambiguous semantic version %q in range %q", v, query)
+		}
+		ok = func(m module.Version) bool {
+			return semver.Compare(m.Version, v) > 0 && allowed(m)
+		}
+		preferOlder = true
+
+	case semver.IsValid(query) && isSemverPrefix(query):
+		ok = func(m module.Version) bool {
+			return matchSemverPrefix(query, m.Version) && allowed(m)
+		}
+		prefix = query + "."
+
+	case semver.IsValid(query):
+		vers := module.CanonicalVersion(query)
+		if !allowed(module.Version{Path: path, Version: vers}) {
+			return nil, fmt.Errorf("%s@%s excluded", path, vers)
+		}
+		return modfetch.Lookup(path).Stat(vers)
+
+	default:
+		return nil, fmt.Errorf("invalid version query %q: must be a semantic version, a version prefix, a version range, or \"latest\"", query)
+	}
+
+	if path == Target.Path {
+		if query != "latest" {
+			return nil, fmt.Errorf("can't query specific version (%q) for the main module (%s)", query, path)
+		}
+		if !allowed(Target) {
+			return nil, fmt.Errorf("internal error: main module version is not allowed")
+		}
+		return &modfetch.RevInfo{Version: Target.Version}, nil
+	}
+
+	// Load versions and execute query.
+	repo := modfetch.Lookup(path)
+	versions, err := repo.Versions(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	v, found := selectVersion(versions, func(v string) bool {
+		return ok(module.Version{Path: path, Version: v})
+	}, preferOlder)
+	if !found {
+		return nil, fmt.Errorf("no matching versions for query %q", query)
+	}
+	return repo.Stat(v)
+}
+
+// selectVersion returns the version in the sorted list versions
+// that Query should choose from those satisfying ok: the newest, or
+// with preferOlder the oldest, with releases preferred over
+// prereleases, including pseudo-versions.
+func selectVersion(versions []string, ok func(string) bool, preferOlder bool) (string, bool) {
+	for _, release := range []bool{true, false} {
+		for i := range versions {
+			v := versions[len(versions)-1-i]
+			if preferOlder {
+				v = versions[i]
+			}
+			if (semver.Prerelease(v) == "") == release && ok(v) {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// QueryPackage looks up a version of the module containing the package
+// with the given import path, trying each prefix of the path as a module
+// path, from longest to shortest.
+// If the path is in the main module, only the query "latest" is allowed,
+// and it returns the main module itself.
+func QueryPackage(path, query string, allowed func(module.Version) bool) (module.Version, *modfetch.RevInfo, error) {
+	if HasModRoot() && maybeInModule(path, Target.Path) {
+		if query != "latest" {
+			return module.Version{}, nil, fmt.Errorf("can't query specific version (%q) for package %s in the main module (%s)", query, path, Target.Path)
+		}
+		if _, ok := dirInModule(path, Target.Path, ModRoot(), true); ok {
+			return Target, &modfetch.RevInfo{Version: Target.Version}, nil
+		}
+	}
+
+	var finalErr error
+	for p := path; p != "." && p != "/"; p = pathpkg.Dir(p) {
+		if p == Target.Path || module.CheckPath(p) != nil {
+			continue
+		}
+		info, err := Query(p, query, allowed)
+		if err != nil {
+			if finalErr == nil {
+				finalErr = err
+			}
+			continue
+		}
+		m := module.Version{Path: p, Version: info.Version}
+		root, isLocal, err := fetch(m)
+		if err != nil {
+			return module.Version{}, nil, err
+		}
+		if _, ok := dirInModule(path, m.Path, root, isLocal); ok {
+			return m, info, nil
+		}
+	}
+	if finalErr == nil {
+		finalErr = fmt.Errorf("cannot find module providing package %s", path)
+	}
+	return module.Version{}, nil, finalErr
+}
+
+// isSemverPrefix reports whether v is a semantic version prefix: v1 or v1.2 (not v1.2.3).
+// The caller is assumed to have checked that semver.IsValid(v) is true.
+func isSemverPrefix(v string) bool {
+	dots := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '-', '+':
+			return false
+		case '.':
+			dots++
+			if dots >= 2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchSemverPrefix reports whether the shortened semantic version p
+// matches the full-width (non-shortened) semantic version v.
+func matchSemverPrefix(p, v string) bool {
+	return len(v) > len(p) && v[len(p)] == '.' && v[:len(p)] == p
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+const (
+	pseudoBase  = "v0.0.0-20180101000000-0123456789ab"
+	pseudoAfter = "v1.2.4-0.20180601000000-ba9876543210"
+)
+
+var isSemverPrefixTests = []struct {
+	v    string
+	want bool
+}{
+	{"v1", true},
+	{"v1.2", true},
+	{"v1.2.3", false},
+	{"v1.2-pre", false},
+	{"v1+build", false},
+	{pseudoAfter, false},
+}
+
+func TestIsSemverPrefix(t *testing.T) {
+	for _, tt := range isSemverPrefixTests {
+		if got := isSemverPrefix(tt.v); got != tt.want {
+			t.Errorf("isSemverPrefix(%q) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+var matchSemverPrefixTests = []struct {
+	p, v string
+	want bool
+}{
+	{"v1", "v1.2.3", true},
+	{"v1.2", "v1.2.3", true},
+	{"v1.2", "v1.20.0", false},
+	{"v1", "v10.0.0", false},
+	{"v1.2", "v1.2", false},
+	{"v1.2", pseudoAfter, true},
+	{"v1.3", pseudoAfter, false},
+	{"v0", pseudoBase, true},
+}
+
+func TestMatchSemverPrefix(t *testing.T) {
+	for _, tt := range matchSemverPrefixTests {
+		if got := matchSemverPrefix(tt.p, tt.v); got != tt.want {
+			t.Errorf("matchSemverPrefix(%q, %q) = %v, want %v", tt.p, tt.v, got, tt.want)
+		}
+	}
+}
+
+var queryErrorTests = []struct {
+	query string
+	err   string
+}{
+	{"<=bad", `invalid semantic version "bad" in range "<=bad"`},
+	{"<bad", `invalid semantic version "bad" in range "<bad"`},
+	{">=bad", `invalid semantic version "bad" in range ">=bad"`},
+	{">bad", `invalid semantic version "bad" in range ">bad"`},
+	{"<=v1.2", `ambiguous semantic version "v1.2" in range "<=v1.2"`},
+	{">v1", `ambiguous semantic version "v1" in range ">v1"`},
+	{"master", `invalid version query "master"`},
+	{"", `invalid version query ""`},
+}
+
+// TestQueryErrors checks that malformed queries are rejected
+// before any module lookup.
+func TestQueryErrors(t *testing.T) {
+	for _, tt := range queryErrorTests {
+		_, err := Query("example.com/m", tt.query, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Query(%q) = %v, want error containing %q", tt.query, err, tt.err)
+		}
+	}
+
+	excluded := func(m module.Version) bool { return m.Version != "v1.0.0" }
+	if _, err := Query("example.com/m", "v1.0.0", excluded); err == nil || err.Error() != "example.com/m@v1.0.0 excluded" {
+		t.Errorf("Query of excluded version = %v, want excluded error", err)
+	}
+}
+
+var selectVersionTests = []struct {
+	query       string
+	ok          func(string) bool
+	preferOlder bool
+	want        string
+}{
+	// A release is preferred over a newer pseudo-version...
+	{"latest", nil, false, "v1.2.3"},
+	{">=v1.0.0", nil, true, "v1.0.0"},
+	{"v1.2", func(v string) bool { return matchSemverPrefix("v1.2", v) }, false, "v1.2.3"},
+	// ...but a pseudo-version is chosen if no release matches.
+	{">v1.2.3", func(v string) bool { return semver.Compare(v, "v1.2.3") > 0 }, true, pseudoAfter},
+	{"<v1.0.0", func(v string) bool { return semver.Compare(v, "v1.0.0") < 0 }, false, "v1.0.0-rc.1"},
+	{"<v1.0.0-rc.1", func(v string) bool { return semver.Compare(v, "v1.0.0-rc.1") < 0 }, false, pseudoBase},
+	{"v2", func(v string) bool { return matchSemverPrefix("v2", v) }, false, ""},
+}
+
+func TestSelectVersion(t *testing.T) {
+	versions := []string{pseudoBase, "v1.0.0-rc.1", "v1.0.0", "v1.2.3", pseudoAfter}
+	for _, tt := range selectVersionTests {
+		ok := tt.ok
+		if ok == nil {
+			ok = func(string) bool { return true }
+		}
+		got, found := selectVersion(versions, ok, tt.preferOlder)
+		if got != tt.want || found != (tt.want != "") {
+			t.Errorf("selectVersion for %q = %q, %v; want %q", tt.query, got, found, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/module"
+)
+
+// matchPackages returns a list of packages in the list of modules
+// matching the pattern. If the pattern's leading path element
+// names a standard library path, the standard library is searched too.
+func matchPackages(pattern string, modules []module.Version) []string {
+	match := func(string) bool { return true }
+	treeCanMatch := func(string) bool { return true }
+	if pattern != "all" {
+		match = load.MatchPattern(pattern)
+		treeCanMatch = load.TreeCanMatchPattern(pattern)
+	}
+
+	have := map[string]bool{
+		"builtin": true, // ignore pseudo-package that exists only for documentation
+	}
+	if !cfg.BuildContext.CgoEnabled {
+		have["runtime/cgo"] = true // ignore during walk
+	}
+	var pkgs []string
+
+	walkPkgs := func(root, importPathRoot string, isLocal bool) {
+		root = filepath.Clean(root)
+		filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			want := true
+			// Avoid .foo, _foo, and testdata directory trees.
+			_, elem := filepath.Split(path)
+			if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" {
+				want = false
+			}
+
+			name := importPathRoot + filepath.ToSlash(path[len(root):])
+			if importPathRoot == "" {
+				name = strings.TrimPrefix(name, "/")
+			}
+			if !treeCanMatch(name) {
+				want = false
+			}
+
+			if !fi.IsDir() {
+				if fi.Mode()&os.ModeSymlink != 0 && want {
+					if target, err := os.Stat(path); err == nil && target.IsDir() {
+						fmt.Fprintf(os.Stderr, "warning: ignoring symlink %s\n", path)
+					}
+				}
+				return nil
+			}
+
+			if !want {
+				return filepath.SkipDir
+			}
+			if path != root {
+				if isLocal {
+					// Stop at module boundaries.
+					if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+						return filepath.SkipDir
+					}
+				}
+				// Vendor directories are not used in module mode,
+				// and GOROOT/src/cmd is only matched by cmd patterns.
+				if path == filepath.Join(root, "vendor") ||
+					importPathRoot == "" && path == filepath.Join(root, "cmd") && !strings.HasPrefix(pattern, "cmd") {
+					return filepath.SkipDir
+				}
+			}
+
+			if !have[name] && name != "" {
+				have[name] = true
+				if match(name) && hasGoFiles(path) {
+					pkgs = append(pkgs, name)
+				}
+			}
+			return nil
+		})
+	}
+
+	if i := strings.Index(pattern, "..."); i >= 0 && isStandardImportPath(pattern[:i]) && pattern[:i] != "" {
+		walkPkgs(cfg.GOROOTsrc, "", false)
+	}
+
+	for _, mod := range modules {
+		if !treeCanMatch(mod.Path) {
+			continue
+		}
+		var root string
+		var isLocal bool
+		if mod == Target {
+			root = ModRoot()
+			isLocal = true
+		} else {
+			var err error
+			root, isLocal, err = fetch(mod)
+			if err != nil {
+				base.Errorf("go: %v", err)
+				continue
+			}
+		}
+		walkPkgs(root, mod.Path, isLocal)
+	}
+
+	return pkgs
+}
+
+// TargetPackages returns the list of packages in the main module.
+func TargetPackages() []string {
+	return matchPackages("all", []module.Version{Target})
+}
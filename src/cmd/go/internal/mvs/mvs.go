@@ -0,0 +1,208 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mvs implements Minimal Version Selection.
+//
+// Each module version lists the minimum versions of the modules it requires.
+// The build list for a target module is computed by taking, for each module
+// path reachable from the target, the maximum of the versions required
+// along any path. Because only the listed minimums are consulted,
+// the result does not change when newer versions are published.
+package mvs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/module"
+)
+
+// A Reqs is the requirement graph on which Minimal Version Selection (MVS) operates.
+//
+// The version strings are opaque except for the special version "none"
+// (see the documentation for module.Version). In particular, MVS does not
+// assume that the version strings are semantic versions; instead, the Max method
+// gives access to the comparison operation.
+type Reqs interface {
+	// Required returns the module versions explicitly required by m itself.
+	// The caller must not modify the returned list.
+	Required(m module.Version) ([]module.Version, error)
+
+	// Max returns the maximum of v1 and v2 (it returns either v1 or v2).
+	//
+	// For all versions v, Max(v, "none") must be v,
+	// and for the target passed as the first argument to MVS functions,
+	// Max(target, v) must be target.
+	//
+	// Note that v1 < v2 can be written Max(v1, v2) != v1
+	// and similarly v1 <= v2 can be written Max(v1, v2) == v2.
+	Max(v1, v2 string) string
+}
+
+// A MissingModuleError reports a failure to load the requirements
+// of a module version, along with the chain of requirements that led to it.
+type MissingModuleError struct {
+	Module module.Version
+	Stack  []module.Version // path from the target to Module, inclusive
+	Err    error
+}
+
+func (e *MissingModuleError) Error() string {
+	var buf strings.Builder
+	for i, m := range e.Stack {
+		if i > 0 {
+			buf.WriteString(" ->\n\t")
+		}
+		buf.WriteString(m.String())
+	}
+	if buf.Len() > 0 {
+		return fmt.Sprintf("%s: %v", buf.String(), e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Module, e.Err)
+}
+
+// BuildList returns the build list for the target module.
+// The first element is the target itself, followed by the selected
+// versions of all other modules in the requirement graph, sorted by path.
+func BuildList(target module.Version, reqs Reqs) ([]module.Version, error) {
+	// Explore the full requirement graph, remembering for each
+	// module the requirements and the path by which it was reached.
+	required := map[module.Version][]module.Version{}
+	parent := map[module.Version]module.Version{}
+	max := map[string]string{}
+	queue := []module.Version{target}
+	seen := map[module.Version]bool{target: true}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		if v, ok := max[m.Path]; !ok || reqs.Max(v, m.Version) != v {
+			max[m.Path] = m.Version
+		}
+		if m.Version == "none" {
+			continue
+		}
+		list, err := reqs.Required(m)
+		if err != nil {
+			var stack []module.Version
+			for n := m; ; n = parent[n] {
+				stack = append([]module.Version{n}, stack...)
+				if n == target {
+					break
+				}
+			}
+			return nil, &MissingModuleError{Module: m, Stack: stack, Err: err}
+		}
+		required[m] = list
+		for _, r := range list {
+			if !seen[r] {
+				seen[r] = true
+				parent[r] = m
+				queue = append(queue, r)
+			}
+		}
+	}
+	if v := max[target.Path]; reqs.Max(target.Version, v) != target.Version {
+		panic(fmt.Sprintf("mistake: %v chose %v instead of target %v", target.Path, v, target.Version))
+	}
+
+	// Walk the graph again using only the selected versions,
+	// so that modules required only by unselected versions drop out.
+	list := []module.Version{target}
+	listed := map[string]bool{target.Path: true}
+	for i := 0; i < len(list); i++ {
+		for _, r := range required[list[i]] {
+			v := max[r.Path]
+			if listed[r.Path] || v == "none" {
+				continue
+			}
+			listed[r.Path] = true
+			list = append(list, module.Version{Path: r.Path, Version: v})
+		}
+	}
+	tail := list[1:]
+	sort.Slice(tail, func(i, j int) bool {
+		return tail[i].Path < tail[j].Path
+	})
+	return list, nil
+}
+
+// Req returns the minimal requirement list for the target module
+// that results in the given build list, with the constraint that all
+// module paths listed in base must appear in the returned list.
+func Req(target module.Version, list []module.Version, base []string, reqs Reqs) ([]module.Version, error) {
+	// Compute postorder, caching requirements.
+	// The list came from a previous BuildList call,
+	// so the requirements are usually already loaded.
+	var postorder []module.Version
+	reqCache := map[module.Version][]module.Version{}
+	reqCache[target] = nil
+	var walk func(module.Version) error
+	walk = func(m module.Version) error {
+		if _, ok := reqCache[m]; ok {
+			return nil
+		}
+		required, err := reqs.Required(m)
+		if err != nil {
+			return err
+		}
+		reqCache[m] = required
+		for _, m1 := range required {
+			if err := walk(m1); err != nil {
+				return err
+			}
+		}
+		postorder = append(postorder, m)
+		return nil
+	}
+	for _, m := range list {
+		if err := walk(m); err != nil {
+			return nil, err
+		}
+	}
+
+	// Walk modules in reverse post-order, only adding those not implied already.
+	have := map[string]string{}
+	var mark func(module.Version)
+	mark = func(m module.Version) {
+		if v, ok := have[m.Path]; ok && reqs.Max(m.Version, v) == v {
+			return
+		}
+		have[m.Path] = m.Version
+		for _, m1 := range reqCache[m] {
+			mark(m1)
+		}
+	}
+	max := map[string]string{}
+	for _, m := range list {
+		if v, ok := max[m.Path]; ok {
+			max[m.Path] = reqs.Max(m.Version, v)
+		} else {
+			max[m.Path] = m.Version
+		}
+	}
+	// First walk the base modules that must be listed.
+	var min []module.Version
+	for _, path := range base {
+		m := module.Version{Path: path, Version: max[path]}
+		min = append(min, m)
+		mark(m)
+	}
+	// Now the reverse postorder to bring in anything else.
+	for i := len(postorder) - 1; i >= 0; i-- {
+		m := postorder[i]
+		if max[m.Path] != m.Version {
+			// Older version.
+			continue
+		}
+		if have[m.Path] != m.Version {
+			min = append(min, m)
+			mark(m)
+		}
+	}
+	sort.Slice(min, func(i, j int) bool {
+		return min[i].Path < min[j].Path
+	})
+	return min, nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mvs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+)
+
+// reqsMap is a Reqs implementation for testing.
+// Versions are single letters or digits compared as strings,
+// and "none" is less than all of them.
+type reqsMap map[module.Version][]module.Version
+
+func (r reqsMap) Max(v1, v2 string) string {
+	if v1 == "none" || v2 == "" {
+		return v2
+	}
+	if v2 == "none" || v1 == "" {
+		return v1
+	}
+	if v1 < v2 {
+		return v2
+	}
+	return v1
+}
+
+func (r reqsMap) Required(m module.Version) ([]module.Version, error) {
+	rr, ok := r[m]
+	if !ok {
+		return nil, fmt.Errorf("missing module: %v", m)
+	}
+	return rr, nil
+}
+
+// parseGraph parses lines of the form "A1: B1 C2" into a reqsMap.
+// The first character of each name is the module path
+// and the rest is the version.
+func parseGraph(t *testing.T, text string) reqsMap {
+	r := reqsMap{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			t.Fatalf("bad graph line %q", line)
+		}
+		m := mv(line[:i])
+		r[m] = []module.Version{}
+		for _, f := range strings.Fields(line[i+1:]) {
+			r[m] = append(r[m], mv(f))
+		}
+	}
+	return r
+}
+
+func mv(s string) module.Version {
+	if s == "A" {
+		return module.Version{Path: "A"}
+	}
+	return module.Version{Path: s[:1], Version: s[1:]}
+}
+
+func mvList(list []module.Version) string {
+	var names []string
+	for _, m := range list {
+		names = append(names, m.Path+m.Version)
+	}
+	return strings.Join(names, " ")
+}
+
+var buildListTests = []struct {
+	name  string
+	graph string
+	want  string
+}{
+	{
+		name: "blog",
+		graph: `
+			A: B1 C2
+			B1: D3
+			C1: D2
+			C2: D4
+			C3: D5
+			C4: G1
+			D2: E1
+			D3: E2
+			D4: E2 F1
+			D5: E2
+			G1: C4
+			E1:
+			E2:
+			E3:
+			F1:
+		`,
+		want: "A B1 C2 D4 E2 F1",
+	},
+	{
+		name: "drop",
+		graph: `
+			A: B1 C1
+			B1: D1
+			C1: D2
+			D1: E2
+			D2:
+			E2:
+		`,
+		// D1 requires E2, but D1 is not selected, so E drops out.
+		want: "A B1 C1 D2",
+	},
+	{
+		name: "none",
+		graph: `
+			A: B1 Cnone
+			B1: C1
+			C1:
+		`,
+		want: "A B1 C1",
+	},
+	{
+		name: "cycle",
+		graph: `
+			A: B1
+			B1: C1
+			C1: B2
+			B2: A
+		`,
+		// C1 is required only by B1, which is not selected.
+		want: "A B2",
+	},
+	{
+		name: "upgrade",
+		graph: `
+			A: B2 C1
+			B1: C1
+			B2: C2
+			C1:
+			C2:
+		`,
+		// Upgrading B to B2 upgrades C as well.
+		want: "A B2 C2",
+	},
+	{
+		name: "downgrade",
+		graph: `
+			A: B1 C1
+			B1: C1
+			B2: C2
+			C1:
+			C2:
+		`,
+		// With A lowered to B1, nothing requires C2 any longer.
+		want: "A B1 C1",
+	},
+	{
+		name: "downgrade blocked",
+		graph: `
+			A: B1 C1
+			B1:
+			B2:
+			C1: B2
+		`,
+		// A lowers its requirement on B, but C1 still requires B2.
+		want: "A B2 C1",
+	},
+	{
+		name: "downgrade to none",
+		graph: `
+			A: Bnone C1
+			C1: D1
+			D1:
+		`,
+		want: "A C1 D1",
+	},
+}
+
+func TestBuildList(t *testing.T) {
+	for _, tt := range buildListTests {
+		reqs := parseGraph(t, tt.graph)
+		list, err := BuildList(module.Version{Path: "A"}, reqs)
+		if err != nil {
+			t.Errorf("%s: BuildList: %v", tt.name, err)
+			continue
+		}
+		if got := mvList(list); got != tt.want {
+			t.Errorf("%s: BuildList = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildListMissing(t *testing.T) {
+	reqs := parseGraph(t, `
+		A: B1
+		B1: C1
+	`)
+	_, err := BuildList(module.Version{Path: "A"}, reqs)
+	merr, ok := err.(*MissingModuleError)
+	if !ok {
+		t.Fatalf("BuildList error = %v, want *MissingModuleError", err)
+	}
+	want := []module.Version{mv("A"), mv("B1"), mv("C1")}
+	if !reflect.DeepEqual(merr.Stack, want) {
+		t.Errorf("Stack = %v, want %v", merr.Stack, want)
+	}
+}
+
+var reqTests = []struct {
+	name  string
+	graph string
+	base  []string
+	want  string
+}{
+	{
+		name: "implied",
+		graph: `
+			A: B1 C1 D1 E1
+			B1: D1
+			C1: E1
+			D1:
+			E1:
+		`,
+		want: "B1 C1",
+	},
+	{
+		name: "base",
+		graph: `
+			A: B1 C1 D1 E1
+			B1: D1
+			C1: E1
+			D1:
+			E1:
+		`,
+		base: []string{"D"},
+		want: "B1 C1 D1",
+	},
+	{
+		name: "upgraded",
+		graph: `
+			A: B1 C2
+			B1: C1
+			C1:
+			C2:
+		`,
+		// B1 requires only C1, so A must keep its requirement on C2.
+		want: "B1 C2",
+	},
+	{
+		name: "downgrade blocked",
+		graph: `
+			A: B1 C1
+			B1:
+			B2:
+			C1: B2
+		`,
+		// C1 implies B2, so the requirement on B1 is redundant.
+		want: "C1",
+	},
+}
+
+func TestReq(t *testing.T) {
+	for _, tt := range reqTests {
+		reqs := parseGraph(t, tt.graph)
+		list, err := BuildList(module.Version{Path: "A"}, reqs)
+		if err != nil {
+			t.Errorf("%s: BuildList: %v", tt.name, err)
+			continue
+		}
+		min, err := Req(module.Version{Path: "A"}, list, tt.base, reqs)
+		if err != nil {
+			t.Errorf("%s: Req: %v", tt.name, err)
+			continue
+		}
+		if got := mvList(min); got != tt.want {
+			t.Errorf("%s: Req = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -44,6 +44,7 @@ func init() {
 	CmdRun.Run = runRun // break init loop
 
 	work.AddBuildFlags(CmdRun)
+	work.AddCoverFlags(CmdRun)
 	CmdRun.Flag.Var((*base.StringsFlag)(&work.ExecCmd), "exec", "")
 }
 
@@ -93,6 +94,7 @@ func runRun(cmd *base.Command, args []string) {
 	if p.Name != "main" {
 		base.Fatalf("go run: cannot run non-main package")
 	}
+	work.PrepareCover([]*load.Package{p})
 	p.Target = "" // must build - not up to date
 	var src string
 	if len(p.GoFiles) > 0 {
@@ -62,8 +62,8 @@ followed by detailed output for each failed package.
 
 'Go test' recompiles each package along with any files with names matching
 the file pattern "*_test.go".
-These additional files can contain test functions, benchmark functions, and
-example functions. See 'go help testfunc' for more.
+These additional files can contain test functions, benchmark functions, fuzz
+targets, and example functions. See 'go help testfunc' for more.
 Each listed package causes the execution of a separate test binary.
 Files whose names begin with "_" (including "_test.go") or "." are ignored.
 
@@ -147,6 +147,8 @@ In addition to the build flags, the flags handled by 'go test' itself are:
 
 	-json
 	    Convert test output to JSON suitable for automated processing.
+	    The output of building the tests, including build and vet
+	    errors, is also reported as JSON events, on standard output.
 	    See 'go doc test2json' for the encoding details.
 
 	-o file
@@ -240,10 +242,30 @@ const testFlag2 = `
 	-failfast
 	    Do not start new tests after the first test failure.
 
+	-fuzz regexp
+	    Run the fuzz target matching the regular expression. When specified,
+	    the command line argument must match exactly one package, and regexp
+	    must match exactly one fuzz target within that package. After tests,
+	    fuzz targets' seed corpora, and examples have completed, the fuzz
+	    target is run with inputs generated by mutating its seed corpus,
+	    guided by coverage instrumentation of the package under test.
+	    Inputs that expand coverage are kept in the build cache.
+	    An input that makes the fuzz target fail is written to
+	    testdata/fuzz/FuzzXxx, where later runs of go test replay it.
+	    See 'go help testfunc' for details.
+
+	-fuzztime t
+	    Run enough iterations of the fuzz target to take t, specified as
+	    a time.Duration (for example, -fuzztime 1h30s), or run exactly
+	    n iterations when written as nx (for example, -fuzztime 1000x).
+	    The default is to run until an input fails or go test is
+	    interrupted.
+
 	-list regexp
-	    List tests, benchmarks, or examples matching the regular expression.
-	    No tests, benchmarks or examples will be run. This will only
-	    list top-level tests. No subtest or subbenchmarks will be shown.
+	    List tests, benchmarks, fuzz targets, or examples matching the
+	    regular expression. No tests, benchmarks, fuzz targets, or examples
+	    will be run. This will only list top-level tests. No subtest or
+	    subbenchmarks will be shown.
 
 	-parallel n
 	    Allow parallel execution of test functions that call t.Parallel.
@@ -277,7 +299,8 @@ const testFlag2 = `
 
 	-v
 	    Verbose output: log all tests as they are run. Also print all
-	    text from Log and Logf calls even if the test succeeds.
+	    text from Log and Logf calls, as they are made, even if the
+	    test succeeds, and the attributes set with Attr.
 
 	-vet list
 	    Configure the invocation of "go vet" during "go test"
@@ -403,8 +426,8 @@ var HelpTestfunc = &base.Command{
 	UsageLine: "testfunc",
 	Short:     "description of testing functions",
 	Long: `
-The 'go test' command expects to find test, benchmark, and example functions
-in the "*_test.go" files corresponding to the package under test.
+The 'go test' command expects to find test, benchmark, fuzz target, and example
+functions in the "*_test.go" files corresponding to the package under test.
 
 A test function is one named TestXXX (where XXX is any alphanumeric string
 not starting with a lower case letter) and should have the signature,
@@ -415,6 +438,16 @@ A benchmark function is one named BenchmarkXXX and should have the signature,
 
 	func BenchmarkXXX(b *testing.B) { ... }
 
+A fuzz target is one named FuzzXXX and should have the signature,
+
+	func FuzzXXX(f *testing.F) { ... }
+
+The fuzz target adds seed inputs with f.Add and passes the function to
+fuzz to f.Fuzz. Without the -fuzz flag, go test calls that function once
+for each seed input, including those stored in files in the directory
+testdata/fuzz/FuzzXXX. With -fuzz, go test also calls it with new inputs
+derived from the seed inputs, as described in 'go help testflag'.
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 If the last comment in the function starts with "Output:" then the output
@@ -467,6 +500,8 @@ var (
 	testCoverPaths   []string        // -coverpkg flag
 	testCoverPkgs    []*load.Package // -coverpkg flag
 	testCoverProfile string          // -coverprofile flag
+	testFuzz         string          // -fuzz flag
+	testFuzzCover    bool            // instrument the package under test to guide -fuzz
 	testOutputDir    string          // -outputdir flag
 	testO            string          // -o flag
 	testProfile      string          // profiling flag that limits test to one package
@@ -541,6 +576,22 @@ func runTest(cmd *base.Command, args []string) {
 	if testProfile != "" && len(pkgs) != 1 {
 		base.Fatalf("cannot use %s flag with multiple packages", testProfile)
 	}
+	if testFuzz != "" {
+		if len(pkgs) != 1 {
+			base.Fatalf("cannot use -fuzz flag with multiple packages")
+		}
+		if !testCover {
+			// The fuzzer uses the coverage counters of the package
+			// under test to decide which inputs are interesting.
+			// Count mode lets it tell apart inputs that run a block
+			// different numbers of times.
+			testFuzzCover = true
+			testCoverMode = "count"
+			if cfg.BuildRace {
+				testCoverMode = "atomic"
+			}
+		}
+	}
 	initCoverProfile()
 	defer closeCoverProfile()
 
@@ -550,8 +601,10 @@ func runTest(cmd *base.Command, args []string) {
 	// timer does not get a chance to fire.
 	if dt, err := time.ParseDuration(testTimeout); err == nil && dt > 0 {
 		testKillTimeout = dt + 1*time.Minute
-	} else if err == nil && dt == 0 {
+	} else if err == nil && dt == 0 || testTimeout == "" && testFuzz != "" {
 		// An explicit zero disables the test timeout.
+		// So does fuzzing, which runs until -fuzztime expires
+		// or an input fails, unless a timeout was given.
 		// Let it have one century (almost) before we kill it.
 		testKillTimeout = 100 * 365 * 24 * time.Hour
 	}
@@ -579,6 +632,9 @@ func runTest(cmd *base.Command, args []string) {
 
 	var b work.Builder
 	b.Init()
+	if testJSON {
+		b.Print = printBuildOutput
+	}
 
 	if cfg.BuildI {
 		cfg.BuildV = testV
@@ -626,6 +682,9 @@ func runTest(cmd *base.Command, args []string) {
 			return
 		}
 		b.Init()
+		if testJSON {
+			b.Print = printBuildOutput
+		}
 	}
 
 	var builds, runs, prints []*work.Action
@@ -669,9 +728,9 @@ func runTest(cmd *base.Command, args []string) {
 			coverFiles = append(coverFiles, p.GoFiles...)
 			coverFiles = append(coverFiles, p.CgoFiles...)
 			coverFiles = append(coverFiles, p.TestGoFiles...)
-			p.Internal.CoverVars = declareCoverVars(p.ImportPath, coverFiles...)
+			p.Internal.CoverVars = load.DeclareCoverVars(p.ImportPath, coverFiles...)
 			if testCover && testCoverMode == "atomic" {
-				ensureImport(p, "sync/atomic")
+				load.EnsureImport(p, "sync/atomic")
 			}
 		}
 	}
@@ -679,8 +738,8 @@ func runTest(cmd *base.Command, args []string) {
 	// Prepare build + run + print actions for all packages being tested.
 	for _, p := range pkgs {
 		// sync/atomic import is inserted by the cover tool. See #18486
-		if testCover && testCoverMode == "atomic" {
-			ensureImport(p, "sync/atomic")
+		if (testCover || testFuzzCover) && testCoverMode == "atomic" {
+			load.EnsureImport(p, "sync/atomic")
 		}
 
 		buildTest, runTest, printTest, err := builderTest(&b, p)
@@ -730,22 +789,6 @@ func runTest(cmd *base.Command, args []string) {
 	b.Do(root)
 }
 
-// ensures that package p imports the named package
-func ensureImport(p *load.Package, pkg string) {
-	for _, d := range p.Internal.Imports {
-		if d.Name == pkg {
-			return
-		}
-	}
-
-	p1 := load.LoadPackage(pkg, &load.ImportStack{})
-	if p1.Error != nil {
-		base.Fatalf("load %s: %v", pkg, p1.Error)
-	}
-
-	p.Internal.Imports = append(p.Internal.Imports, p1)
-}
-
 var windowsBadWords = []string{
 	"install",
 	"patch",
@@ -819,6 +862,16 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 	}
 	stk.Pop()
 
+	// Resolve the //go:embed patterns in the test files.
+	_, testEmbed, err := p.ResolveEmbed(p.TestEmbedPatterns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, xtestEmbed, err := p.ResolveEmbed(p.XTestEmbedPatterns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Use last element of import path, not package name.
 	// They differ when package name is "main".
 	// But if the import path is "command-line-arguments",
@@ -835,7 +888,8 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 	// only for this package and only for this test?
 	// Yes, if -cover is on but -coverpkg has not specified
 	// a list of packages for global coverage.
-	localCover := testCover && testCoverPaths == nil
+	// Fuzzing always instruments the package under test.
+	localCover := testCover && testCoverPaths == nil || testFuzzCover
 
 	// Test package.
 	if len(p.TestGoFiles) > 0 || localCover || p.Name == "main" {
@@ -871,12 +925,24 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 		}
 		ptest.Internal.Build.ImportPos = m
 
+		if len(p.TestEmbedPatterns) > 0 {
+			ptest.EmbedPatterns = str.StringList(p.EmbedPatterns, p.TestEmbedPatterns)
+			ptest.EmbedFiles = str.StringList(p.EmbedFiles, p.TestEmbedFiles)
+			ptest.Internal.Embed = make(map[string][]string)
+			for pattern, files := range p.Internal.Embed {
+				ptest.Internal.Embed[pattern] = files
+			}
+			for pattern, files := range testEmbed {
+				ptest.Internal.Embed[pattern] = files
+			}
+		}
+
 		if localCover {
 			ptest.Internal.CoverMode = testCoverMode
 			var coverFiles []string
 			coverFiles = append(coverFiles, ptest.GoFiles...)
 			coverFiles = append(coverFiles, ptest.CgoFiles...)
-			ptest.Internal.CoverVars = declareCoverVars(ptest.ImportPath, coverFiles...)
+			ptest.Internal.CoverVars = load.DeclareCoverVars(ptest.ImportPath, coverFiles...)
 		}
 	} else {
 		ptest = p
@@ -892,6 +958,9 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 				Dir:        p.Dir,
 				GoFiles:    p.XTestGoFiles,
 				Imports:    p.XTestImports,
+
+				EmbedPatterns: p.XTestEmbedPatterns,
+				EmbedFiles:    p.XTestEmbedFiles,
 			},
 			Internal: load.PackageInternal{
 				LocalPrefix: p.Internal.LocalPrefix,
@@ -900,6 +969,7 @@ func builderTest(b *work.Builder, p *load.Package) (buildAction, runAction, prin
 				},
 				Imports:    ximports,
 				RawImports: rawXTestImports,
+				Embed:      xtestEmbed,
 
 				Asmflags:   p.Internal.Asmflags,
 				Gcflags:    p.Internal.Gcflags,
@@ -1194,31 +1264,6 @@ func recompileForTest(pmain, preal, ptest *load.Package) {
 	}
 }
 
-// isTestFile reports whether the source file is a set of tests and should therefore
-// be excluded from coverage analysis.
-func isTestFile(file string) bool {
-	// We don't cover tests, only the code they test.
-	return strings.HasSuffix(file, "_test.go")
-}
-
-// declareCoverVars attaches the required cover variables names
-// to the files, to be used when annotating the files.
-func declareCoverVars(importPath string, files ...string) map[string]*load.CoverVar {
-	coverVars := make(map[string]*load.CoverVar)
-	coverIndex := 0
-	for _, file := range files {
-		if isTestFile(file) {
-			continue
-		}
-		coverVars[file] = &load.CoverVar{
-			File: filepath.Join(importPath, file),
-			Var:  fmt.Sprintf("GoCover_%d", coverIndex),
-		}
-		coverIndex++
-	}
-	return coverVars
-}
-
 var noTestsToRun = []byte("\ntesting: warning: no tests to run\n")
 
 type runCache struct {
@@ -1244,6 +1289,50 @@ func (lockedStdout) Write(b []byte) (int, error) {
 	return os.Stdout.Write(b)
 }
 
+// printBuildOutput is the Builder's Print function with -json.
+// It writes the output of the build to standard output as JSON events.
+func printBuildOutput(args ...interface{}) (int, error) {
+	out := fmt.Sprint(args...)
+	test2json.WriteBuildOutput(lockedStdout{}, []byte(out), test2json.Timestamp)
+	return len(out), nil
+}
+
+// failedBuild returns the import path of the package whose failed build
+// caused the failure of action a, which depends on it.
+func failedBuild(a *work.Action) string {
+	for _, a1 := range a.Deps {
+		if a1.Failed {
+			if p := failedBuild(a1); p != "" {
+				return p
+			}
+		}
+	}
+	if a.Package != nil {
+		return a.Package.ImportPath
+	}
+	return ""
+}
+
+var buildFail struct {
+	sync.Mutex
+	reported map[string]bool
+}
+
+// writeBuildFail writes the build-fail event for the package
+// to standard output, if it has not already been written.
+func writeBuildFail(pkg string) {
+	buildFail.Lock()
+	defer buildFail.Unlock()
+	if buildFail.reported[pkg] {
+		return
+	}
+	if buildFail.reported == nil {
+		buildFail.reported = make(map[string]bool)
+	}
+	buildFail.reported[pkg] = true
+	test2json.WriteBuildFail(lockedStdout{}, pkg, test2json.Timestamp)
+}
+
 // builderRunTest is the action for running a test binary.
 func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 	if c.buf == nil {
@@ -1258,16 +1347,37 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 		// c.saveOutput will store the result under both IDs.
 		c.tryCacheWithID(b, a, a.Deps[0].BuildContentID())
 	}
+
+	var stdout io.Writer = os.Stdout
+	var json *test2json.Converter
+	if testJSON {
+		json = test2json.NewConverter(lockedStdout{}, a.Package.ImportPath, test2json.Timestamp)
+		defer json.Close()
+		stdout = json
+	}
+
 	if c.buf != nil {
+		if testJSON {
+			// The cached output is the output of the test binary.
+			json.Write(c.buf.Bytes())
+			c.buf.Reset()
+		}
 		a.TestOutput = c.buf
 		return nil
 	}
 
 	if a.Failed {
 		// We were unable to build the binary.
-		a.Failed = false
 		a.TestOutput = new(bytes.Buffer)
-		fmt.Fprintf(a.TestOutput, "FAIL\t%s [build failed]\n", a.Package.ImportPath)
+		var out io.Writer = a.TestOutput
+		if testJSON {
+			pkg := failedBuild(a)
+			writeBuildFail(pkg)
+			json.SetFailedBuild(pkg)
+			out = json
+		}
+		a.Failed = false
+		fmt.Fprintf(out, "FAIL\t%s [build failed]\n", a.Package.ImportPath)
 		base.SetExitStatus(1)
 		return nil
 	}
@@ -1277,7 +1387,15 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 	if !c.disableCache && len(execCmd) == 0 {
 		testlogArg = []string{"-test.testlogfile=" + a.Objdir + "testlog.txt"}
 	}
-	args := str.StringList(execCmd, a.Deps[0].Target, testlogArg, testArgs)
+	fuzzArg := []string{}
+	if testFuzz != "" {
+		// Keep the inputs that expanded coverage in the build cache,
+		// so that later runs can start from them.
+		if dir := cache.DefaultDir(); dir != "off" {
+			fuzzArg = []string{"-test.fuzzcachedir=" + filepath.Join(dir, "fuzz", a.Package.ImportPath)}
+		}
+	}
+	args := str.StringList(execCmd, a.Deps[0].Target, testlogArg, fuzzArg, testArgs)
 
 	if testCoverProfile != "" {
 		// Write coverage to temporary profile, for merging later.
@@ -1299,16 +1417,10 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 	cmd.Dir = a.Package.Dir
 	cmd.Env = base.EnvForDir(cmd.Dir, cfg.OrigEnv)
 	var buf bytes.Buffer
-	var stdout io.Writer = os.Stdout
-	if testJSON {
-		json := test2json.NewConverter(lockedStdout{}, a.Package.ImportPath, test2json.Timestamp)
-		defer json.Close()
-		stdout = json
-	}
-	if len(pkgArgs) == 0 || testBench {
+	if len(pkgArgs) == 0 || testBench || testFuzz != "" {
 		// Stream test output (no buffering) when no package has
 		// been given on the command line (implicit current directory)
-		// or when benchmarking.
+		// or when benchmarking or fuzzing.
 		cmd.Stdout = stdout
 	} else {
 		// If we're only running a single package under test or if parallelism is
@@ -1398,7 +1510,9 @@ func (c *runCache) builderRunTest(b *work.Builder, a *work.Action) error {
 
 	if err == nil {
 		norun := ""
-		if !testShowPass {
+		if !testShowPass && !testJSON {
+			// With -json, keep the output of the test binary,
+			// so that the cached result replays its events.
 			buf.Reset()
 		}
 		if bytes.HasPrefix(out, noTestsToRun[1:]) || bytes.Contains(out, noTestsToRun) {
@@ -1796,7 +1910,7 @@ func builderNoTest(b *work.Builder, a *work.Action) error {
 }
 
 // isTestFunc tells whether fn has the type of a testing function. arg
-// specifies the parameter type we look for: B, M or T.
+// specifies the parameter type we look for: B, F, M or T.
 func isTestFunc(fn *ast.FuncDecl, arg string) bool {
 	if fn.Type.Results != nil && len(fn.Type.Results.List) > 0 ||
 		fn.Type.Params.List == nil ||
@@ -1811,7 +1925,7 @@ func isTestFunc(fn *ast.FuncDecl, arg string) bool {
 	// We can't easily check that the type is *testing.M
 	// because we don't know how testing has been imported,
 	// but at least check that it's *M or *something.M.
-	// Same applies for B and T.
+	// Same applies for B, F and T.
 	if name, ok := ptr.X.(*ast.Ident); ok && name.Name == arg {
 		return true
 	}
@@ -1876,6 +1990,7 @@ func writeTestmain(out string, t *testFuncs) error {
 type testFuncs struct {
 	Tests       []testFunc
 	Benchmarks  []testFunc
+	FuzzTargets []testFunc
 	Examples    []testFunc
 	TestMain    *testFunc
 	Package     *load.Package
@@ -1886,12 +2001,18 @@ type testFuncs struct {
 	Cover       []coverInfo
 }
 
+// CoverMode returns the coverage mode reported to the testing package.
+// When the package is instrumented only to guide fuzzing, the mode is empty
+// so that the test binary does not print a coverage report.
 func (t *testFuncs) CoverMode() string {
+	if !testCover {
+		return ""
+	}
 	return testCoverMode
 }
 
 func (t *testFuncs) CoverEnabled() bool {
-	return testCover
+	return testCover || testFuzzCover
 }
 
 // ImportPath returns the import path of the package being tested, if it is within GOPATH.
@@ -1976,6 +2097,13 @@ func (t *testFuncs) load(filename, pkg string, doImport, seen *bool) error {
 			}
 			t.Benchmarks = append(t.Benchmarks, testFunc{pkg, name, "", false})
 			*doImport, *seen = true, true
+		case isTest(name, "Fuzz"):
+			err := checkTestFunc(n, "F")
+			if err != nil {
+				return err
+			}
+			t.FuzzTargets = append(t.FuzzTargets, testFunc{pkg, name, "", false})
+			*doImport, *seen = true, true
 		}
 	}
 	ex := doc.Examples(f)
@@ -2034,6 +2162,12 @@ var benchmarks = []testing.InternalBenchmark{
 {{end}}
 }
 
+var fuzzTargets = []testing.InternalFuzzTarget{
+{{range .FuzzTargets}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{end}}
+}
+
 var examples = []testing.InternalExample{
 {{range .Examples}}
 	{"{{.Name}}", {{.Package}}.{{.Name}}, {{.Output | printf "%q"}}, {{.Unordered}}},
@@ -2092,7 +2226,7 @@ func main() {
 		CoveredPackages: {{printf "%q" .Covered}},
 	})
 {{end}}
-	m := testing.MainStart(testdeps.TestDeps{}, tests, benchmarks, examples)
+	m := testing.MainStart(testdeps.TestDeps{}, tests, benchmarks, fuzzTargets, examples)
 {{with .TestMain}}
 	{{.Package}}.{{.Name}}(m)
 {{else}}
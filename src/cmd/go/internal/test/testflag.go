@@ -47,6 +47,8 @@ var testFlagDefn = []*cmdflag.Defn{
 	{Name: "cpu", PassToTest: true},
 	{Name: "cpuprofile", PassToTest: true},
 	{Name: "failfast", BoolVar: new(bool), PassToTest: true},
+	{Name: "fuzz", PassToTest: true},
+	{Name: "fuzztime", PassToTest: true},
 	{Name: "list", PassToTest: true},
 	{Name: "memprofile", PassToTest: true},
 	{Name: "memprofilerate", PassToTest: true},
@@ -136,9 +138,6 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 			// bool flags.
 			case "c", "i", "v", "cover", "json":
 				cmdflag.SetBool(cmd, f.BoolVar, value)
-				if f.Name == "json" && testJSON {
-					passToTest = append(passToTest, "-test.v")
-				}
 			case "o":
 				testO = value
 				testNeedBinary = true
@@ -153,6 +152,8 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 				testBench = true
 			case "list":
 				testList = true
+			case "fuzz":
+				testFuzz = value
 			case "timeout":
 				testTimeout = value
 			case "blockprofile", "cpuprofile", "memprofile", "mutexprofile":
@@ -227,6 +228,12 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 		passToTest = append(passToTest, "-test.outputdir", dir)
 	}
 
+	if testJSON {
+		// Ask for verbose output framed for test2json,
+		// overriding any -v flag.
+		passToTest = append(passToTest, "-test.v=test2json")
+	}
+
 	passToTest = append(passToTest, explicitArgs...)
 	return
 }
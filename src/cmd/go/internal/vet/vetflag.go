@@ -5,9 +5,13 @@
 package vet
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"cmd/go/internal/base"
@@ -57,28 +61,101 @@ var vetFlagDefn = []*cmdflag.Defn{
 
 var vetTool string
 
+// buildFlagDefn is the set of build flags accepted by go vet,
+// including -vettool.
+var buildFlagDefn []*cmdflag.Defn
+
 // add build flags to vetFlagDefn.
 func init() {
 	var cmd base.Command
 	work.AddBuildFlags(&cmd)
-	cmd.Flag.StringVar(&vetTool, "vettool", "", "path to vet tool binary") // for cmd/vet tests; undocumented for now
+	cmd.Flag.StringVar(&vetTool, "vettool", "", "path to vet tool binary")
 	cmd.Flag.VisitAll(func(f *flag.Flag) {
-		vetFlagDefn = append(vetFlagDefn, &cmdflag.Defn{
+		buildFlagDefn = append(buildFlagDefn, &cmdflag.Defn{
 			Name:  f.Name,
 			Value: f.Value,
 		})
 	})
+	vetFlagDefn = append(vetFlagDefn, buildFlagDefn...)
+}
+
+// toolFlagDefn returns the flag definitions for running the vet
+// tool at path: the flags the tool reports in response to -flags,
+// together with the build flags.
+func toolFlagDefn(tool string) []*cmdflag.Defn {
+	tool, err := filepath.Abs(tool)
+	if err != nil {
+		base.Fatalf("%v", err)
+	}
+	out := new(bytes.Buffer)
+	vetcmd := exec.Command(tool, "-flags")
+	vetcmd.Stdout = out
+	if err := vetcmd.Run(); err != nil {
+		base.Fatalf("can't execute %s -flags: %v", tool, err)
+	}
+	var toolFlags []struct {
+		Name  string
+		Bool  bool
+		Usage string
+	}
+	if err := json.Unmarshal(out.Bytes(), &toolFlags); err != nil {
+		base.Fatalf("can't unmarshal JSON from %s -flags: %v", tool, err)
+	}
+
+	isBuildFlag := make(map[string]bool)
+	for _, f := range buildFlagDefn {
+		isBuildFlag[f.Name] = true
+	}
+	var defns []*cmdflag.Defn
+	for _, f := range toolFlags {
+		if isBuildFlag[f.Name] {
+			// Flags like -tags and -v are known to
+			// both the tool and the build.
+			continue
+		}
+		defn := &cmdflag.Defn{Name: f.Name}
+		if f.Bool {
+			defn.BoolVar = new(bool)
+		}
+		defns = append(defns, defn)
+	}
+	return append(defns, buildFlagDefn...)
+}
+
+// findVetTool returns the value of the -vettool flag in args, if any.
+func findVetTool(args []string) string {
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if name == "vettool" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "vettool=") {
+			return strings.TrimPrefix(name, "vettool=")
+		}
+	}
+	return ""
 }
 
 // vetFlags processes the command line, splitting it at the first non-flag
 // into the list of flags and list of packages.
+//
+// If the command line names a vet tool with -vettool, the flags
+// accepted are those reported by the tool rather than those of cmd/vet.
 func vetFlags(args []string) (passToVet, packageNames []string) {
+	defns := vetFlagDefn
+	if tool := findVetTool(args); tool != "" {
+		defns = toolFlagDefn(tool)
+	}
+
 	for i := 0; i < len(args); i++ {
 		if !strings.HasPrefix(args[i], "-") {
 			return args[:i], args[i:]
 		}
 
-		f, value, extraWord := cmdflag.Parse(cmd, vetFlagDefn, args, i)
+		f, value, extraWord := cmdflag.Parse(cmd, defns, args, i)
 		if f == nil {
 			fmt.Fprintf(os.Stderr, "vet: flag %q not defined\n", args[i])
 			fmt.Fprintf(os.Stderr, "Run \"go help vet\" for more information\n")
@@ -63,6 +63,7 @@ type Action struct {
 	Deps       []*Action                     // actions that must happen before this one
 	Func       func(*Builder, *Action) error // the action itself (nil = no-op)
 	IgnoreFail bool                          // whether to run f even if dependencies fail
+	VetxOnly   bool                          // Mode=="vet": only run vet tool to compute facts for dependents
 	TestOutput *bytes.Buffer                 // test output buffer
 	Args       []string                      // additional args for runProgram
 
@@ -313,8 +314,8 @@ func (b *Builder) AutoAction(mode, depMode BuildMode, p *load.Package) *Action {
 // depMode is the action (build or install) to use when building dependencies.
 // To turn package main into an executable, call b.Link instead.
 func (b *Builder) CompileAction(mode, depMode BuildMode, p *load.Package) *Action {
-	if mode != ModeBuild && p.Internal.Local && p.Target == "" {
-		// Imported via local path. No permanent target.
+	if mode != ModeBuild && (p.Internal.Local || p.Module != nil) && p.Target == "" {
+		// Imported via local path or using modules. No permanent target.
 		mode = ModeBuild
 	}
 	if mode != ModeBuild && p.Name == "main" {
@@ -370,6 +371,16 @@ func (b *Builder) CompileAction(mode, depMode BuildMode, p *load.Package) *Actio
 // If the caller may be causing p to be installed, it is up to the caller
 // to make sure that the install depends on (runs after) vet.
 func (b *Builder) VetAction(mode, depMode BuildMode, p *load.Package) *Action {
+	a := b.vetAction(mode, depMode, p)
+	a.VetxOnly = false
+	return a
+}
+
+// vetAction returns the vet action for p. Vet actions created for
+// the dependencies of the packages being vetted have VetxOnly set:
+// they run the vet tool only to compute the analysis facts that the
+// vet of the importing packages reads.
+func (b *Builder) vetAction(mode, depMode BuildMode, p *load.Package) *Action {
 	// Construct vet action.
 	a := b.cacheAction("vet", p, func() *Action {
 		a1 := b.CompileAction(mode, depMode, p)
@@ -381,11 +392,23 @@ func (b *Builder) VetAction(mode, depMode BuildMode, p *load.Package) *Action {
 		stk.Pop()
 		aFmt := b.CompileAction(ModeBuild, depMode, p1)
 
+		deps := []*Action{a1, aFmt}
+
+		// Only a separate vet tool can use facts about the
+		// dependencies, so only then vet the dependencies too.
+		if VetTool != "" {
+			for _, p1 := range p.Internal.Imports {
+				deps = append(deps, b.vetAction(mode, depMode, p1))
+			}
+		}
+
 		a := &Action{
-			Mode:    "vet",
-			Package: p,
-			Deps:    []*Action{a1, aFmt},
-			Objdir:  a1.Objdir,
+			Mode:       "vet",
+			Package:    p,
+			Deps:       deps,
+			Objdir:     a1.Objdir,
+			VetxOnly:   true,
+			IgnoreFail: true, // it's OK if vet of dependencies "fails" (reports problems)
 		}
 		if a1.Func == nil {
 			// Built-in packages like unsafe.
@@ -97,6 +97,15 @@ and test commands:
 	-linkshared
 		link against shared libraries previously created with
 		-buildmode=shared.
+	-mod mode
+		module download mode to use: readonly or vendor.
+		See 'go help modules' for more.
+	-pgo file
+		use the CPU profile in file, as written by runtime/pprof,
+		for profile-guided optimization: the compiler inlines more
+		aggressively at the hot call sites of the profile and
+		devirtualizes hot interface method calls.
+		Only supported by the gc compiler.
 	-pkgdir dir
 		install and load all packages from dir instead of the usual locations.
 		For example, when building with a non-standard configuration,
@@ -126,6 +135,28 @@ For example, 'go build -gcflags=-S fmt' prints the disassembly
 only for package fmt, while 'go build -gcflags=all=-S fmt'
 prints the disassembly for fmt and all its dependencies.
 
+The build, install and run commands also accept flags that build
+programs instrumented to record the coverage of their source code:
+
+	-cover
+		enable code coverage instrumentation.
+	-covermode set,count,atomic
+		the mode of coverage analysis, as for 'go test'.
+		The default is "set", or "atomic" if -race is enabled.
+		Sets -cover.
+	-coverpkg pattern1,pattern2,pattern3
+		instrument the packages, among those named on the command line
+		and their dependencies, that match the patterns. The default is
+		to instrument only the packages named on the command line.
+		Sets -cover.
+
+When an instrumented program exits, by returning from main.main or by
+calling os.Exit, it writes its coverage data to the directory named by
+the GOCOVERDIR environment variable. Use 'go tool covdata' to merge the
+data of several runs and to convert it to a profile for 'go tool cover'.
+Package runtime/coverage lets long-running programs write the data
+at other times.
+
 For more about specifying packages, see 'go help packages'.
 For more about where packages and binaries are installed,
 run 'go help gopath'.
@@ -156,6 +187,8 @@ func init() {
 
 	AddBuildFlags(CmdBuild)
 	AddBuildFlags(CmdInstall)
+	AddCoverFlags(CmdBuild)
+	AddCoverFlags(CmdInstall)
 }
 
 // Note that flags consulted by other parts of the code
@@ -220,8 +253,10 @@ func AddBuildFlags(cmd *base.Command) {
 	cmd.Flag.StringVar(&cfg.BuildContext.InstallSuffix, "installsuffix", "", "")
 	cmd.Flag.Var(&load.BuildLdflags, "ldflags", "")
 	cmd.Flag.BoolVar(&cfg.BuildLinkshared, "linkshared", false, "")
+	cmd.Flag.StringVar(&cfg.BuildPGO, "pgo", "", "")
 	cmd.Flag.StringVar(&cfg.BuildPkgdir, "pkgdir", "", "")
 	cmd.Flag.BoolVar(&cfg.BuildRace, "race", false, "")
+	cmd.Flag.StringVar(&cfg.BuildMod, "mod", "", "")
 	cmd.Flag.BoolVar(&cfg.BuildMSan, "msan", false, "")
 	cmd.Flag.Var((*base.StringsFlag)(&cfg.BuildContext.BuildTags), "tags", "")
 	cmd.Flag.Var((*base.StringsFlag)(&cfg.BuildToolexec), "toolexec", "")
@@ -311,6 +346,7 @@ func runBuild(cmd *base.Command, args []string) {
 	}
 
 	pkgs = pkgsFilter(load.Packages(args))
+	PrepareCover(pkgs)
 
 	if cfg.BuildO != "" {
 		if len(pkgs) > 1 {
@@ -419,6 +455,7 @@ func InstallPackages(args []string, forGet bool) {
 	}
 
 	pkgs := pkgsFilter(load.PackagesForBuild(args))
+	PrepareCover(pkgs)
 
 	for _, p := range pkgs {
 		if p.Target == "" && (!p.Standard || p.ImportPath != "unsafe") {
@@ -429,6 +466,8 @@ func InstallPackages(args []string, forGet bool) {
 				base.Errorf("go %s: no install location for .go files listed on command line (GOBIN not set)", cfg.CmdName)
 			case p.ConflictDir != "":
 				base.Errorf("go %s: no install location for %s: hidden by %s", cfg.CmdName, p.Dir, p.ConflictDir)
+			case p.Name != "main" && p.Module != nil:
+				// Non-executables have no target (except the cache) when building with modules.
 			default:
 				base.Errorf("go %s: no install location for directory %s outside GOPATH\n"+
 					"\tFor more details see: 'go help gopath'", cfg.CmdName, p.Dir)
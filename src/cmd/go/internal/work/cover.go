@@ -0,0 +1,159 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Support for go build -cover.
+
+package work
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+)
+
+// coverFlag implements the -covermode and -coverpkg flags,
+// which imply -cover.
+type coverFlag struct {
+	set func(string)
+}
+
+func (f coverFlag) String() string { return "" }
+
+func (f coverFlag) Set(value string) error {
+	f.set(value)
+	cfg.BuildCover = true
+	return nil
+}
+
+// AddCoverFlags adds the coverage flags to the build, install and run
+// commands. The test command has its own, which also control profiling.
+func AddCoverFlags(cmd *base.Command) {
+	cmd.Flag.BoolVar(&cfg.BuildCover, "cover", false, "")
+	cmd.Flag.Var(coverFlag{func(v string) { cfg.BuildCoverMode = v }}, "covermode", "")
+	cmd.Flag.Var(coverFlag{func(v string) {
+		cfg.BuildCoverPkg = nil
+		if v != "" {
+			cfg.BuildCoverPkg = strings.Split(v, ",")
+		}
+	}}, "coverpkg", "")
+}
+
+// PrepareCover marks the packages to be instrumented by a build
+// with -cover: by default, the packages named on the command line,
+// or, with -coverpkg, those of pkgs and their dependencies that match
+// the -coverpkg patterns.
+func PrepareCover(pkgs []*load.Package) {
+	if !cfg.BuildCover {
+		return
+	}
+	switch cfg.BuildCoverMode {
+	case "":
+		cfg.BuildCoverMode = "set"
+		if cfg.BuildRace {
+			// Default coverage mode is atomic when -race is set.
+			cfg.BuildCoverMode = "atomic"
+		}
+	case "set", "count", "atomic":
+	default:
+		base.Fatalf("go %s: invalid flag argument for -covermode: %q", cfg.CmdName, cfg.BuildCoverMode)
+	}
+	if cfg.BuildRace && cfg.BuildCoverMode != "atomic" {
+		base.Fatalf(`go %s: -covermode must be "atomic", not %q, when -race is enabled`, cfg.CmdName, cfg.BuildCoverMode)
+	}
+	if cfg.BuildContext.Compiler != "gc" {
+		base.Fatalf("go %s: -cover is not supported with -compiler=%s", cfg.CmdName, cfg.BuildContext.Compiler)
+	}
+
+	// The packages used to write out the coverage data cannot
+	// themselves be instrumented: the registration of their
+	// counters would create import cycles.
+	rt := load.LoadPackage("runtime/coverage", &load.ImportStack{})
+	if rt.Error != nil {
+		base.Fatalf("load runtime/coverage: %v", rt.Error)
+	}
+	exclude := map[string]bool{"unsafe": true, rt.ImportPath: true}
+	for _, dep := range rt.Deps {
+		exclude[dep] = true
+	}
+
+	var cover []*load.Package
+	if cfg.BuildCoverPkg == nil {
+		cover = pkgs
+	} else {
+		match := make([]func(*load.Package) bool, len(cfg.BuildCoverPkg))
+		matched := make([]bool, len(cfg.BuildCoverPkg))
+		for i, pattern := range cfg.BuildCoverPkg {
+			match[i] = load.MatchPackage(pattern, base.Cwd)
+		}
+		for _, p := range load.PackageList(pkgs) {
+			haveMatch := false
+			for i := range match {
+				if match[i](p) {
+					matched[i] = true
+					haveMatch = true
+				}
+			}
+			if haveMatch {
+				cover = append(cover, p)
+			}
+		}
+		for i, pattern := range cfg.BuildCoverPkg {
+			if !matched[i] {
+				fmt.Fprintf(os.Stderr, "warning: no packages being built depend on matches for pattern %s\n", pattern)
+			}
+		}
+	}
+
+	for _, p := range cover {
+		if exclude[p.ImportPath] || p.Internal.CoverMode != "" {
+			continue
+		}
+		var coverFiles []string
+		coverFiles = append(coverFiles, p.GoFiles...)
+		coverFiles = append(coverFiles, p.CgoFiles...)
+		p.Internal.CoverVars = load.DeclareCoverVars(p.ImportPath, coverFiles...)
+		if len(p.Internal.CoverVars) == 0 {
+			continue
+		}
+		p.Internal.CoverMode = cfg.BuildCoverMode
+		if cfg.BuildCoverMode == "atomic" {
+			// sync/atomic import is inserted by the cover tool.
+			load.EnsureImport(p, "sync/atomic")
+		}
+		load.EnsureImport(p, "internal/coverage/rtcov")
+		load.EnsureImport(p, "runtime/coverage")
+	}
+}
+
+// coverRegisterFile returns the source of a file that registers
+// the coverage variables of package p with the runtime.
+func coverRegisterFile(p *load.Package) []byte {
+	var files []string
+	for file := range p.Internal.CoverVars {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go build -cover. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", p.Name)
+	fmt.Fprintf(&buf, "import (\n")
+	fmt.Fprintf(&buf, "\t_cover_rtcov_ %q\n", "internal/coverage/rtcov")
+	fmt.Fprintf(&buf, "\t_ %q\n", "runtime/coverage")
+	fmt.Fprintf(&buf, ")\n\n")
+	fmt.Fprintf(&buf, "func init() {\n")
+	for _, file := range files {
+		cv := p.Internal.CoverVars[file]
+		fmt.Fprintf(&buf, "\t_cover_rtcov_.RegisterFile(%q, %q, %q, %s.Count[:], %s.Pos[:], %s.NumStmt[:])\n",
+			p.Internal.CoverMode, p.ImportPath, cv.File, cv.Var, cv.Var, cv.Var)
+	}
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes()
+}
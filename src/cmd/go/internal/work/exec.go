@@ -209,6 +209,9 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 	}
 	if p.Internal.CoverMode != "" {
 		fmt.Fprintf(h, "cover %q %q\n", p.Internal.CoverMode, b.toolID("cover"))
+		if cfg.BuildCover {
+			fmt.Fprintf(h, "coverregister\n")
+		}
 	}
 
 	// Configuration specific to compiler toolchain.
@@ -217,6 +220,11 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 		base.Fatalf("buildActionID: unknown build toolchain %q", cfg.BuildToolchainName)
 	case "gc":
 		fmt.Fprintf(h, "compile %s %q %q\n", b.toolID("compile"), forcedGcflags, p.Internal.Gcflags)
+		if cfg.BuildPGO != "" {
+			// Hash the content of the profile, not its name,
+			// so that a new profile invalidates cached builds.
+			fmt.Fprintf(h, "pgo %s\n", b.fileHash(cfg.BuildPGO))
+		}
 		if len(p.SFiles) > 0 {
 			fmt.Fprintf(h, "asm %q %q %q\n", b.toolID("asm"), forcedAsmflags, p.Internal.Asmflags)
 		}
@@ -271,6 +279,9 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 	for _, file := range inputFiles {
 		fmt.Fprintf(h, "file %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
 	}
+	for _, file := range p.EmbedFiles {
+		fmt.Fprintf(h, "embed %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
+	}
 	for _, a1 := range a.Deps {
 		p1 := a1.Package
 		if p1 != nil {
@@ -427,6 +438,16 @@ func (b *Builder) build(a *Action) (err error) {
 				cgofiles[i-len(gofiles)] = coverFile
 			}
 		}
+
+		// For go build -cover, register the coverage variables
+		// with the runtime, which writes them out at exit.
+		if cfg.BuildCover && len(a.Package.Internal.CoverVars) > 0 {
+			regFile := objdir + "_cover_register_.go"
+			if err := b.writeFile(regFile, coverRegisterFile(a.Package)); err != nil {
+				return err
+			}
+			gofiles = append(gofiles, regFile)
+		}
 	}
 
 	// Run cgo.
@@ -495,6 +516,7 @@ func (b *Builder) build(a *Action) (err error) {
 			Compiler:    cfg.BuildToolchainName,
 			Dir:         a.Package.Dir,
 			GoFiles:     mkAbsFiles(a.Package.Dir, gofiles),
+			NonGoFiles:  mkAbsFiles(a.Package.Dir, str.StringList(a.Package.CFiles, a.Package.CXXFiles, a.Package.MFiles, a.Package.FFiles, a.Package.HFiles, a.Package.SFiles, a.Package.SysoFiles)),
 			ImportPath:  a.Package.ImportPath,
 			ImportMap:   make(map[string]string),
 			PackageFile: make(map[string]string),
@@ -548,9 +570,29 @@ func (b *Builder) build(a *Action) (err error) {
 		return nil
 	}
 
+	// Prepare the //go:embed configuration, mapping each pattern
+	// to the files it matches and each file to its location on disk.
+	var embedcfg []byte
+	if len(p.Internal.Embed) > 0 {
+		var embed struct {
+			Patterns map[string][]string
+			Files    map[string]string
+		}
+		embed.Patterns = p.Internal.Embed
+		embed.Files = make(map[string]string)
+		for _, file := range p.EmbedFiles {
+			embed.Files[file] = filepath.Join(p.Dir, file)
+		}
+		js, err := json.MarshalIndent(&embed, "", "\t")
+		if err != nil {
+			return fmt.Errorf("marshal embedcfg: %v", err)
+		}
+		embedcfg = js
+	}
+
 	// Compile Go.
 	objpkg := objdir + "_pkg_.a"
-	ofile, out, err := BuildToolchain.gc(b, a, objpkg, icfg.Bytes(), len(sfiles) > 0, gofiles)
+	ofile, out, err := BuildToolchain.gc(b, a, objpkg, icfg.Bytes(), embedcfg, len(sfiles) > 0, gofiles)
 	if len(out) > 0 {
 		b.showOutput(a, a.Package.Dir, a.Package.ImportPath, b.processOutput(out))
 		if err != nil {
@@ -639,14 +681,18 @@ func (b *Builder) build(a *Action) (err error) {
 }
 
 type vetConfig struct {
-	Compiler    string
-	Dir         string
-	GoFiles     []string
-	ImportMap   map[string]string
-	PackageFile map[string]string
-	ImportPath  string
+	Compiler    string            // compiler name (gc, gccgo)
+	Dir         string            // directory containing package
+	ImportPath  string            // canonical import path ("package path")
+	GoFiles     []string          // absolute paths to package source files
+	NonGoFiles  []string          // absolute paths to package non-Go files
+	ImportMap   map[string]string // map import path in source code to package path
+	PackageFile map[string]string // map package path to .a file with export data
+	PackageVetx map[string]string // map package path to vetx data from earlier vet run
+	VetxOnly    bool              // only compute facts, don't report diagnostics
+	VetxOutput  string            // write vetx data to this output file
 
-	SucceedOnTypecheckFailure bool
+	SucceedOnTypecheckFailure bool // ignore type checking errors; see vet below
 }
 
 // VetTool is the path to an alternate vet tool binary.
@@ -660,14 +706,50 @@ var VetFlags []string
 func (b *Builder) vet(a *Action) error {
 	// a.Deps[0] is the build of the package being vetted.
 	// a.Deps[1] is the build of the "fmt" package.
+	// a.Deps[2:] are the vets of the package's imports, if any.
+
+	if a.Deps[0].Failed {
+		// If compiler failed, there's no point running vet.
+		return nil
+	}
 
 	vcfg := a.Deps[0].vetCfg
 	if vcfg == nil {
 		// Vet config should only be missing if the build failed.
-		if !a.Deps[0].Failed {
-			return fmt.Errorf("vet config not found")
+		return fmt.Errorf("vet config not found")
+	}
+
+	vcfg.VetxOnly = a.VetxOnly
+	vcfg.VetxOutput = a.Objdir + "vet.out"
+	vcfg.PackageVetx = make(map[string]string)
+	for _, a1 := range a.Deps[2:] {
+		if a1.Mode == "vet" && a1.built != "" {
+			vcfg.PackageVetx[a1.Package.ImportPath] = a1.built
+		}
+	}
+
+	// A vet run that only computes facts for dependents reports
+	// nothing, so its output depends only on its inputs and can be
+	// cached.
+	var key cache.ActionID
+	c := cache.Default()
+	if a.VetxOnly && c != nil && !cfg.BuildN {
+		h := cache.NewHash("vet " + a.Package.ImportPath)
+		fmt.Fprintf(h, "vet %s\n", b.fileHash(VetTool))
+		fmt.Fprintf(h, "vetflags %q\n", VetFlags)
+		fmt.Fprintf(h, "pkg %s\n", hashToString(a.Deps[0].actionID))
+		for _, a1 := range a.Deps[2:] {
+			if a1.Mode == "vet" && a1.built != "" {
+				fmt.Fprintf(h, "vetout %q %s\n", a1.Package.ImportPath, b.fileHash(a1.built))
+			}
+		}
+		key = h.Sum()
+		if data, _, err := c.GetBytes(key); err == nil {
+			if err := ioutil.WriteFile(vcfg.VetxOutput, data, 0666); err == nil {
+				a.built = vcfg.VetxOutput
+				return nil
+			}
 		}
-		return nil
 	}
 
 	if vcfg.ImportMap["fmt"] == "" {
@@ -697,7 +779,17 @@ func (b *Builder) vet(a *Action) error {
 	if tool == "" {
 		tool = base.Tool("vet")
 	}
-	return b.run(a, p.Dir, p.ImportPath, nil, cfg.BuildToolexec, tool, VetFlags, a.Objdir+"vet.cfg")
+	runErr := b.run(a, p.Dir, p.ImportPath, nil, cfg.BuildToolexec, tool, VetFlags, a.Objdir+"vet.cfg")
+
+	// If vet wrote its facts, make them available to the vet of
+	// the importing packages.
+	if data, err := ioutil.ReadFile(vcfg.VetxOutput); err == nil {
+		a.built = vcfg.VetxOutput
+		if runErr == nil && key != (cache.ActionID{}) {
+			c.PutBytes(key, data)
+		}
+	}
+	return runErr
 }
 
 // linkActionID computes the action ID for a link action.
@@ -1525,7 +1617,7 @@ func mkAbs(dir, f string) string {
 type toolchain interface {
 	// gc runs the compiler in a specific directory on a set of files
 	// and returns the name of the generated output file.
-	gc(b *Builder, a *Action, archive string, importcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error)
+	gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error)
 	// cc runs the toolchain's C compiler in a directory on a C file
 	// to produce an output file.
 	cc(b *Builder, a *Action, ofile, cfile string) error
@@ -1562,7 +1654,7 @@ func (noToolchain) linker() string {
 	return ""
 }
 
-func (noToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error) {
+func (noToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error) {
 	return "", nil, noCompiler()
 }
 
@@ -2188,7 +2280,7 @@ func (b *Builder) swigDoIntSize(objdir string) (intsize string, err error) {
 
 	p := load.GoFilesPackage(srcs)
 
-	if _, _, e := BuildToolchain.gc(b, &Action{Mode: "swigDoIntSize", Package: p, Objdir: objdir}, "", nil, false, srcs); e != nil {
+	if _, _, e := BuildToolchain.gc(b, &Action{Mode: "swigDoIntSize", Package: p, Objdir: objdir}, "", nil, nil, false, srcs); e != nil {
 		return "32", nil
 	}
 	return "64", nil
@@ -29,6 +29,20 @@ func BuildInit() {
 		}
 		cfg.BuildPkgdir = p
 	}
+
+	if cfg.BuildPGO != "" {
+		if cfg.BuildContext.Compiler != "gc" {
+			base.Fatalf("go %s: -pgo is not supported with -compiler=%s", flag.Args()[0], cfg.BuildContext.Compiler)
+		}
+		p, err := filepath.Abs(cfg.BuildPGO)
+		if err == nil {
+			_, err = os.Stat(p)
+		}
+		if err != nil {
+			base.Fatalf("go %s: -pgo: %v", flag.Args()[0], err)
+		}
+		cfg.BuildPGO = p
+	}
 }
 
 func instrumentInit() {
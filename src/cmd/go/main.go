@@ -27,6 +27,10 @@ import (
 	"cmd/go/internal/get"
 	"cmd/go/internal/help"
 	"cmd/go/internal/list"
+	"cmd/go/internal/modcmd"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modget"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/run"
 	"cmd/go/internal/test"
 	"cmd/go/internal/tool"
@@ -48,6 +52,7 @@ func init() {
 		get.CmdGet,
 		work.CmdInstall,
 		list.CmdList,
+		modcmd.CmdMod,
 		run.CmdRun,
 		test.CmdTest,
 		tool.CmdTool,
@@ -58,11 +63,16 @@ func init() {
 		help.HelpBuildmode,
 		help.HelpFileType,
 		help.HelpGopath,
+		get.HelpGopathGet,
+		modfetch.HelpGoproxy,
 		help.HelpEnvironment,
 		help.HelpImportPath,
+		modload.HelpModules,
+		modget.HelpModuleGet,
 		help.HelpPackages,
 		test.HelpTestflag,
 		test.HelpTestfunc,
+		modload.HelpGoMod,
 	}
 }
 
@@ -127,8 +137,44 @@ func main() {
 		}
 	}
 
-	for _, cmd := range base.Commands {
-		if cmd.Name() == args[0] && cmd.Runnable() {
+	// Running 'go mod init' creates the main module in the
+	// current directory, so module mode must know about it
+	// before it goes looking for an existing go.mod.
+	if len(args) >= 2 && args[0] == "mod" && args[1] == "init" {
+		modload.CmdModInit = true
+	}
+	modload.Init()
+	if cfg.ModulesEnabled {
+		// In module mode, 'go get' adds and updates module requirements.
+		*get.CmdGet = *modget.CmdGet
+	}
+
+	cmds := base.Commands
+BigCmdLoop:
+	for {
+		for _, cmd := range cmds {
+			if cmd.Name() != args[0] {
+				continue
+			}
+			if len(cmd.Commands) > 0 {
+				cmds = cmd.Commands
+				args = args[1:]
+				if len(args) == 0 {
+					help.PrintSubcommandUsage(os.Stderr, cmd)
+					base.SetExitStatus(2)
+					base.Exit()
+				}
+				if args[0] == "help" {
+					// Accept 'go mod help' and 'go mod help foo' for 'go help mod' and 'go help mod foo'.
+					help.Help(append(strings.Split(cfg.CmdName, " "), args[1:]...))
+					return
+				}
+				cfg.CmdName += " " + args[0]
+				continue BigCmdLoop
+			}
+			if !cmd.Runnable() {
+				continue
+			}
 			cmd.Flag.Usage = func() { cmd.Usage() }
 			if cmd.CustomFlags {
 				args = args[1:]
@@ -140,11 +186,14 @@ func main() {
 			base.Exit()
 			return
 		}
+		if i := strings.LastIndex(cfg.CmdName, " "); i >= 0 {
+			fmt.Fprintf(os.Stderr, "go %s: unknown command\nRun 'go help %s' for usage.\n", cfg.CmdName, cfg.CmdName[:i])
+		} else {
+			fmt.Fprintf(os.Stderr, "go: unknown subcommand %q\nRun 'go help' for usage.\n", args[0])
+		}
+		base.SetExitStatus(2)
+		base.Exit()
 	}
-
-	fmt.Fprintf(os.Stderr, "go: unknown subcommand %q\nRun 'go help' for usage.\n", args[0])
-	base.SetExitStatus(2)
-	base.Exit()
 }
 
 func init() {
@@ -0,0 +1,304 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for module mode.
+
+package main_test
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testModProxy builds a file-based module proxy in the temporary
+// directory from the module trees in testdata/modproxy, where each
+// directory named path@version holds the files of that module version.
+func testModProxy(tg *testgoData) {
+	tg.t.Helper()
+	src := filepath.Join(tg.pwd(), "testdata", "modproxy")
+	tg.tempDir("proxy")
+	proxy := tg.path("proxy")
+	versions := make(map[string][]string)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || !strings.Contains(info.Name(), "@") {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		i := strings.LastIndex(rel, "@")
+		mod, vers := filepath.ToSlash(rel[:i]), rel[i+1:]
+		versions[mod] = append(versions[mod], vers)
+		dir := filepath.Join(proxy, filepath.FromSlash(mod), "@v")
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+		info1 := fmt.Sprintf(`{"Version":%q,"Time":"2018-01-01T00:00:00Z"}`, vers)
+		if err := ioutil.WriteFile(filepath.Join(dir, vers+".info"), []byte(info1), 0666); err != nil {
+			return err
+		}
+		gomod, err := ioutil.ReadFile(filepath.Join(path, "go.mod"))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, vers+".mod"), gomod, 0666); err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(dir, vers+".zip"))
+		if err != nil {
+			return err
+		}
+		z := zip.NewWriter(f)
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			data, err := ioutil.ReadFile(filepath.Join(path, file.Name()))
+			if err != nil {
+				return err
+			}
+			w, err := z.Create(mod + "@" + vers + "/" + file.Name())
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		if err := z.Close(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return filepath.SkipDir
+	})
+	tg.must(err)
+	for mod, list := range versions {
+		sort.Strings(list)
+		list := strings.Join(list, "\n") + "\n"
+		tg.must(ioutil.WriteFile(filepath.Join(proxy, filepath.FromSlash(mod), "@v", "list"), []byte(list), 0666))
+	}
+}
+
+// testModProxyURL returns the GOPROXY setting for
+// the proxy built by testModProxy.
+func testModProxyURL(tg *testgoData) string {
+	u := filepath.ToSlash(tg.path("proxy"))
+	if runtime.GOOS == "windows" {
+		u = "/" + u
+	}
+	return "file://" + u
+}
+
+// testModule sets up a module-mode test: a module proxy,
+// an empty GOPATH, and a main module directory x
+// containing the given go.mod and x.go files.
+func testModule(t *testing.T, gomod, xgo string) *testgoData {
+	tg := testgo(t)
+	tg.makeTempdir()
+	tg.setenv("GO111MODULE", "on")
+	testModProxy(tg)
+	tg.setenv("GOPROXY", testModProxyURL(tg))
+	tg.setenv("GOPATH", tg.path("gopath"))
+	if gomod != "" {
+		tg.tempFile("x/go.mod", gomod)
+	}
+	tg.tempFile("x/x.go", xgo)
+	tg.cd(tg.path("x"))
+	return tg
+}
+
+// testModCleanup makes the read-only module cache writable
+// so that tg.cleanup can remove it.
+func testModCleanup(tg *testgoData) {
+	filepath.Walk(tg.path("gopath"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			os.Chmod(path, 0777)
+		}
+		return nil
+	})
+	tg.cleanup()
+}
+
+const testModQuoteMain = `package main
+
+import (
+	"fmt"
+
+	"example.com/quote"
+)
+
+func main() {
+	fmt.Println(quote.Hello())
+}
+`
+
+func TestModInitBuild(t *testing.T) {
+	tg := testModule(t, "", testModQuoteMain)
+	defer testModCleanup(tg)
+
+	tg.run("mod", "init", "x")
+	tg.grepStderr("creating new go.mod: module x", "did not report creating go.mod")
+	tg.runFail("mod", "init", "x")
+	tg.grepStderr("go.mod already exists", "did not refuse to overwrite go.mod")
+
+	tg.run("run", "x.go")
+	tg.grepStdout("hello from sampler v1.0.0 from quote v1.1.0", "wrong output from latest quote")
+	data, err := ioutil.ReadFile(tg.path("x/go.mod"))
+	tg.must(err)
+	if !strings.Contains(string(data), "require example.com/quote v1.1.0\n") {
+		t.Fatalf("go.mod does not require latest quote:\n%s", data)
+	}
+	data, err = ioutil.ReadFile(tg.path("x/go.sum"))
+	tg.must(err)
+	for _, want := range []string{"example.com/quote v1.1.0 h1:", "example.com/quote v1.1.0/go.mod h1:", "example.com/sampler v1.0.0 h1:"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("go.sum is missing %q:\n%s", want, data)
+		}
+	}
+
+	// The module cache is read-only.
+	sampler := tg.path("gopath/pkg/mod/example.com/sampler@v1.0.0/sampler.go")
+	if info, err := os.Stat(sampler); err != nil {
+		t.Error(err)
+	} else if info.Mode()&0222 != 0 {
+		t.Errorf("module cache file %s is writable: %v", sampler, info.Mode())
+	}
+
+	tg.run("list", "-m", "all")
+	tg.grepStdout(`^x$`, "missing main module")
+	tg.grepStdout(`^example.com/quote v1.1.0$`, "missing quote")
+	tg.grepStdout(`^example.com/sampler v1.0.0$`, "missing sampler")
+	tg.run("list", "-m", "-u", "example.com/sampler")
+	tg.grepStdout(`^example.com/sampler v1.0.0 \[v1.2.0\]$`, "missing sampler update")
+	tg.run("list", "-m", "-versions", "example.com/quote")
+	tg.grepStdout(`^example.com/quote v1.0.0 v1.1.0$`, "wrong quote versions")
+	tg.run("env", "GOMOD")
+	tg.grepStdout(`go.mod$`, "go env GOMOD does not name go.mod")
+}
+
+func TestModGet(t *testing.T) {
+	tg := testModule(t, "module x\n\nrequire example.com/quote v1.0.0\n", testModQuoteMain)
+	defer testModCleanup(tg)
+
+	tg.run("get", "example.com/sampler@v1.2.0")
+	tg.run("run", "x.go")
+	tg.grepStdout("hello from sampler v1.2.0 from quote v1.0.0", "did not upgrade sampler")
+	data, err := ioutil.ReadFile(tg.path("x/go.mod"))
+	tg.must(err)
+	if !strings.Contains(string(data), "example.com/sampler v1.2.0 // indirect") {
+		t.Errorf("go.mod does not mark sampler indirect:\n%s", data)
+	}
+
+	tg.run("get", "-m", "example.com/quote@latest")
+	tg.run("list", "-m", "example.com/quote")
+	tg.grepStdout(`^example.com/quote v1.1.0$`, "did not upgrade quote")
+
+	tg.run("get", "-m", "example.com/quote@<v1.1.0")
+	tg.run("list", "-m", "example.com/quote")
+	tg.grepStdout(`^example.com/quote v1.0.0$`, "did not downgrade quote")
+}
+
+func TestModTidyGraphWhy(t *testing.T) {
+	tg := testModule(t, "module x\n\nrequire example.com/unused v1.0.0\n", testModQuoteMain)
+	defer testModCleanup(tg)
+
+	tg.run("mod", "tidy", "-v")
+	tg.grepStderr("unused example.com/unused", "did not report removing unused module")
+	data, err := ioutil.ReadFile(tg.path("x/go.mod"))
+	tg.must(err)
+	if strings.Contains(string(data), "example.com/unused") || !strings.Contains(string(data), "example.com/quote v1.1.0") {
+		t.Errorf("go mod tidy wrote wrong go.mod:\n%s", data)
+	}
+	data, err = ioutil.ReadFile(tg.path("x/go.sum"))
+	tg.must(err)
+	if strings.Contains(string(data), "example.com/unused") {
+		t.Errorf("go mod tidy left unused module in go.sum:\n%s", data)
+	}
+
+	tg.run("mod", "graph")
+	tg.grepStdout(`^x example.com/quote@v1.1.0$`, "missing main module requirement")
+	tg.grepStdout(`^example.com/quote@v1.1.0 example.com/sampler@v1.0.0$`, "missing quote requirement")
+
+	tg.run("mod", "why", "example.com/sampler")
+	if want := "# example.com/sampler\nx\nexample.com/quote\nexample.com/sampler\n"; tg.getStdout() != want {
+		t.Errorf("go mod why example.com/sampler:\n%s\nwant:\n%s", tg.getStdout(), want)
+	}
+	tg.run("mod", "why", "-m", "example.com/sampler")
+	if want := "# example.com/sampler\nx\nexample.com/quote\nexample.com/sampler\n"; tg.getStdout() != want {
+		t.Errorf("go mod why -m example.com/sampler:\n%s\nwant:\n%s", tg.getStdout(), want)
+	}
+}
+
+func TestModVendorVerify(t *testing.T) {
+	tg := testModule(t, "module x\n\nrequire example.com/quote v1.0.0\n", testModQuoteMain)
+	defer testModCleanup(tg)
+
+	tg.run("mod", "vendor")
+	tg.mustExist(tg.path("x/vendor/example.com/quote/quote.go"))
+	tg.mustExist(tg.path("x/vendor/example.com/sampler/sampler.go"))
+	tg.mustNotExist(tg.path("x/vendor/example.com/quote/quote_test.go"))
+	data, err := ioutil.ReadFile(tg.path("x/vendor/modules.txt"))
+	tg.must(err)
+	want := "# example.com/quote v1.0.0\nexample.com/quote\n# example.com/sampler v1.0.0\nexample.com/sampler\n"
+	if string(data) != want {
+		t.Errorf("vendor/modules.txt:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Builds from the vendor directory need neither the proxy nor the cache.
+	tg.setenv("GOPROXY", "off")
+	tg.run("clean", "-modcache")
+	tg.mustNotExist(tg.path("gopath/pkg/mod"))
+	tg.run("run", "-mod=vendor", "x.go")
+	tg.grepStdout("hello from sampler v1.0.0 from quote v1.0.0", "wrong output from vendored build")
+	tg.runFail("run", "x.go")
+	tg.grepStderr("module lookup disabled by GOPROXY=off", "did not report disabled proxy")
+
+	tg.setenv("GOPROXY", testModProxyURL(tg))
+	tg.run("mod", "download")
+	tg.run("mod", "verify")
+	tg.grepStdout("all modules verified", "did not verify modules")
+
+	sampler := tg.path("gopath/pkg/mod/example.com/sampler@v1.0.0/sampler.go")
+	tg.must(os.Chmod(filepath.Dir(sampler), 0777))
+	tg.must(os.Chmod(sampler, 0666))
+	tg.must(ioutil.WriteFile(sampler, []byte("package sampler\n"), 0666))
+	tg.runFail("mod", "verify")
+	tg.grepStderr("example.com/sampler v1.0.0: dir has been modified", "did not detect modified module")
+}
+
+func TestModReadonly(t *testing.T) {
+	tg := testModule(t, "module x\n", testModQuoteMain)
+	defer testModCleanup(tg)
+
+	tg.runFail("build", "-mod=readonly")
+	tg.grepStderr("import lookup disabled by -mod=readonly", "did not refuse to add module")
+	data, err := ioutil.ReadFile(tg.path("x/go.mod"))
+	tg.must(err)
+	if string(data) != "module x\n" {
+		t.Errorf("go build -mod=readonly modified go.mod:\n%s", data)
+	}
+}
+
+func TestModGoSumMismatch(t *testing.T) {
+	tg := testModule(t, "module x\n\nrequire example.com/quote v1.0.0\n", testModQuoteMain)
+	defer testModCleanup(tg)
+
+	tg.tempFile("x/go.sum", "example.com/quote v1.0.0 h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n")
+	tg.runFail("build")
+	tg.grepStderr("verifying example.com/quote@v1.0.0: checksum mismatch", "did not detect go.sum mismatch")
+}
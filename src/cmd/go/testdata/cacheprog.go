@@ -0,0 +1,171 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Cacheprog is a reference GOCACHEPROG helper for the go command.
+// It stores the build cache entries it is given in a directory,
+// which may be shared by go commands using different GOCACHE
+// directories, such as on different machines.
+//
+// Usage:
+//
+//	GOCACHEPROG="cacheprog dir" go build ...
+//
+// See cmd/go/internal/cache/prog.go for the protocol.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type request struct {
+	ID       int64
+	Command  string
+	ActionID []byte
+	OutputID []byte
+	BodySize int64
+}
+
+type response struct {
+	ID            int64
+	Err           string     `json:",omitempty"`
+	KnownCommands []string   `json:",omitempty"`
+	Miss          bool       `json:",omitempty"`
+	OutputID      []byte     `json:",omitempty"`
+	Size          int64      `json:",omitempty"`
+	Time          *time.Time `json:",omitempty"`
+	DiskPath      string     `json:",omitempty"`
+}
+
+// An entry is the content of the file recording an action ID's entry.
+type entry struct {
+	OutputID []byte
+	Size     int64
+	Time     time.Time
+}
+
+var dir string
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cacheprog: ")
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: cacheprog dir\n")
+		os.Exit(2)
+	}
+	var err error
+	if dir, err = filepath.Abs(os.Args[1]); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	reply := func(res *response) {
+		if err := enc.Encode(res); err != nil {
+			log.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	reply(&response{KnownCommands: []string{"get", "put", "close"}})
+
+	dec := json.NewDecoder(bufio.NewReader(os.Stdin))
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				return
+			}
+			log.Fatalf("reading request: %v", err)
+		}
+		var body []byte
+		if req.Command == "put" && req.BodySize > 0 {
+			// The body is a JSON string holding its base64
+			// encoding, which decodes as a []byte.
+			if err := dec.Decode(&body); err != nil {
+				log.Fatalf("reading body of request %d: %v", req.ID, err)
+			}
+		}
+		res := &response{ID: req.ID}
+		switch req.Command {
+		case "get":
+			get(&req, res)
+		case "put":
+			if err := put(&req, body); err != nil {
+				res.Err = err.Error()
+			}
+		case "close":
+		default:
+			res.Err = fmt.Sprintf("unknown command %q", req.Command)
+		}
+		reply(res)
+	}
+}
+
+func actionFile(id []byte) string { return filepath.Join(dir, "a-"+hex.EncodeToString(id)) }
+func outputFile(id []byte) string { return filepath.Join(dir, "o-"+hex.EncodeToString(id)) }
+
+func get(req *request, res *response) {
+	data, err := ioutil.ReadFile(actionFile(req.ActionID))
+	var e entry
+	if err != nil || json.Unmarshal(data, &e) != nil {
+		res.Miss = true
+		return
+	}
+	file := outputFile(e.OutputID)
+	if info, err := os.Stat(file); err != nil || info.Size() != e.Size {
+		res.Miss = true
+		return
+	}
+	res.OutputID = e.OutputID
+	res.Size = e.Size
+	res.Time = &e.Time
+	res.DiskPath = file
+}
+
+func put(req *request, body []byte) error {
+	if int64(len(body)) != req.BodySize {
+		return fmt.Errorf("body is %d bytes, want %d", len(body), req.BodySize)
+	}
+	if err := writeFile(outputFile(req.OutputID), body); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry{req.OutputID, req.BodySize, time.Now()})
+	if err != nil {
+		return err
+	}
+	return writeFile(actionFile(req.ActionID), data)
+}
+
+// writeFile writes the file atomically, so that other
+// helpers sharing the directory never see it partly written.
+func writeFile(name string, data []byte) error {
+	f, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
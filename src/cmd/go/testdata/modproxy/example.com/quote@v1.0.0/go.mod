@@ -0,0 +1,3 @@
+module example.com/quote
+
+require example.com/sampler v1.0.0
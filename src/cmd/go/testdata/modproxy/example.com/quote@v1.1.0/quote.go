@@ -0,0 +1,9 @@
+// Package quote returns a greeting.
+package quote // import "example.com/quote"
+
+import "example.com/sampler"
+
+// Hello returns a greeting.
+func Hello() string {
+	return sampler.Hello() + " from quote v1.1.0"
+}
@@ -0,0 +1,9 @@
+package quote
+
+import "testing"
+
+func TestHello(t *testing.T) {
+	if Hello() == "" {
+		t.Fatal("empty greeting")
+	}
+}
@@ -0,0 +1,7 @@
+// Package sampler returns a greeting.
+package sampler // import "example.com/sampler"
+
+// Hello returns a greeting.
+func Hello() string {
+	return "hello from sampler v1.2.0"
+}
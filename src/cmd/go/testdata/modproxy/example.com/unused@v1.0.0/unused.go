@@ -0,0 +1,2 @@
+// Package unused is not imported by anything.
+package unused
@@ -0,0 +1,12 @@
+package testlist
+
+import (
+	"fmt"
+	"testing"
+)
+
+func FuzzSimple(f *testing.F) {
+	f.Fuzz(func(t *testing.T, s string) {
+		_ = fmt.Sprint("Fuzz simple", s)
+	})
+}
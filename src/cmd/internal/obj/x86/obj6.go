@@ -908,6 +908,15 @@ func preprocess(ctxt *obj.Link, cursym *obj.LSym, newprog obj.ProgAlloc) {
 			p.Spadj = -2
 			continue
 
+		case AADJSP:
+			if p.Spadj == 0 {
+				// Written in assembly, rather than
+				// generated for the prologue above.
+				p.Spadj = int32(p.From.Offset)
+				deltasp += int32(p.From.Offset)
+			}
+			continue
+
 		case obj.ARET:
 			// do nothing
 		}
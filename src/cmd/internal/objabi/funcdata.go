@@ -13,10 +13,16 @@ package objabi
 const (
 	PCDATA_StackMapIndex       = 0
 	PCDATA_InlTreeIndex        = 1
+	PCDATA_UnsafePoint         = 2
 	FUNCDATA_ArgsPointerMaps   = 0
 	FUNCDATA_LocalsPointerMaps = 1
 	FUNCDATA_InlTree           = 2
 
+	// Values of the PCDATA_UnsafePoint table. PCs in a Go function
+	// without a PCDATA_UnsafePoint table are safe points.
+	PCDATA_UnsafePointSafe   = -1 // Safe for async preemption
+	PCDATA_UnsafePointUnsafe = -2 // Unsafe for async preemption
+
 	// ArgsSizeUnknown is set in Func.argsize to mark all functions
 	// whose argument size is unknown (C vararg functions, and
 	// assembly code without an explicit specification).
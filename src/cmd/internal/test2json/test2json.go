@@ -13,6 +13,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -28,12 +29,21 @@ const (
 
 // event is the JSON struct we emit.
 type event struct {
-	Time    *time.Time `json:",omitempty"`
-	Action  string
-	Package string     `json:",omitempty"`
-	Test    string     `json:",omitempty"`
-	Elapsed *float64   `json:",omitempty"`
-	Output  *textBytes `json:",omitempty"`
+	Time        *time.Time `json:",omitempty"`
+	Action      string
+	Package     string             `json:",omitempty"`
+	ImportPath  string             `json:",omitempty"`
+	Test        string             `json:",omitempty"`
+	File        string             `json:",omitempty"`
+	Line        int                `json:",omitempty"`
+	Column      int                `json:",omitempty"`
+	Elapsed     *float64           `json:",omitempty"`
+	Output      *textBytes         `json:",omitempty"`
+	Key         string             `json:",omitempty"`
+	Value       *string            `json:",omitempty"`
+	Iterations  int64              `json:",omitempty"`
+	Metrics     map[string]float64 `json:",omitempty"`
+	FailedBuild string             `json:",omitempty"`
 }
 
 // textBytes is a hack to get JSON to emit a []byte as a string
@@ -44,19 +54,22 @@ type textBytes []byte
 
 func (b textBytes) MarshalText() ([]byte, error) { return b, nil }
 
-// A converter holds the state of a test-to-JSON conversion.
+// A Converter holds the state of a test-to-JSON conversion.
 // It implements io.WriteCloser; the caller writes test output in,
 // and the converter writes JSON output to w.
-type converter struct {
-	w        io.Writer  // JSON output stream
-	pkg      string     // package to name in events
-	mode     Mode       // mode bits
-	start    time.Time  // time converter started
-	testName string     // name of current test, for output attribution
-	report   []*event   // pending test result reports (nested for subtests)
-	result   string     // overall test result if seen
-	input    lineBuffer // input buffer
-	output   lineBuffer // output buffer
+type Converter struct {
+	w           io.Writer  // JSON output stream
+	pkg         string     // package to name in events
+	mode        Mode       // mode bits
+	start       time.Time  // time converter started
+	testName    string     // name of current test, for output attribution
+	report      []*event   // pending test result reports (nested for subtests)
+	result      string     // overall test result if seen
+	failedBuild string     // package whose build failure caused the test to fail
+	marked      bool       // whether a marked framing line has been seen
+	midLine     bool       // whether handleInputPart is in the middle of a line
+	input       lineBuffer // input buffer
+	output      lineBuffer // output buffer
 }
 
 // inBuffer and outBuffer are the input and output buffer sizes.
@@ -99,9 +112,9 @@ var (
 //
 // The pkg string, if present, specifies the import path to
 // report in the JSON stream.
-func NewConverter(w io.Writer, pkg string, mode Mode) io.WriteCloser {
-	c := new(converter)
-	*c = converter{
+func NewConverter(w io.Writer, pkg string, mode Mode) *Converter {
+	c := new(Converter)
+	*c = Converter{
 		w:     w,
 		pkg:   pkg,
 		mode:  mode,
@@ -109,7 +122,7 @@ func NewConverter(w io.Writer, pkg string, mode Mode) io.WriteCloser {
 		input: lineBuffer{
 			b:    make([]byte, 0, inBuffer),
 			line: c.handleInputLine,
-			part: c.output.write,
+			part: c.handleInputPart,
 		},
 		output: lineBuffer{
 			b:    make([]byte, 0, outBuffer),
@@ -121,7 +134,7 @@ func NewConverter(w io.Writer, pkg string, mode Mode) io.WriteCloser {
 }
 
 // Write writes the test input to the converter.
-func (c *converter) Write(b []byte) (int, error) {
+func (c *Converter) Write(b []byte) (int, error) {
 	c.input.write(b)
 	return len(b), nil
 }
@@ -134,24 +147,44 @@ var (
 		[]byte("=== RUN   "),
 		[]byte("=== PAUSE "),
 		[]byte("=== CONT  "),
+		[]byte("=== NAME  "),
+		[]byte("=== ATTR  "),
 	}
 
 	reports = [][]byte{
 		[]byte("--- PASS: "),
 		[]byte("--- FAIL: "),
 		[]byte("--- SKIP: "),
+		[]byte("--- BENCH: "),
 	}
 
+	benchPrefix = []byte("Benchmark")
+
 	fourSpace = []byte("    ")
 
 	skipLinePrefix = []byte("?   \t")
 	skipLineSuffix = []byte("\t[no test files]\n")
 )
 
+// marker is the byte that the testing package prints at the start of
+// its framing lines when run with -test.v=test2json. Once a line with
+// the marker has been seen, only lines with the marker are framing, so
+// that output of a test that looks like framing is not mistaken for it.
+// The marker itself is not part of the output.
+const marker = byte(0x16) // ^V
+
 // handleInputLine handles a single whole test output line.
 // It must write the line to c.output but may choose to do so
 // before or after emitting other events.
-func (c *converter) handleInputLine(line []byte) {
+func (c *Converter) handleInputLine(line []byte) {
+	if len(line) > 0 && line[0] == marker {
+		c.marked = true
+		line = line[1:]
+	} else if c.marked {
+		c.output.write(line)
+		return
+	}
+
 	// Final PASS or FAIL.
 	if bytes.Equal(line, bigPass) || bytes.Equal(line, bigFail) {
 		c.flushReport(0)
@@ -200,7 +233,13 @@ func (c *converter) handleInputLine(line []byte) {
 	}
 
 	if !ok {
-		// Not a special test output line.
+		// Not a special test output line,
+		// but perhaps the result of a benchmark.
+		if e := benchEvent(origLine); e != nil {
+			c.flushReport(0)
+			c.testName = e.Test
+			c.writeEvent(e)
+		}
 		c.output.write(origLine)
 		return
 	}
@@ -210,6 +249,14 @@ func (c *converter) handleInputLine(line []byte) {
 	name := strings.TrimSpace(string(line[4+6:]))
 
 	e := &event{Action: action}
+	if action == "bench" {
+		// The output of a benchmark, which does not
+		// report a result: that was in the bench event.
+		c.flushReport(0)
+		c.testName = name
+		c.output.write(origLine)
+		return
+	}
 	if line[0] == '-' { // PASS or FAIL report
 		// Parse out elapsed time.
 		if i := strings.Index(name, " ("); i >= 0 {
@@ -239,8 +286,29 @@ func (c *converter) handleInputLine(line []byte) {
 	// === update.
 	// Finish any pending PASS/FAIL reports.
 	c.flushReport(0)
+	if action == "attr" {
+		// "=== ATTR  name key value", where the value may be
+		// empty or contain spaces but the name and key may not.
+		f := strings.SplitN(name, " ", 3)
+		if len(f) < 2 {
+			c.output.write(origLine)
+			return
+		}
+		name, e.Key = f[0], f[1]
+		value := ""
+		if len(f) == 3 {
+			value = f[2]
+		}
+		e.Value = &value
+	}
 	c.testName = name
 
+	if action == "name" {
+		// The following output is from the named test,
+		// which has already started running.
+		c.output.write(origLine)
+		return
+	}
 	if action == "pause" {
 		// For a pause, we want to write the pause notification before
 		// delivering the pause event, just so it doesn't look like the test
@@ -255,8 +323,57 @@ func (c *converter) handleInputLine(line []byte) {
 	return
 }
 
+// benchEvent returns the bench event for a line reporting the result
+// of a benchmark, such as
+//
+//	BenchmarkDecode-8   	   10000	    105732 ns/op	  92.84 MB/s	   40176 B/op	     312 allocs/op
+//
+// or nil if line is not such a line. The metrics are keyed by their units.
+func benchEvent(line []byte) *event {
+	if !bytes.HasPrefix(line, benchPrefix) {
+		return nil
+	}
+	f := strings.Split(strings.TrimSpace(string(line)), "\t")
+	if len(f) < 3 {
+		return nil
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(f[1]), 10, 64)
+	if err != nil {
+		return nil
+	}
+	e := &event{
+		Action:     "bench",
+		Test:       strings.TrimSpace(f[0]),
+		Iterations: n,
+		Metrics:    make(map[string]float64),
+	}
+	for _, m := range f[2:] {
+		vu := strings.Fields(m)
+		if len(vu) != 2 {
+			return nil
+		}
+		v, err := strconv.ParseFloat(vu[0], 64)
+		if err != nil {
+			return nil
+		}
+		e.Metrics[vu[1]] = v
+	}
+	return e
+}
+
+// handleInputPart handles a part of a test output line too long
+// to be recognized as a framing line. It writes the part to c.output,
+// without the marker if the line starts with one.
+func (c *Converter) handleInputPart(part []byte) {
+	if !c.midLine && len(part) > 0 && part[0] == marker {
+		part = part[1:]
+	}
+	c.midLine = !bytes.HasSuffix(part, []byte("\n"))
+	c.output.write(part)
+}
+
 // flushReport flushes all pending PASS/FAIL reports at levels >= depth.
-func (c *converter) flushReport(depth int) {
+func (c *Converter) flushReport(depth int) {
 	c.testName = ""
 	for len(c.report) > depth {
 		e := c.report[len(c.report)-1]
@@ -265,16 +382,26 @@ func (c *converter) flushReport(depth int) {
 	}
 }
 
+// SetFailedBuild records that the test binary could not be built
+// because the build of the package with the given import path failed.
+// The final "fail" event reports the package in its FailedBuild field.
+func (c *Converter) SetFailedBuild(importPath string) {
+	c.failedBuild = importPath
+}
+
 // Close marks the end of the go test output.
 // It flushes any pending input and then output (only partial lines at this point)
 // and then emits the final overall package-level pass/fail event.
-func (c *converter) Close() error {
+func (c *Converter) Close() error {
 	c.input.flush()
 	c.output.flush()
 	e := &event{Action: "fail"}
 	if c.result != "" {
 		e.Action = c.result
 	}
+	if e.Action == "fail" {
+		e.FailedBuild = c.failedBuild
+	}
 	if c.mode&Timestamp != 0 {
 		dt := time.Since(c.start).Round(1 * time.Millisecond).Seconds()
 		e.Elapsed = &dt
@@ -284,7 +411,7 @@ func (c *converter) Close() error {
 }
 
 // writeOutputEvent writes a single output event with the given bytes.
-func (c *converter) writeOutputEvent(out []byte) {
+func (c *Converter) writeOutputEvent(out []byte) {
 	c.writeEvent(&event{
 		Action: "output",
 		Output: (*textBytes)(&out),
@@ -293,23 +420,79 @@ func (c *converter) writeOutputEvent(out []byte) {
 
 // writeEvent writes a single event.
 // It adds the package, time (if requested), and test name (if needed).
-func (c *converter) writeEvent(e *event) {
+func (c *Converter) writeEvent(e *event) {
 	e.Package = c.pkg
-	if c.mode&Timestamp != 0 {
-		t := time.Now()
-		e.Time = &t
-	}
 	if e.Test == "" {
 		e.Test = c.testName
 	}
+	writeEvent(c.w, e, c.mode)
+}
+
+// writeEvent writes a single event to w,
+// adding the time if requested by mode.
+func writeEvent(w io.Writer, e *event, mode Mode) {
+	if mode&Timestamp != 0 {
+		t := time.Now()
+		e.Time = &t
+	}
 	js, err := json.Marshal(e)
 	if err != nil {
 		// Should not happen - event is valid for json.Marshal.
-		c.w.Write([]byte(fmt.Sprintf("testjson internal error: %v\n", err)))
+		w.Write([]byte(fmt.Sprintf("testjson internal error: %v\n", err)))
 		return
 	}
 	js = append(js, '\n')
-	c.w.Write(js)
+	w.Write(js)
+}
+
+// buildErrorRE matches a line of build output reporting an error,
+// or a vet diagnostic, at a position: file:line: or file:line:column:.
+var buildErrorRE = regexp.MustCompile(`^([^\s:]+\.\w+):(\d+)(?::(\d+))?: `)
+
+// WriteBuildOutput converts the output of a build step, as printed by
+// the go command, to JSON events written to w.
+//
+// If the output starts with a "# importpath" line, as the output of
+// building a package does, the events report the package in their
+// ImportPath field. Lines reporting an error at a position in a source
+// file are written as build-error events, giving the position in the
+// File, Line and Column fields; the other lines are written as
+// build-output events. The concatenation of the Output fields of the
+// events is the output.
+func WriteBuildOutput(w io.Writer, out []byte, mode Mode) {
+	var importPath string
+	if bytes.HasPrefix(out, []byte("# ")) {
+		header := out[2:]
+		if i := bytes.IndexByte(header, '\n'); i >= 0 {
+			header = header[:i]
+		}
+		importPath = string(header)
+	}
+	for len(out) > 0 {
+		line := out
+		if i := bytes.IndexByte(out, '\n'); i >= 0 {
+			line = out[:i+1]
+		}
+		out = out[len(line):]
+		e := &event{
+			Action:     "build-output",
+			ImportPath: importPath,
+			Output:     (*textBytes)(&line),
+		}
+		if m := buildErrorRE.FindSubmatch(line); m != nil {
+			e.Action = "build-error"
+			e.File = string(m[1])
+			e.Line, _ = strconv.Atoi(string(m[2]))
+			e.Column, _ = strconv.Atoi(string(m[3]))
+		}
+		writeEvent(w, e, mode)
+	}
+}
+
+// WriteBuildFail writes to w the build-fail event reporting
+// that the build of the package with the given import path failed.
+func WriteBuildFail(w io.Writer, importPath string, mode Mode) {
+	writeEvent(w, &event{Action: "build-fail", ImportPath: importPath}, mode)
 }
 
 // A lineBuffer is an I/O buffer that reacts to writes by invoking
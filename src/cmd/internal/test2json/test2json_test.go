@@ -275,3 +275,34 @@ func TestTrimUTF8(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildOutput(t *testing.T) {
+	var buf bytes.Buffer
+	out := "# example.com/p\n" +
+		"./p.go:3:2: undefined: x\n" +
+		"./p_test.go:12: Errorf format %d has arg s of wrong type string\n" +
+		"\thave (int)\n" +
+		"too many errors\n"
+	WriteBuildOutput(&buf, []byte(out), 0)
+	WriteBuildFail(&buf, "example.com/p", 0)
+	c := NewConverter(&buf, "example.com/p", 0)
+	c.SetFailedBuild("example.com/p")
+	fmt.Fprintf(c, "FAIL\texample.com/p [build failed]\n")
+	c.Close()
+
+	want := `{"Action":"build-output","ImportPath":"example.com/p","Output":"# example.com/p\n"}
+{"Action":"build-error","ImportPath":"example.com/p","File":"./p.go","Line":3,"Column":2,"Output":"./p.go:3:2: undefined: x\n"}
+{"Action":"build-error","ImportPath":"example.com/p","File":"./p_test.go","Line":12,"Output":"./p_test.go:12: Errorf format %d has arg s of wrong type string\n"}
+{"Action":"build-output","ImportPath":"example.com/p","Output":"\thave (int)\n"}
+{"Action":"build-output","ImportPath":"example.com/p","Output":"too many errors\n"}
+{"Action":"build-fail","ImportPath":"example.com/p"}
+{"Action":"output","Package":"example.com/p","Output":"FAIL\texample.com/p [build failed]\n"}
+{"Action":"fail","Package":"example.com/p","FailedBuild":"example.com/p"}
+`
+	diffJSON(t, buf.Bytes(), []byte(want))
+
+	// Without a "# importpath" header, the output is not attributed to a package.
+	buf.Reset()
+	WriteBuildOutput(&buf, []byte("go: cannot find main module\n"), 0)
+	diffJSON(t, buf.Bytes(), []byte(`{"Action":"build-output","Output":"go: cannot find main module\n"}`+"\n"))
+}
@@ -6,7 +6,7 @@
 //
 // Usage:
 //
-//	go tool test2json [-p pkg] [-t] [./pkg.test -test.v]
+//	go tool test2json [-p pkg] [-t] [./pkg.test -test.v=test2json]
 //
 // Test2json runs the given test command and converts its output to JSON;
 // with no command specified, test2json expects test output on standard input.
@@ -22,18 +22,34 @@
 // binary's output. To convert the output of a "go test" command,
 // use "go test -json" instead of invoking test2json directly.
 //
+// The test binary should be run with -test.v=test2json, which is like
+// -test.v but starts each line that frames the output of the tests,
+// such as "=== RUN" or "--- PASS", with a ^V (0x16) byte. Once
+// test2json has seen such a line, it takes only lines starting with
+// ^V as framing, so that test output resembling framing lines is
+// reported as output. The ^V bytes are not part of the output.
+//
 // Output Format
 //
 // The JSON stream is a newline-separated sequence of TestEvent objects
 // corresponding to the Go struct:
 //
 //	type TestEvent struct {
-//		Time    time.Time // encodes as an RFC3339-format string
-//		Action  string
-//		Package string
-//		Test    string
-//		Elapsed float64 // seconds
-//		Output  string
+//		Time        time.Time // encodes as an RFC3339-format string
+//		Action      string
+//		Package     string
+//		ImportPath  string
+//		Test        string
+//		File        string
+//		Line        int
+//		Column      int
+//		Elapsed     float64 // seconds
+//		Output      string
+//		Key         string
+//		Value       string
+//		Iterations  int64
+//		Metrics     map[string]float64
+//		FailedBuild string
 //	}
 //
 // The Time field holds the time the event happened.
@@ -41,12 +57,17 @@
 //
 // The Action field is one of a fixed set of action descriptions:
 //
-//	run    - the test has started running
-//	pause  - the test has been paused
-//	cont   - the test has continued running
-//	pass   - the test passed
-//	fail   - the test failed
-//	output - the test printed output
+//	run          - the test has started running
+//	pause        - the test has been paused
+//	cont         - the test has continued running
+//	pass         - the test passed
+//	fail         - the test failed
+//	output       - the test printed output
+//	attr         - the test set an attribute
+//	bench        - the benchmark reported a result
+//	build-output - the go command printed build output
+//	build-error  - the go command printed a build or vet error
+//	build-fail   - the build of a package failed
 //
 // The Package field, if present, specifies the package being tested.
 // When the go command runs parallel tests in -json mode, events from
@@ -67,6 +88,29 @@
 // the concatenation of the Output fields of all output events is the exact
 // output of the test execution.
 //
+// The Key and Value fields are set for "attr" events, which report an
+// attribute set by the test with the Attr method of testing.T or testing.B.
+// Attributes are reported only when the test is run with -test.v.
+//
+// The Iterations and Metrics fields are set for "bench" events, which
+// report the result of a benchmark, as it is printed in the output that
+// follows the event. Iterations is the number of iterations the benchmark
+// ran for. Metrics maps the units of the measurements, such as "ns/op",
+// "MB/s", "B/op" and "allocs/op", to their values.
+//
+// The FailedBuild field is set for "fail" events of a package test that
+// failed because the test binary could not be built. It gives the import
+// path of the package whose build failed.
+//
+// The go command reports the output of building packages as "build-output"
+// and "build-error" events, whose Output fields are lines of the output;
+// they have no Package or Test fields. The ImportPath field gives the
+// import path of the package that was being built, if known. A
+// "build-error" event reports a build or vet error at a position in a
+// source file, given by the File, Line and, if known, Column fields.
+// A "build-fail" event reports that the build of the package given by
+// the ImportPath field failed.
+//
 package main
 
 import (
@@ -85,7 +129,7 @@ var (
 )
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: go tool test2json [-p pkg] [-t] [./pkg.test -test.v]\n")
+	fmt.Fprintf(os.Stderr, "usage: go tool test2json [-p pkg] [-t] [./pkg.test -test.v=test2json]\n")
 	os.Exit(2)
 }
 
@@ -0,0 +1,672 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// User-defined tasks and regions (see runtime/trace).
+
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"internal/trace"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/usertasks", httpUserTasks)
+	http.HandleFunc("/usertask", httpUserTask)
+	http.HandleFunc("/userregions", httpUserRegions)
+	http.HandleFunc("/userregion", httpUserRegion)
+}
+
+// taskDesc describes a task created with trace.NewTask.
+type taskDesc struct {
+	name       string
+	id         uint64
+	create     *trace.Event    // task create event; nil if the task was created before tracing started
+	end        *trace.Event    // task end event; nil if the task did not end before tracing stopped
+	parent     *taskDesc       // parent task, if it was created in the trace
+	children   []*taskDesc     // subtasks
+	regions    []*regionDesc   // regions that belong to the task, in order of start time
+	events     []*trace.Event  // log, region and subtask creation events, in order of time
+	goroutines map[uint64]bool // goroutines that recorded events for the task
+}
+
+// regionDesc describes a region started with trace.WithRegion or trace.StartRegion.
+type regionDesc struct {
+	name  string
+	task  *taskDesc    // the task the region belongs to; nil for the background task
+	g     uint64       // goroutine that executed the region
+	start *trace.Event // region start event
+	end   *trace.Event // region end event; nil if the region did not end before tracing stopped
+}
+
+// annotationAnalysis is the result of the analysis of user annotations in a trace.
+type annotationAnalysis struct {
+	tasks   map[uint64]*taskDesc     // tasks by id
+	regions map[string][]*regionDesc // regions by name, in order of start time
+	firstTs int64                    // timestamp of the first event in the trace
+	lastTs  int64                    // timestamp of the last event in the trace
+}
+
+var (
+	annotationsInit sync.Once
+	annotations     *annotationAnalysis
+)
+
+// analyzeAnnotations collects user annotations from events and stores the result in annotations.
+func analyzeAnnotations(events []*trace.Event) {
+	annotationsInit.Do(func() {
+		annotations = computeAnnotations(events)
+	})
+}
+
+// computeAnnotations groups the user annotation events by task and by region name.
+func computeAnnotations(events []*trace.Event) *annotationAnalysis {
+	res := &annotationAnalysis{
+		tasks:   make(map[uint64]*taskDesc),
+		regions: make(map[string][]*regionDesc),
+	}
+	if len(events) == 0 {
+		return res
+	}
+	res.firstTs = events[0].Ts
+	res.lastTs = events[len(events)-1].Ts
+
+	task := func(id uint64) *taskDesc {
+		if id == 0 { // background task
+			return nil
+		}
+		t := res.tasks[id]
+		if t == nil {
+			t = &taskDesc{id: id, goroutines: make(map[uint64]bool)}
+			res.tasks[id] = t
+		}
+		return t
+	}
+
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvUserTaskCreate:
+			t := task(ev.Args[0])
+			t.name = ev.SArgs[0]
+			t.create = ev
+			t.goroutines[ev.G] = true
+			if parent := task(ev.Args[1]); parent != nil {
+				t.parent = parent
+				parent.children = append(parent.children, t)
+				parent.events = append(parent.events, ev)
+			}
+		case trace.EvUserTaskEnd:
+			t := task(ev.Args[0])
+			t.end = ev
+			t.goroutines[ev.G] = true
+		case trace.EvUserRegion:
+			t := task(ev.Args[0])
+			if t != nil {
+				t.events = append(t.events, ev)
+				t.goroutines[ev.G] = true
+			}
+			if ev.Args[1] != 0 { // region end, already linked from the start
+				continue
+			}
+			r := &regionDesc{name: ev.SArgs[0], task: t, g: ev.G, start: ev, end: ev.Link}
+			res.regions[r.name] = append(res.regions[r.name], r)
+			if t != nil {
+				t.regions = append(t.regions, r)
+			}
+		case trace.EvUserLog:
+			if t := task(ev.Args[0]); t != nil {
+				t.events = append(t.events, ev)
+				t.goroutines[ev.G] = true
+			}
+		}
+	}
+	return res
+}
+
+// complete reports whether both the start and the end of the task are in the trace.
+func (t *taskDesc) complete() bool {
+	return t.create != nil && t.end != nil
+}
+
+// startTs returns the creation time of the task, or the beginning of the
+// trace if the task was created before tracing started.
+func (t *taskDesc) startTs(a *annotationAnalysis) int64 {
+	if t.create != nil {
+		return t.create.Ts
+	}
+	return a.firstTs
+}
+
+// endTs returns the end time of the task, or the end of the trace
+// if the task did not end before tracing stopped.
+func (t *taskDesc) endTs(a *annotationAnalysis) int64 {
+	if t.end != nil {
+		return t.end.Ts
+	}
+	return a.lastTs
+}
+
+func (t *taskDesc) duration(a *annotationAnalysis) time.Duration {
+	return time.Duration(t.endTs(a) - t.startTs(a))
+}
+
+// complete reports whether the region ended before tracing stopped.
+func (r *regionDesc) complete() bool {
+	return r.end != nil
+}
+
+func (r *regionDesc) duration(a *annotationAnalysis) time.Duration {
+	if r.end != nil {
+		return time.Duration(r.end.Ts - r.start.Ts)
+	}
+	return time.Duration(a.lastTs - r.start.Ts)
+}
+
+// durationHistogram is a histogram of durations on a logarithmic
+// scale, with five buckets for every power of ten.
+type durationHistogram struct {
+	Count                int
+	Buckets              []int
+	MinBucket, MaxBucket int
+}
+
+var logDiv = math.Log(math.Pow(10, 1.0/5))
+
+func (h *durationHistogram) add(d time.Duration) {
+	var bucket int
+	if d > 0 {
+		bucket = int(math.Log(float64(d)) / logDiv)
+	}
+	if len(h.Buckets) <= bucket {
+		h.Buckets = append(h.Buckets, make([]int, bucket-len(h.Buckets)+1)...)
+	}
+	h.Buckets[bucket]++
+	if h.Count == 0 || bucket < h.MinBucket {
+		h.MinBucket = bucket
+	}
+	if h.Count == 0 || bucket > h.MaxBucket {
+		h.MaxBucket = bucket
+	}
+	h.Count++
+}
+
+// bucketMin returns the smallest duration that falls into bucket.
+func (h *durationHistogram) bucketMin(bucket int) time.Duration {
+	return time.Duration(math.Exp(float64(bucket) * logDiv))
+}
+
+// histogramRow is a row of a rendered durationHistogram.
+type histogramRow struct {
+	Min   time.Duration
+	Count int
+	Width int    // width of the bar, in pixels
+	URL   string // link to the entries in the bucket
+}
+
+// rows returns the rows to render h; link builds the URL for the
+// entries whose duration is in [min, max).
+func (h *durationHistogram) rows(link func(min, max time.Duration) string) []histogramRow {
+	if h.Count == 0 {
+		return nil
+	}
+	const barWidth = 400
+	maxCount := 0
+	for _, n := range h.Buckets {
+		if n > maxCount {
+			maxCount = n
+		}
+	}
+	var rows []histogramRow
+	for i := h.MinBucket; i <= h.MaxBucket; i++ {
+		min, max := h.bucketMin(i), h.bucketMin(i+1)
+		if i == 0 {
+			min = 0
+		}
+		rows = append(rows, histogramRow{
+			Min:   min,
+			Count: h.Buckets[i],
+			Width: h.Buckets[i] * barWidth / maxCount,
+			URL:   link(min, max),
+		})
+	}
+	return rows
+}
+
+// parseLatencyRange parses the optional latmin and latmax parameters of r,
+// which restrict the listed tasks or regions by duration.
+func parseLatencyRange(r *http.Request) (min, max time.Duration, err error) {
+	max = math.MaxInt64
+	if s := r.FormValue("latmin"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse latmin parameter '%v': %v", s, err)
+		}
+		min = time.Duration(n)
+	}
+	if s := r.FormValue("latmax"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse latmax parameter '%v': %v", s, err)
+		}
+		max = time.Duration(n)
+	}
+	return min, max, nil
+}
+
+func latencyURL(path, typ string, min, max time.Duration) string {
+	return fmt.Sprintf("%s?type=%s&complete=1&latmin=%d&latmax=%d", path, url.QueryEscape(typ), int64(min), int64(max))
+}
+
+// taskType is a group of tasks with the same name.
+type taskType struct {
+	Type      string
+	Count     int // number of tasks of this type
+	Complete  int // number of tasks that started and ended in the trace
+	Histogram []histogramRow
+}
+
+// httpUserTasks serves the list of task types with the latency distribution of each.
+func httpUserTasks(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analyzeAnnotations(events)
+	hists := make(map[string]*durationHistogram)
+	types := make(map[string]*taskType)
+	for _, t := range annotations.tasks {
+		tt := types[t.name]
+		if tt == nil {
+			tt = &taskType{Type: t.name}
+			types[t.name] = tt
+			hists[t.name] = new(durationHistogram)
+		}
+		tt.Count++
+		if t.complete() {
+			tt.Complete++
+			hists[t.name].add(t.duration(annotations))
+		}
+	}
+	var list []*taskType
+	for name, tt := range types {
+		tt.Histogram = hists[name].rows(func(min, max time.Duration) string {
+			return latencyURL("/usertask", name, min, max)
+		})
+		list = append(list, tt)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Type < list[j].Type
+	})
+	if err := templUserTasks.Execute(w, list); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templUserTasks = template.Must(template.New("").Parse(`
+<html>
+<body>
+User-defined tasks: <br>
+<table border="1" sortable="1">
+<tr>
+<th> Task type </th>
+<th> Count </th>
+<th> Duration distribution (complete tasks) </th>
+</tr>
+{{range $}}
+  <tr>
+    <td> {{.Type}} </td>
+    <td> <a href="/usertask?type={{.Type}}">{{.Count}}</a> (<a href="/usertask?type={{.Type}}&complete=1">{{.Complete}} complete</a>) </td>
+    <td>
+      <table>
+      {{range .Histogram}}
+        <tr>
+          <td align="right"> <a href="{{.URL}}">{{.Min}}</a> </td>
+          <td> <div style="width:{{.Width}}px;background:blue">&nbsp;</div> </td>
+          <td align="right"> {{.Count}} </td>
+        </tr>
+      {{end}}
+      </table>
+    </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// taskEvent is a line of the timeline of a task.
+type taskEvent struct {
+	When    time.Duration // time since the start of the task
+	Elapsed time.Duration // time since the previous line
+	G       uint64
+	What    string
+}
+
+// taskTimeline is a task with the timeline of its events.
+type taskTimeline struct {
+	ID         uint64
+	Parent     uint64
+	Start      time.Duration // time since the start of the trace
+	Duration   time.Duration
+	Complete   bool
+	Goroutines []uint64
+	Events     []taskEvent
+}
+
+// httpUserTask serves the timelines of the tasks of a given type.
+func httpUserTask(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	latmin, latmax, err := parseLatencyRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	typ := r.FormValue("type")
+	completeOnly := r.FormValue("complete") != ""
+	analyzeAnnotations(events)
+	a := annotations
+
+	var tasks []*taskDesc
+	for _, t := range a.tasks {
+		if t.name != typ || completeOnly && !t.complete() {
+			continue
+		}
+		if d := t.duration(a); d < latmin || d >= latmax {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].startTs(a) < tasks[j].startTs(a)
+	})
+
+	var list []taskTimeline
+	for _, t := range tasks {
+		list = append(list, t.timeline(a))
+	}
+	err = templUserTask.Execute(w, struct {
+		Type  string
+		Tasks []taskTimeline
+	}{typ, list})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// timeline returns the events of t in order of time, relative to the start of t.
+func (t *taskDesc) timeline(a *annotationAnalysis) taskTimeline {
+	start := t.startTs(a)
+	tl := taskTimeline{
+		ID:       t.id,
+		Start:    time.Duration(start - a.firstTs),
+		Duration: t.duration(a),
+		Complete: t.complete(),
+	}
+	if t.parent != nil {
+		tl.Parent = t.parent.id
+	}
+	for g := range t.goroutines {
+		tl.Goroutines = append(tl.Goroutines, g)
+	}
+	sort.Slice(tl.Goroutines, func(i, j int) bool { return tl.Goroutines[i] < tl.Goroutines[j] })
+
+	last := start
+	add := func(ev *trace.Event, what string) {
+		tl.Events = append(tl.Events, taskEvent{
+			When:    time.Duration(ev.Ts - start),
+			Elapsed: time.Duration(ev.Ts - last),
+			G:       ev.G,
+			What:    what,
+		})
+		last = ev.Ts
+	}
+	if t.create != nil {
+		add(t.create, fmt.Sprintf("task %s (id %d) created", t.name, t.id))
+	}
+	for _, ev := range t.events {
+		add(ev, describeUserEvent(ev, a))
+	}
+	if t.end != nil {
+		add(t.end, "task end")
+	}
+	return tl
+}
+
+// describeUserEvent returns a one-line description of a user annotation event.
+func describeUserEvent(ev *trace.Event, a *annotationAnalysis) string {
+	switch ev.Type {
+	case trace.EvUserTaskCreate:
+		return fmt.Sprintf("subtask %s (id %d) created", ev.SArgs[0], ev.Args[0])
+	case trace.EvUserRegion:
+		if ev.Args[1] != 0 {
+			return fmt.Sprintf("region %s ended", ev.SArgs[0])
+		}
+		if ev.Link == nil {
+			return fmt.Sprintf("region %s started (unfinished)", ev.SArgs[0])
+		}
+		return fmt.Sprintf("region %s started (duration: %v)", ev.SArgs[0], time.Duration(ev.Link.Ts-ev.Ts))
+	case trace.EvUserLog:
+		return formatUserLog(ev)
+	}
+	return ev.String()
+}
+
+// formatUserLog returns the text of a log event.
+func formatUserLog(ev *trace.Event) string {
+	category, message := ev.SArgs[0], ev.SArgs[1]
+	if category == "" {
+		return message
+	}
+	return category + ": " + message
+}
+
+var templUserTask = template.Must(template.New("").Parse(`
+<html>
+<body>
+User-defined task: {{.Type}} <br>
+<table border="1" sortable="1">
+<tr>
+<th> When </th>
+<th> Elapsed </th>
+<th> Goroutine </th>
+<th> Events </th>
+</tr>
+{{range .Tasks}}
+  <tr>
+    <td> {{.Start}} </td>
+    <td> {{.Duration}} </td>
+    <td> {{range .Goroutines}}<a href="/trace?goid={{.}}">{{.}}</a> {{end}}</td>
+    <td> <b>Task {{.ID}}</b>{{if .Parent}} (parent task {{.Parent}}){{end}}{{if not .Complete}} (incomplete){{end}} </td>
+  </tr>
+  {{range .Events}}
+  <tr>
+    <td align="right"> {{.When}} </td>
+    <td align="right"> {{.Elapsed}} </td>
+    <td> <a href="/trace?goid={{.G}}">{{.G}}</a> </td>
+    <td> {{.What}} </td>
+  </tr>
+  {{end}}
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// regionType is a group of regions with the same name.
+type regionType struct {
+	Type      string
+	Count     int // number of regions of this type
+	Complete  int // number of regions that ended before tracing stopped
+	Histogram []histogramRow
+}
+
+// httpUserRegions serves the list of region types with the duration distribution of each.
+func httpUserRegions(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analyzeAnnotations(events)
+	var list []*regionType
+	for name, regions := range annotations.regions {
+		rt := &regionType{Type: name, Count: len(regions)}
+		var hist durationHistogram
+		for _, r := range regions {
+			if r.complete() {
+				rt.Complete++
+				hist.add(r.duration(annotations))
+			}
+		}
+		rt.Histogram = hist.rows(func(min, max time.Duration) string {
+			return latencyURL("/userregion", name, min, max)
+		})
+		list = append(list, rt)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Type < list[j].Type
+	})
+	if err := templUserRegions.Execute(w, list); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templUserRegions = template.Must(template.New("").Parse(`
+<html>
+<body>
+User-defined regions: <br>
+<table border="1" sortable="1">
+<tr>
+<th> Region type </th>
+<th> Count </th>
+<th> Duration distribution (complete regions) </th>
+</tr>
+{{range $}}
+  <tr>
+    <td> {{.Type}} </td>
+    <td> <a href="/userregion?type={{.Type}}">{{.Count}}</a> ({{.Complete}} complete) </td>
+    <td>
+      <table>
+      {{range .Histogram}}
+        <tr>
+          <td align="right"> <a href="{{.URL}}">{{.Min}}</a> </td>
+          <td> <div style="width:{{.Width}}px;background:blue">&nbsp;</div> </td>
+          <td align="right"> {{.Count}} </td>
+        </tr>
+      {{end}}
+      </table>
+    </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// regionInstance is a row of the list of regions of a given type.
+type regionInstance struct {
+	G        uint64
+	TaskID   uint64
+	TaskName string
+	Start    time.Duration // time since the start of the trace
+	Duration time.Duration
+	Complete bool
+}
+
+// httpUserRegion serves the list of regions of a given type, longest first.
+func httpUserRegion(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	latmin, latmax, err := parseLatencyRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	typ := r.FormValue("type")
+	completeOnly := r.FormValue("complete") != ""
+	analyzeAnnotations(events)
+	a := annotations
+
+	var list []regionInstance
+	for _, reg := range a.regions[typ] {
+		if completeOnly && !reg.complete() {
+			continue
+		}
+		d := reg.duration(a)
+		if d < latmin || d >= latmax {
+			continue
+		}
+		ri := regionInstance{
+			G:        reg.g,
+			Start:    time.Duration(reg.start.Ts - a.firstTs),
+			Duration: d,
+			Complete: reg.complete(),
+		}
+		if reg.task != nil {
+			ri.TaskID = reg.task.id
+			ri.TaskName = reg.task.name
+		}
+		list = append(list, ri)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Duration > list[j].Duration
+	})
+	err = templUserRegion.Execute(w, struct {
+		Type    string
+		Regions []regionInstance
+	}{typ, list})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templUserRegion = template.Must(template.New("").Parse(`
+<html>
+<body>
+User-defined region: {{.Type}} <br>
+<table border="1" sortable="1">
+<tr>
+<th> Goroutine </th>
+<th> Task </th>
+<th> Start </th>
+<th> Duration </th>
+</tr>
+{{range .Regions}}
+  <tr>
+    <td> <a href="/trace?goid={{.G}}">{{.G}}</a> </td>
+    <td> {{if .TaskID}}<a href="/usertask?type={{.TaskName}}">{{.TaskName}}</a> ({{.TaskID}}){{end}} </td>
+    <td> {{.Start}} </td>
+    <td> {{.Duration}}{{if not .Complete}} (unfinished){{end}} </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,192 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"internal/trace"
+	rtrace "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+// prog0 starts three goroutines.
+//
+//   goroutine 1: taskless region
+//   goroutine 2: starts task0, do work in task0.region0, starts task1 which ends immediately.
+//   goroutine 3: do work in task0.region1 and task0.region2, ends task0
+func prog0() {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() { // goroutine 1
+		defer wg.Done()
+		rtrace.WithRegion(ctx, "taskless.region", func() {
+			rtrace.Log(ctx, "key0", "val0")
+		})
+	}()
+
+	wg.Add(1)
+	go func() { // goroutine 2
+		defer wg.Done()
+		ctx, task := rtrace.NewTask(ctx, "task0")
+		rtrace.WithRegion(ctx, "task0.region0", func() {
+			wg.Add(1)
+			go func() { // goroutine 3
+				defer wg.Done()
+				defer task.End()
+				rtrace.WithRegion(ctx, "task0.region1", func() {
+					rtrace.WithRegion(ctx, "task0.region2", func() {
+						rtrace.Log(ctx, "key2", "val2")
+					})
+					rtrace.Log(ctx, "key1", "val1")
+				})
+			}()
+		})
+		ctx2, task2 := rtrace.NewTask(ctx, "task1")
+		rtrace.Log(ctx2, "key3", "val3")
+		task2.End()
+	}()
+	wg.Wait()
+}
+
+func traceProgram(t *testing.T, f func()) []*trace.Event {
+	buf := new(bytes.Buffer)
+	if err := rtrace.Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	f()
+	rtrace.Stop()
+
+	res, err := trace.Parse(buf, "")
+	if err != nil {
+		t.Fatalf("failed to parse trace: %v", err)
+	}
+	return res.Events
+}
+
+func TestAnalyzeAnnotations(t *testing.T) {
+	a := computeAnnotations(traceProgram(t, prog0))
+
+	tasks := make(map[string]*taskDesc)
+	for _, task := range a.tasks {
+		tasks[task.name] = task
+	}
+	task0, task1 := tasks["task0"], tasks["task1"]
+	if len(tasks) != 2 || task0 == nil || task1 == nil {
+		t.Fatalf("got tasks %v; want task0 and task1", tasks)
+	}
+	if !task0.complete() || !task1.complete() {
+		t.Errorf("task0 complete=%v, task1 complete=%v; want both complete", task0.complete(), task1.complete())
+	}
+	if task1.parent != task0 || len(task0.children) != 1 || task0.children[0] != task1 {
+		t.Errorf("task1 is not linked as a subtask of task0")
+	}
+	if len(task0.goroutines) != 2 {
+		t.Errorf("task0 recorded events in %d goroutines; want 2", len(task0.goroutines))
+	}
+	if task0.duration(a) <= 0 {
+		t.Errorf("task0 duration = %v; want > 0", task0.duration(a))
+	}
+
+	var regions []string
+	for _, r := range task0.regions {
+		regions = append(regions, r.name)
+		if !r.complete() {
+			t.Errorf("region %s is not complete", r.name)
+		}
+		if r.duration(a) > task0.duration(a) {
+			t.Errorf("region %s is longer than its task: %v > %v", r.name, r.duration(a), task0.duration(a))
+		}
+	}
+	if want := []string{"task0.region0", "task0.region1", "task0.region2"}; !equalStrings(regions, want) {
+		t.Errorf("task0 regions = %v; want %v", regions, want)
+	}
+	if r := a.regions["taskless.region"]; len(r) != 1 || r[0].task != nil {
+		t.Errorf("taskless.region = %v; want one region without a task", r)
+	}
+
+	var logs []string
+	for _, ev := range task0.events {
+		if ev.Type == trace.EvUserLog {
+			logs = append(logs, formatUserLog(ev))
+		}
+	}
+	if want := []string{"key2: val2", "key1: val1"}; !equalStrings(logs, want) {
+		t.Errorf("task0 logs = %v; want %v", logs, want)
+	}
+}
+
+func TestTaskTimeline(t *testing.T) {
+	a := computeAnnotations(traceProgram(t, prog0))
+
+	for _, task := range a.tasks {
+		if task.name != "task1" {
+			continue
+		}
+		tl := task.timeline(a)
+		var what []string
+		for _, ev := range tl.Events {
+			what = append(what, ev.What)
+			if ev.When < 0 || ev.When > tl.Duration {
+				t.Errorf("event %q at %v is outside of the task (duration %v)", ev.What, ev.When, tl.Duration)
+			}
+		}
+		want := []string{
+			fmt.Sprintf("task task1 (id %d) created", task.id),
+			"key3: val3",
+			"task end",
+		}
+		if !equalStrings(what, want) {
+			t.Errorf("task1 timeline = %q; want %q", what, want)
+		}
+		return
+	}
+	t.Fatalf("task1 not found")
+}
+
+func TestDurationHistogram(t *testing.T) {
+	var h durationHistogram
+	for _, d := range []time.Duration{0, 1, 10 * time.Microsecond, 11 * time.Microsecond, time.Second} {
+		h.add(d)
+	}
+	if h.Count != 5 {
+		t.Errorf("Count = %d; want 5", h.Count)
+	}
+	rows := h.rows(func(min, max time.Duration) string { return "" })
+	if len(rows) != h.MaxBucket-h.MinBucket+1 {
+		t.Fatalf("got %d rows; want %d", len(rows), h.MaxBucket-h.MinBucket+1)
+	}
+	total := 0
+	for i, r := range rows {
+		total += r.Count
+		if i > 0 && r.Min <= rows[i-1].Min {
+			t.Errorf("row %d starts at %v, not after row %d at %v", i, r.Min, i-1, rows[i-1].Min)
+		}
+	}
+	if total != h.Count {
+		t.Errorf("rows contain %d entries; want %d", total, h.Count)
+	}
+	if last := rows[len(rows)-1]; last.Min > time.Second || last.Count != 1 {
+		t.Errorf("last row = %+v; want one entry at most 1s", last)
+	}
+}
+
+func equalStrings(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -27,6 +27,11 @@ Supported profile types are:
 Then, you can use the pprof tool to analyze the profile:
 	go tool pprof TYPE.pprof
 
+Programs annotated with the tasks, regions and logs of package runtime/trace
+can be inspected on the 'User-defined tasks' and 'User-defined regions'
+pages, which show the latency distribution of each task and region type
+and, for each task, the timeline of its regions and log messages.
+
 Note that while the various profiles available when launching
 'go tool trace' work on every browser, the trace viewer itself
 (the 'view trace' page) comes from the Chrome/Chromium project
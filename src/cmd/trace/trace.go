@@ -274,6 +274,7 @@ type traceContext struct {
 	frameTree frameNode
 	frameSeq  int
 	arrowSeq  uint64
+	regionSeq uint64
 	gcount    uint64
 
 	heapStats, prevHeapStats     heapStats
@@ -328,6 +329,7 @@ type ViewerData struct {
 
 type ViewerEvent struct {
 	Name     string      `json:"name,omitempty"`
+	Category string      `json:"cat,omitempty"`
 	Phase    string      `json:"ph"`
 	Scope    string      `json:"s,omitempty"`
 	Time     float64     `json:"ts"`
@@ -560,6 +562,12 @@ func generateTrace(params *traceParams) (ViewerData, error) {
 			ctx.emitInstant(ev, "syscall")
 		case trace.EvGoSysExit:
 			ctx.emitArrow(ev, "sysexit")
+		case trace.EvUserRegion:
+			if ctx.gtrace && ev.Args[1] == 0 { // region start
+				ctx.emitRegion(ev)
+			}
+		case trace.EvUserLog:
+			ctx.emitInstant(ev, formatUserLog(ev))
 		}
 		// Emit any counter updates.
 		ctx.emitThreadCounters(ev)
@@ -725,6 +733,23 @@ func (ctx *traceContext) emitArrow(ev *trace.Event, name string) {
 	ctx.emit(&ViewerEvent{Name: name, Phase: "t", Tid: ctx.proc(ev.Link), ID: ctx.arrowSeq, Time: ctx.time(ev.Link)})
 }
 
+// emitRegion emits a user-defined region as a pair of async events on the
+// row of the goroutine that executed it. A region that did not end before
+// tracing stopped extends to the end of the displayed range.
+func (ctx *traceContext) emitRegion(ev *trace.Event) {
+	ctx.regionSeq++
+	name := ev.SArgs[0]
+	ctx.emit(&ViewerEvent{Category: "Region", Name: name, Phase: "b", Tid: ev.G, ID: ctx.regionSeq, Time: ctx.time(ev), Stack: ctx.stack(ev.Stk)})
+	end := &ViewerEvent{Category: "Region", Name: name, Phase: "e", Tid: ev.G, ID: ctx.regionSeq}
+	if ev.Link != nil && ev.Link.Ts <= ctx.endTime {
+		end.Time = ctx.time(ev.Link)
+		end.Stack = ctx.stack(ev.Link.Stk)
+	} else {
+		end.Time = float64(ctx.endTime-ctx.startTime) / 1000
+	}
+	ctx.emit(end)
+}
+
 func (ctx *traceContext) stack(stk []*trace.Frame) int {
 	return ctx.buildBranch(ctx.frameTree, stk)
 }
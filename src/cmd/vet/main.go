@@ -34,6 +34,8 @@ var (
 	tags    = flag.String("tags", "", "space-separated list of build tags to apply when parsing")
 	tagList = []string{} // exploded version of tags flag; set in main
 
+	printflags = flag.Bool("flags", false, "print flags in JSON and exit")
+
 	vcfg          vetConfig
 	mustTypecheck bool
 )
@@ -210,6 +212,13 @@ func main() {
 	flag.Usage = Usage
 	flag.Parse()
 
+	// -flags: describe the flags to the go command,
+	// for use as "go vet -vettool".
+	if *printflags {
+		printFlags()
+		os.Exit(0)
+	}
+
 	// If any flag is set, we run only those checks requested.
 	// If all flag is set true or if no flags are set true, set all the non-experimental ones
 	// not explicitly set (in effect, set the "-all" flag).
@@ -275,6 +284,30 @@ func main() {
 	os.Exit(exitCode)
 }
 
+// printFlags prints the vet flags as a JSON list of
+// {Name, Bool, Usage} objects.
+func printFlags() {
+	type jsonFlag struct {
+		Name  string
+		Bool  bool
+		Usage string
+	}
+	var flags []jsonFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name == "flags" {
+			return
+		}
+		b, isBool := f.Value.(interface{ IsBoolFlag() bool })
+		isBool = isBool && b.IsBoolFlag()
+		flags = append(flags, jsonFlag{f.Name, isBool, f.Usage})
+	})
+	data, err := json.MarshalIndent(flags, "", "\t")
+	if err != nil {
+		errorf("%v", err)
+	}
+	os.Stdout.Write(data)
+}
+
 // prefixDirectory places the directory name on the beginning of each name in the list.
 func prefixDirectory(directory string, names []string) {
 	if directory != "." {
@@ -292,6 +325,7 @@ type vetConfig struct {
 	GoFiles     []string
 	ImportMap   map[string]string
 	PackageFile map[string]string
+	VetxOnly    bool
 
 	SucceedOnTypecheckFailure bool
 
@@ -339,6 +373,11 @@ func doPackageCfg(cfgFile string) {
 	if err := json.Unmarshal(js, &vcfg); err != nil {
 		errorf("parsing vet config %s: %v", cfgFile, err)
 	}
+	if vcfg.VetxOnly {
+		// Vet computes no facts for the
+		// analysis of importing packages.
+		return
+	}
 	stdImporter = &vcfg
 	inittypes()
 	mustTypecheck = true
@@ -317,6 +317,7 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string) {
 	// Hard part: check formats against args.
 	argNum := firstArg
 	maxArgNum := firstArg
+	anyW := false
 	for i, w := 0, 0; i < len(format); i += w {
 		w = 1
 		if format[i] != '%' {
@@ -330,6 +331,17 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string) {
 		if !f.okPrintfArg(call, state) { // One error per format is enough.
 			return
 		}
+		if state.verb == 'w' {
+			if name != "Errorf" {
+				f.Badf(call.Pos(), "%s call has error-wrapping directive %%w", name)
+				return
+			}
+			if anyW {
+				f.Badf(call.Pos(), "%s call has more than one error-wrapping directive %%w", name)
+				return
+			}
+			anyW = true
+		}
 		if len(state.argNums) > 0 {
 			// Continue with the next sequential argument.
 			argNum = state.argNums[len(state.argNums)-1] + 1
@@ -495,6 +507,7 @@ const (
 	argFloat
 	argComplex
 	argPointer
+	argError
 	anyType printfArgType = ^0
 )
 
@@ -538,6 +551,7 @@ var printVerbs = []printVerb{
 	{'T', "-", anyType},
 	{'U', "-#", argRune | argInt},
 	{'v', allFlags, anyType},
+	{'w', allFlags, argError},
 	{'x', sharpNumFlag, argRune | argInt | argString},
 	{'X', sharpNumFlag, argRune | argInt | argString},
 }
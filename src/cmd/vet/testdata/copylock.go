@@ -185,4 +185,15 @@ func AtomicTypesCheck() {
 	var vYY = vX
 	vP := &vX
 	vZ := &atomic.Value{}
+
+	// The typed atomic values contain a noCopy.
+	var iX atomic.Int64
+	iY := iX // ERROR "assignment copies lock value to iY: sync/atomic.Int64 contains sync/atomic.noCopy"
+	iY = iX  // ERROR "assignment copies lock value to iY: sync/atomic.Int64 contains sync/atomic.noCopy"
+	var bX atomic.Bool
+	var bYY = bX // ERROR "variable declaration copies lock value to bYY: sync/atomic.Bool contains sync/atomic.noCopy"
+	pX := atomic.Pointer{}
+	pY := pX // ERROR "assignment copies lock value to pY: sync/atomic.Pointer contains sync/atomic.noCopy"
+	iP := &iX
+	iZ := &atomic.Uint64{}
 }
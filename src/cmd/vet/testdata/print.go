@@ -114,6 +114,10 @@ func PrintfTests() {
 	fmt.Printf("%t", 1+2i)                      // ERROR "Printf format %t has arg 1 \+ 2i of wrong type complex128"
 	fmt.Printf("%c", 2.3)                       // ERROR "Printf format %c has arg 2.3 of wrong type float64"
 	fmt.Printf("%d", 2.3)                       // ERROR "Printf format %d has arg 2.3 of wrong type float64"
+	fmt.Errorf("%w", 3)                         // ERROR "Errorf format %w has arg 3 of wrong type int"
+	fmt.Printf("%w", os.ErrNotExist)            // ERROR "Printf call has error-wrapping directive %w"
+	fmt.Errorf("%w %w", os.ErrExist, os.ErrExist) // ERROR "Errorf call has more than one error-wrapping directive %w"
+	fmt.Errorf("%w: %v", os.ErrExist, os.ErrExist)
 	fmt.Printf("%e", "hi")                      // ERROR "Printf format %e has arg \x22hi\x22 of wrong type string"
 	fmt.Printf("%E", true)                      // ERROR "Printf format %E has arg true of wrong type bool"
 	fmt.Printf("%f", "hi")                      // ERROR "Printf format %f has arg \x22hi\x22 of wrong type string"
@@ -5,6 +5,7 @@
 package context
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"runtime"
@@ -648,3 +649,277 @@ func XTestDeadlineExceededSupportsTimeout(t testingT) {
 		t.Fatal("wrong value for timeout")
 	}
 }
+
+func XTestCause(t testingT) {
+	var (
+		forever       = 1e6 * time.Second
+		parentCause   = errors.New("parentCause")
+		childCause    = errors.New("childCause")
+		tooSlow       = errors.New("tooSlow")
+		finishedEarly = errors.New("finishedEarly")
+	)
+	for _, test := range []struct {
+		name  string
+		ctx   func() Context
+		err   error
+		cause error
+	}{
+		{
+			name: "Background",
+			ctx:  Background,
+		},
+		{
+			name: "WithCancelCause canceled with nil",
+			ctx: func() Context {
+				ctx, cancel := WithCancelCause(Background())
+				cancel(nil)
+				return ctx
+			},
+			err:   Canceled,
+			cause: Canceled,
+		},
+		{
+			name: "WithCancelCause",
+			ctx: func() Context {
+				ctx, cancel := WithCancelCause(Background())
+				cancel(parentCause)
+				return ctx
+			},
+			err:   Canceled,
+			cause: parentCause,
+		},
+		{
+			name: "parent canceled first",
+			ctx: func() Context {
+				ctx, cancelParent := WithCancelCause(Background())
+				ctx, cancelChild := WithCancelCause(ctx)
+				cancelParent(parentCause)
+				cancelChild(childCause)
+				return ctx
+			},
+			err:   Canceled,
+			cause: parentCause,
+		},
+		{
+			name: "child canceled first",
+			ctx: func() Context {
+				ctx, cancelParent := WithCancelCause(Background())
+				ctx, cancelChild := WithCancelCause(ctx)
+				cancelChild(childCause)
+				cancelParent(parentCause)
+				return ctx
+			},
+			err:   Canceled,
+			cause: childCause,
+		},
+		{
+			name: "child of canceled parent",
+			ctx: func() Context {
+				ctx, cancel := WithCancelCause(Background())
+				cancel(parentCause)
+				ctx, _ = WithTimeout(WithValue(ctx, "k", "v"), forever)
+				return ctx
+			},
+			err:   Canceled,
+			cause: parentCause,
+		},
+		{
+			name: "WithTimeoutCause expired",
+			ctx: func() Context {
+				ctx, _ := WithTimeoutCause(Background(), 0, tooSlow)
+				return ctx
+			},
+			err:   DeadlineExceeded,
+			cause: tooSlow,
+		},
+		{
+			name: "WithTimeoutCause canceled",
+			ctx: func() Context {
+				ctx, cancel := WithTimeoutCause(Background(), forever, tooSlow)
+				cancel()
+				return ctx
+			},
+			err:   Canceled,
+			cause: Canceled,
+		},
+		{
+			name: "WithDeadlineCause in WithCancelCause",
+			ctx: func() Context {
+				ctx, cancel := WithCancelCause(Background())
+				ctx, _ = WithDeadlineCause(ctx, time.Now().Add(forever), tooSlow)
+				cancel(finishedEarly)
+				return ctx
+			},
+			err:   Canceled,
+			cause: finishedEarly,
+		},
+		{
+			name: "WithoutCancel",
+			ctx: func() Context {
+				ctx, cancel := WithCancelCause(Background())
+				cancel(parentCause)
+				return WithoutCancel(ctx)
+			},
+		},
+	} {
+		ctx := test.ctx()
+		if got, want := ctx.Err(), test.err; got != want {
+			t.Errorf("%s: ctx.Err() = %v want %v", test.name, got, want)
+		}
+		if got, want := Cause(ctx), test.cause; got != want {
+			t.Errorf("%s: Cause(ctx) = %v want %v", test.name, got, want)
+		}
+	}
+}
+
+func XTestCauseRace(t testingT) {
+	cause := errors.New("TestCauseRace")
+	ctx, cancel := WithCancelCause(Background())
+	go func() {
+		cancel(cause)
+	}()
+	for {
+		// Poll Cause, rather than waiting for Done, to test that
+		// access to the underlying cause is synchronized properly.
+		if err := Cause(ctx); err != nil {
+			if err != cause {
+				t.Errorf("Cause returned %v, want %v", err, cause)
+			}
+			break
+		}
+		runtime.Gosched()
+	}
+}
+
+func XTestWithoutCancel(t testingT) {
+	key, value := "key", "value"
+	ctx := WithValue(Background(), key, value)
+	ctx = WithoutCancel(ctx)
+	if d, ok := ctx.Deadline(); !d.IsZero() || ok != false {
+		t.Errorf("ctx.Deadline() = %v, %v want zero, false", d, ok)
+	}
+	if done := ctx.Done(); done != nil {
+		t.Errorf("ctx.Deadline() = %v want nil", done)
+	}
+	if err := ctx.Err(); err != nil {
+		t.Errorf("ctx.Err() = %v want nil", err)
+	}
+	if v := ctx.Value(key); v != value {
+		t.Errorf("ctx.Value(%q) = %q want %q", key, v, value)
+	}
+
+	// A context derived from one without cancellation
+	// is not canceled with the original parent.
+	parent, cancelParent := WithCancel(Background())
+	child, cancelChild := WithCancel(WithoutCancel(parent))
+	defer cancelChild()
+	pc, _ := parentCancelCtx(parent)
+	if len(pc.children) != 0 {
+		t.Errorf("WithoutCancel context registered %d children with parent", len(pc.children))
+	}
+	cancelParent()
+	select {
+	case <-child.Done():
+		t.Errorf("child of WithoutCancel context canceled with parent")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if got, want := fmt.Sprint(WithoutCancel(Background())), "context.Background.WithoutCancel"; got != want {
+		t.Errorf("WithoutCancel(Background()) String() = %q want %q", got, want)
+	}
+}
+
+func XTestAfterFuncCalledAfterCancel(t testingT) {
+	ctx, cancel := WithCancel(Background())
+	donec := make(chan struct{})
+	stop := AfterFunc(WithValue(ctx, "k", "v"), func() {
+		close(donec)
+	})
+	// The function is registered with the cancelCtx,
+	// rather than waited for by a goroutine.
+	pc, _ := parentCancelCtx(ctx)
+	if len(pc.children) != 1 {
+		t.Fatalf("AfterFunc registered %d children with ctx, want 1", len(pc.children))
+	}
+	select {
+	case <-donec:
+		t.Fatalf("AfterFunc called before context is done")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case <-donec:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("AfterFunc not called after context is canceled")
+	}
+	if stop() {
+		t.Fatalf("stop() = true, want false")
+	}
+}
+
+func XTestAfterFuncCalledAfterTimeout(t testingT) {
+	ctx, cancel := WithTimeout(Background(), 5*time.Millisecond)
+	defer cancel()
+	donec := make(chan struct{})
+	AfterFunc(ctx, func() {
+		close(donec)
+	})
+	select {
+	case <-donec:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("AfterFunc not called after context times out")
+	}
+}
+
+func XTestAfterFuncCalledImmediately(t testingT) {
+	ctx, cancel := WithCancel(Background())
+	cancel()
+	donec := make(chan struct{})
+	AfterFunc(ctx, func() {
+		close(donec)
+	})
+	select {
+	case <-donec:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("AfterFunc not called for already-canceled context")
+	}
+}
+
+func XTestAfterFuncNotCalledAfterStop(t testingT) {
+	ctx, cancel := WithCancel(Background())
+	donec := make(chan struct{})
+	stop := AfterFunc(ctx, func() {
+		close(donec)
+	})
+	if !stop() {
+		t.Fatalf("stop() = false, want true")
+	}
+	pc, _ := parentCancelCtx(ctx)
+	if len(pc.children) != 0 {
+		t.Errorf("stopped AfterFunc still registered with ctx")
+	}
+	cancel()
+	select {
+	case <-donec:
+		t.Fatalf("AfterFunc called for context after stop")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if stop() {
+		t.Fatalf("stop() = true, want false")
+	}
+}
+
+func XTestAfterFuncOtherContext(t testingT) {
+	// AfterFunc works with contexts not created by this package,
+	// by waiting for them to be done.
+	ctx, cancel := WithCancel(Background())
+	donec := make(chan struct{})
+	AfterFunc(otherContext{ctx}, func() {
+		close(donec)
+	})
+	cancel()
+	select {
+	case <-donec:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("AfterFunc not called after other context is canceled")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// This file implements the signatures of the TLS 1.3 CertificateVerify
+// message. See RFC 8446, Section 4.4.3.
+
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+var signaturePadding = bytes.Repeat([]byte{0x20}, 64)
+
+// supportedSignatureAlgorithmsTLS13 contains the signature algorithms that
+// are advertised and accepted in TLS 1.3. RSA PKCS #1 v1.5 and SHA-1 are not
+// allowed in TLS 1.3 CertificateVerify messages.
+var supportedSignatureAlgorithmsTLS13 = []SignatureScheme{
+	PSSWithSHA256,
+	ECDSAWithP256AndSHA256,
+	PSSWithSHA384,
+	ECDSAWithP384AndSHA384,
+	PSSWithSHA512,
+	ECDSAWithP521AndSHA512,
+}
+
+// signedMessage returns the digest that is signed by a TLS 1.3
+// CertificateVerify: the hash, with sigHash, of the padding, the context
+// string and the current transcript hash.
+func signedMessage(sigHash crypto.Hash, context string, transcript hash.Hash) []byte {
+	h := sigHash.New()
+	h.Write(signaturePadding)
+	io.WriteString(h, context)
+	h.Write(transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+// signatureSchemesForKeyTLS13 returns the TLS 1.3 signature algorithms that
+// can be used with pub, in order of preference.
+func signatureSchemesForKeyTLS13(pub crypto.PublicKey) []SignatureScheme {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return []SignatureScheme{ECDSAWithP256AndSHA256}
+		case elliptic.P384():
+			return []SignatureScheme{ECDSAWithP384AndSHA384}
+		case elliptic.P521():
+			return []SignatureScheme{ECDSAWithP521AndSHA512}
+		}
+	case *rsa.PublicKey:
+		// RSA-PSS needs the key to fit the hash twice, plus two bytes.
+		var schemes []SignatureScheme
+		for _, s := range []SignatureScheme{PSSWithSHA256, PSSWithSHA384, PSSWithSHA512} {
+			h, _ := lookupTLSHash(s)
+			if (pub.N.BitLen()+7)/8 >= 2*h.Size()+2 {
+				schemes = append(schemes, s)
+			}
+		}
+		return schemes
+	}
+	return nil
+}
+
+// selectSignatureSchemeTLS13 picks the first signature algorithm usable with
+// pub that the peer advertised in peerAlgs.
+func selectSignatureSchemeTLS13(pub crypto.PublicKey, peerAlgs []SignatureScheme) (SignatureScheme, error) {
+	for _, s := range signatureSchemesForKeyTLS13(pub) {
+		if isSupportedSignatureAlgorithm(s, peerAlgs) {
+			return s, nil
+		}
+	}
+	return 0, errors.New("tls: peer doesn't support any of the certificate's signature algorithms")
+}
+
+// signTLS13 produces the signature of a TLS 1.3 CertificateVerify message.
+func signTLS13(rand io.Reader, key crypto.PrivateKey, sigAlg SignatureScheme, context string, transcript hash.Hash) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("tls: certificate private key of type %T does not implement crypto.Signer", key)
+	}
+	sigHash, err := lookupTLSHash(sigAlg)
+	if err != nil {
+		return nil, err
+	}
+	var opts crypto.SignerOpts = sigHash
+	if _, ok := signer.Public().(*rsa.PublicKey); ok {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
+	}
+	return signer.Sign(rand, signedMessage(sigHash, context, transcript), opts)
+}
+
+// verifyTLS13 checks the signature of a TLS 1.3 CertificateVerify message.
+func verifyTLS13(pub crypto.PublicKey, sigAlg SignatureScheme, context string, transcript hash.Hash, sig []byte) error {
+	if !isSupportedSignatureAlgorithm(sigAlg, signatureSchemesForKeyTLS13(pub)) {
+		return errors.New("tls: invalid signature algorithm for certificate key")
+	}
+	sigHash, err := lookupTLSHash(sigAlg)
+	if err != nil {
+		return err
+	}
+	digest := signedMessage(sigHash, context, transcript)
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		ecdsaSig := new(ecdsaSignature)
+		if _, err := asn1.Unmarshal(sig, ecdsaSig); err != nil {
+			return err
+		}
+		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
+			return errors.New("tls: ECDSA signature contained zero or negative values")
+		}
+		if !ecdsa.Verify(pub, digest, ecdsaSig.R, ecdsaSig.S) {
+			return errors.New("tls: ECDSA verification failure")
+		}
+	case *rsa.PublicKey:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		if err := rsa.VerifyPSS(pub, sigHash, digest, sig, opts); err != nil {
+			return err
+		}
+	default:
+		return errors.New("tls: unsupported certificate key type")
+	}
+	return nil
+}
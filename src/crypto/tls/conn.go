@@ -81,6 +81,10 @@ type Conn struct {
 	clientProtocol         string
 	clientProtocolFallback bool
 
+	// resumptionSecret is the TLS 1.3 resumption_master_secret, from which
+	// the PSKs of the session tickets sent after the handshake are derived.
+	resumptionSecret []byte
+
 	// input/output
 	in, out   halfConn     // in.Mutex < out.Mutex
 	rawInput  *block       // raw input, right off the wire
@@ -156,6 +160,8 @@ type halfConn struct {
 	nextCipher interface{} // next encryption state
 	nextMac    macFunction // next MAC algorithm
 
+	trafficSecret []byte // current TLS 1.3 traffic secret
+
 	// used to save allocating a new buffer for each MAC.
 	inDigestBuf, outDigestBuf []byte
 }
@@ -189,6 +195,19 @@ func (hc *halfConn) changeCipherSpec() error {
 	return nil
 }
 
+// setTrafficSecret sets the TLS 1.3 traffic secret and the keys derived from
+// it, taking effect immediately.
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, secret []byte) {
+	hc.trafficSecret = secret
+	key, iv := suite.trafficKey(secret)
+	hc.version = VersionTLS13
+	hc.cipher = suite.aead(key, iv)
+	hc.mac = nil
+	for i := range hc.seq {
+		hc.seq[i] = 0
+	}
+}
+
 // incSeq increments the sequence number.
 func (hc *halfConn) incSeq() {
 	for i := 7; i >= 0; i-- {
@@ -302,16 +321,41 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 				nonce = hc.seq[:]
 			}
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			n := len(payload) - c.Overhead()
-			hc.additionalData[11] = byte(n >> 8)
-			hc.additionalData[12] = byte(n)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				// In TLS 1.3 the additional data is the record header.
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				n := len(payload) - c.Overhead()
+				hc.additionalData[11] = byte(n >> 8)
+				hc.additionalData[12] = byte(n)
+				additionalData = hc.additionalData[:]
+			}
 			var err error
-			payload, err = c.Open(payload[:0], nonce, payload, hc.additionalData[:])
+			payload, err = c.Open(payload[:0], nonce, payload, additionalData)
 			if err != nil {
 				return false, 0, alertBadRecordMAC
 			}
+			if hc.version == VersionTLS13 {
+				// The real content type is the last non-zero byte of the
+				// inner plaintext, followed by optional zero padding. It
+				// is stored in the record header, where readRecord
+				// expects it. See RFC 8446, Section 5.4.
+				if recordType(b.data[0]) != recordTypeApplicationData {
+					return false, 0, alertUnexpectedMessage
+				}
+				i := len(payload) - 1
+				for i >= 0 && payload[i] == 0 {
+					i--
+				}
+				if i < 0 {
+					return false, 0, alertUnexpectedMessage
+				}
+				b.data[0] = payload[i]
+				payload = payload[:i]
+			}
 			b.resize(recordHeaderLen + explicitIVLen + len(payload))
 		case cbcMode:
 			blockSize := c.BlockSize()
@@ -408,6 +452,13 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 		case cipher.Stream:
 			c.XORKeyStream(payload, payload)
 		case aead:
+			if hc.version == VersionTLS13 {
+				// Append the real content type to the plaintext and send
+				// the record as application_data. See RFC 8446, Section 5.2.
+				b.resize(len(b.data) + 1)
+				b.data[len(b.data)-1] = b.data[0]
+				b.data[0] = byte(recordTypeApplicationData)
+			}
 			payloadLen := len(b.data) - recordHeaderLen - explicitIVLen
 			b.resize(len(b.data) + c.Overhead())
 			nonce := b.data[recordHeaderLen : recordHeaderLen+explicitIVLen]
@@ -417,12 +468,23 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 			payload := b.data[recordHeaderLen+explicitIVLen:]
 			payload = payload[:payloadLen]
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			hc.additionalData[11] = byte(payloadLen >> 8)
-			hc.additionalData[12] = byte(payloadLen)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				// In TLS 1.3 the additional data is the record header,
+				// which already carries the final length.
+				n := payloadLen + c.Overhead()
+				b.data[3] = byte(n >> 8)
+				b.data[4] = byte(n)
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				hc.additionalData[11] = byte(payloadLen >> 8)
+				hc.additionalData[12] = byte(payloadLen)
+				additionalData = hc.additionalData[:]
+			}
 
-			c.Seal(payload[:0], nonce, payload, hc.additionalData[:])
+			c.Seal(payload[:0], nonce, payload, additionalData)
 		case cbcMode:
 			blockSize := c.BlockSize()
 			if explicitIVLen > 0 {
@@ -574,7 +636,8 @@ func (c *Conn) readRecord(want recordType) error {
 		c.sendAlert(alertInternalError)
 		return c.in.setErrorLocked(errors.New("tls: unknown record type requested"))
 	case recordTypeHandshake, recordTypeChangeCipherSpec:
-		if c.handshakeComplete {
+		// TLS 1.3 post-handshake messages may span several records.
+		if c.handshakeComplete && !(want == recordTypeHandshake && c.vers == VersionTLS13) {
 			c.sendAlert(alertInternalError)
 			return c.in.setErrorLocked(errors.New("tls: handshake or ChangeCipherSpec requested while not in handshake"))
 		}
@@ -617,7 +680,7 @@ Again:
 
 	vers := uint16(b.data[1])<<8 | uint16(b.data[2])
 	n := int(b.data[3])<<8 | int(b.data[4])
-	if c.haveVers && vers != c.vers {
+	if c.haveVers && c.vers != VersionTLS13 && vers != c.vers {
 		c.sendAlert(alertProtocolVersion)
 		msg := fmt.Sprintf("received record with version %x when expecting version %x", vers, c.vers)
 		return c.in.setErrorLocked(c.newRecordHeaderError(msg))
@@ -649,11 +712,27 @@ Again:
 
 	// Process message.
 	b, c.rawInput = c.in.splitBlock(b, recordHeaderLen+n)
+
+	// In TLS 1.3, a peer in middlebox compatibility mode may send an
+	// unencrypted ChangeCipherSpec record during the handshake, which is
+	// ignored. See RFC 8446, Appendix D.4.
+	if c.vers == VersionTLS13 && typ == recordTypeChangeCipherSpec {
+		ok := !c.handshakeComplete && n == 1 && b.data[recordHeaderLen] == 1
+		c.in.freeBlock(b)
+		if !ok {
+			return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+		}
+		goto Again
+	}
+
 	ok, off, alertValue := c.in.decrypt(b)
 	if !ok {
 		c.in.freeBlock(b)
 		return c.in.setErrorLocked(c.sendAlert(alertValue))
 	}
+	// In TLS 1.3, decrypt replaces the outer record type with the real
+	// content type.
+	typ = recordType(b.data[0])
 	b.off = off
 	data := b.data[b.off:]
 	if len(data) > maxPlaintext {
@@ -723,7 +802,10 @@ Again:
 
 	case recordTypeHandshake:
 		// TODO(rsc): Should at least pick off connection close.
-		if typ != want && !(c.isClient && c.config.Renegotiation != RenegotiateNever) {
+		// Handshake messages received after the handshake are either
+		// renegotiation requests, which a client may accept, or TLS 1.3
+		// post-handshake messages.
+		if typ != want && c.vers != VersionTLS13 && !(c.isClient && c.config.Renegotiation != RenegotiateNever) {
 			return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
 		}
 		c.hand.Write(data)
@@ -817,6 +899,9 @@ func (c *Conn) maxPayloadSizeForWrite(typ recordType, explicitIVLen int) int {
 			payloadBytes -= macSize
 		case cipher.AEAD:
 			payloadBytes -= ciph.Overhead()
+			if c.out.version == VersionTLS13 {
+				payloadBytes-- // encrypted content type
+			}
 		case cbcMode:
 			blockSize := ciph.BlockSize()
 			// The payload must fit in a multiple of blockSize, with
@@ -911,6 +996,9 @@ func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
 			// Some TLS servers fail if the record version is
 			// greater than TLS 1.0 for the initial ClientHello.
 			vers = VersionTLS10
+		} else if vers == VersionTLS13 {
+			// TLS 1.3 records carry the TLS 1.2 version number.
+			vers = VersionTLS12
 		}
 		b.data[1] = byte(vers >> 8)
 		b.data[2] = byte(vers)
@@ -991,12 +1079,26 @@ func (c *Conn) readHandshake() (interface{}, error) {
 	case typeServerHello:
 		m = new(serverHelloMsg)
 	case typeNewSessionTicket:
-		m = new(newSessionTicketMsg)
+		if c.vers == VersionTLS13 {
+			m = new(newSessionTicketMsgTLS13)
+		} else {
+			m = new(newSessionTicketMsg)
+		}
+	case typeEncryptedExtensions:
+		m = new(encryptedExtensionsMsg)
 	case typeCertificate:
-		m = new(certificateMsg)
+		if c.vers == VersionTLS13 {
+			m = new(certificateMsgTLS13)
+		} else {
+			m = new(certificateMsg)
+		}
 	case typeCertificateRequest:
-		m = &certificateRequestMsg{
-			hasSignatureAndHash: c.vers >= VersionTLS12,
+		if c.vers == VersionTLS13 {
+			m = new(certificateRequestMsgTLS13)
+		} else {
+			m = &certificateRequestMsg{
+				hasSignatureAndHash: c.vers >= VersionTLS12,
+			}
 		}
 	case typeCertificateStatus:
 		m = new(certificateStatusMsg)
@@ -1014,6 +1116,8 @@ func (c *Conn) readHandshake() (interface{}, error) {
 		m = new(nextProtoMsg)
 	case typeFinished:
 		m = new(finishedMsg)
+	case typeKeyUpdate:
+		m = new(keyUpdateMsg)
 	default:
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
@@ -1133,6 +1237,67 @@ func (c *Conn) handleRenegotiation() error {
 	return c.handshakeErr
 }
 
+// handlePostHandshakeMessage processes a handshake message received after
+// the handshake is complete. Up to TLS 1.2 it indicates the start of a
+// renegotiation; in TLS 1.3 it is a NewSessionTicket or KeyUpdate message.
+// c.in.Mutex <= L
+func (c *Conn) handlePostHandshakeMessage() error {
+	if c.vers != VersionTLS13 {
+		return c.handleRenegotiation()
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	switch msg := msg.(type) {
+	case *newSessionTicketMsgTLS13:
+		return c.handleNewSessionTicket(msg)
+	case *keyUpdateMsg:
+		return c.handleKeyUpdate(msg)
+	default:
+		c.sendAlert(alertUnexpectedMessage)
+		return fmt.Errorf("tls: received unexpected handshake message of type %T", msg)
+	}
+}
+
+// handleKeyUpdate processes a TLS 1.3 KeyUpdate message, updating the read
+// traffic secret and, if requested by the peer, the write one.
+// c.in.Mutex <= L
+func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
+	suite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if suite == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+
+	// A KeyUpdate must be the last message of its record, since the
+	// following records are protected with the new keys.
+	if c.hand.Len() > 0 {
+		return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+	}
+
+	newSecret := suite.nextTrafficSecret(c.in.trafficSecret)
+	c.in.setTrafficSecret(suite, newSecret)
+
+	if keyUpdate.updateRequested {
+		c.out.Lock()
+		defer c.out.Unlock()
+
+		msg := &keyUpdateMsg{}
+		if _, err := c.writeRecordLocked(recordTypeHandshake, msg.marshal()); err != nil {
+			// Surface the error at the next write.
+			c.out.setErrorLocked(err)
+			return nil
+		}
+
+		newSecret := suite.nextTrafficSecret(c.out.trafficSecret)
+		c.out.setTrafficSecret(suite, newSecret)
+	}
+
+	return nil
+}
+
 // Read can be made to time out and return a net.Error with Timeout() == true
 // after a fixed time limit; see SetDeadline and SetReadDeadline.
 func (c *Conn) Read(b []byte) (n int, err error) {
@@ -1157,10 +1322,11 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 				// Soft error, like EAGAIN
 				return 0, err
 			}
-			if c.hand.Len() > 0 {
+			for c.hand.Len() > 0 {
 				// We received handshake bytes, indicating the
-				// start of a renegotiation.
-				if err := c.handleRenegotiation(); err != nil {
+				// start of a renegotiation or a TLS 1.3
+				// post-handshake message.
+				if err := c.handlePostHandshakeMessage(); err != nil {
 					return 0, err
 				}
 			}
@@ -1369,7 +1535,7 @@ func (c *Conn) ConnectionState() ConnectionState {
 		state.VerifiedChains = c.verifiedChains
 		state.SignedCertificateTimestamps = c.scts
 		state.OCSPResponse = c.ocspResponse
-		if !c.didResume {
+		if !c.didResume && c.vers != VersionTLS13 {
 			if c.clientFinishedIsFirst {
 				state.TLSUnique = c.clientFinished[:]
 			} else {
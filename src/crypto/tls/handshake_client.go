@@ -29,26 +29,34 @@ type clientHandshakeState struct {
 	session      *ClientSessionState
 }
 
-func makeClientHello(config *Config) (*clientHelloMsg, error) {
+func (c *Conn) makeClientHello() (*clientHelloMsg, ecdheParameters, error) {
+	config := c.config
 	if len(config.ServerName) == 0 && !config.InsecureSkipVerify {
-		return nil, errors.New("tls: either ServerName or InsecureSkipVerify must be specified in the tls.Config")
+		return nil, nil, errors.New("tls: either ServerName or InsecureSkipVerify must be specified in the tls.Config")
 	}
 
 	nextProtosLength := 0
 	for _, proto := range config.NextProtos {
 		if l := len(proto); l == 0 || l > 255 {
-			return nil, errors.New("tls: invalid NextProtos value")
+			return nil, nil, errors.New("tls: invalid NextProtos value")
 		} else {
 			nextProtosLength += 1 + l
 		}
 	}
 
 	if nextProtosLength > 0xffff {
-		return nil, errors.New("tls: NextProtos values too large")
+		return nil, nil, errors.New("tls: NextProtos values too large")
+	}
+
+	maxVersion := config.maxVersion()
+	if c.handshakes > 0 && maxVersion > VersionTLS12 {
+		// Renegotiation only exists up to TLS 1.2, so it never offers
+		// TLS 1.3.
+		maxVersion = VersionTLS12
 	}
 
 	hello := &clientHelloMsg{
-		vers:                         config.maxVersion(),
+		vers:                         maxVersion,
 		compressionMethods:           []uint8{compressionNone},
 		random:                       make([]byte, 32),
 		ocspStapling:                 true,
@@ -81,14 +89,38 @@ NextCipherSuite:
 
 	_, err := io.ReadFull(config.rand(), hello.random)
 	if err != nil {
-		return nil, errors.New("tls: short read from Rand: " + err.Error())
+		return nil, nil, errors.New("tls: short read from Rand: " + err.Error())
 	}
 
 	if hello.vers >= VersionTLS12 {
 		hello.supportedSignatureAlgorithms = supportedSignatureAlgorithms
 	}
 
-	return hello, nil
+	var params ecdheParameters
+	if hello.vers >= VersionTLS13 {
+		// TLS 1.3 is negotiated through the supported_versions extension,
+		// while the legacy version field stays at TLS 1.2.
+		hello.vers = VersionTLS12
+		hello.supportedVersions = config.supportedVersions()
+		hello.cipherSuites = append(append([]uint16(nil), defaultCipherSuitesTLS13()...), hello.cipherSuites...)
+		// TLS 1.3 requires RSASSA-PSS. It is advertised last, since TLS 1.2
+		// only supports PKCS #1 v1.5 signatures.
+		hello.supportedSignatureAlgorithms = append(append([]SignatureScheme(nil), supportedSignatureAlgorithms...),
+			PSSWithSHA256, PSSWithSHA384, PSSWithSHA512)
+
+		curveID := config.curvePreferences()[0]
+		if _, ok := curveForCurveID(curveID); curveID != X25519 && !ok {
+			return nil, nil, errors.New("tls: CurvePreferences includes unsupported curve")
+		}
+		params, err = generateECDHEParameters(config.rand(), curveID)
+		if err != nil {
+			return nil, nil, err
+		}
+		hello.keyShares = []keyShare{{group: curveID, data: params.PublicKey()}}
+		hello.pskModes = []uint8{pskModeDHE}
+	}
+
+	return hello, params, nil
 }
 
 // c.out.Mutex <= L; c.handshakeMutex <= L.
@@ -101,7 +133,7 @@ func (c *Conn) clientHandshake() error {
 	// need to be reset.
 	c.didResume = false
 
-	hello, err := makeClientHello(c.config)
+	hello, ecdheParams, err := c.makeClientHello()
 	if err != nil {
 		return err
 	}
@@ -142,13 +174,21 @@ func (c *Conn) clientHandshake() error {
 
 			versOk := candidateSession.vers >= c.config.minVersion() &&
 				candidateSession.vers <= c.config.maxVersion()
-			if versOk && cipherSuiteOk {
+			// TLS 1.3 tickets have a lifetime set by the server.
+			expired := candidateSession.vers == VersionTLS13 &&
+				c.config.time().After(candidateSession.useBy)
+			if versOk && cipherSuiteOk && !expired {
 				session = candidateSession
 			}
 		}
 	}
 
-	if session != nil {
+	var earlySecret, binderKey []byte
+	if session != nil && session.vers == VersionTLS13 {
+		// TLS 1.3 sessions are offered as pre-shared keys, which must be
+		// the last extension of the ClientHello.
+		earlySecret, binderKey = c.offerSessionTLS13(hello, session)
+	} else if session != nil {
 		hello.sessionTicket = session.sessionTicket
 		// A random session ID is used to detect when the
 		// server accepted the ticket and is resuming a session
@@ -159,10 +199,47 @@ func (c *Conn) clientHandshake() error {
 		}
 	}
 
+	// send ClientHello
+	if _, err := c.writeRecord(recordTypeHandshake, hello.marshal()); err != nil {
+		return err
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	serverHello, ok := msg.(*serverHelloMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(serverHello, msg)
+	}
+
+	if err := c.pickTLSVersion(serverHello); err != nil {
+		return err
+	}
+
+	if c.vers == VersionTLS13 {
+		hs := &clientHandshakeStateTLS13{
+			c:           c,
+			serverHello: serverHello,
+			hello:       hello,
+			ecdheParams: ecdheParams,
+			session:     session,
+			earlySecret: earlySecret,
+			binderKey:   binderKey,
+		}
+
+		// In TLS 1.3, session tickets are delivered after the handshake
+		// and cached by handleNewSessionTicket.
+		return hs.handshake()
+	}
+
 	hs := &clientHandshakeState{
-		c:       c,
-		hello:   hello,
-		session: session,
+		c:           c,
+		serverHello: serverHello,
+		hello:       hello,
+		session:     session,
 	}
 
 	if err = hs.handshake(); err != nil {
@@ -179,31 +256,12 @@ func (c *Conn) clientHandshake() error {
 }
 
 // Does the handshake, either a full one or resumes old session.
-// Requires hs.c, hs.hello, and, optionally, hs.session to be set.
+// Requires hs.c, hs.hello, hs.serverHello, and, optionally, hs.session to
+// be set.
 func (hs *clientHandshakeState) handshake() error {
 	c := hs.c
 
-	// send ClientHello
-	if _, err := c.writeRecord(recordTypeHandshake, hs.hello.marshal()); err != nil {
-		return err
-	}
-
-	msg, err := c.readHandshake()
-	if err != nil {
-		return err
-	}
-
-	var ok bool
-	if hs.serverHello, ok = msg.(*serverHelloMsg); !ok {
-		c.sendAlert(alertUnexpectedMessage)
-		return unexpectedMessageError(hs.serverHello, msg)
-	}
-
-	if err = hs.pickTLSVersion(); err != nil {
-		return err
-	}
-
-	if err = hs.pickCipherSuite(); err != nil {
+	if err := hs.pickCipherSuite(); err != nil {
 		return err
 	}
 
@@ -271,16 +329,46 @@ func (hs *clientHandshakeState) handshake() error {
 	return nil
 }
 
-func (hs *clientHandshakeState) pickTLSVersion() error {
-	vers, ok := hs.c.config.mutualVersion(hs.serverHello.vers)
+func (c *Conn) pickTLSVersion(serverHello *serverHelloMsg) error {
+	if serverHello.supportedVersion != 0 {
+		// The supported_versions extension may only select TLS 1.3, and
+		// only if the client offered it.
+		if serverHello.supportedVersion != VersionTLS13 || c.config.maxVersion() < VersionTLS13 || c.handshakes > 0 {
+			c.sendAlert(alertIllegalParameter)
+			return fmt.Errorf("tls: server selected unsupported protocol version %x", serverHello.supportedVersion)
+		}
+		c.vers = VersionTLS13
+		c.haveVers = true
+		return nil
+	}
+
+	peerVersion := serverHello.vers
+	if peerVersion > VersionTLS12 {
+		// Without supported_versions, the highest version a server can
+		// select is TLS 1.2.
+		peerVersion = VersionTLS12
+	}
+	vers, ok := c.config.mutualVersion(peerVersion)
 	if !ok || vers < VersionTLS10 {
 		// TLS 1.0 is the minimum version supported as a client.
-		hs.c.sendAlert(alertProtocolVersion)
-		return fmt.Errorf("tls: server selected unsupported protocol version %x", hs.serverHello.vers)
+		c.sendAlert(alertProtocolVersion)
+		return fmt.Errorf("tls: server selected unsupported protocol version %x", serverHello.vers)
 	}
 
-	hs.c.vers = vers
-	hs.c.haveVers = true
+	// A server that supports TLS 1.3 signals in its random value that it
+	// negotiated a lower version, so that a downgrade by an attacker can
+	// be detected. See RFC 8446, Section 4.1.3.
+	if c.config.maxVersion() >= VersionTLS13 && c.handshakes == 0 && len(serverHello.random) == 32 {
+		canary := string(serverHello.random[24:])
+		if (vers == VersionTLS12 && canary == downgradeCanaryTLS12) ||
+			(vers <= VersionTLS11 && canary == downgradeCanaryTLS11) {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: downgrade attempt detected, possibly due to a MitM attack or a broken middlebox")
+		}
+	}
+
+	c.vers = vers
+	c.haveVers = true
 
 	return nil
 }
@@ -312,53 +400,9 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	if c.handshakes == 0 {
 		// If this is the first handshake on a connection, process and
 		// (optionally) verify the server's certificates.
-		certs := make([]*x509.Certificate, len(certMsg.certificates))
-		for i, asn1Data := range certMsg.certificates {
-			cert, err := x509.ParseCertificate(asn1Data)
-			if err != nil {
-				c.sendAlert(alertBadCertificate)
-				return errors.New("tls: failed to parse certificate from server: " + err.Error())
-			}
-			certs[i] = cert
-		}
-
-		if !c.config.InsecureSkipVerify {
-			opts := x509.VerifyOptions{
-				Roots:         c.config.RootCAs,
-				CurrentTime:   c.config.time(),
-				DNSName:       c.config.ServerName,
-				Intermediates: x509.NewCertPool(),
-			}
-
-			for i, cert := range certs {
-				if i == 0 {
-					continue
-				}
-				opts.Intermediates.AddCert(cert)
-			}
-			c.verifiedChains, err = certs[0].Verify(opts)
-			if err != nil {
-				c.sendAlert(alertBadCertificate)
-				return err
-			}
-		}
-
-		if c.config.VerifyPeerCertificate != nil {
-			if err := c.config.VerifyPeerCertificate(certMsg.certificates, c.verifiedChains); err != nil {
-				c.sendAlert(alertBadCertificate)
-				return err
-			}
-		}
-
-		switch certs[0].PublicKey.(type) {
-		case *rsa.PublicKey, *ecdsa.PublicKey:
-			break
-		default:
-			c.sendAlert(alertUnsupportedCertificate)
-			return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
+		if err := c.verifyServerCertificate(certMsg.certificates); err != nil {
+			return err
 		}
-
-		c.peerCertificates = certs
 	} else {
 		// This is a renegotiation handshake. We require that the
 		// server's identity (i.e. leaf certificate) is unchanged and
@@ -418,7 +462,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 		certRequested = true
 		hs.finishedHash.Write(certReq.marshal())
 
-		if chainToSend, err = hs.getCertificate(certReq); err != nil {
+		if chainToSend, err = c.getClientCertificate(certReq); err != nil {
 			c.sendAlert(alertInternalError)
 			return err
 		}
@@ -508,7 +552,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	}
 
 	hs.masterSecret = masterFromPreMasterSecret(c.vers, hs.suite, preMasterSecret, hs.hello.random, hs.serverHello.random)
-	if err := c.config.writeKeyLog(hs.hello.random, hs.masterSecret); err != nil {
+	if err := c.config.writeKeyLog(keyLogLabelTLS12, hs.hello.random, hs.masterSecret); err != nil {
 		c.sendAlert(alertInternalError)
 		return errors.New("tls: failed to write to key log: " + err.Error())
 	}
@@ -518,6 +562,61 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	return nil
 }
 
+// verifyServerCertificate parses and, unless InsecureSkipVerify is set,
+// verifies the certificate chain sent by the server, then stores it in
+// c.peerCertificates.
+func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
+	certs := make([]*x509.Certificate, len(certificates))
+	for i, asn1Data := range certificates {
+		cert, err := x509.ParseCertificate(asn1Data)
+		if err != nil {
+			c.sendAlert(alertBadCertificate)
+			return errors.New("tls: failed to parse certificate from server: " + err.Error())
+		}
+		certs[i] = cert
+	}
+
+	if !c.config.InsecureSkipVerify {
+		opts := x509.VerifyOptions{
+			Roots:         c.config.RootCAs,
+			CurrentTime:   c.config.time(),
+			DNSName:       c.config.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+
+		for i, cert := range certs {
+			if i == 0 {
+				continue
+			}
+			opts.Intermediates.AddCert(cert)
+		}
+		var err error
+		c.verifiedChains, err = certs[0].Verify(opts)
+		if err != nil {
+			c.sendAlert(alertBadCertificate)
+			return err
+		}
+	}
+
+	if c.config.VerifyPeerCertificate != nil {
+		if err := c.config.VerifyPeerCertificate(certificates, c.verifiedChains); err != nil {
+			c.sendAlert(alertBadCertificate)
+			return err
+		}
+	}
+
+	switch certs[0].PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		break
+	default:
+		c.sendAlert(alertUnsupportedCertificate)
+		return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
+	}
+
+	c.peerCertificates = certs
+	return nil
+}
+
 func (hs *clientHandshakeState) establishKeys() error {
 	c := hs.c
 
@@ -720,9 +819,7 @@ const (
 	tls11SignatureSchemesNumRSA = 4
 )
 
-func (hs *clientHandshakeState) getCertificate(certReq *certificateRequestMsg) (*Certificate, error) {
-	c := hs.c
-
+func (c *Conn) getClientCertificate(certReq *certificateRequestMsg) (*Certificate, error) {
 	var rsaAvail, ecdsaAvail bool
 	for _, certType := range certReq.certificateTypes {
 		switch certType {
@@ -7,6 +7,8 @@ package tls
 import (
 	"bytes"
 	"strings"
+
+	"golang_org/x/crypto/cryptobyte"
 )
 
 type clientHelloMsg struct {
@@ -28,6 +30,15 @@ type clientHelloMsg struct {
 	secureRenegotiation          []byte
 	secureRenegotiationSupported bool
 	alpnProtocols                []string
+
+	// TLS 1.3 extensions.
+	supportedSignatureAlgorithmsCert []SignatureScheme
+	supportedVersions                []uint16
+	cookie                           []byte
+	keyShares                        []keyShare
+	pskModes                         []uint8
+	pskIdentities                    []pskIdentity
+	pskBinders                       [][]byte
 }
 
 func (m *clientHelloMsg) equal(i interface{}) bool {
@@ -53,7 +64,14 @@ func (m *clientHelloMsg) equal(i interface{}) bool {
 		eqSignatureAlgorithms(m.supportedSignatureAlgorithms, m1.supportedSignatureAlgorithms) &&
 		m.secureRenegotiationSupported == m1.secureRenegotiationSupported &&
 		bytes.Equal(m.secureRenegotiation, m1.secureRenegotiation) &&
-		eqStrings(m.alpnProtocols, m1.alpnProtocols)
+		eqStrings(m.alpnProtocols, m1.alpnProtocols) &&
+		eqSignatureAlgorithms(m.supportedSignatureAlgorithmsCert, m1.supportedSignatureAlgorithmsCert) &&
+		eqUint16s(m.supportedVersions, m1.supportedVersions) &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		eqKeyShares(m.keyShares, m1.keyShares) &&
+		bytes.Equal(m.pskModes, m1.pskModes) &&
+		eqPSKIdentities(m.pskIdentities, m1.pskIdentities) &&
+		eqByteSlices(m.pskBinders, m1.pskBinders)
 }
 
 func (m *clientHelloMsg) marshal() []byte {
@@ -109,8 +127,9 @@ func (m *clientHelloMsg) marshal() []byte {
 	if m.scts {
 		numExtensions++
 	}
-	if numExtensions > 0 {
-		extensionsLength += 4 * numExtensions
+	tls13Extensions := m.marshalTLS13Extensions()
+	if numExtensions > 0 || len(tls13Extensions) > 0 {
+		extensionsLength += 4*numExtensions + len(tls13Extensions)
 		length += 2 + extensionsLength
 	}
 
@@ -136,7 +155,7 @@ func (m *clientHelloMsg) marshal() []byte {
 	copy(z[1:], m.compressionMethods)
 
 	z = z[1+len(m.compressionMethods):]
-	if numExtensions > 0 {
+	if numExtensions > 0 || len(tls13Extensions) > 0 {
 		z[0] = byte(extensionsLength >> 8)
 		z[1] = byte(extensionsLength)
 		z = z[2:]
@@ -291,12 +310,126 @@ func (m *clientHelloMsg) marshal() []byte {
 		// zero uint16 for the zero-length extension_data
 		z = z[4:]
 	}
+	copy(z, tls13Extensions)
 
 	m.raw = x
 
 	return x
 }
 
+// marshalTLS13Extensions returns the TLS 1.3 extensions of m, including
+// their headers. They are marshaled last, so that pre_shared_key is the
+// final extension, as required by RFC 8446, Section 4.2.11.
+func (m *clientHelloMsg) marshalTLS13Extensions() []byte {
+	var b cryptobyte.Builder
+	if len(m.supportedSignatureAlgorithmsCert) > 0 {
+		// RFC 8446, Section 4.2.3
+		b.AddUint16(extensionSignatureAlgorithmsCert)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, sigAlgo := range m.supportedSignatureAlgorithmsCert {
+					b.AddUint16(uint16(sigAlgo))
+				}
+			})
+		})
+	}
+	if len(m.supportedVersions) > 0 {
+		// RFC 8446, Section 4.2.1
+		b.AddUint16(extensionSupportedVersions)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, vers := range m.supportedVersions {
+					b.AddUint16(vers)
+				}
+			})
+		})
+	}
+	if len(m.cookie) > 0 {
+		// RFC 8446, Section 4.2.2
+		b.AddUint16(extensionCookie)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(m.cookie)
+			})
+		})
+	}
+	if len(m.keyShares) > 0 {
+		// RFC 8446, Section 4.2.8
+		b.AddUint16(extensionKeyShare)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, ks := range m.keyShares {
+					b.AddUint16(uint16(ks.group))
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(ks.data)
+					})
+				}
+			})
+		})
+	}
+	if len(m.pskModes) > 0 {
+		// RFC 8446, Section 4.2.9
+		b.AddUint16(extensionPSKModes)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(m.pskModes)
+			})
+		})
+	}
+	if len(m.pskIdentities) > 0 {
+		// RFC 8446, Section 4.2.11
+		b.AddUint16(extensionPreSharedKey)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, psk := range m.pskIdentities {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(psk.label)
+					})
+					b.AddUint32(psk.obfuscatedTicketAge)
+				}
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, binder := range m.pskBinders {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(binder)
+					})
+				}
+			})
+		})
+	}
+	return b.BytesOrPanic()
+}
+
+// marshalWithoutBinders returns the ClientHello through the end of the
+// pre_shared_key identities, which is the part covered by the PSK binders.
+// See RFC 8446, Section 4.2.11.2.
+func (m *clientHelloMsg) marshalWithoutBinders() []byte {
+	bindersLen := 2 // uint16 length prefix
+	for _, binder := range m.pskBinders {
+		bindersLen += 1 // uint8 length prefix
+		bindersLen += len(binder)
+	}
+
+	fullMessage := m.marshal()
+	return fullMessage[:len(fullMessage)-bindersLen]
+}
+
+// updateBinders replaces the PSK binders of m, which must have the same
+// lengths as the current ones, and re-marshals the message.
+func (m *clientHelloMsg) updateBinders(pskBinders [][]byte) {
+	if len(pskBinders) != len(m.pskBinders) {
+		panic("tls: internal error: pskBinders length mismatch")
+	}
+	for i := range m.pskBinders {
+		if len(pskBinders[i]) != len(m.pskBinders[i]) {
+			panic("tls: internal error: pskBinders length mismatch")
+		}
+	}
+	m.pskBinders = pskBinders
+	m.raw = nil
+	m.marshal()
+}
+
 func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	if len(data) < 42 {
 		return false
@@ -347,6 +480,13 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	m.supportedSignatureAlgorithms = nil
 	m.alpnProtocols = nil
 	m.scts = false
+	m.supportedSignatureAlgorithmsCert = nil
+	m.supportedVersions = nil
+	m.cookie = nil
+	m.keyShares = nil
+	m.pskModes = nil
+	m.pskIdentities = nil
+	m.pskBinders = nil
 
 	if len(data) == 0 {
 		// ClientHello is optionally followed by extension data
@@ -496,6 +636,91 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 			if length != 0 {
 				return false
 			}
+		case extensionSignatureAlgorithmsCert:
+			// RFC 8446, Section 4.2.3
+			d := cryptobyte.String(data[:length])
+			var sigAndAlgs cryptobyte.String
+			if !d.ReadUint16LengthPrefixed(&sigAndAlgs) || sigAndAlgs.Empty() || !d.Empty() {
+				return false
+			}
+			for !sigAndAlgs.Empty() {
+				var sigAndAlg uint16
+				if !sigAndAlgs.ReadUint16(&sigAndAlg) {
+					return false
+				}
+				m.supportedSignatureAlgorithmsCert = append(m.supportedSignatureAlgorithmsCert, SignatureScheme(sigAndAlg))
+			}
+		case extensionSupportedVersions:
+			// RFC 8446, Section 4.2.1
+			d := cryptobyte.String(data[:length])
+			var versList cryptobyte.String
+			if !d.ReadUint8LengthPrefixed(&versList) || versList.Empty() || !d.Empty() {
+				return false
+			}
+			for !versList.Empty() {
+				var vers uint16
+				if !versList.ReadUint16(&vers) {
+					return false
+				}
+				m.supportedVersions = append(m.supportedVersions, vers)
+			}
+		case extensionCookie:
+			// RFC 8446, Section 4.2.2
+			d := cryptobyte.String(data[:length])
+			if !readUint16LengthPrefixed(&d, &m.cookie) || len(m.cookie) == 0 || !d.Empty() {
+				return false
+			}
+		case extensionKeyShare:
+			// RFC 8446, Section 4.2.8
+			d := cryptobyte.String(data[:length])
+			var clientShares cryptobyte.String
+			if !d.ReadUint16LengthPrefixed(&clientShares) || !d.Empty() {
+				return false
+			}
+			for !clientShares.Empty() {
+				var ks keyShare
+				if !clientShares.ReadUint16((*uint16)(&ks.group)) ||
+					!readUint16LengthPrefixed(&clientShares, &ks.data) ||
+					len(ks.data) == 0 {
+					return false
+				}
+				m.keyShares = append(m.keyShares, ks)
+			}
+		case extensionPSKModes:
+			// RFC 8446, Section 4.2.9
+			d := cryptobyte.String(data[:length])
+			if !readUint8LengthPrefixed(&d, &m.pskModes) || !d.Empty() {
+				return false
+			}
+		case extensionPreSharedKey:
+			// RFC 8446, Section 4.2.11
+			if len(data) != length {
+				return false // pre_shared_key must be the last extension
+			}
+			d := cryptobyte.String(data[:length])
+			var identities, binders cryptobyte.String
+			if !d.ReadUint16LengthPrefixed(&identities) || identities.Empty() {
+				return false
+			}
+			for !identities.Empty() {
+				var psk pskIdentity
+				if !readUint16LengthPrefixed(&identities, &psk.label) ||
+					!identities.ReadUint32(&psk.obfuscatedTicketAge) ||
+					len(psk.label) == 0 {
+					return false
+				}
+				m.pskIdentities = append(m.pskIdentities, psk)
+			}
+			if !d.ReadUint16LengthPrefixed(&binders) || binders.Empty() || !d.Empty() {
+				return false
+			}
+			for !binders.Empty() {
+				var binder []byte
+				if !readUint8LengthPrefixed(&binders, &binder) || len(binder) == 0 {
+					return false
+				}
+				m.pskBinders = append(m.pskBinders, binder)
+			}
 		}
 		data = data[length:]
 	}
@@ -518,6 +743,16 @@ type serverHelloMsg struct {
 	secureRenegotiation          []byte
 	secureRenegotiationSupported bool
 	alpnProtocol                 string
+
+	// TLS 1.3 extensions.
+	supportedVersion        uint16
+	serverShare             keyShare
+	selectedIdentityPresent bool
+	selectedIdentity        uint16
+
+	// HelloRetryRequest extensions.
+	cookie        []byte
+	selectedGroup CurveID
 }
 
 func (m *serverHelloMsg) equal(i interface{}) bool {
@@ -547,7 +782,14 @@ func (m *serverHelloMsg) equal(i interface{}) bool {
 		m.ticketSupported == m1.ticketSupported &&
 		m.secureRenegotiationSupported == m1.secureRenegotiationSupported &&
 		bytes.Equal(m.secureRenegotiation, m1.secureRenegotiation) &&
-		m.alpnProtocol == m1.alpnProtocol
+		m.alpnProtocol == m1.alpnProtocol &&
+		m.supportedVersion == m1.supportedVersion &&
+		m.serverShare.group == m1.serverShare.group &&
+		bytes.Equal(m.serverShare.data, m1.serverShare.data) &&
+		m.selectedIdentityPresent == m1.selectedIdentityPresent &&
+		m.selectedIdentity == m1.selectedIdentity &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		m.selectedGroup == m1.selectedGroup
 }
 
 func (m *serverHelloMsg) marshal() []byte {
@@ -593,9 +835,10 @@ func (m *serverHelloMsg) marshal() []byte {
 		extensionsLength += 2 + sctLen
 		numExtensions++
 	}
+	tls13Extensions := m.marshalTLS13Extensions()
 
-	if numExtensions > 0 {
-		extensionsLength += 4 * numExtensions
+	if numExtensions > 0 || len(tls13Extensions) > 0 {
+		extensionsLength += 4*numExtensions + len(tls13Extensions)
 		length += 2 + extensionsLength
 	}
 
@@ -615,7 +858,7 @@ func (m *serverHelloMsg) marshal() []byte {
 	z[2] = m.compressionMethod
 
 	z = z[3:]
-	if numExtensions > 0 {
+	if numExtensions > 0 || len(tls13Extensions) > 0 {
 		z[0] = byte(extensionsLength >> 8)
 		z[1] = byte(extensionsLength)
 		z = z[2:]
@@ -688,12 +931,55 @@ func (m *serverHelloMsg) marshal() []byte {
 			z = z[len(sct)+2:]
 		}
 	}
+	copy(z, tls13Extensions)
 
 	m.raw = x
 
 	return x
 }
 
+// marshalTLS13Extensions returns the TLS 1.3 extensions of m, including
+// their headers.
+func (m *serverHelloMsg) marshalTLS13Extensions() []byte {
+	var b cryptobyte.Builder
+	if m.supportedVersion != 0 {
+		b.AddUint16(extensionSupportedVersions)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(m.supportedVersion)
+		})
+	}
+	if m.serverShare.group != 0 {
+		b.AddUint16(extensionKeyShare)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(uint16(m.serverShare.group))
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(m.serverShare.data)
+			})
+		})
+	}
+	if m.selectedIdentityPresent {
+		b.AddUint16(extensionPreSharedKey)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(m.selectedIdentity)
+		})
+	}
+	if len(m.cookie) > 0 {
+		b.AddUint16(extensionCookie)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(m.cookie)
+			})
+		})
+	}
+	if m.selectedGroup != 0 {
+		b.AddUint16(extensionKeyShare)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(uint16(m.selectedGroup))
+		})
+	}
+	return b.BytesOrPanic()
+}
+
 func (m *serverHelloMsg) unmarshal(data []byte) bool {
 	if len(data) < 42 {
 		return false
@@ -720,6 +1006,12 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 	m.scts = nil
 	m.ticketSupported = false
 	m.alpnProtocol = ""
+	m.supportedVersion = 0
+	m.serverShare = keyShare{}
+	m.selectedIdentityPresent = false
+	m.selectedIdentity = 0
+	m.cookie = nil
+	m.selectedGroup = 0
 
 	if len(data) == 0 {
 		// ServerHello is optionally followed by extension data
@@ -827,6 +1119,37 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 				m.scts = append(m.scts, d[:sctLen])
 				d = d[sctLen:]
 			}
+		case extensionSupportedVersions:
+			d := cryptobyte.String(data[:length])
+			if !d.ReadUint16(&m.supportedVersion) || !d.Empty() {
+				return false
+			}
+		case extensionCookie:
+			d := cryptobyte.String(data[:length])
+			if !readUint16LengthPrefixed(&d, &m.cookie) || len(m.cookie) == 0 || !d.Empty() {
+				return false
+			}
+		case extensionKeyShare:
+			// This extension has different formats in ServerHello and
+			// HelloRetryRequest messages.
+			d := cryptobyte.String(data[:length])
+			if length == 2 {
+				if !d.ReadUint16((*uint16)(&m.selectedGroup)) {
+					return false
+				}
+			} else {
+				if !d.ReadUint16((*uint16)(&m.serverShare.group)) ||
+					!readUint16LengthPrefixed(&d, &m.serverShare.data) ||
+					len(m.serverShare.data) == 0 || !d.Empty() {
+					return false
+				}
+			}
+		case extensionPreSharedKey:
+			m.selectedIdentityPresent = true
+			d := cryptobyte.String(data[:length])
+			if !d.ReadUint16(&m.selectedIdentity) || !d.Empty() {
+				return false
+			}
 		}
 		data = data[length:]
 	}
@@ -1502,6 +1825,514 @@ func (*helloRequestMsg) unmarshal(data []byte) bool {
 	return len(data) == 4
 }
 
+type encryptedExtensionsMsg struct {
+	raw          []byte
+	alpnProtocol string
+}
+
+func (m *encryptedExtensionsMsg) equal(i interface{}) bool {
+	m1, ok := i.(*encryptedExtensionsMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.alpnProtocol == m1.alpnProtocol
+}
+
+func (m *encryptedExtensionsMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See RFC 8446, Section 4.3.1.
+	var b cryptobyte.Builder
+	b.AddUint8(typeEncryptedExtensions)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			if len(m.alpnProtocol) > 0 {
+				b.AddUint16(extensionALPN)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddBytes([]byte(m.alpnProtocol))
+						})
+					})
+				})
+			}
+		})
+	})
+
+	m.raw = b.BytesOrPanic()
+	return m.raw
+}
+
+func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
+	*m = encryptedExtensionsMsg{raw: data}
+	s := cryptobyte.String(data)
+
+	var extensions cryptobyte.String
+	if !s.Skip(4) || // message type and uint24 length field
+		!s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+		return false
+	}
+
+	for !extensions.Empty() {
+		var extension uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extension) ||
+			!extensions.ReadUint16LengthPrefixed(&extData) {
+			return false
+		}
+
+		switch extension {
+		case extensionALPN:
+			var protoList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&protoList) || protoList.Empty() {
+				return false
+			}
+			var proto cryptobyte.String
+			if !protoList.ReadUint8LengthPrefixed(&proto) ||
+				proto.Empty() || !protoList.Empty() {
+				return false
+			}
+			m.alpnProtocol = string(proto)
+		default:
+			// Ignore unknown extensions.
+			continue
+		}
+
+		if !extData.Empty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// certificateMsgTLS13 is the TLS 1.3 Certificate message, which carries
+// per-certificate extensions. Only the extensions of the leaf certificate
+// are kept. See RFC 8446, Section 4.4.2.
+type certificateMsgTLS13 struct {
+	raw          []byte
+	certificates [][]byte
+	ocspStaple   []byte
+	scts         [][]byte
+}
+
+func (m *certificateMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqByteSlices(m.certificates, m1.certificates) &&
+		bytes.Equal(m.ocspStaple, m1.ocspStaple) &&
+		eqByteSlices(m.scts, m1.scts)
+}
+
+func (m *certificateMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	var b cryptobyte.Builder
+	b.AddUint8(typeCertificate)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(0) // certificate_request_context
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			for i, cert := range m.certificates {
+				b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddBytes(cert)
+				})
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					if i > 0 {
+						return
+					}
+					if len(m.ocspStaple) > 0 {
+						b.AddUint16(extensionStatusRequest)
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint8(statusTypeOCSP)
+							b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+								b.AddBytes(m.ocspStaple)
+							})
+						})
+					}
+					if len(m.scts) > 0 {
+						b.AddUint16(extensionSCT)
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+								for _, sct := range m.scts {
+									b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+										b.AddBytes(sct)
+									})
+								}
+							})
+						})
+					}
+				})
+			}
+		})
+	})
+
+	m.raw = b.BytesOrPanic()
+	return m.raw
+}
+
+func (m *certificateMsgTLS13) unmarshal(data []byte) bool {
+	*m = certificateMsgTLS13{raw: data}
+	s := cryptobyte.String(data)
+
+	var context, certList cryptobyte.String
+	if !s.Skip(4) || // message type and uint24 length field
+		!s.ReadUint8LengthPrefixed(&context) || !context.Empty() ||
+		!s.ReadUint24LengthPrefixed(&certList) || !s.Empty() {
+		return false
+	}
+
+	for !certList.Empty() {
+		var cert []byte
+		var extensions cryptobyte.String
+		if !readUint24LengthPrefixed(&certList, &cert) || len(cert) == 0 ||
+			!certList.ReadUint16LengthPrefixed(&extensions) {
+			return false
+		}
+		leaf := len(m.certificates) == 0
+		m.certificates = append(m.certificates, cert)
+		for !extensions.Empty() {
+			var extension uint16
+			var extData cryptobyte.String
+			if !extensions.ReadUint16(&extension) ||
+				!extensions.ReadUint16LengthPrefixed(&extData) {
+				return false
+			}
+			if !leaf {
+				continue
+			}
+
+			switch extension {
+			case extensionStatusRequest:
+				var statusType uint8
+				if !extData.ReadUint8(&statusType) || statusType != statusTypeOCSP ||
+					!readUint24LengthPrefixed(&extData, &m.ocspStaple) ||
+					len(m.ocspStaple) == 0 {
+					return false
+				}
+			case extensionSCT:
+				var sctList cryptobyte.String
+				if !extData.ReadUint16LengthPrefixed(&sctList) || sctList.Empty() {
+					return false
+				}
+				for !sctList.Empty() {
+					var sct []byte
+					if !readUint16LengthPrefixed(&sctList, &sct) ||
+						len(sct) == 0 {
+						return false
+					}
+					m.scts = append(m.scts, sct)
+				}
+			default:
+				// Ignore unknown extensions.
+				continue
+			}
+
+			if !extData.Empty() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+type certificateRequestMsgTLS13 struct {
+	raw                              []byte
+	ocspStapling                     bool
+	scts                             bool
+	supportedSignatureAlgorithms     []SignatureScheme
+	supportedSignatureAlgorithmsCert []SignatureScheme
+	certificateAuthorities           [][]byte
+}
+
+func (m *certificateRequestMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateRequestMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.ocspStapling == m1.ocspStapling &&
+		m.scts == m1.scts &&
+		eqSignatureAlgorithms(m.supportedSignatureAlgorithms, m1.supportedSignatureAlgorithms) &&
+		eqSignatureAlgorithms(m.supportedSignatureAlgorithmsCert, m1.supportedSignatureAlgorithmsCert) &&
+		eqByteSlices(m.certificateAuthorities, m1.certificateAuthorities)
+}
+
+func (m *certificateRequestMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See RFC 8446, Section 4.3.2.
+	var b cryptobyte.Builder
+	b.AddUint8(typeCertificateRequest)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		// certificate_request_context (SHALL be zero length unless used for
+		// post-handshake authentication)
+		b.AddUint8(0)
+
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			if m.ocspStapling {
+				b.AddUint16(extensionStatusRequest)
+				b.AddUint16(0) // empty extension_data
+			}
+			if m.scts {
+				// RFC 8446, Section 4.4.2.1 makes no mention of
+				// signed_certificate_timestamp in CertificateRequest, but
+				// "Extensions in the Certificate message from the client MUST
+				// correspond to extensions in the CertificateRequest message
+				// from the server." and it appears in the table in Section 4.2.
+				b.AddUint16(extensionSCT)
+				b.AddUint16(0) // empty extension_data
+			}
+			if len(m.supportedSignatureAlgorithms) > 0 {
+				b.AddUint16(extensionSignatureAlgorithms)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						for _, sigAlgo := range m.supportedSignatureAlgorithms {
+							b.AddUint16(uint16(sigAlgo))
+						}
+					})
+				})
+			}
+			if len(m.supportedSignatureAlgorithmsCert) > 0 {
+				b.AddUint16(extensionSignatureAlgorithmsCert)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						for _, sigAlgo := range m.supportedSignatureAlgorithmsCert {
+							b.AddUint16(uint16(sigAlgo))
+						}
+					})
+				})
+			}
+			if len(m.certificateAuthorities) > 0 {
+				b.AddUint16(extensionCertificateAuthorities)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						for _, ca := range m.certificateAuthorities {
+							b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+								b.AddBytes(ca)
+							})
+						}
+					})
+				})
+			}
+		})
+	})
+
+	m.raw = b.BytesOrPanic()
+	return m.raw
+}
+
+func (m *certificateRequestMsgTLS13) unmarshal(data []byte) bool {
+	*m = certificateRequestMsgTLS13{raw: data}
+	s := cryptobyte.String(data)
+
+	var context, extensions cryptobyte.String
+	if !s.Skip(4) || // message type and uint24 length field
+		!s.ReadUint8LengthPrefixed(&context) || !context.Empty() ||
+		!s.ReadUint16LengthPrefixed(&extensions) ||
+		!s.Empty() {
+		return false
+	}
+
+	for !extensions.Empty() {
+		var extension uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extension) ||
+			!extensions.ReadUint16LengthPrefixed(&extData) {
+			return false
+		}
+
+		switch extension {
+		case extensionStatusRequest:
+			m.ocspStapling = true
+		case extensionSCT:
+			m.scts = true
+		case extensionSignatureAlgorithms, extensionSignatureAlgorithmsCert:
+			var sigAndAlgs cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&sigAndAlgs) || sigAndAlgs.Empty() {
+				return false
+			}
+			var algs []SignatureScheme
+			for !sigAndAlgs.Empty() {
+				var sigAndAlg uint16
+				if !sigAndAlgs.ReadUint16(&sigAndAlg) {
+					return false
+				}
+				algs = append(algs, SignatureScheme(sigAndAlg))
+			}
+			if extension == extensionSignatureAlgorithms {
+				m.supportedSignatureAlgorithms = algs
+			} else {
+				m.supportedSignatureAlgorithmsCert = algs
+			}
+		case extensionCertificateAuthorities:
+			var auths cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&auths) || auths.Empty() {
+				return false
+			}
+			for !auths.Empty() {
+				var ca []byte
+				if !readUint16LengthPrefixed(&auths, &ca) || len(ca) == 0 {
+					return false
+				}
+				m.certificateAuthorities = append(m.certificateAuthorities, ca)
+			}
+		default:
+			// Ignore unknown extensions.
+			continue
+		}
+
+		if !extData.Empty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+type newSessionTicketMsgTLS13 struct {
+	raw      []byte
+	lifetime uint32
+	ageAdd   uint32
+	nonce    []byte
+	label    []byte
+}
+
+func (m *newSessionTicketMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*newSessionTicketMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.lifetime == m1.lifetime &&
+		m.ageAdd == m1.ageAdd &&
+		bytes.Equal(m.nonce, m1.nonce) &&
+		bytes.Equal(m.label, m1.label)
+}
+
+func (m *newSessionTicketMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See RFC 8446, Section 4.6.1.
+	var b cryptobyte.Builder
+	b.AddUint8(typeNewSessionTicket)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint32(m.lifetime)
+		b.AddUint32(m.ageAdd)
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(m.nonce)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(m.label)
+		})
+		b.AddUint16(0) // no extensions
+	})
+
+	m.raw = b.BytesOrPanic()
+	return m.raw
+}
+
+func (m *newSessionTicketMsgTLS13) unmarshal(data []byte) bool {
+	*m = newSessionTicketMsgTLS13{raw: data}
+	s := cryptobyte.String(data)
+
+	// Extensions, such as early_data, are skipped: early data is not
+	// supported.
+	var extensions cryptobyte.String
+	if !s.Skip(4) || // message type and uint24 length field
+		!s.ReadUint32(&m.lifetime) ||
+		!s.ReadUint32(&m.ageAdd) ||
+		!readUint8LengthPrefixed(&s, &m.nonce) ||
+		!readUint16LengthPrefixed(&s, &m.label) || len(m.label) == 0 ||
+		!s.ReadUint16LengthPrefixed(&extensions) ||
+		!s.Empty() {
+		return false
+	}
+
+	return true
+}
+
+type keyUpdateMsg struct {
+	raw             []byte
+	updateRequested bool
+}
+
+func (m *keyUpdateMsg) equal(i interface{}) bool {
+	m1, ok := i.(*keyUpdateMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.updateRequested == m1.updateRequested
+}
+
+func (m *keyUpdateMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See RFC 8446, Section 4.6.3.
+	x := []byte{typeKeyUpdate, 0, 0, 1, 0}
+	if m.updateRequested {
+		x[4] = 1
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *keyUpdateMsg) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) != 5 {
+		return false
+	}
+
+	switch data[4] {
+	case 0:
+		m.updateRequested = false
+	case 1:
+		m.updateRequested = true
+	default:
+		return false
+	}
+	return true
+}
+
+// readUint8LengthPrefixed acts like s.ReadUint8LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint8LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	return s.ReadUint8LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// readUint16LengthPrefixed acts like s.ReadUint16LengthPrefixed, but targets
+// a []byte instead of a cryptobyte.String.
+func readUint16LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	return s.ReadUint16LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// readUint24LengthPrefixed acts like s.ReadUint24LengthPrefixed, but targets
+// a []byte instead of a cryptobyte.String.
+func readUint24LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	return s.ReadUint24LengthPrefixed((*cryptobyte.String)(out))
+}
+
 func eqUint16s(x, y []uint16) bool {
 	if len(x) != len(y) {
 		return false
@@ -1561,3 +2392,27 @@ func eqSignatureAlgorithms(x, y []SignatureScheme) bool {
 	}
 	return true
 }
+
+func eqKeyShares(x, y []keyShare) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].group != y[i].group || !bytes.Equal(x[i].data, y[i].data) {
+			return false
+		}
+	}
+	return true
+}
+
+func eqPSKIdentities(x, y []pskIdentity) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !bytes.Equal(x[i].label, y[i].label) || x[i].obfuscatedTicketAge != y[i].obfuscatedTicketAge {
+			return false
+		}
+	}
+	return true
+}
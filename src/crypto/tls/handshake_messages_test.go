@@ -26,6 +26,12 @@ var tests = []interface{}{
 	&nextProtoMsg{},
 	&newSessionTicketMsg{},
 	&sessionState{},
+	&encryptedExtensionsMsg{},
+	&certificateMsgTLS13{},
+	&certificateRequestMsgTLS13{},
+	&newSessionTicketMsgTLS13{},
+	&keyUpdateMsg{},
+	&sessionStateTLS13{},
 }
 
 type testMessage interface {
@@ -154,6 +160,36 @@ func (*clientHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 	if rand.Intn(10) > 5 {
 		m.scts = true
 	}
+	if rand.Intn(10) > 5 {
+		m.supportedSignatureAlgorithmsCert = supportedSignatureAlgorithmsTLS13
+	}
+	if rand.Intn(10) > 5 {
+		m.supportedVersions = make([]uint16, rand.Intn(5)+1)
+		for i := range m.supportedVersions {
+			m.supportedVersions[i] = uint16(rand.Intn(30000))
+		}
+	}
+	if rand.Intn(10) > 5 {
+		m.cookie = randomBytes(rand.Intn(500)+1, rand)
+	}
+	for i := 0; i < rand.Intn(5); i++ {
+		var ks keyShare
+		ks.group = CurveID(rand.Intn(30000))
+		ks.data = randomBytes(rand.Intn(200)+1, rand)
+		m.keyShares = append(m.keyShares, ks)
+	}
+	if rand.Intn(10) > 5 {
+		m.pskModes = randomBytes(rand.Intn(5)+1, rand)
+	}
+	if rand.Intn(10) > 5 {
+		for i := 0; i < rand.Intn(5)+1; i++ {
+			var psk pskIdentity
+			psk.obfuscatedTicketAge = uint32(rand.Intn(500000))
+			psk.label = randomBytes(rand.Intn(500)+1, rand)
+			m.pskIdentities = append(m.pskIdentities, psk)
+			m.pskBinders = append(m.pskBinders, randomBytes(rand.Intn(50)+32, rand))
+		}
+	}
 
 	return reflect.ValueOf(m)
 }
@@ -192,6 +228,23 @@ func (*serverHelloMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 		}
 	}
 
+	if rand.Intn(10) > 5 {
+		m.supportedVersion = uint16(rand.Intn(30000)) + 1
+	}
+	if rand.Intn(10) > 5 {
+		m.cookie = randomBytes(rand.Intn(500)+1, rand)
+	}
+	if rand.Intn(10) > 5 {
+		m.selectedGroup = CurveID(rand.Intn(30000)) + 1
+	} else if rand.Intn(10) > 5 {
+		m.serverShare.group = CurveID(rand.Intn(30000)) + 1
+		m.serverShare.data = randomBytes(rand.Intn(200)+1, rand)
+	}
+	if rand.Intn(10) > 5 {
+		m.selectedIdentityPresent = true
+		m.selectedIdentity = uint16(rand.Intn(0xffff))
+	}
+
 	return reflect.ValueOf(m)
 }
 
@@ -270,6 +323,75 @@ func (*sessionState) Generate(rand *rand.Rand, size int) reflect.Value {
 	return reflect.ValueOf(s)
 }
 
+func (*encryptedExtensionsMsg) Generate(rand *rand.Rand, size int) reflect.Value {
+	m := &encryptedExtensionsMsg{}
+	if rand.Intn(10) > 5 {
+		m.alpnProtocol = randomString(rand.Intn(32)+1, rand)
+	}
+	return reflect.ValueOf(m)
+}
+
+func (*certificateMsgTLS13) Generate(rand *rand.Rand, size int) reflect.Value {
+	m := &certificateMsgTLS13{}
+	numCerts := rand.Intn(20)
+	for i := 0; i < numCerts; i++ {
+		m.certificates = append(m.certificates, randomBytes(rand.Intn(10)+1, rand))
+	}
+	if numCerts > 0 && rand.Intn(10) > 5 {
+		m.ocspStaple = randomBytes(rand.Intn(100)+1, rand)
+	}
+	if numCerts > 0 && rand.Intn(10) > 5 {
+		for i := 0; i < rand.Intn(2)+1; i++ {
+			m.scts = append(m.scts, randomBytes(rand.Intn(500)+1, rand))
+		}
+	}
+	return reflect.ValueOf(m)
+}
+
+func (*certificateRequestMsgTLS13) Generate(rand *rand.Rand, size int) reflect.Value {
+	m := &certificateRequestMsgTLS13{}
+	m.ocspStapling = rand.Intn(10) > 5
+	m.scts = rand.Intn(10) > 5
+	if rand.Intn(10) > 5 {
+		m.supportedSignatureAlgorithms = supportedSignatureAlgorithmsTLS13
+	}
+	if rand.Intn(10) > 5 {
+		m.supportedSignatureAlgorithmsCert = supportedSignatureAlgorithms
+	}
+	numCAs := rand.Intn(10)
+	for i := 0; i < numCAs; i++ {
+		m.certificateAuthorities = append(m.certificateAuthorities, randomBytes(rand.Intn(15)+1, rand))
+	}
+	return reflect.ValueOf(m)
+}
+
+func (*newSessionTicketMsgTLS13) Generate(rand *rand.Rand, size int) reflect.Value {
+	m := &newSessionTicketMsgTLS13{}
+	m.lifetime = uint32(rand.Intn(500000))
+	m.ageAdd = uint32(rand.Intn(500000))
+	m.nonce = randomBytes(rand.Intn(100), rand)
+	m.label = randomBytes(rand.Intn(1000)+1, rand)
+	return reflect.ValueOf(m)
+}
+
+func (*keyUpdateMsg) Generate(rand *rand.Rand, size int) reflect.Value {
+	m := &keyUpdateMsg{}
+	m.updateRequested = rand.Intn(10) > 5
+	return reflect.ValueOf(m)
+}
+
+func (*sessionStateTLS13) Generate(rand *rand.Rand, size int) reflect.Value {
+	s := &sessionStateTLS13{}
+	s.cipherSuite = uint16(rand.Intn(10000))
+	s.createdAt = uint64(rand.Int63())
+	s.resumptionSecret = randomBytes(rand.Intn(100)+1, rand)
+	numCerts := rand.Intn(20)
+	for i := 0; i < numCerts; i++ {
+		s.certificates = append(s.certificates, randomBytes(rand.Intn(10)+1, rand))
+	}
+	return reflect.ValueOf(s)
+}
+
 func TestRejectEmptySCTList(t *testing.T) {
 	// https://tools.ietf.org/html/rfc6962#section-3.3.1 specifies that
 	// empty SCT lists are invalid.
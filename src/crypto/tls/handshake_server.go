@@ -50,6 +50,15 @@ func (c *Conn) serverHandshake() error {
 		return err
 	}
 
+	if c.vers == VersionTLS13 {
+		hs := serverHandshakeStateTLS13{
+			c:           c,
+			clientHello: hs.clientHello,
+			helloInfo:   hs.clientHelloInfo(),
+		}
+		return hs.handshake()
+	}
+
 	// For an overview of TLS handshaking, see https://tools.ietf.org/html/rfc5246#section-7.3
 	c.buffering = true
 	if isResume {
@@ -108,8 +117,9 @@ func (c *Conn) serverHandshake() error {
 	return nil
 }
 
-// readClientHello reads a ClientHello message from the client and decides
-// whether we will perform session resumption.
+// readClientHello reads a ClientHello message from the client and selects
+// the protocol version. Up to TLS 1.2, it also decides whether we will perform
+// session resumption.
 func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
 	c := hs.c
 
@@ -134,13 +144,33 @@ func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
 		}
 	}
 
-	c.vers, ok = c.config.mutualVersion(hs.clientHello.vers)
-	if !ok {
-		c.sendAlert(alertProtocolVersion)
-		return false, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", hs.clientHello.vers)
+	if len(hs.clientHello.supportedVersions) > 0 {
+		c.vers, ok = c.config.mutualVersionFromList(hs.clientHello.supportedVersions)
+		if !ok {
+			c.sendAlert(alertProtocolVersion)
+			return false, fmt.Errorf("tls: client offered only unsupported versions: %x", hs.clientHello.supportedVersions)
+		}
+	} else {
+		// Without supported_versions, the legacy version field can't
+		// negotiate TLS 1.3. See RFC 8446, Section 4.2.1.
+		clientVersion := hs.clientHello.vers
+		if clientVersion > VersionTLS12 {
+			clientVersion = VersionTLS12
+		}
+		c.vers, ok = c.config.mutualVersion(clientVersion)
+		if !ok {
+			c.sendAlert(alertProtocolVersion)
+			return false, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", hs.clientHello.vers)
+		}
 	}
 	c.haveVers = true
 
+	if c.vers == VersionTLS13 {
+		// The rest of the ClientHello is processed by
+		// serverHandshakeStateTLS13.
+		return false, nil
+	}
+
 	hs.hello = new(serverHelloMsg)
 
 	supportedCurve := false
@@ -186,6 +216,17 @@ Curves:
 		return false, err
 	}
 
+	// A server that supports TLS 1.3 signals that it negotiated a lower
+	// version in the last eight bytes of its random value, so that clients
+	// can detect downgrade attacks. See RFC 8446, Section 4.1.3.
+	if c.config.maxVersion() >= VersionTLS13 {
+		if c.vers == VersionTLS12 {
+			copy(hs.hello.random[24:], downgradeCanaryTLS12)
+		} else {
+			copy(hs.hello.random[24:], downgradeCanaryTLS11)
+		}
+	}
+
 	if len(hs.clientHello.secureRenegotiation) != 0 {
 		c.sendAlert(alertHandshakeFailure)
 		return false, errors.New("tls: initial handshake had non-empty renegotiation extension")
@@ -290,9 +331,12 @@ func (hs *serverHandshakeState) checkForResumption() bool {
 		return false
 	}
 
-	var ok bool
-	var sessionTicket = append([]uint8{}, hs.clientHello.sessionTicket...)
-	if hs.sessionState, ok = c.decryptTicket(sessionTicket); !ok {
+	plaintext, usedOldKey := c.decryptTicket(hs.clientHello.sessionTicket)
+	if plaintext == nil {
+		return false
+	}
+	hs.sessionState = &sessionState{usedOldKey: usedOldKey}
+	if !hs.sessionState.unmarshal(plaintext) {
 		return false
 	}
 
@@ -347,9 +391,10 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	}
 
 	if len(hs.sessionState.certificates) > 0 {
-		if _, err := hs.processCertsFromClient(hs.sessionState.certificates); err != nil {
+		if _, err := c.processCertsFromClient(hs.sessionState.certificates); err != nil {
 			return err
 		}
+		hs.certsFromClient = hs.sessionState.certificates
 	}
 
 	hs.masterSecret = hs.sessionState.masterSecret
@@ -471,10 +516,11 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			}
 		}
 
-		pub, err = hs.processCertsFromClient(certMsg.certificates)
+		pub, err = c.processCertsFromClient(certMsg.certificates)
 		if err != nil {
 			return err
 		}
+		hs.certsFromClient = certMsg.certificates
 
 		msg, err = c.readHandshake()
 		if err != nil {
@@ -496,7 +542,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		return err
 	}
 	hs.masterSecret = masterFromPreMasterSecret(c.vers, hs.suite, preMasterSecret, hs.clientHello.random, hs.hello.random)
-	if err := c.config.writeKeyLog(hs.clientHello.random, hs.masterSecret); err != nil {
+	if err := c.config.writeKeyLog(keyLogLabelTLS12, hs.clientHello.random, hs.masterSecret); err != nil {
 		c.sendAlert(alertInternalError)
 		return err
 	}
@@ -670,7 +716,7 @@ func (hs *serverHandshakeState) sendSessionTicket() error {
 		masterSecret: hs.masterSecret,
 		certificates: hs.certsFromClient,
 	}
-	m.ticket, err = c.encryptTicket(&state)
+	m.ticket, err = c.encryptTicket(state.marshal())
 	if err != nil {
 		return err
 	}
@@ -706,10 +752,7 @@ func (hs *serverHandshakeState) sendFinished(out []byte) error {
 // processCertsFromClient takes a chain of client certificates either from a
 // Certificates message or from a sessionState and verifies them. It returns
 // the public key of the leaf certificate.
-func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
-	c := hs.c
-
-	hs.certsFromClient = certificates
+func (c *Conn) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
 	certs := make([]*x509.Certificate, len(certificates))
 	var err error
 	for i, asn1Data := range certificates {
@@ -815,7 +858,9 @@ func (hs *serverHandshakeState) clientHelloInfo() *ClientHelloInfo {
 	}
 
 	var supportedVersions []uint16
-	if hs.clientHello.vers > VersionTLS12 {
+	if len(hs.clientHello.supportedVersions) > 0 {
+		supportedVersions = hs.clientHello.supportedVersions
+	} else if hs.clientHello.vers > VersionTLS12 {
 		supportedVersions = suppVersArray[:]
 	} else if hs.clientHello.vers >= VersionSSL30 {
 		supportedVersions = suppVersArray[VersionTLS12-hs.clientHello.vers:]
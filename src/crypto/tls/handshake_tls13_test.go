@@ -0,0 +1,389 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+// The TLS 1.3 handshake sends the server's first flight, and possibly
+// session tickets, before it reads from the client, so these tests run over
+// a TCP loopback connection rather than the synchronous net.Pipe.
+
+func testConfigTLS13() *Config {
+	config := testConfig.Clone()
+	config.Rand = nil
+	config.MinVersion = VersionTLS10
+	config.MaxVersion = VersionTLS13
+	config.CipherSuites = nil
+	return config
+}
+
+// loopbackHandshake runs a handshake between a client and a server over a
+// loopback TCP connection and exchanges one byte of application data in
+// each direction, so that the client also processes any session tickets.
+// It returns the connection states as seen by each side.
+func loopbackHandshake(t *testing.T, clientConfig, serverConfig *Config) (clientState, serverState ConnectionState, err error) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+
+	type result struct {
+		state ConnectionState
+		err   error
+	}
+	serverResult := make(chan result, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			serverResult <- result{err: err}
+			return
+		}
+		srv := Server(c, serverConfig)
+		defer srv.Close()
+		var b [1]byte
+		if _, err := io.ReadFull(srv, b[:]); err != nil {
+			serverResult <- result{err: err}
+			return
+		}
+		if _, err := srv.Write(b[:]); err != nil {
+			serverResult <- result{err: err}
+			return
+		}
+		serverResult <- result{state: srv.ConnectionState()}
+	}()
+
+	cli, err := Dial("tcp", ln.Addr().String(), clientConfig)
+	if err != nil {
+		ln.Close()
+		<-serverResult
+		return
+	}
+	defer cli.Close()
+	if _, err = cli.Write([]byte{'x'}); err != nil {
+		<-serverResult
+		return
+	}
+	var b [1]byte
+	if _, err = io.ReadFull(cli, b[:]); err != nil {
+		<-serverResult
+		return
+	}
+	clientState = cli.ConnectionState()
+
+	r := <-serverResult
+	return clientState, r.state, r.err
+}
+
+func TestTLS13Handshake(t *testing.T) {
+	clientState, serverState, err := loopbackHandshake(t, testConfigTLS13(), testConfigTLS13())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientState.Version != VersionTLS13 || serverState.Version != VersionTLS13 {
+		t.Errorf("got versions %x (client) and %x (server), want %x", clientState.Version, serverState.Version, VersionTLS13)
+	}
+	if clientState.CipherSuite != serverState.CipherSuite {
+		t.Errorf("client and server disagree on the cipher suite: %x vs %x", clientState.CipherSuite, serverState.CipherSuite)
+	}
+	if cipherSuiteTLS13ByID(clientState.CipherSuite) == nil {
+		t.Errorf("negotiated non-TLS 1.3 cipher suite %x", clientState.CipherSuite)
+	}
+	if !clientState.HandshakeComplete || !serverState.HandshakeComplete {
+		t.Error("handshake not reported as complete")
+	}
+	if len(clientState.PeerCertificates) != 1 {
+		t.Errorf("client got %d peer certificates, want 1", len(clientState.PeerCertificates))
+	}
+	if clientState.DidResume || serverState.DidResume {
+		t.Error("first connection reported as resumed")
+	}
+}
+
+func TestTLS13VersionNegotiation(t *testing.T) {
+	tests := []struct {
+		clientMin, clientMax uint16
+		serverMin, serverMax uint16
+		want                 uint16 // zero if the handshake must fail
+	}{
+		{VersionTLS10, VersionTLS13, VersionTLS10, VersionTLS13, VersionTLS13},
+		{VersionTLS10, VersionTLS13, VersionTLS10, VersionTLS12, VersionTLS12},
+		{VersionTLS10, VersionTLS12, VersionTLS10, VersionTLS13, VersionTLS12},
+		{VersionTLS10, VersionTLS13, VersionTLS10, 0, VersionTLS12},
+		{VersionTLS10, 0, VersionTLS10, VersionTLS13, VersionTLS12},
+		{VersionTLS13, VersionTLS13, VersionTLS10, VersionTLS12, 0},
+		{VersionTLS10, VersionTLS12, VersionTLS13, VersionTLS13, 0},
+		{VersionTLS13, VersionTLS13, VersionTLS13, VersionTLS13, VersionTLS13},
+	}
+	for i, test := range tests {
+		clientConfig, serverConfig := testConfigTLS13(), testConfigTLS13()
+		clientConfig.MinVersion, clientConfig.MaxVersion = test.clientMin, test.clientMax
+		serverConfig.MinVersion, serverConfig.MaxVersion = test.serverMin, test.serverMax
+
+		clientState, serverState, err := loopbackHandshake(t, clientConfig, serverConfig)
+		if test.want == 0 {
+			if err == nil {
+				t.Errorf("#%d: handshake succeeded with version %x, expected failure", i, clientState.Version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: handshake failed: %s", i, err)
+			continue
+		}
+		if clientState.Version != test.want || serverState.Version != test.want {
+			t.Errorf("#%d: got versions %x (client) and %x (server), want %x", i, clientState.Version, serverState.Version, test.want)
+		}
+	}
+}
+
+func TestTLS13CipherSuites(t *testing.T) {
+	defaults := defaultCipherSuitesTLS13()
+	defer func() { varDefaultCipherSuitesTLS13 = defaults }()
+
+	for _, suite := range cipherSuitesTLS13 {
+		varDefaultCipherSuitesTLS13 = []uint16{suite.id}
+		clientState, _, err := loopbackHandshake(t, testConfigTLS13(), testConfigTLS13())
+		if err != nil {
+			t.Errorf("%x: %s", suite.id, err)
+			continue
+		}
+		if clientState.CipherSuite != suite.id {
+			t.Errorf("%x: negotiated cipher suite %x", suite.id, clientState.CipherSuite)
+		}
+	}
+}
+
+func TestTLS13Curves(t *testing.T) {
+	for _, curve := range []CurveID{X25519, CurveP256, CurveP384, CurveP521} {
+		clientConfig, serverConfig := testConfigTLS13(), testConfigTLS13()
+		clientConfig.CurvePreferences = []CurveID{curve}
+		serverConfig.CurvePreferences = []CurveID{curve}
+		if _, _, err := loopbackHandshake(t, clientConfig, serverConfig); err != nil {
+			t.Errorf("curve %d: %s", curve, err)
+		}
+	}
+}
+
+func TestTLS13HelloRetryRequest(t *testing.T) {
+	clientConfig, serverConfig := testConfigTLS13(), testConfigTLS13()
+	// The client only sends a key share for its first preference, so the
+	// server has to ask for X25519 in a HelloRetryRequest.
+	clientConfig.CurvePreferences = []CurveID{CurveP256, X25519}
+	serverConfig.CurvePreferences = []CurveID{X25519}
+	clientState, _, err := loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientState.Version != VersionTLS13 {
+		t.Errorf("got version %x, want %x", clientState.Version, VersionTLS13)
+	}
+
+	// Without a mutually supported group, the handshake must fail.
+	clientConfig.CurvePreferences = []CurveID{CurveP256}
+	if _, _, err := loopbackHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Error("handshake succeeded without a common group")
+	}
+}
+
+func TestTLS13Resumption(t *testing.T) {
+	clientConfig, serverConfig := testConfigTLS13(), testConfigTLS13()
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(32)
+
+	clientState, _, err := loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientState.DidResume {
+		t.Fatal("first connection reported as resumed")
+	}
+
+	clientState, serverState, err := loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clientState.DidResume || !serverState.DidResume {
+		t.Fatalf("second connection not resumed: client %v, server %v", clientState.DidResume, serverState.DidResume)
+	}
+	if clientState.Version != VersionTLS13 {
+		t.Errorf("resumed with version %x, want %x", clientState.Version, VersionTLS13)
+	}
+	if len(clientState.PeerCertificates) != 1 {
+		t.Errorf("resumed connection has %d peer certificates, want 1", len(clientState.PeerCertificates))
+	}
+
+	// A server with different ticket keys falls back to a full handshake.
+	serverConfig = testConfigTLS13()
+	serverConfig.SetSessionTicketKeys([][32]byte{{1, 2, 3}})
+	clientState, _, err = loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientState.DidResume {
+		t.Error("resumed with an unknown ticket key")
+	}
+
+	// Disabling tickets on the server also prevents resumption.
+	serverConfig = testConfigTLS13()
+	serverConfig.SessionTicketsDisabled = true
+	clientState, _, err = loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientState.DidResume {
+		t.Error("resumed with session tickets disabled")
+	}
+}
+
+func TestTLS13ClientAuth(t *testing.T) {
+	clientConfig, serverConfig := testConfigTLS13(), testConfigTLS13()
+	serverConfig.ClientAuth = RequireAnyClientCert
+	clientConfig.Certificates = []Certificate{{
+		Certificate: [][]byte{testECDSACertificate},
+		PrivateKey:  testECDSAPrivateKey,
+	}}
+
+	_, serverState, err := loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serverState.PeerCertificates) != 1 {
+		t.Fatalf("server got %d peer certificates, want 1", len(serverState.PeerCertificates))
+	}
+	want, err := x509.ParseCertificate(testECDSACertificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !serverState.PeerCertificates[0].Equal(want) {
+		t.Error("server got the wrong client certificate")
+	}
+
+	clientConfig.Certificates = nil
+	if _, _, err := loopbackHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Error("handshake succeeded without a client certificate")
+	}
+}
+
+func TestTLS13ALPN(t *testing.T) {
+	clientConfig, serverConfig := testConfigTLS13(), testConfigTLS13()
+	clientConfig.NextProtos = []string{"proto1", "proto2"}
+	serverConfig.NextProtos = []string{"proto2", "proto3"}
+	clientState, serverState, err := loopbackHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientState.NegotiatedProtocol != "proto2" || serverState.NegotiatedProtocol != "proto2" {
+		t.Errorf("got protocols %q (client) and %q (server), want \"proto2\"", clientState.NegotiatedProtocol, serverState.NegotiatedProtocol)
+	}
+	if !clientState.NegotiatedProtocolIsMutual {
+		t.Error("protocol not reported as mutual")
+	}
+}
+
+func TestTLS13KeyUpdate(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+
+	const messages = 4
+	serverErr := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		srv := Server(c, testConfigTLS13())
+		defer srv.Close()
+		buf := make([]byte, 5)
+		for i := 0; i < messages; i++ {
+			if _, err := io.ReadFull(srv, buf); err != nil {
+				serverErr <- err
+				return
+			}
+			if _, err := srv.Write(buf); err != nil {
+				serverErr <- err
+				return
+			}
+		}
+		serverErr <- nil
+	}()
+
+	cli, err := Dial("tcp", ln.Addr().String(), testConfigTLS13())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	buf := make([]byte, 5)
+	for i := 0; i < messages; i++ {
+		if i > 0 {
+			// Update the client's sending keys and ask the server to
+			// update its own.
+			cli.out.Lock()
+			suite := cipherSuiteTLS13ByID(cli.cipherSuite)
+			_, err := cli.writeRecordLocked(recordTypeHandshake, (&keyUpdateMsg{updateRequested: true}).marshal())
+			if err == nil {
+				cli.out.setTrafficSecret(suite, suite.nextTrafficSecret(cli.out.trafficSecret))
+			}
+			cli.out.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		msg := []byte("ping" + string('0'+i))
+		if _, err := cli.Write(msg); err != nil {
+			t.Fatalf("write %d: %s", i, err)
+		}
+		if _, err := io.ReadFull(cli, buf); err != nil {
+			t.Fatalf("read %d: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("read %d: got %q, want %q", i, buf, msg)
+		}
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLS13DowngradeCanary(t *testing.T) {
+	tests := []struct {
+		maxVersion uint16
+		vers       uint16
+		canary     string
+		wantErr    bool
+	}{
+		{VersionTLS13, VersionTLS12, downgradeCanaryTLS12, true},
+		{VersionTLS13, VersionTLS11, downgradeCanaryTLS11, true},
+		{VersionTLS13, VersionTLS12, "", false},
+		{VersionTLS12, VersionTLS12, downgradeCanaryTLS12, false},
+		{VersionTLS12, VersionTLS11, downgradeCanaryTLS11, false},
+	}
+	for i, test := range tests {
+		random := make([]byte, 32)
+		copy(random[24:], test.canary)
+		serverHello := &serverHelloMsg{vers: test.vers, random: random}
+
+		config := testConfigTLS13()
+		config.MaxVersion = test.maxVersion
+		cConn, sConn := net.Pipe()
+		go io.Copy(ioutil.Discard, sConn)
+		c := Client(cConn, config)
+		err := c.pickTLSVersion(serverHello)
+		cConn.Close()
+		if test.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "downgrade") {
+				t.Errorf("#%d: got error %v, want a downgrade error", i, err)
+			}
+		} else if err != nil {
+			t.Errorf("#%d: unexpected error: %s", i, err)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"crypto/subtle"
 	"errors"
 	"io"
+
+	"golang_org/x/crypto/cryptobyte"
 )
 
 // sessionState contains the information that is serialized into a session
@@ -129,8 +131,77 @@ func (s *sessionState) unmarshal(data []byte) bool {
 	return len(data) == 0
 }
 
-func (c *Conn) encryptTicket(state *sessionState) ([]byte, error) {
-	serialized := state.marshal()
+// sessionStateTLS13 is the content of a TLS 1.3 session ticket. It is
+// serialized with a leading version field, like sessionState, so that the
+// two can't be confused.
+type sessionStateTLS13 struct {
+	cipherSuite      uint16
+	createdAt        uint64 // seconds since the Unix epoch
+	resumptionSecret []byte // resumption_master_secret, from which the PSK is derived
+	certificates     [][]byte
+}
+
+func (s *sessionStateTLS13) equal(i interface{}) bool {
+	s1, ok := i.(*sessionStateTLS13)
+	if !ok {
+		return false
+	}
+
+	return s.cipherSuite == s1.cipherSuite &&
+		s.createdAt == s1.createdAt &&
+		bytes.Equal(s.resumptionSecret, s1.resumptionSecret) &&
+		eqByteSlices(s.certificates, s1.certificates)
+}
+
+func (s *sessionStateTLS13) marshal() []byte {
+	var b cryptobyte.Builder
+	b.AddUint16(VersionTLS13)
+	b.AddUint16(s.cipherSuite)
+	b.AddUint32(uint32(s.createdAt >> 32))
+	b.AddUint32(uint32(s.createdAt))
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.resumptionSecret)
+	})
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, cert := range s.certificates {
+			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(cert)
+			})
+		}
+	})
+	return b.BytesOrPanic()
+}
+
+func (s *sessionStateTLS13) unmarshal(data []byte) bool {
+	*s = sessionStateTLS13{}
+	input := cryptobyte.String(data)
+	var version uint16
+	var createdAtHigh, createdAtLow uint32
+	var certList cryptobyte.String
+	if !input.ReadUint16(&version) || version != VersionTLS13 ||
+		!input.ReadUint16(&s.cipherSuite) ||
+		!input.ReadUint32(&createdAtHigh) ||
+		!input.ReadUint32(&createdAtLow) ||
+		!readUint8LengthPrefixed(&input, &s.resumptionSecret) ||
+		len(s.resumptionSecret) == 0 ||
+		!input.ReadUint24LengthPrefixed(&certList) ||
+		!input.Empty() {
+		return false
+	}
+	s.createdAt = uint64(createdAtHigh)<<32 | uint64(createdAtLow)
+	for !certList.Empty() {
+		var cert []byte
+		if !readUint24LengthPrefixed(&certList, &cert) || len(cert) == 0 {
+			return false
+		}
+		s.certificates = append(s.certificates, cert)
+	}
+	return true
+}
+
+// encryptTicket encrypts and authenticates a serialized session state with
+// the current session ticket key.
+func (c *Conn) encryptTicket(serialized []byte) ([]byte, error) {
 	encrypted := make([]byte, ticketKeyNameLen+aes.BlockSize+len(serialized)+sha256.Size)
 	keyName := encrypted[:ticketKeyNameLen]
 	iv := encrypted[ticketKeyNameLen : ticketKeyNameLen+aes.BlockSize]
@@ -154,7 +225,11 @@ func (c *Conn) encryptTicket(state *sessionState) ([]byte, error) {
 	return encrypted, nil
 }
 
-func (c *Conn) decryptTicket(encrypted []byte) (*sessionState, bool) {
+// decryptTicket authenticates and decrypts a session ticket, returning the
+// serialized session state, or nil if the ticket is invalid. usedOldKey
+// reports whether the ticket was encrypted with an older key and should be
+// refreshed.
+func (c *Conn) decryptTicket(encrypted []byte) (plaintext []byte, usedOldKey bool) {
 	if c.config.SessionTicketsDisabled ||
 		len(encrypted) < ticketKeyNameLen+aes.BlockSize+sha256.Size {
 		return nil, false
@@ -191,10 +266,8 @@ func (c *Conn) decryptTicket(encrypted []byte) (*sessionState, bool) {
 		return nil, false
 	}
 	ciphertext := encrypted[ticketKeyNameLen+aes.BlockSize : len(encrypted)-sha256.Size]
-	plaintext := ciphertext
+	plaintext = make([]byte, len(ciphertext))
 	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
 
-	state := &sessionState{usedOldKey: keyIndex > 0}
-	ok := state.unmarshal(plaintext)
-	return state, ok
+	return plaintext, keyIndex > 0
 }
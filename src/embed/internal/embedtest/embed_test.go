@@ -0,0 +1,200 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embedtest
+
+import (
+	"embed"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+//go:embed testdata/h*.txt
+//go:embed c*.txt testdata/g*.txt
+var global embed.FS
+
+//go:embed c*txt
+var concurrency string
+
+//go:embed testdata/g*.txt
+var glass []byte
+
+//go:embed testdata/ascii.txt
+var ascii string
+
+//go:embed testdata
+var testDirAll embed.FS
+
+//go:embed "testdata/hello.txt" `testdata/ascii.txt`
+var quoted embed.FS
+
+var empty embed.FS
+
+func testFiles(t *testing.T, f embed.FS, name, data string) {
+	t.Helper()
+	d, err := f.ReadFile(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(d) != data {
+		t.Errorf("read %v = %q, want %q", name, d, data)
+	}
+}
+
+func testString(t *testing.T, s, name, data string) {
+	t.Helper()
+	if s != data {
+		t.Errorf("%v = %q, want %q", name, s, data)
+	}
+}
+
+func testDir(t *testing.T, f embed.FS, name string, expect ...string) {
+	t.Helper()
+	dirs, err := f.ReadDir(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var names []string
+	for _, d := range dirs {
+		name := d.Name()
+		if d.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("readdir %v = %v, want %v", name, names, expect)
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	testFiles(t, global, "concurrency.txt", "Concurrency is not parallelism.\n")
+	testFiles(t, global, "testdata/hello.txt", "hello, world\n")
+	testFiles(t, global, "testdata/glass.txt", "glass\n")
+
+	testString(t, concurrency, "concurrency", "Concurrency is not parallelism.\n")
+	testString(t, string(glass), "glass", "glass\n")
+	testString(t, ascii, "ascii", "ascii\n")
+
+	testDir(t, global, ".", "concurrency.txt", "testdata/")
+	testDir(t, global, "testdata", "glass.txt", "hello.txt")
+
+	if _, err := global.ReadFile("testdata/ascii.txt"); err == nil {
+		t.Error("ReadFile of unembedded file succeeded")
+	}
+}
+
+func TestBytesWritable(t *testing.T) {
+	// Each []byte variable has its own copy of the data,
+	// which the program may modify.
+	old := glass[0]
+	glass[0] = 'G'
+	defer func() { glass[0] = old }()
+	testString(t, string(glass), "glass", "Glass\n")
+	testFiles(t, global, "testdata/glass.txt", "glass\n")
+}
+
+func TestDir(t *testing.T) {
+	testDir(t, testDirAll, ".", "testdata/")
+	testDir(t, testDirAll, "testdata", "ascii.txt", "glass.txt", "hello.txt", "sub/")
+	testDir(t, testDirAll, "testdata/sub", "-not-hidden/", "sub.txt")
+
+	testFiles(t, testDirAll, "testdata/sub/-not-hidden/fortune.txt", "not hidden\n")
+	for _, name := range []string{"testdata/.hidden/fortune.txt", "testdata/sub/_ignored.txt"} {
+		if _, err := testDirAll.Open(name); err == nil {
+			t.Errorf("Open(%q) succeeded, want hidden file to be omitted", name)
+		}
+	}
+}
+
+func TestQuoted(t *testing.T) {
+	testFiles(t, quoted, "testdata/hello.txt", "hello, world\n")
+	testFiles(t, quoted, "testdata/ascii.txt", "ascii\n")
+}
+
+func TestEmpty(t *testing.T) {
+	testDir(t, empty, ".")
+	if _, err := empty.Open("x"); err == nil {
+		t.Error("Open on empty FS succeeded")
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	var paths []string
+	err := fs.WalkDir(testDirAll, "testdata/sub", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"testdata/sub",
+		"testdata/sub/-not-hidden",
+		"testdata/sub/-not-hidden/fortune.txt",
+		"testdata/sub/sub.txt",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("WalkDir = %v, want %v", paths, want)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	f, err := global.Open("testdata/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name() != "hello.txt" || info.Size() != 13 || info.IsDir() || info.Mode() != 0444 {
+		t.Errorf("Stat = %s %d %v %v, want hello.txt 13 false -r--r--r--", info.Name(), info.Size(), info.IsDir(), info.Mode())
+	}
+
+	s, ok := f.(io.Seeker)
+	if !ok {
+		t.Fatal("embedded file does not implement io.Seeker")
+	}
+	if _, err := s.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest := make([]byte, 10)
+	n, err := f.Read(rest)
+	if err != nil || string(rest[:n]) != "world\n" {
+		t.Errorf("Read after Seek = %q, %v, want %q, nil", rest[:n], err, "world\n")
+	}
+	if n, err := f.Read(rest); n != 0 || err != io.EOF {
+		t.Errorf("Read at end = %d, %v, want 0, EOF", n, err)
+	}
+
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatal("embedded file does not implement io.ReaderAt")
+	}
+	buf := make([]byte, 5)
+	if n, err := r.ReadAt(buf, 0); n != 5 || err != nil || string(buf) != "hello" {
+		t.Errorf("ReadAt = %d, %v, %q, want 5, nil, %q", n, err, buf, "hello")
+	}
+
+	d, err := global.Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Read(buf); err == nil {
+		t.Error("Read of directory succeeded")
+	}
+	if _, err := global.ReadFile("testdata"); err == nil {
+		t.Error("ReadFile of directory succeeded")
+	}
+}
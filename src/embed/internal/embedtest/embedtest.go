@@ -0,0 +1,7 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package embedtest tests the //go:embed directive, covering both
+// the in-package and the external test packages. It has no API.
+package embedtest
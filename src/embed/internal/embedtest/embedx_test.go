@@ -0,0 +1,50 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embedtest_test
+
+import (
+	"embed"
+	"testing"
+)
+
+var (
+	global2      = global
+	concurrency2 = concurrency
+	glass2       = glass
+)
+
+//go:embed testdata/*.txt
+var global embed.FS
+
+//go:embed c*txt
+var concurrency string
+
+//go:embed testdata/g*.txt
+var glass []byte
+
+func TestXGlobal(t *testing.T) {
+	data, err := global.ReadFile("testdata/hello.txt")
+	if err != nil || string(data) != "hello, world\n" {
+		t.Errorf("ReadFile(testdata/hello.txt) = %q, %v", data, err)
+	}
+	if concurrency != "Concurrency is not parallelism.\n" {
+		t.Errorf("concurrency = %q", concurrency)
+	}
+	if string(glass) != "glass\n" {
+		t.Errorf("glass = %q", glass)
+	}
+
+	// Package-level variables initialized from embedded
+	// variables see the embedded contents.
+	if _, err := global2.ReadFile("testdata/hello.txt"); err != nil {
+		t.Errorf("global2: %v", err)
+	}
+	if concurrency2 != concurrency {
+		t.Errorf("concurrency2 = %q, want %q", concurrency2, concurrency)
+	}
+	if string(glass2) != string(glass) {
+		t.Errorf("glass2 = %q, want %q", glass2, glass)
+	}
+}
@@ -4,18 +4,19 @@
 
 // Package expvar provides a standardized interface to public variables, such
 // as operation counters in servers. It exposes these variables via HTTP at
-// /debug/vars in JSON format.
+// /debug/vars in JSON format, and at /debug/vars/prometheus in the
+// Prometheus text exposition format.
 //
 // Operations to set or modify these public variables are atomic.
 //
-// In addition to adding the HTTP handler, this package registers the
+// In addition to adding the HTTP handlers, this package registers the
 // following variables:
 //
 //	cmdline   os.Args
 //	memstats  runtime.Memstats
 //
 // The package is sometimes only imported for the side effect of
-// registering its HTTP handler and the above variables. To use it
+// registering its HTTP handlers and the above variables. To use it
 // this way, link this package into your program:
 //	import _ "expvar"
 //
@@ -32,6 +33,7 @@ import (
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -165,6 +167,20 @@ func (v *Map) Set(key string, av Var) {
 	v.m.Store(key, av)
 }
 
+// loadOrStore returns the value for key, first storing
+// the result of newVar if there is none.
+func (v *Map) loadOrStore(key string, newVar func() Var) Var {
+	i, ok := v.m.Load(key)
+	if !ok {
+		var dup bool
+		i, dup = v.m.LoadOrStore(key, newVar())
+		if !dup {
+			v.addKey(key)
+		}
+	}
+	return i.(Var)
+}
+
 // Add adds delta to the *Int value stored under the given map key.
 func (v *Map) Add(key string, delta int64) {
 	i, ok := v.m.Load(key)
@@ -246,6 +262,226 @@ func (f Func) String() string {
 	return string(v)
 }
 
+// Histogram is a variable that counts observations, such as request
+// latencies, in buckets, and satisfies the Var interface.
+// Its String method reports the number and sum of the observations
+// and, for each bucket, the number of observations less than or equal
+// to its upper bound, like
+//
+// 	{"count": 3, "sum": 0.7, "buckets": {"0.1": 1, "1": 3, "+Inf": 3}}
+//
+// The zero Histogram has only the bucket with bound +Inf.
+type Histogram struct {
+	bounds []float64 // sorted upper bounds, excluding +Inf
+	counts []uint64  // per bucket, with counts[len(bounds)] for +Inf
+	count  uint64
+	sum    Float
+}
+
+// DefaultBuckets are bucket bounds suitable for durations in seconds,
+// such as the latencies of network services.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Init sets the upper bounds of the buckets of v, which must be in
+// increasing order, and removes all observations. An implicit bucket
+// with bound +Inf holds all observations greater than the last bound.
+// Init must not be called concurrently with the other methods of v.
+func (v *Histogram) Init(bounds []float64) *Histogram {
+	for i, b := range bounds {
+		if math.IsNaN(b) || i > 0 && b <= bounds[i-1] {
+			panic("expvar: histogram bounds not in increasing order")
+		}
+	}
+	if n := len(bounds); n > 0 && math.IsInf(bounds[n-1], +1) {
+		bounds = bounds[:n-1]
+	}
+	v.bounds = append([]float64(nil), bounds...)
+	v.counts = make([]uint64, len(bounds)+1)
+	atomic.StoreUint64(&v.count, 0)
+	v.sum.Set(0)
+	return v
+}
+
+// Observe adds the observation x to v.
+func (v *Histogram) Observe(x float64) {
+	if v.counts == nil {
+		// The zero Histogram has a single bucket, which
+		// Observe cannot safely allocate: count only.
+		atomic.AddUint64(&v.count, 1)
+		v.sum.Add(x)
+		return
+	}
+	i := sort.SearchFloat64s(v.bounds, x)
+	atomic.AddUint64(&v.counts[i], 1)
+	atomic.AddUint64(&v.count, 1)
+	v.sum.Add(x)
+}
+
+// Count returns the number of observations.
+func (v *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&v.count)
+}
+
+// Sum returns the sum of the observations.
+func (v *Histogram) Sum() float64 {
+	return v.sum.Value()
+}
+
+// Buckets returns the upper bounds of the buckets of v, ending with +Inf,
+// and the cumulative counts of observations less than or equal to each.
+// The final count may differ from Count if v is concurrently updated.
+func (v *Histogram) Buckets() (bounds []float64, counts []uint64) {
+	bounds = append(append(bounds, v.bounds...), math.Inf(+1))
+	var n uint64
+	for i := range v.bounds {
+		n += atomic.LoadUint64(&v.counts[i])
+		counts = append(counts, n)
+	}
+	if v.counts == nil {
+		n = v.Count()
+	} else {
+		n += atomic.LoadUint64(&v.counts[len(v.bounds)])
+	}
+	return bounds, append(counts, n)
+}
+
+func (v *Histogram) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"count": %d, "sum": %s, "buckets": {`, v.Count(), &v.sum)
+	bounds, counts := v.Buckets()
+	for i, bound := range bounds {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%q: %d", formatFloat(bound), counts[i])
+	}
+	b.WriteString("}}")
+	return b.String()
+}
+
+// formatFloat formats f as Prometheus does, with +Inf, -Inf and NaN
+// for the special values.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// CounterVec is a set of Int counters, one for each combination of
+// values of its labels, such as the method and status of requests.
+// The key of each counter lists the labels and values, like
+// method="GET",code="200".
+type CounterVec struct {
+	m      Map
+	labels []string
+}
+
+// GaugeVec is a set of Float gauges, one for each combination of values
+// of its labels. The keys are those of a CounterVec with the same labels.
+type GaugeVec struct {
+	m      Map
+	labels []string
+}
+
+// Init removes all counters from v and sets its label names,
+// which must be distinct and consist of ASCII letters, digits
+// and underscores, not beginning with a digit.
+func (v *CounterVec) Init(labels ...string) *CounterVec {
+	v.labels = checkLabels(labels)
+	v.m.Init()
+	return v
+}
+
+// With returns the counter for the label values, which are in the
+// order of the labels of v, adding it if there is none.
+// It panics if the number of values differs from that of labels.
+func (v *CounterVec) With(values ...string) *Int {
+	return v.m.loadOrStore(labelKey(v.labels, values), func() Var { return new(Int) }).(*Int)
+}
+
+// Do calls f for each counter in v, in lexicographical order of the keys.
+func (v *CounterVec) Do(f func(KeyValue)) { v.m.Do(f) }
+
+func (v *CounterVec) String() string { return v.m.String() }
+
+// Init removes all gauges from v and sets its label names,
+// as CounterVec.Init does.
+func (v *GaugeVec) Init(labels ...string) *GaugeVec {
+	v.labels = checkLabels(labels)
+	v.m.Init()
+	return v
+}
+
+// With returns the gauge for the label values, which are in the
+// order of the labels of v, adding it if there is none.
+// It panics if the number of values differs from that of labels.
+func (v *GaugeVec) With(values ...string) *Float {
+	return v.m.loadOrStore(labelKey(v.labels, values), func() Var { return new(Float) }).(*Float)
+}
+
+// Do calls f for each gauge in v, in lexicographical order of the keys.
+func (v *GaugeVec) Do(f func(KeyValue)) { v.m.Do(f) }
+
+func (v *GaugeVec) String() string { return v.m.String() }
+
+func checkLabels(labels []string) []string {
+	seen := make(map[string]bool)
+	for _, l := range labels {
+		if !validName(l, false) || seen[l] {
+			panic("expvar: invalid or duplicate label name " + strconv.Quote(l))
+		}
+		seen[l] = true
+	}
+	return append([]string(nil), labels...)
+}
+
+// labelKey returns the Map key of the label values.
+func labelKey(labels, values []string) string {
+	if len(values) != len(labels) {
+		panic(fmt.Sprintf("expvar: %d label values for %d labels", len(values), len(labels)))
+	}
+	var b bytes.Buffer
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l)
+		b.WriteString(`="`)
+		labelEscaper.WriteString(&b, values[i])
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// validName reports whether s is a valid Prometheus metric name or,
+// if metric is false, label name.
+func validName(s string, metric bool) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !nameChar(s[i], i == 0, metric) {
+			return false
+		}
+	}
+	return true
+}
+
+// nameChar reports whether c may appear in a Prometheus metric or, if
+// metric is false, label name, at the start of the name if first is true.
+func nameChar(c byte, first, metric bool) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' ||
+		!first && '0' <= c && c <= '9' || metric && c == ':'
+}
+
 // All published variables.
 var (
 	vars      sync.Map // map[string]Var
@@ -294,6 +530,24 @@ func NewMap(name string) *Map {
 	return v
 }
 
+func NewHistogram(name string, bounds []float64) *Histogram {
+	v := new(Histogram).Init(bounds)
+	Publish(name, v)
+	return v
+}
+
+func NewCounterVec(name string, labels ...string) *CounterVec {
+	v := new(CounterVec).Init(labels...)
+	Publish(name, v)
+	return v
+}
+
+func NewGaugeVec(name string, labels ...string) *GaugeVec {
+	v := new(GaugeVec).Init(labels...)
+	Publish(name, v)
+	return v
+}
+
 func NewString(name string) *String {
 	v := new(String)
 	Publish(name, v)
@@ -345,6 +599,7 @@ func memstats() interface{} {
 
 func init() {
 	http.HandleFunc("/debug/vars", expvarHandler)
+	http.HandleFunc("/debug/vars/prometheus", prometheusHandler)
 	Publish("cmdline", Func(cmdline))
 	Publish("memstats", Func(memstats))
 }
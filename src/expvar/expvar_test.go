@@ -8,11 +8,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"net/http/httptest"
 	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -387,6 +389,210 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+func TestHistogram(t *testing.T) {
+	RemoveAll()
+	h := NewHistogram("requestLatency", []float64{0.1, 0.5, 1})
+	if h.Count() != 0 || h.Sum() != 0 {
+		t.Errorf("new histogram has count %d, sum %v, want 0, 0", h.Count(), h.Sum())
+	}
+	for _, x := range []float64{0.05, 0.1, 0.3, 2} {
+		h.Observe(x)
+	}
+	if got, want := h.Count(), uint64(4); got != want {
+		t.Errorf("h.Count() = %d, want %d", got, want)
+	}
+	if got, want := h.Sum(), 2.45; math.Abs(got-want) > 1e-9 {
+		t.Errorf("h.Sum() = %v, want %v", got, want)
+	}
+	bounds, counts := h.Buckets()
+	if want := []float64{0.1, 0.5, 1, math.Inf(+1)}; !reflect.DeepEqual(bounds, want) {
+		t.Errorf("h.Buckets() bounds = %v, want %v", bounds, want)
+	}
+	if want := []uint64{2, 3, 3, 4}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("h.Buckets() counts = %v, want %v", counts, want)
+	}
+	if got, want := h.String(), `{"count": 4, "sum": 2.45, "buckets": {"0.1": 2, "0.5": 3, "1": 3, "+Inf": 4}}`; got != want {
+		t.Errorf("h.String() = %s, want %s", got, want)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(h.String()), &v); err != nil {
+		t.Errorf("h.String() is not valid JSON: %v", err)
+	}
+
+	var zero Histogram
+	zero.Observe(3)
+	if got, want := zero.String(), `{"count": 1, "sum": 3, "buckets": {"+Inf": 1}}`; got != want {
+		t.Errorf("zero.String() = %s, want %s", got, want)
+	}
+
+	for _, bounds := range [][]float64{{1, 1}, {2, 1}, {math.NaN()}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Init(%v) did not panic", bounds)
+				}
+			}()
+			new(Histogram).Init(bounds)
+		}()
+	}
+}
+
+func BenchmarkHistogramObserve(b *testing.B) {
+	h := new(Histogram).Init(DefaultBuckets)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h.Observe(0.2)
+		}
+	})
+}
+
+func TestCounterVec(t *testing.T) {
+	RemoveAll()
+	v := NewCounterVec("requests", "method", "code")
+	v.With("GET", "200").Add(2)
+	v.With("POST", "500").Add(1)
+	v.With("GET", "200").Add(1)
+	if got, want := v.With("GET", "200").Value(), int64(3); got != want {
+		t.Errorf(`v.With("GET", "200").Value() = %d, want %d`, got, want)
+	}
+	if got, want := v.String(), `{"method=\"GET\",code=\"200\"": 3, "method=\"POST\",code=\"500\"": 1}`; got != want {
+		t.Errorf("v.String() = %s, want %s", got, want)
+	}
+
+	g := NewGaugeVec("temperature", "room")
+	g.With(`a"b`).Set(20.5)
+	var keys []string
+	g.Do(func(kv KeyValue) { keys = append(keys, kv.Key) })
+	if want := []string{`room="a\"b"`}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("g keys = %q, want %q", keys, want)
+	}
+
+	for _, f := range []func(){
+		func() { v.With("GET") },
+		func() { NewCounterVec("bad1", "a-b") },
+		func() { NewGaugeVec("bad2", "x", "x") },
+		func() { NewGaugeVec("bad3", "1x") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("no panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	RemoveAll()
+	NewInt("requests.total").Set(7)
+	NewFloat("load").Set(0.5)
+	h := NewHistogram("latency", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(3)
+	v := NewCounterVec("http_requests", "method", "code")
+	v.With("GET", "200").Add(2)
+	g := NewGaugeVec("queue", "name")
+	g.With(`a"b`).Set(1.5)
+	m := NewMap("map")
+	m.Add("a", 1)
+	m.Set("hist", new(Histogram).Init([]float64{1}))
+	m.Set("str", new(String))
+	Publish("stats", Func(func() interface{} {
+		return struct {
+			Alloc  uint64
+			Enable bool
+			Name   string
+			Pauses []int
+			Nested struct{ N int }
+		}{Alloc: 1 << 60, Enable: true, Name: "x", Pauses: []int{1}}
+	}))
+
+	rr := httptest.NewRecorder()
+	rr.Body = new(bytes.Buffer)
+	prometheusHandler(rr, nil)
+	want := `# TYPE http_requests counter
+http_requests{method="GET",code="200"} 2
+# TYPE latency histogram
+latency_bucket{le="0.1"} 1
+latency_bucket{le="1"} 1
+latency_bucket{le="+Inf"} 2
+latency_sum 3.05
+latency_count 2
+# TYPE load untyped
+load 0.5
+# TYPE map untyped
+map{key="a"} 1
+# TYPE map_hist histogram
+map_hist_bucket{le="1"} 0
+map_hist_bucket{le="+Inf"} 0
+map_hist_sum 0
+map_hist_count 0
+# TYPE queue gauge
+queue{name="a\"b"} 1.5
+# TYPE requests_total untyped
+requests_total 7
+# TYPE stats_Alloc untyped
+stats_Alloc 1152921504606846976
+# TYPE stats_Enable untyped
+stats_Enable 1
+# TYPE stats_Nested_N untyped
+stats_Nested_N 0
+`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("Prometheus handler wrote:\n%s\nWant:\n%s", got, want)
+	}
+	if got, want := rr.HeaderMap.Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+}
+
+func TestPrometheusNameCollision(t *testing.T) {
+	RemoveAll()
+	NewInt("requests.total").Set(1)
+	NewInt("requests_total").Set(2)
+	m := NewMap("lat")
+	m.Set("p", new(Histogram).Init([]float64{1}))
+	NewInt("lat.p").Set(3)
+	NewInt("lat_p_sum").Set(4)
+	NewFloat("lat_p_x").Set(5)
+
+	rr := httptest.NewRecorder()
+	rr.Body = new(bytes.Buffer)
+	prometheusHandler(rr, nil)
+	want := `# TYPE lat_p histogram
+lat_p_bucket{le="1"} 0
+lat_p_bucket{le="+Inf"} 0
+lat_p_sum 0
+lat_p_count 0
+# TYPE lat_p_x untyped
+lat_p_x 5
+# TYPE requests_total untyped
+requests_total 1
+`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("Prometheus handler wrote:\n%s\nWant:\n%s", got, want)
+	}
+}
+
+func TestPrometheusMemstats(t *testing.T) {
+	RemoveAll()
+	Publish("memstats", Func(memstats))
+	rr := httptest.NewRecorder()
+	rr.Body = new(bytes.Buffer)
+	prometheusHandler(rr, nil)
+	body := rr.Body.String()
+	for _, want := range []string{"\n# TYPE memstats_HeapAlloc untyped\nmemstats_HeapAlloc ", "\nmemstats_NumGC "} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Prometheus handler output does not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "PauseNs") {
+		t.Errorf("Prometheus handler output contains array PauseNs")
+	}
+}
+
 func BenchmarkRealworldExpvarUsage(b *testing.B) {
 	var (
 		bytesSent Int
@@ -0,0 +1,216 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package expvar
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file renders the published variables in the Prometheus text
+// exposition format, for monitoring systems that scrape it.
+//
+// Each variable becomes one or more metric families, named after the
+// variable with any characters not allowed in metric names replaced
+// by underscores:
+//
+// An Int or Float becomes an untyped metric.
+// A Histogram becomes a histogram, with the _bucket, _sum and _count
+// series of its buckets and observations.
+// A CounterVec or GaugeVec becomes a counter or gauge with a series
+// for each combination of label values.
+// A Map becomes an untyped metric with a series labeled key="KEY" for
+// each Int or Float in it; its other values become metrics named
+// NAME_KEY.
+// Any other variable, such as a Func, is rendered from its JSON
+// value: a number becomes an untyped metric, a boolean one with the
+// value 0 or 1, and each field of an object a metric named NAME_FIELD.
+// Strings, arrays and nulls are omitted.
+//
+// For example, the memstats variable becomes metrics such as
+// memstats_HeapAlloc and memstats_NumGC.
+//
+// Distinct variables can map to the same name, such as requests.total
+// and requests_total, or a variable and a value in a Map. The format
+// allows each name only once, so only the first metric with a given
+// name is rendered, in the order of the variables' names.
+
+func prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	pw := &promWriter{Writer: bufio.NewWriter(w), names: make(map[string]bool)}
+	Do(func(kv KeyValue) {
+		pw.writeVar(metricName(kv.Key), kv.Value)
+	})
+	pw.Flush()
+}
+
+// PrometheusHandler returns the HTTP Handler that renders the exported
+// variables in the Prometheus text exposition format. The package
+// installs it at /debug/vars/prometheus.
+//
+// This is only needed to install the handler in a non-standard location.
+func PrometheusHandler() http.Handler {
+	return http.HandlerFunc(prometheusHandler)
+}
+
+// metricName returns s with the characters not allowed
+// in Prometheus metric names replaced by underscores.
+func metricName(s string) string {
+	if validName(s, true) {
+		return s
+	}
+	if s == "" {
+		return "_"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if !nameChar(c, i == 0, true) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// A promWriter writes metric families, skipping those whose names
+// have already been written.
+type promWriter struct {
+	*bufio.Writer
+	names map[string]bool // names of the metrics written so far
+}
+
+// family writes the header of the metric family name of type typ and
+// reports whether it did. It writes nothing and returns false if the
+// family, or for a histogram one of its series, would reuse the name
+// of a metric already written.
+func (w *promWriter) family(name, typ string) bool {
+	names := []string{name}
+	if typ == "histogram" {
+		names = append(names, name+"_bucket", name+"_sum", name+"_count")
+	}
+	for _, n := range names {
+		if w.names[n] {
+			return false
+		}
+	}
+	for _, n := range names {
+		w.names[n] = true
+	}
+	io.WriteString(w, "# TYPE "+name+" "+typ+"\n")
+	return true
+}
+
+func (w *promWriter) writeVar(name string, v Var) {
+	switch v := v.(type) {
+	case *Int:
+		if w.family(name, "untyped") {
+			writeSample(w, name, "", v.String())
+		}
+	case *Float:
+		if w.family(name, "untyped") {
+			writeSample(w, name, "", formatFloat(v.Value()))
+		}
+	case *Histogram:
+		if !w.family(name, "histogram") {
+			return
+		}
+		bounds, counts := v.Buckets()
+		for i, b := range bounds {
+			writeSample(w, name+"_bucket", `le="`+formatFloat(b)+`"`, strconv.FormatUint(counts[i], 10))
+		}
+		writeSample(w, name+"_sum", "", formatFloat(v.Sum()))
+		writeSample(w, name+"_count", "", strconv.FormatUint(counts[len(counts)-1], 10))
+	case *CounterVec:
+		if !w.family(name, "counter") {
+			return
+		}
+		v.Do(func(kv KeyValue) {
+			writeSample(w, name, kv.Key, kv.Value.(*Int).String())
+		})
+	case *GaugeVec:
+		if !w.family(name, "gauge") {
+			return
+		}
+		v.Do(func(kv KeyValue) {
+			writeSample(w, name, kv.Key, formatFloat(kv.Value.(*Float).Value()))
+		})
+	case *Map:
+		var others []KeyValue
+		first, ok := true, false
+		v.Do(func(kv KeyValue) {
+			var val string
+			switch x := kv.Value.(type) {
+			case *Int:
+				val = x.String()
+			case *Float:
+				val = formatFloat(x.Value())
+			default:
+				others = append(others, kv)
+				return
+			}
+			if first {
+				ok = w.family(name, "untyped")
+				first = false
+			}
+			if !ok {
+				return
+			}
+			var label bytes.Buffer
+			label.WriteString(`key="`)
+			labelEscaper.WriteString(&label, kv.Key)
+			label.WriteByte('"')
+			writeSample(w, name, label.String(), val)
+		})
+		for _, kv := range others {
+			w.writeVar(name+"_"+metricName(kv.Key), kv.Value)
+		}
+	default:
+		dec := json.NewDecoder(strings.NewReader(v.String()))
+		dec.UseNumber()
+		var x interface{}
+		if dec.Decode(&x) == nil {
+			w.writeJSON(name, x)
+		}
+	}
+}
+
+// writeJSON writes the metrics for the JSON value x, decoded with UseNumber.
+func (w *promWriter) writeJSON(name string, x interface{}) {
+	switch x := x.(type) {
+	case json.Number:
+		if w.family(name, "untyped") {
+			writeSample(w, name, "", x.String())
+		}
+	case bool:
+		val := "0"
+		if x {
+			val = "1"
+		}
+		if w.family(name, "untyped") {
+			writeSample(w, name, "", val)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			w.writeJSON(name+"_"+metricName(k), x[k])
+		}
+	}
+}
+
+func writeSample(w io.Writer, name, labels, value string) {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	io.WriteString(w, name+" "+value+"\n")
+}
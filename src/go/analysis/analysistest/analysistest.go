@@ -0,0 +1,478 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysistest provides utilities for testing analyzers.
+package analysistest
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go/analysis"
+	"go/analysis/internal/checker"
+	"go/analysis/internal/facts"
+)
+
+// TestData returns the absolute name of the "testdata"
+// directory of the package whose tests are running.
+func TestData() string {
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return testdata
+}
+
+// Testing is an abstraction of a *testing.T.
+type Testing interface {
+	Errorf(format string, args ...interface{})
+}
+
+// A Result holds the result of applying an analyzer to a package.
+type Result struct {
+	Pkg         *types.Package
+	Fset        *token.FileSet
+	Diagnostics []analysis.Diagnostic
+	Facts       map[types.Object][]analysis.Fact
+	Result      interface{}
+	Err         error
+}
+
+// Run applies an analysis to the packages denoted by the patterns,
+// which are import paths of packages in dir/src, treated as a GOPATH
+// workspace. Each package is analyzed together with its in-package
+// test files. Packages of the testdata workspace imported by the
+// analyzed packages are analyzed first for their facts.
+//
+// Run checks that the diagnostics and facts reported by the analysis
+// match the expectations in comments of the form
+//
+//	// want "regexp" name:"regexp" ...
+//
+// A quoted regular expression must match a diagnostic reported at
+// the line of the comment, and an expression prefixed by name: must
+// match the string form of a fact about the object of that name
+// declared on that line; the name package denotes a fact about the
+// package itself. Each diagnostic and fact must match exactly one
+// expectation. Regular expressions may be written as raw strings,
+// which is usually more convenient.
+//
+// Failures are reported through t.Errorf. Run returns the results
+// so that the caller may make additional checks.
+func Run(t Testing, dir string, a *analysis.Analyzer, patterns ...string) []*Result {
+	if err := analysis.Validate([]*analysis.Analyzer{a}); err != nil {
+		t.Errorf("%v", err)
+		return nil
+	}
+	facts.RegisterTypes([]*analysis.Analyzer{a})
+
+	l := newLoader(dir, a)
+	var results []*Result
+	for _, pattern := range patterns {
+		pkg, err := l.load(pattern, true)
+		if err != nil {
+			t.Errorf("loading %s: %v", pattern, err)
+			continue
+		}
+		factSet, err := l.decode(pkg.Types)
+		if err != nil {
+			t.Errorf("loading %s: %v", pattern, err)
+			continue
+		}
+		act := checker.Run(pkg, []*analysis.Analyzer{a}, factSet, false)[0]
+		res := &Result{
+			Pkg:         pkg.Types,
+			Fset:        l.fset,
+			Diagnostics: act.Diagnostics,
+			Facts:       make(map[types.Object][]analysis.Fact),
+			Result:      act.Result,
+			Err:         act.Err,
+		}
+		if act.Err != nil {
+			t.Errorf("error analyzing %s: %v", pattern, act.Err)
+		} else {
+			check(t, l.fset, pkg, act.Diagnostics, ownFacts(a, pkg.Types, factSet, res))
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// RunWithSuggestedFixes behaves like Run, but additionally applies
+// the suggested fixes of the reported diagnostics and checks that
+// the result for each file f matches the file f.golden, after both
+// have been formatted with go/format.
+func RunWithSuggestedFixes(t Testing, dir string, a *analysis.Analyzer, patterns ...string) []*Result {
+	results := Run(t, dir, a, patterns...)
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		edits, err := checker.Edits(res.Fset, res.Diagnostics)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		var names []string
+		for name := range edits {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			golden, err := ioutil.ReadFile(name + ".golden")
+			if err != nil {
+				t.Errorf("%v", err)
+				continue
+			}
+			want, err := format.Source(golden)
+			if err != nil {
+				t.Errorf("%s.golden: %v", name, err)
+				continue
+			}
+			got, err := format.Source(edits[name])
+			if err != nil {
+				t.Errorf("%s: suggested fixes produced invalid code: %v\n%s", name, err, edits[name])
+				continue
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: suggested fixes do not match %s.golden; got:\n%s", name, filepath.Base(name), got)
+			}
+		}
+	}
+	return results
+}
+
+// A factEntry is a fact about the package being tested
+// or about one of its objects.
+type factEntry struct {
+	pos  token.Pos
+	name string
+	fact analysis.Fact
+}
+
+// ownFacts returns the facts of a's fact types about pkg and its
+// objects, recording the object facts in res.
+func ownFacts(a *analysis.Analyzer, pkg *types.Package, factSet *facts.Set, res *Result) []factEntry {
+	filter := make(map[reflect.Type]bool)
+	for _, f := range a.FactTypes {
+		filter[reflect.TypeOf(f)] = true
+	}
+	var entries []factEntry
+	for _, f := range factSet.AllObjectFacts(filter) {
+		if f.Object.Pkg() != pkg {
+			continue
+		}
+		res.Facts[f.Object] = append(res.Facts[f.Object], f.Fact)
+		entries = append(entries, factEntry{f.Object.Pos(), f.Object.Name(), f.Fact})
+	}
+	for _, f := range factSet.AllPackageFacts(filter) {
+		if f.Package != pkg {
+			continue
+		}
+		entries = append(entries, factEntry{token.NoPos, "package", f.Fact})
+	}
+	return entries
+}
+
+// An expectation is a regular expression from a "want" comment.
+type expectation struct {
+	name string // "" for a diagnostic, else an object name or "package"
+	rx   *regexp.Regexp
+}
+
+type lineKey struct {
+	file string
+	line int
+}
+
+// check reports, through t, the diagnostics and facts that match
+// no expectation in the want comments of pkg, and the expectations
+// that match no diagnostic or fact.
+func check(t Testing, fset *token.FileSet, pkg *checker.Package, diags []analysis.Diagnostic, facts []factEntry) {
+	want := make(map[lineKey][]expectation)
+	var pkgWant []expectation
+	for _, f := range pkg.Files {
+		for _, cgroup := range f.Comments {
+			for _, c := range cgroup.List {
+				text := strings.TrimPrefix(c.Text, "//")
+				if text == c.Text {
+					continue // not a line comment
+				}
+				text = strings.TrimSpace(text)
+				if !strings.HasPrefix(text, "want ") {
+					continue
+				}
+				posn := fset.Position(c.Pos())
+				expects, err := parseExpectations(strings.TrimPrefix(text, "want "))
+				if err != nil {
+					t.Errorf("%s: in 'want' comment: %v", posn, err)
+					continue
+				}
+				k := lineKey{posn.Filename, posn.Line}
+				for _, e := range expects {
+					if e.name == "package" {
+						pkgWant = append(pkgWant, e)
+					} else {
+						want[k] = append(want[k], e)
+					}
+				}
+			}
+		}
+	}
+
+	// match consumes and reports whether there is an expectation
+	// of the given name matching message.
+	match := func(list []expectation, name, message string) ([]expectation, bool) {
+		for i, e := range list {
+			if e.name == name && e.rx.MatchString(message) {
+				return append(list[:i:i], list[i+1:]...), true
+			}
+		}
+		return list, false
+	}
+
+	for _, d := range diags {
+		posn := fset.Position(d.Pos)
+		k := lineKey{posn.Filename, posn.Line}
+		var ok bool
+		if want[k], ok = match(want[k], "", d.Message); !ok {
+			t.Errorf("%v: unexpected diagnostic: %v", posn, d.Message)
+		}
+	}
+
+	for _, f := range facts {
+		message := fmt.Sprint(f.fact)
+		var ok bool
+		if f.pos == token.NoPos {
+			if pkgWant, ok = match(pkgWant, "package", message); !ok {
+				t.Errorf("%s: unexpected package fact: %s", pkg.Types.Path(), message)
+			}
+			continue
+		}
+		posn := fset.Position(f.pos)
+		k := lineKey{posn.Filename, posn.Line}
+		if want[k], ok = match(want[k], f.name, message); !ok {
+			t.Errorf("%v: unexpected fact: %s: %s", posn, f.name, message)
+		}
+	}
+
+	// Report unmatched expectations in a deterministic order.
+	var keys []lineKey
+	for k, list := range want {
+		if len(list) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].file != keys[j].file {
+			return keys[i].file < keys[j].file
+		}
+		return keys[i].line < keys[j].line
+	})
+	for _, k := range keys {
+		for _, e := range want[k] {
+			if e.name == "" {
+				t.Errorf("%s:%d: no diagnostic was reported matching %#q", k.file, k.line, e.rx)
+			} else {
+				t.Errorf("%s:%d: no fact was inferred for %s matching %#q", k.file, k.line, e.name, e.rx)
+			}
+		}
+	}
+	for _, e := range pkgWant {
+		t.Errorf("%s: no package fact was inferred matching %#q", pkg.Types.Path(), e.rx)
+	}
+}
+
+// parseExpectations parses the text of a "want" comment following
+// the word want: a sequence of string literals, each optionally
+// preceded by "name:".
+func parseExpectations(text string) ([]expectation, error) {
+	var expects []expectation
+	var s scanner.Scanner
+	var errs scanner.ErrorList
+	fset := token.NewFileSet()
+	file := fset.AddFile("want", -1, len(text))
+	s.Init(file, []byte(text), func(pos token.Position, msg string) {
+		errs.Add(pos, msg)
+	}, 0)
+
+	name := ""
+	for {
+		_, tok, lit := s.Scan()
+		switch tok {
+		case token.EOF, token.SEMICOLON:
+			if errs.Len() > 0 {
+				return nil, errs.Err()
+			}
+			if name != "" {
+				return nil, fmt.Errorf("missing pattern after %s:", name)
+			}
+			return expects, nil
+		case token.IDENT:
+			if name != "" {
+				return nil, fmt.Errorf("unexpected %s after %s", lit, name)
+			}
+			_, tok, _ := s.Scan()
+			if tok != token.COLON {
+				return nil, fmt.Errorf("got %s after %s, want ':'", tok, lit)
+			}
+			name = lit
+		case token.STRING:
+			pattern, err := strconv.Unquote(lit)
+			if err != nil {
+				return nil, err
+			}
+			rx, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			expects = append(expects, expectation{name, rx})
+			name = ""
+		default:
+			return nil, fmt.Errorf("unexpected %s", tok)
+		}
+	}
+}
+
+// A loader loads and type-checks the packages of a testdata workspace.
+// Imports of packages outside the workspace, such as those of the
+// standard library, are type-checked from source but not analyzed.
+type loader struct {
+	ctxt     build.Context
+	fset     *token.FileSet
+	analyzer *analysis.Analyzer
+	std      types.ImporterFrom
+	pkgs     map[string]*types.Package // dependencies, by import path
+	vetx     map[string][]byte         // encoded facts of dependencies
+}
+
+func newLoader(dir string, a *analysis.Analyzer) *loader {
+	ctxt := build.Default
+	ctxt.GOPATH = dir
+	ctxt.CgoEnabled = false
+	return &loader{
+		ctxt:     ctxt,
+		fset:     token.NewFileSet(),
+		analyzer: a,
+		std:      importer.For("source", nil).(types.ImporterFrom),
+		pkgs:     make(map[string]*types.Package),
+		vetx:     make(map[string][]byte),
+	}
+}
+
+// load parses and type-checks the package with the given import path,
+// including its in-package test files if withTests is set.
+func (l *loader) load(path string, withTests bool) (*checker.Package, error) {
+	bp, err := l.ctxt.Import(path, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	names := bp.GoFiles
+	if withTests {
+		names = append(names[:len(names):len(names)], bp.TestGoFiles...)
+	}
+	var files []*ast.File
+	for _, name := range names {
+		f, err := parser.ParseFile(l.fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	tc := &types.Config{
+		Importer: importerFunc(func(path, dir string) (*types.Package, error) {
+			return l.importFrom(path, dir)
+		}),
+		Sizes: types.SizesFor("gc", l.ctxt.GOARCH),
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	pkg, err := tc.Check(bp.ImportPath, l.fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	return &checker.Package{
+		Fset:       l.fset,
+		Files:      files,
+		OtherFiles: bp.SFiles,
+		Types:      pkg,
+		TypesInfo:  info,
+		TypesSizes: tc.Sizes,
+	}, nil
+}
+
+// importFrom returns the package imported by path from a package in dir.
+// A package of the testdata workspace is loaded and analyzed for facts.
+func (l *loader) importFrom(path, dir string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bp, err := l.ctxt.Import(path, dir, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	if bp.Goroot {
+		return l.std.ImportFrom(path, dir, 0)
+	}
+	if pkg := l.pkgs[bp.ImportPath]; pkg != nil {
+		return pkg, nil
+	}
+	dep, err := l.load(bp.ImportPath, false)
+	if err != nil {
+		return nil, err
+	}
+	factSet, err := l.decode(dep.Types)
+	if err != nil {
+		return nil, err
+	}
+	for _, act := range checker.Run(dep, []*analysis.Analyzer{l.analyzer}, factSet, true) {
+		if act.Err != nil {
+			return nil, fmt.Errorf("analyzing %s: %v", bp.ImportPath, act.Err)
+		}
+	}
+	l.pkgs[bp.ImportPath] = dep.Types
+	l.vetx[bp.ImportPath] = factSet.Encode()
+	return dep.Types, nil
+}
+
+// decode returns the facts about the dependencies of pkg
+// computed by earlier analyses.
+func (l *loader) decode(pkg *types.Package) (*facts.Set, error) {
+	return facts.Decode(pkg, func(path string) ([]byte, error) {
+		return l.vetx[path], nil
+	})
+}
+
+type importerFunc func(path, dir string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path, "")
+}
+
+func (f importerFunc) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	return f(path, dir)
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package analysis defines the interface between a modular static
+analysis and an analysis driver program.
+
+Background
+
+A static analysis is a function that inspects a package of Go code and
+reports a set of diagnostics (typically mistakes in the code), and
+perhaps produces other results as well, such as suggested refactorings
+or other facts. An analysis that reports mistakes is informally called a
+"checker". For example, the printf checker reports mistakes in
+fmt.Printf format strings.
+
+A "modular" analysis is one that inspects one package at a time but can
+save information from a lower-level package and use it when inspecting a
+higher-level package, analogous to separate compilation in a toolchain.
+The printf checker is modular: when it discovers that a function such as
+log.Fatalf delegates to fmt.Printf, it records this fact, and checks
+calls to that function too, including calls made from another package.
+
+By implementing a common interface, checkers from a variety of sources
+can be easily selected, incorporated, and reused in a wide range of
+driver programs including command-line tools (such as vet), text editors
+and IDEs, build and code review systems, and batch pipelines for large
+code bases.
+
+Analyzer
+
+The primary type in the API is Analyzer. An Analyzer statically
+describes an analysis function: its name, documentation, flags,
+relationship to other analyzers, and of course, its logic.
+
+To define an analysis, a user declares a (logically constant) variable
+of type Analyzer. Here is a typical example from one of the analyzers in
+the go/analysis/passes/ subdirectory:
+
+	package assign
+
+	var Analyzer = &analysis.Analyzer{
+		Name: "assign",
+		Doc:  "check for useless assignments",
+		Run:  run,
+	}
+
+An analysis driver is a program such as vet that runs a set of
+analyses and prints the diagnostics that they report.
+The driver program must import the list of Analyzers it needs.
+Typically each Analyzer resides in a separate package.
+To add a new Analyzer to an existing driver, add another item to the list:
+
+	import (
+		"go/analysis/passes/assign"
+		"go/analysis/passes/printf"
+	)
+
+	var analyses = []*analysis.Analyzer{
+		assign.Analyzer,
+		printf.Analyzer,
+	}
+
+A driver may use the name, flags, and documentation to provide on-line
+help that describes the analyses it performs.
+
+The Requires field specifies a set of analyses that must run before
+this one, and whose results this analysis may use. Analyzers form a
+directed acyclic graph through Requires; Validate checks this and the
+other structural properties of a set of analyzers.
+
+The optional ResultType field specifies the type of the result value
+computed by this analysis and made available to other analyses.
+The Requires and ResultType fields allow one analysis to compute an
+intermediate result, such as a table of the package's functions, that
+several other analyses share.
+
+Pass
+
+A Pass describes a single unit of work: the application of a particular
+Analyzer to a particular package of Go code. The Pass provides
+information to the Analyzer's Run function about the package being
+analyzed, and provides operations to the Run function for reporting
+diagnostics and other information back to the driver.
+
+The Fset, Files, Pkg, and TypesInfo fields provide the syntax trees,
+type information, and source positions for a single package of Go code.
+
+The OtherFiles field provides the names, but not the contents, of
+non-Go files such as assembly that are part of this package.
+
+The ResultOf field provides the results computed by the analyzers
+required by this one, as expressed in its Analyzer.Requires field. The
+driver runs the required analyzers first and makes their results
+available in this map. Each Analyzer must return a value of the type
+described in its Analyzer.ResultType field.
+
+The Report function emits a diagnostic, a message associated with a
+source position. For most analyses, diagnostics are their primary
+result. For convenience, Pass provides a helper method, Reportf, to
+report a new diagnostic by formatting a string. Diagnostics may carry
+SuggestedFixes, a set of text edits that a driver may apply, with the
+user's consent, to correct the reported problem.
+
+Modular analysis with Facts
+
+To improve efficiency and scalability, large programs are routinely
+built using separate compilation: units of the program are compiled
+separately, and recompiled only when one of their dependencies changes;
+independent modules may be compiled in parallel. The same technique may
+be applied to static analyses, for the same benefits. Such analyses are
+described as "modular".
+
+A compiler's type checker is an example of a modular static analysis.
+Many other checkers we would like to apply to Go programs can be
+understood as alternative or non-standard type systems. For example,
+vet's printf checker infers whether a function has the "printf wrapper"
+type, and it applies stricter checks to calls of such functions. In
+addition, it records which functions are printf wrappers for use by
+later analysis passes to identify other printf wrappers by induction.
+
+A Fact is a serializable piece of information about an object or a
+package, computed by the analysis of one package and made available to
+the analysis of packages that import it. An Analyzer declares the types
+of facts it may produce in its FactTypes field. Facts are exported by
+ExportObjectFact and ExportPackageFact and imported by the corresponding
+Import methods of the Pass.
+
+A driver serializes facts using encoding/gob, so each fact type must be
+a pointer to a struct that gob can encode. Facts may be associated
+only with the package itself and with the package-level objects of the
+package, including methods of its package-level named types: these are
+the objects that importing packages can refer to.
+
+Testing an Analyzer
+
+The analysistest subpackage provides utilities for testing an Analyzer.
+In a few lines of code, it is possible to run an analyzer on a package
+of testdata files and check that it reported all the expected
+diagnostics and facts (and no more). Expectations are expressed using
+"// want ..." comments in the input code.
+
+Standalone commands
+
+Analyzers are provided in the form of packages that a driver program is
+expected to import. The vet command imports a set of several analyzers,
+but users may wish to define their own analysis commands that perform
+additional checks. To simplify the task of creating an analysis command,
+either for a single analyzer or for a whole suite, we provide the
+multichecker subpackage. Its Main function runs the given analyzers
+on the packages named on the command line:
+
+	package main
+
+	import (
+		"go/analysis/multichecker"
+		"go/analysis/passes/printf"
+	)
+
+	func main() { multichecker.Main(printf.Analyzer) }
+
+A command built this way may also be used with the go vet command's
+-vettool flag, which is implemented by the unitchecker subpackage:
+
+	$ go vet -vettool=$(which mychecker) ./...
+*/
+package analysis
@@ -0,0 +1,205 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package checker applies a set of analyzers to a single
+// parsed and type-checked package. It is the common core of
+// the analysis drivers: the unitchecker, which is invoked by
+// "go vet -vettool", and the analysistest package.
+package checker
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"go/analysis"
+	"go/analysis/internal/facts"
+)
+
+// A Package is a parsed and type-checked package to be analyzed.
+type Package struct {
+	Fset       *token.FileSet
+	Files      []*ast.File
+	OtherFiles []string
+	Types      *types.Package
+	TypesInfo  *types.Info
+	TypesSizes types.Sizes
+	IllTyped   bool // the package has parse or type errors
+}
+
+// An Action records the application of one analyzer to the package.
+type Action struct {
+	Analyzer    *analysis.Analyzer
+	Diagnostics []analysis.Diagnostic
+	Result      interface{}
+	Err         error
+}
+
+// Run applies the analyzers, and those they require, to pkg.
+// It returns one Action for each of the given analyzers, in order.
+//
+// Facts about pkg's dependencies are read from factSet, which also
+// receives the facts exported by the analyzers. If factsOnly is set,
+// only the analyzers that use facts are run; this is how a driver
+// analyzes a dependency of the packages that are its real subject.
+func Run(pkg *Package, analyzers []*analysis.Analyzer, factSet *facts.Set, factsOnly bool) []*Action {
+	if factsOnly {
+		var factAnalyzers []*analysis.Analyzer
+		for _, a := range analyzers {
+			if len(a.FactTypes) > 0 {
+				factAnalyzers = append(factAnalyzers, a)
+			}
+		}
+		analyzers = factAnalyzers
+	}
+
+	actions := make(map[*analysis.Analyzer]*Action)
+	var exec func(a *analysis.Analyzer) *Action
+	exec = func(a *analysis.Analyzer) *Action {
+		if act, ok := actions[a]; ok {
+			return act
+		}
+		act := &Action{Analyzer: a}
+		actions[a] = act
+
+		// Run the prerequisites first.
+		inputs := make(map[*analysis.Analyzer]interface{})
+		for _, req := range a.Requires {
+			reqact := exec(req)
+			if reqact.Err != nil {
+				act.Err = fmt.Errorf("failed prerequisite: %s", req)
+				return act
+			}
+			inputs[req] = reqact.Result
+		}
+
+		if pkg.IllTyped && !a.RunDespiteErrors {
+			act.Err = fmt.Errorf("analysis skipped due to errors in package")
+			return act
+		}
+
+		factFilter := make(map[reflect.Type]bool)
+		for _, f := range a.FactTypes {
+			factFilter[reflect.TypeOf(f)] = true
+		}
+		checkFact := func(fact analysis.Fact) {
+			if !factFilter[reflect.TypeOf(fact)] {
+				panic(fmt.Sprintf("analyzer %s: fact type %T not declared in FactTypes", a, fact))
+			}
+		}
+
+		pass := &analysis.Pass{
+			Analyzer:   a,
+			Fset:       pkg.Fset,
+			Files:      pkg.Files,
+			OtherFiles: pkg.OtherFiles,
+			Pkg:        pkg.Types,
+			TypesInfo:  pkg.TypesInfo,
+			TypesSizes: pkg.TypesSizes,
+			ResultOf:   inputs,
+			Report: func(d analysis.Diagnostic) {
+				act.Diagnostics = append(act.Diagnostics, d)
+			},
+			ImportObjectFact: func(obj types.Object, fact analysis.Fact) bool {
+				checkFact(fact)
+				return factSet.ImportObjectFact(obj, fact)
+			},
+			ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+				checkFact(fact)
+				factSet.ExportObjectFact(obj, fact)
+			},
+			ImportPackageFact: func(pkg *types.Package, fact analysis.Fact) bool {
+				checkFact(fact)
+				return factSet.ImportPackageFact(pkg, fact)
+			},
+			ExportPackageFact: func(fact analysis.Fact) {
+				checkFact(fact)
+				factSet.ExportPackageFact(fact)
+			},
+			AllObjectFacts: func() []analysis.ObjectFact {
+				return factSet.AllObjectFacts(factFilter)
+			},
+			AllPackageFacts: func() []analysis.PackageFact {
+				return factSet.AllPackageFacts(factFilter)
+			},
+		}
+
+		act.Result, act.Err = a.Run(pass)
+		if act.Err == nil && a.ResultType != nil {
+			if got := reflect.TypeOf(act.Result); got != a.ResultType {
+				act.Err = fmt.Errorf(
+					"internal error: on package %s, analyzer %s returned a result of type %v, but declared ResultType %v",
+					pkg.Types.Path(), a, got, a.ResultType)
+			}
+		}
+		return act
+	}
+
+	var results []*Action
+	for _, a := range analyzers {
+		results = append(results, exec(a))
+	}
+	return results
+}
+
+// Edits returns the new content of each file changed by the suggested
+// fixes of the diagnostics, keyed by file name. It reports an error if
+// the edits for a file overlap.
+func Edits(fset *token.FileSet, diags []analysis.Diagnostic) (map[string][]byte, error) {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	byFile := make(map[string][]edit)
+	for _, d := range diags {
+		for _, fix := range d.SuggestedFixes {
+			for _, e := range fix.TextEdits {
+				end := e.End
+				if !end.IsValid() {
+					end = e.Pos
+				}
+				file := fset.File(e.Pos)
+				if file == nil || fset.File(end) != file || end < e.Pos {
+					return nil, fmt.Errorf("invalid text edit for diagnostic %q", d.Message)
+				}
+				byFile[file.Name()] = append(byFile[file.Name()], edit{
+					start: file.Offset(e.Pos),
+					end:   file.Offset(end),
+					text:  string(e.NewText),
+				})
+			}
+		}
+	}
+
+	out := make(map[string][]byte)
+	for name, edits := range byFile {
+		sort.SliceStable(edits, func(i, j int) bool {
+			return edits[i].start < edits[j].start
+		})
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var buf []byte
+		last := 0
+		for i, e := range edits {
+			if i > 0 && e == edits[i-1] {
+				continue // duplicate
+			}
+			if e.start < last || e.end > len(src) {
+				return nil, fmt.Errorf("%s: overlapping or invalid text edits", name)
+			}
+			buf = append(buf, src[last:e.start]...)
+			buf = append(buf, e.text...)
+			last = e.end
+		}
+		buf = append(buf, src[last:]...)
+		out[name] = buf
+	}
+	return out, nil
+}
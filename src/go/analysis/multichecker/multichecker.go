@@ -0,0 +1,92 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package multichecker defines the main function for an analysis driver
+// with several analyzers. This package makes it easy for anyone to build
+// an analysis tool containing just the analyzers they need.
+//
+// A tool built with multichecker analyzes the packages named on its
+// command line:
+//
+//	$ mychecker ./...
+//
+// It does so by running "go vet" with the tool itself as the vet tool,
+// so the packages are loaded, and facts computed for their dependencies,
+// exactly as for "go vet -vettool=$(which mychecker)".
+package multichecker
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go/analysis"
+	"go/analysis/internal/analysisflags"
+	"go/analysis/unitchecker"
+)
+
+// Main is the main function of an analysis tool with the given analyzers.
+// Given a single argument ending in ".cfg", it analyzes the unit
+// described by that configuration file, as invoked by "go vet".
+// Otherwise it treats its arguments as package patterns and runs
+// "go vet" on them, passing on its flags.
+func Main(analyzers ...*analysis.Analyzer) {
+	progname := filepath.Base(os.Args[0])
+	log.SetFlags(0)
+	log.SetPrefix(progname + ": ")
+
+	if err := analysis.Validate(analyzers); err != nil {
+		log.Fatal(err)
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `%[1]s is a tool for static analysis of Go programs.
+
+Usage: %[1]s [-flag] [package]
+
+Registered analyzers:
+
+`, progname)
+		for _, a := range analyzers {
+			title := strings.Split(a.Doc, "\n\n")[0]
+			fmt.Fprintf(os.Stderr, "    %-12s %s\n", a.Name, title)
+		}
+		fmt.Fprintf(os.Stderr, "\nFlags:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	analyzers = analysisflags.Parse(analyzers, true)
+
+	args := flag.Args()
+	if len(args) == 1 && strings.HasSuffix(args[0], ".cfg") {
+		unitchecker.Run(args[0], analyzers)
+		panic("unreachable")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("cannot locate executable: %v", err)
+	}
+
+	// Pass on the flags given to this command,
+	// which are those before the package patterns.
+	flags := os.Args[1 : len(os.Args)-len(args)]
+	vetArgs := append([]string{"vet", "-vettool=" + exe}, flags...)
+	vetArgs = append(vetArgs, args...)
+
+	cmd := exec.Command("go", vetArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			os.Exit(1)
+		}
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package assign defines an Analyzer that detects useless assignments.
+//
+// The analyzer reports assignments of the form x = x or a[i] = a[i].
+// These are almost always useless, and even when they aren't they are
+// usually a mistake. Each diagnostic carries a suggested fix that
+// removes the assignment.
+package assign
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"reflect"
+
+	"go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "assign",
+	Doc:  "check for useless assignments",
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if stmt, ok := n.(*ast.AssignStmt); ok {
+				checkAssignStmt(pass, stmt)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// checkAssignStmt checks for assignments of the form "<expr> = <expr>".
+func checkAssignStmt(pass *analysis.Pass, stmt *ast.AssignStmt) {
+	if stmt.Tok != token.ASSIGN {
+		return // ignore :=
+	}
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		// If LHS and RHS have different cardinality, they can't be the same.
+		return
+	}
+	for i, lhs := range stmt.Lhs {
+		rhs := stmt.Rhs[i]
+		if hasSideEffects(lhs) || hasSideEffects(rhs) {
+			continue // expressions may not be equal
+		}
+		if reflect.TypeOf(lhs) != reflect.TypeOf(rhs) {
+			continue // short-circuit the heavy-weight gofmt check
+		}
+		le := gofmt(pass.Fset, lhs)
+		re := gofmt(pass.Fset, rhs)
+		if le != re {
+			continue
+		}
+		d := analysis.Diagnostic{
+			Pos:     stmt.Pos(),
+			End:     stmt.End(),
+			Message: "self-assignment of " + re + " to " + le,
+		}
+		if len(stmt.Lhs) == 1 {
+			// Removing the whole statement is safe
+			// only if it assigns nothing else.
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Remove self-assignment",
+				TextEdits: []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.End()}},
+			}}
+		}
+		pass.Report(d)
+	}
+}
+
+// hasSideEffects reports whether evaluation of e has side effects.
+func hasSideEffects(e ast.Expr) bool {
+	safe := true
+	ast.Inspect(e, func(node ast.Node) bool {
+		switch n := node.(type) {
+		// Using CallExpr here will catch conversions
+		// as well as function and method invocations.
+		// We'll live with the false negatives for now.
+		case *ast.CallExpr:
+			safe = false
+			return false
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				safe = false
+				return false
+			}
+		}
+		return true
+	})
+	return !safe
+}
+
+// gofmt returns a string representation of the expression.
+func gofmt(fset *token.FileSet, x ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, x)
+	return buf.String()
+}
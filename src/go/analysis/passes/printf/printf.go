@@ -0,0 +1,983 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package printf defines an Analyzer that checks consistency
+// of Printf format strings and arguments.
+//
+// The analyzer checks calls to the formatted print functions of the
+// fmt, log and testing packages, and to any function that it infers
+// to be a wrapper of one of them, in the package being analyzed or
+// in one of its dependencies.
+//
+// A function is a printf wrapper if its last two parameters are
+// format string and args ...interface{}, and it passes them on as
+// "format, args..." to a printf-like function. It is a print wrapper
+// if its last parameter is args ...interface{} and it passes "args..."
+// on to a print-like function. Wrapper facts are exported, so that
+// calls to a wrapper are checked in importing packages too.
+//
+// The -funcs flag names additional print functions to check,
+// as a comma-separated list of case-insensitive function names
+// such as "Errorf,Warn". A name ending in f is taken to denote
+// a formatted print function.
+package printf
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "printf",
+	Doc:       "check consistency of Printf format strings and arguments",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(isWrapper)},
+}
+
+// funcs is the value of the -funcs flag.
+var funcs string
+
+func init() {
+	Analyzer.Flags.StringVar(&funcs, "funcs", "", "comma-separated list of print function names to check")
+}
+
+// funcKind is the kind of a print function.
+type funcKind int
+
+const (
+	kindNone   funcKind = iota // not a print wrapper
+	kindPrintf             // function is a printf wrapper
+	kindPrint              // function is a print wrapper
+)
+
+func (kind funcKind) String() string {
+	switch kind {
+	case kindPrintf:
+		return "printfWrapper"
+	case kindPrint:
+		return "printWrapper"
+	}
+	return "none"
+}
+
+// isWrapper is a fact indicating that a function is a print or printf
+// wrapper.
+type isWrapper struct{ Kind funcKind }
+
+func (f *isWrapper) AFact() {}
+
+func (f *isWrapper) String() string { return f.Kind.String() }
+
+// isPrint records the print functions.
+// If a key ends in 'f' then it is assumed to be a formatted print.
+var isPrint = map[string]bool{
+	"fmt.Errorf":         true,
+	"fmt.Fprint":         true,
+	"fmt.Fprintf":        true,
+	"fmt.Fprintln":       true,
+	"fmt.Print":          true,
+	"fmt.Printf":         true,
+	"fmt.Println":        true,
+	"fmt.Sprint":         true,
+	"fmt.Sprintf":        true,
+	"fmt.Sprintln":       true,
+	"log.Fatal":          true,
+	"log.Fatalf":         true,
+	"log.Fatalln":        true,
+	"log.Logger.Fatal":   true,
+	"log.Logger.Fatalf":  true,
+	"log.Logger.Fatalln": true,
+	"log.Logger.Panic":   true,
+	"log.Logger.Panicf":  true,
+	"log.Logger.Panicln": true,
+	"log.Logger.Printf":  true,
+	"log.Logger.Println": true,
+	"log.Panic":          true,
+	"log.Panicf":         true,
+	"log.Panicln":        true,
+	"log.Print":          true,
+	"log.Printf":         true,
+	"log.Println":        true,
+	"testing.B.Error":    true,
+	"testing.B.Errorf":   true,
+	"testing.B.Fatal":    true,
+	"testing.B.Fatalf":   true,
+	"testing.B.Log":      true,
+	"testing.B.Logf":     true,
+	"testing.B.Skip":     true,
+	"testing.B.Skipf":    true,
+	"testing.T.Error":    true,
+	"testing.T.Errorf":   true,
+	"testing.T.Fatal":    true,
+	"testing.T.Fatalf":   true,
+	"testing.T.Log":      true,
+	"testing.T.Logf":     true,
+	"testing.T.Skip":     true,
+	"testing.T.Skipf":    true,
+	"testing.TB.Error":   true,
+	"testing.TB.Errorf":  true,
+	"testing.TB.Fatal":   true,
+	"testing.TB.Fatalf":  true,
+	"testing.TB.Log":     true,
+	"testing.TB.Logf":    true,
+	"testing.TB.Skip":    true,
+	"testing.TB.Skipf":   true,
+}
+
+// checker holds the state of the analysis of one package.
+type checker struct {
+	pass *analysis.Pass
+
+	// byName records the print functions named by the -funcs flag,
+	// in lower case.
+	byName map[string]bool
+
+	// wrappers records the wrappers found in this package.
+	wrappers map[*types.Func]funcKind
+
+	// stringers records the receivers of the String methods
+	// of the file being checked.
+	stringers map[*ast.Object]bool
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	c := &checker{
+		pass:     pass,
+		byName:   make(map[string]bool),
+		wrappers: make(map[*types.Func]funcKind),
+	}
+	if funcs != "" {
+		for _, name := range strings.Split(funcs, ",") {
+			if len(name) == 0 {
+				return nil, fmt.Errorf("empty function name in -funcs=%s", funcs)
+			}
+
+			// Backwards compatibility: skip optional first argument
+			// index after the colon.
+			if colon := strings.LastIndex(name, ":"); colon > 0 {
+				name = name[:colon]
+			}
+
+			c.byName[strings.ToLower(name)] = true
+		}
+	}
+
+	c.findWrappers()
+	for _, file := range pass.Files {
+		c.stringers = nil
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				c.recordStringer(n)
+			case *ast.CallExpr:
+				c.checkCall(n)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// A wrapperCandidate is a function of the package whose signature
+// permits it to be a print or printf wrapper.
+type wrapperCandidate struct {
+	fn     *types.Func
+	body   *ast.BlockStmt
+	format *types.Var // the format parameter, or nil
+	args   *types.Var // the ...interface{} parameter
+}
+
+// findWrappers finds the print and printf wrappers of the package and
+// exports a fact for each of them. A function may wrap another wrapper
+// of the same package, so the search iterates until no new wrapper is
+// found.
+func (c *checker) findWrappers() {
+	var candidates []*wrapperCandidate
+	for _, file := range c.pass.Files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Body == nil {
+				continue
+			}
+			fn, ok := c.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			sig := fn.Type().(*types.Signature)
+			if !sig.Variadic() {
+				continue
+			}
+			params := sig.Params()
+			args := params.At(params.Len() - 1)
+			if !isEmptyInterfaceSlice(args.Type()) {
+				continue
+			}
+			var format *types.Var
+			if params.Len() >= 2 {
+				if p := params.At(params.Len() - 2); types.Identical(p.Type(), types.Typ[types.String]) {
+					format = p
+				}
+			}
+			candidates = append(candidates, &wrapperCandidate{fn, decl.Body, format, args})
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, w := range candidates {
+			if c.wrappers[w.fn] != kindNone {
+				continue
+			}
+			if kind := c.wrapperKind(w); kind != kindNone {
+				c.wrappers[w.fn] = kind
+				changed = true
+			}
+		}
+	}
+
+	for fn, kind := range c.wrappers {
+		c.pass.ExportObjectFact(fn, &isWrapper{Kind: kind})
+	}
+}
+
+// wrapperKind reports whether the candidate's body forwards its
+// arguments to a print or printf function.
+func (c *checker) wrapperKind(w *wrapperCandidate) funcKind {
+	uses := func(e ast.Expr, v *types.Var) bool {
+		id, ok := unparen(e).(*ast.Ident)
+		return ok && v != nil && c.pass.TypesInfo.Uses[id] == v
+	}
+	kind := kindNone
+	ast.Inspect(w.body, func(n ast.Node) bool {
+		if kind == kindPrintf {
+			return false // can't do better
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !call.Ellipsis.IsValid() {
+			return true
+		}
+		nargs := len(call.Args)
+		if !uses(call.Args[nargs-1], w.args) {
+			return true
+		}
+		switch callee, _ := c.printFuncKind(call); callee {
+		case kindPrintf:
+			if nargs >= 2 && uses(call.Args[nargs-2], w.format) {
+				kind = kindPrintf
+			}
+		case kindPrint:
+			kind = kindPrint
+		}
+		return true
+	})
+	return kind
+}
+
+// printFuncKind returns the kind of print function called by call,
+// and the function's name, for use in diagnostics.
+func (c *checker) printFuncKind(call *ast.CallExpr) (funcKind, string) {
+	info := c.pass.TypesInfo
+	var fn *types.Func
+	var name string // like pkg.Printf or pkg.Type.Printf, for lookup
+	switch x := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		if f, ok := info.Uses[x].(*types.Func); ok {
+			fn = f
+			if f.Pkg() != nil {
+				name = f.Pkg().Path() + "." + x.Name
+			}
+		}
+
+	case *ast.SelectorExpr:
+		// Check for "fmt.Printf".
+		if id, ok := x.X.(*ast.Ident); ok {
+			if pkgName, ok := info.Uses[id].(*types.PkgName); ok {
+				fn, _ = info.Uses[x.Sel].(*types.Func)
+				name = pkgName.Imported().Path() + "." + x.Sel.Name
+				break
+			}
+		}
+
+		// Check for t.Logf where t is a *testing.T.
+		if sel := info.Selections[x]; sel != nil {
+			fn, _ = sel.Obj().(*types.Func)
+			recv := sel.Recv()
+			if p, ok := recv.(*types.Pointer); ok {
+				recv = p.Elem()
+			}
+			if named, ok := recv.(*types.Named); ok {
+				obj := named.Obj()
+				if obj.Pkg() != nil {
+					name = obj.Pkg().Path() + "." + obj.Name() + "." + x.Sel.Name
+				}
+			}
+		}
+	}
+	if fn == nil {
+		return kindNone, ""
+	}
+	shortName := fn.Name()
+
+	if kind := c.wrappers[fn]; kind != kindNone {
+		return kind, shortName
+	}
+	var fact isWrapper
+	if c.pass.ImportObjectFact(fn, &fact) {
+		return fact.Kind, shortName
+	}
+
+	// Look up the full name, then just the short
+	// name, for use with -funcs.
+	if isPrint[name] || c.byName[strings.ToLower(shortName)] {
+		if strings.HasSuffix(shortName, "f") {
+			return kindPrintf, shortName
+		}
+		return kindPrint, shortName
+	}
+	return kindNone, ""
+}
+
+// checkCall triggers the print-specific checks if the call invokes a print function.
+func (c *checker) checkCall(call *ast.CallExpr) {
+	switch kind, name := c.printFuncKind(call); kind {
+	case kindPrintf:
+		c.checkPrintf(call, name)
+	case kindPrint:
+		c.checkPrint(call, name)
+	}
+}
+
+// recordStringer remembers the receiver of d if d is a String method.
+func (c *checker) recordStringer(d *ast.FuncDecl) {
+	if !c.isStringer(d) {
+		return
+	}
+	if c.stringers == nil {
+		c.stringers = make(map[*ast.Object]bool)
+	}
+	if l := d.Recv.List; len(l) == 1 {
+		if n := l[0].Names; len(n) == 1 {
+			c.stringers[n[0].Obj] = true
+		}
+	}
+}
+
+// isStringer returns true if the provided declaration is a "String() string"
+// method, an implementation of fmt.Stringer.
+func (c *checker) isStringer(d *ast.FuncDecl) bool {
+	return d.Recv != nil && d.Name.Name == "String" && d.Type.Results != nil &&
+		len(d.Type.Params.List) == 0 && len(d.Type.Results.List) == 1 &&
+		c.pass.TypesInfo.Types[d.Type.Results.List[0].Type].Type == types.Typ[types.String]
+}
+
+// formatString returns the format string argument and its index within
+// the given printf-like call expression.
+//
+// The last parameter before variadic arguments is assumed to be
+// a format string.
+//
+// The first string literal or string constant is assumed to be a format string
+// if the call's signature cannot be determined.
+//
+// If it cannot find any format string parameter, it returns ("", -1).
+func (c *checker) formatString(call *ast.CallExpr) (format string, idx int) {
+	typ := c.pass.TypesInfo.Types[call.Fun].Type
+	if typ != nil {
+		if sig, ok := typ.(*types.Signature); ok {
+			if !sig.Variadic() {
+				// Skip checking non-variadic functions.
+				return "", -1
+			}
+			idx := sig.Params().Len() - 2
+			if idx < 0 {
+				// Skip checking variadic functions without
+				// fixed arguments.
+				return "", -1
+			}
+			s, ok := c.stringConstantArg(call, idx)
+			if !ok {
+				// The last argument before variadic args isn't a string.
+				return "", -1
+			}
+			return s, idx
+		}
+	}
+
+	// Cannot determine call's signature. Fall back to scanning for the first
+	// string constant in the call.
+	for idx := range call.Args {
+		if s, ok := c.stringConstantArg(call, idx); ok {
+			return s, idx
+		}
+		if c.pass.TypesInfo.Types[call.Args[idx]].Type == types.Typ[types.String] {
+			// Skip checking a call with a non-constant format
+			// string argument, since its contents are unavailable
+			// for validation.
+			return "", -1
+		}
+	}
+	return "", -1
+}
+
+// stringConstantArg returns call's string constant argument at the index idx.
+//
+// ("", false) is returned if call's argument at the index idx isn't a string
+// constant.
+func (c *checker) stringConstantArg(call *ast.CallExpr, idx int) (string, bool) {
+	if idx >= len(call.Args) {
+		return "", false
+	}
+	arg := call.Args[idx]
+	lit := c.pass.TypesInfo.Types[arg].Value
+	if lit != nil && lit.Kind() == constant.String {
+		return constant.StringVal(lit), true
+	}
+	return "", false
+}
+
+// formatState holds the parsed representation of a printf directive such as "%3.*[4]d".
+// It is constructed by parsePrintfVerb.
+type formatState struct {
+	verb     rune   // the format verb: 'd' for "%d"
+	format   string // the full format directive from % through verb, "%.3d".
+	name     string // Printf, Sprintf etc.
+	flags    []byte // the list of # + etc.
+	argNums  []int  // the successive argument numbers that are consumed, adjusted to refer to actual arg in call
+	firstArg int    // Index of first argument after the format in the Printf call.
+	// Used only during parse.
+	checker      *checker
+	call         *ast.CallExpr
+	argNum       int  // Which argument we're expecting to format now.
+	indexPending bool // Whether we have an indexed argument that has not resolved.
+	nbytes       int  // number of bytes of the format string consumed.
+}
+
+// checkPrintf checks a call to a formatted print routine such as Printf.
+func (c *checker) checkPrintf(call *ast.CallExpr, name string) {
+	format, idx := c.formatString(call)
+	if idx < 0 {
+		return
+	}
+
+	firstArg := idx + 1 // Arguments are immediately after format string.
+	if !strings.Contains(format, "%") {
+		if len(call.Args) > firstArg {
+			c.pass.Reportf(call.Pos(), "%s call has arguments but no formatting directives", name)
+		}
+		return
+	}
+	// Hard part: check formats against args.
+	argNum := firstArg
+	maxArgNum := firstArg
+	anyW := false
+	for i, w := 0, 0; i < len(format); i += w {
+		w = 1
+		if format[i] != '%' {
+			continue
+		}
+		state := c.parsePrintfVerb(call, name, format[i:], firstArg, argNum)
+		if state == nil {
+			return
+		}
+		w = len(state.format)
+		if !c.okPrintfArg(call, state) { // One error per format is enough.
+			return
+		}
+		if state.verb == 'w' {
+			if name != "Errorf" {
+				c.pass.Reportf(call.Pos(), "%s call has error-wrapping directive %%w", name)
+				return
+			}
+			if anyW {
+				c.pass.Reportf(call.Pos(), "%s call has more than one error-wrapping directive %%w", name)
+				return
+			}
+			anyW = true
+		}
+		if len(state.argNums) > 0 {
+			// Continue with the next sequential argument.
+			argNum = state.argNums[len(state.argNums)-1] + 1
+		}
+		for _, n := range state.argNums {
+			if n >= maxArgNum {
+				maxArgNum = n + 1
+			}
+		}
+	}
+	// Dotdotdot is hard.
+	if call.Ellipsis.IsValid() && maxArgNum >= len(call.Args)-1 {
+		return
+	}
+	// There should be no leftover arguments.
+	if maxArgNum != len(call.Args) {
+		expect := maxArgNum - firstArg
+		numArgs := len(call.Args) - firstArg
+		c.pass.Reportf(call.Pos(), "%s call needs %v but has %v", name, count(expect, "arg"), count(numArgs, "arg"))
+	}
+}
+
+// parseFlags accepts any printf flags.
+func (s *formatState) parseFlags() {
+	for s.nbytes < len(s.format) {
+		switch c := s.format[s.nbytes]; c {
+		case '#', '0', '+', '-', ' ', '@':
+			s.flags = append(s.flags, c)
+			s.nbytes++
+		default:
+			return
+		}
+	}
+}
+
+// scanNum advances through a decimal number if present.
+func (s *formatState) scanNum() {
+	for ; s.nbytes < len(s.format); s.nbytes++ {
+		c := s.format[s.nbytes]
+		if c < '0' || '9' < c {
+			return
+		}
+	}
+}
+
+// parseIndex scans an index expression. It returns false if there is a syntax error.
+func (s *formatState) parseIndex() bool {
+	if s.nbytes == len(s.format) || s.format[s.nbytes] != '[' {
+		return true
+	}
+	// Argument index present.
+	s.nbytes++ // skip '['
+	start := s.nbytes
+	s.scanNum()
+	ok := true
+	if s.nbytes == len(s.format) || s.nbytes == start || s.format[s.nbytes] != ']' {
+		ok = false
+		s.nbytes = strings.Index(s.format, "]")
+		if s.nbytes < 0 {
+			s.checker.pass.Reportf(s.call.Pos(), "%s format %s is missing closing ]", s.name, s.format)
+			return false
+		}
+	}
+	arg32, err := strconv.ParseInt(s.format[start:s.nbytes], 10, 32)
+	if err != nil || !ok || arg32 <= 0 || arg32 > int64(len(s.call.Args)-s.firstArg) {
+		s.checker.pass.Reportf(s.call.Pos(), "%s format has invalid argument index [%s]", s.name, s.format[start:s.nbytes])
+		return false
+	}
+	s.nbytes++ // skip ']'
+	arg := int(arg32)
+	arg += s.firstArg - 1 // We want to zero-index the actual arguments.
+	s.argNum = arg
+	s.indexPending = true
+	return true
+}
+
+// parseNum scans a width or precision (or *). It returns false if there's a bad index expression.
+func (s *formatState) parseNum() bool {
+	if s.nbytes < len(s.format) && s.format[s.nbytes] == '*' {
+		if s.indexPending { // Absorb it.
+			s.indexPending = false
+		}
+		s.nbytes++
+		s.argNums = append(s.argNums, s.argNum)
+		s.argNum++
+	} else {
+		s.scanNum()
+	}
+	return true
+}
+
+// parsePrecision scans for a precision. It returns false if there's a bad index expression.
+func (s *formatState) parsePrecision() bool {
+	// If there's a period, there may be a precision.
+	if s.nbytes < len(s.format) && s.format[s.nbytes] == '.' {
+		s.flags = append(s.flags, '.') // Treat precision as a flag.
+		s.nbytes++
+		if !s.parseIndex() {
+			return false
+		}
+		if !s.parseNum() {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePrintfVerb looks the formatting directive that begins the format string
+// and returns a formatState that encodes what the directive wants, without looking
+// at the actual arguments present in the call. The result is nil if there is an error.
+func (c *checker) parsePrintfVerb(call *ast.CallExpr, name, format string, firstArg, argNum int) *formatState {
+	state := &formatState{
+		format:   format,
+		name:     name,
+		flags:    make([]byte, 0, 5),
+		argNum:   argNum,
+		argNums:  make([]int, 0, 1),
+		nbytes:   1, // There's guaranteed to be a percent sign.
+		firstArg: firstArg,
+		checker:  c,
+		call:     call,
+	}
+	// There may be flags.
+	state.parseFlags()
+	// There may be an index.
+	if !state.parseIndex() {
+		return nil
+	}
+	// There may be a width.
+	if !state.parseNum() {
+		return nil
+	}
+	// There may be a precision.
+	if !state.parsePrecision() {
+		return nil
+	}
+	// Now a verb, possibly prefixed by an index (which we may already have).
+	if !state.indexPending && !state.parseIndex() {
+		return nil
+	}
+	if state.nbytes == len(state.format) {
+		c.pass.Reportf(call.Pos(), "%s format %s is missing verb at end of string", name, state.format)
+		return nil
+	}
+	verb, w := utf8.DecodeRuneInString(state.format[state.nbytes:])
+	state.verb = verb
+	state.nbytes += w
+	if verb != '%' {
+		state.argNums = append(state.argNums, state.argNum)
+	}
+	state.format = state.format[:state.nbytes]
+	return state
+}
+
+// printfArgType encodes the types of expressions a printf verb accepts. It is a bitmask.
+type printfArgType int
+
+const (
+	argBool printfArgType = 1 << iota
+	argInt
+	argRune
+	argString
+	argFloat
+	argComplex
+	argPointer
+	argError
+	anyType printfArgType = ^0
+)
+
+type printVerb struct {
+	verb  rune   // User may provide verb through Formatter; could be a rune.
+	flags string // known flags are all ASCII
+	typ   printfArgType
+}
+
+// Common flag sets for printf verbs.
+const (
+	noFlag       = ""
+	numFlag      = " -+.0"
+	sharpNumFlag = " -+.0#"
+	allFlags     = " -+.0#@"
+)
+
+// printVerbs identifies which flags are known to printf for each verb.
+var printVerbs = []printVerb{
+	// '-' is a width modifier, always valid.
+	// '.' is a precision for float, max width for strings.
+	// '+' is required sign for numbers, Go format for %v.
+	// '#' is alternate format for several verbs.
+	// ' ' is spacer for numbers
+	// '@' print %v as pretty format(a indented-multi-lines style string)
+	{'%', noFlag, 0},
+	{'b', numFlag, argInt | argFloat | argComplex},
+	{'c', "-", argRune | argInt},
+	{'d', numFlag, argInt},
+	{'e', sharpNumFlag, argFloat | argComplex},
+	{'E', sharpNumFlag, argFloat | argComplex},
+	{'f', sharpNumFlag, argFloat | argComplex},
+	{'F', sharpNumFlag, argFloat | argComplex},
+	{'g', sharpNumFlag, argFloat | argComplex},
+	{'G', sharpNumFlag, argFloat | argComplex},
+	{'o', sharpNumFlag, argInt},
+	{'p', "-#", argPointer},
+	{'q', " -+.0#", argRune | argInt | argString},
+	{'s', " -+.0", argString},
+	{'t', "-", argBool},
+	{'T', "-", anyType},
+	{'U', "-#", argRune | argInt},
+	{'v', allFlags, anyType},
+	{'w', allFlags, argError},
+	{'x', sharpNumFlag, argRune | argInt | argString},
+	{'X', sharpNumFlag, argRune | argInt | argString},
+}
+
+// okPrintfArg compares the formatState to the arguments actually present,
+// reporting any discrepancies it can discern. If the final argument is ellipsissed,
+// there's little it can do for that.
+func (c *checker) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
+	var v printVerb
+	found := false
+	// Linear scan is fast enough for a small list.
+	for _, v = range printVerbs {
+		if v.verb == state.verb {
+			found = true
+			break
+		}
+	}
+
+	// Does current arg implement fmt.Formatter?
+	formatter := false
+	if state.argNum < len(call.Args) {
+		if tv, ok := c.pass.TypesInfo.Types[call.Args[state.argNum]]; ok {
+			formatter = isFormatter(tv.Type)
+		}
+	}
+
+	if !formatter {
+		if !found {
+			c.pass.Reportf(call.Pos(), "%s format %s has unknown verb %c", state.name, state.format, state.verb)
+			return false
+		}
+		for _, flag := range state.flags {
+			if !strings.ContainsRune(v.flags, rune(flag)) {
+				c.pass.Reportf(call.Pos(), "%s format %s has unrecognized flag %c", state.name, state.format, flag)
+				return false
+			}
+		}
+	}
+	// Verb is good. If len(state.argNums)>trueArgs, we have something like %.*s and all
+	// but the final arg must be an integer.
+	trueArgs := 1
+	if state.verb == '%' {
+		trueArgs = 0
+	}
+	nargs := len(state.argNums)
+	for i := 0; i < nargs-trueArgs; i++ {
+		argNum := state.argNums[i]
+		if !c.argCanBeChecked(call, i, state) {
+			return
+		}
+		arg := call.Args[argNum]
+		if !c.matchArgType(argInt, nil, arg) {
+			c.pass.Reportf(call.Pos(), "%s format %s uses non-int %s as argument of *", state.name, state.format, c.gofmt(arg))
+			return false
+		}
+	}
+	if state.verb == '%' || formatter {
+		return true
+	}
+	argNum := state.argNums[len(state.argNums)-1]
+	if !c.argCanBeChecked(call, len(state.argNums)-1, state) {
+		return false
+	}
+	arg := call.Args[argNum]
+	if c.isFunctionValue(arg) && state.verb != 'p' && state.verb != 'T' {
+		c.pass.Reportf(call.Pos(), "%s format %s arg %s is a func value, not called", state.name, state.format, c.gofmt(arg))
+		return false
+	}
+	if !c.matchArgType(v.typ, nil, arg) {
+		typeString := ""
+		if typ := c.pass.TypesInfo.Types[arg].Type; typ != nil {
+			typeString = typ.String()
+		}
+		c.pass.Reportf(call.Pos(), "%s format %s has arg %s of wrong type %s", state.name, state.format, c.gofmt(arg), typeString)
+		return false
+	}
+	if v.typ&argString != 0 && v.verb != 'T' && !bytes.Contains(state.flags, []byte{'#'}) && c.recursiveStringer(arg) {
+		c.pass.Reportf(call.Pos(), "%s format %s with arg %s causes recursive String method call", state.name, state.format, c.gofmt(arg))
+		return false
+	}
+	return true
+}
+
+// recursiveStringer reports whether the provided argument is r or &r for the
+// fmt.Stringer receiver identifier r.
+func (c *checker) recursiveStringer(e ast.Expr) bool {
+	if len(c.stringers) == 0 {
+		return false
+	}
+	var obj *ast.Object
+	switch e := e.(type) {
+	case *ast.Ident:
+		obj = e.Obj
+	case *ast.UnaryExpr:
+		if id, ok := e.X.(*ast.Ident); ok && e.Op == token.AND {
+			obj = id.Obj
+		}
+	}
+
+	// It's unlikely to be a recursive stringer if it has a Format method.
+	if typ := c.pass.TypesInfo.Types[e].Type; typ != nil {
+		if c.hasMethod(typ, "Format") {
+			return false
+		}
+	}
+
+	// We compare the underlying Object, which checks that the identifier
+	// is the one we declared as the receiver for the String method in
+	// which this printf appears.
+	return c.stringers[obj]
+}
+
+// isFunctionValue reports whether the expression is a function as opposed to a function call.
+// It is almost always a mistake to print a function value.
+func (c *checker) isFunctionValue(e ast.Expr) bool {
+	if typ := c.pass.TypesInfo.Types[e].Type; typ != nil {
+		_, ok := typ.(*types.Signature)
+		return ok
+	}
+	return false
+}
+
+// argCanBeChecked reports whether the specified argument is statically present;
+// it may be beyond the list of arguments or in a terminal slice... argument, which
+// means we can't see it.
+func (c *checker) argCanBeChecked(call *ast.CallExpr, formatArg int, state *formatState) bool {
+	argNum := state.argNums[formatArg]
+	if argNum <= 0 {
+		// Shouldn't happen, so catch it with prejudice.
+		panic("negative arg num")
+	}
+	if argNum < len(call.Args)-1 {
+		return true // Always OK.
+	}
+	if call.Ellipsis.IsValid() {
+		return false // We just can't tell; there could be many more arguments.
+	}
+	if argNum < len(call.Args) {
+		return true
+	}
+	// There are bad indexes in the format or there are fewer arguments than the format needs.
+	// This is the argument number relative to the format: Printf("%s", "hi") will give 1 for the "hi".
+	arg := argNum - state.firstArg + 1 // People think of arguments as 1-indexed.
+	c.pass.Reportf(call.Pos(), "%s format %s reads arg #%d, but call has only %v", state.name, state.format, arg, count(len(call.Args)-state.firstArg, "arg"))
+	return false
+}
+
+// printFormatRE is the regexp we match and report as a possible format string
+// in the first argument to unformatted prints like fmt.Print.
+// We exclude the space flag, so that printing a string like "x % y" is not reported as a format.
+var printFormatRE = regexp.MustCompile(`%` + flagsRE + numOptRE + `\.?` + numOptRE + indexOptRE + verbRE)
+
+const (
+	flagsRE    = `[+\-#@]*`
+	indexOptRE = `(\[[0-9]+\])?`
+	numOptRE   = `([0-9]+|` + indexOptRE + `\*)?`
+	verbRE     = `[bcdefgopqstvxEFGUX]`
+)
+
+// checkPrint checks a call to an unformatted print routine such as Println.
+func (c *checker) checkPrint(call *ast.CallExpr, name string) {
+	firstArg := 0
+	typ := c.pass.TypesInfo.Types[call.Fun].Type
+	if typ == nil {
+		// Skip checking functions with unknown type.
+		return
+	}
+	if sig, ok := typ.(*types.Signature); ok {
+		if !sig.Variadic() {
+			// Skip checking non-variadic functions.
+			return
+		}
+		params := sig.Params()
+		firstArg = params.Len() - 1
+
+		if !isEmptyInterfaceSlice(params.At(firstArg).Type()) {
+			// Skip variadic functions accepting non-interface{} args.
+			return
+		}
+	}
+	args := call.Args
+	if len(args) <= firstArg {
+		// Skip calls without variadic args.
+		return
+	}
+	args = args[firstArg:]
+
+	if firstArg == 0 {
+		if sel, ok := call.Args[0].(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok {
+				if x.Name == "os" && strings.HasPrefix(sel.Sel.Name, "Std") {
+					c.pass.Reportf(call.Pos(), "%s does not take io.Writer but has first arg %s", name, c.gofmt(call.Args[0]))
+				}
+			}
+		}
+	}
+
+	arg := args[0]
+	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		// Ignore trailing % character in lit.Value.
+		// The % in "abc 0.0%" couldn't be a formatting directive.
+		s := strings.TrimSuffix(lit.Value, `%"`)
+		if strings.Contains(s, "%") {
+			m := printFormatRE.FindStringSubmatch(s)
+			if m != nil {
+				c.pass.Reportf(call.Pos(), "%s call has possible formatting directive %s", name, m[0])
+			}
+		}
+	}
+	if strings.HasSuffix(name, "ln") {
+		// The last item, if a string, should not have a newline.
+		arg = args[len(args)-1]
+		if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			str, _ := strconv.Unquote(lit.Value)
+			if strings.HasSuffix(str, "\n") {
+				c.pass.Reportf(call.Pos(), "%s arg list ends with redundant newline", name)
+			}
+		}
+	}
+	for _, arg := range args {
+		if c.isFunctionValue(arg) {
+			c.pass.Reportf(call.Pos(), "%s arg %s is a func value, not called", name, c.gofmt(arg))
+		}
+		if c.recursiveStringer(arg) {
+			c.pass.Reportf(call.Pos(), "%s arg %s causes recursive call to String method", name, c.gofmt(arg))
+		}
+	}
+}
+
+// gofmt returns a string representation of the expression.
+func (c *checker) gofmt(x ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, c.pass.Fset, x)
+	return buf.String()
+}
+
+// count(n, what) returns "1 what" or "N whats"
+// (assuming the plural of what is whats).
+func count(n int, what string) string {
+	if n == 1 {
+		return "1 " + what
+	}
+	return fmt.Sprintf("%d %ss", n, what)
+}
+
+// isEmptyInterfaceSlice reports whether t is []interface{}.
+func isEmptyInterfaceSlice(t types.Type) bool {
+	s, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	it, ok := s.Elem().(*types.Interface)
+	return ok && it.Empty()
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
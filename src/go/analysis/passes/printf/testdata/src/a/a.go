@@ -0,0 +1,65 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the printf checker.
+
+package a
+
+import (
+	"b"
+	"fmt"
+	"os"
+)
+
+type ptrStringer int
+
+func (p *ptrStringer) String() string {
+	return fmt.Sprintf("%s", p) // want `Sprintf format %s with arg p causes recursive String method call`
+}
+
+func PrintfTests() {
+	var i int
+	var s string
+	fmt.Printf("%d %s", i, s)
+	fmt.Printf("%d", s)                 // want `Printf format %d has arg s of wrong type string`
+	fmt.Printf("%s", i)                 // want `Printf format %s has arg i of wrong type int`
+	fmt.Printf("%d %d", i)              // want `Printf format %d reads arg #2, but call has only 1 arg`
+	fmt.Printf("no directives", i)      // want `Printf call has arguments but no formatting directives`
+	fmt.Printf("%z", i)                 // want `Printf format %z has unknown verb z`
+	fmt.Printf("%d", PrintfTests)       // want `Printf format %d arg PrintfTests is a func value, not called`
+	fmt.Println("trailing newline\n")   // want `Println arg list ends with redundant newline`
+	fmt.Print("%d", i)                  // want `Print call has possible formatting directive %d`
+	fmt.Println(os.Stderr, "to stderr") // want `Println does not take io.Writer but has first arg os.Stderr`
+	_ = fmt.Errorf("%w", i)             // want `Errorf format %w has arg i of wrong type int`
+	fmt.Printf("%w", fmt.Errorf("x"))   // want `Printf call has error-wrapping directive %w`
+	fmt.Printf("%[3]d", i, i)           // want `Printf format has invalid argument index \[3\]`
+	fmt.Printf("%@v", i)
+
+	// Wrappers in another package.
+	b.Wrapf("%s", i)            // want `Wrapf format %s has arg i of wrong type int`
+	b.Wrap("%s", i)             // want `Wrap call has possible formatting directive %s`
+	new(b.Logger).Logf("%d", s) // want `Logf format %d has arg s of wrong type string`
+
+	// Wrappers in this package.
+	wrapf("%d", s) // want `wrapf format %d has arg s of wrong type string`
+	wrapf2("%d", i)
+	wrapf2("%d", s) // want `wrapf2 format %d has arg s of wrong type string`
+
+	// Functions named by -funcs.
+	Warnf("%d", s) // want `Warnf format %d has arg s of wrong type string`
+	Warn("%d", i)  // want `Warn call has possible formatting directive %d`
+}
+
+func wrapf(format string, args ...interface{}) { // want wrapf:"printfWrapper"
+	b.Wrapf(format, args...)
+}
+
+// wrapf2 wraps wrapf, declared earlier in the file.
+func wrapf2(format string, args ...interface{}) { // want wrapf2:"printfWrapper"
+	wrapf(format, args...)
+}
+
+func Warnf(format string, args ...interface{}) {}
+
+func Warn(args ...interface{}) {}
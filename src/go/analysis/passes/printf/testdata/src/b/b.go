@@ -0,0 +1,36 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the printf checker.
+
+package b
+
+import "fmt"
+
+// Wrapf is a printf wrapper.
+func Wrapf(format string, args ...interface{}) { // want Wrapf:"printfWrapper"
+	fmt.Printf(format, args...)
+}
+
+// Wrap is a print wrapper.
+func Wrap(args ...interface{}) { // want Wrap:"printWrapper"
+	fmt.Print(args...)
+}
+
+// Logger has a printf wrapper method that wraps another wrapper.
+type Logger struct{}
+
+func (l *Logger) Logf(format string, args ...interface{}) { // want Logf:"printfWrapper"
+	Wrapf(format, args...)
+}
+
+// notWrapper does not pass its format on.
+func notWrapper(format string, args ...interface{}) {
+	fmt.Printf("%s", args...)
+}
+
+func _() {
+	Wrapf("%d", "hi") // want "Wrapf format %d has arg \"hi\" of wrong type string"
+	notWrapper("%d", "hi")
+}
@@ -0,0 +1,317 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package unitchecker defines the main function for an analysis
+// driver that analyzes a single compilation unit during a build.
+// It is invoked by a build system such as "go vet":
+//
+//	$ go vet -vettool=$(which vet)
+//
+// It supports the following command-line protocol:
+//
+//	-flags          describe flags                  (to the build tool)
+//	foo.cfg         description of compilation unit (from the build tool)
+//
+// If you need a standalone tool that also accepts package patterns,
+// use multichecker, which supports this mode too.
+package unitchecker
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go/analysis"
+	"go/analysis/internal/analysisflags"
+	"go/analysis/internal/checker"
+	"go/analysis/internal/facts"
+)
+
+// A Config describes a compilation unit to be analyzed.
+// It is provided to the tool in a JSON-encoded file
+// whose name ends with ".cfg".
+type Config struct {
+	Compiler                  string
+	Dir                       string
+	ImportPath                string
+	GoFiles                   []string
+	NonGoFiles                []string
+	ImportMap                 map[string]string
+	PackageFile               map[string]string
+	PackageVetx               map[string]string
+	VetxOnly                  bool
+	VetxOutput                string
+	SucceedOnTypecheckFailure bool
+}
+
+// Main is the main function of a vet-like analysis tool that must be
+// invoked by a build system to analyze a single package.
+//
+// The protocol required by 'go vet -vettool=...' is that the tool must support:
+//
+//	-flags          describe flags in JSON
+//	foo.cfg         perform separate modular analysis on the single
+//	                unit described by a JSON config file foo.cfg.
+func Main(analyzers ...*analysis.Analyzer) {
+	progname := filepath.Base(os.Args[0])
+	log.SetFlags(0)
+	log.SetPrefix(progname + ": ")
+
+	if err := analysis.Validate(analyzers); err != nil {
+		log.Fatal(err)
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `%[1]s is a tool for static analysis of Go programs.
+
+Usage of %[1]s:
+	%.16[1]s unit.cfg	# execute analysis specified by config file
+	%.16[1]s help    	# general help
+	%.16[1]s help name	# help on specific analyzer and its flags
+`, progname)
+		os.Exit(1)
+	}
+
+	analyzers = analysisflags.Parse(analyzers, true)
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+	}
+	if args[0] == "help" {
+		help(progname, analyzers, args[1:])
+		os.Exit(0)
+	}
+	if len(args) != 1 || !strings.HasSuffix(args[0], ".cfg") {
+		log.Fatalf(`invoking %s directly is unsupported; use "go vet -vettool"`, progname)
+	}
+	Run(args[0], analyzers)
+}
+
+// help prints the documentation of the named analyzers,
+// or a summary of all of them.
+func help(progname string, analyzers []*analysis.Analyzer, names []string) {
+	if len(names) == 0 {
+		fmt.Printf("%s is a tool for static analysis of Go programs.\n\n", progname)
+		fmt.Println("Registered analyzers:")
+		fmt.Println()
+		sort.Slice(analyzers, func(i, j int) bool {
+			return analyzers[i].Name < analyzers[j].Name
+		})
+		for _, a := range analyzers {
+			title := strings.Split(a.Doc, "\n\n")[0]
+			fmt.Printf("    %-12s %s\n", a.Name, title)
+		}
+		fmt.Printf("\nTo see details and flags of a specific analyzer, run '%s help name'.\n", progname)
+		return
+	}
+
+outer:
+	for _, name := range names {
+		for _, a := range analyzers {
+			if a.Name == name {
+				fmt.Printf("%s: %s\n", a.Name, a.Doc)
+				a.Flags.VisitAll(func(f *flag.Flag) {
+					fmt.Printf("  -%s.%s\n    \t%s\n", a.Name, f.Name, f.Usage)
+				})
+				continue outer
+			}
+		}
+		log.Fatalf("Analyzer %q not registered", name)
+	}
+}
+
+// Run reads the *.cfg file, runs the analysis,
+// and calls os.Exit with an appropriate error code.
+// It assumes flags have already been set.
+func Run(configFile string, analyzers []*analysis.Analyzer) {
+	cfg, err := readConfig(configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	results, err := run(fset, cfg, analyzers)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// In VetxOnly mode, the analysis is run only for facts.
+	if cfg.VetxOnly {
+		os.Exit(0)
+	}
+
+	code := 0
+	if analysisflags.JSON {
+		tree := make(analysisflags.JSONTree)
+		for _, res := range results {
+			tree.Add(fset, cfg.ImportPath, res.Analyzer.Name, res.Diagnostics, res.Err)
+		}
+		tree.Print(os.Stdout)
+	} else {
+		var all []analysis.Diagnostic
+		for _, res := range results {
+			if res.Err != nil {
+				log.Println(res.Err)
+				code = 1
+				continue
+			}
+			for _, diag := range res.Diagnostics {
+				analysisflags.PrintPlain(fset, diag)
+				code = 1
+			}
+			all = append(all, res.Diagnostics...)
+		}
+		if analysisflags.Fix {
+			if err := applyFixes(fset, all); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	os.Exit(code)
+}
+
+func readConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(Config)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("cannot decode JSON config file %s: %v", filename, err)
+	}
+	if len(cfg.GoFiles) == 0 {
+		// The go command disallows packages with no files.
+		// The only exception is unsafe, but the go command
+		// doesn't call vet on it.
+		return nil, fmt.Errorf("package has no files: %s", cfg.ImportPath)
+	}
+	return cfg, nil
+}
+
+func run(fset *token.FileSet, cfg *Config, analyzers []*analysis.Analyzer) ([]*checker.Action, error) {
+	// Load, parse, typecheck.
+	var files []*ast.File
+	for _, name := range cfg.GoFiles {
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			if cfg.SucceedOnTypecheckFailure {
+				// Silently succeed; let the compiler
+				// report parse errors.
+				return nil, writeVetx(cfg, nil)
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	compilerImporter := importer.For(cfg.Compiler, func(path string) (io.ReadCloser, error) {
+		// path is a resolved package path, not an import path.
+		file, ok := cfg.PackageFile[path]
+		if !ok {
+			return nil, fmt.Errorf("no package file for %q", path)
+		}
+		return os.Open(file)
+	})
+	imp := importerFunc(func(importPath string) (*types.Package, error) {
+		if importPath == "unsafe" {
+			return compilerImporter.Import("unsafe")
+		}
+		path, ok := cfg.ImportMap[importPath] // resolve vendoring, etc
+		if !ok {
+			return nil, fmt.Errorf("can't resolve import %q", importPath)
+		}
+		return compilerImporter.Import(path)
+	})
+	tc := &types.Config{
+		Importer: imp,
+		Sizes:    types.SizesFor(cfg.Compiler, build.Default.GOARCH),
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	pkg, err := tc.Check(cfg.ImportPath, fset, files, info)
+	if err != nil {
+		if cfg.SucceedOnTypecheckFailure {
+			// Silently succeed; let the compiler
+			// report type errors.
+			return nil, writeVetx(cfg, nil)
+		}
+		return nil, err
+	}
+
+	// Read facts from the dependencies' vetx files. A missing
+	// entry means the dependency has no facts, for example
+	// because it was not analyzed by this tool.
+	facts.RegisterTypes(analyzers)
+	factSet, err := facts.Decode(pkg, func(path string) ([]byte, error) {
+		file, ok := cfg.PackageVetx[path]
+		if !ok {
+			return nil, nil
+		}
+		return ioutil.ReadFile(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := checker.Run(&checker.Package{
+		Fset:       fset,
+		Files:      files,
+		OtherFiles: cfg.NonGoFiles,
+		Types:      pkg,
+		TypesInfo:  info,
+		TypesSizes: tc.Sizes,
+	}, analyzers, factSet, cfg.VetxOnly)
+
+	return results, writeVetx(cfg, factSet.Encode())
+}
+
+// writeVetx writes the encoded facts to the VetxOutput file,
+// if the config requests one.
+func writeVetx(cfg *Config, data []byte) error {
+	if cfg.VetxOutput == "" {
+		return nil
+	}
+	if err := ioutil.WriteFile(cfg.VetxOutput, data, 0666); err != nil {
+		return fmt.Errorf("failed to write analysis facts: %v", err)
+	}
+	return nil
+}
+
+// applyFixes rewrites the files changed by the suggested fixes
+// of the diagnostics.
+func applyFixes(fset *token.FileSet, diags []analysis.Diagnostic) error {
+	edits, err := checker.Edits(fset, diags)
+	if err != nil {
+		return err
+	}
+	for name, content := range edits {
+		if err := ioutil.WriteFile(name, content, 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
@@ -418,13 +418,25 @@ type Package struct {
 	Imports   []string                    // import paths from GoFiles, CgoFiles
 	ImportPos map[string][]token.Position // line information for Imports
 
+	// //go:embed patterns found in Go source files.
+	// For example, if a source file says
+	//	//go:embed a* b.c
+	// then the list will contain those two strings as separate entries.
+	// (See package embed for more details about //go:embed.)
+	EmbedPatterns   []string                    // patterns from GoFiles, CgoFiles
+	EmbedPatternPos map[string][]token.Position // line information for EmbedPatterns
+
 	// Test information
-	TestGoFiles    []string                    // _test.go files in package
-	TestImports    []string                    // import paths from TestGoFiles
-	TestImportPos  map[string][]token.Position // line information for TestImports
-	XTestGoFiles   []string                    // _test.go files outside package
-	XTestImports   []string                    // import paths from XTestGoFiles
-	XTestImportPos map[string][]token.Position // line information for XTestImports
+	TestGoFiles          []string                    // _test.go files in package
+	TestImports          []string                    // import paths from TestGoFiles
+	TestImportPos        map[string][]token.Position // line information for TestImports
+	TestEmbedPatterns    []string                    // patterns from TestGoFiles
+	TestEmbedPatternPos  map[string][]token.Position // line information for TestEmbedPatterns
+	XTestGoFiles         []string                    // _test.go files outside package
+	XTestImports         []string                    // import paths from XTestGoFiles
+	XTestImportPos       map[string][]token.Position // line information for XTestImports
+	XTestEmbedPatterns   []string                    // patterns from XTestGoFiles
+	XTestEmbedPatternPos map[string][]token.Position // line information for XTestEmbedPatterns
 }
 
 // IsCommand reports whether the package is considered a
@@ -717,6 +729,9 @@ Found:
 	imported := make(map[string][]token.Position)
 	testImported := make(map[string][]token.Position)
 	xTestImported := make(map[string][]token.Position)
+	embedPos := make(map[string][]token.Position)
+	testEmbedPos := make(map[string][]token.Position)
+	xTestEmbedPos := make(map[string][]token.Position)
 	allTags := make(map[string]bool)
 	fset := token.NewFileSet()
 	for _, d := range dirs {
@@ -834,6 +849,7 @@ Found:
 
 		// Record imports and information about cgo.
 		isCgo := false
+		isEmbed := false
 		for _, decl := range pf.Decls {
 			d, ok := decl.(*ast.GenDecl)
 			if !ok {
@@ -856,6 +872,9 @@ Found:
 				} else {
 					imported[path] = append(imported[path], fset.Position(spec.Pos()))
 				}
+				if path == "embed" {
+					isEmbed = true
+				}
 				if path == "C" {
 					if isTest {
 						badFile(fmt.Errorf("use of cgo in test %s not supported", filename))
@@ -874,6 +893,22 @@ Found:
 				}
 			}
 		}
+		if isEmbed {
+			embeds, err := ctxt.readGoEmbed(fset, filename)
+			if err != nil {
+				badFile(err)
+				continue
+			}
+			for _, e := range embeds {
+				if isXTest {
+					xTestEmbedPos[e.pattern] = append(xTestEmbedPos[e.pattern], e.pos)
+				} else if isTest {
+					testEmbedPos[e.pattern] = append(testEmbedPos[e.pattern], e.pos)
+				} else {
+					embedPos[e.pattern] = append(embedPos[e.pattern], e.pos)
+				}
+			}
+		}
 		if isCgo {
 			allTags["cgo"] = true
 			if ctxt.CgoEnabled {
@@ -904,6 +939,15 @@ Found:
 	p.Imports, p.ImportPos = cleanImports(imported)
 	p.TestImports, p.TestImportPos = cleanImports(testImported)
 	p.XTestImports, p.XTestImportPos = cleanImports(xTestImported)
+	if len(embedPos) > 0 {
+		p.EmbedPatterns, p.EmbedPatternPos = cleanImports(embedPos)
+	}
+	if len(testEmbedPos) > 0 {
+		p.TestEmbedPatterns, p.TestEmbedPatternPos = cleanImports(testEmbedPos)
+	}
+	if len(xTestEmbedPos) > 0 {
+		p.XTestEmbedPatterns, p.XTestEmbedPatternPos = cleanImports(xTestEmbedPos)
+	}
 
 	// add the .S files only if we are using cgo
 	// (which means gcc will compile them).
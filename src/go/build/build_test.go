@@ -5,6 +5,7 @@
 package build
 
 import (
+	"go/token"
 	"internal/testenv"
 	"io"
 	"os"
@@ -109,6 +110,30 @@ func TestMultiplePackageImport(t *testing.T) {
 	}
 }
 
+func TestImportEmbed(t *testing.T) {
+	p, err := ImportDir("testdata/embed", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, got []string, pos map[string][]token.Position, want []string) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+		for _, pattern := range got {
+			if len(pos[pattern]) == 0 {
+				t.Errorf("%s: no position recorded for %q", name, pattern)
+			}
+		}
+	}
+	check("EmbedPatterns", p.EmbedPatterns, p.EmbedPatternPos, []string{"x", "y", "z"})
+	check("TestEmbedPatterns", p.TestEmbedPatterns, p.TestEmbedPatternPos, []string{"t"})
+	check("XTestEmbedPatterns", p.XTestEmbedPatterns, p.XTestEmbedPatternPos, []string{"x"})
+	if pos := p.EmbedPatternPos["z"][0]; filepath.Base(pos.Filename) != "embed.go" || pos.Line != 8 || pos.Column != 12 {
+		t.Errorf("EmbedPatternPos[z] = %v, want embed.go:8:12", pos)
+	}
+}
+
 func TestLocalDirectory(t *testing.T) {
 	if runtime.GOOS == "darwin" {
 		switch runtime.GOARCH {
@@ -34,9 +34,10 @@ import (
 //
 var pkgDeps = map[string][]string{
 	// L0 is the lowest level, core, nearly unavoidable packages.
-	"errors":                  {},
+	"errors":                  {"runtime", "internal/reflectlite"},
 	"io":                      {"errors", "sync", "sync/atomic"},
 	"runtime":                 {"unsafe", "runtime/internal/atomic", "runtime/internal/sys"},
+	"internal/reflectlite":    {"runtime", "unsafe"},
 	"runtime/internal/sys":    {},
 	"runtime/internal/atomic": {"unsafe", "runtime/internal/sys"},
 	"internal/race":           {"runtime", "unsafe"},
@@ -47,6 +48,7 @@ var pkgDeps = map[string][]string{
 
 	"L0": {
 		"errors",
+		"internal/reflectlite",
 		"io",
 		"runtime",
 		"runtime/internal/atomic",
@@ -137,7 +139,8 @@ var pkgDeps = map[string][]string{
 	// End of linear dependency definitions.
 
 	// Operating system access.
-	"syscall":                           {"L0", "internal/race", "internal/syscall/windows/sysdll", "unicode/utf16"},
+	"internal/oserror":                  {"errors"},
+	"syscall":                           {"L0", "internal/oserror", "internal/race", "internal/syscall/windows/sysdll", "unicode/utf16"},
 	"internal/syscall/unix":             {"L0", "syscall"},
 	"internal/syscall/windows":          {"L0", "syscall", "internal/syscall/windows/sysdll"},
 	"internal/syscall/windows/registry": {"L0", "syscall", "internal/syscall/windows/sysdll", "unicode/utf16"},
@@ -156,7 +159,9 @@ var pkgDeps = map[string][]string{
 
 	"internal/poll":    {"L0", "internal/race", "syscall", "time", "unicode/utf16", "unicode/utf8", "internal/syscall/windows"},
 	"internal/testlog": {"L0"},
-	"os":               {"L1", "os", "syscall", "time", "internal/poll", "internal/syscall/windows", "internal/testlog"},
+	"io/fs":            {"L0", "internal/oserror", "path", "sort", "time", "unicode/utf8"},
+	"embed":            {"L0", "io/fs", "time"},
+	"os":               {"L1", "os", "io/fs", "syscall", "time", "internal/oserror", "internal/poll", "internal/syscall/windows", "internal/testlog"},
 	"path/filepath":    {"L2", "os", "syscall", "internal/syscall/windows"},
 	"io/ioutil":        {"L2", "os", "path/filepath", "time"},
 	"os/exec":          {"L2", "os", "context", "path/filepath", "syscall"},
@@ -165,6 +170,7 @@ var pkgDeps = map[string][]string{
 	// OS enables basic operating system functionality,
 	// but not direct use of package syscall, nor os/signal.
 	"OS": {
+		"io/fs",
 		"io/ioutil",
 		"os",
 		"os/exec",
@@ -173,18 +179,28 @@ var pkgDeps = map[string][]string{
 	},
 
 	// Formatted I/O: few dependencies (L1) but we must add reflect.
-	"fmt": {"L1", "os", "reflect"},
-	"log": {"L1", "os", "fmt", "time"},
+	"fmt":          {"L1", "os", "reflect"},
+	"log":          {"L1", "os", "fmt", "log/internal", "time"},
+	"log/internal": {},
 
 	// Packages used by testing must be low-level (L2+fmt).
 	"regexp":         {"L2", "regexp/syntax"},
 	"regexp/syntax":  {"L2"},
 	"runtime/debug":  {"L2", "fmt", "io/ioutil", "os", "time"},
 	"runtime/pprof":  {"L2", "compress/gzip", "context", "encoding/binary", "fmt", "io/ioutil", "os", "text/tabwriter", "time"},
-	"runtime/trace":  {"L0"},
+	"runtime/trace":  {"L0", "context", "fmt"},
 	"text/tabwriter": {"L2"},
 
-	"testing":          {"L2", "flag", "fmt", "internal/race", "os", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
+	// Coverage support for programs built with go build -cover.
+	"internal/coverage":       {"L2", "hash/fnv"},
+	"internal/coverage/rtcov": {"L0"},
+	"runtime/coverage":        {"L2", "fmt", "internal/coverage", "internal/coverage/rtcov", "io/ioutil", "os", "path/filepath", "time"},
+
+	// Runtime metrics, read without stopping the world.
+	"runtime/metrics": {"L0", "math"},
+
+	"testing":          {"L2", "flag", "fmt", "internal/race", "io/ioutil", "os", "path/filepath", "reflect", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
+	"testing/fstest":   {"L2", "io/fs", "time"},
 	"testing/iotest":   {"L2", "log"},
 	"testing/quick":    {"L2", "flag", "fmt", "reflect", "time"},
 	"internal/testenv": {"L2", "OS", "flag", "testing", "syscall"},
@@ -226,6 +242,23 @@ var pkgDeps = map[string][]string{
 	"go/internal/srcimporter":   {"L4", "fmt", "go/ast", "go/build", "go/parser", "go/token", "go/types", "path/filepath"},
 	"go/types":                  {"L4", "GOPARSER", "container/heap", "go/constant"},
 
+	// Modular static analysis.
+	"go/analysis":                        {"L4", "flag", "go/ast", "go/token", "go/types"},
+	"go/analysis/internal/facts":         {"L4", "OS", "encoding/gob", "go/analysis", "go/types"},
+	"go/analysis/internal/checker":       {"L4", "OS", "go/analysis", "go/analysis/internal/facts", "go/ast", "go/token", "go/types"},
+	"go/analysis/internal/analysisflags": {"L4", "OS", "encoding/json", "flag", "go/analysis", "go/token"},
+	"go/analysis/unitchecker": {
+		"L4", "OS", "encoding/json", "flag", "go/analysis", "go/analysis/internal/analysisflags",
+		"go/analysis/internal/checker", "go/analysis/internal/facts", "go/build", "go/importer", "GOPARSER", "go/types",
+	},
+	"go/analysis/multichecker": {"L4", "OS", "flag", "os/exec", "go/analysis", "go/analysis/internal/analysisflags", "go/analysis/unitchecker"},
+	"go/analysis/analysistest": {
+		"L4", "OS", "go/analysis", "go/analysis/internal/checker", "go/analysis/internal/facts",
+		"go/build", "go/format", "go/importer", "GOPARSER", "go/types", "regexp",
+	},
+	"go/analysis/passes/assign": {"L4", "GOPARSER", "go/analysis"},
+	"go/analysis/passes/printf": {"L4", "GOPARSER", "go/analysis", "go/constant", "go/types", "regexp"},
+
 	// One of a kind.
 	"archive/tar":              {"L4", "OS", "syscall", "os/user"},
 	"archive/zip":              {"L4", "OS", "compress/flate"},
@@ -271,7 +304,8 @@ var pkgDeps = map[string][]string{
 	"net/url":                  {"L4"},
 	"plugin":                   {"L0", "OS", "CGO"},
 	"runtime/pprof/internal/profile": {"L4", "OS", "compress/gzip", "regexp"},
-	"testing/internal/testdeps":      {"L4", "internal/testlog", "runtime/pprof", "regexp"},
+	"testing/internal/testdeps":      {"L4", "internal/fuzz", "internal/testlog", "runtime/pprof", "regexp"},
+	"internal/fuzz":                  {"L4", "OS", "crypto/sha256", "os/exec", "os/signal"},
 	"text/scanner":                   {"L4", "OS"},
 	"text/template/parse":            {"L4"},
 
@@ -322,6 +356,9 @@ var pkgDeps = map[string][]string{
 		"net/url",
 	},
 
+	// Structured logging.
+	"log/slog": {"L4", "OS", "context", "encoding", "encoding/json", "log/internal"},
+
 	// Uses of networking.
 	"log/syslog":    {"L4", "OS", "net"},
 	"net/mail":      {"L4", "NET", "OS", "mime"},
@@ -378,6 +415,7 @@ var pkgDeps = map[string][]string{
 	"crypto/tls": {
 		"L4", "CRYPTO-MATH", "OS",
 		"container/list", "crypto/x509", "encoding/pem", "net", "syscall",
+		"golang_org/x/crypto/cryptobyte",
 	},
 	"crypto/x509": {
 		"L4", "CRYPTO-MATH", "OS", "CGO",
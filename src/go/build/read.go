@@ -7,7 +7,14 @@ package build
 import (
 	"bufio"
 	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
 	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -245,3 +252,139 @@ func readImports(f io.Reader, reportSyntaxError bool, imports *[]string) ([]byte
 
 	return r.buf, r.err
 }
+
+// A fileEmbed is a single //go:embed pattern and its position.
+type fileEmbed struct {
+	pattern string
+	pos     token.Position
+}
+
+// readGoEmbed reads the named Go source file and returns the
+// patterns listed in its //go:embed directives.
+// Directives are only recognized in line comments starting at the
+// beginning of a line, so that text inside string literals or general
+// comments is never mistaken for one.
+func (ctxt *Context) readGoEmbed(fset *token.FileSet, filename string) ([]fileEmbed, error) {
+	f, err := ctxt.openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", filename, err)
+	}
+
+	var s scanner.Scanner
+	file := fset.AddFile(filename, -1, len(data))
+	s.Init(file, data, nil, scanner.ScanComments)
+
+	var embeds []fileEmbed
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT || !strings.HasPrefix(lit, "//go:embed") {
+			continue
+		}
+		args := lit[len("//go:embed"):]
+		if args == "" {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(args); !unicode.IsSpace(r) {
+			// Some other directive, like //go:embedded.
+			continue
+		}
+		start := fset.Position(pos)
+		if start.Column != 1 {
+			// Like the compiler, only recognize directives
+			// at the beginning of a line.
+			continue
+		}
+		start.Column += len("//go:embed")
+		start.Offset += len("//go:embed")
+		list, err := parseGoEmbed(args, start)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", start, err)
+		}
+		embeds = append(embeds, list...)
+	}
+	return embeds, nil
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the glob patterns.
+// It accepts unquoted space-separated patterns as well as double-quoted and back-quoted Go strings.
+// pos is the position of the start of args; the returned positions
+// point at the start of each pattern.
+func parseGoEmbed(args string, pos token.Position) ([]fileEmbed, error) {
+	trimSpace := func(s string) string {
+		trim := strings.TrimLeftFunc(s, unicode.IsSpace)
+		pos.Offset += len(s) - len(trim)
+		pos.Column += len(s) - len(trim)
+		return trim
+	}
+
+	var list []fileEmbed
+	for args = trimSpace(args); args != ""; args = trimSpace(args) {
+		var path string
+		pathPos := pos
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			path = args[:i]
+			args = args[i:]
+			pos.Offset += i
+			pos.Column += i
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			path = args[1 : 1+i]
+			args = args[1+i+1:]
+			pos.Offset += 1 + i + 1
+			pos.Column += 1 + i + 1
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					path = q
+					args = args[i+1:]
+					pos.Offset += i + 1
+					pos.Column += i + 1
+					break Switch
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, fileEmbed{path, pathPos})
+	}
+	return list, nil
+}
@@ -5,7 +5,10 @@
 package build
 
 import (
+	"fmt"
+	"go/token"
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 )
@@ -224,3 +227,65 @@ func TestReadFailuresIgnored(t *testing.T) {
 	}
 	testRead(t, tests, func(r io.Reader) ([]byte, error) { return readImports(r, false, nil) })
 }
+
+var readEmbedTests = []struct {
+	in, out string
+}{
+	{
+		"package p\n",
+		"",
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n//go:embed x y z\nvar files embed.FS",
+		`test:4:12:x
+		 test:4:14:y
+		 test:4:16:z`,
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n//go:embed x \"\\x79\" `z`\nvar files embed.FS",
+		`test:4:12:x
+		 test:4:14:y
+		 test:4:21:z`,
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n\t //go:embed x y\n//go:embed z\nvar files embed.FS",
+		`test:5:12:z`,
+	},
+	{
+		"package p\nimport \"embed\"\nvar s = `\n//go:embed x\n`\n/*\n//go:embed y\n*/\n//go:embedded z\n",
+		"",
+	},
+}
+
+func TestReadEmbed(t *testing.T) {
+	fset := token.NewFileSet()
+	for i, tt := range readEmbedTests {
+		ctxt := &Context{
+			OpenFile: func(string) (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader(tt.in)), nil
+			},
+		}
+		embeds, err := ctxt.readGoEmbed(fset, "test")
+		if err != nil {
+			t.Errorf("#%d: %v", i, err)
+			continue
+		}
+		var got []string
+		for _, e := range embeds {
+			got = append(got, fmt.Sprintf("%s:%d:%d:%s", e.pos.Filename, e.pos.Line, e.pos.Column, e.pattern))
+		}
+		want := strings.Fields(tt.out)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("#%d: embeds:\n%s\nwant:\n%s", i, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	_, err := (&Context{
+		OpenFile: func(string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("package p\n//go:embed \"x\n")), nil
+		},
+	}).readGoEmbed(fset, "test")
+	if err == nil || !strings.Contains(err.Error(), "invalid quoted string") {
+		t.Errorf("unterminated quoted pattern: err = %v, want invalid quoted string", err)
+	}
+}
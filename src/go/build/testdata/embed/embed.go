@@ -0,0 +1,9 @@
+package p
+
+import "embed"
+
+//go:embed x y
+var files embed.FS
+
+//go:embed "z"
+var z string
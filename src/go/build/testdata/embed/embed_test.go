@@ -0,0 +1,6 @@
+package p
+
+import _ "embed"
+
+//go:embed t
+var t []byte
@@ -0,0 +1,6 @@
+package p_test
+
+import _ "embed"
+
+//go:embed x
+var x string
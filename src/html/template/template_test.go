@@ -9,6 +9,7 @@ import (
 	. "html/template"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestTemplateClone(t *testing.T) {
@@ -110,6 +111,9 @@ func TestRedefineOtherParsers(t *testing.T) {
 	if _, err := c.root.ParseGlob("*.no.template"); err == nil || !strings.Contains(err.Error(), "Execute") {
 		t.Errorf("ParseGlob: %v\nwanted error about already having Executed", err)
 	}
+	if _, err := c.root.ParseFS(fstest.MapFS{"a.tmpl": {}}, "a.tmpl"); err == nil || !strings.Contains(err.Error(), "Execute") {
+		t.Errorf("ParseFS: %v\nwanted error about already having Executed", err)
+	}
 	if _, err := c.root.AddParseTree("t1", c.root.Tree); err == nil || !strings.Contains(err.Error(), "Execute") {
 		t.Errorf("AddParseTree: %v\nwanted error about already having Executed", err)
 	}
@@ -127,6 +131,35 @@ func newTestCase(t *testing.T) *testCase {
 	}
 }
 
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.tmpl":      {Data: []byte(`<a href="{{.}}">{{template "link.tmpl" .}}</a>`)},
+		"dir/link.tmpl":  {Data: []byte(`{{.}}`)},
+		"dir/other.tmpl": {Data: []byte(`other`)},
+	}
+	tmpl, err := ParseFS(fsys, "page.tmpl", "dir/l*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tmpl.Name(), "page.tmpl"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+	if tmpl.Lookup("other.tmpl") != nil {
+		t.Error("other.tmpl was parsed but does not match any pattern")
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, "a<b"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `<a href="a%3cb">a&lt;b</a>`; got != want {
+		t.Errorf("Execute: got %q, want %q", got, want)
+	}
+
+	if _, err := ParseFS(fsys, "*.missing"); err == nil {
+		t.Error("expected error for pattern matching no files; got none")
+	}
+}
+
 func (c *testCase) lookup(name string) *Template {
 	return c.root.Lookup(name)
 }
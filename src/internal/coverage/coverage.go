@@ -0,0 +1,375 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package coverage defines the binary file formats that hold the
+// coverage data of programs built with "go build -cover". The files
+// are written by runtime/coverage and read by cmd/covdata.
+//
+// A coverage data directory holds two kinds of files.
+//
+// A meta-data file, named covmeta.<hash>, describes an instrumented
+// program: its coverage mode, its instrumented packages and, for each
+// source file, the position and statement count of each basic block.
+// The hash is computed from the encoded contents, so all runs of the
+// same binary share a single meta-data file.
+//
+// A counter data file, named covcounters.<hash>.<pid>.<nanotime>,
+// holds the execution counts recorded by one run of a program,
+// listed in the order in which the blocks appear in the meta-data
+// file with the same hash.
+//
+// Both kinds of file start with a four-byte magic number and a version
+// byte, followed by unsigned varint-encoded integers and strings, each
+// string being preceded by its length.
+package coverage
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// File name prefixes of the meta-data and counter data files.
+const (
+	MetaFilePrefix    = "covmeta"
+	CounterFilePrefix = "covcounters"
+)
+
+const version = 1
+
+var (
+	metaMagic    = [4]byte{0x00, 'c', 'v', 'm'}
+	counterMagic = [4]byte{0x00, 'c', 'v', 'c'}
+)
+
+// A Hash identifies the meta-data of an instrumented program.
+type Hash [16]byte
+
+// String returns the hexadecimal form of h, as used in file names.
+func (h Hash) String() string {
+	const digits = "0123456789abcdef"
+	var buf [2 * len(h)]byte
+	for i, b := range h {
+		buf[2*i] = digits[b>>4]
+		buf[2*i+1] = digits[b&0xF]
+	}
+	return string(buf[:])
+}
+
+// ParseHash parses the hexadecimal form of a hash.
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	if len(s) != 2*len(h) {
+		return h, errors.New("coverage: malformed hash " + strconv.Quote(s))
+	}
+	for i := range h {
+		v, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return h, errors.New("coverage: malformed hash " + strconv.Quote(s))
+		}
+		h[i] = byte(v)
+	}
+	return h, nil
+}
+
+// MetaFileName returns the name of the meta-data file with hash h.
+func MetaFileName(h Hash) string {
+	return MetaFilePrefix + "." + h.String()
+}
+
+// CounterFileName returns the name of the counter data file written
+// at time nanotime by the process pid running the program with hash h.
+func CounterFileName(h Hash, pid int, nanotime int64) string {
+	return CounterFilePrefix + "." + h.String() + "." + strconv.Itoa(pid) + "." + strconv.FormatInt(nanotime, 10)
+}
+
+// ParseFileName reports whether name is the name of a meta-data
+// or counter data file, and if so, the hash it refers to.
+func ParseFileName(name string) (h Hash, isMeta, isCounter bool) {
+	f := strings.Split(name, ".")
+	var err error
+	switch {
+	case len(f) == 2 && f[0] == MetaFilePrefix:
+		h, err = ParseHash(f[1])
+		return h, err == nil, false
+	case len(f) == 4 && f[0] == CounterFilePrefix:
+		h, err = ParseHash(f[1])
+		return h, false, err == nil
+	}
+	return h, false, false
+}
+
+// A Block is a basic block of a source file, as identified by cmd/cover.
+// Lines and columns are 1-based; the end position is exclusive.
+type Block struct {
+	StartLine uint32
+	StartCol  uint32
+	EndLine   uint32
+	EndCol    uint32
+	NumStmt   uint32
+}
+
+// A File lists the basic blocks of an instrumented source file.
+// Its Name is the import path of the package followed by a slash
+// and the base name of the file, as in coverage profiles.
+type File struct {
+	Name   string
+	Blocks []Block
+}
+
+// A Package lists the instrumented source files of a package.
+type Package struct {
+	Path  string
+	Files []File
+}
+
+// Meta is the meta-data of an instrumented program.
+type Meta struct {
+	Mode     string // "set", "count" or "atomic"
+	Packages []Package
+}
+
+// Encode returns the binary encoding of m.
+func (m *Meta) Encode() []byte {
+	var e encoder
+	e.header(metaMagic)
+	e.string(m.Mode)
+	e.uvarint(uint64(len(m.Packages)))
+	for _, p := range m.Packages {
+		e.string(p.Path)
+		e.uvarint(uint64(len(p.Files)))
+		for _, f := range p.Files {
+			e.string(f.Name)
+			e.uvarint(uint64(len(f.Blocks)))
+			for _, b := range f.Blocks {
+				e.uvarint(uint64(b.StartLine))
+				e.uvarint(uint64(b.StartCol))
+				e.uvarint(uint64(b.EndLine))
+				e.uvarint(uint64(b.EndCol))
+				e.uvarint(uint64(b.NumStmt))
+			}
+		}
+	}
+	return e.buf
+}
+
+// Files returns the files of all the packages in m, in order.
+// The counts of a counter data file are listed in this order.
+func (m *Meta) Files() []*File {
+	var files []*File
+	for i := range m.Packages {
+		p := &m.Packages[i]
+		for j := range p.Files {
+			files = append(files, &p.Files[j])
+		}
+	}
+	return files
+}
+
+// HashMeta returns the hash of the encoded meta-data data.
+func HashMeta(data []byte) Hash {
+	var h Hash
+	f := fnv.New128a()
+	f.Write(data)
+	f.Sum(h[:0])
+	return h
+}
+
+// DecodeMeta decodes a meta-data file.
+func DecodeMeta(data []byte) (*Meta, error) {
+	d := decoder{buf: data}
+	d.header(metaMagic)
+	m := &Meta{Mode: d.string()}
+	np := d.count()
+	for i := 0; i < np && d.err == nil; i++ {
+		p := Package{Path: d.string()}
+		nf := d.count()
+		for j := 0; j < nf && d.err == nil; j++ {
+			f := File{Name: d.string()}
+			nb := d.count()
+			for k := 0; k < nb && d.err == nil; k++ {
+				f.Blocks = append(f.Blocks, Block{
+					StartLine: d.uint32(),
+					StartCol:  d.uint32(),
+					EndLine:   d.uint32(),
+					EndCol:    d.uint32(),
+					NumStmt:   d.uint32(),
+				})
+			}
+			p.Files = append(p.Files, f)
+		}
+		m.Packages = append(m.Packages, p)
+	}
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Counters is the counter data recorded by one run of a program.
+type Counters struct {
+	MetaHash Hash       // hash of the program's meta-data
+	Counts   [][]uint32 // counts for each block of each file of the meta-data
+}
+
+// Encode returns the binary encoding of c.
+func (c *Counters) Encode() []byte {
+	var e encoder
+	e.header(counterMagic)
+	e.buf = append(e.buf, c.MetaHash[:]...)
+	e.uvarint(uint64(len(c.Counts)))
+	for _, counts := range c.Counts {
+		e.uvarint(uint64(len(counts)))
+		for _, n := range counts {
+			e.uvarint(uint64(n))
+		}
+	}
+	return e.buf
+}
+
+// DecodeCounters decodes a counter data file.
+func DecodeCounters(data []byte) (*Counters, error) {
+	d := decoder{buf: data}
+	d.header(counterMagic)
+	c := new(Counters)
+	if d.err == nil {
+		if len(d.buf) < len(c.MetaHash) {
+			d.fail()
+		} else {
+			copy(c.MetaHash[:], d.buf)
+			d.buf = d.buf[len(c.MetaHash):]
+		}
+	}
+	nf := d.count()
+	for i := 0; i < nf && d.err == nil; i++ {
+		nb := d.count()
+		counts := make([]uint32, 0, nb)
+		for j := 0; j < nb && d.err == nil; j++ {
+			counts = append(counts, d.uint32())
+		}
+		c.Counts = append(c.Counts, counts)
+	}
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Check reports whether the shape of the counter data c
+// matches that of the meta-data m.
+func (c *Counters) Check(m *Meta) error {
+	files := m.Files()
+	if len(files) != len(c.Counts) {
+		return errors.New("coverage: counter data does not match meta-data")
+	}
+	for i, f := range files {
+		if len(f.Blocks) != len(c.Counts[i]) {
+			return errors.New("coverage: counter data does not match meta-data for " + f.Name)
+		}
+	}
+	return nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) header(magic [4]byte) {
+	e.buf = append(e.buf, magic[:]...)
+	e.buf = append(e.buf, version)
+}
+
+func (e *encoder) uvarint(x uint64) {
+	for x >= 0x80 {
+		e.buf = append(e.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	e.buf = append(e.buf, byte(x))
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+var errCorrupt = errors.New("coverage: corrupt data file")
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errCorrupt
+	}
+	d.buf = nil
+}
+
+func (d *decoder) header(magic [4]byte) {
+	if len(d.buf) < len(magic)+1 || string(d.buf[:len(magic)]) != string(magic[:]) {
+		d.fail()
+		return
+	}
+	if d.buf[len(magic)] != version {
+		d.err = errors.New("coverage: unsupported data file version " + strconv.Itoa(int(d.buf[len(magic)])))
+		d.buf = nil
+		return
+	}
+	d.buf = d.buf[len(magic)+1:]
+}
+
+func (d *decoder) uvarint() uint64 {
+	var x uint64
+	var s uint
+	for i, b := range d.buf {
+		if i == 10 {
+			break
+		}
+		if b < 0x80 {
+			d.buf = d.buf[i+1:]
+			return x | uint64(b)<<s
+		}
+		x |= uint64(b&0x7F) << s
+		s += 7
+	}
+	d.fail()
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	x := d.uvarint()
+	if x > 1<<32-1 {
+		d.fail()
+		return 0
+	}
+	return uint32(x)
+}
+
+// count decodes a number of elements to follow. Each element takes
+// at least one byte, so a count larger than the remaining data is
+// an error, not an invitation to allocate.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.count()
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.buf) != 0 {
+		d.fail()
+	}
+	return d.err
+}
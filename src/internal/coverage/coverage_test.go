@@ -0,0 +1,100 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package coverage
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testMeta = &Meta{
+	Mode: "count",
+	Packages: []Package{
+		{
+			Path: "example.com/a",
+			Files: []File{
+				{Name: "example.com/a/a.go", Blocks: []Block{{1, 2, 3, 4, 5}, {6, 7, 8, 9, 10}}},
+				{Name: "example.com/a/b.go"},
+			},
+		},
+		{
+			Path: "example.com/b",
+			Files: []File{
+				{Name: "example.com/b/b.go", Blocks: []Block{{200, 1, 300, 1 << 16, 1}}},
+			},
+		},
+	},
+}
+
+func TestMetaRoundTrip(t *testing.T) {
+	data := testMeta.Encode()
+	m, err := DecodeMeta(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, testMeta) {
+		t.Errorf("DecodeMeta(Encode()) = %+v, want %+v", m, testMeta)
+	}
+	if HashMeta(data) != HashMeta(m.Encode()) {
+		t.Errorf("hash changed after round trip")
+	}
+	if _, err := DecodeCounters(data); err == nil {
+		t.Errorf("DecodeCounters accepted a meta-data file")
+	}
+}
+
+func TestCountersRoundTrip(t *testing.T) {
+	c := &Counters{
+		MetaHash: HashMeta(testMeta.Encode()),
+		Counts:   [][]uint32{{0, 1<<32 - 1}, {}, {7}},
+	}
+	got, err := DecodeCounters(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("DecodeCounters(Encode()) = %+v, want %+v", got, c)
+	}
+	if err := got.Check(testMeta); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+	got.Counts[2] = nil
+	if err := got.Check(testMeta); err == nil {
+		t.Errorf("Check accepted counters of the wrong shape")
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	data := testMeta.Encode()
+	for i := 0; i < len(data); i++ {
+		if _, err := DecodeMeta(data[:i]); err == nil {
+			t.Errorf("DecodeMeta accepted data truncated to %d bytes", i)
+		}
+	}
+	if _, err := DecodeMeta(append(data, 0)); err == nil {
+		t.Errorf("DecodeMeta accepted trailing data")
+	}
+	bad := append([]byte(nil), data...)
+	bad[len(metaMagic)] = version + 1
+	if _, err := DecodeMeta(bad); err == nil {
+		t.Errorf("DecodeMeta accepted an unknown version")
+	}
+}
+
+func TestFileNames(t *testing.T) {
+	h := HashMeta(testMeta.Encode())
+	if got, isMeta, isCounter := ParseFileName(MetaFileName(h)); got != h || !isMeta || isCounter {
+		t.Errorf("ParseFileName(%q) = %v, %v, %v", MetaFileName(h), got, isMeta, isCounter)
+	}
+	name := CounterFileName(h, 1234, 5678)
+	if got, isMeta, isCounter := ParseFileName(name); got != h || isMeta || !isCounter {
+		t.Errorf("ParseFileName(%q) = %v, %v, %v", name, got, isMeta, isCounter)
+	}
+	for _, name := range []string{"covmeta", "covmeta.xyz", "covcounters." + h.String(), "profile.txt"} {
+		if _, isMeta, isCounter := ParseFileName(name); isMeta || isCounter {
+			t.Errorf("ParseFileName(%q) accepted a malformed name", name)
+		}
+	}
+}
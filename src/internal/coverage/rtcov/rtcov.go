@@ -0,0 +1,49 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rtcov records the coverage counters of a program built
+// with "go build -cover". The go command adds to each instrumented
+// package a file whose init function registers the counters that
+// cmd/cover created for the package's source files.
+package rtcov
+
+import "sync"
+
+// A File holds the coverage counters of an instrumented source file.
+// Count, Pos and NumStmt are the fields of the variable generated by
+// cmd/cover, with Pos holding three entries per block.
+type File struct {
+	PkgPath string
+	Name    string
+	Count   []uint32
+	Pos     []uint32
+	NumStmt []uint16
+}
+
+var (
+	mu    sync.Mutex
+	mode  string
+	files []File
+)
+
+// RegisterFile records the coverage counters of the named file of
+// the package with import path pkgPath, instrumented in the given mode.
+func RegisterFile(covermode, pkgPath, name string, count, pos []uint32, numStmt []uint16) {
+	mu.Lock()
+	defer mu.Unlock()
+	if mode == "" {
+		mode = covermode
+	} else if mode != covermode {
+		panic("coverage: inconsistent coverage modes " + mode + " and " + covermode)
+	}
+	files = append(files, File{pkgPath, name, count, pos, numStmt})
+}
+
+// Files returns the coverage mode of the program
+// and the files registered so far, in order of registration.
+func Files() (string, []File) {
+	mu.Lock()
+	defer mu.Unlock()
+	return mode, files[:len(files):len(files)]
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// encVersion1 will be the first line of a file with version 1 encoding.
+var encVersion1 = "go test fuzz v1"
+
+// marshalCorpusFile encodes an arbitrary number of arguments into the file format for the
+// corpus.
+func marshalCorpusFile(vals ...interface{}) []byte {
+	if len(vals) == 0 {
+		panic("must have at least one value to marshal")
+	}
+	b := bytes.NewBuffer([]byte(encVersion1 + "\n"))
+	for _, val := range vals {
+		switch t := val.(type) {
+		case int, int8, int16, int64, uint, uint16, uint32, uint64, bool:
+			fmt.Fprintf(b, "%T(%v)\n", t, t)
+		case float32:
+			fmt.Fprintf(b, "float32(%s)\n", strconv.FormatFloat(float64(t), 'g', -1, 32))
+		case float64:
+			fmt.Fprintf(b, "float64(%s)\n", strconv.FormatFloat(t, 'g', -1, 64))
+		case string:
+			fmt.Fprintf(b, "string(%q)\n", t)
+		case rune: // int32
+			// Only encode as a rune literal if the value is a valid,
+			// printable code point; otherwise the literal would not
+			// round-trip.
+			if utf8.ValidRune(t) && strconv.IsPrint(t) {
+				fmt.Fprintf(b, "rune(%q)\n", t)
+			} else {
+				fmt.Fprintf(b, "int32(%v)\n", t)
+			}
+		case byte: // uint8
+			fmt.Fprintf(b, "byte(%q)\n", t)
+		case []byte:
+			fmt.Fprintf(b, "[]byte(%q)\n", t)
+		default:
+			panic(fmt.Sprintf("unsupported type: %T", t))
+		}
+	}
+	return b.Bytes()
+}
+
+// unmarshalCorpusFile decodes corpus bytes into their respective values.
+func unmarshalCorpusFile(b []byte) ([]interface{}, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("cannot unmarshal empty string")
+	}
+	lines := bytes.Split(b, []byte("\n"))
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("must include version and at least one value")
+	}
+	if string(bytes.TrimSpace(lines[0])) != encVersion1 {
+		return nil, fmt.Errorf("unknown encoding version: %s", lines[0])
+	}
+	var vals []interface{}
+	for _, line := range lines[1:] {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		v, err := parseCorpusValue(string(line))
+		if err != nil {
+			return nil, fmt.Errorf("malformed line %q: %v", line, err)
+		}
+		vals = append(vals, v)
+	}
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("must include version and at least one value")
+	}
+	return vals, nil
+}
+
+// parseCorpusValue parses a single line of a corpus file,
+// which has the form of a Go conversion of a literal to a type,
+// such as string("hello") or int(-5).
+func parseCorpusValue(line string) (interface{}, error) {
+	i := strings.Index(line, "(")
+	if i <= 0 || !strings.HasSuffix(line, ")") {
+		return nil, fmt.Errorf("expected call expression")
+	}
+	typ, lit := line[:i], strings.TrimSpace(line[i+1:len(line)-1])
+	if lit == "" {
+		return nil, fmt.Errorf("expected one argument to conversion")
+	}
+
+	switch typ {
+	case "[]byte":
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return nil, fmt.Errorf("string literal required for type []byte")
+		}
+		return []byte(s), nil
+	case "string":
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return nil, fmt.Errorf("string literal required for type string")
+		}
+		return s, nil
+	case "bool":
+		switch lit {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("true or false required for type bool")
+	case "byte", "uint8":
+		if isCharLit(lit) {
+			r, err := parseCharLit(lit)
+			if err != nil {
+				return nil, err
+			}
+			if r > 0xff {
+				return nil, fmt.Errorf("character literal %s out of range for type %s", lit, typ)
+			}
+			return byte(r), nil
+		}
+		u, err := strconv.ParseUint(lit, 0, 8)
+		return uint8(u), err
+	case "rune", "int32":
+		if isCharLit(lit) {
+			return parseCharLit(lit)
+		}
+		n, err := strconv.ParseInt(lit, 0, 32)
+		return int32(n), err
+	case "int":
+		n, err := strconv.ParseInt(lit, 0, strconv.IntSize)
+		return int(n), err
+	case "int8":
+		n, err := strconv.ParseInt(lit, 0, 8)
+		return int8(n), err
+	case "int16":
+		n, err := strconv.ParseInt(lit, 0, 16)
+		return int16(n), err
+	case "int64":
+		return strconv.ParseInt(lit, 0, 64)
+	case "uint":
+		u, err := strconv.ParseUint(lit, 0, strconv.IntSize)
+		return uint(u), err
+	case "uint16":
+		u, err := strconv.ParseUint(lit, 0, 16)
+		return uint16(u), err
+	case "uint32":
+		u, err := strconv.ParseUint(lit, 0, 32)
+		return uint32(u), err
+	case "uint64":
+		return strconv.ParseUint(lit, 0, 64)
+	case "float32":
+		f, err := strconv.ParseFloat(lit, 32)
+		return float32(f), err
+	case "float64":
+		return strconv.ParseFloat(lit, 64)
+	}
+	return nil, fmt.Errorf("expected []byte or primitive type, found %s", typ)
+}
+
+func isCharLit(lit string) bool {
+	return strings.HasPrefix(lit, "'")
+}
+
+func parseCharLit(lit string) (rune, error) {
+	s, err := strconv.Unquote(lit)
+	if err != nil {
+		return 0, err
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) {
+		return 0, fmt.Errorf("invalid character literal %s", lit)
+	}
+	return r, nil
+}
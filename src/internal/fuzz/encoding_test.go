@@ -0,0 +1,222 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalMarshal(t *testing.T) {
+	var tests = []struct {
+		in string
+		ok bool
+	}{
+		{
+			in: "int(1234)",
+			ok: false, // missing version
+		},
+		{
+			in: `go test fuzz v1
+string("a"bcad")`,
+			ok: false, // malformed
+		},
+		{
+			in: `go test fuzz v1
+int()`,
+			ok: false, // empty value
+		},
+		{
+			in: `go test fuzz v1
+uint(-32)`,
+			ok: false, // invalid negative uint
+		},
+		{
+			in: `go test fuzz v1
+int8(1234456)`,
+			ok: false, // int8 too large
+		},
+		{
+			in: `go test fuzz v1
+int(20*5)`,
+			ok: false, // expressions are not allowed
+		},
+		{
+			in: `go test fuzz v1
+int(--5)`,
+			ok: false, // expressions are not allowed
+		},
+		{
+			in: `go test fuzz v1
+bool(0)`,
+			ok: false, // malformed bool
+		},
+		{
+			in: `go test fuzz v1
+byte('aa)`,
+			ok: false, // malformed byte
+		},
+		{
+			in: `go test fuzz v1
+byte('☃')`,
+			ok: false, // byte out of range
+		},
+		{
+			in: `go test fuzz v1
+complex64(1)`,
+			ok: false, // unsupported type
+		},
+		{
+			in: `go test fuzz v1
+string("extra")
+[]byte("spacing")
+    `,
+			ok: true,
+		},
+		{
+			in: `go test fuzz v1
+float64(0)
+float32(0)`,
+			ok: true,
+		},
+		{
+			in: `go test fuzz v1
+int(-23)
+int8(-2)
+int64(2342425)
+uint(1)
+uint16(234)
+uint32(352342)
+uint64(123)
+rune('œ')
+byte('K')
+byte('ÿ')
+[]byte("hello¿")
+[]byte("a")
+bool(true)
+string("hello\\xbd\\xb2=\\xbc ⌘")
+float64(-12.5)
+float32(2.5)`,
+			ok: true,
+		},
+		{
+			in: `go test fuzz v1
+float32(-0)
+float64(-0)
+float32(+Inf)
+float32(-Inf)
+float32(NaN)
+float64(+Inf)
+float64(-Inf)
+float64(NaN)`,
+			ok: true,
+		},
+		{
+			in: `go test fuzz v1
+int32(-1)
+int32(2147483647)
+int32(-2147483648)
+rune('\x00')`,
+			ok: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			vals, err := unmarshalCorpusFile([]byte(test.in))
+			if test.ok && err != nil {
+				t.Fatalf("unmarshal unexpected error: %v", err)
+			} else if !test.ok && err == nil {
+				t.Fatalf("unmarshal unexpected success")
+			}
+			if !test.ok {
+				return // skip the rest of the test
+			}
+			newB := marshalCorpusFile(vals...)
+			if len(newB) == 0 {
+				t.Fatalf("marshal unexpected empty output")
+			}
+			got, err := unmarshalCorpusFile(newB)
+			if err != nil {
+				t.Fatalf("unmarshal of marshaled output failed: %v\n%s", err, newB)
+			}
+			if !valuesEqual(got, vals) {
+				t.Errorf("values changed in round trip:\ngot  %#v\nwant %#v\nencoded:\n%s", got, vals, newB)
+			}
+		})
+	}
+}
+
+// valuesEqual reports whether a and b hold the same values,
+// treating NaNs of the same type as equal.
+func valuesEqual(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if reflect.TypeOf(a[i]) != reflect.TypeOf(b[i]) {
+			return false
+		}
+		switch x := a[i].(type) {
+		case float32:
+			y := b[i].(float32)
+			if x != y && !(math.IsNaN(float64(x)) && math.IsNaN(float64(y))) {
+				return false
+			}
+		case float64:
+			y := b[i].(float64)
+			if x != y && !(math.IsNaN(x) && math.IsNaN(y)) {
+				return false
+			}
+		default:
+			if !reflect.DeepEqual(a[i], b[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestMarshalAllRunes(t *testing.T) {
+	// Every int32 value in and around the valid rune range must round trip,
+	// whether it is encoded as a rune literal or an integer.
+	for _, r := range []rune{-1, 0, 0x7f, 0x80, 0xff, 0xd800, 0xdfff, 0xfffd, 0x10ffff, 0x110000, math.MaxInt32} {
+		b := marshalCorpusFile(r)
+		vals, err := unmarshalCorpusFile(b)
+		if err != nil {
+			t.Fatalf("rune %#x: %v\n%s", r, err, b)
+		}
+		if vals[0] != r {
+			t.Errorf("rune %#x: got %#x after round trip\n%s", r, vals[0], b)
+		}
+	}
+	for i := 0; i < 256; i++ {
+		b := marshalCorpusFile(byte(i))
+		vals, err := unmarshalCorpusFile(b)
+		if err != nil {
+			t.Fatalf("byte %#x: %v\n%s", i, err, b)
+		}
+		if vals[0] != byte(i) {
+			t.Errorf("byte %#x: got %#x after round trip\n%s", i, vals[0], b)
+		}
+	}
+}
+
+func TestMarshalFormat(t *testing.T) {
+	b := marshalCorpusFile("x", []byte{0, 'a'}, 5, true, rune('y'))
+	want := strings.Join([]string{
+		encVersion1,
+		`string("x")`,
+		`[]byte("\x00a")`,
+		`int(5)`,
+		`bool(true)`,
+		`rune('y')`,
+		"",
+	}, "\n")
+	if string(b) != want {
+		t.Errorf("marshalCorpusFile:\n%s\nwant:\n%s", b, want)
+	}
+}
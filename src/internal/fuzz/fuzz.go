@@ -0,0 +1,517 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fuzz provides the fuzzing engine used by 'go test -fuzz'.
+// It reads and writes corpus files, mutates inputs, and decides which
+// mutated inputs are worth keeping based on the coverage they produce.
+// The inputs run in a worker process, a copy of the test binary, so
+// that an input that crashes it can still be reported.
+// The testing package reaches it through testing/internal/testdeps.
+package fuzz
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// CorpusEntry represents an individual input for fuzzing.
+//
+// The testing and testing/internal/testdeps packages use an identical
+// struct type. The testing package cannot import this package, and it
+// must not export the type either, so each package declares an alias
+// (not a defined type) for the same struct.
+type CorpusEntry = struct {
+	// Parent is the path of the entry this one was mutated from, if any.
+	Parent string
+
+	// Path is the path of the corpus file, if the entry was loaded from
+	// or written to disk.
+	Path string
+
+	// Data is the encoded form of Values, as stored in a corpus file.
+	// It is only set for entries loaded from or written to disk.
+	Data []byte
+
+	// Values holds the arguments passed to the fuzz function.
+	Values []interface{}
+
+	// Generation is the number of mutations separating the entry
+	// from the seed corpus.
+	Generation int
+
+	// IsSeed reports whether the entry is part of the seed corpus:
+	// added with F.Add or read from testdata/fuzz.
+	IsSeed bool
+}
+
+// CoordinateFuzzingOpts is a set of arguments for CoordinateFuzzing.
+type CoordinateFuzzingOpts struct {
+	// Log is a writer for progress messages and for the standard
+	// output of the worker process.
+	Log io.Writer
+
+	// CorpusDir is the directory where crashing inputs are written,
+	// usually testdata/fuzz/FuzzXxx.
+	CorpusDir string
+}
+
+// CoordinateFuzzing runs the fuzzing loop in a worker process: a copy
+// of the current test binary, started with -test.fuzzworker, that
+// calls RunFuzzWorker. Running the inputs in another process means
+// that an input that crashes it, with a fatal error, a stack overflow
+// or a call to os.Exit, is not lost: the worker records each input
+// before running it, and if the worker dies, CoordinateFuzzing writes
+// the input it was running to opts.CorpusDir.
+//
+// If an input fails, CoordinateFuzzing returns an error that
+// implements CrashPath() string.
+func CoordinateFuzzing(opts CoordinateFuzzingOpts) error {
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+	dir, err := ioutil.TempDir("", "fuzzworker")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("starting fuzzing process: %v", err)
+	}
+
+	cmd := exec.Command(exe, append([]string{"-test.fuzzworker=" + dir}, os.Args[1:]...)...)
+	cmd.Stdout = opts.Log
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// The worker exits when its standard input is closed,
+	// so that it does not outlive this process.
+	if _, err := cmd.StdinPipe(); err != nil {
+		return err
+	}
+
+	// The worker stops fuzzing when interrupted. Forward interrupts
+	// to it rather than exiting before it has reported its results.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting fuzzing process: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var waitErr error
+Wait:
+	for {
+		select {
+		case <-interrupt:
+			cmd.Process.Signal(os.Interrupt)
+		case waitErr = <-done:
+			break Wait
+		}
+	}
+	return workerResult(dir, opts.CorpusDir, waitErr, stderr.Bytes())
+}
+
+// workerResult returns the result of the worker that shared dir with
+// the coordinator and exited with waitErr. If the worker died while
+// running an input, workerResult writes the input to corpusDir and
+// returns an error including stderr, the worker's standard error.
+// Otherwise it copies stderr to its own standard error.
+func workerResult(dir, corpusDir string, waitErr error, stderr []byte) error {
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "result")); err == nil {
+		os.Stderr.Write(stderr)
+		path, msg := splitFirstLine(data)
+		if path == "" {
+			return errors.New(string(msg))
+		}
+		return &crashError{path: path, err: errors.New(string(msg))}
+	}
+	// The worker clears the recorded input when it stops fuzzing,
+	// so an input left behind is one that it did not finish.
+	input, _ := ioutil.ReadFile(filepath.Join(dir, "input"))
+	if waitErr == nil && len(input) == 0 {
+		os.Stderr.Write(stderr)
+		return nil
+	}
+	if waitErr == nil {
+		waitErr = errors.New("exit status 0")
+	}
+
+	msg := fmt.Sprintf("fuzzing process terminated unexpectedly: %v", waitErr)
+	if out := bytes.TrimSpace(stderr); len(out) > 0 {
+		msg += "\n" + string(out)
+	}
+	err := errors.New(msg)
+	if len(input) == 0 {
+		return err
+	}
+	path, data := splitFirstLine(input)
+	return crash(corpusDir, CorpusEntry{Path: path, Data: data}, err)
+}
+
+// splitFirstLine returns the first line of data,
+// without its newline, and the rest of data.
+func splitFirstLine(data []byte) (string, []byte) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return string(data), nil
+	}
+	return string(data[:i]), data[i+1:]
+}
+
+// WorkerOpts is a set of arguments for RunFuzzWorker.
+type WorkerOpts struct {
+	// Log is a writer for progress messages.
+	Log io.Writer
+
+	// Timeout is the amount of wall clock time to spend fuzzing
+	// after the baseline corpus has been run. If zero, there is no
+	// time limit.
+	Timeout time.Duration
+
+	// Limit is the number of random inputs to test. If zero,
+	// there is no limit.
+	Limit int64
+
+	// Seed is the seed corpus, as added with F.Add and read from
+	// testdata/fuzz.
+	Seed []CorpusEntry
+
+	// Types is the list of types making up a corpus entry.
+	Types []reflect.Type
+
+	// CorpusDir is the directory where crashing inputs are written,
+	// usually testdata/fuzz/FuzzXxx.
+	CorpusDir string
+
+	// CacheDir is the directory where inputs that expand coverage are
+	// kept between runs. If empty, they are kept only in memory.
+	CacheDir string
+
+	// Run calls the fuzz function with the given entry and reports
+	// its failure, if any, as an error.
+	Run func(CorpusEntry) error
+
+	// Coverage returns a snapshot of the coverage produced by the
+	// most recent call to Run: one byte for each coverage counter,
+	// with a bit set for each range of counts that was hit.
+	// If nil, or if it returns nil, fuzzing is not coverage-guided.
+	Coverage func() []byte
+}
+
+// RunFuzzWorker runs the fuzzing loop in the worker process started
+// by CoordinateFuzzing, which shares dir with it.
+//
+// Before passing each input to opts.Run, the worker records it in
+// dir, so that the coordinator can save it should the worker die.
+// When the loop ends, the worker writes its result to dir as well:
+// the path of the failing input, if any, and the error message.
+func RunFuzzWorker(dir string, opts WorkerOpts) error {
+	// Exit when the coordinator closes our standard input,
+	// most likely because it has died.
+	go func() {
+		io.Copy(ioutil.Discard, os.Stdin)
+		os.Exit(1)
+	}()
+	return runWorker(dir, opts)
+}
+
+// runWorker is RunFuzzWorker without the check for the death
+// of the coordinator.
+func runWorker(dir string, opts WorkerOpts) error {
+	input, err := os.OpenFile(filepath.Join(dir, "input"), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	record := func(e CorpusEntry) error {
+		data := e.Data
+		if data == nil {
+			data = marshalCorpusFile(e.Values...)
+		}
+		buf := make([]byte, 0, len(e.Path)+1+len(data))
+		buf = append(append(append(buf, e.Path...), '\n'), data...)
+		if err := input.Truncate(0); err != nil {
+			return err
+		}
+		_, err := input.WriteAt(buf, 0)
+		return err
+	}
+
+	err = fuzzInputs(opts, record)
+	if terr := input.Truncate(0); terr != nil && err == nil {
+		err = terr
+	}
+	if err == nil {
+		return nil
+	}
+	var path string
+	if c, ok := err.(*crashError); ok {
+		path = c.path
+	}
+	if werr := ioutil.WriteFile(filepath.Join(dir, "result"), []byte(path+"\n"+err.Error()), 0666); werr != nil {
+		return fmt.Errorf("%v\nfuzz: writing result: %v", err, werr)
+	}
+	return err
+}
+
+// fuzzInputs repeatedly mutates inputs from the corpus and passes
+// them to opts.Run until the time or input limit is reached, the
+// process is interrupted, or an input fails. It calls record with
+// each input before running it.
+//
+// Inputs that reach code not reached by any earlier input are added
+// to the corpus and written to opts.CacheDir. If an input fails,
+// it is written to opts.CorpusDir and fuzzInputs returns a *crashError.
+func fuzzInputs(opts WorkerOpts, record func(CorpusEntry) error) error {
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+	if opts.Coverage == nil {
+		opts.Coverage = func() []byte { return nil }
+	}
+
+	c := &worker{opts: opts, start: time.Now()}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// Gather baseline coverage from the seed corpus and from the inputs
+	// cached by earlier runs.
+	corpus := append([]CorpusEntry(nil), opts.Seed...)
+	if opts.CacheDir != "" {
+		cached, err := ReadCorpus(opts.CacheDir, opts.Types)
+		if err != nil {
+			// A cached entry may no longer match the fuzz function.
+			// Drop the bad entries rather than failing the run.
+			fmt.Fprintf(opts.Log, "fuzz: ignoring cached inputs: %v\n", err)
+		}
+		corpus = append(corpus, cached...)
+	}
+	if len(corpus) == 0 {
+		vals := make([]interface{}, len(opts.Types))
+		for i, t := range opts.Types {
+			vals[i] = reflect.Zero(t).Interface()
+		}
+		corpus = append(corpus, CorpusEntry{Values: vals, IsSeed: true})
+	}
+	for _, e := range corpus {
+		if err := record(e); err != nil {
+			return err
+		}
+		if err := opts.Run(e); err != nil {
+			return crash(opts.CorpusDir, e, err)
+		}
+		c.updateCoverage(opts.Coverage())
+	}
+	c.corpus = corpus
+	fmt.Fprintf(opts.Log, "fuzz: elapsed: %s, gathering baseline coverage: %d/%d completed, now fuzzing\n", c.elapsed(), len(corpus), len(corpus))
+
+	m := newMutator(time.Now().UnixNano())
+	var deadline <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	var execs int64
+	for {
+		select {
+		case <-interrupt:
+			c.logStats(execs)
+			return nil
+		case <-deadline:
+			c.logStats(execs)
+			return nil
+		case <-ticker.C:
+			c.logStats(execs)
+		default:
+		}
+		if opts.Limit > 0 && execs >= opts.Limit {
+			c.logStats(execs)
+			return nil
+		}
+
+		parent := c.corpus[m.r.Intn(len(c.corpus))]
+		vals := append([]interface{}(nil), parent.Values...)
+		m.mutate(vals)
+		e := CorpusEntry{
+			Parent:     parent.Path,
+			Values:     vals,
+			Generation: parent.Generation + 1,
+		}
+		execs++
+		if err := record(e); err != nil {
+			return err
+		}
+		if err := opts.Run(e); err != nil {
+			c.logStats(execs)
+			return crash(opts.CorpusDir, e, err)
+		}
+		if c.updateCoverage(opts.Coverage()) {
+			if opts.CacheDir != "" {
+				e.Data = marshalCorpusFile(e.Values...)
+				path, err := writeToCorpus(e.Data, opts.CacheDir)
+				if err != nil {
+					return err
+				}
+				e.Path = path
+			}
+			c.corpus = append(c.corpus, e)
+			c.interesting++
+		}
+	}
+}
+
+// A worker holds the state of a fuzzing run.
+type worker struct {
+	opts        WorkerOpts
+	start       time.Time
+	corpus      []CorpusEntry // inputs that mutations are derived from
+	coverage    []byte        // union of all coverage seen so far
+	interesting int           // number of new inputs added to the corpus
+}
+
+// updateCoverage merges snapshot into the coverage seen so far
+// and reports whether it contained anything new.
+func (c *worker) updateCoverage(snapshot []byte) bool {
+	if len(c.coverage) < len(snapshot) {
+		c.coverage = append(c.coverage, make([]byte, len(snapshot)-len(c.coverage))...)
+	}
+	isNew := false
+	for i, b := range snapshot {
+		if b&^c.coverage[i] != 0 {
+			c.coverage[i] |= b
+			isNew = true
+		}
+	}
+	return isNew
+}
+
+func (c *worker) elapsed() time.Duration {
+	return time.Since(c.start).Round(time.Second)
+}
+
+func (c *worker) logStats(execs int64) {
+	rate := float64(execs) / time.Since(c.start).Seconds()
+	fmt.Fprintf(c.opts.Log, "fuzz: elapsed: %s, execs: %d (%.0f/sec), new interesting: %d (total: %d)\n", c.elapsed(), execs, rate, c.interesting, len(c.corpus))
+}
+
+// crash records that entry e failed with err, writing e to corpusDir
+// unless it was read from there to begin with.
+func crash(corpusDir string, e CorpusEntry, err error) error {
+	if e.Path != "" && filepath.Dir(e.Path) == filepath.Clean(corpusDir) {
+		return &crashError{path: e.Path, err: err}
+	}
+	data := e.Data
+	if data == nil {
+		data = marshalCorpusFile(e.Values...)
+	}
+	path, werr := writeToCorpus(data, corpusDir)
+	if werr != nil {
+		return fmt.Errorf("%v\nfuzz: writing failing input: %v", err, werr)
+	}
+	return &crashError{path: path, err: err}
+}
+
+// crashError wraps an error returned by the fuzz function
+// along with the path of the corpus file holding the failing input.
+type crashError struct {
+	path string
+	err  error
+}
+
+func (e *crashError) Error() string {
+	return e.err.Error()
+}
+
+// CrashPath returns the path of the corpus file holding the failing input.
+func (e *crashError) CrashPath() string {
+	return e.path
+}
+
+// ReadCorpus reads the corpus from the provided dir. The returned corpus
+// entries are guaranteed to match the given types. Any malformed files
+// are reported in a single error, after the well-formed entries.
+// A missing directory is treated as an empty corpus.
+func ReadCorpus(dir string, types []reflect.Type) ([]CorpusEntry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading seed corpus from testdata: %v", err)
+	}
+	var corpus []CorpusEntry
+	var errs []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read corpus file: %v", err)
+		}
+		vals, err := unmarshalCorpusFile(data)
+		if err == nil {
+			err = CheckCorpus(vals, types)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", filename, err))
+			continue
+		}
+		corpus = append(corpus, CorpusEntry{Path: filename, Data: data, Values: vals})
+	}
+	if len(errs) > 0 {
+		return corpus, fmt.Errorf("malformed corpus files:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	return corpus, nil
+}
+
+// CheckCorpus verifies that the types in vals match the expected types
+// provided.
+func CheckCorpus(vals []interface{}, types []reflect.Type) error {
+	if len(vals) != len(types) {
+		return fmt.Errorf("wrong number of values in corpus entry: %d, want %d", len(vals), len(types))
+	}
+	valsT := make([]reflect.Type, len(vals))
+	for i, v := range vals {
+		valsT[i] = reflect.TypeOf(v)
+	}
+	for i := range types {
+		if valsT[i] != types[i] {
+			return fmt.Errorf("mismatched types in corpus entry: %v, want %v", valsT, types)
+		}
+	}
+	return nil
+}
+
+// writeToCorpus writes data to a file in dir named after its hash,
+// creating dir if needed, and returns the file's path.
+func writeToCorpus(data []byte, dir string) (path string, err error) {
+	name := fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+	path = filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		os.Remove(path) // remove partially written file
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var (
+	bytesType  = reflect.TypeOf([]byte(nil))
+	stringType = reflect.TypeOf("")
+	intType    = reflect.TypeOf(0)
+)
+
+func TestMutatorPreservesTypes(t *testing.T) {
+	m := newMutator(1)
+	vals := []interface{}{
+		[]byte("abc"), "xyz", true, byte(1), rune(2), float32(3), float64(4),
+		int(5), int8(6), int16(7), int32(8), int64(9),
+		uint(10), uint16(11), uint32(12), uint64(13),
+	}
+	types := make([]reflect.Type, len(vals))
+	for i, v := range vals {
+		types[i] = reflect.TypeOf(v)
+	}
+	for i := 0; i < 10000; i++ {
+		m.mutate(vals)
+		if err := CheckCorpus(vals, types); err != nil {
+			t.Fatalf("after %d mutations: %v", i+1, err)
+		}
+	}
+}
+
+func TestCheckCorpus(t *testing.T) {
+	types := []reflect.Type{bytesType, intType}
+	if err := CheckCorpus([]interface{}{[]byte("a"), 1}, types); err != nil {
+		t.Errorf("CheckCorpus of matching values: %v", err)
+	}
+	if err := CheckCorpus([]interface{}{[]byte("a")}, types); err == nil {
+		t.Errorf("CheckCorpus with too few values succeeded")
+	}
+	if err := CheckCorpus([]interface{}{"a", 1}, types); err == nil {
+		t.Errorf("CheckCorpus with mismatched types succeeded")
+	}
+}
+
+func TestReadCorpus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if c, err := ReadCorpus(filepath.Join(dir, "missing"), nil); c != nil || err != nil {
+		t.Fatalf("ReadCorpus of missing directory = %v, %v; want nil, nil", c, err)
+	}
+
+	good, err := writeToCorpus(marshalCorpusFile("hello"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad"), []byte("not a corpus file"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "wrongtype"), marshalCorpusFile(1), 0666); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCorpus(dir, []reflect.Type{stringType})
+	if err == nil || !strings.Contains(err.Error(), "bad") || !strings.Contains(err.Error(), "wrongtype") {
+		t.Errorf("ReadCorpus error = %v; want error naming bad and wrongtype", err)
+	}
+	if len(c) != 1 || c[0].Path != good || !reflect.DeepEqual(c[0].Values, []interface{}{"hello"}) {
+		t.Errorf("ReadCorpus = %+v; want one entry for %s", c, good)
+	}
+}
+
+func TestRunWorker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	corpusDir := filepath.Join(dir, "corpus")
+	cacheDir := filepath.Join(dir, "cache")
+
+	// Simulate coverage of a function that fails only when its input
+	// begins with "bug", with a counter for each matching prefix byte,
+	// so that the search must be guided by coverage to finish quickly.
+	var cov [3]byte
+	run := func(e CorpusEntry) error {
+		cov = [3]byte{}
+		b := e.Values[0].([]byte)
+		for i, c := range []byte("bug") {
+			if len(b) <= i || b[i] != c {
+				return nil
+			}
+			cov[i] = 1
+		}
+		return errors.New("found bug")
+	}
+	err = runWorker(dir, WorkerOpts{
+		Limit:     10000000,
+		Seed:      []CorpusEntry{{Values: []interface{}{[]byte("seed")}, IsSeed: true}},
+		Types:     []reflect.Type{bytesType},
+		CorpusDir: corpusDir,
+		CacheDir:  cacheDir,
+		Run:       run,
+		Coverage:  func() []byte { return cov[:] },
+	})
+	crash, ok := err.(*crashError)
+	if !ok {
+		t.Fatalf("runWorker returned %v; want crash", err)
+	}
+	if crash.Error() != "found bug" {
+		t.Errorf("crash error = %q; want %q", crash.Error(), "found bug")
+	}
+	c, err := ReadCorpus(corpusDir, []reflect.Type{bytesType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 1 || c[0].Path != crash.CrashPath() || !strings.HasPrefix(string(c[0].Values[0].([]byte)), "bug") {
+		t.Fatalf("corpus after crash = %+v; want single entry at %s beginning with bug", c, crash.CrashPath())
+	}
+	cached, err := ReadCorpus(cacheDir, []reflect.Type{bytesType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) == 0 {
+		t.Errorf("no interesting inputs cached")
+	}
+
+	// The coordinator reports the crash recorded by the worker,
+	// even though the worker exited normally.
+	err = workerResult(dir, corpusDir, nil, nil)
+	if crash2, ok := err.(*crashError); !ok || crash2.CrashPath() != crash.CrashPath() || crash2.Error() != "found bug" {
+		t.Errorf("workerResult = %v; want crash at %s", err, crash.CrashPath())
+	}
+	os.Remove(filepath.Join(dir, "result"))
+
+	// Running again with the crasher in the seed corpus reports it
+	// without writing a new file.
+	err = runWorker(dir, WorkerOpts{
+		Limit:     1,
+		Seed:      c,
+		Types:     []reflect.Type{bytesType},
+		CorpusDir: corpusDir,
+		Run:       run,
+	})
+	if crash2, ok := err.(*crashError); !ok || crash2.CrashPath() != crash.CrashPath() {
+		t.Errorf("runWorker with crashing seed returned %v; want crash at %s", err, crash.CrashPath())
+	}
+}
+
+func TestWorkerCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	corpusDir := filepath.Join(dir, "corpus")
+
+	// The worker records each input before running it. Simulate a
+	// worker that dies while running the second input.
+	n := 0
+	run := func(e CorpusEntry) error {
+		if n++; n == 2 {
+			runtime.Goexit()
+		}
+		return nil
+	}
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		runWorker(dir, WorkerOpts{
+			Seed:  []CorpusEntry{{Values: []interface{}{"first"}}, {Values: []interface{}{"second"}}},
+			Types: []reflect.Type{stringType},
+			Run:   run,
+		})
+	}()
+	<-done
+
+	err = workerResult(dir, corpusDir, errors.New("exit status 2"), []byte("fatal error: boom\n"))
+	crash, ok := err.(*crashError)
+	if !ok {
+		t.Fatalf("workerResult returned %v; want crash", err)
+	}
+	for _, want := range []string{"terminated unexpectedly: exit status 2", "fatal error: boom"} {
+		if !strings.Contains(crash.Error(), want) {
+			t.Errorf("crash error = %q; want it to contain %q", crash.Error(), want)
+		}
+	}
+	c, err := ReadCorpus(corpusDir, []reflect.Type{stringType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 1 || c[0].Path != crash.CrashPath() || c[0].Values[0] != "second" {
+		t.Fatalf("corpus after crash = %+v; want single entry at %s holding the second input", c, crash.CrashPath())
+	}
+
+	// A worker dying on an input read from the corpus directory
+	// reports the existing file.
+	done = make(chan bool)
+	n = 0
+	go func() {
+		defer close(done)
+		runWorker(dir, WorkerOpts{
+			Seed:  []CorpusEntry{{Values: []interface{}{"first"}}, c[0]},
+			Types: []reflect.Type{stringType},
+			Run:   run,
+		})
+	}()
+	<-done
+	err = workerResult(dir, corpusDir, errors.New("exit status 2"), nil)
+	if crash2, ok := err.(*crashError); !ok || crash2.CrashPath() != crash.CrashPath() {
+		t.Errorf("workerResult for crashing corpus entry = %v; want crash at %s", err, crash.CrashPath())
+	}
+	if files, _ := ioutil.ReadDir(corpusDir); len(files) != 1 {
+		t.Errorf("found %d files in corpus directory, want 1", len(files))
+	}
+
+	// A worker exiting with status 0 while running an input,
+	// as a fuzz function calling os.Exit(0) makes it, also crashed.
+	err = workerResult(dir, corpusDir, nil, nil)
+	if crash2, ok := err.(*crashError); !ok || crash2.CrashPath() != crash.CrashPath() || !strings.Contains(err.Error(), "exit status 0") {
+		t.Errorf("workerResult for worker exiting with status 0 = %v; want crash at %s", err, crash.CrashPath())
+	}
+
+	// A worker that dies outside any input reports no input.
+	if err := os.Truncate(filepath.Join(dir, "input"), 0); err != nil {
+		t.Fatal(err)
+	}
+	err = workerResult(dir, corpusDir, errors.New("exit status 2"), nil)
+	if _, ok := err.(*crashError); ok || err == nil || !strings.Contains(err.Error(), "terminated unexpectedly") {
+		t.Errorf("workerResult with no recorded input = %v; want plain error", err)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+)
+
+// maxValueLen is the largest length a mutated string or []byte value may grow to.
+const maxValueLen = 1 << 20
+
+const (
+	maxInt  = int64(^uint(0) >> 1)
+	maxUint = uint64(^uint(0))
+)
+
+// A mutator makes random changes to fuzz inputs.
+type mutator struct {
+	r *rand.Rand
+}
+
+func newMutator(seed int64) *mutator {
+	return &mutator{r: rand.New(rand.NewSource(seed))}
+}
+
+// chooseLen chooses a length in the range [0, n], biased towards short lengths.
+func (m *mutator) chooseLen(n int) int {
+	switch x := m.r.Intn(100); {
+	case x < 90:
+		return m.r.Intn(min(8, n) + 1)
+	case x < 99:
+		return m.r.Intn(min(32, n) + 1)
+	default:
+		return m.r.Intn(n + 1)
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// mutate makes a few random mutations to the elements of vals,
+// replacing them in place.
+func (m *mutator) mutate(vals []interface{}) {
+	for n := 1 + m.r.Intn(4); n > 0; n-- {
+		m.mutateOne(vals, m.r.Intn(len(vals)))
+	}
+}
+
+// mutateOne performs one mutation on vals[i].
+func (m *mutator) mutateOne(vals []interface{}, i int) {
+	switch v := vals[i].(type) {
+	case int:
+		vals[i] = int(m.mutateInt(int64(v), maxInt))
+	case int8:
+		vals[i] = int8(m.mutateInt(int64(v), math.MaxInt8))
+	case int16:
+		vals[i] = int16(m.mutateInt(int64(v), math.MaxInt16))
+	case int32:
+		vals[i] = int32(m.mutateInt(int64(v), math.MaxInt32))
+	case int64:
+		vals[i] = m.mutateInt(v, math.MaxInt64)
+	case uint:
+		vals[i] = uint(m.mutateUInt(uint64(v), maxUint))
+	case uint8:
+		vals[i] = uint8(m.mutateUInt(uint64(v), math.MaxUint8))
+	case uint16:
+		vals[i] = uint16(m.mutateUInt(uint64(v), math.MaxUint16))
+	case uint32:
+		vals[i] = uint32(m.mutateUInt(uint64(v), math.MaxUint32))
+	case uint64:
+		vals[i] = m.mutateUInt(v, math.MaxUint64)
+	case float32:
+		vals[i] = float32(m.mutateFloat(float64(v), math.MaxFloat32))
+	case float64:
+		vals[i] = m.mutateFloat(v, math.MaxFloat64)
+	case bool:
+		vals[i] = !v
+	case string:
+		vals[i] = string(m.mutateBytes([]byte(v)))
+	case []byte:
+		vals[i] = m.mutateBytes(append([]byte(nil), v...))
+	default:
+		panic(fmt.Sprintf("type not supported for mutating: %T", vals[i]))
+	}
+}
+
+func (m *mutator) mutateInt(v, maxValue int64) int64 {
+	switch m.r.Intn(4) {
+	case 0:
+		// Add a small number.
+		n := int64(1 + m.r.Intn(16))
+		if v < maxValue-n {
+			return v + n
+		}
+		return v - n
+	case 1:
+		// Subtract a small number.
+		n := int64(1 + m.r.Intn(16))
+		if v > -maxValue+n {
+			return v - n
+		}
+		return v + n
+	case 2:
+		// Negate.
+		if v == -maxValue-1 {
+			return maxValue
+		}
+		return -v
+	default:
+		// Pick an interesting value.
+		vals := []int64{0, 1, -1, maxValue, -maxValue - 1}
+		return vals[m.r.Intn(len(vals))]
+	}
+}
+
+func (m *mutator) mutateUInt(v, maxValue uint64) uint64 {
+	switch m.r.Intn(4) {
+	case 0:
+		// Add a small number.
+		n := uint64(1 + m.r.Intn(16))
+		if v < maxValue-n {
+			return v + n
+		}
+		return v - n
+	case 1:
+		// Subtract a small number.
+		n := uint64(1 + m.r.Intn(16))
+		if v >= n {
+			return v - n
+		}
+		return v + n
+	case 2:
+		// Flip a bit.
+		return v ^ 1<<uint(m.r.Intn(bits.Len64(maxValue)))
+	default:
+		// Pick an interesting value.
+		vals := []uint64{0, 1, maxValue}
+		return vals[m.r.Intn(len(vals))]
+	}
+}
+
+func (m *mutator) mutateFloat(v, maxValue float64) float64 {
+	switch m.r.Intn(4) {
+	case 0:
+		// Add a small number.
+		n := float64(1 + m.r.Intn(16))
+		if v < maxValue-n {
+			return v + n
+		}
+		return v - n
+	case 1:
+		// Multiply or divide by a small number.
+		n := float64(2 + m.r.Intn(8))
+		if math.Abs(v) < maxValue/n {
+			return v * n
+		}
+		return v / n
+	case 2:
+		// Negate.
+		return -v
+	default:
+		// Pick an interesting value.
+		vals := []float64{0, 1, -1, 0.5, maxValue, -maxValue, math.Inf(1), math.Inf(-1), math.NaN()}
+		return vals[m.r.Intn(len(vals))]
+	}
+}
+
+// mutateBytes returns a mutated copy of b. It may modify b's contents.
+func (m *mutator) mutateBytes(b []byte) []byte {
+	for {
+		switch m.r.Intn(8) {
+		case 0:
+			// Insert random bytes.
+			if len(b) >= maxValueLen {
+				continue
+			}
+			n := 1 + m.chooseLen(min(16, maxValueLen-len(b))-1)
+			pos := m.r.Intn(len(b) + 1)
+			ins := make([]byte, n)
+			m.r.Read(ins)
+			b = append(b[:pos], append(ins, b[pos:]...)...)
+		case 1:
+			// Remove a range of bytes.
+			if len(b) == 0 {
+				continue
+			}
+			pos := m.r.Intn(len(b))
+			n := 1 + m.chooseLen(len(b)-pos-1)
+			b = append(b[:pos], b[pos+n:]...)
+		case 2:
+			// Duplicate a range of bytes.
+			if len(b) == 0 || len(b) >= maxValueLen {
+				continue
+			}
+			src := m.r.Intn(len(b))
+			n := 1 + m.chooseLen(min(len(b)-src, maxValueLen-len(b))-1)
+			dst := m.r.Intn(len(b) + 1)
+			dup := append([]byte(nil), b[src:src+n]...)
+			b = append(b[:dst], append(dup, b[dst:]...)...)
+		case 3:
+			// Flip a bit.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.r.Intn(len(b))] ^= 1 << uint(m.r.Intn(8))
+		case 4:
+			// Set a byte to a random value.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.r.Intn(len(b))] = byte(m.r.Intn(256))
+		case 5:
+			// Swap two bytes.
+			if len(b) < 2 {
+				continue
+			}
+			i, j := m.r.Intn(len(b)), m.r.Intn(len(b))
+			b[i], b[j] = b[j], b[i]
+		case 6:
+			// Set a byte to an interesting value.
+			if len(b) == 0 {
+				continue
+			}
+			vals := []byte{0, 1, '0', 'a', 'A', ' ', '\n', 0x7f, 0x80, 0xff}
+			b[m.r.Intn(len(b))] = vals[m.r.Intn(len(vals))]
+		default:
+			// Add or subtract a small number from a byte.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.r.Intn(len(b))] += byte(m.r.Intn(35) - 17)
+		}
+		return b
+	}
+}
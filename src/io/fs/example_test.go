@@ -0,0 +1,37 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"testing/fstest"
+)
+
+func ExampleWalkDir() {
+	fsys := fstest.MapFS{
+		"docs/guide.md":   {Data: []byte("# Guide")},
+		"docs/api/ref.md": {Data: []byte("# Reference")},
+		"README":          {Data: []byte("hello")},
+	}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// .
+	// README
+	// docs
+	// docs/api
+	// docs/api/ref.md
+	// docs/guide.md
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	. "io/fs"
+	"testing"
+)
+
+var isValidPathTests = []struct {
+	name string
+	ok   bool
+}{
+	{".", true},
+	{"x", true},
+	{"x/y", true},
+
+	{"", false},
+	{"..", false},
+	{"/", false},
+	{"x/", false},
+	{"/x", false},
+	{"x/y/", false},
+	{"/x/y", false},
+	{"./", false},
+	{"./x", false},
+	{"x/.", false},
+	{"x/./y", false},
+	{"../", false},
+	{"../x", false},
+	{"x/..", false},
+	{"x/../y", false},
+	{"x//y", false},
+	{`x\`, true},
+	{`x\y`, true},
+	{`x:y`, true},
+	{`\x`, true},
+	{"x\xff", false},
+}
+
+func TestValidPath(t *testing.T) {
+	for _, tt := range isValidPathTests {
+		ok := ValidPath(tt.name)
+		if ok != tt.ok {
+			t.Errorf("ValidPath(%q) = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestFileModeType(t *testing.T) {
+	for _, test := range []struct {
+		m    FileMode
+		want string
+	}{
+		{0644, "-rw-r--r--"},
+		{ModeDir | 0755, "drwxr-xr-x"},
+		{ModeSymlink | 0777, "Lrwxrwxrwx"},
+		{ModeNamedPipe | ModeSetuid | 0600, "purw-------"},
+	} {
+		if got := test.m.String(); got != test.want {
+			t.Errorf("%o.String() = %q, want %q", uint32(test.m), got, test.want)
+		}
+		if got, want := test.m.Type(), test.m&ModeType; got != want {
+			t.Errorf("%v.Type() = %v, want %v", test.m, got, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	"errors"
+	. "io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var walkTree = fstest.MapFS{
+	"a/x":     {},
+	"a/y":     {},
+	"a/b/c/z": {},
+	"a/d":     {Mode: ModeDir},
+	"a/e/f":   {},
+}
+
+func TestWalkDir(t *testing.T) {
+	var got []string
+	err := WalkDir(walkTree, "a", func(path string, d DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			got = append(got, path+"/")
+		} else {
+			got = append(got, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a/", "a/b/", "a/b/c/", "a/b/c/z", "a/d/", "a/e/", "a/e/f", "a/x", "a/y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkDir visited %v, want %v", got, want)
+	}
+}
+
+func TestWalkDirSkipDir(t *testing.T) {
+	var got []string
+	err := WalkDir(walkTree, ".", func(path string, d DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, path)
+		switch path {
+		case "a/b":
+			return SkipDir // skip the directory
+		case "a/e/f":
+			return SkipDir // skip the rest of a/e
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "a", "a/b", "a/d", "a/e", "a/e/f", "a/x", "a/y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkDir visited %v, want %v", got, want)
+	}
+}
+
+func TestWalkDirErrors(t *testing.T) {
+	// A missing root is reported to the callback with a nil DirEntry.
+	var calls int
+	err := WalkDir(walkTree, "missing", func(path string, d DirEntry, err error) error {
+		calls++
+		if d != nil {
+			t.Errorf("got DirEntry %v for missing root, want nil", d)
+		}
+		return err
+	})
+	if calls != 1 || !errors.Is(err, ErrNotExist) {
+		t.Errorf("WalkDir(missing) = %v after %d calls, want ErrNotExist after 1", err, calls)
+	}
+
+	// Errors from the callback stop the walk.
+	stop := errors.New("stop")
+	calls = 0
+	err = WalkDir(walkTree, "a", func(path string, d DirEntry, err error) error {
+		calls++
+		if path == "a/b/c" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 3 {
+		t.Errorf("WalkDir = %v after %d calls, want %v after 3", err, calls, stop)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// removeTime removes the time from the output so that it is stable.
+func removeTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func ExampleTextHandler() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger.Info("hello", "count", 3)
+	logger.Debug("not shown")
+
+	// Output:
+	// level=INFO msg=hello count=3
+}
+
+func ExampleJSONHandler() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: removeTime,
+	}))
+	logger.Debug("request", "method", "GET", slog.Group("user", "id", 7, "admin", false))
+
+	// Output:
+	// {"level":"DEBUG","msg":"request","method":"GET","user":{"id":7,"admin":false}}
+}
+
+func ExampleLogger_WithGroup() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger = logger.With("service", "api").WithGroup("req")
+	logger.Warn("slow", "path", "/x", "ms", 250)
+
+	// Output:
+	// level=WARN msg=slow service=api req.path=/x req.ms=250
+}
+
+func ExampleNewContext() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	ctx := slog.NewContext(context.Background(), logger.With("trace", "abc"))
+
+	// Code further down the call chain retrieves the Logger from the context.
+	slog.FromContext(ctx).InfoContext(ctx, "done")
+
+	// Output:
+	// level=INFO msg=done trace=abc
+}
+
+func ExampleLevelVar() {
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       &level,
+		ReplaceAttr: removeTime,
+	}))
+	logger.Info("hidden")
+	level.Set(slog.LevelInfo)
+	logger.Info("visible")
+
+	// Output:
+	// level=INFO msg=visible
+}
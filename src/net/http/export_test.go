@@ -217,3 +217,9 @@ func ExportSetH2GoawayTimeout(d time.Duration) (restore func()) {
 	http2goAwayTimeout = d
 	return func() { http2goAwayTimeout = old }
 }
+
+func ExportSetLegacyMux(v bool) (restore func()) {
+	old := useLegacyMux
+	useLegacyMux = v
+	return func() { useLegacyMux = old }
+}
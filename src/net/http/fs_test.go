@@ -26,6 +26,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -578,6 +579,54 @@ func TestServeIndexHtml(t *testing.T) {
 	}
 }
 
+func TestFileServerFS(t *testing.T) {
+	defer afterTest(t)
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("index page")},
+		"css/style.css":   {Data: []byte("body {}")},
+		"img/a.txt":       {Data: []byte("a")},
+		"img/b.txt":       {Data: []byte("b")},
+		"img/sub/skip.go": {Data: []byte("package skip")},
+	}
+	ts := httptest.NewServer(FileServer(FS(fsys)))
+	defer ts.Close()
+
+	get := func(path string) (string, string) {
+		t.Helper()
+		res, err := Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b), res.Header.Get("Content-Type")
+	}
+
+	if body, _ := get("/"); body != "index page" {
+		t.Errorf("GET / = %q, want %q", body, "index page")
+	}
+	if body, ctype := get("/css/style.css"); body != "body {}" || !strings.HasPrefix(ctype, "text/css") {
+		t.Errorf("GET /css/style.css = %q (%s), want %q (text/css)", body, ctype, "body {}")
+	}
+	body, _ := get("/img/")
+	for _, name := range []string{"a.txt", "b.txt", "sub/"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("directory listing %q does not contain %q", body, name)
+		}
+	}
+	res, err := Get(ts.URL + "/missing.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusNotFound {
+		t.Errorf("GET /missing.txt: status %d, want %d", res.StatusCode, StatusNotFound)
+	}
+}
+
 func TestFileServerZeroByte(t *testing.T) {
 	defer afterTest(t)
 	ts := httptest.NewServer(FileServer(Dir(".")))
@@ -0,0 +1,330 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Patterns for ServeMux routing.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// A pattern is a parsed ServeMux pattern, of the form
+//
+// 	[METHOD ][HOST]/[PATH]
+//
+// See the ServeMux documentation for its syntax and meaning.
+type pattern struct {
+	str    string // original string
+	method string // empty if the pattern matches all methods
+	host   string // empty if the pattern matches all hosts
+
+	// The path is a list of segments, one for each element of the
+	// path after the leading slash. A literal segment is stored
+	// unescaped and matches the unescaped element exactly. A wildcard
+	// matches any non-empty element, and a multi wildcard, which is
+	// always last, matches the rest of the path, including any
+	// slashes. A pattern ending in a slash ends in an anonymous
+	// multi wildcard, and a pattern ending in "/{$}" ends in the
+	// literal segment "/", which matches only the empty element
+	// after a trailing slash.
+	segments []segment
+}
+
+// A segment is a pattern path segment.
+type segment struct {
+	s     string // literal, or wildcard name, or "/" for "{$}"
+	wild  bool
+	multi bool // "..." wildcard
+}
+
+func (p *pattern) String() string { return p.str }
+
+func (p *pattern) lastSegment() segment {
+	return p.segments[len(p.segments)-1]
+}
+
+// parsePattern parses a ServeMux pattern.
+func parsePattern(s string) (*pattern, error) {
+	if s == "" {
+		return nil, errors.New("empty pattern")
+	}
+	p := &pattern{str: s}
+	rest := s
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		p.method, rest = s[:i], strings.TrimLeft(s[i+1:], " \t")
+		if !validMethod(p.method) {
+			return nil, fmt.Errorf("invalid method %q", p.method)
+		}
+	}
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		return nil, errors.New("host/path missing /")
+	}
+	p.host, rest = rest[:i], rest[i:]
+	if strings.IndexByte(p.host, '{') >= 0 {
+		return nil, errors.New("host contains '{' (missing initial '/'?)")
+	}
+	if cleanPath(rest) != rest {
+		return nil, fmt.Errorf("non-canonical path %q", rest)
+	}
+
+	seen := make(map[string]bool)
+	elems := strings.Split(rest[1:], "/")
+	for i, e := range elems {
+		last := i == len(elems)-1
+		if e == "" {
+			// A trailing slash: cleanPath allows no other empty element.
+			p.segments = append(p.segments, segment{wild: true, multi: true})
+			continue
+		}
+		if strings.IndexByte(e, '{') < 0 {
+			p.segments = append(p.segments, segment{s: pathUnescape(e)})
+			continue
+		}
+		if e[0] != '{' || e[len(e)-1] != '}' {
+			return nil, fmt.Errorf("bad wildcard segment %q (must be entire segment)", e)
+		}
+		name := e[1 : len(e)-1]
+		if name == "$" {
+			if !last {
+				return nil, errors.New("{$} not at end")
+			}
+			p.segments = append(p.segments, segment{s: "/"})
+			continue
+		}
+		multi := strings.HasSuffix(name, "...")
+		if multi {
+			if !last {
+				return nil, fmt.Errorf("%s wildcard not at end", e)
+			}
+			name = name[:len(name)-len("...")]
+		}
+		if !isValidWildcardName(name) {
+			return nil, fmt.Errorf("bad wildcard name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate wildcard name %q", name)
+		}
+		seen[name] = true
+		p.segments = append(p.segments, segment{s: name, wild: true, multi: multi})
+	}
+	return p, nil
+}
+
+// isValidWildcardName reports whether s is a Go identifier.
+func isValidWildcardName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMethod reports whether p matches requests with the method.
+// A pattern for GET also matches HEAD requests.
+func (p *pattern) matchMethod(method string) bool {
+	return p.method == "" || p.method == method || p.method == "GET" && method == "HEAD"
+}
+
+// matchPath reports whether p matches the path, which begins with
+// a slash and is escaped as by URL.EscapedPath, and if so returns
+// the values of its named wildcards. The path is split at its
+// unescaped slashes and each element is unescaped before it is
+// compared or captured, so "/a%2Fb" has the single element "a/b".
+func (p *pattern) matchPath(path string) (matches []string, ok bool) {
+	rest := path
+	for _, seg := range p.segments {
+		if rest == "" || rest[0] != '/' {
+			return nil, false
+		}
+		rest = rest[1:]
+		if seg.multi {
+			if seg.s != "" {
+				matches = append(matches, pathUnescape(rest))
+			}
+			return matches, true
+		}
+		elem := rest
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			elem, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+		elem = pathUnescape(elem)
+		switch {
+		case seg.s == "/":
+			if elem != "" {
+				return nil, false
+			}
+		case seg.wild:
+			if elem == "" {
+				return nil, false
+			}
+			matches = append(matches, elem)
+		case elem != seg.s:
+			return nil, false
+		}
+	}
+	if rest != "" {
+		return nil, false
+	}
+	return matches, true
+}
+
+// pathUnescape returns the unescaped form of the escaped path s,
+// or s itself if it is not validly escaped.
+func pathUnescape(s string) string {
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+	u, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return u
+}
+
+// wildcardIndex returns the index in the values returned by matchPath
+// of the named wildcard, or -1 if p has no such wildcard.
+func (p *pattern) wildcardIndex(name string) int {
+	i := 0
+	for _, seg := range p.segments {
+		if seg.wild && seg.s != "" {
+			if seg.s == name {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
+// A relationship describes how the sets of requests matched by two
+// patterns relate to each other.
+type relationship string
+
+const (
+	equivalent   relationship = "equivalent"   // both match the same requests
+	moreGeneral  relationship = "moreGeneral"  // p1 matches everything p2 does and more
+	moreSpecific relationship = "moreSpecific" // p2 matches everything p1 does and more
+	disjoint     relationship = "disjoint"     // no request matches both
+	overlaps     relationship = "overlaps"     // some requests match both, but neither is more specific
+)
+
+// inverseRelationship returns the relationship of p2 to p1,
+// given that of p1 to p2.
+func inverseRelationship(r relationship) relationship {
+	switch r {
+	case moreGeneral:
+		return moreSpecific
+	case moreSpecific:
+		return moreGeneral
+	}
+	return r
+}
+
+// combineRelationships returns the relationship of two patterns,
+// given the relationships of two independent parts of them.
+func combineRelationships(r1, r2 relationship) relationship {
+	switch r1 {
+	case equivalent:
+		return r2
+	case disjoint:
+		return disjoint
+	case overlaps:
+		if r2 == disjoint {
+			return disjoint
+		}
+		return overlaps
+	}
+	// r1 is moreGeneral or moreSpecific.
+	switch r2 {
+	case equivalent:
+		return r1
+	case inverseRelationship(r1):
+		return overlaps
+	}
+	return r2
+}
+
+// compareMethodsAndPaths returns the relationship of the requests
+// matched by p1 to those matched by p2, ignoring their hosts.
+func (p1 *pattern) compareMethodsAndPaths(p2 *pattern) relationship {
+	mrel := p1.compareMethods(p2)
+	if mrel == disjoint {
+		return disjoint
+	}
+	return combineRelationships(mrel, p1.comparePaths(p2))
+}
+
+func (p1 *pattern) compareMethods(p2 *pattern) relationship {
+	switch {
+	case p1.method == p2.method:
+		return equivalent
+	case p1.method == "":
+		return moreGeneral
+	case p2.method == "":
+		return moreSpecific
+	case p1.method == "GET" && p2.method == "HEAD":
+		return moreGeneral
+	case p1.method == "HEAD" && p2.method == "GET":
+		return moreSpecific
+	}
+	return disjoint
+}
+
+func (p1 *pattern) comparePaths(p2 *pattern) relationship {
+	segs1, segs2 := p1.segments, p2.segments
+	rel := equivalent
+	for len(segs1) > 0 && len(segs2) > 0 {
+		rel = combineRelationships(rel, compareSegments(segs1[0], segs2[0]))
+		if rel == disjoint || segs1[0].multi || segs2[0].multi {
+			// A multi wildcard matches the rest of the other path,
+			// whatever its length.
+			return rel
+		}
+		segs1, segs2 = segs1[1:], segs2[1:]
+	}
+	if len(segs1) != len(segs2) {
+		// Neither path ends in a multi wildcard, and one is longer.
+		return disjoint
+	}
+	return rel
+}
+
+func compareSegments(s1, s2 segment) relationship {
+	switch {
+	case s1.multi && s2.multi:
+		return equivalent
+	case s1.multi:
+		return moreGeneral
+	case s2.multi:
+		return moreSpecific
+	case s1.wild && s2.wild:
+		return equivalent
+	case s1.wild:
+		if s2.s == "/" {
+			// A single wildcard does not match the empty element.
+			return disjoint
+		}
+		return moreGeneral
+	case s2.wild:
+		if s1.s == "/" {
+			return disjoint
+		}
+		return moreSpecific
+	case s1.s == s2.s:
+		return equivalent
+	}
+	return disjoint
+}
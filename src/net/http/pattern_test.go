@@ -0,0 +1,175 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	lit := func(name string) segment { return segment{s: name} }
+	wild := func(name string) segment { return segment{s: name, wild: true} }
+	multi := func(name string) segment { return segment{s: name, wild: true, multi: true} }
+
+	for _, test := range []struct {
+		in   string
+		want pattern
+	}{
+		{"/", pattern{segments: []segment{multi("")}}},
+		{"/a", pattern{segments: []segment{lit("a")}}},
+		{"/a/", pattern{segments: []segment{lit("a"), multi("")}}},
+		{"/path/to/something", pattern{segments: []segment{lit("path"), lit("to"), lit("something")}}},
+		{"/{w1}/lit/{w2}", pattern{segments: []segment{wild("w1"), lit("lit"), wild("w2")}}},
+		{"/{w1}/lit/{w2}/", pattern{segments: []segment{wild("w1"), lit("lit"), wild("w2"), multi("")}}},
+		{"example.com/", pattern{host: "example.com", segments: []segment{multi("")}}},
+		{"GET /", pattern{method: "GET", segments: []segment{multi("")}}},
+		{"POST example.com/foo/{w}", pattern{method: "POST", host: "example.com", segments: []segment{lit("foo"), wild("w")}}},
+		{"/{$}", pattern{segments: []segment{lit("/")}}},
+		{"DELETE example.com/a/{foo12}/{$}", pattern{method: "DELETE", host: "example.com", segments: []segment{lit("a"), wild("foo12"), lit("/")}}},
+		{"/foo/{$}", pattern{segments: []segment{lit("foo"), lit("/")}}},
+		{"/{a}/foo/{rest...}", pattern{segments: []segment{wild("a"), lit("foo"), multi("rest")}}},
+		{"/a%2Fb/c%20d", pattern{segments: []segment{lit("a/b"), lit("c d")}}},
+		{"GET \t  /a", pattern{method: "GET", segments: []segment{lit("a")}}},
+	} {
+		got, err := parsePattern(test.in)
+		if err != nil {
+			t.Errorf("parsePattern(%q): %v", test.in, err)
+			continue
+		}
+		test.want.str = test.in
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("parsePattern(%q):\ngot  %#v\nwant %#v", test.in, *got, test.want)
+		}
+	}
+}
+
+func TestParsePatternError(t *testing.T) {
+	for _, test := range []struct {
+		in       string
+		contains string
+	}{
+		{"", "empty pattern"},
+		{"A=B /", "invalid method"},
+		{"example.com", "missing /"},
+		{"/{w}x", "bad wildcard segment"},
+		{"/x{w}", "bad wildcard segment"},
+		{"/{wx", "bad wildcard segment"},
+		{"/{a$}", "bad wildcard name"},
+		{"/{}", "bad wildcard name"},
+		{"/{1a}", "bad wildcard name"},
+		{"/{a...}/", "not at end"},
+		{"/{$}/", "{$} not at end"},
+		{"/{$...}", "bad wildcard name"},
+		{"/{a}/{a}", "duplicate wildcard name"},
+		{"/a/../b", "non-canonical path"},
+		{"/a//b", "non-canonical path"},
+		{"example.com{x}/", "host contains '{'"},
+		{"x{foo}", "missing /"},
+	} {
+		_, err := parsePattern(test.in)
+		if err == nil || !strings.Contains(err.Error(), test.contains) {
+			t.Errorf("parsePattern(%q): got %v, want error containing %q", test.in, err, test.contains)
+		}
+	}
+}
+
+func TestComparePatterns(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   relationship
+	}{
+		{"/a", "/a", equivalent},
+		{"/a", "/b", disjoint},
+		{"/a", "/a/", disjoint},
+		{"/a/", "/a/b", moreGeneral},
+		{"/", "/a", moreGeneral},
+		{"/a/b/", "/a/", moreSpecific},
+		{"/{x}", "/a", moreGeneral},
+		{"/{x}", "/{y}", equivalent},
+		{"/a/", "/a/{rest...}", equivalent},
+		{"/a/{x}", "/a/{$}", disjoint},
+		{"/a/", "/a/{$}", moreGeneral},
+		{"/{x}/b", "/a/{y}", overlaps},
+		{"/{x}/{y}", "/a/{y}", moreGeneral},
+		{"/{x}", "/a/b", disjoint},
+		{"/{x...}", "/a/b", moreGeneral},
+
+		{"GET /a", "/a", moreSpecific},
+		{"GET /a", "POST /a", disjoint},
+		{"GET /a", "HEAD /a", moreGeneral},
+		{"HEAD /", "GET /a", overlaps},
+		{"GET /", "/index.html", overlaps},
+		{"GET /a/", "/a/b", overlaps},
+		{"GET /a/b", "/a/", moreSpecific},
+		{"POST /{x}", "GET /a", disjoint},
+	} {
+		p1, err := parsePattern(test.p1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := parsePattern(test.p2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p1.compareMethodsAndPaths(p2); got != test.want {
+			t.Errorf("%q vs %q: got %s, want %s", test.p1, test.p2, got, test.want)
+		}
+		// The relationship should be the inverse with the arguments reversed.
+		if got, want := p2.compareMethodsAndPaths(p1), inverseRelationship(test.want); got != want {
+			t.Errorf("%q vs %q: got %s, want %s", test.p2, test.p1, got, want)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, test := range []struct {
+		pat  string
+		path string
+		want []string // nil for no match
+	}{
+		{"/", "/", []string{}},
+		{"/", "/a/b", []string{}},
+		{"/a", "/a", []string{}},
+		{"/a", "/a/", nil},
+		{"/a/", "/a", nil},
+		{"/a/", "/a/", []string{}},
+		{"/{$}", "/", []string{}},
+		{"/{$}", "/a", nil},
+		{"/a/{$}", "/a/", []string{}},
+		{"/a/{$}", "/a/b", nil},
+		{"/{x}", "/", nil},
+		{"/{x}", "/a", []string{"a"}},
+		{"/{x}", "/a/", nil},
+		{"/{x}/", "/a/b/c", []string{"a"}},
+		{"/items/{id}/{rest...}", "/items/3/x/y", []string{"3", "x/y"}},
+		{"/items/{id}/{rest...}", "/items/3/", []string{"3", ""}},
+		{"/items/{id}/{rest...}", "/items/3", nil},
+		{"/{x}", "/a%2Fb", []string{"a/b"}},
+		{"/{x}/{rest...}", "/a%2Fb/c%2Fd/e", []string{"a/b", "c/d/e"}},
+		{"/a%2Fb", "/a%2fb", []string{}},
+		{"/a%2Fb", "/a/b", nil},
+		{"/a/b", "/a%2Fb", nil},
+		{"/{x}", "/bad%zz", []string{"bad%zz"}},
+	} {
+		p, err := parsePattern(test.pat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := p.matchPath(test.path)
+		if !ok {
+			if test.want != nil {
+				t.Errorf("%q.matchPath(%q): no match, want %q", test.pat, test.path, test.want)
+			}
+			continue
+		}
+		if test.want == nil {
+			t.Errorf("%q.matchPath(%q): matched %q, want no match", test.pat, test.path, got)
+		} else if len(got) != len(test.want) || len(got) > 0 && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q.matchPath(%q) = %q, want %q", test.pat, test.path, got, test.want)
+		}
+	}
+}
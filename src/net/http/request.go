@@ -300,6 +300,11 @@ type Request struct {
 	// It is unexported to prevent people from using Context wrong
 	// and mutating the contexts held by callers of the same request.
 	ctx context.Context
+
+	// The following fields are for requests matched by ServeMux.
+	pat         *pattern          // the pattern that matched
+	matches     []string          // values for the matching wildcards in pat
+	otherValues map[string]string // for calls to SetPathValue that don't match a wildcard
 }
 
 // Context returns the request's context. To change the context, use
@@ -342,6 +347,39 @@ func (r *Request) WithContext(ctx context.Context) *Request {
 	return r2
 }
 
+// PathValue returns the value for the named path wildcard in the ServeMux
+// pattern that matched the request. It returns the empty string if the
+// request was not matched against a pattern or there is no such wildcard
+// in the pattern.
+func (r *Request) PathValue(name string) string {
+	if i := r.patIndex(name); i >= 0 {
+		return r.matches[i]
+	}
+	return r.otherValues[name]
+}
+
+// SetPathValue sets name to value, so that subsequent calls to
+// r.PathValue(name) return value.
+func (r *Request) SetPathValue(name, value string) {
+	if i := r.patIndex(name); i >= 0 {
+		r.matches[i] = value
+		return
+	}
+	if r.otherValues == nil {
+		r.otherValues = make(map[string]string)
+	}
+	r.otherValues[name] = value
+}
+
+// patIndex returns the index of name in the values of the
+// wildcards of r's pattern, or -1 if there is no such wildcard.
+func (r *Request) patIndex(name string) int {
+	if r.pat == nil {
+		return -1
+	}
+	return r.pat.wildcardIndex(name)
+}
+
 // ProtoAtLeast reports whether the HTTP protocol used
 // in the request is at least major.minor.
 func (r *Request) ProtoAtLeast(major, minor int) bool {
@@ -523,6 +523,223 @@ func TestServeWithSlashRedirectKeepsQueryString(t *testing.T) {
 	}
 }
 
+func TestServeMuxPatterns(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	for _, pattern := range []string{
+		"/",
+		"GET /items/",
+		"GET /items/{id}",
+		"DELETE /items/{id}",
+		"GET /items/latest",
+		"/files/{path...}",
+		"GET /{$}",
+		"example.com/items/{id}",
+	} {
+		pattern := pattern
+		mux.HandleFunc(pattern, func(w ResponseWriter, r *Request) {
+			fmt.Fprintf(w, "%s id=%s path=%s", pattern, r.PathValue("id"), r.PathValue("path"))
+		})
+	}
+
+	for _, tt := range []struct {
+		method string
+		host   string
+		path   string
+		code   int
+		body   string
+	}{
+		{"GET", "google.com", "/", 200, "GET /{$} id= path="},
+		{"POST", "google.com", "/", 200, "/ id= path="},
+		{"GET", "google.com", "/other", 200, "/ id= path="},
+		{"GET", "google.com", "/items/3", 200, "GET /items/{id} id=3 path="},
+		{"HEAD", "google.com", "/items/3", 200, "GET /items/{id} id=3 path="},
+		{"DELETE", "google.com", "/items/3", 200, "DELETE /items/{id} id=3 path="},
+		{"GET", "google.com", "/items/latest", 200, "GET /items/latest id= path="},
+		{"GET", "google.com", "/items/3/parts", 200, "GET /items/ id= path="},
+		{"GET", "google.com", "/items", 301, ""},
+		{"POST", "google.com", "/items/3", 200, "/ id= path="},
+		{"GET", "google.com", "/files/a/b/c", 200, "/files/{path...} id= path=a/b/c"},
+		{"GET", "google.com", "/files/", 200, "/files/{path...} id= path="},
+		{"GET", "google.com", "/files", 301, ""},
+		{"PUT", "example.com", "/items/4", 200, "example.com/items/{id} id=4 path="},
+		{"GET", "example.com", "/items/latest", 200, "example.com/items/{id} id=latest path="},
+	} {
+		r := httptest.NewRequest(tt.method, "http://"+tt.host+tt.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+		if rr.Code != tt.code || tt.code == 200 && rr.Body.String() != tt.body {
+			t.Errorf("%s %s%s = %d, %q; want %d, %q", tt.method, tt.host, tt.path, rr.Code, rr.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestServeMuxMethodNotAllowed(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("DELETE /items/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("POST /items/", func(w ResponseWriter, r *Request) {})
+
+	r := httptest.NewRequest("PUT", "/items/3", nil)
+	h, pattern := mux.Handler(r)
+	if pattern != "" {
+		t.Errorf("pattern = %q, want empty", pattern)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, r)
+	if rr.Code != StatusMethodNotAllowed {
+		t.Errorf("code = %d, want %d", rr.Code, StatusMethodNotAllowed)
+	}
+	if got, want := rr.Header().Get("Allow"), "DELETE, GET, HEAD, POST"; got != want {
+		t.Errorf("Allow = %q, want %q", got, want)
+	}
+
+	r = httptest.NewRequest("PUT", "/other", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, r)
+	if rr.Code != StatusNotFound {
+		t.Errorf("unmatched path: code = %d, want %d", rr.Code, StatusNotFound)
+	}
+}
+
+func TestServeMuxRegisterPanics(t *testing.T) {
+	for _, tt := range []struct {
+		patterns []string
+		want     string
+	}{
+		{[]string{""}, "empty pattern"},
+		{[]string{"/{x"}, "bad wildcard segment"},
+		{[]string{"/a", "/a"}, "multiple registrations for /a"},
+		{[]string{"/items/{id}", "/items/{name}"}, "match the same requests"},
+		{[]string{"GET /", "/index.html"}, "neither is more specific"},
+		{[]string{"/{x}/b", "/a/{y}"}, "neither is more specific"},
+	} {
+		func() {
+			defer func() {
+				err := recover()
+				if err == nil {
+					t.Errorf("registering %q: no panic", tt.patterns)
+				} else if s := fmt.Sprint(err); !strings.Contains(s, tt.want) {
+					t.Errorf("registering %q: panic %q, want it to contain %q", tt.patterns, s, tt.want)
+				}
+			}()
+			mux := NewServeMux()
+			for _, p := range tt.patterns {
+				mux.HandleFunc(p, func(ResponseWriter, *Request) {})
+			}
+		}()
+	}
+
+	// Patterns for different hosts, or with and without a host,
+	// do not conflict.
+	mux := NewServeMux()
+	mux.HandleFunc("/items/{id}", func(ResponseWriter, *Request) {})
+	mux.HandleFunc("a.com/items/{name}", func(ResponseWriter, *Request) {})
+	mux.HandleFunc("b.com/items/{name}", func(ResponseWriter, *Request) {})
+}
+
+func TestRequestPathValue(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	mux.HandleFunc("/b/{bucket}/o/{objectname...}", func(w ResponseWriter, r *Request) {
+		r.SetPathValue("bucket", "other")
+		r.SetPathValue("extra", "x")
+		fmt.Fprintf(w, "%s %s %s %q", r.PathValue("bucket"), r.PathValue("objectname"), r.PathValue("extra"), r.PathValue("missing"))
+	})
+	r := httptest.NewRequest("GET", "/b/mybucket/o/dir/file.txt", nil)
+	if got := r.PathValue("bucket"); got != "" {
+		t.Errorf("PathValue before matching = %q, want empty", got)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, r)
+	if got, want := rr.Body.String(), `other dir/file.txt x ""`; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestServeMuxEscapedPath(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	for _, pattern := range []string{
+		"/items/{id}",
+		"/files/{path...}",
+		"/a%2fb/",
+	} {
+		pattern := pattern
+		mux.HandleFunc(pattern, func(w ResponseWriter, r *Request) {
+			fmt.Fprintf(w, "%s id=%s path=%s", pattern, r.PathValue("id"), r.PathValue("path"))
+		})
+	}
+
+	for _, tt := range []struct {
+		path string
+		code int
+		want string // body, or Location for a redirect
+	}{
+		{"/items/a%2Fb", 200, "/items/{id} id=a/b path="},
+		{"/items/a/b", 404, ""},
+		{"/items/100%25", 200, "/items/{id} id=100% path="},
+		{"/files/x%2Fy/z%20w", 200, "/files/{path...} id= path=x/y/z w"},
+		{"/a%2Fb/c", 200, "/a%2fb/ id= path="},
+		{"/a/b/c", 404, ""},
+		{"/items/./a%2Fb", 301, "/items/a%2Fb"},
+		{"/a%2Fb", 301, "/a%2Fb/"},
+	} {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+		got := rr.Body.String()
+		if tt.code == 301 {
+			got = rr.Header().Get("Location")
+		}
+		if rr.Code != tt.code || tt.code != 404 && got != tt.want {
+			t.Errorf("GET %s = %d, %q; want %d, %q", tt.path, rr.Code, got, tt.code, tt.want)
+		}
+	}
+}
+
+func TestServeMuxLegacy(t *testing.T) {
+	defer ExportSetLegacyMux(true)()
+
+	mux := NewServeMux()
+	for _, pattern := range []string{
+		"/a{b}",
+		"/x y",
+		"/items/",
+		"/{x}/{x}",
+	} {
+		pattern := pattern
+		mux.HandleFunc(pattern, func(w ResponseWriter, r *Request) {
+			fmt.Fprintf(w, "%s id=%s", pattern, r.PathValue("x"))
+		})
+	}
+
+	for _, tt := range []struct {
+		path string
+		code int
+		want string // body, or Location for a redirect
+	}{
+		{"/a%7Bb%7D", 200, "/a{b} id="},
+		{"/x%20y", 200, "/x y id="},
+		{"/items/a%2Fb", 200, "/items/ id="},
+		{"/items", 301, "/items/"},
+		{"/{x}/{x}", 200, "/{x}/{x} id="},
+		{"/a/b", 404, ""},
+	} {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+		got := rr.Body.String()
+		if tt.code == 301 {
+			got = rr.Header().Get("Location")
+		}
+		if rr.Code != tt.code || tt.code != 404 && got != tt.want {
+			t.Errorf("GET %s = %d, %q; want %d, %q", tt.path, rr.Code, got, tt.code, tt.want)
+		}
+	}
+}
+
 func BenchmarkServeMux(b *testing.B) {
 
 	type test struct {
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -2100,45 +2101,136 @@ func RedirectHandler(url string, code int) Handler {
 // patterns and calls the handler for the pattern that
 // most closely matches the URL.
 //
-// Patterns name fixed, rooted paths, like "/favicon.ico",
-// or rooted subtrees, like "/images/" (note the trailing slash).
-// Longer patterns take precedence over shorter ones, so that
-// if there are handlers registered for both "/images/"
-// and "/images/thumbnails/", the latter handler will be
-// called for paths beginning "/images/thumbnails/" and the
-// former will receive requests for any other paths in the
-// "/images/" subtree.
-//
-// Note that since a pattern ending in a slash names a rooted subtree,
-// the pattern "/" matches all paths not matched by other registered
-// patterns, not just the URL with Path == "/".
-//
-// If a subtree has been registered and a request is received naming the
-// subtree root without its trailing slash, ServeMux redirects that
-// request to the subtree root (adding the trailing slash). This behavior can
-// be overridden with a separate registration for the path without
-// the trailing slash. For example, registering "/images/" causes ServeMux
-// to redirect a request for "/images" to "/images/", unless "/images" has
-// been registered separately.
-//
-// Patterns may optionally begin with a host name, restricting matches to
-// URLs on that host only. Host-specific patterns take precedence over
-// general patterns, so that a handler might register for the two patterns
+// Patterns
+//
+// Patterns can match the method, host and path of a request.
+// Some examples:
+//
+// 	"/index.html" matches the path "/index.html" for any host and method.
+// 	"GET /static/" matches a GET request whose path begins with "/static/".
+// 	"example.com/" matches any request to the host "example.com".
+// 	"example.com/{$}" matches requests with host "example.com" and path "/".
+// 	"/b/{bucket}/o/{objectname...}" matches paths whose first segment is "b"
+// 	and whose third segment is "o". The name "bucket" denotes the second
+// 	segment and "objectname" denotes the remainder of the path.
+//
+// In general, a pattern looks like
+//
+// 	[METHOD ][HOST]/[PATH]
+//
+// All three parts are optional; "/" is a valid pattern.
+// If METHOD is present, it must be followed by at least one space or tab.
+//
+// Literal (that is, non-wildcard) parts of a pattern match the
+// corresponding parts of a request case-sensitively.
+//
+// Patterns match unescaped paths segment by segment. For example, the
+// path "/a%2Fb/100%25" has the two segments "a/b" and "100%", so the
+// pattern "/a%2fb/" matches it but the pattern "/a/b/" does not.
+// Wildcard values are unescaped in the same way.
+//
+// A pattern with no method matches every method. A pattern
+// with the method GET matches both GET and HEAD requests.
+// Otherwise, the method must match exactly.
+//
+// A pattern with no host matches every host.
+// A pattern with a host matches URLs on that host only.
+//
+// A path can include wildcard segments of the form {NAME} or {NAME...}.
+// For example, "/b/{bucket}/o/{objectname...}".
+// The wildcard name must be a valid Go identifier.
+// Wildcards must be full path segments: they must be preceded by a slash
+// and followed by either a slash or the end of the string.
+// For example, "/b_{bucket}" is not a valid pattern.
+//
+// Normally a wildcard matches only a single, non-empty path segment,
+// ending at the next slash in the URL. But if the ... is present,
+// then the wildcard matches the remainder of the URL path, including slashes.
+// (Therefore it is invalid for a ... wildcard to appear anywhere
+// but at the end of a pattern.)
+// The match for a wildcard can be obtained by calling Request.PathValue
+// with the wildcard's name.
+// A trailing slash in a path acts as an anonymous ... wildcard.
+//
+// The special wildcard {$} matches only the end of the URL.
+// For example, the pattern "/{$}" matches only the path "/",
+// whereas the pattern "/" matches every path.
+//
+// The path of a pattern must be in canonical form, without . or ..
+// elements or repeated slashes.
+//
+// Precedence
+//
+// If two or more patterns match a request, then the most specific pattern
+// takes precedence. A pattern P1 is more specific than P2 if P1 matches
+// a strict subset of P2's requests; that is, if P2 matches all the
+// requests of P1 and more. If neither is more specific, then the patterns
+// conflict. There is one exception to this rule: if two patterns would
+// otherwise conflict and one has a host while the other does not, then
+// the pattern with the host takes precedence. More generally, patterns
+// with a host take precedence over patterns without one for requests to
+// that host, so that a handler might register for the two patterns
 // "/codesearch" and "codesearch.google.com/" without also taking over
 // requests for "http://www.google.com/".
 //
-// ServeMux also takes care of sanitizing the URL request path,
-// redirecting any request containing . or .. elements or repeated slashes
-// to an equivalent, cleaner URL.
+// For example, "/images/thumbnails/" is more specific than "/images/",
+// so both can be registered: the former matches paths beginning
+// "/images/thumbnails/" and the latter matches any other paths in the
+// "/images/" subtree. Likewise "GET /items/{id}" is more specific than
+// "/items/{id}", and "/items/latest" is more specific than "/items/{id}".
+//
+// The patterns "GET /" and "/index.html" both match a GET request for
+// "/index.html", but the former matches all other GET and HEAD requests,
+// while the latter matches any request for "/index.html" that uses a
+// different method. Neither is more specific, so registering both panics,
+// as does registering two patterns that match the same requests, such as
+// "/items/{id}" and "/items/{name}".
+//
+// Trailing-slash redirection
+//
+// Consider a ServeMux with a handler for a subtree, registered using a
+// trailing slash or a ... wildcard. If the ServeMux receives a request
+// for the subtree root without a trailing slash, it redirects the request
+// by adding the trailing slash. This behavior can be overridden with a
+// separate registration for the path without the trailing slash or
+// ... wildcard. For example, registering "/images/" causes ServeMux to
+// redirect a request for "/images" to "/images/", unless "/images" has
+// been registered separately.
+//
+// Request sanitizing
+//
+// ServeMux also takes care of sanitizing the URL request path and the
+// Host header, stripping the port number and redirecting any request
+// containing . or .. elements or repeated slashes to an equivalent,
+// cleaner URL.
+//
+// If a pattern matches the path of a request but not its method,
+// ServeMux replies with a 405 Method Not Allowed error and an Allow
+// header listing the methods of the patterns that match the path.
+//
+// Compatibility
+//
+// Earlier versions of ServeMux accepted only paths and host/path
+// prefixes as patterns, matched against the unescaped request path,
+// and so treated braces and spaces in patterns literally. Setting the
+// GODEBUG environment variable to "httpmuxlegacy=1" restores that
+// behavior. The setting is read once, at program startup.
 type ServeMux struct {
-	mu    sync.RWMutex
-	m     map[string]muxEntry
-	hosts bool // whether any patterns contain hostnames
+	mu      sync.RWMutex
+	entries []*muxEntry // in order of registration
+	hosts   bool        // whether any patterns contain hostnames
+
+	// To avoid trying every pattern, entries whose path begins with
+	// a literal segment are indexed by it; the others are in wild.
+	index map[string][]*muxEntry
+	wild  []*muxEntry
+
+	legacy legacyServeMux // used instead of the above if useLegacyMux
 }
 
 type muxEntry struct {
-	h       Handler
-	pattern string
+	h   Handler
+	pat *pattern
 }
 
 // NewServeMux allocates and returns a new ServeMux.
@@ -2149,19 +2241,6 @@ var DefaultServeMux = &defaultServeMux
 
 var defaultServeMux ServeMux
 
-// Does path match pattern?
-func pathMatch(pattern, path string) bool {
-	if len(pattern) == 0 {
-		// should not happen
-		return false
-	}
-	n := len(pattern)
-	if pattern[n-1] != '/' {
-		return pattern == path
-	}
-	return len(path) >= n && path[0:n] == pattern
-}
-
 // Return the canonical path for p, eliminating . and .. elements.
 func cleanPath(p string) string {
 	if p == "" {
@@ -2192,53 +2271,108 @@ func stripHostPort(h string) string {
 	return host
 }
 
-// Find a handler on a handler map given a path string.
-// Most-specific (longest) pattern wins.
-func (mux *ServeMux) match(path string) (h Handler, pattern string) {
-	// Check for exact match first.
-	v, ok := mux.m[path]
-	if ok {
-		return v.h, v.pattern
+// match finds the entry whose pattern most specifically matches the
+// request with the given host, method and path, trying the patterns
+// for the host before those for all hosts. It returns the values of
+// the pattern's wildcards, or a nil entry if no pattern matches.
+func (mux *ServeMux) match(host, method, path string) (*muxEntry, []string) {
+	if mux.hosts {
+		if e, matches := mux.matchHost(host, method, path); e != nil {
+			return e, matches
+		}
 	}
+	return mux.matchHost("", method, path)
+}
 
-	// Check for longest valid match.
-	var n = 0
-	for k, v := range mux.m {
-		if !pathMatch(k, path) {
-			continue
-		}
-		if h == nil || len(k) > n {
-			n = len(k)
-			h = v.h
-			pattern = v.pattern
+func (mux *ServeMux) matchHost(host, method, path string) (best *muxEntry, matches []string) {
+	for _, list := range [2][]*muxEntry{mux.index[firstElem(path)], mux.wild} {
+		for _, e := range list {
+			if e.pat.host != host || !e.pat.matchMethod(method) {
+				continue
+			}
+			m, ok := e.pat.matchPath(path)
+			if !ok {
+				continue
+			}
+			// Registered patterns with the same host do not conflict,
+			// so of any two matching the request, one is more specific.
+			if best == nil || e.pat.compareMethodsAndPaths(best.pat) == moreSpecific {
+				best, matches = e, m
+			}
 		}
 	}
-	return
+	return best, matches
 }
 
-// redirectToPathSlash determines if the given path needs appending "/" to it.
-// This occurs when a handler for path + "/" was already registered, but
-// not for path itself. If the path needs appending to, it creates a new
-// URL, setting the path to u.Path + "/" and returning true to indicate so.
-func (mux *ServeMux) redirectToPathSlash(path string, u *url.URL) (*url.URL, bool) {
-	if !mux.shouldRedirect(path) {
-		return u, false
+// firstElem returns the unescaped first element of the escaped path,
+// after its leading slash.
+func firstElem(path string) string {
+	if path == "" {
+		return ""
+	}
+	path = path[1:]
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
 	}
-	path = path + "/"
-	u = &url.URL{Path: path, RawQuery: u.RawQuery}
-	return u, true
+	return pathUnescape(path)
 }
 
-// shouldRedirect reports whether the given path should be redirected to
-// path+"/". This should happen if a handler is registered for path+"/" but
-// not path -- see comments at ServeMux.
-func (mux *ServeMux) shouldRedirect(path string) bool {
-	if _, exist := mux.m[path]; exist {
+// matchOrRedirect looks up the entry for the request, as match does.
+// If the entry does not match the escaped path exactly but an entry
+// would match path+"/" exactly, it instead returns a URL, based on u,
+// redirecting to path+"/". A nil u disables the redirect.
+func (mux *ServeMux) matchOrRedirect(host, method, path string, u *url.URL) (*muxEntry, []string, *url.URL) {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	e, matches := mux.match(host, method, path)
+	if u != nil && !exactMatch(e, path) && !strings.HasSuffix(path, "/") {
+		if e2, _ := mux.match(host, method, path+"/"); exactMatch(e2, path+"/") {
+			return nil, nil, &url.URL{Path: pathUnescape(path) + "/", RawPath: path + "/", RawQuery: u.RawQuery}
+		}
+	}
+	return e, matches, nil
+}
+
+// exactMatch reports whether the entry e matched the path without
+// consuming part of it in a multi wildcard; that is, whether e's
+// pattern names path itself rather than a subtree containing it.
+func exactMatch(e *muxEntry, path string) bool {
+	if e == nil {
 		return false
 	}
-	n := len(path)
-	_, exist := mux.m[path+"/"]
-	return n > 0 && path[n-1] != '/' && exist
+	if !e.pat.lastSegment().multi {
+		return true
+	}
+	// The multi wildcard matched the empty string only if the path
+	// ends in the slash before it.
+	return strings.HasSuffix(path, "/") && len(e.pat.segments) == strings.Count(path, "/")
+}
+
+// allowedMethods returns the sorted methods of the patterns that
+// match the host and path, for the Allow header of a 405 response.
+func (mux *ServeMux) allowedMethods(host, path string) []string {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, e := range mux.entries {
+		if e.pat.host != "" && e.pat.host != host {
+			continue
+		}
+		if _, ok := e.pat.matchPath(path); ok {
+			seen[e.pat.method] = true
+		}
+	}
+	if seen["GET"] {
+		seen["HEAD"] = true
+	}
+	var methods []string
+	for m := range seen {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
 }
 
 // Handler returns the handler to use for the given request,
@@ -2256,58 +2390,71 @@ func (mux *ServeMux) shouldRedirect(path string) bool {
 //
 // If there is no registered handler that applies to the request,
 // Handler returns a ``page not found'' handler and an empty pattern.
+// If a registered pattern matches the request except for its method,
+// Handler returns a ``method not allowed'' handler and an empty pattern.
 func (mux *ServeMux) Handler(r *Request) (h Handler, pattern string) {
+	if useLegacyMux {
+		return mux.legacy.findHandler(r)
+	}
+	h, pattern, _, _ = mux.findHandler(r)
+	return
+}
+
+// findHandler is the main implementation of Handler. It also returns
+// the matching pattern, if any, and the values of its wildcards.
+func (mux *ServeMux) findHandler(r *Request) (h Handler, patStr string, _ *pattern, matches []string) {
+	var e *muxEntry
+	// Match against the escaped path, so that an escaped slash
+	// does not separate path elements.
+	escapedPath := r.URL.EscapedPath()
+	host, path := r.Host, escapedPath
 
 	// CONNECT requests are not canonicalized.
 	if r.Method == "CONNECT" {
 		// If r.URL.Path is /tree and its handler is not registered,
 		// the /tree -> /tree/ redirect applies to CONNECT requests
 		// but the path canonicalization does not.
-		if u, ok := mux.redirectToPathSlash(r.URL.Path, r.URL); ok {
-			return RedirectHandler(u.String(), StatusMovedPermanently), u.Path
+		var u *url.URL
+		e, matches, u = mux.matchOrRedirect(host, r.Method, path, r.URL)
+		if u != nil {
+			return RedirectHandler(u.String(), StatusMovedPermanently), u.Path, nil, nil
+		}
+	} else {
+		// All other requests have any port stripped and path cleaned
+		// before matching.
+		host = stripHostPort(host)
+		path = cleanPath(path)
+
+		// If the given path is /tree and its handler is not registered,
+		// redirect for /tree/.
+		var u *url.URL
+		e, matches, u = mux.matchOrRedirect(host, r.Method, path, r.URL)
+		if u != nil {
+			return RedirectHandler(u.String(), StatusMovedPermanently), u.Path, nil, nil
 		}
 
-		return mux.handler(r.Host, r.URL.Path)
-	}
-
-	// All other requests have any port stripped and path cleaned
-	// before passing to mux.handler.
-	host := stripHostPort(r.Host)
-	path := cleanPath(r.URL.Path)
-
-	// If the given path is /tree and its handler is not registered,
-	// redirect for /tree/.
-	if u, ok := mux.redirectToPathSlash(path, r.URL); ok {
-		return RedirectHandler(u.String(), StatusMovedPermanently), u.Path
-	}
-
-	if path != r.URL.Path {
-		_, pattern = mux.handler(host, path)
-		url := *r.URL
-		url.Path = path
-		return RedirectHandler(url.String(), StatusMovedPermanently), pattern
+		if path != escapedPath {
+			if e != nil {
+				patStr = e.pat.str
+			}
+			url := *r.URL
+			url.Path, url.RawPath = pathUnescape(path), path
+			return RedirectHandler(url.String(), StatusMovedPermanently), patStr, nil, nil
+		}
 	}
 
-	return mux.handler(host, r.URL.Path)
-}
-
-// handler is the main implementation of Handler.
-// The path is known to be in canonical form, except for CONNECT methods.
-func (mux *ServeMux) handler(host, path string) (h Handler, pattern string) {
-	mux.mu.RLock()
-	defer mux.mu.RUnlock()
-
-	// Host-specific pattern takes precedence over generic ones
-	if mux.hosts {
-		h, pattern = mux.match(host + path)
-	}
-	if h == nil {
-		h, pattern = mux.match(path)
-	}
-	if h == nil {
-		h, pattern = NotFoundHandler(), ""
+	if e == nil {
+		// Distinguish a request that matches no pattern from one
+		// that matches only patterns for other methods.
+		if methods := mux.allowedMethods(host, path); len(methods) > 0 {
+			return HandlerFunc(func(w ResponseWriter, r *Request) {
+				w.Header().Set("Allow", strings.Join(methods, ", "))
+				Error(w, StatusText(StatusMethodNotAllowed), StatusMethodNotAllowed)
+			}), "", nil, nil
+		}
+		return NotFoundHandler(), "", nil, nil
 	}
-	return
+	return e.h, e.pat.str, e.pat, matches
 }
 
 // ServeHTTP dispatches the request to the handler whose
@@ -2320,32 +2467,67 @@ func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
 		w.WriteHeader(StatusBadRequest)
 		return
 	}
-	h, _ := mux.Handler(r)
+	if useLegacyMux {
+		h, _ := mux.legacy.findHandler(r)
+		h.ServeHTTP(w, r)
+		return
+	}
+	h, _, pat, matches := mux.findHandler(r)
+	r.pat = pat
+	r.matches = matches
 	h.ServeHTTP(w, r)
 }
 
 // Handle registers the handler for the given pattern.
-// If a handler already exists for pattern, Handle panics.
+// If the pattern is invalid or conflicts with one that is already
+// registered, Handle panics.
+// The documentation for ServeMux explains the syntax of patterns
+// and when they conflict.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	if useLegacyMux {
+		mux.legacy.handle(pattern, handler)
+		return
+	}
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
 
-	if pattern == "" {
-		panic("http: invalid pattern")
-	}
 	if handler == nil {
 		panic("http: nil handler")
 	}
-	if _, exist := mux.m[pattern]; exist {
-		panic("http: multiple registrations for " + pattern)
+	pat, err := parsePattern(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("http: parsing %q: %v", pattern, err))
 	}
-
-	if mux.m == nil {
-		mux.m = make(map[string]muxEntry)
+	for _, e := range mux.entries {
+		if e.pat.host != pat.host {
+			continue
+		}
+		switch pat.compareMethodsAndPaths(e.pat) {
+		case equivalent:
+			if e.pat.str == pattern {
+				panic("http: multiple registrations for " + pattern)
+			}
+			panic(fmt.Sprintf("http: pattern %q conflicts with pattern %q: they match the same requests", pattern, e.pat.str))
+		case overlaps:
+			panic(fmt.Sprintf("http: pattern %q conflicts with pattern %q: both match some requests, but neither is more specific", pattern, e.pat.str))
+		}
 	}
-	mux.m[pattern] = muxEntry{h: handler, pattern: pattern}
 
-	if pattern[0] != '/' {
+	e := &muxEntry{h: handler, pat: pat}
+	mux.entries = append(mux.entries, e)
+	if seg := pat.segments[0]; seg.wild {
+		mux.wild = append(mux.wild, e)
+	} else {
+		key := seg.s
+		if key == "/" {
+			key = "" // "/{$}" matches an empty first element
+		}
+		if mux.index == nil {
+			mux.index = make(map[string][]*muxEntry)
+		}
+		mux.index[key] = append(mux.index[key], e)
+	}
+	if pat.host != "" {
 		mux.hosts = true
 	}
 }
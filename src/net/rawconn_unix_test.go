@@ -8,8 +8,12 @@ package net
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"os"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func TestRawConn(t *testing.T) {
@@ -142,3 +146,159 @@ func TestRawConnListener(t *testing.T) {
 		t.Fatal("Control after Close should fail")
 	}
 }
+
+// controlOnConnSetup sets SO_REUSEADDR on the socket under
+// construction and records the network it was called with.
+func controlOnConnSetup(called *string) func(string, string, syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		*called = network
+		var operr error
+		fn := func(s uintptr) {
+			operr = syscall.SetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+		}
+		if err := c.Control(fn); err != nil {
+			return err
+		}
+		return operr
+	}
+}
+
+func TestListenConfigControl(t *testing.T) {
+	for _, tt := range []struct {
+		network, address, want string
+	}{
+		{"tcp", "127.0.0.1:0", "tcp4"},
+		{"tcp4", "127.0.0.1:0", "tcp4"},
+		{"tcp6", "[::1]:0", "tcp6"},
+		{"unix", testUnixAddr(), "unix"},
+		{"unixpacket", testUnixAddr(), "unixpacket"},
+	} {
+		if !testableNetwork(tt.network) || !testableListenArgs(tt.network, tt.address, "") {
+			continue
+		}
+		var called string
+		lc := ListenConfig{Control: controlOnConnSetup(&called)}
+		ln, err := lc.Listen(context.Background(), tt.network, tt.address)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		ln.Close()
+		if called != tt.want {
+			t.Errorf("%s: Control called with %q; want %q", tt.network, called, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		network, address, want string
+	}{
+		{"udp", "127.0.0.1:0", "udp4"},
+		{"udp4", "127.0.0.1:0", "udp4"},
+		{"udp6", "[::1]:0", "udp6"},
+		{"unixgram", testUnixAddr(), "unixgram"},
+	} {
+		if !testableNetwork(tt.network) || !testableListenArgs(tt.network, tt.address, "") {
+			continue
+		}
+		var called string
+		lc := ListenConfig{Control: controlOnConnSetup(&called)}
+		c, err := lc.ListenPacket(context.Background(), tt.network, tt.address)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		c.Close()
+		if tt.network == "unixgram" {
+			os.Remove(tt.address)
+		}
+		if called != tt.want {
+			t.Errorf("%s: Control called with %q; want %q", tt.network, called, tt.want)
+		}
+	}
+
+	errControl := errors.New("control failed")
+	lc := ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			return errControl
+		},
+	}
+	ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err == nil {
+		ln.Close()
+		t.Fatal("Listen succeeded despite Control error")
+	}
+	if operr, ok := err.(*OpError); !ok || operr.Err != errControl {
+		t.Errorf("got %v; want OpError wrapping %v", err, errControl)
+	}
+}
+
+func TestDialerControl(t *testing.T) {
+	for _, network := range []string{"tcp", "tcp4", "tcp6", "unix", "unixpacket"} {
+		if !testableNetwork(network) {
+			continue
+		}
+		ln, err := newLocalListener(network)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		var called string
+		d := Dialer{Control: controlOnConnSetup(&called)}
+		c, err := d.Dial(network, ln.Addr().String())
+		if err != nil {
+			ln.Close()
+			t.Error(err)
+			continue
+		}
+		c.Close()
+		ln.Close()
+		want := network
+		if network == "tcp" {
+			want = "tcp4"
+			if ln.Addr().(*TCPAddr).IP.To4() == nil {
+				want = "tcp6"
+			}
+		}
+		if called != want {
+			t.Errorf("%s: Control called with %q; want %q", network, called, want)
+		}
+	}
+}
+
+func TestListenConfigKeepAlive(t *testing.T) {
+	if !testableNetwork("tcp") {
+		t.Skip("tcp is not supported")
+	}
+	lc := ListenConfig{KeepAlive: 30 * time.Second}
+	ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c, err := Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	rc, err := sc.(*TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keepalive int
+	var operr error
+	err = rc.Control(func(s uintptr) {
+		keepalive, operr = syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+	})
+	if err != nil || operr != nil {
+		t.Fatal(err, operr)
+	}
+	if keepalive == 0 {
+		t.Error("keep-alive not enabled on accepted connection")
+	}
+}
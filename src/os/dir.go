@@ -4,6 +4,8 @@
 
 package os
 
+import "io/fs"
+
 // Readdir reads the contents of the directory associated with file and
 // returns a slice of up to n FileInfo values, as would be returned
 // by Lstat, in directory order. Subsequent calls on the same file will yield
@@ -44,3 +46,29 @@ func (f *File) Readdirnames(n int) (names []string, err error) {
 	}
 	return f.readdirnames(n)
 }
+
+// A DirEntry is an entry read from a directory
+// (using the File.ReadDir method).
+type DirEntry = fs.DirEntry
+
+// ReadDir reads the contents of the directory associated with the file f and
+// returns a slice of DirEntry values in directory order.
+// Subsequent calls on the same file will yield later DirEntry records in the directory.
+//
+// If n > 0, ReadDir returns at most n DirEntry records.
+// In this case, if ReadDir returns an empty slice, it will return an error explaining why.
+// At the end of a directory, the error is io.EOF.
+//
+// If n <= 0, ReadDir returns all the DirEntry records remaining in the directory.
+// When it succeeds, it returns a nil error (not io.EOF).
+func (f *File) ReadDir(n int) ([]DirEntry, error) {
+	if f == nil {
+		return nil, ErrInvalid
+	}
+	infos, err := f.readdir(n)
+	dirents := make([]DirEntry, len(infos))
+	for i, info := range infos {
+		dirents[i] = fs.FileInfoToDirEntry(info)
+	}
+	return dirents, err
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"internal/testenv"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	. "os"
 	osexec "os/exec"
@@ -2296,3 +2297,83 @@ func TestDoubleCloseError(t *testing.T) {
 		t.Logf("second close returned expected error %q", err)
 	}
 }
+
+func TestDirFS(t *testing.T) {
+	fsys := DirFS("./testdata/dirfs")
+	data, err := fs.ReadFile(fsys, "a")
+	if err != nil || string(data) != "" {
+		t.Fatalf("ReadFile(a) = %q, %v, want \"\", nil", data, err)
+	}
+	dirs, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range dirs {
+		names = append(names, d.Name())
+	}
+	if want := []string{"a", "b", "dir"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir(.) = %v, want %v", names, want)
+	}
+	info, err := fs.Stat(fsys, "dir")
+	if err != nil || !info.IsDir() {
+		t.Errorf("Stat(dir) = %v, %v, want directory", info, err)
+	}
+	var walked []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".", "a", "b", "dir", "dir/x"}; !reflect.DeepEqual(walked, want) {
+		t.Errorf("WalkDir visited %v, want %v", walked, want)
+	}
+
+	// Test that Open does not accept backslash as separator.
+	d := DirFS(".")
+	_, err = d.Open(`testdata\dirfs`)
+	if err == nil {
+		t.Fatalf(`Open testdata\dirfs succeeded`)
+	}
+	for _, name := range []string{"/testdata", "testdata/", "../os"} {
+		if _, err := d.Open(name); err == nil {
+			t.Errorf("Open(%q) succeeded", name)
+		} else if !errors.Is(err, ErrInvalid) {
+			t.Errorf("Open(%q) = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestReadDirFile(t *testing.T) {
+	f, err := Open("testdata/dirfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	for {
+		dirs, err := f.ReadDir(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirs) != 1 {
+			t.Fatalf("ReadDir(1) returned %d entries", len(dirs))
+		}
+		names = append(names, dirs[0].Name())
+		if dirs[0].Name() == "dir" && !dirs[0].IsDir() {
+			t.Errorf("entry %q is not a directory", dirs[0].Name())
+		}
+	}
+	sort.Strings(names)
+	if want := []string{"a", "b", "dir"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir entries = %v, want %v", names, want)
+	}
+}
@@ -57,12 +57,13 @@ func Getgroups() ([]int, error) {
 // Conventionally, code zero indicates success, non-zero an error.
 // The program terminates immediately; deferred functions are not run.
 func Exit(code int) {
-	if code == 0 {
-		// Give race detector a chance to fail the program.
-		// Racy programs do not have the right to finish successfully.
-		runtime_beforeExit()
-	}
+	// Run the runtime's exit hooks, such as the one writing the
+	// coverage data of programs built with "go build -cover".
+	// If code is zero, this also gives the race detector a chance
+	// to fail the program: racy programs do not have the right
+	// to finish successfully.
+	runtime_beforeExit(code)
 	syscall.Exit(code)
 }
 
-func runtime_beforeExit() // implemented in runtime
+func runtime_beforeExit(exitCode int) // implemented in runtime
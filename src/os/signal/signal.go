@@ -23,6 +23,16 @@ var handlers struct {
 	stopping []stopping
 }
 
+var (
+	// watchSignalLoopOnce guards calling the conditionally
+	// initialized watchSignalLoop. If watchSignalLoop is non-nil,
+	// it will be run in a goroutine lazily once Notify is invoked,
+	// so that merely importing the package doesn't start a
+	// goroutine that waits for signals.
+	watchSignalLoopOnce sync.Once
+	watchSignalLoop     func()
+)
+
 type stopping struct {
 	c chan<- os.Signal
 	h *handler
@@ -127,6 +137,14 @@ func Notify(c chan<- os.Signal, sig ...os.Signal) {
 			h.set(n)
 			if handlers.ref[n] == 0 {
 				enableSignal(n)
+
+				// The runtime requires that we enable a
+				// signal before starting the watcher.
+				watchSignalLoopOnce.Do(func() {
+					if watchSignalLoop != nil {
+						go watchSignalLoop()
+					}
+				})
 			}
 			handlers.ref[n]++
 		}
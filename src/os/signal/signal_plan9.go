@@ -18,8 +18,7 @@ func signal_ignore(uint32)
 func signal_recv() string
 
 func init() {
-	signal_enable(0) // first call - initialize
-	go loop()
+	watchSignalLoop = loop
 }
 
 func loop() {
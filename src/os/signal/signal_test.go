@@ -394,3 +394,53 @@ func atomicStopTestProgram() {
 
 	os.Exit(0)
 }
+
+// Test that importing the package does not start the goroutine that
+// waits for signals: programs that never call Notify, such as test
+// binaries, should not have it. The first call to Notify starts it.
+func TestNotifyStartsLoop(t *testing.T) {
+	if os.Getenv("GO_TEST_NOTIFY_STARTS_LOOP") != "" {
+		notifyStartsLoopTestProgram()
+		t.Fatal("notifyStartsLoopTestProgram returned")
+	}
+
+	testenv.MustHaveExec(t)
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestNotifyStartsLoop")
+	cmd.Env = append(os.Environ(), "GO_TEST_NOTIFY_STARTS_LOOP=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil || string(out) != "OK\n" {
+		t.Fatalf("subprocess failed: %v\n%s", err, out)
+	}
+}
+
+// notifyStartsLoopTestProgram is run in a subprocess by
+// TestNotifyStartsLoop, in which nothing has called Notify yet.
+func notifyStartsLoopTestProgram() {
+	hasLoop := func() bool {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		return bytes.Contains(buf, []byte("os/signal.loop("))
+	}
+	if hasLoop() {
+		fmt.Println("signal loop running before Notify")
+		os.Exit(1)
+	}
+
+	c := make(chan os.Signal, 1)
+	Notify(c, syscall.SIGUSR1)
+	if !hasLoop() {
+		fmt.Println("signal loop not running after Notify")
+		os.Exit(1)
+	}
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	select {
+	case <-c:
+	case <-time.After(10 * time.Second):
+		fmt.Println("timeout waiting for SIGUSR1")
+		os.Exit(1)
+	}
+
+	fmt.Println("OK")
+	os.Exit(0)
+}
@@ -27,7 +27,7 @@ func (file *File) Stat() (FileInfo, error) {
 
 	ft, err := file.pfd.GetFileType()
 	if err != nil {
-		return nil, &PathError{"GetFileType", file.name, err}
+		return nil, &PathError{Op: "GetFileType", Path: file.name, Err: err}
 	}
 	switch ft {
 	case syscall.FILE_TYPE_PIPE, syscall.FILE_TYPE_CHAR:
@@ -37,7 +37,7 @@ func (file *File) Stat() (FileInfo, error) {
 	var d syscall.ByHandleFileInformation
 	err = file.pfd.GetFileInformationByHandle(&d)
 	if err != nil {
-		return nil, &PathError{"GetFileInformationByHandle", file.name, err}
+		return nil, &PathError{Op: "GetFileInformationByHandle", Path: file.name, Err: err}
 	}
 	return &fileStat{
 		name: basename(file.name),
@@ -59,14 +59,14 @@ func (file *File) Stat() (FileInfo, error) {
 // statNolog implements Stat for Windows.
 func statNolog(name string) (FileInfo, error) {
 	if len(name) == 0 {
-		return nil, &PathError{"Stat", name, syscall.Errno(syscall.ERROR_PATH_NOT_FOUND)}
+		return nil, &PathError{Op: "Stat", Path: name, Err: syscall.Errno(syscall.ERROR_PATH_NOT_FOUND)}
 	}
 	if name == DevNull {
 		return &devNullStat, nil
 	}
 	namep, err := syscall.UTF16PtrFromString(fixLongPath(name))
 	if err != nil {
-		return nil, &PathError{"Stat", name, err}
+		return nil, &PathError{Op: "Stat", Path: name, Err: err}
 	}
 	// Apparently (see https://golang.org/issues/19922#issuecomment-300031421)
 	// GetFileAttributesEx is fastest approach to get file info.
@@ -79,7 +79,7 @@ func statNolog(name string) (FileInfo, error) {
 		if !isAbs(fs.path) {
 			fs.path, err = syscall.FullPath(fs.path)
 			if err != nil {
-				return nil, &PathError{"FullPath", name, err}
+				return nil, &PathError{Op: "FullPath", Path: name, Err: err}
 			}
 		}
 		fs.name = basename(name)
@@ -94,14 +94,14 @@ func statNolog(name string) (FileInfo, error) {
 			// try FindFirstFile now that CreateFile failed
 			return statWithFindFirstFile(name, namep)
 		}
-		return nil, &PathError{"CreateFile", name, err}
+		return nil, &PathError{Op: "CreateFile", Path: name, Err: err}
 	}
 	defer syscall.CloseHandle(h)
 
 	var d syscall.ByHandleFileInformation
 	err = syscall.GetFileInformationByHandle(h, &d)
 	if err != nil {
-		return nil, &PathError{"GetFileInformationByHandle", name, err}
+		return nil, &PathError{Op: "GetFileInformationByHandle", Path: name, Err: err}
 	}
 	return &fileStat{
 		name: basename(name),
@@ -130,7 +130,7 @@ func statWithFindFirstFile(name string, namep *uint16) (FileInfo, error) {
 	var fd syscall.Win32finddata
 	h, err := syscall.FindFirstFile(namep, &fd)
 	if err != nil {
-		return nil, &PathError{"FindFirstFile", name, err}
+		return nil, &PathError{Op: "FindFirstFile", Path: name, Err: err}
 	}
 	syscall.FindClose(h)
 
@@ -138,7 +138,7 @@ func statWithFindFirstFile(name string, namep *uint16) (FileInfo, error) {
 	if !isAbs(fullpath) {
 		fullpath, err = syscall.FullPath(fullpath)
 		if err != nil {
-			return nil, &PathError{"FullPath", name, err}
+			return nil, &PathError{Op: "FullPath", Path: name, Err: err}
 		}
 	}
 	return &fileStat{
@@ -158,7 +158,7 @@ func statWithFindFirstFile(name string, namep *uint16) (FileInfo, error) {
 // lstatNolog implements Lstat for Windows.
 func lstatNolog(name string) (FileInfo, error) {
 	if len(name) == 0 {
-		return nil, &PathError{"Lstat", name, syscall.Errno(syscall.ERROR_PATH_NOT_FOUND)}
+		return nil, &PathError{Op: "Lstat", Path: name, Err: syscall.Errno(syscall.ERROR_PATH_NOT_FOUND)}
 	}
 	if name == DevNull {
 		return &devNullStat, nil
@@ -166,12 +166,12 @@ func lstatNolog(name string) (FileInfo, error) {
 	fs := &fileStat{name: basename(name)}
 	namep, e := syscall.UTF16PtrFromString(fixLongPath(name))
 	if e != nil {
-		return nil, &PathError{"Lstat", name, e}
+		return nil, &PathError{Op: "Lstat", Path: name, Err: e}
 	}
 	e = syscall.GetFileAttributesEx(namep, syscall.GetFileExInfoStandard, (*byte)(unsafe.Pointer(&fs.sys)))
 	if e != nil {
 		if e != windows.ERROR_SHARING_VIOLATION {
-			return nil, &PathError{"GetFileAttributesEx", name, e}
+			return nil, &PathError{Op: "GetFileAttributesEx", Path: name, Err: e}
 		}
 		// try FindFirstFile now that GetFileAttributesEx failed
 		return statWithFindFirstFile(name, namep)
@@ -180,7 +180,7 @@ func lstatNolog(name string) (FileInfo, error) {
 	if !isAbs(fs.path) {
 		fs.path, e = syscall.FullPath(fs.path)
 		if e != nil {
-			return nil, &PathError{"FullPath", name, e}
+			return nil, &PathError{Op: "FullPath", Path: name, Err: e}
 		}
 	}
 	return fs, nil
@@ -0,0 +1,208 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package coverage provides access to the coverage data of programs
+// built with "go build -cover".
+//
+// When such a program exits, by returning from main.main or by calling
+// os.Exit, it writes its coverage meta-data and counter data files to
+// the directory named by the GOCOVERDIR environment variable. The files
+// can then be merged, compared and converted to the text profile format
+// read by "go tool cover" with "go tool covdata".
+//
+// The functions in this package let a program write its coverage data
+// at other times, for instance a server that is never expected to exit,
+// or that wants to report coverage for each of a series of requests.
+// In a program that was not built with -cover they return an error.
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"internal/coverage"
+	"internal/coverage/rtcov"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// runtime_addExitHook is provided by the runtime.
+func runtime_addExitHook(f func())
+
+func init() {
+	runtime_addExitHook(emitOnExit)
+}
+
+var errNoCoverage = errors.New("coverage: program not built with -cover")
+
+// emitOnExit writes the coverage data of the program
+// to the directory named by $GOCOVERDIR.
+func emitOnExit() {
+	if mode, _ := rtcov.Files(); mode == "" {
+		return
+	}
+	dir := os.Getenv("GOCOVERDIR")
+	if dir == "" {
+		fmt.Fprintf(os.Stderr, "warning: GOCOVERDIR not set, no coverage data emitted\n")
+		return
+	}
+	if err := WriteMetaDir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "error: coverage meta-data emit failed: %v\n", err)
+		return
+	}
+	if err := WriteCountersDir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "error: coverage counter data emit failed: %v\n", err)
+	}
+}
+
+// meta returns the meta-data of the program, its encoding and its hash,
+// and the counters of its files, in the order of the meta-data.
+func meta() (*coverage.Meta, []byte, coverage.Hash, []rtcov.File, error) {
+	mode, files := rtcov.Files()
+	if mode == "" {
+		return nil, nil, coverage.Hash{}, nil, errNoCoverage
+	}
+	m := &coverage.Meta{Mode: mode}
+	pkgIndex := make(map[string]int)
+	var pkgFiles [][]rtcov.File
+	for _, f := range files {
+		i, ok := pkgIndex[f.PkgPath]
+		if !ok {
+			i = len(m.Packages)
+			pkgIndex[f.PkgPath] = i
+			m.Packages = append(m.Packages, coverage.Package{Path: f.PkgPath})
+			pkgFiles = append(pkgFiles, nil)
+		}
+		blocks := make([]coverage.Block, len(f.Count))
+		for j := range blocks {
+			blocks[j] = coverage.Block{
+				StartLine: f.Pos[3*j],
+				StartCol:  f.Pos[3*j+2] & 0xFFFF,
+				EndLine:   f.Pos[3*j+1],
+				EndCol:    f.Pos[3*j+2] >> 16,
+				NumStmt:   uint32(f.NumStmt[j]),
+			}
+		}
+		p := &m.Packages[i]
+		p.Files = append(p.Files, coverage.File{Name: f.Name, Blocks: blocks})
+		pkgFiles[i] = append(pkgFiles[i], f)
+	}
+	var ordered []rtcov.File
+	for _, fs := range pkgFiles {
+		ordered = append(ordered, fs...)
+	}
+	data := m.Encode()
+	return m, data, coverage.HashMeta(data), ordered, nil
+}
+
+// WriteMeta writes the coverage meta-data of the program to w.
+func WriteMeta(w io.Writer) error {
+	_, data, _, _, err := meta()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteMetaDir writes the coverage meta-data file of the program
+// to the directory dir, unless the directory holds it already.
+func WriteMetaDir(dir string) error {
+	_, data, hash, _, err := meta()
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(dir, coverage.MetaFileName(hash))
+	if _, err := os.Stat(name); err == nil {
+		return nil
+	}
+	return writeFile(dir, name, data)
+}
+
+// counters returns the current counter data of the program.
+func counters() (*coverage.Counters, error) {
+	m, _, hash, files, err := meta()
+	if err != nil {
+		return nil, err
+	}
+	c := &coverage.Counters{MetaHash: hash}
+	for _, f := range files {
+		counts := make([]uint32, len(f.Count))
+		if m.Mode == "atomic" {
+			for i := range f.Count {
+				counts[i] = atomic.LoadUint32(&f.Count[i])
+			}
+		} else {
+			copy(counts, f.Count)
+		}
+		c.Counts = append(c.Counts, counts)
+	}
+	return c, nil
+}
+
+// WriteCounters writes the current coverage counter data of the program to w.
+func WriteCounters(w io.Writer) error {
+	c, err := counters()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(c.Encode())
+	return err
+}
+
+// WriteCountersDir writes the current coverage counter data of the
+// program to a new counter data file in the directory dir.
+// The meta-data file, written by WriteMetaDir, must be present in
+// the same directory for the data to be usable.
+func WriteCountersDir(dir string) error {
+	c, err := counters()
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(dir, coverage.CounterFileName(c.MetaHash, os.Getpid(), time.Now().UnixNano()))
+	return writeFile(dir, name, c.Encode())
+}
+
+// ClearCounters resets the coverage counters of the program to zero.
+// Because the counters may be updated concurrently, it is only
+// supported in programs built with -covermode=atomic.
+func ClearCounters() error {
+	mode, files := rtcov.Files()
+	switch mode {
+	case "":
+		return errNoCoverage
+	case "atomic":
+	default:
+		return errors.New("coverage: ClearCounters requires -covermode=atomic, program uses " + mode)
+	}
+	for _, f := range files {
+		for i := range f.Count {
+			atomic.StoreUint32(&f.Count[i], 0)
+		}
+	}
+	return nil
+}
+
+// writeFile writes data to the named file in dir, going through
+// a temporary file so that readers never see a partial file.
+func writeFile(dir, name string, data []byte) error {
+	f, err := ioutil.TempFile(dir, "tmp."+filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -92,6 +92,29 @@ func SetGCPercent(percent int) int {
 	return int(setGCPercent(int32(percent)))
 }
 
+// SetMemoryLimit sets a soft limit, in bytes, on the memory used by
+// the Go runtime: all the memory it has mapped and not released to
+// the operating system, including the heap, goroutine stacks and the
+// runtime's own data structures, but not the program's executable or
+// memory allocated outside the runtime, such as by C code.
+// SetMemoryLimit returns the previous limit. A negative limit does not
+// change the limit, so SetMemoryLimit(-1) returns the current one.
+//
+// As memory use approaches the limit, the garbage collector runs more
+// often, even if garbage collection is disabled by SetGCPercent, and
+// the runtime returns idle memory to the operating system eagerly.
+// The limit is soft: memory use may exceed it, notably when the live
+// heap does not fit, since the collector limits itself to about half
+// of the available CPU time rather than run continuously.
+//
+// The initial setting is the value of the GOMEMLIMIT environment
+// variable at startup, or math.MaxInt64, meaning no limit, if the
+// variable is not set. GOMEMLIMIT is a number of bytes, with an
+// optional unit suffix of B, KiB, MiB, GiB or TiB.
+func SetMemoryLimit(limit int64) int64 {
+	return setMemoryLimit(limit)
+}
+
 // FreeOSMemory forces a garbage collection followed by an
 // attempt to return as much memory to the operating system
 // as possible. (Even if this is not called, the runtime gradually
@@ -172,6 +172,50 @@ func TestSetGCPercent(t *testing.T) {
 	}
 }
 
+var setMemoryLimitSink []byte
+
+func TestSetMemoryLimit(t *testing.T) {
+	// Test that the limit is being set and returned correctly.
+	old := SetMemoryLimit(123 << 20)
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(123<<20); SetMemoryLimit(-1) = %d, want %d", got, 123<<20)
+	}
+	if got := SetMemoryLimit(old); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(123<<20); SetMemoryLimit(x) = %d, want %d", got, 123<<20)
+	}
+
+	// Test that the limit triggers collections with GC disabled,
+	// and bounds the memory in use.
+	defer func() {
+		SetMemoryLimit(old)
+		setMemoryLimitSink = nil
+	}()
+	defer SetGCPercent(SetGCPercent(-1))
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	limit := ms.Sys - ms.HeapReleased + 32<<20
+	SetMemoryLimit(int64(limit))
+	ngc := ms.NumGC
+	var peak uint64
+	for i := 0; i < 512; i++ {
+		setMemoryLimitSink = make([]byte, 1<<20)
+		if i%16 == 0 {
+			runtime.ReadMemStats(&ms)
+			if inUse := ms.Sys - ms.HeapReleased; inUse > peak {
+				peak = inUse
+			}
+		}
+	}
+	runtime.ReadMemStats(&ms)
+	if ms.NumGC == ngc {
+		t.Errorf("allocated 512 MB with GC disabled and a %d MB memory limit, but GC did not run", limit>>20)
+	}
+	if peak > limit+limit/10 {
+		t.Errorf("memory in use peaked at %d MB, want at most %d MB, the limit, plus 10%%", peak>>20, (limit+limit/10)>>20)
+	}
+}
+
 func abs64(a int64) int64 {
 	if a < 0 {
 		return -a
@@ -21,12 +21,26 @@ is GOGC=100. Setting GOGC=off disables the garbage collector entirely.
 The runtime/debug package's SetGCPercent function allows changing this
 percentage at run time. See https://golang.org/pkg/runtime/debug/#SetGCPercent.
 
+The GOMEMLIMIT variable sets a soft limit on the memory used by the Go runtime,
+in bytes, with an optional unit suffix of B, KiB, MiB, GiB or TiB. As memory
+use approaches the limit, collections are triggered more often, even with
+GOGC=off, and idle memory is returned to the operating system eagerly. The
+default is GOMEMLIMIT=off, meaning no limit. The runtime/debug package's
+SetMemoryLimit function allows changing this limit at run time.
+
 The GODEBUG variable controls debugging variables within the runtime.
 It is a comma-separated list of name=val pairs setting these named variables:
 
 	allocfreetrace: setting allocfreetrace=1 causes every allocation to be
 	profiled and a stack trace printed on each object's allocation and free.
 
+	asyncpreemptoff: setting asyncpreemptoff=1 disables signal-based
+	asynchronous goroutine preemption. This makes some loops
+	non-preemptible for long periods, which may delay GC and
+	goroutine scheduling. This is useful for debugging GC issues
+	because it also disables the conservative stack scanning used
+	for asynchronously preempted goroutines.
+
 	cgocheck: setting cgocheck=0 disables all checks for packages
 	using cgo to incorrectly pass Go pointers to non-Go code.
 	Setting cgocheck=1 (the default) enables relatively cheap
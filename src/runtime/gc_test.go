@@ -517,6 +517,47 @@ func TestUserForcedGC(t *testing.T) {
 	}
 }
 
+func TestParseByteCount(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want uint64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"1024", 1024, true},
+		{"1024B", 1024, true},
+		{"1KiB", 1 << 10, true},
+		{"512MiB", 512 << 20, true},
+		{"2GiB", 2 << 30, true},
+		{"3TiB", 3 << 40, true},
+		{"9223372036854775807", 1<<63 - 1, true},
+		{"9223372036854775807B", 1<<63 - 1, true},
+		{"", 0, false},
+		{"B", 0, false},
+		{"MiB", 0, false},
+		{"-1", 0, false},
+		{"1.5GiB", 0, false},
+		{"1MB", 0, false},
+		{"1 MiB", 0, false},
+		{"9223372036854775808", 0, false},
+		{"8388608TiB", 0, false},
+		{"99999999999999999999", 0, false},
+	} {
+		got, ok := runtime.ParseByteCount(test.in)
+		if got != test.want || ok != test.ok {
+			t.Errorf("ParseByteCount(%q) = %d, %v, want %d, %v", test.in, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestGOMEMLIMIT(t *testing.T) {
+	got := runTestProg(t, "testprog", "GCMemoryLimit", "GOMEMLIMIT=100MiB")
+	want := "104857600\n"
+	if got != want {
+		t.Fatalf("expected %q, but got %q", want, got)
+	}
+}
+
 func writeBarrierBenchmark(b *testing.B, f func()) {
 	runtime.GC()
 	var ms runtime.MemStats
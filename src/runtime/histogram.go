@@ -0,0 +1,109 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+)
+
+const (
+	// For the time histogram type, we use an HDR-style histogram
+	// with super-buckets, one for each power of two of nanoseconds,
+	// each divided linearly into timeHistNumSubBuckets sub-buckets.
+	// The first super-bucket is special: it holds the durations
+	// 0 to timeHistNumSubBuckets-1 ns, one per sub-bucket.
+	//
+	// Hence each bucket spans at most 1/timeHistNumSubBuckets of
+	// its lower bound, and the buckets reach
+	// 2^(timeHistNumSuperBuckets+timeHistSubBucketBits-1) ns,
+	// about 19.5 hours. Longer durations are counted in overflow.
+	timeHistSubBucketBits   = 3
+	timeHistNumSubBuckets   = 1 << timeHistSubBucketBits
+	timeHistNumSuperBuckets = 44
+	timeHistNumBuckets      = timeHistNumSuperBuckets * timeHistNumSubBuckets
+)
+
+// timeHistogram is a histogram of durations, such as pause times,
+// which may be updated concurrently. It must be 8-byte aligned;
+// global variables of this type are.
+type timeHistogram struct {
+	counts   [timeHistNumBuckets]uint64
+	overflow uint64
+}
+
+// record adds the duration, in nanoseconds, to the histogram.
+// Negative durations, as may result from clock skew, count as 0.
+//
+//go:nosplit
+func (h *timeHistogram) record(duration int64) {
+	if duration < 0 {
+		duration = 0
+	}
+	var superBucket, subBucket uint
+	if duration >= timeHistNumSubBuckets {
+		// The super-bucket is given by the position of the
+		// highest set bit, and the sub-bucket by the
+		// timeHistSubBucketBits bits below it.
+		superBucket = uint(bitLen64(uint64(duration))) - timeHistSubBucketBits
+		if superBucket >= timeHistNumSuperBuckets {
+			atomic.Xadd64(&h.overflow, 1)
+			return
+		}
+		subBucket = uint(duration>>(superBucket-1)) % timeHistNumSubBuckets
+	} else {
+		subBucket = uint(duration)
+	}
+	atomic.Xadd64(&h.counts[superBucket*timeHistNumSubBuckets+subBucket], 1)
+}
+
+// bitLen64 returns the number of bits needed to represent x.
+//
+//go:nosplit
+func bitLen64(x uint64) int {
+	n := 0
+	for ; x >= 1<<16; x >>= 16 {
+		n += 16
+	}
+	for ; x != 0; x >>= 1 {
+		n++
+	}
+	return n
+}
+
+// timeHistogramLowerBound returns the lower bound,
+// in nanoseconds, of bucket i of a timeHistogram.
+func timeHistogramLowerBound(i int) int64 {
+	superBucket, subBucket := uint(i/timeHistNumSubBuckets), int64(i%timeHistNumSubBuckets)
+	if superBucket == 0 {
+		return subBucket
+	}
+	return 1<<(superBucket+timeHistSubBucketBits-1) + subBucket<<(superBucket-1)
+}
+
+// timeHistogramMetricsBuckets returns the bucket boundaries, in
+// seconds, of a timeHistogram as a metricFloat64Histogram: the lower
+// bound of each bucket, then the upper bound of the last one and
+// +Inf, bounding the overflow bucket.
+func timeHistogramMetricsBuckets() []float64 {
+	b := make([]float64, timeHistNumBuckets+2)
+	for i := 0; i <= timeHistNumBuckets; i++ {
+		b[i] = float64(timeHistogramLowerBound(i)) / 1e9
+	}
+	b[timeHistNumBuckets+1] = inf
+	return b
+}
+
+// write copies the counts of h, ending with the overflow count,
+// to the metric histogram.
+func (h *timeHistogram) write(out *metricFloat64Histogram) {
+	if cap(out.counts) < timeHistNumBuckets+1 {
+		out.counts = make([]uint64, timeHistNumBuckets+1)
+	}
+	out.counts = out.counts[:timeHistNumBuckets+1]
+	for i := range h.counts {
+		out.counts[i] = atomic.Load64(&h.counts[i])
+	}
+	out.counts[timeHistNumBuckets] = atomic.Load64(&h.overflow)
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	. "runtime"
+	"testing"
+)
+
+var dummyTimeHistogram TimeHistogram
+
+func TestTimeHistogram(t *testing.T) {
+	// We need to use a global dummy because this
+	// could get stack-allocated with a non-8-byte alignment.
+	// The result of this bad alignment is a segfault on
+	// 32-bit platforms when calling Record.
+	h := &dummyTimeHistogram
+
+	// Record exactly one sample in each bucket.
+	for i := 0; i < TimeHistNumSuperBuckets; i++ {
+		var base int64
+		if i > 0 {
+			base = int64(1) << (uint(i) + TimeHistSubBucketBits - 1)
+		}
+		for j := 0; j < TimeHistNumSubBuckets; j++ {
+			v := int64(j)
+			if i > 0 {
+				v <<= uint(i) - 1
+			}
+			h.Record(base + v)
+		}
+	}
+	// Hit the overflow bucket.
+	h.Record(int64(^uint64(0) >> 1))
+	// A negative duration counts as zero.
+	h.Record(-1)
+
+	// Check to make sure there's exactly one count in each
+	// bucket, and two in the first.
+	for i := uint(0); i < TimeHistNumSuperBuckets; i++ {
+		for j := uint(0); j < TimeHistNumSubBuckets; j++ {
+			want := uint64(1)
+			if i == 0 && j == 0 {
+				want = 2
+			}
+			c, ok := h.Count(i, j)
+			if !ok {
+				t.Errorf("hit overflow bucket unexpectedly: (%d, %d)", i, j)
+			} else if c != want {
+				t.Errorf("bucket (%d, %d) has count that is not %d: %d", i, j, want, c)
+			}
+		}
+	}
+	c, ok := h.Count(TimeHistNumSuperBuckets, 0)
+	if ok {
+		t.Errorf("overflow bucket has valid index")
+	} else if c != 1 {
+		t.Errorf("overflow bucket has count that is not 1: %d", c)
+	}
+	dummyTimeHistogram = TimeHistogram{}
+}
+
+func TestTimeHistogramLowerBound(t *testing.T) {
+	var last int64 = -1
+	for i := 0; i <= TimeHistNumSuperBuckets*TimeHistNumSubBuckets; i++ {
+		b := TimeHistogramLowerBound(i)
+		if b <= last {
+			t.Fatalf("lower bound of bucket %d is %d, not more than that of bucket %d, %d", i, b, i-1, last)
+		}
+		last = b
+	}
+	if want := int64(1) << (TimeHistNumSuperBuckets + TimeHistSubBucketBits - 1); last != want {
+		t.Errorf("upper bound of last bucket is %d, want %d", last, want)
+	}
+}
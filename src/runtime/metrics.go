@@ -0,0 +1,287 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// Metrics implementation exported to runtime/metrics.
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+var (
+	// metricsSema serializes calls to readMetrics, which share agg,
+	// and protects the construction of metrics on first use.
+	metricsSema uint32 = 1
+
+	// metrics maps the name of each supported metric to the
+	// function computing its value.
+	metrics map[string]func(*statAggregate, *metricValue)
+
+	// agg is the statistics aggregate for the current readMetrics.
+	// It is global, rather than on the stack of readMetrics, since
+	// it is passed to the functions in metrics.
+	agg statAggregate
+
+	// timeHistBuckets are the shared bucket boundaries
+	// of the time histogram metrics.
+	timeHistBuckets []float64
+)
+
+// Durations of the stop-the-world pauses of the garbage collector,
+// and of the time goroutines spend runnable before they run.
+var (
+	gcPauseDist      timeHistogram
+	schedLatencyDist timeHistogram
+)
+
+// gTrackingPeriod is how often the scheduling latency of a goroutine
+// is measured: the first time it becomes runnable, and every
+// gTrackingPeriod-th time after that.
+const gTrackingPeriod = 8
+
+// mutexWaitTime is the total time, in nanoseconds, goroutines have
+// spent blocked on a sync.Mutex. It is updated atomically.
+var mutexWaitTime uint64
+
+func initMetrics() {
+	if metrics != nil {
+		return
+	}
+	timeHistBuckets = timeHistogramMetricsBuckets()
+	metrics = map[string]func(*statAggregate, *metricValue){
+		"/gc/cycles/automatic:gc-cycles": func(in *statAggregate, out *metricValue) {
+			out.setUint64(uint64(atomic.Load(&memstats.numgc) - atomic.Load(&memstats.numforcedgc)))
+		},
+		"/gc/cycles/forced:gc-cycles": func(in *statAggregate, out *metricValue) {
+			out.setUint64(uint64(atomic.Load(&memstats.numforcedgc)))
+		},
+		"/gc/cycles/total:gc-cycles": func(in *statAggregate, out *metricValue) {
+			out.setUint64(uint64(atomic.Load(&memstats.numgc)))
+		},
+		"/gc/gomemlimit:bytes": func(in *statAggregate, out *metricValue) {
+			out.setUint64(atomic.Load64(&memoryLimit))
+		},
+		"/gc/heap/goal:bytes": func(in *statAggregate, out *metricValue) {
+			out.setUint64(atomic.Load64(&memstats.next_gc))
+		},
+		"/gc/heap/live:bytes": func(in *statAggregate, out *metricValue) {
+			out.setUint64(atomic.Load64(&memstats.heap_marked))
+		},
+		"/gc/pauses:seconds": func(in *statAggregate, out *metricValue) {
+			gcPauseDist.write(out.float64HistOrInit(timeHistBuckets))
+		},
+		"/memory/classes/heap/free:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.heapFree)
+		},
+		"/memory/classes/heap/objects:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.heapObjects)
+		},
+		"/memory/classes/heap/released:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.heapReleased)
+		},
+		"/memory/classes/heap/stacks:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.stacksInUse)
+		},
+		"/memory/classes/heap/unused:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.heapUnused)
+		},
+		"/memory/classes/metadata/mcache/free:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.mcacheSys - in.mcacheInUse)
+		},
+		"/memory/classes/metadata/mcache/inuse:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.mcacheInUse)
+		},
+		"/memory/classes/metadata/mspan/free:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.mspanSys - in.mspanInUse)
+		},
+		"/memory/classes/metadata/mspan/inuse:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.mspanInUse)
+		},
+		"/memory/classes/metadata/other:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.gcSys)
+		},
+		"/memory/classes/os-stacks:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.stacksSys)
+		},
+		"/memory/classes/other:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.otherSys)
+		},
+		"/memory/classes/profiling/buckets:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.buckHashSys)
+		},
+		"/memory/classes/total:bytes": func(in *statAggregate, out *metricValue) {
+			in.ensureHeapStats()
+			out.setUint64(in.heapObjects + in.heapUnused + in.heapFree + in.heapReleased +
+				in.stacksInUse + in.stacksSys + in.mspanSys + in.mcacheSys +
+				in.buckHashSys + in.gcSys + in.otherSys)
+		},
+		"/sched/gomaxprocs:threads": func(in *statAggregate, out *metricValue) {
+			out.setUint64(uint64(gomaxprocs))
+		},
+		"/sched/goroutines:goroutines": func(in *statAggregate, out *metricValue) {
+			out.setUint64(uint64(gcount()))
+		},
+		"/sched/latencies:seconds": func(in *statAggregate, out *metricValue) {
+			schedLatencyDist.write(out.float64HistOrInit(timeHistBuckets))
+		},
+		"/sync/mutex/wait/total:seconds": func(in *statAggregate, out *metricValue) {
+			out.setFloat64(float64(atomic.Load64(&mutexWaitTime)) / 1e9)
+		},
+	}
+}
+
+// statAggregate is a snapshot of runtime statistics needed by more
+// than one metric, computed at most once per call to readMetrics.
+type statAggregate struct {
+	haveHeapStats bool
+
+	heapObjects  uint64 // bytes of objects allocated and not known to be free
+	heapUnused   uint64 // bytes of in-use spans not in heapObjects
+	heapFree     uint64 // bytes of idle spans not released to the OS
+	heapReleased uint64
+	stacksInUse  uint64
+	stacksSys    uint64
+	mspanInUse   uint64
+	mspanSys     uint64
+	mcacheInUse  uint64
+	mcacheSys    uint64
+	buckHashSys  uint64
+	gcSys        uint64
+	otherSys     uint64
+}
+
+// ensureHeapStats fills in the memory statistics of a, reading them
+// under the heap lock, but without stopping the world.
+func (a *statAggregate) ensureHeapStats() {
+	if a.haveHeapStats {
+		return
+	}
+	a.haveHeapStats = true
+	systemstack(func() {
+		lock(&mheap_.lock)
+		// heap_live counts the objects marked by the last GC and
+		// all space handed out for allocation since, including the
+		// free space in the spans held by mcaches. Unlike
+		// heap_alloc, it is kept up to date without flushing them.
+		a.heapObjects = atomic.Load64(&memstats.heap_live)
+		if inuse := memstats.heap_inuse; inuse > a.heapObjects {
+			a.heapUnused = inuse - a.heapObjects
+		} else {
+			a.heapObjects = inuse
+		}
+		a.heapFree = memstats.heap_idle - memstats.heap_released
+		a.heapReleased = memstats.heap_released
+		a.stacksInUse = memstats.stacks_inuse
+		a.mspanInUse = uint64(mheap_.spanalloc.inuse)
+		a.mspanSys = memstats.mspan_sys
+		a.mcacheInUse = uint64(mheap_.cachealloc.inuse)
+		a.mcacheSys = memstats.mcache_sys
+		unlock(&mheap_.lock)
+	})
+	// These are updated atomically, outside the heap lock.
+	a.stacksSys = atomic.Load64(&memstats.stacks_sys)
+	a.buckHashSys = atomic.Load64(&memstats.buckhash_sys)
+	a.gcSys = atomic.Load64(&memstats.gc_sys)
+	a.otherSys = atomic.Load64(&memstats.other_sys)
+}
+
+// metricKind is a runtime copy of runtime/metrics.ValueKind and
+// must be kept structurally identical to that type.
+type metricKind int
+
+const (
+	// These values must be kept identical to their corresponding Kind* values
+	// in the runtime/metrics package.
+	metricKindBad metricKind = iota
+	metricKindUint64
+	metricKindFloat64
+	metricKindFloat64Histogram
+)
+
+// metricSample is a runtime copy of runtime/metrics.Sample and
+// must be kept structurally identical to that type.
+type metricSample struct {
+	name  string
+	value metricValue
+}
+
+// metricValue is a runtime copy of runtime/metrics.Value and
+// must be kept structurally identical to that type.
+type metricValue struct {
+	kind    metricKind
+	scalar  uint64         // contains scalar values for scalar Kinds.
+	pointer unsafe.Pointer // contains non-scalar values.
+}
+
+// metricFloat64Histogram is a runtime copy of
+// runtime/metrics.Float64Histogram and must be kept
+// structurally identical to that type.
+type metricFloat64Histogram struct {
+	counts  []uint64
+	buckets []float64
+}
+
+func (v *metricValue) setUint64(x uint64) {
+	v.kind = metricKindUint64
+	v.scalar = x
+}
+
+func (v *metricValue) setFloat64(x float64) {
+	v.kind = metricKindFloat64
+	v.scalar = float64bits(x)
+}
+
+// float64HistOrInit returns the histogram of v, reusing the one
+// from a previous read of the sample if there is one, and sets its
+// bucket boundaries, which are shared and must not be modified.
+func (v *metricValue) float64HistOrInit(buckets []float64) *metricFloat64Histogram {
+	var hist *metricFloat64Histogram
+	if v.kind == metricKindFloat64Histogram && v.pointer != nil {
+		hist = (*metricFloat64Histogram)(v.pointer)
+	} else {
+		v.kind = metricKindFloat64Histogram
+		hist = new(metricFloat64Histogram)
+		v.pointer = unsafe.Pointer(hist)
+	}
+	hist.buckets = buckets
+	return hist
+}
+
+// readMetrics is the implementation of runtime/metrics.Read.
+//
+//go:linkname readMetrics runtime/metrics.runtime_readMetrics
+func readMetrics(samplesp unsafe.Pointer, len int, cap int) {
+	// Construct a slice from the args.
+	sl := slice{samplesp, len, cap}
+	samples := *(*[]metricSample)(unsafe.Pointer(&sl))
+
+	semacquire(&metricsSema)
+	initMetrics()
+	agg = statAggregate{}
+	for i := range samples {
+		sample := &samples[i]
+		compute, ok := metrics[sample.name]
+		if !ok {
+			sample.value.kind = metricKindBad
+			continue
+		}
+		compute(&agg, &sample.value)
+	}
+	semrelease(&metricsSema)
+}
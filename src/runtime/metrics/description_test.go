@@ -0,0 +1,48 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics_test
+
+import (
+	"io/ioutil"
+	"regexp"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDescriptionNameFormat(t *testing.T) {
+	r := regexp.MustCompile("^(?P<name>/[^:]+):(?P<unit>[^:*/]+(?:[*/][^:*/]+)*)$")
+	descriptions := metrics.All()
+	for _, desc := range descriptions {
+		if !r.MatchString(desc.Name) {
+			t.Errorf("metrics %q does not match regexp %s", desc.Name, r)
+		}
+	}
+	if !sort.SliceIsSorted(descriptions, func(i, j int) bool {
+		return descriptions[i].Name < descriptions[j].Name
+	}) {
+		t.Errorf("descriptions are not sorted by name")
+	}
+	for i := 1; i < len(descriptions); i++ {
+		if descriptions[i].Name == descriptions[i-1].Name {
+			t.Errorf("duplicate metric %q", descriptions[i].Name)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	b, err := ioutil.ReadFile("doc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := strings.Join(strings.Fields(string(b)), " ")
+	for _, desc := range metrics.All() {
+		want := desc.Name + " " + desc.Description
+		if !strings.Contains(doc, want) {
+			t.Errorf("doc.go does not describe %s as %q", desc.Name, desc.Description)
+		}
+	}
+}
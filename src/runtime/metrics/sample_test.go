@@ -0,0 +1,176 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics_test
+
+import (
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"testing"
+	"time"
+)
+
+func prepareAllMetricsSamples() (map[string]metrics.Description, []metrics.Sample) {
+	all := metrics.All()
+	samples := make([]metrics.Sample, len(all))
+	descs := make(map[string]metrics.Description)
+	for i := range all {
+		samples[i].Name = all[i].Name
+		descs[all[i].Name] = all[i]
+	}
+	return descs, samples
+}
+
+func TestReadMetricsKinds(t *testing.T) {
+	descs, samples := prepareAllMetricsSamples()
+	metrics.Read(samples)
+	for _, s := range samples {
+		desc := descs[s.Name]
+		if s.Value.Kind() != desc.Kind {
+			t.Errorf("metric %q has kind %v, want %v", s.Name, s.Value.Kind(), desc.Kind)
+			continue
+		}
+		if desc.Kind == metrics.KindFloat64Histogram {
+			h := s.Value.Float64Histogram()
+			if len(h.Buckets) != len(h.Counts)+1 {
+				t.Errorf("histogram %q has %d buckets for %d counts", s.Name, len(h.Buckets), len(h.Counts))
+				continue
+			}
+			for i := 1; i < len(h.Buckets); i++ {
+				if h.Buckets[i] <= h.Buckets[i-1] {
+					t.Errorf("histogram %q buckets not in increasing order at %d", s.Name, i)
+					break
+				}
+			}
+		}
+	}
+
+	// An unknown metric reads as KindBad.
+	bad := []metrics.Sample{{Name: "/no/such/metric:bytes"}}
+	metrics.Read(bad)
+	if bad[0].Value.Kind() != metrics.KindBad {
+		t.Errorf("unknown metric has kind %v, want KindBad", bad[0].Value.Kind())
+	}
+
+	// Reading nothing is fine.
+	metrics.Read(nil)
+}
+
+func TestReadMetricsValues(t *testing.T) {
+	// Generate some mutex contention, scheduling and GC activity.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mu.Lock()
+				time.Sleep(10 * time.Microsecond)
+				mu.Unlock()
+				runtime.Gosched()
+			}
+		}()
+	}
+	wg.Wait()
+	runtime.GC()
+
+	_, samples := prepareAllMetricsSamples()
+	metrics.Read(samples)
+	var mstats runtime.MemStats
+	runtime.ReadMemStats(&mstats)
+
+	var totalClasses uint64
+	values := make(map[string]metrics.Value)
+	for _, s := range samples {
+		values[s.Name] = s.Value
+		if len(s.Name) > len("/memory/classes/") && s.Name[:len("/memory/classes/")] == "/memory/classes/" && s.Name != "/memory/classes/total:bytes" {
+			totalClasses += s.Value.Uint64()
+		}
+	}
+	if got := values["/memory/classes/total:bytes"].Uint64(); got != totalClasses {
+		t.Errorf("/memory/classes/total:bytes = %d, want sum of classes %d", got, totalClasses)
+	}
+	// The runtime may map more memory between the two reads,
+	// but not a lot.
+	if total, sys := values["/memory/classes/total:bytes"].Uint64(), mstats.Sys; total > sys || float64(sys-total) > 0.1*float64(sys) {
+		t.Errorf("/memory/classes/total:bytes = %d, far from MemStats.Sys %d", total, sys)
+	}
+	if got, want := values["/memory/classes/heap/released:bytes"].Uint64(), mstats.HeapReleased; got > want+(1<<20) {
+		t.Errorf("/memory/classes/heap/released:bytes = %d, MemStats.HeapReleased = %d", got, want)
+	}
+	total := values["/gc/cycles/total:gc-cycles"].Uint64()
+	forced := values["/gc/cycles/forced:gc-cycles"].Uint64()
+	auto := values["/gc/cycles/automatic:gc-cycles"].Uint64()
+	if total < 1 || forced < 1 || auto+forced != total {
+		t.Errorf("gc cycles: total %d, forced %d, automatic %d", total, forced, auto)
+	}
+	if total > uint64(mstats.NumGC) {
+		t.Errorf("/gc/cycles/total:gc-cycles = %d, more than MemStats.NumGC %d", total, mstats.NumGC)
+	}
+	if got, want := values["/sched/gomaxprocs:threads"].Uint64(), uint64(runtime.GOMAXPROCS(0)); got != want {
+		t.Errorf("/sched/gomaxprocs:threads = %d, want %d", got, want)
+	}
+	if got := values["/sched/goroutines:goroutines"].Uint64(); got < 1 {
+		t.Errorf("/sched/goroutines:goroutines = %d, want at least 1", got)
+	}
+	if got := values["/sync/mutex/wait/total:seconds"].Float64(); got <= 0 || math.IsNaN(got) {
+		t.Errorf("/sync/mutex/wait/total:seconds = %v, want positive", got)
+	}
+	// runtime.GC stops the world twice.
+	if n := histogramCount(values["/gc/pauses:seconds"].Float64Histogram()); n < 2*total {
+		t.Errorf("/gc/pauses:seconds has %d pauses for %d cycles, want at least %d", n, total, 2*total)
+	}
+	if n := histogramCount(values["/sched/latencies:seconds"].Float64Histogram()); n == 0 {
+		t.Errorf("/sched/latencies:seconds has no samples")
+	}
+}
+
+func histogramCount(h *metrics.Float64Histogram) uint64 {
+	var n uint64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+func TestReadMetricsReuse(t *testing.T) {
+	s := []metrics.Sample{{Name: "/gc/pauses:seconds"}}
+	metrics.Read(s)
+	h := s[0].Value.Float64Histogram()
+	before := histogramCount(h)
+	runtime.GC()
+	metrics.Read(s)
+	if s[0].Value.Float64Histogram() != h {
+		t.Errorf("Read did not reuse the histogram of the sample")
+	}
+	if after := histogramCount(h); after <= before {
+		t.Errorf("pause count did not increase after GC: %d, then %d", before, after)
+	}
+}
+
+func TestReadMetricsConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, samples := prepareAllMetricsSamples()
+			for j := 0; j < 100; j++ {
+				metrics.Read(samples)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkReadMetricsLatency(b *testing.B) {
+	_, samples := prepareAllMetricsSamples()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		metrics.Read(samples)
+	}
+}
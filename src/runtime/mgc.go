@@ -189,8 +189,9 @@ func gcinit() {
 	// This will go into computing the initial GC goal.
 	memstats.heap_marked = uint64(float64(heapminimum) / (1 + memstats.triggerRatio))
 
-	// Set gcpercent from the environment. This will also compute
-	// and set the GC trigger and goal.
+	// Set the memory limit and gcpercent from the environment.
+	// This will also compute and set the GC trigger and goal.
+	memoryLimit = readgomemlimit()
 	_ = setGCPercent(readgogc())
 
 	work.startSema = 1
@@ -454,6 +455,9 @@ func (c *gcControllerState) startCycle() {
 	if gcpercent < 0 {
 		memstats.next_gc = ^uint64(0)
 	}
+	if limitGoal := memoryLimitHeapGoal(); limitGoal < memstats.next_gc {
+		memstats.next_gc = limitGoal
+	}
 
 	// Ensure that the heap goal is at least a little larger than
 	// the current live heap size. This may not be the case if GC
@@ -589,11 +593,13 @@ func (c *gcControllerState) revise() {
 
 // endCycle computes the trigger ratio for the next cycle.
 func (c *gcControllerState) endCycle() float64 {
-	if work.userForced {
+	if work.userForced || gcpercent < 0 {
 		// Forced GC means this cycle didn't start at the
 		// trigger, so where it finished isn't good
 		// information about how to adjust the trigger.
-		// Just leave it where it is.
+		// Just leave it where it is. Likewise if only the
+		// memory limit triggers GC, there is no GOGC-based
+		// goal to adjust the trigger to.
 		return memstats.triggerRatio
 	}
 
@@ -836,7 +842,6 @@ func gcSetTriggerRatio(triggerRatio float64) {
 			throw("gc_trigger underflow")
 		}
 	}
-	memstats.gc_trigger = trigger
 
 	// Compute the next GC goal, which is when the allocated heap
 	// has grown by GOGC/100 over the heap marked by the last
@@ -851,7 +856,30 @@ func gcSetTriggerRatio(triggerRatio float64) {
 			goal = trigger
 		}
 	}
+
+	// The memory limit may lower the goal, and the trigger with
+	// it. The trigger is placed at the same fraction of the way
+	// from heap_marked to the goal as the trigger ratio puts it
+	// for GOGC.
+	if limitGoal := memoryLimitHeapGoal(); limitGoal < goal {
+		goal = limitGoal
+		runway := 0.7
+		if gcpercent > 0 {
+			runway = triggerRatio / (float64(gcpercent) / 100)
+		}
+		if runway > 0.95 {
+			runway = 0.95
+		}
+		limitTrigger := memstats.heap_marked + uint64(runway*float64(goal-memstats.heap_marked))
+		if limitTrigger < trigger {
+			trigger = limitTrigger
+		}
+	}
+	memstats.gc_trigger = trigger
 	memstats.next_gc = goal
+	if goal != ^uint64(0) {
+		gcCPULimiter.runway = goal - memstats.heap_marked
+	}
 	if trace.enabled {
 		traceNextGC()
 	}
@@ -992,6 +1020,10 @@ var work struct {
 	// STW GC.
 	markrootDone bool
 
+	// findLeaks indicates that the mark phase of the current
+	// cycle must look for leaked goroutines. See mgcleak.go.
+	findLeaks bool
+
 	// Each type of GC state transition is protected by a lock.
 	// Since multiple threads can simultaneously detect the state
 	// transition condition, any thread that detects a transition
@@ -1163,6 +1195,7 @@ const (
 	gcBackgroundMode gcMode = iota // concurrent GC and sweep
 	gcForceMode                    // stop-the-world GC now, concurrent sweep
 	gcForceBlockMode               // stop-the-world GC now and STW sweep (forced by user)
+	gcForceLeakMode                // stop-the-world GC now that finds leaked goroutines, concurrent sweep
 )
 
 // A gcTrigger is a predicate for starting a GC cycle. Specifically,
@@ -1309,6 +1342,7 @@ func gcStart(mode gcMode, trigger gcTrigger) {
 	work.heap0 = atomic.Load64(&memstats.heap_live)
 	work.pauseNS = 0
 	work.mode = mode
+	work.findLeaks = mode == gcForceLeakMode
 
 	now := nanotime()
 	work.tSweepTerm = now
@@ -1372,6 +1406,7 @@ func gcStart(mode gcMode, trigger gcTrigger) {
 			now = startTheWorldWithSema(trace.enabled)
 		})
 		work.pauseNS += now - work.pauseStart
+		gcPauseDist.record(now - work.pauseStart)
 		work.tMark = now
 	} else {
 		if trace.enabled {
@@ -1596,14 +1631,12 @@ func gcMarkTermination(nextTriggerRatio float64) {
 		throw("gc done but gcphase != _GCoff")
 	}
 
-	// Update GC trigger and pacing for the next cycle.
-	gcSetTriggerRatio(nextTriggerRatio)
-
 	// Update timing memstats
 	now := nanotime()
 	sec, nsec, _ := time_now()
 	unixNow := sec*1e9 + int64(nsec)
 	work.pauseNS += now - work.pauseStart
+	gcPauseDist.record(now - work.pauseStart)
 	work.tEnd = now
 	atomic.Store64(&memstats.last_gc_unix, uint64(unixNow)) // must be Unix time to make sense to user
 	atomic.Store64(&memstats.last_gc_nanotime, uint64(now)) // monotonic time for us
@@ -1624,6 +1657,13 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	totalCpu := sched.totaltime + (now-sched.procresizetime)*int64(gomaxprocs)
 	memstats.gc_cpu_fraction = float64(work.totaltime) / float64(totalCpu)
 
+	// Update GC trigger and pacing for the next cycle, limiting
+	// the GC CPU utilization the memory limit may cause.
+	if !work.userForced {
+		gcCPULimiter.update(cycleCpu, now)
+	}
+	gcSetTriggerRatio(nextTriggerRatio)
+
 	// Reset sweep state.
 	sweep.nbgsweep = 0
 	sweep.npausesweep = 0
@@ -1953,6 +1993,12 @@ func gcMark(start_time int64) {
 	}
 	work.tstart = start_time
 
+	if work.findLeaks {
+		// Withhold the stacks of blocked goroutines from
+		// the roots before anything is marked.
+		gcLeakPrepare()
+	}
+
 	// Queue root marking jobs.
 	gcMarkRootPrepare()
 
@@ -1998,11 +2044,6 @@ func gcMark(start_time int64) {
 	}
 	gcw.dispose()
 
-	if debug.gccheckmark > 0 {
-		// This is expensive when there's a large number of
-		// Gs, so only do it if checkmark is also enabled.
-		gcMarkRootCheck()
-	}
 	if work.full != 0 {
 		throw("work.full != 0")
 	}
@@ -2011,6 +2052,20 @@ func gcMark(start_time int64) {
 		notesleep(&work.alldone)
 	}
 
+	if work.findLeaks {
+		// Mark from the withheld goroutines that are still
+		// reachable. This must wait for the helpers, since
+		// it depends on the mark being complete.
+		gcFindLeaks()
+		work.findLeaks = false
+	}
+
+	if debug.gccheckmark > 0 {
+		// This is expensive when there's a large number of
+		// Gs, so only do it if checkmark is also enabled.
+		gcMarkRootCheck()
+	}
+
 	// Record that at least one root marking pass has completed.
 	work.markrootDone = true
 
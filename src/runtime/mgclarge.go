@@ -251,6 +251,22 @@ func scavengetreap(treap *treapNode, now, limit uint64) uintptr {
 		scavengetreap(treap.right, now, limit)
 }
 
+// scavengetreapBytes scavenges the spans in the treap until at least
+// nbytes have been released, and returns the number of bytes released.
+func scavengetreapBytes(treap *treapNode, nbytes uint64) uint64 {
+	if treap == nil || nbytes == 0 {
+		return 0
+	}
+	released := uint64(treap.spanKey.scavenge())
+	if released < nbytes {
+		released += scavengetreapBytes(treap.left, nbytes-released)
+	}
+	if released < nbytes {
+		released += scavengetreapBytes(treap.right, nbytes-released)
+	}
+	return released
+}
+
 // rotateLeft rotates the tree rooted at node x.
 // turning (x a (y b c)) into (y (x a b) c).
 func (root *mTreap) rotateLeft(x *treapNode) {
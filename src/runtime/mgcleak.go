@@ -0,0 +1,208 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Garbage collector: goroutine leak detection.
+//
+// A goroutine blocked on a channel, a sync.Mutex, a sync.WaitGroup or
+// a sync.Cond can only be woken by another goroutine that operates on
+// the same object. If nothing but the blocked goroutines themselves
+// can reach that object, they are blocked forever: they have leaked.
+//
+// A leak-detecting GC finds such goroutines during the mark phase. It
+// runs with the world stopped. Before marking starts, gcLeakPrepare
+// withholds the stacks of blocked goroutines from the roots and hides
+// the runtime's own references from them to the objects they are
+// blocked on. Once the rest of the heap is marked, gcFindLeaks scans
+// the stack of each withheld goroutine whose object has been marked,
+// marks what it reaches and repeats until no more goroutines become
+// reachable. The goroutines that remain have leaked. Their stacks are
+// scanned too, so the leak never frees memory they still use.
+//
+// Leaks found this way are real, but not every leak is found: a
+// goroutine is considered reachable if anything outside its stack,
+// such as one of its deferred calls, refers to the object it is
+// blocked on. Likewise, a small object without pointers, such as a
+// lone sync.Mutex, shares its tiny allocation block with other objects
+// and is marked along with any of them.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// leakCycle is the last GC cycle that looked for leaked goroutines.
+// Accessed atomically.
+var leakCycle uint32
+
+// goroutineLeakGC runs a garbage collection that finds leaked
+// goroutines and blocks the caller until it is complete. The world is
+// stopped for the whole mark phase.
+//
+//go:linkname goroutineLeakGC runtime/pprof.runtime_goroutineLeakGC
+func goroutineLeakGC() {
+	gp := getg()
+	for memstats.enablegc {
+		// Wait for the mark phase of the current cycle, if
+		// any, to finish, as GC does.
+		lock(&work.sweepWaiters.lock)
+		n := atomic.Load(&work.cycles)
+		if gcphase == _GCmark {
+			gp.schedlink = work.sweepWaiters.head
+			work.sweepWaiters.head.set(gp)
+			goparkunlock(&work.sweepWaiters.lock, "wait for GC cycle", traceEvGoBlock, 1)
+		} else {
+			unlock(&work.sweepWaiters.lock)
+		}
+
+		// A forced cycle completes before gcStart returns.
+		// If another goroutine started cycle n+1 first, it
+		// did not look for leaks, so try again.
+		gcStart(gcForceLeakMode, gcTrigger{kind: gcTriggerCycle, n: n + 1})
+		if atomic.Load(&leakCycle) == n+1 {
+			return
+		}
+	}
+}
+
+// isLeakCandidate reports whether gp is blocked in a way that only
+// another goroutine can end, by operating on the object gp is blocked
+// on.
+func isLeakCandidate(gp *g) bool {
+	if readgstatus(gp) != _Gwaiting || isSystemGoroutine(gp) {
+		return false
+	}
+	switch gp.waitreason {
+	case "chan send", "chan receive", "select",
+		"chan send (nil chan)", "chan receive (nil chan)", "select (no cases)":
+		return true
+	case "semacquire":
+		return gp.blockedOn != 0
+	}
+	return false
+}
+
+// gcLeakPrepare withholds the blocked goroutines from the roots of a
+// leak-detecting GC and hides the sudog fields that refer to the
+// objects they are blocked on. The hidden fields are cleared without
+// write barriers, which would otherwise shade the objects.
+//
+// The world must be stopped and nothing must be marked yet.
+//
+//go:nowritebarrier
+func gcLeakPrepare() {
+	for _, gp := range allgs {
+		gp.leaked = false
+		if !isLeakCandidate(gp) {
+			continue
+		}
+		gp.leakCandidate = true
+		for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+			sg.hiddenc = uintptr(unsafe.Pointer(sg.c))
+			*(*uintptr)(unsafe.Pointer(&sg.c)) = 0
+		}
+	}
+	for i := range semtable {
+		hideSemaWaiters(semtable[i].root.treap, true)
+	}
+}
+
+// gcFindLeaks finishes the mark phase of a leak-detecting GC. It
+// repeatedly marks from the withheld goroutines whose blocking objects
+// have been marked. The goroutines that remain are flagged as leaked
+// and marked from as well.
+//
+// The world must be stopped and the mark helpers must be done.
+//
+//go:nowritebarrier
+func gcFindLeaks() {
+	gcw := &getg().m.p.ptr().gcw
+	for {
+		found := false
+		for _, gp := range allgs {
+			if gp.leakCandidate && isLeakCandidateReachable(gp) {
+				releaseLeakCandidate(gp, gcw)
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+		gcDrain(gcw, gcDrainNoBlock)
+	}
+
+	for _, gp := range allgs {
+		if gp.leakCandidate {
+			gp.leaked = true
+			releaseLeakCandidate(gp, gcw)
+		}
+	}
+	for i := range semtable {
+		hideSemaWaiters(semtable[i].root.treap, false)
+	}
+	gcDrain(gcw, gcDrainNoBlock)
+	gcw.dispose()
+
+	atomic.Store(&leakCycle, work.cycles)
+}
+
+// isLeakCandidateReachable reports whether any object gp is blocked on
+// has been marked.
+func isLeakCandidateReachable(gp *g) bool {
+	for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+		if sg.hiddenc != 0 && isMarkedOrNotHeap(sg.hiddenc) {
+			return true
+		}
+	}
+	return gp.blockedOn != 0 && isMarkedOrNotHeap(gp.blockedOn)
+}
+
+// isMarkedOrNotHeap reports whether the heap object containing p has
+// been marked. Anything outside the heap is always reachable.
+func isMarkedOrNotHeap(p uintptr) bool {
+	obj, _, span, objIndex := heapBitsForObject(p, 0, 0)
+	return obj == 0 || span.markBitsForIndex(objIndex).isMarked()
+}
+
+// releaseLeakCandidate restores and shades what gcLeakPrepare hid for
+// gp and scans gp's stack.
+//
+//go:nowritebarrier
+func releaseLeakCandidate(gp *g, gcw *gcWork) {
+	gp.leakCandidate = false
+	for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+		if sg.hiddenc != 0 {
+			*(*uintptr)(unsafe.Pointer(&sg.c)) = sg.hiddenc
+			shade(sg.hiddenc)
+			sg.hiddenc = 0
+		}
+	}
+	if gp.blockedOn != 0 {
+		shade(gp.blockedOn)
+	}
+	scang(gp, gcw)
+}
+
+// hideSemaWaiters hides or restores the semaphore address of each
+// leak candidate waiting in the semaRoot treap rooted at s. Restoring
+// does not shade the semaphores: releaseLeakCandidate already has.
+//
+//go:nowritebarrier
+func hideSemaWaiters(s *sudog, hide bool) {
+	if s == nil {
+		return
+	}
+	for t := s; t != nil; t = t.waitlink {
+		if hide && t.g.leakCandidate {
+			t.hiddenelem = uintptr(t.elem)
+			*(*uintptr)(unsafe.Pointer(&t.elem)) = 0
+		} else if !hide && t.hiddenelem != 0 {
+			*(*uintptr)(unsafe.Pointer(&t.elem)) = t.hiddenelem
+			t.hiddenelem = 0
+		}
+	}
+	hideSemaWaiters(s.prev, hide)
+	hideSemaWaiters(s.next, hide)
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Soft memory limit.
+//
+// The memory limit bounds the memory mapped by the runtime and not
+// released to the operating system: the heap, including its idle
+// spans, goroutine stacks and runtime metadata. The limit is soft.
+// As memory use approaches it, the heap goal is lowered below the one
+// set by GOGC, so the collector runs more often, and the scavenger
+// returns idle heap memory to the operating system without waiting
+// for it to age.
+//
+// If the live heap itself approaches the limit, collecting more often
+// frees little, and a program could end up spending all of its time
+// in the collector. To avoid that, the GC CPU limiter widens the heap
+// goal until the collector uses no more than gcCPULimit of the
+// available CPU time, letting memory use exceed the limit instead.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+const (
+	// maxMemoryLimit is the value of memoryLimit when there is
+	// no limit.
+	maxMemoryLimit = 1<<63 - 1
+
+	// The heap goal and the scavenger aim to keep memory use
+	// 1/memoryLimitHeadroomDiv below the memory limit, leaving
+	// room for fragmentation and for the heap to overshoot its
+	// goal while the collector finishes a cycle.
+	memoryLimitHeadroomDiv = 20
+
+	// gcCPULimit is the fraction of the available CPU time the
+	// collector may use before the GC CPU limiter lets memory use
+	// exceed the memory limit.
+	gcCPULimit = 0.5
+
+	// gcCPULimiterMinRunway is the least heap growth, in bytes,
+	// the GC CPU limiter allows between cycles once the collector
+	// has exceeded gcCPULimit.
+	gcCPULimiterMinRunway = 1 << 20
+)
+
+// memoryLimit is the soft memory limit in bytes. It is set from
+// $GOMEMLIMIT at startup and by runtime/debug.SetMemoryLimit with
+// mheap_.lock held, and may be read atomically without it.
+var memoryLimit uint64 = maxMemoryLimit
+
+// gcCPULimiter is the GC CPU limiter.
+var gcCPULimiter gcCPULimiterState
+
+// gcCPULimiterState is the state of the GC CPU limiter.
+// It is updated with the world stopped or mheap_.lock held.
+type gcCPULimiterState struct {
+	// runway is the heap growth, in bytes, the current heap
+	// goal allows over heap_marked.
+	runway uint64
+
+	// minRunway is the least heap growth the memory limit may
+	// leave between cycles. At the end of each cycle it is set
+	// to that cycle's runway, scaled by the ratio of the GC CPU
+	// utilization during the cycle to gcCPULimit, so it grows
+	// while the collector uses too much CPU time and shrinks
+	// again while it does not.
+	minRunway uint64
+
+	// lastEnd is the nanotime the last cycle ended.
+	lastEnd int64
+}
+
+// readgomemlimit returns the memory limit set by $GOMEMLIMIT.
+func readgomemlimit() uint64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxMemoryLimit
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT")
+	}
+	return n
+}
+
+// parseByteCount parses a non-negative number of bytes, with an
+// optional unit suffix of B, KiB, MiB, GiB or TiB. The bool result
+// reports whether s is valid and its value fits in an int64.
+func parseByteCount(s string) (uint64, bool) {
+	shift := uint(0)
+	switch {
+	case hasSuffix(s, "KiB"):
+		shift = 10
+	case hasSuffix(s, "MiB"):
+		shift = 20
+	case hasSuffix(s, "GiB"):
+		shift = 30
+	case hasSuffix(s, "TiB"):
+		shift = 40
+	case hasSuffix(s, "B"):
+		s = s[:len(s)-1]
+	}
+	if shift != 0 {
+		s = s[:len(s)-3]
+	}
+	if s == "" {
+		return 0, false
+	}
+	n := uint64(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		if n > maxMemoryLimit/10 {
+			// overflow
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	if n > maxMemoryLimit>>shift {
+		return 0, false
+	}
+	return n << shift, true
+}
+
+func hasSuffix(s, suffix string) bool {
+	return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
+}
+
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	lock(&mheap_.lock)
+	out = int64(memoryLimit)
+	if in >= 0 {
+		atomic.Store64(&memoryLimit, uint64(in))
+		// Update pacing in response to the memory limit change.
+		gcSetTriggerRatio(memstats.triggerRatio)
+	}
+	unlock(&mheap_.lock)
+	return out
+}
+
+// nonHeapSys returns the memory mapped by the runtime for
+// everything other than heap objects.
+func nonHeapSys() uint64 {
+	return memstats.stacks_inuse + atomic.Load64(&memstats.stacks_sys) +
+		memstats.mspan_sys + memstats.mcache_sys +
+		atomic.Load64(&memstats.buckhash_sys) + atomic.Load64(&memstats.gc_sys) +
+		atomic.Load64(&memstats.other_sys)
+}
+
+// mappedReady returns the memory mapped by the runtime and not
+// released to the operating system, which the memory limit bounds.
+func mappedReady() uint64 {
+	return memstats.heap_sys - memstats.heap_released + nonHeapSys()
+}
+
+// memoryLimitHeapGoal returns the heap goal the memory limit
+// implies, or ^uint64(0) if there is no limit. That is the limit,
+// less the memory the runtime uses outside the heap and some
+// headroom, but at least gcCPULimiter.minRunway over heap_marked.
+//
+// mheap_.lock must be held or the world must be stopped.
+func memoryLimitHeapGoal() uint64 {
+	limit := atomic.Load64(&memoryLimit)
+	if limit == maxMemoryLimit {
+		return ^uint64(0)
+	}
+	var goal uint64
+	if used := nonHeapSys() + limit/memoryLimitHeadroomDiv; used < limit {
+		goal = limit - used
+	}
+	if min := memstats.heap_marked + gcCPULimiter.minRunway; goal < min {
+		goal = min
+	}
+	return goal
+}
+
+// update updates the GC CPU limiter at the end of a GC cycle, given
+// the CPU time the cycle used and the time it ended.
+//
+// The world must be stopped.
+func (l *gcCPULimiterState) update(cycleCpu, now int64) {
+	lastEnd := l.lastEnd
+	l.lastEnd = now
+	if lastEnd == 0 || now <= lastEnd {
+		return
+	}
+	// The utilization over the time since the last cycle ended.
+	utilization := float64(cycleCpu) / float64((now-lastEnd)*int64(gomaxprocs))
+	minRunway := uint64(float64(l.runway) * utilization / gcCPULimit)
+	if utilization > gcCPULimit && minRunway < gcCPULimiterMinRunway {
+		minRunway = gcCPULimiterMinRunway
+	}
+	l.minRunway = minRunway
+}
+
+// scavengeToLimit returns idle heap memory to the operating system,
+// however long it has been idle, until the memory mapped by the
+// runtime, after the heap grows by grow bytes, is below the memory
+// limit, less headroom. It returns the number of bytes released.
+//
+// h must be locked.
+func (h *mheap) scavengeToLimit(grow uint64) uint64 {
+	limit := atomic.Load64(&memoryLimit)
+	if limit == maxMemoryLimit {
+		return 0
+	}
+	goal := limit - limit/memoryLimitHeadroomDiv
+	if mapped := mappedReady() + grow; mapped > goal {
+		return h.scavengeBytes(mapped - goal)
+	}
+	return 0
+}
+
+// scavengeIfOverLimit is like scavengeToLimit, for callers that do
+// not hold the heap lock.
+func (h *mheap) scavengeIfOverLimit() {
+	limit := atomic.Load64(&memoryLimit)
+	if limit == maxMemoryLimit || mappedReady() <= limit-limit/memoryLimitHeadroomDiv {
+		// This racy check avoids taking the heap lock
+		// in the common case.
+		return
+	}
+	// Disallow malloc or panic while holding the heap lock,
+	// as in scavenge.
+	gp := getg()
+	gp.m.mallocing++
+	lock(&h.lock)
+	h.scavengeToLimit(0)
+	unlock(&h.lock)
+	gp.m.mallocing--
+}
+
+// scavengeBytes returns at least nbytes of idle heap memory to the
+// operating system, if there is that much, and returns the number of
+// bytes released. It releases the largest spans first.
+//
+// h must be locked.
+func (h *mheap) scavengeBytes(nbytes uint64) uint64 {
+	if memstats.heap_idle <= memstats.heap_released {
+		// Every idle span has been released already.
+		// Don't walk them all to find that out.
+		return 0
+	}
+	released := scavengetreapBytes(h.freelarge.treap, nbytes)
+	for i := len(h.free) - 1; i >= 0 && released < nbytes; i-- {
+		for s := h.free[i].first; s != nil && released < nbytes; s = s.next {
+			released += uint64(s.scavenge())
+		}
+	}
+	return released
+}
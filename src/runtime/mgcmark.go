@@ -240,6 +240,12 @@ func markroot(gcw *gcWork, i uint32) {
 			gp.waitsince = work.tstart
 		}
 
+		if gp.leakCandidate {
+			// gcFindLeaks scans gp's stack once it knows
+			// whether gp can ever be woken.
+			return
+		}
+
 		// scang must be done on the system stack in case
 		// we're trying to scan our own stack.
 		systemstack(func() {
@@ -779,8 +785,13 @@ func scanstack(gp *g, gcw *gcWork) {
 
 	// Scan the stack.
 	var cache pcvalueCache
+	// conservative is set if the frame being scanned was
+	// interrupted by an asynchronous preemption.
+	conservative := false
 	scanframe := func(frame *stkframe, unused unsafe.Pointer) bool {
-		scanframeworker(frame, &cache, gcw)
+		isAsyncPreempt := frame.fn.entry == asyncPreemptPC
+		scanframeworker(frame, &cache, gcw, conservative || isAsyncPreempt)
+		conservative = isAsyncPreempt
 		return true
 	}
 	gentraceback(^uintptr(0), ^uintptr(0), 0, gp, 0, nil, 0x7fffffff, scanframe, nil, 0)
@@ -789,8 +800,11 @@ func scanstack(gp *g, gcw *gcWork) {
 }
 
 // Scan a stack frame: local variables and function arguments/results.
+// If conservative is set, the frame has no stack map for its current
+// pc, because it is the frame of asyncPreempt or the frame asyncPreempt
+// interrupted, and is scanned conservatively.
 //go:nowritebarrier
-func scanframeworker(frame *stkframe, cache *pcvalueCache, gcw *gcWork) {
+func scanframeworker(frame *stkframe, cache *pcvalueCache, gcw *gcWork, conservative bool) {
 
 	f := frame.fn
 	targetpc := frame.continpc
@@ -801,6 +815,16 @@ func scanframeworker(frame *stkframe, cache *pcvalueCache, gcw *gcWork) {
 	if _DebugGC > 1 {
 		print("scanframe ", funcname(f), "\n")
 	}
+
+	if conservative {
+		if frame.varp > frame.sp {
+			scanConservative(frame.sp, frame.varp-frame.sp, gcw)
+		}
+		if frame.arglen > 0 {
+			scanConservative(frame.argp, frame.arglen, gcw)
+		}
+		return
+	}
 	if targetpc != f.entry {
 		targetpc--
 	}
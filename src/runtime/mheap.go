@@ -817,6 +817,12 @@ func (h *mheap) allocSpanLocked(npage uintptr, stat *uint64) *mspan {
 	// Best fit in list of large spans.
 	s = h.allocLarge(npage) // allocLarge removed s from h.freelarge for us
 	if s == nil {
+		// If growing the heap takes the memory in use over the
+		// memory limit, return idle memory to the OS first.
+		// Without a limit, don't pay for the check.
+		if atomic.Load64(&memoryLimit) != maxMemoryLimit {
+			h.scavengeToLimit(uint64(npage << _PageShift))
+		}
 		if !h.grow(npage) {
 			return nil
 		}
@@ -1103,37 +1109,47 @@ func (h *mheap) busyList(npages uintptr) *mSpanList {
 	return &h.busylarge
 }
 
+// scavenge returns the pages of the free span s to the operating
+// system and returns the number of bytes released.
+//
+// The heap must be locked.
+func (s *mspan) scavenge() uintptr {
+	if s.npreleased == s.npages {
+		return 0
+	}
+	start := s.base()
+	end := start + s.npages<<_PageShift
+	if physPageSize > _PageSize {
+		// We can only release pages in
+		// physPageSize blocks, so round start
+		// and end in. (Otherwise, madvise
+		// will round them *out* and release
+		// more memory than we want.)
+		start = (start + physPageSize - 1) &^ (physPageSize - 1)
+		end &^= physPageSize - 1
+		if end <= start {
+			// start and end don't span a
+			// whole physical page.
+			return 0
+		}
+	}
+	len := end - start
+	released := len - (s.npreleased << _PageShift)
+	if physPageSize > _PageSize && released == 0 {
+		return 0
+	}
+	memstats.heap_released += uint64(released)
+	s.npreleased = len >> _PageShift
+	sysUnused(unsafe.Pointer(start), len)
+	return released
+}
+
 func scavengeTreapNode(t *treapNode, now, limit uint64) uintptr {
 	s := t.spanKey
-	var sumreleased uintptr
-	if (now-uint64(s.unusedsince)) > limit && s.npreleased != s.npages {
-		start := s.base()
-		end := start + s.npages<<_PageShift
-		if physPageSize > _PageSize {
-			// We can only release pages in
-			// physPageSize blocks, so round start
-			// and end in. (Otherwise, madvise
-			// will round them *out* and release
-			// more memory than we want.)
-			start = (start + physPageSize - 1) &^ (physPageSize - 1)
-			end &^= physPageSize - 1
-			if end <= start {
-				// start and end don't span a
-				// whole physical page.
-				return sumreleased
-			}
-		}
-		len := end - start
-		released := len - (s.npreleased << _PageShift)
-		if physPageSize > _PageSize && released == 0 {
-			return sumreleased
-		}
-		memstats.heap_released += uint64(released)
-		sumreleased += released
-		s.npreleased = len >> _PageShift
-		sysUnused(unsafe.Pointer(start), len)
+	if (now - uint64(s.unusedsince)) > limit {
+		return s.scavenge()
 	}
-	return sumreleased
+	return 0
 }
 
 func scavengelist(list *mSpanList, now, limit uint64) uintptr {
@@ -1143,35 +1159,9 @@ func scavengelist(list *mSpanList, now, limit uint64) uintptr {
 
 	var sumreleased uintptr
 	for s := list.first; s != nil; s = s.next {
-		if (now-uint64(s.unusedsince)) <= limit || s.npreleased == s.npages {
-			continue
-		}
-		start := s.base()
-		end := start + s.npages<<_PageShift
-		if physPageSize > _PageSize {
-			// We can only release pages in
-			// physPageSize blocks, so round start
-			// and end in. (Otherwise, madvise
-			// will round them *out* and release
-			// more memory than we want.)
-			start = (start + physPageSize - 1) &^ (physPageSize - 1)
-			end &^= physPageSize - 1
-			if end <= start {
-				// start and end don't span a
-				// whole physical page.
-				continue
-			}
-		}
-		len := end - start
-
-		released := len - (s.npreleased << _PageShift)
-		if physPageSize > _PageSize && released == 0 {
-			continue
+		if (now - uint64(s.unusedsince)) > limit {
+			sumreleased += s.scavenge()
 		}
-		memstats.heap_released += uint64(released)
-		sumreleased += released
-		s.npreleased = len >> _PageShift
-		sysUnused(unsafe.Pointer(start), len)
 	}
 	return sumreleased
 }
@@ -718,6 +718,22 @@ func ThreadCreateProfile(p []StackRecord) (n int, ok bool) {
 // Most clients should use the runtime/pprof package instead
 // of calling GoroutineProfile directly.
 func GoroutineProfile(p []StackRecord) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, nil)
+}
+
+// runtime_goroutineProfileWithLabels is like GoroutineProfile, but
+// also records the profiler labels of each goroutine in labels, if
+// len(labels) == len(p).
+//
+//go:linkname runtime_goroutineProfileWithLabels runtime/pprof.runtime_goroutineProfileWithLabels
+func runtime_goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, labels)
+}
+
+func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
 	gp := getg()
 
 	isOK := func(gp1 *g) bool {
@@ -737,7 +753,7 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 
 	if n <= len(p) {
 		ok = true
-		r := p
+		r, lbl := p, labels
 
 		// Save current goroutine.
 		sp := getcallersp(unsafe.Pointer(&p))
@@ -746,6 +762,10 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 			saveg(pc, sp, gp, &r[0])
 		})
 		r = r[1:]
+		if labels != nil {
+			lbl[0] = gp.labels
+			lbl = lbl[1:]
+		}
 
 		// Save other goroutines.
 		for _, gp1 := range allgs {
@@ -757,6 +777,10 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 				}
 				saveg(^uintptr(0), ^uintptr(0), gp1, &r[0])
 				r = r[1:]
+				if labels != nil {
+					lbl[0] = gp1.labels
+					lbl = lbl[1:]
+				}
 			}
 		}
 	}
@@ -766,6 +790,50 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 	return n, ok
 }
 
+// runtime_goroutineLeakProfileWithLabels is like
+// runtime_goroutineProfileWithLabels, but only records the goroutines
+// that the last leak-detecting GC found leaked.
+//
+//go:linkname runtime_goroutineLeakProfileWithLabels runtime/pprof.runtime_goroutineLeakProfileWithLabels
+func runtime_goroutineLeakProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
+
+	stopTheWorld("profile")
+
+	for _, gp1 := range allgs {
+		if isLeaked(gp1) {
+			n++
+		}
+	}
+
+	if n <= len(p) {
+		ok = true
+		i := 0
+		for _, gp1 := range allgs {
+			if isLeaked(gp1) {
+				saveg(^uintptr(0), ^uintptr(0), gp1, &p[i])
+				if labels != nil {
+					labels[i] = gp1.labels
+				}
+				i++
+			}
+		}
+	}
+
+	startTheWorld()
+
+	return n, ok
+}
+
+// isLeaked reports whether gp was found leaked by the last
+// leak-detecting GC. A leaked goroutine stays blocked forever, but
+// check its status anyway rather than trust a stale flag.
+func isLeaked(gp *g) bool {
+	return gp.leaked && readgstatus(gp) == _Gwaiting
+}
+
 func saveg(pc, sp uintptr, gp *g, r *StackRecord) {
 	n := gentraceback(pc, sp, 0, gp, 0, &r.Stack0[0], len(r.Stack0), nil, nil, 0)
 	if n < len(r.Stack0) {
@@ -811,6 +879,42 @@ func Stack(buf []byte, all bool) int {
 	return n
 }
 
+// runtime_goroutineLeakStacks formats the stack traces of the
+// goroutines that the last leak-detecting GC found leaked into buf and
+// returns the number of bytes written to buf.
+//
+//go:linkname runtime_goroutineLeakStacks runtime/pprof.runtime_goroutineLeakStacks
+func runtime_goroutineLeakStacks(buf []byte) int {
+	stopTheWorld("stack trace")
+
+	n := 0
+	if len(buf) > 0 {
+		systemstack(func() {
+			g0 := getg()
+			g0.m.traceback = 1
+			g0.writebuf = buf[0:0:len(buf)]
+			first := true
+			for _, gp := range allgs {
+				if !isLeaked(gp) {
+					continue
+				}
+				if !first {
+					print("\n")
+				}
+				first = false
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+			}
+			g0.m.traceback = 0
+			n = len(g0.writebuf)
+			g0.writebuf = nil
+		})
+	}
+
+	startTheWorld()
+	return n
+}
+
 // Tracing of alloc/free/gc.
 
 var tracelock mutex
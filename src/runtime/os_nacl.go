@@ -291,6 +291,10 @@ func closeonexec(int32)                                   {}
 // gsignalStack is unused on nacl.
 type gsignalStack struct{}
 
+// sigPreempt is never sent on nacl, which does not support
+// asynchronous preemption.
+const sigPreempt = 0
+
 var writelock uint32 // test-and-set spin lock for write
 
 /*
@@ -6,6 +6,9 @@ package pprof
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 )
 
 type label struct {
@@ -24,31 +27,71 @@ type labelContextKey struct{}
 func labelValue(ctx context.Context) labelMap {
 	labels, _ := ctx.Value(labelContextKey{}).(*labelMap)
 	if labels == nil {
-		return labelMap(nil)
+		return labelMap{}
 	}
 	return *labels
 }
 
 // labelMap is the representation of the label set held in the context type.
-// This is an initial implementation, but it will be replaced with something
-// that admits incremental immutable modification more efficiently.
-type labelMap map[string]string
+// Its labels are sorted by key, and no key appears twice.
+//
+// A labelMap is never modified once created. The runtime depends on
+// this, and on the representation, to print the labels of a goroutine
+// in its traceback without allocating.
+type labelMap struct {
+	LabelSet
+}
+
+// String returns the labels as key:value pairs sorted by key.
+func (l *labelMap) String() string {
+	if l == nil {
+		return ""
+	}
+	keyVals := make([]string, 0, len(l.list))
+	for _, lbl := range l.list {
+		keyVals = append(keyVals, fmt.Sprintf("%q:%q", lbl.key, lbl.value))
+	}
+	return "{" + strings.Join(keyVals, ", ") + "}"
+}
 
 // WithLabels returns a new context.Context with the given labels added.
 // A label overwrites a prior label with the same key.
 func WithLabels(ctx context.Context, labels LabelSet) context.Context {
-	childLabels := make(labelMap)
-	parentLabels := labelValue(ctx)
-	// TODO(matloob): replace the map implementation with something
-	// more efficient so creating a child context WithLabels doesn't need
-	// to clone the map.
-	for k, v := range parentLabels {
-		childLabels[k] = v
+	parentLabels := labelValue(ctx).list
+
+	// Sort the new labels, keeping only the last label for each key.
+	added := make([]label, len(labels.list))
+	copy(added, labels.list)
+	sort.SliceStable(added, func(i, j int) bool { return added[i].key < added[j].key })
+	n := 0
+	for i, lbl := range added {
+		if i+1 < len(added) && added[i+1].key == lbl.key {
+			continue
+		}
+		added[n] = lbl
+		n++
 	}
-	for _, label := range labels.list {
-		childLabels[label.key] = label.value
+	added = added[:n]
+
+	// Merge them into the parent's labels.
+	childLabels := make([]label, 0, len(parentLabels)+len(added))
+	for len(parentLabels) > 0 && len(added) > 0 {
+		switch p, a := parentLabels[0], added[0]; {
+		case p.key < a.key:
+			childLabels = append(childLabels, p)
+			parentLabels = parentLabels[1:]
+		case p.key > a.key:
+			childLabels = append(childLabels, a)
+			added = added[1:]
+		default:
+			childLabels = append(childLabels, a)
+			parentLabels = parentLabels[1:]
+			added = added[1:]
+		}
 	}
-	return context.WithValue(ctx, labelContextKey{}, &childLabels)
+	childLabels = append(childLabels, parentLabels...)
+	childLabels = append(childLabels, added...)
+	return context.WithValue(ctx, labelContextKey{}, &labelMap{LabelSet{list: childLabels}})
 }
 
 // Labels takes an even number of strings representing key-value pairs
@@ -68,17 +111,20 @@ func Labels(args ...string) LabelSet {
 // Label returns the value of the label with the given key on ctx, and a boolean indicating
 // whether that label exists.
 func Label(ctx context.Context, key string) (string, bool) {
-	ctxLabels := labelValue(ctx)
-	v, ok := ctxLabels[key]
-	return v, ok
+	ctxLabels := labelValue(ctx).list
+	i := sort.Search(len(ctxLabels), func(i int) bool { return ctxLabels[i].key >= key })
+	if i < len(ctxLabels) && ctxLabels[i].key == key {
+		return ctxLabels[i].value, true
+	}
+	return "", false
 }
 
 // ForLabels invokes f with each label set on the context.
 // The function f should return true to continue iteration or false to stop iteration early.
 func ForLabels(ctx context.Context, f func(key, value string) bool) {
-	ctxLabels := labelValue(ctx)
-	for k, v := range ctxLabels {
-		if !f(k, v) {
+	ctxLabels := labelValue(ctx).list
+	for _, lbl := range ctxLabels {
+		if !f(lbl.key, lbl.value) {
 			break
 		}
 	}
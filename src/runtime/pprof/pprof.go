@@ -98,15 +98,28 @@ import (
 //
 // Each Profile has a unique name. A few profiles are predefined:
 //
-//	goroutine    - stack traces of all current goroutines
-//	heap         - a sampling of all heap allocations
-//	threadcreate - stack traces that led to the creation of new OS threads
-//	block        - stack traces that led to blocking on synchronization primitives
-//	mutex        - stack traces of holders of contended mutexes
+//	goroutine     - stack traces of all current goroutines
+//	goroutineleak - stack traces of goroutines blocked forever
+//	heap          - a sampling of all heap allocations
+//	threadcreate  - stack traces that led to the creation of new OS threads
+//	block         - stack traces that led to blocking on synchronization primitives
+//	mutex         - stack traces of holders of contended mutexes
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
 //
+// The goroutine and goroutineleak profiles record the labels set with
+// Do or SetGoroutineLabels on each goroutine.
+//
+// The goroutineleak profile reports the goroutines blocked on a channel,
+// a sync.Mutex, a sync.WaitGroup or a sync.Cond that no other goroutine
+// can reach, so that nothing can ever wake them. Writing the profile runs
+// a garbage collection that finds these goroutines, during which the
+// whole program is stopped. Its Count reports the leaked goroutines found
+// by the last such collection. Some leaks go undetected: for example, a
+// goroutine whose deferred calls refer to the object it is blocked on is
+// never reported.
+//
 // The heap profile reports statistics as of the most recently completed
 // garbage collection; it elides more recent allocation to avoid skewing
 // the profile away from live data and toward garbage.
@@ -138,6 +151,12 @@ var goroutineProfile = &Profile{
 	write: writeGoroutine,
 }
 
+var goroutineLeakProfile = &Profile{
+	name:  "goroutineleak",
+	count: countGoroutineLeak,
+	write: writeGoroutineLeak,
+}
+
 var threadcreateProfile = &Profile{
 	name:  "threadcreate",
 	count: countThreadCreate,
@@ -167,11 +186,12 @@ func lockProfiles() {
 	if profiles.m == nil {
 		// Initial built-in profiles.
 		profiles.m = map[string]*Profile{
-			"goroutine":    goroutineProfile,
-			"threadcreate": threadcreateProfile,
-			"heap":         heapProfile,
-			"block":        blockProfile,
-			"mutex":        mutexProfile,
+			"goroutine":     goroutineProfile,
+			"goroutineleak": goroutineLeakProfile,
+			"threadcreate":  threadcreateProfile,
+			"heap":          heapProfile,
+			"block":         blockProfile,
+			"mutex":         mutexProfile,
 		}
 	}
 }
@@ -299,9 +319,9 @@ func (p *Profile) Remove(value interface{}) {
 // and line numbers, so that a programmer can read the profile without tools.
 //
 // The predefined profiles may assign meaning to other debug values;
-// for example, when printing the "goroutine" profile, debug=2 means to
-// print the goroutine stacks in the same form that a Go program uses
-// when dying due to an unrecovered panic.
+// for example, when printing the "goroutine" and "goroutineleak"
+// profiles, debug=2 means to print the goroutine stacks in the same form
+// that a Go program uses when dying due to an unrecovered panic.
 func (p *Profile) WriteTo(w io.Writer, debug int) error {
 	if p.name == "" {
 		panic("pprof: use of zero Profile")
@@ -336,14 +356,16 @@ type stackProfile [][]uintptr
 
 func (x stackProfile) Len() int              { return len(x) }
 func (x stackProfile) Stack(i int) []uintptr { return x[i] }
+func (x stackProfile) Label(i int) *labelMap { return nil }
 
 // A countProfile is a set of stack traces to be printed as counts
-// grouped by stack trace. There are multiple implementations:
+// grouped by stack trace and labels. There are multiple implementations:
 // all that matters is that we can find out how many traces there are
-// and obtain each trace in turn.
+// and obtain each trace and its labels in turn.
 type countProfile interface {
 	Len() int
 	Stack(i int) []uintptr
+	Label(i int) *labelMap
 }
 
 // printCountCycleProfile outputs block profile records (for block or mutex profiles)
@@ -386,12 +408,16 @@ func printCountCycleProfile(w io.Writer, countName, cycleName string, records []
 func printCountProfile(w io.Writer, debug int, name string, p countProfile) error {
 	// Build count of each stack.
 	var buf bytes.Buffer
-	key := func(stk []uintptr) string {
+	key := func(stk []uintptr, lbls *labelMap) string {
 		buf.Reset()
 		fmt.Fprintf(&buf, "@")
 		for _, pc := range stk {
 			fmt.Fprintf(&buf, " %#x", pc)
 		}
+		if lbls != nil {
+			buf.WriteString("\n# labels: ")
+			buf.WriteString(lbls.String())
+		}
 		return buf.String()
 	}
 	count := map[string]int{}
@@ -399,7 +425,7 @@ func printCountProfile(w io.Writer, debug int, name string, p countProfile) erro
 	var keys []string
 	n := p.Len()
 	for i := 0; i < n; i++ {
-		k := key(p.Stack(i))
+		k := key(p.Stack(i), p.Label(i))
 		if count[k] == 0 {
 			index[k] = i
 			keys = append(keys, k)
@@ -440,7 +466,15 @@ func printCountProfile(w io.Writer, debug int, name string, p countProfile) erro
 			}
 			locs = append(locs, l)
 		}
-		b.pbSample(values, locs, nil)
+		var labels func()
+		if lbls := p.Label(index[k]); lbls != nil {
+			labels = func() {
+				for _, lbl := range lbls.list {
+					b.pbLabel(tagSample_Label, lbl.key, lbl.value, 0)
+				}
+			}
+		}
+		b.pbSample(values, locs, labels)
 	}
 	b.build()
 	return nil
@@ -623,7 +657,11 @@ func countThreadCreate() int {
 
 // writeThreadCreate writes the current runtime ThreadCreateProfile to w.
 func writeThreadCreate(w io.Writer, debug int) error {
-	return writeRuntimeProfile(w, debug, "threadcreate", runtime.ThreadCreateProfile)
+	// The records have no stacks, so there is no point in
+	// recording labels.
+	return writeRuntimeProfile(w, debug, "threadcreate", func(p []runtime.StackRecord, _ []unsafe.Pointer) (n int, ok bool) {
+		return runtime.ThreadCreateProfile(p)
+	})
 }
 
 // countGoroutine returns the number of goroutines.
@@ -634,18 +672,37 @@ func countGoroutine() int {
 // writeGoroutine writes the current runtime GoroutineProfile to w.
 func writeGoroutine(w io.Writer, debug int) error {
 	if debug >= 2 {
-		return writeGoroutineStacks(w)
+		return writeGoroutineStacks(w, func(buf []byte) int { return runtime.Stack(buf, true) })
 	}
-	return writeRuntimeProfile(w, debug, "goroutine", runtime.GoroutineProfile)
+	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels)
 }
 
-func writeGoroutineStacks(w io.Writer) error {
+// countGoroutineLeak returns the number of leaked goroutines found by
+// the last leak-detecting garbage collection.
+func countGoroutineLeak() int {
+	n, _ := runtime_goroutineLeakProfileWithLabels(nil, nil)
+	return n
+}
+
+// writeGoroutineLeak runs a leak-detecting garbage collection and
+// writes the leaked goroutines it found to w.
+func writeGoroutineLeak(w io.Writer, debug int) error {
+	runtime_goroutineLeakGC()
+	if debug >= 2 {
+		return writeGoroutineStacks(w, runtime_goroutineLeakStacks)
+	}
+	return writeRuntimeProfile(w, debug, "goroutineleak", runtime_goroutineLeakProfileWithLabels)
+}
+
+// writeGoroutineStacks writes the stack traces formatted by stacks,
+// which has the signature of runtime.Stack, to w.
+func writeGoroutineStacks(w io.Writer, stacks func([]byte) int) error {
 	// We don't know how big the buffer needs to be to collect
 	// all the goroutines. Start with 1 MB and try a few times, doubling each time.
 	// Give up and use a truncated trace if 64 MB is not enough.
 	buf := make([]byte, 1<<20)
 	for i := 0; ; i++ {
-		n := runtime.Stack(buf, true)
+		n := stacks(buf)
 		if n < len(buf) {
 			buf = buf[:n]
 			break
@@ -660,21 +717,23 @@ func writeGoroutineStacks(w io.Writer) error {
 	return err
 }
 
-func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord) (int, bool)) error {
-	// Find out how many records there are (fetch(nil)),
+func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord, []unsafe.Pointer) (int, bool)) error {
+	// Find out how many records there are (fetch(nil, nil)),
 	// allocate that many records, and get the data.
 	// There's a race—more records might be added between
 	// the two calls—so allocate a few extra records for safety
 	// and also try again if we're very unlucky.
 	// The loop should only execute one iteration in the common case.
 	var p []runtime.StackRecord
-	n, ok := fetch(nil)
+	var labels []unsafe.Pointer
+	n, ok := fetch(nil, nil)
 	for {
 		// Allocate room for a slightly bigger profile,
 		// in case a few more entries have been added
 		// since the call to ThreadProfile.
 		p = make([]runtime.StackRecord, n+10)
-		n, ok = fetch(p)
+		labels = make([]unsafe.Pointer, n+10)
+		n, ok = fetch(p, labels)
 		if ok {
 			p = p[0:n]
 			break
@@ -682,13 +741,17 @@ func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runti
 		// Profile grew; try again.
 	}
 
-	return printCountProfile(w, debug, name, runtimeProfile(p))
+	return printCountProfile(w, debug, name, &runtimeProfile{p, labels})
 }
 
-type runtimeProfile []runtime.StackRecord
+type runtimeProfile struct {
+	stk    []runtime.StackRecord
+	labels []unsafe.Pointer
+}
 
-func (p runtimeProfile) Len() int              { return len(p) }
-func (p runtimeProfile) Stack(i int) []uintptr { return p[i].Stack() }
+func (p *runtimeProfile) Len() int              { return len(p.stk) }
+func (p *runtimeProfile) Stack(i int) []uintptr { return p.stk[i].Stack() }
+func (p *runtimeProfile) Label(i int) *labelMap { return (*labelMap)(p.labels[i]) }
 
 var cpu struct {
 	sync.Mutex
@@ -823,6 +823,161 @@ func containsCounts(prof *profile.Profile, counts []int64) bool {
 	return true
 }
 
+func TestGoroutineProfileLabels(t *testing.T) {
+	// Setting GOMAXPROCS to 1 ensures we can force all goroutines to the
+	// desired blocking point.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	c := make(chan int)
+	Do(context.Background(), Labels("user", "gopher", "request", "42"), func(context.Context) {
+		for i := 0; i < 3; i++ {
+			go func4(c)
+		}
+	})
+	// Let goroutines block on channel
+	for j := 0; j < 5; j++ {
+		runtime.Gosched()
+	}
+
+	var w bytes.Buffer
+	goroutineProf := Lookup("goroutine")
+
+	// Check debug profile
+	goroutineProf.WriteTo(&w, 1)
+	prof := w.String()
+	labels := `{"request":"42", "user":"gopher"}`
+	if !containsInOrder(prof, "\n3 @ ", "\n# labels: "+labels+"\n", "runtime/pprof.func4") {
+		t.Errorf("expected labeled goroutines in debug=1 profile:\n%s", prof)
+	}
+
+	// Check stack dump
+	w.Reset()
+	goroutineProf.WriteTo(&w, 2)
+	prof = w.String()
+	if n := strings.Count(prof, "[chan receive, labels: "+labels+"]:\nruntime/pprof.func4("); n != 3 {
+		t.Errorf("found %d labeled goroutines in debug=2 profile, want 3:\n%s", n, prof)
+	}
+
+	// Check proto profile
+	w.Reset()
+	goroutineProf.WriteTo(&w, 0)
+	p, err := profile.Parse(&w)
+	if err != nil {
+		t.Fatalf("error parsing protobuf profile: %v", err)
+	}
+	found := false
+	for _, s := range p.Sample {
+		if len(s.Label["request"]) == 1 && s.Label["request"][0] == "42" &&
+			len(s.Label["user"]) == 1 && s.Label["user"][0] == "gopher" && s.Value[0] == 3 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected labeled sample with count 3 in protobuf profile, got %v", p)
+	}
+
+	close(c)
+
+	time.Sleep(10 * time.Millisecond) // let goroutines exit
+}
+
+func TestGoroutineDumpLabelsQuoted(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	c := make(chan int)
+	Do(context.Background(), Labels("k\"}", "v\", \"x\":\"y", "ctl", "a\nb\tc\x00\x7f\\"), func(context.Context) {
+		go func4(c)
+	})
+	for j := 0; j < 5; j++ {
+		runtime.Gosched()
+	}
+
+	// The stack dump quotes labels as the debug=1 profile does.
+	labels := `{"ctl":"a\nb\tc\x00\u007f\\", "k\"}":"v\", \"x\":\"y"}`
+	var w bytes.Buffer
+	Lookup("goroutine").WriteTo(&w, 1)
+	if prof := w.String(); !strings.Contains(prof, "\n# labels: "+labels+"\n") {
+		t.Errorf("expected quoted labels %s in debug=1 profile:\n%s", labels, prof)
+	}
+	w.Reset()
+	Lookup("goroutine").WriteTo(&w, 2)
+	if prof := w.String(); !strings.Contains(prof, "[chan receive, labels: "+labels+"]:\nruntime/pprof.func4(") {
+		t.Errorf("expected quoted labels %s in debug=2 profile:\n%s", labels, prof)
+	}
+	close(c)
+}
+
+func blockForever()      { <-make(chan int) }
+func blockOn(c chan int) { <-c }
+
+func TestGoroutineLeakProfile(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	var w bytes.Buffer
+	leakProf := Lookup("goroutineleak")
+	const leaked = "[chan receive, leaked, labels: {\"leak\":\"yes\"}]:\nruntime/pprof.blockForever("
+
+	// Earlier runs of this test leave leaked goroutines behind.
+	leakProf.WriteTo(&w, 2)
+	want := strings.Count(w.String(), leaked) + 5
+
+	c := make(chan int)
+	Do(context.Background(), Labels("leak", "yes"), func(context.Context) {
+		for i := 0; i < 5; i++ {
+			go blockForever()
+			go blockOn(c)
+		}
+	})
+	for j := 0; j < 5; j++ {
+		runtime.Gosched()
+	}
+
+	// Check stack dump
+	w.Reset()
+	leakProf.WriteTo(&w, 2)
+	prof := w.String()
+	if n := strings.Count(prof, leaked); n != want {
+		t.Errorf("found %d leaked goroutines in debug=2 profile, want %d:\n%s", n, want, prof)
+	}
+	if strings.Contains(prof, "runtime/pprof.blockOn(") {
+		t.Errorf("goroutine blocked on reachable channel reported as leaked:\n%s", prof)
+	}
+	if n := leakProf.Count(); n < want {
+		t.Errorf("Count() = %d, want at least %d", n, want)
+	}
+
+	// Check proto profile
+	w.Reset()
+	leakProf.WriteTo(&w, 0)
+	p, err := profile.Parse(&w)
+	if err != nil {
+		t.Fatalf("error parsing protobuf profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Errorf("protobuf profile is invalid: %v", err)
+	}
+	found := false
+	for _, s := range p.Sample {
+		for _, loc := range s.Location {
+			for _, line := range loc.Line {
+				switch line.Function.Name {
+				case "runtime/pprof.blockForever":
+					if s.Value[0] == int64(want) && len(s.Label["leak"]) == 1 {
+						found = true
+					}
+				case "runtime/pprof.blockOn":
+					t.Errorf("goroutine blocked on reachable channel reported as leaked")
+				}
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected %d leaked goroutines in protobuf profile, got %v", want, p)
+	}
+
+	close(c)
+}
+
 // Issue 18836.
 func TestEmptyCallStack(t *testing.T) {
 	t.Parallel()
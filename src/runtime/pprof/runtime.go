@@ -6,6 +6,7 @@ package pprof
 
 import (
 	"context"
+	"runtime"
 	"unsafe"
 )
 
@@ -15,6 +16,18 @@ func runtime_setProfLabel(labels unsafe.Pointer)
 // runtime_getProfLabel is defined in runtime/proflabel.go.
 func runtime_getProfLabel() unsafe.Pointer
 
+// runtime_goroutineProfileWithLabels is defined in runtime/mprof.go.
+func runtime_goroutineProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+
+// runtime_goroutineLeakProfileWithLabels is defined in runtime/mprof.go.
+func runtime_goroutineLeakProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+
+// runtime_goroutineLeakStacks is defined in runtime/mprof.go.
+func runtime_goroutineLeakStacks(buf []byte) int
+
+// runtime_goroutineLeakGC is defined in runtime/mgcleak.go.
+func runtime_goroutineLeakGC()
+
 // SetGoroutineLabels sets the current goroutine's labels to match ctx.
 // This is a lower-level API than Do, which should be used instead when possible.
 func SetGoroutineLabels(ctx context.Context) {
@@ -0,0 +1,203 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Goroutine preemption
+//
+// A goroutine can be preempted at any safe point. There are three
+// kinds of safe points:
+//
+// 1. A blocked safe point occurs for the duration that a goroutine is
+//    descheduled, blocked on synchronization, or in a system call.
+//
+// 2. Synchronous safe points occur when a running goroutine checks
+//    for a preemption request. The request is folded into the stack
+//    growth check: the runtime sets gp.stackguard0 to stackPreempt,
+//    so the next function prologue calls morestack and newstack
+//    notices the request.
+//
+// 3. Asynchronous safe points occur at any instruction in user code
+//    where the goroutine can be safely paused and a conservative
+//    scan of its registers and innermost frame can find its roots.
+//
+// Synchronous safe points only occur at calls, so a loop without
+// calls can delay the garbage collector and the scheduler for as long
+// as it runs. To preempt such a loop, the runtime sends the thread
+// running the goroutine a signal, sigPreempt. If the signal arrives
+// at an asynchronous safe point, the signal handler injects a call to
+// asyncPreempt, which saves all registers and then acts on the
+// request as newstack would. The compiler marks the instructions that
+// are not asynchronous safe points, such as write barrier sequences,
+// in the _PCDATA_UnsafePoint table.
+//
+// The interrupted frame has no stack map at the instruction it
+// stopped at, so the garbage collector scans it, and the registers
+// saved by asyncPreempt, conservatively. For the same reason, the
+// stack of a goroutine stopped at an asynchronous safe point is never
+// moved.
+
+package runtime
+
+import (
+	"runtime/internal/sys"
+	"unsafe"
+)
+
+// asyncPreemptStack is the number of bytes of stack space needed to
+// inject an asyncPreempt call.
+const asyncPreemptStack = 1024
+
+// no_pointers_stackmap is the locals stack map of assembly functions
+// that use NO_LOCAL_POINTERS. It is defined in asm.s.
+var no_pointers_stackmap uint64
+
+//go:nosplit
+func asyncPreempt2() {
+	gp := getg()
+	gp.asyncSafePoint = true
+	mcall(preemptAtSafePoint)
+	gp.asyncSafePoint = false
+}
+
+// preemptAtSafePoint acts on a preemption request for gp, which has
+// stopped at a synchronous or asynchronous safe point. If the garbage
+// collector asked gp to scan its own stack, it does so and resumes gp.
+// Otherwise it reschedules gp as if it had called runtime.Gosched.
+//
+// preemptAtSafePoint runs on g0 and does not return.
+func preemptAtSafePoint(gp *g) {
+	// Synchronize with scang.
+	casgstatus(gp, _Grunning, _Gwaiting)
+	if gp.preemptscan {
+		for !castogscanstatus(gp, _Gwaiting, _Gscanwaiting) {
+			// Likely to be racing with the GC as
+			// it sees a _Gwaiting and does the
+			// stack scan. If so, gcworkdone will
+			// be set and gcphasework will simply
+			// return.
+		}
+		if !gp.gcscandone {
+			// gcw is safe because we're on the
+			// system stack.
+			gcw := &gp.m.p.ptr().gcw
+			scanstack(gp, gcw)
+			if gcBlackenPromptly {
+				gcw.dispose()
+			}
+			gp.gcscandone = true
+		}
+		gp.preemptscan = false
+		gp.preempt = false
+		casfrom_Gscanstatus(gp, _Gscanwaiting, _Gwaiting)
+		// This clears gcscanvalid.
+		casgstatus(gp, _Gwaiting, _Grunning)
+		gp.stackguard0 = gp.stack.lo + _StackGuard
+		gogo(&gp.sched) // never return
+	}
+
+	// Act like goroutine called runtime.Gosched.
+	casgstatus(gp, _Gwaiting, _Grunning)
+	gopreempt_m(gp) // never return
+}
+
+// wantAsyncPreempt returns whether an asynchronous preemption is
+// queued for gp.
+//
+//go:nosplit
+func wantAsyncPreempt(gp *g) bool {
+	return gp.preempt && readgstatus(gp)&^_Gscan == _Grunning
+}
+
+// canPreemptM reports whether mp is in a state that is safe to preempt.
+//
+//go:nosplit
+func canPreemptM(mp *m) bool {
+	return mp.locks == 0 && mp.mallocing == 0 && mp.preemptoff == "" && mp.p.ptr().status == _Prunning
+}
+
+// isAsyncSafePoint reports whether gp, interrupted by a signal at
+// instruction pc with stack pointer sp, is at an asynchronous safe
+// point. The signal handler calls it on gp's M.
+//
+//go:nosplit
+func isAsyncSafePoint(gp *g, pc, sp uintptr) bool {
+	mp := gp.m
+
+	// Only user Gs can have safe points. We check this first
+	// because it's extremely common that we'll catch mp in the
+	// scheduler processing this G preemption.
+	if mp.curg != gp {
+		return false
+	}
+
+	// Check M state.
+	if mp.p == 0 || !canPreemptM(mp) {
+		return false
+	}
+
+	// Check stack space.
+	if sp < gp.stack.lo || sp-gp.stack.lo < asyncPreemptStack {
+		return false
+	}
+
+	// Check if pc is an unsafe point.
+	f := findfunc(pc)
+	if !f.valid() {
+		// Not Go code.
+		return false
+	}
+	if pcdatavalue(f, _PCDATA_UnsafePoint, pc, nil) != _PCDATA_UnsafePointSafe {
+		return false
+	}
+	if fd := funcdata(f, _FUNCDATA_LocalsPointerMaps); fd == nil || fd == unsafe.Pointer(&no_pointers_stackmap) {
+		// This is assembly code. Don't assume it's
+		// well-formed.
+		return false
+	}
+	name := funcname(f)
+	if inldata := funcdata(f, _FUNCDATA_InlTree); inldata != nil {
+		inltree := (*[1 << 20]inlinedCall)(inldata)
+		if ix := pcdatavalue(f, _PCDATA_InlTreeIndex, pc, nil); ix >= 0 {
+			name = funcnameFromNameoff(f, inltree[ix].func_)
+		}
+	}
+	if hasprefix(name, "runtime.") ||
+		hasprefix(name, "runtime/internal/") ||
+		hasprefix(name, "reflect.") {
+		// We never asynchronously preempt the runtime or
+		// anything closely tied to it. The scheduler has many
+		// stretches that must not be preempted, and the defer
+		// and reflect call implementations keep untyped data
+		// on the stack.
+		return false
+	}
+
+	return true
+}
+
+// scanConservative scans block [b, b+n) conservatively, treating any
+// word that points into an allocated heap object as a pointer to it.
+//
+// gcw must be owned by the current P.
+//
+//go:nowritebarrier
+func scanConservative(b, n uintptr, gcw *gcWork) {
+	for i := uintptr(0); i < n; i += sys.PtrSize {
+		val := *(*uintptr)(unsafe.Pointer(b + i))
+
+		// Check if val points into an in-use span.
+		s := spanOf(val)
+		if s == nil || s.state != mSpanInUse || val < s.base() || val >= s.limit {
+			continue
+		}
+
+		// Check if val points to an allocated object.
+		if s.isFree(s.objIndex(val)) {
+			continue
+		}
+
+		if obj, hbits, span, objIndex := heapBitsForObject(val, b, i); obj != 0 {
+			greyobject(obj, b, i, hbits, span, gcw, objIndex)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// asyncPreempt saves all user registers, calls asyncPreempt2 and
+// restores them. The signal handler injects a call to it at an
+// asynchronous safe point, so it must preserve the state of the
+// interrupted code, including the flags and the x87 state, which
+// holds floating point values when GO386=387.
+TEXT runtime·asyncPreempt(SB),NOSPLIT,$0-0
+	// Save flags before clobbering them.
+	PUSHFL
+	ADJSP	$264
+	MOVL	AX, 0(SP)
+	MOVL	BX, 4(SP)
+	MOVL	CX, 8(SP)
+	MOVL	DX, 12(SP)
+	MOVL	SI, 16(SP)
+	MOVL	DI, 20(SP)
+	MOVL	BP, 24(SP)
+	FSAVE	156(SP)
+	CMPB	runtime·support_sse2(SB), $1
+	JNE	nosse
+	MOVUPS	X0, 28(SP)
+	MOVUPS	X1, 44(SP)
+	MOVUPS	X2, 60(SP)
+	MOVUPS	X3, 76(SP)
+	MOVUPS	X4, 92(SP)
+	MOVUPS	X5, 108(SP)
+	MOVUPS	X6, 124(SP)
+	MOVUPS	X7, 140(SP)
+nosse:
+	CALL	runtime·asyncPreempt2(SB)
+	CMPB	runtime·support_sse2(SB), $1
+	JNE	nosse2
+	MOVUPS	140(SP), X7
+	MOVUPS	124(SP), X6
+	MOVUPS	108(SP), X5
+	MOVUPS	92(SP), X4
+	MOVUPS	76(SP), X3
+	MOVUPS	60(SP), X2
+	MOVUPS	44(SP), X1
+	MOVUPS	28(SP), X0
+nosse2:
+	FRSTOR	156(SP)
+	MOVL	24(SP), BP
+	MOVL	20(SP), DI
+	MOVL	16(SP), SI
+	MOVL	12(SP), DX
+	MOVL	8(SP), CX
+	MOVL	4(SP), BX
+	MOVL	0(SP), AX
+	ADJSP	$-264
+	POPFL
+	RET
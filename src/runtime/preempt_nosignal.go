@@ -0,0 +1,15 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux !amd64,!386
+// +build !dragonfly,!freebsd,!netbsd,!openbsd !amd64
+
+package runtime
+
+// preemptMSupported is true if preemptM actually preempts Ms.
+const preemptMSupported = false
+
+// preemptM does nothing on systems that can't signal a thread.
+// Goroutines there are only preempted at synchronous safe points.
+func preemptM(mp *m) {}
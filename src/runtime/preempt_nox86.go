@@ -0,0 +1,14 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !amd64,!386
+
+package runtime
+
+// asyncPreempt is only implemented on amd64 and 386. On other
+// architectures preemptM never sends preemption signals, so it is
+// never called.
+func asyncPreempt() {
+	throw("asyncPreempt not implemented")
+}
@@ -0,0 +1,29 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,amd64 linux,386 dragonfly,amd64 freebsd,amd64 netbsd,amd64 openbsd,amd64
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// preemptMSupported is true if preemptM actually preempts Ms.
+const preemptMSupported = true
+
+// preemptM sends a preemption request to mp. This request may be
+// handled asynchronously and may be coalesced with other requests to
+// the M. When the request is received, if the running G or P are
+// marked for preemption and the goroutine is at an asynchronous
+// safe point, it will preempt the goroutine. It always atomically
+// clears mp.signalPending after handling the request.
+func preemptM(mp *m) {
+	if debug.asyncpreemptoff != 0 || isarchive || islibrary {
+		// In c-archive and c-shared mode the host program
+		// owns the preemption signal.
+		return
+	}
+	if atomic.Cas(&mp.signalPending, 0, 1) {
+		signalM(mp, sigPreempt)
+	}
+}
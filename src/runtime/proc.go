@@ -196,6 +196,7 @@ func main() {
 	}
 	fn = main_main // make an indirect call, as the linker doesn't know the address of the main package when laying down the runtime
 	fn()
+	runExitHooks()
 	if raceenabled {
 		racefini()
 	}
@@ -224,14 +225,50 @@ func main() {
 	}
 }
 
-// os_beforeExit is called from os.Exit(0).
+// os_beforeExit is called from os.Exit.
 //go:linkname os_beforeExit os.runtime_beforeExit
-func os_beforeExit() {
-	if raceenabled {
+func os_beforeExit(exitCode int) {
+	runExitHooks()
+	if exitCode == 0 && raceenabled {
 		racefini()
 	}
 }
 
+// exitHooks are the functions run when the program exits normally,
+// that is, when main.main returns or os.Exit is called.
+// They are not run when the program dies of a panic or a fatal error.
+var exitHooks struct {
+	lock    mutex
+	hooks   []func()
+	running bool
+}
+
+// coverage_runtime_addExitHook is used by runtime/coverage
+// to write the coverage data of the program when it exits.
+//go:linkname coverage_runtime_addExitHook runtime/coverage.runtime_addExitHook
+func coverage_runtime_addExitHook(f func()) {
+	lock(&exitHooks.lock)
+	exitHooks.hooks = append(exitHooks.hooks, f)
+	unlock(&exitHooks.lock)
+}
+
+// runExitHooks runs the exit hooks, the most recently added first.
+// It does nothing if the hooks are already running,
+// as happens when a hook calls os.Exit.
+func runExitHooks() {
+	lock(&exitHooks.lock)
+	if exitHooks.running {
+		unlock(&exitHooks.lock)
+		return
+	}
+	exitHooks.running = true
+	hooks := exitHooks.hooks
+	unlock(&exitHooks.lock)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
 // start forcegc helper goroutine
 func init() {
 	go forcegchelper()
@@ -823,6 +860,20 @@ func casgstatus(gp *g, oldval, newval uint32) {
 	if newval == _Grunning {
 		gp.gcscanvalid = false
 	}
+
+	// Measure the time a sample of the transitions of gp to
+	// runnable take to be followed by it running.
+	switch {
+	case newval == _Grunnable:
+		gp.runnableTime = 0
+		if gp.trackingSeq%gTrackingPeriod == 0 {
+			gp.runnableTime = nanotime()
+		}
+		gp.trackingSeq++
+	case oldval == _Grunnable && newval == _Grunning && gp.runnableTime != 0:
+		schedLatencyDist.record(nanotime() - gp.runnableTime)
+		gp.runnableTime = 0
+	}
 }
 
 // casgstatus(gp, oldstatus, Gcopystack), assuming oldstatus is Gwaiting or Grunnable.
@@ -857,6 +908,7 @@ func scang(gp *g, gcw *gcWork) {
 	// See http://golang.org/cl/21503 for justification of the yield delay.
 	const yieldDelay = 10 * 1000
 	var nextYield int64
+	var nextPreemptM int64
 
 	// Endeavor to get gcscandone set to true,
 	// either by doing the stack scan ourselves or by coercing gp to scan itself.
@@ -897,11 +949,13 @@ loop:
 
 		case _Grunning:
 			// Goroutine running. Try to preempt execution so it can scan itself.
-			// The preemption handler (in newstack) does the actual scan.
+			// The preemption handler (in newstack or asyncPreempt) does the
+			// actual scan.
 
 			// Optimization: if there is already a pending preemption request
-			// (from the previous loop iteration), don't bother with the atomics.
-			if gp.preemptscan && gp.preempt && gp.stackguard0 == stackPreempt {
+			// (from the previous loop iteration), don't bother with the atomics,
+			// unless it is time to signal gp's M again.
+			if gp.preemptscan && gp.preempt && gp.stackguard0 == stackPreempt && nanotime() < nextPreemptM {
 				break
 			}
 
@@ -911,6 +965,19 @@ loop:
 					gp.preemptscan = true
 					gp.preempt = true
 					gp.stackguard0 = stackPreempt
+					// The signal may arrive when gp is not
+					// at an asynchronous safe point, so
+					// send it again if gp hasn't stopped
+					// after a while.
+					//
+					// execute sets gp.m only after gp
+					// starts running, so gp may not have
+					// an M yet. Signal it on a later
+					// iteration instead.
+					if mp := gp.m; mp != nil {
+						preemptM(mp)
+						nextPreemptM = nanotime() + yieldDelay
+					}
 				}
 				casfrom_Gscanstatus(gp, _Gscanrunning, _Grunning)
 			}
@@ -4286,6 +4353,8 @@ func sysmon() {
 			lastscavenge = now
 			nscavenge++
 		}
+		// return idle heap memory to the OS when over the memory limit
+		mheap_.scavengeIfOverLimit()
 		if debug.schedtrace > 0 && lasttrace+int64(debug.schedtrace)*1000000 <= now {
 			lasttrace = now
 			schedtrace(debug.scheddetail > 0)
@@ -4416,6 +4485,11 @@ func preemptone(_p_ *p) bool {
 	// Setting gp->stackguard0 to StackPreempt folds
 	// preemption into the normal stack overflow check.
 	gp.stackguard0 = stackPreempt
+
+	// Request an async preemption of this goroutine, in case it
+	// doesn't make a call.
+	preemptM(mp)
+
 	return true
 }
 
@@ -308,6 +308,11 @@ type sudog struct {
 	waitlink    *sudog // g.waiting list or semaRoot
 	waittail    *sudog // semaRoot
 	c           *hchan // channel
+
+	// hiddenc and hiddenelem hold c and elem while a leak-detecting
+	// GC hides them from the mark phase. See mgcleak.go.
+	hiddenc    uintptr
+	hiddenelem uintptr
 }
 
 type libcall struct {
@@ -366,6 +371,9 @@ type g struct {
 	preemptscan    bool     // preempted g does scan for gc
 	gcscandone     bool     // g has scanned stack; protected by _Gscan bit in status
 	gcscanvalid    bool     // false at start of gc cycle, true if G has not run since last scan; TODO: remove?
+	asyncSafePoint bool     // set if g is stopped at an asynchronous safe point
+	leakCandidate  bool     // stack withheld from the roots by a leak-detecting GC
+	leaked         bool     // found blocked forever by the last leak-detecting GC
 	throwsplit     bool     // must not split stack
 	raceignore     int8     // ignore race detection events
 	sysblocktraced bool     // StartTrace has emitted EvGoInSyscall about this goroutine
@@ -384,6 +392,7 @@ type g struct {
 	waiting        *sudog         // sudog structures this g is waiting on (that have a valid elem ptr); in lock order
 	cgoCtxt        []uintptr      // cgo traceback context
 	labels         unsafe.Pointer // profiler labels
+	blockedOn      uintptr        // semaphore or notifyList this g is blocked on; not a pointer so the GC does not see it
 	timer          *timer         // cached timer for time.Sleep
 	selectDone     uint32         // are we participating in a select and did someone win the race?
 
@@ -397,6 +406,13 @@ type g struct {
 	// and check for debt in the malloc hot path. The assist ratio
 	// determines how this corresponds to scan work debt.
 	gcAssistBytes int64
+
+	// Scheduling latency tracking for the /sched/latencies:seconds
+	// metric. runnableTime is the nanotime when the G last became
+	// runnable, or 0 if that wait is not being measured, and
+	// trackingSeq counts the times it has become runnable.
+	runnableTime int64
+	trackingSeq  uint8
 }
 
 type m struct {
@@ -458,6 +474,7 @@ type m struct {
 	syscalltick   uint32
 	thread        uintptr // thread handle
 	freelink      *m      // on sched.freem
+	signalPending uint32  // whether a preemption signal is pending (atomic)
 
 	// these are here because they are too large to be on the stack
 	// of low-level NOSPLIT functions.
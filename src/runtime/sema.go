@@ -128,6 +128,10 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags) {
 		}
 		s.acquiretime = t0
 	}
+	var waitStart int64
+	if profile&semaMutexProfile != 0 {
+		waitStart = nanotime()
+	}
 	for {
 		lock(&root.lock)
 		// Add ourselves to nwait to disable "easy case" in semrelease.
@@ -141,7 +145,9 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags) {
 		// Any semrelease after the cansemacquire knows we're waiting
 		// (we set nwait above), so go to sleep.
 		root.queue(addr, s, lifo)
+		gp.blockedOn = uintptr(unsafe.Pointer(addr))
 		goparkunlock(&root.lock, "semacquire", traceEvGoBlockSync, 4)
+		gp.blockedOn = 0
 		if s.ticket != 0 || cansemacquire(addr) {
 			break
 		}
@@ -149,6 +155,9 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags) {
 	if s.releasetime > 0 {
 		blockevent(s.releasetime-t0, 3)
 	}
+	if waitStart != 0 {
+		atomic.Xadd64(&mutexWaitTime, nanotime()-waitStart)
+	}
 	releaseSudog(s)
 }
 
@@ -492,8 +501,9 @@ func notifyListWait(l *notifyList, t uint32) {
 	}
 
 	// Enqueue itself.
+	gp := getg()
 	s := acquireSudog()
-	s.g = getg()
+	s.g = gp
 	s.ticket = t
 	s.releasetime = 0
 	t0 := int64(0)
@@ -507,7 +517,9 @@ func notifyListWait(l *notifyList, t uint32) {
 		l.tail.next = s
 	}
 	l.tail = s
+	gp.blockedOn = uintptr(unsafe.Pointer(l))
 	goparkunlock(&l.lock, "semacquire", traceEvGoBlockCond, 3)
+	gp.blockedOn = 0
 	if t0 != 0 {
 		blockevent(s.releasetime-t0, 2)
 	}
@@ -81,3 +81,15 @@ func (c *sigctxt) preparePanic(sig uint32, gp *g) {
 	}
 	c.set_eip(uint32(funcPC(sigpanic)))
 }
+
+// pushCall makes it look like the interrupted instruction called
+// targetPC, so that when the signal handler returns, targetPC runs
+// and then returns to the interrupted instruction.
+func (c *sigctxt) pushCall(targetPC uintptr) {
+	pc := uintptr(c.eip())
+	sp := uintptr(c.esp())
+	sp -= sys.PtrSize
+	*(*uintptr)(unsafe.Pointer(sp)) = pc
+	c.set_esp(uint32(sp))
+	c.set_eip(uint32(targetPC))
+}
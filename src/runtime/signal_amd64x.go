@@ -92,3 +92,19 @@ func (c *sigctxt) preparePanic(sig uint32, gp *g) {
 	}
 	c.set_rip(uint64(funcPC(sigpanic)))
 }
+
+// pushCall makes it look like the interrupted instruction called
+// targetPC, so that when the signal handler returns, targetPC runs
+// and then returns to the interrupted instruction.
+func (c *sigctxt) pushCall(targetPC uintptr) {
+	pc := uintptr(c.rip())
+	sp := uintptr(c.rsp())
+	if sys.RegSize > sys.PtrSize {
+		sp -= sys.PtrSize
+		*(*uintptr)(unsafe.Pointer(sp)) = 0
+	}
+	sp -= sys.PtrSize
+	*(*uintptr)(unsafe.Pointer(sp)) = pc
+	c.set_rsp(uint64(sp))
+	c.set_rip(uint64(targetPC))
+}
@@ -49,3 +49,10 @@ func (c *sigctxt) set_rip(x uint64)     { c.regs().mc_rip = x }
 func (c *sigctxt) set_rsp(x uint64)     { c.regs().mc_rsp = x }
 func (c *sigctxt) set_sigcode(x uint64) { c.info.si_code = int32(x) }
 func (c *sigctxt) set_sigaddr(x uint64) { c.info.si_addr = x }
+
+func thr_kill(tid int64, sig int)
+
+// signalM sends sig to mp's thread.
+func signalM(mp *m, sig int) {
+	thr_kill(int64(mp.procid), sig)
+}
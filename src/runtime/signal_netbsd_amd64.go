@@ -53,3 +53,10 @@ func (c *sigctxt) set_sigcode(x uint64) { c.info._code = int32(x) }
 func (c *sigctxt) set_sigaddr(x uint64) {
 	*(*uint64)(unsafe.Pointer(&c.info._reason[0])) = x
 }
+
+func lwp_kill(tid int32, sig int)
+
+// signalM sends sig to mp's thread.
+func signalM(mp *m, sig int) {
+	lwp_kill(int32(mp.procid), sig)
+}
@@ -0,0 +1,15 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux nacl netbsd openbsd solaris
+// +build !386,!amd64,!amd64p32
+
+package runtime
+
+// pushCall is only implemented on amd64 and 386. On other
+// architectures preemptM never sends preemption signals, so it is
+// never called.
+func (c *sigctxt) pushCall(targetPC uintptr) {
+	throw("pushCall not implemented")
+}
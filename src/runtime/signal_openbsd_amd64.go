@@ -53,3 +53,10 @@ func (c *sigctxt) set_sigcode(x uint64) { c.info.si_code = int32(x) }
 func (c *sigctxt) set_sigaddr(x uint64) {
 	*(*uint64)(add(unsafe.Pointer(c.info), 16)) = x
 }
+
+func thrkill(tid int32, sig int)
+
+// signalM sends sig to mp's thread.
+func signalM(mp *m, sig int) {
+	thrkill(int32(mp.procid), sig)
+}
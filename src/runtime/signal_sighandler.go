@@ -7,6 +7,7 @@
 package runtime
 
 import (
+	"runtime/internal/atomic"
 	"unsafe"
 )
 
@@ -34,6 +35,14 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 		return
 	}
 
+	if sig == sigPreempt && preemptMSupported && debug.asyncpreemptoff == 0 {
+		// Might be a preemption signal.
+		doSigPreempt(gp, c)
+		// Even if this was a preemption signal, it may have
+		// coalesced with a signal sent by the program, so we
+		// still let it through to the usual handling.
+	}
+
 	flags := int32(_SigThrow)
 	if sig < uint32(len(sigtable)) {
 		flags = sigtable[sig].flags
@@ -131,3 +140,17 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 
 	exit(2)
 }
+
+// doSigPreempt handles a preemption signal on gp. If gp wants to be
+// preempted and is at an asynchronous safe point, it injects a call to
+// asyncPreempt.
+//
+//go:nowritebarrierrec
+func doSigPreempt(gp *g, c *sigctxt) {
+	if wantAsyncPreempt(gp) && isAsyncSafePoint(gp, c.sigpc(), c.sigsp()) {
+		c.pushCall(funcPC(asyncPreempt))
+	}
+
+	// Acknowledge the preemption.
+	atomic.Store(&gp.m.signalPending, 0)
+}
@@ -38,6 +38,15 @@ const (
 	_SIG_IGN uintptr = 1
 )
 
+// sigPreempt is the signal used for non-cooperative preemption.
+//
+// It must be a signal that is passed through by debuggers by default,
+// that is not used internally by libc, and that programs tolerate
+// receiving spuriously, because it may coalesce with a signal sent by
+// the program itself. SIGURG is only sent for out-of-band socket
+// data, which programs rarely use, and is ignored by default.
+const sigPreempt = _SIGURG
+
 // Stores the signal handlers registered before Go installed its own.
 // These signal handlers will be invoked in cases where Go doesn't want to
 // handle a particular signal (e.g., signal occurred on a non-Go thread).
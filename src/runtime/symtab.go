@@ -74,9 +74,10 @@ type stackExpander struct {
 	// pcExpander expands the current PC into a sequence of Frames.
 	pcExpander pcExpander
 
-	// If previous caller in iteration was a panic, then the next
-	// PC in the call stack is the address of the faulting
-	// instruction instead of the return address of the call.
+	// If previous caller in iteration was a panic or an
+	// asynchronous preemption, then the next PC in the call stack
+	// is the address of the interrupted instruction instead of the
+	// return address of the call.
 	wasPanic bool
 
 	// skip > 0 indicates that skip frames in the expansion of the
@@ -133,7 +134,8 @@ again:
 		}
 		se.pcExpander.init(ncallers[0], se.wasPanic)
 		ncallers = ncallers[1:]
-		se.wasPanic = se.pcExpander.funcInfo.valid() && se.pcExpander.funcInfo.entry == sigpanicPC
+		se.wasPanic = se.pcExpander.funcInfo.valid() &&
+			(se.pcExpander.funcInfo.entry == sigpanicPC || se.pcExpander.funcInfo.entry == asyncPreemptPC)
 		if se.skip > 0 {
 			for ; se.skip > 0; se.skip-- {
 				se.pcExpander.next()
@@ -196,9 +198,9 @@ type pcExpander struct {
 //
 // A pcExpander can be reused by calling init again.
 //
-// If pc was a "call" to sigpanic, panicCall should be true. In this
-// case, pc is treated as the address of a faulting instruction
-// instead of the return address of a call.
+// If pc was a "call" to sigpanic or asyncPreempt, panicCall should be
+// true. In this case, pc is treated as the address of a faulting or
+// interrupted instruction instead of the return address of a call.
 func (ex *pcExpander) init(pc uintptr, panicCall bool) {
 	ex.more = false
 
@@ -343,12 +345,19 @@ func (f *Func) funcInfo() funcInfo {
 const (
 	_PCDATA_StackMapIndex       = 0
 	_PCDATA_InlTreeIndex        = 1
+	_PCDATA_UnsafePoint         = 2
 	_FUNCDATA_ArgsPointerMaps   = 0
 	_FUNCDATA_LocalsPointerMaps = 1
 	_FUNCDATA_InlTree           = 2
 	_ArgsSizeUnknown            = -0x80000000
 )
 
+// Values of the _PCDATA_UnsafePoint table.
+const (
+	_PCDATA_UnsafePointSafe   = -1 // Safe for async preemption
+	_PCDATA_UnsafePointUnsafe = -2 // Unsafe for async preemption
+)
+
 // moduledata records information about the layout of the executable
 // image. It is written by the linker. Any changes here must be
 // matched changes to the code in cmd/internal/ld/symtab.go:symtab.
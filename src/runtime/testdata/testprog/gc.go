@@ -17,6 +17,7 @@ func init() {
 	register("GCFairness", GCFairness)
 	register("GCFairness2", GCFairness2)
 	register("GCSys", GCSys)
+	register("GCMemoryLimit", GCMemoryLimit)
 }
 
 func GCSys() {
@@ -52,6 +53,10 @@ func workthegc() []byte {
 	return make([]byte, 1029)
 }
 
+func GCMemoryLimit() {
+	fmt.Println(debug.SetMemoryLimit(-1))
+}
+
 func GCFairness() {
 	runtime.GOMAXPROCS(1)
 	f, err := os.Open("/dev/null")
@@ -0,0 +1,78 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+func init() {
+	register("AsyncPreempt", AsyncPreempt)
+	register("AsyncPreemptScheduling", AsyncPreemptScheduling)
+}
+
+func AsyncPreempt() {
+	// Run with just 1 GOMAXPROCS so the runtime is required to
+	// use scheduler preemption.
+	runtime.GOMAXPROCS(1)
+	// Disable GC so we have complete control of what we're testing.
+	debug.SetGCPercent(-1)
+
+	// Start a goroutine with no synchronous safe points.
+	var ready uint32
+	go func() {
+		for {
+			atomic.StoreUint32(&ready, 1)
+		}
+	}()
+
+	// Wait for the goroutine to stop passing through synchronous
+	// safe points. Getting back here needs the scheduler to
+	// preempt it.
+	for atomic.LoadUint32(&ready) == 0 {
+		runtime.Gosched()
+	}
+
+	// Run a GC, which will have to stop the goroutine for STW and
+	// for stack scanning. If this doesn't work, the test will
+	// deadlock and time out.
+	runtime.GC()
+
+	println("OK")
+}
+
+func AsyncPreemptScheduling() {
+	// Keep goroutines moving on and off several Ps while the GC
+	// scans their stacks, so that it finds goroutines that have
+	// just started running and may not have an M yet.
+	runtime.GOMAXPROCS(4)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		runtime.GC()
+	}
+	close(stop)
+	wg.Wait()
+
+	println("OK")
+}
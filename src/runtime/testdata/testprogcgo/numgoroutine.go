@@ -31,8 +31,6 @@ import (
 	"strings"
 )
 
-var baseGoroutines int
-
 func init() {
 	register("NumGoroutine", NumGoroutine)
 }
@@ -41,14 +39,7 @@ func NumGoroutine() {
 	// Test that there are just the expected number of goroutines
 	// running. Specifically, test that the spare M's goroutine
 	// doesn't show up.
-	//
-	// On non-Windows platforms there's a signal handling thread
-	// started by os/signal.init in addition to the main
-	// goroutine.
-	if runtime.GOOS != "windows" {
-		baseGoroutines = 1
-	}
-	if _, ok := checkNumGoroutine("first", 1+baseGoroutines); !ok {
+	if _, ok := checkNumGoroutine("first", 1); !ok {
 		return
 	}
 
@@ -58,7 +49,7 @@ func NumGoroutine() {
 	}
 
 	// Make sure we're back to the initial goroutines.
-	if _, ok := checkNumGoroutine("third", 1+baseGoroutines); !ok {
+	if _, ok := checkNumGoroutine("third", 1); !ok {
 		return
 	}
 
@@ -86,7 +77,7 @@ var callbackok bool
 
 //export CallbackNumGoroutine
 func CallbackNumGoroutine() {
-	stk, ok := checkNumGoroutine("second", 2+baseGoroutines)
+	stk, ok := checkNumGoroutine("second", 2)
 	if !ok {
 		return
 	}
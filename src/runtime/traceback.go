@@ -44,6 +44,7 @@ var (
 	mstartPC             uintptr
 	rt0_goPC             uintptr
 	sigpanicPC           uintptr
+	asyncPreemptPC       uintptr
 	runfinqPC            uintptr
 	bgsweepPC            uintptr
 	forcegchelperPC      uintptr
@@ -71,6 +72,7 @@ func tracebackinit() {
 	mstartPC = funcPC(mstart)
 	rt0_goPC = funcPC(rt0_go)
 	sigpanicPC = funcPC(sigpanic)
+	asyncPreemptPC = funcPC(asyncPreempt)
 	runfinqPC = funcPC(runfinq)
 	bgsweepPC = funcPC(bgsweep)
 	forcegchelperPC = funcPC(forcegchelper)
@@ -181,6 +183,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 		frame.lr = lr0
 	}
 	waspanic := false
+	injectedCall := false
 	cgoCtxt := gp.cgoCtxt
 	printing := pcbuf == nil && callback == nil
 	_defer := gp._defer
@@ -347,7 +350,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 			} else {
 				// backup to CALL instruction to read inlining info (same logic as below)
 				tracepc := frame.pc
-				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !waspanic {
+				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !injectedCall {
 					tracepc--
 				}
 				inldata := funcdata(f, _FUNCDATA_InlTree)
@@ -401,7 +404,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 				//		/home/rsc/go/src/runtime/x.go:23 +0xf
 				//
 				tracepc := frame.pc // back up to CALL instruction for funcline.
-				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !waspanic {
+				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !injectedCall {
 					tracepc--
 				}
 				file, line := funcline(f, tracepc)
@@ -463,6 +466,9 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 		}
 
 		waspanic = f.entry == sigpanicPC
+		// The next frame did not call f if f was injected by
+		// a signal handler, so its pc is not a return address.
+		injectedCall = waspanic || f.entry == asyncPreemptPC
 
 		// Do not unwind past the bottom of the stack.
 		if !flr.valid() {
@@ -830,9 +836,81 @@ func goroutineheader(gp *g) {
 	if gp.lockedm != 0 {
 		print(", locked to thread")
 	}
+	if gp.leaked && gpstatus == _Gwaiting {
+		print(", leaked")
+	}
+	if gp.labels != nil {
+		print(", labels: ")
+		printlabels(gp.labels)
+	}
 	print("]:\n")
 }
 
+// profLabel mirrors a label in runtime/pprof.
+type profLabel struct {
+	key, value string
+}
+
+// printlabels prints a set of profiler labels, as set by
+// runtime/pprof.
+//
+// The labels are a pointer to a slice of profLabels sorted by key,
+// which runtime/pprof never modifies once it has been set on a
+// goroutine.
+func printlabels(labels unsafe.Pointer) {
+	print("{")
+	for i, l := range *(*[]profLabel)(labels) {
+		if i > 0 {
+			print(", ")
+		}
+		printquoted(l.key)
+		print(":")
+		printquoted(l.value)
+	}
+	print("}")
+}
+
+// printquoted prints s in double quotes, escaping quotes, backslashes
+// and ASCII control characters as strconv.Quote does, so that labels
+// print as in runtime/pprof's debug=1 output and cannot end the label
+// set or the goroutine header early. Other bytes print unchanged.
+func printquoted(s string) {
+	const hexdigits = "0123456789abcdef"
+	print("\"")
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= ' ' && c != '"' && c != '\\' && c != 0x7f {
+			continue
+		}
+		print(s[start:i])
+		switch c {
+		case '"', '\\':
+			print("\\", s[i:i+1])
+		case '\a':
+			print("\\a")
+		case '\b':
+			print("\\b")
+		case '\f':
+			print("\\f")
+		case '\n':
+			print("\\n")
+		case '\r':
+			print("\\r")
+		case '\t':
+			print("\\t")
+		case '\v':
+			print("\\v")
+		case 0x7f:
+			print("\\u007f")
+		default:
+			print("\\x", hexdigits[c>>4:c>>4+1], hexdigits[c&0xf:c&0xf+1])
+		}
+		start = i + 1
+	}
+	print(s[start:], "\"")
+}
+
 func tracebackothers(me *g) {
 	level, _, _ := gotraceback()
 
@@ -50,7 +50,8 @@ import (
 // On both ARM and x86-32, it is the caller's responsibility to arrange for 64-bit
 // alignment of 64-bit words accessed atomically. The first word in a
 // variable or in an allocated struct, array, or slice can be relied upon to be
-// 64-bit aligned.
+// 64-bit aligned. The Int64 and Uint64 types are 64-bit aligned wherever
+// they are used.
 
 // SwapInt32 atomically stores new into *addr and returns the previous *addr value.
 func SwapInt32(addr *int32, new int32) (old int32)
@@ -0,0 +1,143 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atomic_test
+
+import (
+	"sync"
+	. "sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+func TestBool(t *testing.T) {
+	var x Bool
+	if x.Load() {
+		t.Errorf("zero Bool is true")
+	}
+	x.Store(true)
+	if !x.Load() {
+		t.Errorf("Store(true) did not store true")
+	}
+	if old := x.Swap(false); !old || x.Load() {
+		t.Errorf("Swap(false) = %v, value %v; want true, false", old, x.Load())
+	}
+	if x.CompareAndSwap(true, true) {
+		t.Errorf("CompareAndSwap(true, true) of false succeeded")
+	}
+	if !x.CompareAndSwap(false, true) || !x.Load() {
+		t.Errorf("CompareAndSwap(false, true) of false failed")
+	}
+}
+
+func TestPointer(t *testing.T) {
+	var x Pointer
+	a, b := new(int), new(int)
+	if x.Load() != nil {
+		t.Errorf("zero Pointer is not nil")
+	}
+	x.Store(unsafe.Pointer(a))
+	if x.Load() != unsafe.Pointer(a) {
+		t.Errorf("Load did not return stored pointer")
+	}
+	if old := x.Swap(unsafe.Pointer(b)); old != unsafe.Pointer(a) {
+		t.Errorf("Swap returned wrong pointer")
+	}
+	if x.CompareAndSwap(unsafe.Pointer(a), nil) {
+		t.Errorf("CompareAndSwap with wrong old pointer succeeded")
+	}
+	if !x.CompareAndSwap(unsafe.Pointer(b), nil) || x.Load() != nil {
+		t.Errorf("CompareAndSwap with right old pointer failed")
+	}
+}
+
+func TestInt64Methods(t *testing.T) {
+	var x Int64
+	x.Store(-3)
+	if v := x.Add(5); v != 2 {
+		t.Errorf("Add(5) = %d, want 2", v)
+	}
+	if old := x.Swap(1 << 40); old != 2 {
+		t.Errorf("Swap = %d, want 2", old)
+	}
+	if x.CompareAndSwap(2, 3) || !x.CompareAndSwap(1<<40, 3) || x.Load() != 3 {
+		t.Errorf("CompareAndSwap did not swap only with the right old value")
+	}
+}
+
+func TestTypesAdd(t *testing.T) {
+	// The types with Add are counted up concurrently
+	// and must not lose any increments.
+	const (
+		procs = 4
+		n     = 1000
+	)
+	var (
+		i32 Int32
+		i64 Int64
+		u32 Uint32
+		u64 Uint64
+		up  Uintptr
+		wg  sync.WaitGroup
+	)
+	for p := 0; p < procs; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				i32.Add(1)
+				i64.Add(1)
+				u32.Add(1)
+				u64.Add(1)
+				up.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if i32.Load() != procs*n || i64.Load() != procs*n || u32.Load() != procs*n || u64.Load() != procs*n || up.Load() != procs*n {
+		t.Errorf("counts = %d, %d, %d, %d, %d; want %d", i32.Load(), i64.Load(), u32.Load(), u64.Load(), up.Load(), procs*n)
+	}
+}
+
+func TestTypesAlignment(t *testing.T) {
+	// A 64-bit type following a 32-bit field is aligned
+	// even where int64 fields are only 32-bit aligned.
+	var s struct {
+		a int32
+		b Int64
+		c int32
+		d Uint64
+	}
+	if off := unsafe.Offsetof(s.b); off%8 != 0 {
+		t.Errorf("Int64 field at offset %d", off)
+	}
+	if off := unsafe.Offsetof(s.d); off%8 != 0 {
+		t.Errorf("Uint64 field at offset %d", off)
+	}
+	if a := unsafe.Alignof(s.b); a != 8 {
+		t.Errorf("Alignof(Int64) = %d, want 8", a)
+	}
+
+	// So are variables of these types and of types containing them,
+	// wherever they are allocated. On 32-bit systems these
+	// operations crash if the value is not aligned.
+	var x Int64
+	x.Add(1)
+	s.b.Add(1)
+	s.d.Add(1)
+	arr := make([]Uint64, 3)
+	for i := range arr {
+		arr[i].Add(1)
+	}
+	p := new(struct {
+		a int32
+		b Uint64
+	})
+	p.b.Add(1)
+	for _, a := range []uintptr{uintptr(unsafe.Pointer(&x)), uintptr(unsafe.Pointer(&s.b)), uintptr(unsafe.Pointer(&arr[1])), uintptr(unsafe.Pointer(&p.b))} {
+		if a%8 != 0 {
+			t.Errorf("64-bit atomic value at misaligned address %#x", a)
+		}
+	}
+}
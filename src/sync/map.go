@@ -266,8 +266,9 @@ func (e *entry) tryLoadOrStore(i interface{}) (actual interface{}, loaded, ok bo
 	}
 }
 
-// Delete deletes the value for a key.
-func (m *Map) Delete(key interface{}) {
+// LoadAndDelete deletes the value for a key, returning the previous value if any.
+// The loaded result reports whether the key was present.
+func (m *Map) LoadAndDelete(key interface{}) (value interface{}, loaded bool) {
 	read, _ := m.read.Load().(readOnly)
 	e, ok := read.m[key]
 	if !ok && read.amended {
@@ -275,25 +276,189 @@ func (m *Map) Delete(key interface{}) {
 		read, _ = m.read.Load().(readOnly)
 		e, ok = read.m[key]
 		if !ok && read.amended {
+			e, ok = m.dirty[key]
 			delete(m.dirty, key)
+			// Regardless of whether the entry was present, record a miss: this key
+			// will take the slow path until the dirty map is promoted to the read
+			// map.
+			m.missLocked()
 		}
 		m.mu.Unlock()
 	}
 	if ok {
-		e.delete()
+		return e.delete()
 	}
+	return nil, false
 }
 
-func (e *entry) delete() (hadValue bool) {
+// Delete deletes the value for a key.
+func (m *Map) Delete(key interface{}) {
+	m.LoadAndDelete(key)
+}
+
+func (e *entry) delete() (value interface{}, ok bool) {
 	for {
 		p := atomic.LoadPointer(&e.p)
 		if p == nil || p == expunged {
+			return nil, false
+		}
+		if atomic.CompareAndSwapPointer(&e.p, p, nil) {
+			return *(*interface{})(p), true
+		}
+	}
+}
+
+// Swap stores the value for a key and returns the previous value if any.
+// The loaded result reports whether the key was present.
+func (m *Map) Swap(key, value interface{}) (previous interface{}, loaded bool) {
+	read, _ := m.read.Load().(readOnly)
+	if e, ok := read.m[key]; ok {
+		if p, ok := e.trySwap(&value); ok {
+			if p == nil {
+				return nil, false
+			}
+			return *(*interface{})(p), true
+		}
+	}
+
+	m.mu.Lock()
+	read, _ = m.read.Load().(readOnly)
+	if e, ok := read.m[key]; ok {
+		if e.unexpungeLocked() {
+			// The entry was previously expunged, which implies that there is a
+			// non-nil dirty map and this entry is not in it.
+			m.dirty[key] = e
+		}
+		if p := e.swapLocked(&value); p != nil {
+			previous, loaded = *(*interface{})(p), true
+		}
+	} else if e, ok := m.dirty[key]; ok {
+		if p := e.swapLocked(&value); p != nil {
+			previous, loaded = *(*interface{})(p), true
+		}
+	} else {
+		if !read.amended {
+			// We're adding the first new key to the dirty map.
+			// Make sure it is allocated and mark the read-only map as incomplete.
+			m.dirtyLocked()
+			m.read.Store(readOnly{m: read.m, amended: true})
+		}
+		m.dirty[key] = newEntry(value)
+	}
+	m.mu.Unlock()
+	return previous, loaded
+}
+
+// trySwap swaps a value if the entry has not been expunged,
+// returning the previous pointer, which is nil if the entry was deleted.
+//
+// If the entry is expunged, trySwap returns false and leaves the entry
+// unchanged.
+func (e *entry) trySwap(i *interface{}) (unsafe.Pointer, bool) {
+	for {
+		p := atomic.LoadPointer(&e.p)
+		if p == expunged {
+			return nil, false
+		}
+		if atomic.CompareAndSwapPointer(&e.p, p, unsafe.Pointer(i)) {
+			return p, true
+		}
+	}
+}
+
+// swapLocked unconditionally swaps a value into the entry,
+// returning the previous pointer.
+//
+// The entry must be known not to be expunged.
+func (e *entry) swapLocked(i *interface{}) unsafe.Pointer {
+	return atomic.SwapPointer(&e.p, unsafe.Pointer(i))
+}
+
+// CompareAndSwap swaps the old and new values for key
+// if the value stored in the map is equal to old.
+// The old value must be of a comparable type.
+func (m *Map) CompareAndSwap(key, old, new interface{}) (swapped bool) {
+	read, _ := m.read.Load().(readOnly)
+	if e, ok := read.m[key]; ok {
+		return e.tryCompareAndSwap(old, new)
+	} else if !read.amended {
+		return false // No existing value for key.
+	}
+
+	m.mu.Lock()
+	read, _ = m.read.Load().(readOnly)
+	if e, ok := read.m[key]; ok {
+		swapped = e.tryCompareAndSwap(old, new)
+	} else if e, ok := m.dirty[key]; ok {
+		swapped = e.tryCompareAndSwap(old, new)
+		// The set of keys did not change, so this lookup in the dirty map
+		// counts as a miss, like one by Load.
+		m.missLocked()
+	}
+	m.mu.Unlock()
+	return swapped
+}
+
+// tryCompareAndSwap swaps the value of the entry with new if it is
+// equal to old and the entry has not been expunged.
+//
+// If the entry is expunged, tryCompareAndSwap returns false and leaves
+// the entry unchanged.
+func (e *entry) tryCompareAndSwap(old, new interface{}) bool {
+	p := atomic.LoadPointer(&e.p)
+	if p == nil || p == expunged || *(*interface{})(p) != old {
+		return false
+	}
+
+	// Copy the interface after the first load to make this method more amenable
+	// to escape analysis: if the comparison fails from the start, we shouldn't
+	// bother heap-allocating an interface value to store.
+	nc := new
+	for {
+		if atomic.CompareAndSwapPointer(&e.p, p, unsafe.Pointer(&nc)) {
+			return true
+		}
+		p = atomic.LoadPointer(&e.p)
+		if p == nil || p == expunged || *(*interface{})(p) != old {
+			return false
+		}
+	}
+}
+
+// CompareAndDelete deletes the entry for key if its value is equal to old.
+// The old value must be of a comparable type.
+//
+// If there is no current value for key in the map, CompareAndDelete
+// returns false, even if old is the nil interface value.
+func (m *Map) CompareAndDelete(key, old interface{}) (deleted bool) {
+	read, _ := m.read.Load().(readOnly)
+	e, ok := read.m[key]
+	if !ok && read.amended {
+		m.mu.Lock()
+		read, _ = m.read.Load().(readOnly)
+		e, ok = read.m[key]
+		if !ok && read.amended {
+			e, ok = m.dirty[key]
+			// Leave the key in m.dirty, since the value may not match:
+			// a deleted entry is expunged when the dirty map is next rebuilt.
+			//
+			// Regardless of whether the entry was present, record a miss: this key
+			// will take the slow path until the dirty map is promoted to the read
+			// map.
+			m.missLocked()
+		}
+		m.mu.Unlock()
+	}
+	for ok {
+		p := atomic.LoadPointer(&e.p)
+		if p == nil || p == expunged || *(*interface{})(p) != old {
 			return false
 		}
 		if atomic.CompareAndSwapPointer(&e.p, p, nil) {
 			return true
 		}
 	}
+	return false
 }
 
 // Range calls f sequentially for each key and value present in the map.
@@ -16,13 +16,26 @@ import (
 type mapOp string
 
 const (
-	opLoad        = mapOp("Load")
-	opStore       = mapOp("Store")
-	opLoadOrStore = mapOp("LoadOrStore")
-	opDelete      = mapOp("Delete")
+	opLoad             = mapOp("Load")
+	opStore            = mapOp("Store")
+	opLoadOrStore      = mapOp("LoadOrStore")
+	opLoadAndDelete    = mapOp("LoadAndDelete")
+	opDelete           = mapOp("Delete")
+	opSwap             = mapOp("Swap")
+	opCompareAndSwap   = mapOp("CompareAndSwap")
+	opCompareAndDelete = mapOp("CompareAndDelete")
 )
 
-var mapOps = [...]mapOp{opLoad, opStore, opLoadOrStore, opDelete}
+var mapOps = [...]mapOp{
+	opLoad,
+	opStore,
+	opLoadOrStore,
+	opLoadAndDelete,
+	opDelete,
+	opSwap,
+	opCompareAndSwap,
+	opCompareAndDelete,
+}
 
 // mapCall is a quick.Generator for calls on mapInterface.
 type mapCall struct {
@@ -39,9 +52,26 @@ func (c mapCall) apply(m mapInterface) (interface{}, bool) {
 		return nil, false
 	case opLoadOrStore:
 		return m.LoadOrStore(c.k, c.v)
+	case opLoadAndDelete:
+		return m.LoadAndDelete(c.k)
 	case opDelete:
 		m.Delete(c.k)
 		return nil, false
+	case opSwap:
+		return m.Swap(c.k, c.v)
+	case opCompareAndSwap:
+		if m.CompareAndSwap(c.k, c.v, rand.Int()) {
+			m.Delete(c.k)
+			return c.v, true
+		}
+		return nil, false
+	case opCompareAndDelete:
+		if m.CompareAndDelete(c.k, c.v) {
+			if _, ok := m.Load(c.k); !ok {
+				return nil, true
+			}
+		}
+		return nil, false
 	default:
 		panic("invalid mapOp")
 	}
@@ -63,7 +93,7 @@ func randValue(r *rand.Rand) interface{} {
 func (mapCall) Generate(r *rand.Rand, size int) reflect.Value {
 	c := mapCall{op: mapOps[rand.Intn(len(mapOps))], k: randValue(r)}
 	switch c.op {
-	case opStore, opLoadOrStore:
+	case opStore, opLoadOrStore, opSwap, opCompareAndSwap, opCompareAndDelete:
 		c.v = randValue(r)
 	}
 	return reflect.ValueOf(c)
@@ -168,3 +198,92 @@ func TestConcurrentRange(t *testing.T) {
 		}
 	}
 }
+
+func TestConcurrentCompareAndSwap(t *testing.T) {
+	// Each goroutine increments the counters by CompareAndSwap,
+	// so that no increment is lost.
+	const (
+		keys  = 8
+		incrs = 1000
+	)
+	m := new(sync.Map)
+	for k := 0; k < keys; k++ {
+		m.Store(k, 0)
+	}
+	procs := runtime.GOMAXPROCS(0)
+	var wg sync.WaitGroup
+	for g := 0; g < procs; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < incrs; i++ {
+				k := i % keys
+				for {
+					v, _ := m.Load(k)
+					if m.CompareAndSwap(k, v, v.(int)+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	m.Range(func(k, v interface{}) bool {
+		if want := procs * incrs / keys; v.(int) != want {
+			t.Errorf("counter %v = %v, want %v", k, v, want)
+		}
+		return true
+	})
+}
+
+func TestConcurrentLoadAndDelete(t *testing.T) {
+	// Exactly one of the goroutines racing to delete each key gets its value.
+	const keys = 1000
+	m := new(sync.Map)
+	for k := 0; k < keys; k++ {
+		m.Store(k, k)
+	}
+	var (
+		mu     sync.Mutex
+		loaded = make(map[interface{}]int)
+		wg     sync.WaitGroup
+	)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < keys; k++ {
+				if v, ok := m.LoadAndDelete(k); ok {
+					mu.Lock()
+					loaded[v]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(loaded) != keys {
+		t.Errorf("LoadAndDelete returned %d distinct values, want %d", len(loaded), keys)
+	}
+	for v, n := range loaded {
+		if n != 1 {
+			t.Errorf("LoadAndDelete returned %v %d times, want once", v, n)
+		}
+	}
+}
+
+func TestCompareAndSwapMissing(t *testing.T) {
+	var m sync.Map
+	if m.CompareAndSwap("k", nil, 1) {
+		t.Errorf("CompareAndSwap of missing key with nil old value succeeded")
+	}
+	if m.CompareAndDelete("k", nil) {
+		t.Errorf("CompareAndDelete of missing key with nil old value succeeded")
+	}
+	if v, loaded := m.Swap("k", 1); loaded || v != nil {
+		t.Errorf("Swap of missing key = %v, %v; want nil, false", v, loaded)
+	}
+	if v, loaded := m.Swap("k", 2); !loaded || v != 1 {
+		t.Errorf("Swap = %v, %v; want 1, true", v, loaded)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sync_test
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestOnceFunc(t *testing.T) {
+	calls := 0
+	f := sync.OnceFunc(func() { calls++ })
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+	wg.Wait()
+	f()
+	if calls != 1 {
+		t.Errorf("OnceFunc called f %d times, want 1", calls)
+	}
+}
+
+func TestOnceValue(t *testing.T) {
+	calls := 0
+	f := sync.OnceValue(func() interface{} {
+		calls++
+		return calls
+	})
+	for i := 0; i < 3; i++ {
+		if v := f(); v != 1 {
+			t.Errorf("OnceValue returned %v, want 1", v)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("OnceValue called f %d times, want 1", calls)
+	}
+}
+
+// testOncePanic checks that every call of f panics with "x",
+// and that the function wrapped by f runs only once.
+func testOncePanic(t *testing.T, calls *int, f func()) {
+	for i := 0; i < 3; i++ {
+		func() {
+			defer func() {
+				if p := recover(); p != "x" {
+					t.Errorf("call %d: recovered %v, want x", i, p)
+				}
+			}()
+			f()
+		}()
+	}
+	if *calls != 1 {
+		t.Errorf("wrapped function called %d times, want 1", *calls)
+	}
+}
+
+func TestOnceFuncPanic(t *testing.T) {
+	calls := 0
+	f := sync.OnceFunc(func() {
+		calls++
+		panic("x")
+	})
+	testOncePanic(t, &calls, f)
+}
+
+func TestOnceValuePanic(t *testing.T) {
+	calls := 0
+	f := sync.OnceValue(func() interface{} {
+		calls++
+		panic("x")
+	})
+	testOncePanic(t, &calls, func() { f() })
+}
@@ -8,11 +8,15 @@ import (
 	"flag"
 	"fmt"
 	"internal/race"
+	"math"
 	"os"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 var matchBenchmarks = flag.String("test.bench", "", "run only benchmarks matching `regexp`")
@@ -66,6 +70,8 @@ type B struct {
 	// The net total of this test after being run.
 	netAllocs uint64
 	netBytes  uint64
+	// Extra metrics collected by ReportMetric.
+	extra map[string]float64
 }
 
 // StartTimer starts timing a test. This function is called automatically
@@ -103,6 +109,15 @@ func (b *B) ResetTimer() {
 		b.startBytes = memStats.TotalAlloc
 		b.start = time.Now()
 	}
+	// Metrics reported before the reset no longer apply.
+	// When called by runN, the map of the previous run is reused.
+	if b.extra == nil {
+		b.extra = make(map[string]float64, 16)
+	} else {
+		for k := range b.extra {
+			delete(b.extra, k)
+		}
+	}
 	b.duration = 0
 	b.netAllocs = 0
 	b.netBytes = 0
@@ -119,6 +134,35 @@ func (b *B) ReportAllocs() {
 	b.showAllocResult = true
 }
 
+// Elapsed returns the measured elapsed time of the benchmark,
+// as accumulated by StartTimer, StopTimer and ResetTimer.
+// It includes the time since the timer was last started, if it is running.
+func (b *B) Elapsed() time.Duration {
+	d := b.duration
+	if b.timerOn {
+		d += time.Since(b.start)
+	}
+	return d
+}
+
+// ReportMetric adds "n unit" to the reported benchmark results.
+// If the metric is per-iteration, the caller should divide by b.N,
+// and by convention the unit should end in "/op".
+// ReportMetric overrides any value previously reported for the same unit.
+// If unit is one reported by the benchmark framework itself, such as
+// "ns/op" or "allocs/op", the reported value replaces the measured one;
+// setting "ns/op" to 0 suppresses that metric.
+// ReportMetric panics if unit is empty or contains whitespace.
+func (b *B) ReportMetric(n float64, unit string) {
+	if unit == "" {
+		panic("testing: metric unit must not be empty")
+	}
+	if strings.IndexFunc(unit, unicode.IsSpace) >= 0 {
+		panic("testing: metric unit must not contain whitespace")
+	}
+	b.extra[unit] = n
+}
+
 func (b *B) nsPerOp() int64 {
 	if b.N <= 0 {
 		return 0
@@ -130,6 +174,7 @@ func (b *B) nsPerOp() int64 {
 func (b *B) runN(n int) {
 	benchmarkLock.Lock()
 	defer benchmarkLock.Unlock()
+	defer b.runCleanup(normalPanic)
 	// Try to get a comparable environment for each run
 	// by clearing garbage from previous runs.
 	runtime.GC()
@@ -215,7 +260,7 @@ func (b *B) run1() bool {
 	}()
 	<-b.signal
 	if b.failed {
-		fmt.Fprintf(b.w, "--- FAIL: %s\n%s", b.name, b.output)
+		fmt.Fprintf(b.w, "%s--- FAIL: %s\n%s", b.chatty.prefix(), b.name, b.output)
 		return false
 	}
 	// Only print the output if we know we are not going to proceed.
@@ -225,9 +270,9 @@ func (b *B) run1() bool {
 		if b.skipped {
 			tag = "SKIP"
 		}
-		if b.chatty && (len(b.output) > 0 || b.finished) {
+		if b.chatty != nil && (len(b.output) > 0 || b.finished) {
 			b.trimOutput()
-			fmt.Fprintf(b.w, "--- %s: %s\n%s", tag, b.name, b.output)
+			fmt.Fprintf(b.w, "%s--- %s: %s\n%s", b.chatty.prefix(), tag, b.name, b.output)
 		}
 		return false
 	}
@@ -289,7 +334,13 @@ func (b *B) launch() {
 		n = roundUp(n)
 		b.runN(n)
 	}
-	b.result = BenchmarkResult{b.N, b.duration, b.bytes, b.netAllocs, b.netBytes}
+	// Copy the metrics: ResetTimer clears b.extra in place
+	// if b runs again.
+	extra := make(map[string]float64, len(b.extra))
+	for k, v := range b.extra {
+		extra[k] = v
+	}
+	b.result = BenchmarkResult{b.N, b.duration, b.bytes, b.netAllocs, b.netBytes, extra}
 }
 
 // The results of a benchmark run.
@@ -299,9 +350,17 @@ type BenchmarkResult struct {
 	Bytes     int64         // Bytes processed in one iteration.
 	MemAllocs uint64        // The total number of memory allocations.
 	MemBytes  uint64        // The total number of bytes allocated.
+
+	// Extra records additional metrics reported by ReportMetric,
+	// keyed by unit.
+	Extra map[string]float64
 }
 
+// NsPerOp returns the "ns/op" metric.
 func (r BenchmarkResult) NsPerOp() int64 {
+	if v, ok := r.Extra["ns/op"]; ok {
+		return int64(v)
+	}
 	if r.N <= 0 {
 		return 0
 	}
@@ -309,46 +368,106 @@ func (r BenchmarkResult) NsPerOp() int64 {
 }
 
 func (r BenchmarkResult) mbPerSec() float64 {
+	if v, ok := r.Extra["MB/s"]; ok {
+		return v
+	}
 	if r.Bytes <= 0 || r.T <= 0 || r.N <= 0 {
 		return 0
 	}
 	return (float64(r.Bytes) * float64(r.N) / 1e6) / r.T.Seconds()
 }
 
-// AllocsPerOp returns r.MemAllocs / r.N.
+// AllocsPerOp returns the "allocs/op" metric,
+// which is r.MemAllocs / r.N unless reported by ReportMetric.
 func (r BenchmarkResult) AllocsPerOp() int64 {
+	if v, ok := r.Extra["allocs/op"]; ok {
+		return int64(v)
+	}
 	if r.N <= 0 {
 		return 0
 	}
 	return int64(r.MemAllocs) / int64(r.N)
 }
 
-// AllocedBytesPerOp returns r.MemBytes / r.N.
+// AllocedBytesPerOp returns the "B/op" metric,
+// which is r.MemBytes / r.N unless reported by ReportMetric.
 func (r BenchmarkResult) AllocedBytesPerOp() int64 {
+	if v, ok := r.Extra["B/op"]; ok {
+		return int64(v)
+	}
 	if r.N <= 0 {
 		return 0
 	}
 	return int64(r.MemBytes) / int64(r.N)
 }
 
+// String returns a summary of the benchmark results in the format
+// of a 'go test' benchmark line, without the benchmark name: the
+// number of iterations, ns/op, MB/s if set, and any extra metrics
+// reported by ReportMetric, ordered by unit.
 func (r BenchmarkResult) String() string {
-	mbs := r.mbPerSec()
-	mb := ""
-	if mbs != 0 {
-		mb = fmt.Sprintf("\t%7.2f MB/s", mbs)
-	}
-	nsop := r.NsPerOp()
-	ns := fmt.Sprintf("%10d ns/op", nsop)
-	if r.N > 0 && nsop < 100 {
-		// The format specifiers here make sure that
-		// the ones digits line up for all three possible formats.
-		if nsop < 10 {
-			ns = fmt.Sprintf("%13.2f ns/op", float64(r.T.Nanoseconds())/float64(r.N))
-		} else {
-			ns = fmt.Sprintf("%12.1f ns/op", float64(r.T.Nanoseconds())/float64(r.N))
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%8d", r.N)
+
+	ns, ok := r.Extra["ns/op"]
+	if !ok && r.N > 0 {
+		ns = float64(r.T.Nanoseconds()) / float64(r.N)
+	}
+	if ns != 0 || !ok {
+		buf.WriteByte('\t')
+		writeNsPerOp(&buf, ns)
+	}
+	if mbs := r.mbPerSec(); mbs != 0 {
+		fmt.Fprintf(&buf, "\t%7.2f MB/s", mbs)
+	}
+
+	var units []string
+	for unit := range r.Extra {
+		switch unit {
+		case "ns/op", "MB/s", "B/op", "allocs/op":
+			// Printed as built-in metrics.
+			continue
 		}
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	for _, unit := range units {
+		buf.WriteByte('\t')
+		writeMetric(&buf, r.Extra[unit], unit)
+	}
+	return buf.String()
+}
+
+// writeNsPerOp writes ns in the format used for ns/op, which
+// lines up the ones digits of integral and fractional values.
+func writeNsPerOp(buf *strings.Builder, ns float64) {
+	switch {
+	case ns >= 100 || ns == 0:
+		fmt.Fprintf(buf, "%10d ns/op", int64(ns))
+	case ns >= 10:
+		fmt.Fprintf(buf, "%12.1f ns/op", ns)
+	default:
+		fmt.Fprintf(buf, "%13.2f ns/op", ns)
+	}
+}
+
+// writeMetric writes "x unit", printing x with more
+// digits after the decimal point the smaller it is.
+func writeMetric(buf *strings.Builder, x float64, unit string) {
+	var format string
+	switch y := math.Abs(x); {
+	case y == 0 || y >= 100 && y == math.Trunc(y):
+		format = "%10.0f %s"
+	case y >= 99.995:
+		format = "%12.1f %s"
+	case y >= 0.9995:
+		format = "%13.2f %s"
+	case y >= 0.0099995:
+		format = "%14.4f %s"
+	default:
+		format = "%15.6g %s"
 	}
-	return fmt.Sprintf("%8d\t%s%s", r.N, ns, mb)
+	fmt.Fprintf(buf, format, x, unit)
 }
 
 // MemString returns r.AllocedBytesPerOp and r.AllocsPerOp in the same format as 'go test'.
@@ -406,9 +525,9 @@ func runBenchmarks(importPath string, matchString func(pat, str string) (bool, e
 	}
 	main := &B{
 		common: common{
-			name:   "Main",
-			w:      os.Stdout,
-			chatty: *chatty,
+			name:  "Main",
+			w:     os.Stdout,
+			bench: true,
 		},
 		importPath: importPath,
 		benchFunc: func(b *B) {
@@ -419,6 +538,9 @@ func runBenchmarks(importPath string, matchString func(pat, str string) (bool, e
 		benchTime: *benchTime,
 		context:   ctx,
 	}
+	if Verbose() {
+		main.chatty = newChattyPrinter(main.w)
+	}
 	main.runN(1)
 	return !main.failed
 }
@@ -429,7 +551,12 @@ func (ctx *benchContext) processBench(b *B) {
 		for j := uint(0); j < *count; j++ {
 			runtime.GOMAXPROCS(procs)
 			benchName := benchmarkName(b.name, procs)
-			fmt.Fprintf(b.w, "%-*s\t", ctx.maxLen, benchName)
+			// For cmd/test2json, the result is printed as a
+			// whole line, once the benchmark has run.
+			json := b.chatty != nil && b.chatty.json
+			if !json {
+				fmt.Fprintf(b.w, "%-*s\t", ctx.maxLen, benchName)
+			}
 			// Recompute the running time for all but the first iteration.
 			if i > 0 || j > 0 {
 				b = &B{
@@ -438,6 +565,7 @@ func (ctx *benchContext) processBench(b *B) {
 						name:   b.name,
 						w:      b.w,
 						chatty: b.chatty,
+						bench:  true,
 					},
 					benchFunc: b.benchFunc,
 					benchTime: b.benchTime,
@@ -449,19 +577,23 @@ func (ctx *benchContext) processBench(b *B) {
 				// The output could be very long here, but probably isn't.
 				// We print it all, regardless, because we don't want to trim the reason
 				// the benchmark failed.
-				fmt.Fprintf(b.w, "--- FAIL: %s\n%s", benchName, b.output)
+				fmt.Fprintf(b.w, "%s--- FAIL: %s\n%s", b.chatty.prefix(), benchName, b.output)
 				continue
 			}
 			results := r.String()
 			if *benchmarkMemory || b.showAllocResult {
 				results += "\t" + r.MemString()
 			}
-			fmt.Fprintln(b.w, results)
+			if json {
+				fmt.Fprintf(b.w, "%s%-*s\t%s\n", b.chatty.prefix(), ctx.maxLen, benchName, results)
+			} else {
+				fmt.Fprintln(b.w, results)
+			}
 			// Unlike with tests, we ignore the -chatty flag and always print output for
 			// benchmarks since the output generation time will skew the results.
 			if len(b.output) > 0 {
 				b.trimOutput()
-				fmt.Fprintf(b.w, "--- BENCH: %s\n%s", benchName, b.output)
+				fmt.Fprintf(b.w, "%s--- BENCH: %s\n%s", b.chatty.prefix(), benchName, b.output)
 			}
 			if p := runtime.GOMAXPROCS(-1); p != procs {
 				fmt.Fprintf(os.Stderr, "testing: %s left GOMAXPROCS set to %d\n", benchName, p)
@@ -497,6 +629,7 @@ func (b *B) Run(name string, f func(b *B)) bool {
 			level:  b.level + 1,
 			w:      b.w,
 			chatty: b.chatty,
+			bench:  true,
 		},
 		importPath: b.importPath,
 		benchFunc:  f,
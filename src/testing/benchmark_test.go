@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 	"testing"
 	"text/template"
+	"time"
 )
 
 var roundDownTests = []struct {
@@ -63,6 +64,99 @@ func TestRoundUp(t *testing.T) {
 	}
 }
 
+var benchmarkResultStringTests = []struct {
+	r    testing.BenchmarkResult
+	want string
+}{
+	{testing.BenchmarkResult{N: 1, T: 1500 * time.Nanosecond}, "       1\t      1500 ns/op"},
+	{testing.BenchmarkResult{N: 10, T: 155 * time.Nanosecond}, "      10\t        15.5 ns/op"},
+	{testing.BenchmarkResult{N: 4, T: 6 * time.Nanosecond}, "       4\t         1.50 ns/op"},
+	{testing.BenchmarkResult{N: 1, T: time.Second, Bytes: 1e6}, "       1\t1000000000 ns/op\t   1.00 MB/s"},
+	{
+		testing.BenchmarkResult{N: 100, T: 100 * time.Microsecond, Extra: map[string]float64{"req/s": 12345, "hit-ratio": 0.75, "p99-ns": 103.5}},
+		"     100\t      1000 ns/op\t        0.7500 hit-ratio\t       103.5 p99-ns\t     12345 req/s",
+	},
+	{testing.BenchmarkResult{N: 1, T: time.Second, Extra: map[string]float64{"ns/op": 0, "x/op": 1e-6}}, "       1\t          1e-06 x/op"},
+	{testing.BenchmarkResult{N: 1, T: time.Second, Extra: map[string]float64{"ns/op": 42, "MB/s": 2}}, "       1\t        42.0 ns/op\t   2.00 MB/s"},
+}
+
+func TestBenchmarkResultString(t *testing.T) {
+	for _, tt := range benchmarkResultStringTests {
+		if got := tt.r.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestReportMetric(t *testing.T) {
+	res := testing.Benchmark(func(b *testing.B) {
+		b.ReportMetric(1, "discarded/op") // cleared by ResetTimer
+		b.ResetTimer()
+		b.ReportMetric(12345, "ns/op")
+		b.ReportMetric(0.5, "hits/op")
+		b.ReportMetric(0.75, "hits/op")
+	})
+	if _, ok := res.Extra["discarded/op"]; ok {
+		t.Errorf("metric reported before ResetTimer in Extra: %v", res.Extra)
+	}
+	if got := res.Extra["hits/op"]; got != 0.75 {
+		t.Errorf("hits/op = %v, want 0.75", got)
+	}
+	if got := res.NsPerOp(); got != 12345 {
+		t.Errorf("NsPerOp() = %d, want 12345", got)
+	}
+
+	for _, unit := range []string{"", "req per s", "ns/op\n"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ReportMetric(1, %q) did not panic", unit)
+				}
+			}()
+			new(testing.B).ReportMetric(1, unit)
+		}()
+	}
+}
+
+func TestReportMetricResultIsCopied(t *testing.T) {
+	var bench *testing.B
+	res := testing.Benchmark(func(b *testing.B) {
+		bench = b
+		b.ReportMetric(3, "widgets/op")
+	})
+	// Resetting b must not change the result it already produced.
+	bench.ResetTimer()
+	bench.ReportMetric(4, "gadgets/op")
+	if got, ok := res.Extra["widgets/op"]; !ok || got != 3 {
+		t.Errorf("widgets/op = %v, %v after ResetTimer; want 3, true", got, ok)
+	}
+	if _, ok := res.Extra["gadgets/op"]; ok {
+		t.Errorf("metric reported after the benchmark ran in Extra: %v", res.Extra)
+	}
+}
+
+func TestElapsed(t *testing.T) {
+	testing.Benchmark(func(b *testing.B) {
+		if b.N != 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+		if d := b.Elapsed(); d < 10*time.Millisecond {
+			t.Errorf("Elapsed() = %v with timer running, want at least 10ms", d)
+		}
+		b.StopTimer()
+		d := b.Elapsed()
+		time.Sleep(10 * time.Millisecond)
+		if d2 := b.Elapsed(); d2 != d {
+			t.Errorf("Elapsed() = %v with timer stopped, want %v", d2, d)
+		}
+		b.ResetTimer()
+		if d := b.Elapsed(); d != 0 {
+			t.Errorf("Elapsed() = %v after ResetTimer, want 0", d)
+		}
+	})
+}
+
 func TestRunParallel(t *testing.T) {
 	testing.Benchmark(func(b *testing.B) {
 		procs := uint32(0)
@@ -0,0 +1,81 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fstest
+
+import (
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestMapFS(t *testing.T) {
+	m := MapFS{
+		"hello":             {Data: []byte("hello, world\n")},
+		"fortune/k/ken.txt": {Data: []byte("If a program is too slow, it must have a loop.\n")},
+		"empty":             {Mode: fs.ModeDir},
+	}
+
+	data, err := fs.ReadFile(m, "fortune/k/ken.txt")
+	if err != nil || string(data) != "If a program is too slow, it must have a loop.\n" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+
+	var names []string
+	dirs, err := fs.ReadDir(m, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dirs {
+		names = append(names, d.Name())
+	}
+	if want := []string{"empty", "fortune", "hello"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir(.) = %v, want %v", names, want)
+	}
+
+	for _, name := range []string{".", "empty", "fortune", "fortune/k"} {
+		info, err := fs.Stat(m, name)
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(%q) = %v, %v, want directory", name, info, err)
+		}
+	}
+	for _, name := range []string{"missing", "fortune/x", "/hello", "hello/"} {
+		if _, err := m.Open(name); err == nil {
+			t.Errorf("Open(%q) succeeded", name)
+		}
+	}
+
+	sub, err := fs.Sub(m, "fortune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := fs.Glob(sub, "*/*.txt")
+	if err != nil || !reflect.DeepEqual(matches, []string{"k/ken.txt"}) {
+		t.Errorf("Glob(sub, */*.txt) = %v, %v", matches, err)
+	}
+}
+
+func TestMapFSReadDirN(t *testing.T) {
+	m := MapFS{"a": {}, "b": {}, "c": {}}
+	f, err := m.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := f.(fs.ReadDirFile)
+	var n int
+	for {
+		list, err := d.ReadDir(2)
+		n += len(list)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n != 3 {
+		t.Errorf("read %d entries, want 3", n)
+	}
+}
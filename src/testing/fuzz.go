@@ -0,0 +1,692 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"internal/race"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	matchFuzz     = flag.String("test.fuzz", "", "run the fuzz target matching `regexp`")
+	fuzzCacheDir  = flag.String("test.fuzzcachedir", "", "directory where interesting fuzzing inputs are stored (for use only by cmd/go)")
+	fuzzWorkerDir = flag.String("test.fuzzworker", "", "run fuzzing inputs for the coordinator sharing `dir` (for use only by the fuzzing coordinator)")
+	fuzzDuration  durationOrCountFlag
+)
+
+func init() {
+	flag.Var(&fuzzDuration, "test.fuzztime", "time to spend fuzzing; default is to run indefinitely")
+}
+
+// corpusDir is the parent directory of the fuzz targets' seed corpora.
+const corpusDir = "testdata/fuzz"
+
+// durationOrCountFlag is a flag value holding either a duration,
+// such as 30s, or a count of iterations, written as 1000x.
+type durationOrCountFlag struct {
+	d time.Duration
+	n int
+}
+
+func (f *durationOrCountFlag) String() string {
+	if f.n > 0 {
+		return fmt.Sprintf("%dx", f.n)
+	}
+	return f.d.String()
+}
+
+func (f *durationOrCountFlag) Set(s string) error {
+	if strings.HasSuffix(s, "x") {
+		n, err := strconv.ParseInt(s[:len(s)-1], 10, 0)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid count")
+		}
+		*f = durationOrCountFlag{n: int(n)}
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid duration")
+	}
+	*f = durationOrCountFlag{d: d}
+	return nil
+}
+
+// InternalFuzzTarget is an internal type but exported because it is cross-package;
+// it is part of the implementation of the "go test" command.
+type InternalFuzzTarget struct {
+	Name string
+	Fn   func(f *F)
+}
+
+// F is a type passed to fuzz targets.
+//
+// A fuzz target is a function of the form
+//
+//	func FuzzXxx(*testing.F)
+//
+// It adds seed inputs with F.Add and then calls F.Fuzz with the function
+// to fuzz, whose first parameter is a *T and whose remaining parameters
+// are the inputs, of the types listed in the documentation for F.Add.
+//
+// By default, 'go test' runs the fuzz function once for each seed input:
+// those added with F.Add and those stored as files in testdata/fuzz/FuzzXxx.
+// With the -fuzz flag, 'go test' instead generates new inputs by
+// mutating the seed corpus, guided by the code coverage each input reaches.
+// A failing input is written to testdata/fuzz/FuzzXxx, where later runs
+// of 'go test' replay it as a regular test.
+//
+// The methods of F other than Fuzz must be called before F.Fuzz.
+// Inside the fuzz function, only the methods of the *T argument may be used.
+type F struct {
+	common
+	fuzzContext *fuzzContext
+	testContext *testContext
+
+	// inFuzzFn is true when the fuzz function is running. Most F methods
+	// can't be called when inFuzzFn is true.
+	inFuzzFn bool
+
+	// corpus is a set of seed corpus entries, added with F.Add and loaded
+	// from testdata.
+	corpus []corpusEntry
+
+	fuzzCalled bool
+}
+
+var _ TB = (*F)(nil)
+
+// corpusEntry is an alias to the same type as internal/fuzz.CorpusEntry.
+// We use a type alias because we don't want to export this type, and we can't
+// import internal/fuzz from testing.
+type corpusEntry = struct {
+	Parent     string
+	Path       string
+	Data       []byte
+	Values     []interface{}
+	Generation int
+	IsSeed     bool
+}
+
+// fuzzCrashError is implemented by the error returned by
+// testDeps.CoordinateFuzzing when an input fails or crashes
+// the worker process.
+type fuzzCrashError interface {
+	error
+	CrashPath() string
+}
+
+// fuzzMode says how a fuzz target is being run.
+type fuzzMode uint8
+
+const (
+	// seedCorpusOnly runs the fuzz function once for each seed corpus entry.
+	seedCorpusOnly fuzzMode = iota
+
+	// fuzzCoordinator starts a worker process to fuzz the target and
+	// reports its results.
+	fuzzCoordinator
+
+	// fuzzWorker generates and runs new inputs in addition to the
+	// seed corpus, on behalf of the coordinator.
+	fuzzWorker
+)
+
+// fuzzContext holds fields common to all fuzz targets.
+type fuzzContext struct {
+	deps testDeps
+	mode fuzzMode
+}
+
+// supportedTypes lists the types that may be used for fuzz function
+// arguments and with F.Add.
+var supportedTypes = map[reflect.Type]bool{
+	reflect.TypeOf(([]byte)("")):  true,
+	reflect.TypeOf((string)("")):  true,
+	reflect.TypeOf((bool)(false)): true,
+	reflect.TypeOf((byte)(0)):     true,
+	reflect.TypeOf((rune)(0)):     true,
+	reflect.TypeOf((float32)(0)):  true,
+	reflect.TypeOf((float64)(0)):  true,
+	reflect.TypeOf((int)(0)):      true,
+	reflect.TypeOf((int8)(0)):     true,
+	reflect.TypeOf((int16)(0)):    true,
+	reflect.TypeOf((int32)(0)):    true,
+	reflect.TypeOf((int64)(0)):    true,
+	reflect.TypeOf((uint)(0)):     true,
+	reflect.TypeOf((uint8)(0)):    true,
+	reflect.TypeOf((uint16)(0)):   true,
+	reflect.TypeOf((uint32)(0)):   true,
+	reflect.TypeOf((uint64)(0)):   true,
+}
+
+// Helper marks the calling function as a test helper function.
+// When printing file and line information, that function will be skipped.
+// Helper may be called simultaneously from multiple goroutines.
+func (f *F) Helper() {
+	if f.inFuzzFn {
+		panic("testing: f.Helper was called inside the fuzz function, use t.Helper instead")
+	}
+
+	// common.Helper is inlined here.
+	// If we called it, it would mark F.Helper as the helper
+	// instead of the caller.
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.helpers == nil {
+		f.helpers = make(map[string]struct{})
+	}
+	f.helpers[callerName(1)] = struct{}{}
+}
+
+// Fail marks the function as having failed but continues execution.
+func (f *F) Fail() {
+	// (*F).Fail may be called by (*T).Fail, which we should allow. However, we
+	// shouldn't allow direct (*F).Fail calls from inside the fuzz function.
+	if f.inFuzzFn {
+		panic("testing: f.Fail was called inside the fuzz function, use t.Fail instead")
+	}
+	f.common.Helper()
+	f.common.Fail()
+}
+
+// Skipped reports whether the test was skipped.
+func (f *F) Skipped() bool {
+	if f.inFuzzFn {
+		panic("testing: f.Skipped was called inside the fuzz function, use t.Skipped instead")
+	}
+	f.common.Helper()
+	return f.common.Skipped()
+}
+
+// Add will add the arguments to the seed corpus for the fuzz target. This will
+// be a no-op if called after or within the Fuzz function. The args must match
+// those in the Fuzz function.
+//
+// The supported argument types are []byte, string, bool, byte, rune,
+// float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16,
+// uint32, and uint64.
+func (f *F) Add(args ...interface{}) {
+	if f.inFuzzFn || f.fuzzCalled {
+		return
+	}
+	var values []interface{}
+	for i := range args {
+		if t := reflect.TypeOf(args[i]); !supportedTypes[t] {
+			panic(fmt.Sprintf("testing: unsupported type to Add %v", t))
+		}
+		values = append(values, args[i])
+	}
+	f.corpus = append(f.corpus, corpusEntry{Values: values, IsSeed: true, Path: fmt.Sprintf("seed#%d", len(f.corpus))})
+}
+
+// Fuzz runs the fuzz function, ff, for fuzz testing. If ff fails for a set of
+// arguments, those arguments will be added to the seed corpus.
+//
+// ff must be a function with no return value whose first argument is *T and
+// whose remaining arguments are the types to be fuzzed.
+// For example:
+//
+//	f.Fuzz(func(t *testing.T, b []byte, i int) { ... })
+//
+// The following types are allowed: []byte, string, bool, byte, rune, float32,
+// float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64.
+// More types may be supported in the future.
+//
+// ff must not call any *F methods, e.g. (*F).Log, (*F).Error, (*F).Skip. Use
+// the corresponding *T method instead. ff must not call t.Parallel.
+//
+// When fuzzing, F.Fuzz does not return until a problem is found, time runs out
+// (set with -fuzztime), or the test process is interrupted by a signal.
+// F.Fuzz should be called exactly once.
+func (f *F) Fuzz(ff interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Fuzz called more than once")
+	}
+	f.fuzzCalled = true
+	if f.failed {
+		return
+	}
+	f.Helper()
+
+	// ff should be in the form func(*testing.T, ...interface{})
+	fn := reflect.ValueOf(ff)
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func {
+		panic("testing: F.Fuzz must receive a function")
+	}
+	if fnType.NumIn() < 2 || fnType.In(0) != reflect.TypeOf((*T)(nil)) {
+		panic("testing: fuzz target must receive at least two arguments, where the first argument is a *T")
+	}
+	if fnType.NumOut() != 0 {
+		panic("testing: fuzz target must not return a value")
+	}
+
+	// Save the types of the function to compare against the corpus.
+	var types []reflect.Type
+	for i := 1; i < fnType.NumIn(); i++ {
+		t := fnType.In(i)
+		if !supportedTypes[t] {
+			panic(fmt.Sprintf("testing: unsupported type for fuzzing %v", t))
+		}
+		types = append(types, t)
+	}
+
+	// Check the types of the entries added with F.Add, then load the
+	// seed corpus from testdata.
+	deps := f.fuzzContext.deps
+	for _, e := range f.corpus {
+		if err := deps.CheckCorpus(e.Values, types); err != nil {
+			f.Fatal(err)
+		}
+	}
+	c, err := deps.ReadCorpus(filepath.Join(corpusDir, f.name), types)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for i := range c {
+		c[i].IsSeed = true
+	}
+	f.corpus = append(f.corpus, c...)
+
+	switch f.fuzzContext.mode {
+	case fuzzCoordinator:
+		err := deps.CoordinateFuzzing(filepath.Join(corpusDir, f.name), os.Stdout)
+		if err != nil {
+			f.Fail()
+			fmt.Fprintf(f.w, "%v\n", err)
+			if crashErr, ok := err.(fuzzCrashError); ok {
+				crashPath := crashErr.CrashPath()
+				fmt.Fprintf(f.w, "Failing input written to %s\n", crashPath)
+				testName := filepath.Base(crashPath)
+				fmt.Fprintf(f.w, "To re-run:\ngo test -run=%s/%s\n", f.name, testName)
+			}
+		}
+
+	case fuzzWorker:
+		// Run the seed corpus and then new inputs, stopping at the first
+		// failure. Each input runs as a T whose parent is detached from f,
+		// so that its failure can be reported along with the input.
+		counters := coverCounters()
+		snapshot := make([]byte, coverLen(counters))
+		run := func(e corpusEntry) error {
+			var buf bytes.Buffer
+			parent := &common{w: &buf, name: f.name, level: f.level}
+			resetCoverage(counters)
+			if f.runInput(parent, f.name, fn, e) {
+				return nil
+			}
+			return errors.New(strings.TrimSpace(buf.String()))
+		}
+		coverage := func() []byte {
+			if len(snapshot) == 0 {
+				return nil
+			}
+			snapshotCoverage(counters, snapshot)
+			return snapshot
+		}
+		var cacheDir string
+		if *fuzzCacheDir != "" {
+			cacheDir = filepath.Join(*fuzzCacheDir, f.name)
+		}
+		// The worker records its result for the coordinator,
+		// which reports it.
+		err := deps.RunFuzzWorker(
+			*fuzzWorkerDir,
+			fuzzDuration.d,
+			int64(fuzzDuration.n),
+			f.corpus,
+			types,
+			filepath.Join(corpusDir, f.name),
+			cacheDir,
+			os.Stdout,
+			run,
+			coverage)
+		if err != nil {
+			f.Fail()
+		}
+
+	default:
+		// Fuzzing is not enabled. Run each seed corpus entry as a subtest
+		// named after its file, so that -run can select a single entry.
+		for _, e := range f.corpus {
+			name, ok, _ := f.testContext.match.fullName(&f.common, filepath.Base(e.Path))
+			if !ok || shouldFailFast() {
+				continue
+			}
+			f.runInput(&f.common, name, fn, e)
+		}
+	}
+}
+
+// runInput calls fn with the values in e, in its own goroutine,
+// as a test named name whose parent is parent, and reports whether
+// the test passed. When fuzzing, a panic in fn is reported as a
+// failure of the input rather than stopping the test binary.
+func (f *F) runInput(parent *common, name string, fn reflect.Value, e corpusEntry) bool {
+	atomic.StoreInt32(&parent.hasSub, 1)
+	t := &T{
+		common: common{
+			barrier: make(chan bool),
+			signal:  make(chan bool),
+			name:    name,
+			parent:  parent,
+			level:   parent.level + 1,
+			chatty:  parent.chatty,
+		},
+		context: f.testContext,
+	}
+	t.w = indenter{&t.common}
+	if t.chatty != nil {
+		t.chatty.Updatef(t.name, "=== RUN   %s\n", t.name)
+	}
+	args := []reflect.Value{reflect.ValueOf(t)}
+	for _, v := range e.Values {
+		args = append(args, reflect.ValueOf(v))
+	}
+	go tRunner(t, func(t *T) {
+		if f.fuzzContext.mode == fuzzWorker {
+			defer func() {
+				if err := recover(); err != nil {
+					t.Fail()
+					fmt.Fprintf(t.w, "panic: %v\n%s", err, debug.Stack())
+				}
+			}()
+		}
+		f.inFuzzFn = true
+		defer func() { f.inFuzzFn = false }()
+		fn.Call(args)
+	})
+	<-t.signal
+	return !t.Failed()
+}
+
+func (f *F) report() {
+	if f.parent == nil {
+		return
+	}
+	dstr := fmtDuration(f.duration)
+	format := "--- %s: %s (%s)\n"
+	if f.Failed() {
+		f.flushToParent(f.name, format, "FAIL", f.name, dstr)
+	} else if f.chatty != nil {
+		if f.Skipped() {
+			f.flushToParent(f.name, format, "SKIP", f.name, dstr)
+		} else {
+			f.flushToParent(f.name, format, "PASS", f.name, dstr)
+		}
+	}
+}
+
+// fRunner wraps a call to a fuzz target and ensures that cleanup functions are
+// called and status flags are set. fRunner should be called in its own
+// goroutine. To wait for its completion, receive from f.signal.
+//
+// fRunner is analogous to tRunner, which wraps subtests started with T.Run.
+// Tests and fuzz targets work a little differently, so for now, these functions
+// aren't consolidated.
+func fRunner(f *F, fn func(*F)) {
+	f.runner = callerName(0)
+
+	// When this goroutine is done, either because fn(f) returned normally
+	// or because a test failure triggered a call to runtime.Goexit, record
+	// the duration and send a signal saying that the target is done.
+	defer func() {
+		if f.raceErrors+race.Errors() > 0 {
+			f.Errorf("race detected during execution of test")
+		}
+
+		f.duration += time.Since(f.start)
+		// If the target panicked, print any output before dying.
+		err := recover()
+		if !f.finished && err == nil {
+			err = fmt.Errorf("test executed panic(nil) or runtime.Goexit")
+		}
+		if err != nil {
+			f.Fail()
+			f.report()
+			panic(err)
+		}
+
+		if len(f.sub) > 0 {
+			// Run the parallel subtests started by seed corpus entries.
+			f.testContext.release()
+			close(f.barrier)
+			for _, sub := range f.sub {
+				<-sub.signal
+			}
+			f.runCleanup(normalPanic)
+			f.testContext.waitParallel()
+		}
+		f.report()
+		f.done = true
+		f.setRan()
+		f.signal <- true
+	}()
+	defer func() {
+		if len(f.sub) == 0 {
+			f.runCleanup(normalPanic)
+		}
+	}()
+
+	f.start = time.Now()
+	f.raceErrors = -race.Errors()
+	fn(f)
+
+	// Code beyond this point is only executed if fn returned normally.
+	// That means fn did not call FailNow or SkipNow, and it did not panic.
+	if f.failed {
+		atomic.AddUint32(&numFailed, 1)
+	}
+	f.finished = true
+}
+
+// runFuzzTarget runs the fuzz target ft as a child of parent
+// and reports whether it passed.
+func runFuzzTarget(parent *T, fctx *fuzzContext, ft InternalFuzzTarget) bool {
+	atomic.StoreInt32(&parent.hasSub, 1)
+	name, ok, _ := parent.context.match.fullName(&parent.common, ft.Name)
+	if !ok || shouldFailFast() {
+		return true
+	}
+	f := &F{
+		common: common{
+			barrier: make(chan bool),
+			signal:  make(chan bool),
+			name:    name,
+			parent:  &parent.common,
+			level:   parent.level + 1,
+			chatty:  parent.chatty,
+		},
+		fuzzContext: fctx,
+		testContext: parent.context,
+	}
+	f.w = indenter{&f.common}
+	if f.chatty != nil {
+		f.chatty.Updatef(f.name, "=== RUN   %s\n", f.name)
+	}
+	go fRunner(f, ft.Fn)
+	<-f.signal
+	return !f.Failed()
+}
+
+// runFuzzTests runs the fuzz targets matching the pattern for -run. This will
+// only run the fuzz function for each seed corpus entry, without generating
+// new inputs.
+func runFuzzTests(deps testDeps, fuzzTargets []InternalFuzzTarget) (ran, ok bool) {
+	ok = true
+	if len(fuzzTargets) == 0 {
+		return ran, ok
+	}
+	for _, procs := range cpuList {
+		runtime.GOMAXPROCS(procs)
+		for i := uint(0); i < *count; i++ {
+			if shouldFailFast() {
+				break
+			}
+			fctx := &fuzzContext{deps: deps, mode: seedCorpusOnly}
+			ctx := newTestContext(*parallel, newMatcher(deps.MatchString, *match, "-test.run"))
+			t := &T{
+				common: common{
+					signal:  make(chan bool),
+					barrier: make(chan bool),
+					w:       os.Stdout,
+				},
+				context: ctx,
+			}
+			if Verbose() {
+				t.chatty = newChattyPrinter(t.w)
+			}
+			tRunner(t, func(t *T) {
+				for _, ft := range fuzzTargets {
+					runFuzzTarget(t, fctx, ft)
+				}
+				// Run catching the signal rather than the tRunner as a separate
+				// goroutine to avoid adding a goroutine during the sequential
+				// phase as this pollutes the stacktrace output when aborting.
+				go func() { <-t.signal }()
+			})
+			ok = ok && !t.Failed()
+			ran = ran || t.ran
+		}
+	}
+	return ran, ok
+}
+
+// runFuzzing runs the fuzz target matching the pattern for -fuzz, generating
+// and testing new inputs until it finds a failure or runs out of time.
+// Only one fuzz target may match. runFuzzing reports whether no failure
+// was found.
+func runFuzzing(deps testDeps, fuzzTargets []InternalFuzzTarget) (ok bool) {
+	if *matchFuzz == "" {
+		return true
+	}
+	if _, err := deps.MatchString(*matchFuzz, "non-empty"); err != nil {
+		fmt.Fprintf(os.Stderr, "testing: invalid regexp for -test.fuzz: %s\n", err)
+		os.Exit(1)
+	}
+	var targets []InternalFuzzTarget
+	for _, ft := range fuzzTargets {
+		if ok, _ := deps.MatchString(*matchFuzz, ft.Name); ok {
+			targets = append(targets, ft)
+		}
+	}
+	switch len(targets) {
+	case 0:
+		fmt.Fprintln(os.Stderr, "testing: warning: no fuzz targets to fuzz")
+		return true
+	case 1:
+	default:
+		var names []string
+		for _, ft := range targets {
+			names = append(names, ft.Name)
+		}
+		fmt.Fprintf(os.Stderr, "testing: will not fuzz, -fuzz matches more than one fuzz target: %v\n", names)
+		return false
+	}
+
+	fctx := &fuzzContext{deps: deps, mode: fuzzCoordinator}
+	ctx := newTestContext(1, newMatcher(deps.MatchString, "", ""))
+	t := &T{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			w:       os.Stdout,
+		},
+		context: ctx,
+	}
+	if *fuzzWorkerDir != "" {
+		// The coordinator reports the results of the worker.
+		fctx.mode = fuzzWorker
+		t.w = discard{}
+	} else if Verbose() {
+		t.chatty = newChattyPrinter(t.w)
+	}
+	tRunner(t, func(t *T) {
+		runFuzzTarget(t, fctx, targets[0])
+		go func() { <-t.signal }()
+	})
+	return !t.Failed()
+}
+
+// coverCounters returns the coverage counters registered with
+// RegisterCover, in a fixed order.
+func coverCounters() [][]uint32 {
+	var names []string
+	for name := range cover.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	counters := make([][]uint32, len(names))
+	for i, name := range names {
+		counters[i] = cover.Counters[name]
+	}
+	return counters
+}
+
+// coverLen returns the total number of counters.
+func coverLen(counters [][]uint32) int {
+	n := 0
+	for _, c := range counters {
+		n += len(c)
+	}
+	return n
+}
+
+// resetCoverage sets all counters to zero.
+func resetCoverage(counters [][]uint32) {
+	for _, c := range counters {
+		for i := range c {
+			atomic.StoreUint32(&c[i], 0)
+		}
+	}
+}
+
+// snapshotCoverage records the current value of each counter in snapshot,
+// one byte per counter. A count sets one bit according to its magnitude,
+// so that an input hitting a block many more times than any earlier input
+// also counts as new coverage.
+func snapshotCoverage(counters [][]uint32, snapshot []byte) {
+	i := 0
+	for _, c := range counters {
+		for j := range c {
+			snapshot[i] = countBucket(atomic.LoadUint32(&c[j]))
+			i++
+		}
+	}
+}
+
+// countBucket returns a bit identifying the range containing n:
+// 0, 1, 2, 3, 4-7, 8-15, 16-31, 32-127, or 128 and above.
+func countBucket(n uint32) byte {
+	switch {
+	case n == 0:
+		return 0
+	case n <= 3:
+		return 1 << (n - 1)
+	case n <= 7:
+		return 1 << 3
+	case n <= 15:
+		return 1 << 4
+	case n <= 31:
+		return 1 << 5
+	case n <= 127:
+		return 1 << 6
+	}
+	return 1 << 7
+}
@@ -12,12 +12,15 @@ package testdeps
 
 import (
 	"bufio"
+	"internal/fuzz"
 	"internal/testlog"
 	"io"
+	"reflect"
 	"regexp"
 	"runtime/pprof"
 	"strings"
 	"sync"
+	"time"
 )
 
 // TestDeps is an implementation of the testing.testDeps interface,
@@ -126,3 +129,54 @@ func (TestDeps) StopTestLog() error {
 	log.w = nil
 	return err
 }
+
+// corpusEntry is an alias to the same type as internal/fuzz.CorpusEntry.
+// We use a type alias because we don't want to export this type, and we can't
+// import internal/fuzz from testing.
+type corpusEntry = struct {
+	Parent     string
+	Path       string
+	Data       []byte
+	Values     []interface{}
+	Generation int
+	IsSeed     bool
+}
+
+func (TestDeps) CoordinateFuzzing(corpusDir string, log io.Writer) error {
+	return fuzz.CoordinateFuzzing(fuzz.CoordinateFuzzingOpts{
+		Log:       log,
+		CorpusDir: corpusDir,
+	})
+}
+
+func (TestDeps) RunFuzzWorker(
+	dir string,
+	timeout time.Duration,
+	limit int64,
+	seed []corpusEntry,
+	types []reflect.Type,
+	corpusDir,
+	cacheDir string,
+	log io.Writer,
+	run func(corpusEntry) error,
+	coverage func() []byte) error {
+	return fuzz.RunFuzzWorker(dir, fuzz.WorkerOpts{
+		Log:       log,
+		Timeout:   timeout,
+		Limit:     limit,
+		Seed:      seed,
+		Types:     types,
+		CorpusDir: corpusDir,
+		CacheDir:  cacheDir,
+		Run:       run,
+		Coverage:  coverage,
+	})
+}
+
+func (TestDeps) ReadCorpus(dir string, types []reflect.Type) ([]corpusEntry, error) {
+	return fuzz.ReadCorpus(dir, types)
+}
+
+func (TestDeps) CheckCorpus(vals []interface{}, types []reflect.Type) error {
+	return fuzz.CheckCorpus(vals, types)
+}
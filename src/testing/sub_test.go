@@ -7,6 +7,7 @@ package testing
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
@@ -345,10 +346,12 @@ func TestTRun(t *T) {
 				signal: make(chan bool),
 				name:   "Test",
 				w:      buf,
-				chatty: tc.chatty,
 			},
 			context: ctx,
 		}
+		if tc.chatty {
+			root.chatty = newChattyPrinter(root.w)
+		}
 		ok := root.Run(tc.desc, tc.f)
 		ctx.release()
 
@@ -492,11 +495,14 @@ func TestBRun(t *T) {
 				signal: make(chan bool),
 				name:   "root",
 				w:      buf,
-				chatty: tc.chatty,
+				bench:  true,
 			},
 			benchFunc: func(b *B) { ok = b.Run("test", tc.f) }, // Use Run to catch failure.
 			benchTime: time.Microsecond,
 		}
+		if tc.chatty {
+			root.chatty = newChattyPrinter(root.w)
+		}
 		root.runN(1)
 		if ok != !tc.failed {
 			t.Errorf("%s:ok: got %v; want %v", tc.desc, ok, !tc.failed)
@@ -517,6 +523,43 @@ func TestBRun(t *T) {
 	}
 }
 
+func TestTRunChattyJSON(t *T) {
+	buf := &bytes.Buffer{}
+	root := &T{
+		common: common{
+			signal: make(chan bool),
+			name:   "Test",
+			w:      buf,
+		},
+		context: newTestContext(1, newMatcher(regexp.MatchString, "", "")),
+	}
+	root.chatty = &chattyPrinter{w: buf, json: true}
+	root.Run("attr", func(t *T) {
+		t.Attr("key", "some value")
+		t.Run("sub", func(t *T) {
+			t.Log("in sub")
+		})
+		t.Log("in parent")
+		t.Attr("bad key", "")
+	})
+	got := strings.TrimSpace(buf.String())
+	want := `
+^V=== RUN   attr
+^V=== ATTR  attr key some value
+^V=== RUN   attr/sub
+	sub_test.go:NNN: in sub
+^V=== NAME  attr
+	sub_test.go:NNN: in parent
+	sub_test.go:NNN: invalid attribute key "bad key"
+^V--- FAIL: attr (N.NNs)
+^V    --- PASS: attr/sub (N.NNs)
+^V=== NAME  Test`
+	want = strings.Replace(strings.TrimSpace(want), "^V", "\x16", -1)
+	if ok, err := regexp.MatchString("^"+makeRegexp(want)+"$", got); !ok || err != nil {
+		t.Errorf("output:\ngot:\n%q\nwant:\n%q", got, want)
+	}
+}
+
 func makeRegexp(s string) string {
 	s = strings.Replace(s, ":NNN:", `:\d\d\d:`, -1)
 	s = strings.Replace(s, "(N.NNs)", `\(\d*\.\d*s\)`, -1)
@@ -566,9 +609,13 @@ func TestParallelSub(t *T) {
 	}
 }
 
-type funcWriter func([]byte) (int, error)
+// funcWriter is a pointer to a struct, so that it can be compared
+// with the writer of a chattyPrinter.
+type funcWriter struct {
+	write func([]byte) (int, error)
+}
 
-func (fw funcWriter) Write(b []byte) (int, error) { return fw(b) }
+func (fw *funcWriter) Write(b []byte) (int, error) { return fw.write(b) }
 
 func TestRacyOutput(t *T) {
 	var runs int32  // The number of running Writes
@@ -586,9 +633,10 @@ func TestRacyOutput(t *T) {
 
 	var wg sync.WaitGroup
 	root := &T{
-		common:  common{w: funcWriter(raceDetector), chatty: true},
+		common:  common{w: &funcWriter{raceDetector}},
 		context: newTestContext(1, newMatcher(regexp.MatchString, "", "")),
 	}
+	root.chatty = newChattyPrinter(root.w)
 	root.Run("", func(t *T) {
 		for i := 0; i < 100; i++ {
 			wg.Add(1)
@@ -621,3 +669,122 @@ func TestBenchmark(t *T) {
 		t.Errorf("want >5ms; got %v", time.Duration(res.NsPerOp()))
 	}
 }
+
+func TestCleanup(t *T) {
+	var cleanups []int
+	t.Run("test", func(t *T) {
+		t.Cleanup(func() { cleanups = append(cleanups, 1) })
+		t.Cleanup(func() { cleanups = append(cleanups, 2) })
+	})
+	if got, want := cleanups, []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected cleanup record; got %v want %v", got, want)
+	}
+}
+
+func TestConcurrentCleanup(t *T) {
+	cleanups := 0
+	t.Run("test", func(t *T) {
+		done := make(chan struct{})
+		for i := 0; i < 2; i++ {
+			i := i
+			go func() {
+				t.Cleanup(func() {
+					cleanups |= 1 << uint(i)
+				})
+				done <- struct{}{}
+			}()
+		}
+		<-done
+		<-done
+	})
+	if cleanups != 1|2 {
+		t.Errorf("unexpected cleanup; got %d want 3", cleanups)
+	}
+}
+
+func TestCleanupCalledEvenAfterGoexit(t *T) {
+	cleanups := 0
+	t.Run("test", func(t *T) {
+		t.Cleanup(func() {
+			cleanups++
+		})
+		t.Cleanup(func() {
+			runtime.Goexit()
+		})
+	})
+	if cleanups != 1 {
+		t.Errorf("unexpected cleanup count; got %d want 1", cleanups)
+	}
+}
+
+func TestRunCleanup(t *T) {
+	outerCleanup := 0
+	innerCleanup := 0
+	t.Run("test", func(t *T) {
+		t.Cleanup(func() { outerCleanup++ })
+		t.Run("x", func(t *T) {
+			t.Cleanup(func() { innerCleanup++ })
+		})
+	})
+	if innerCleanup != 1 {
+		t.Errorf("unexpected inner cleanup count; got %d want 1", innerCleanup)
+	}
+	if outerCleanup != 1 {
+		t.Errorf("unexpected outer cleanup count; got %d want 1", outerCleanup)
+	}
+}
+
+func TestCleanupParallelSubtests(t *T) {
+	ranCleanup := 0
+	t.Run("test", func(t *T) {
+		t.Cleanup(func() { ranCleanup++ })
+		t.Run("x", func(t *T) {
+			t.Parallel()
+			if ranCleanup > 0 {
+				t.Error("outer cleanup ran before parallel subtest")
+			}
+		})
+	})
+	if ranCleanup != 1 {
+		t.Errorf("unexpected cleanup count; got %d want 1", ranCleanup)
+	}
+}
+
+func TestNestedCleanup(t *T) {
+	ranCleanup := 0
+	t.Run("test", func(t *T) {
+		t.Cleanup(func() {
+			if ranCleanup != 2 {
+				t.Errorf("unexpected cleanup count in first cleanup: got %d want 2", ranCleanup)
+			}
+			ranCleanup++
+		})
+		t.Cleanup(func() {
+			if ranCleanup != 0 {
+				t.Errorf("unexpected cleanup count in second cleanup: got %d want 0", ranCleanup)
+			}
+			ranCleanup++
+			t.Cleanup(func() {
+				if ranCleanup != 1 {
+					t.Errorf("unexpected cleanup count in nested cleanup: got %d want 1", ranCleanup)
+				}
+				ranCleanup++
+			})
+		})
+	})
+	if ranCleanup != 3 {
+		t.Errorf("unexpected cleanup count: got %d want 3", ranCleanup)
+	}
+}
+
+func TestBenchmarkCleanup(t *T) {
+	var runs, cleanups int32
+	Benchmark(func(b *B) {
+		atomic.AddInt32(&runs, 1)
+		b.Cleanup(func() { atomic.AddInt32(&cleanups, 1) })
+	})
+	// Cleanup runs after each run of the benchmark function.
+	if runs == 0 || cleanups != runs {
+		t.Errorf("got %d cleanups for %d runs", cleanups, runs)
+	}
+}
@@ -132,6 +132,46 @@
 // example function, at least one other function, type, variable, or constant
 // declaration, and no test or benchmark functions.
 //
+// Fuzzing
+//
+// 'go test' and the testing package support fuzzing, a testing technique where
+// a function is called with randomly generated inputs to find bugs not
+// anticipated by unit tests.
+//
+// Functions of the form
+//     func FuzzXxx(*testing.F)
+// are considered fuzz targets. A fuzz target adds seed inputs with F.Add and
+// passes the function to fuzz to F.Fuzz:
+//
+//     func FuzzHex(f *testing.F) {
+//         for _, seed := range [][]byte{{}, {0}, {9}, {0xa}, {0xf}, {1, 2, 3, 4}} {
+//             f.Add(seed)
+//         }
+//         f.Fuzz(func(t *testing.T, in []byte) {
+//             enc := hex.EncodeToString(in)
+//             out, err := hex.DecodeString(enc)
+//             if err != nil {
+//                 t.Fatalf("%v: decode: %v", in, err)
+//             }
+//             if !bytes.Equal(in, out) {
+//                 t.Fatalf("%v: not equal after round trip: %v", in, out)
+//             }
+//         })
+//     }
+//
+// Without the -fuzz flag, 'go test' calls the fuzz function once for each
+// seed input: those added with F.Add and those stored in files in the
+// directory testdata/fuzz/FuzzXxx. With -fuzz, 'go test' instead builds the
+// package with coverage instrumentation and repeatedly mutates the seed
+// inputs, keeping the mutations that reach new code. The inputs run in a
+// separate worker process. When an input makes the fuzz function fail, or
+// crashes the worker, it is written to testdata/fuzz/FuzzXxx, so that
+// later runs of 'go test' replay it as a regular test:
+//
+//     go test -fuzz=FuzzHex -fuzztime=30s
+//
+// See the documentation of type F for details.
+//
 // Subtests and Sub-benchmarks
 //
 // The Run methods of T and B allow defining subtests and sub-benchmarks,
@@ -223,7 +263,9 @@ import (
 	"fmt"
 	"internal/race"
 	"io"
+	"io/ioutil"
 	"os"
+	"reflect"
 	"runtime"
 	"runtime/debug"
 	"runtime/trace"
@@ -232,6 +274,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 var (
@@ -252,7 +295,7 @@ var (
 	outputDir = flag.String("test.outputdir", "", "write profiles to `dir`")
 
 	// Report as tests are run; default is silent for success.
-	chatty               = flag.Bool("test.v", false, "verbose: print additional output")
+	chatty               = new(chattyFlag)
 	count                = flag.Uint("test.count", 1, "run tests and benchmarks `n` times")
 	coverProfile         = flag.String("test.coverprofile", "", "write a coverage profile to `file`")
 	matchList            = flag.String("test.list", "", "list tests, examples, and benchmarks matching `regexp` then exit")
@@ -278,6 +321,104 @@ var (
 	numFailed uint32 // number of test failures
 )
 
+func init() {
+	flag.Var(chatty, "test.v", "verbose: print additional output")
+}
+
+// marker is the byte that starts each framing line of the verbose
+// output, such as "=== RUN" and "--- PASS", when -test.v=test2json is
+// set, so that cmd/test2json can tell them apart from the output of tests.
+const marker = byte(0x16) // ^V
+
+// chattyFlag is the -test.v flag. Besides true and false, it accepts
+// the value test2json, which is set by go test -json.
+type chattyFlag struct {
+	on   bool // -test.v is set
+	json bool // -test.v=test2json is set
+}
+
+func (*chattyFlag) IsBoolFlag() bool { return true }
+
+func (f *chattyFlag) Set(arg string) error {
+	if arg == "test2json" {
+		f.on, f.json = true, true
+		return nil
+	}
+	on, err := strconv.ParseBool(arg)
+	if err != nil {
+		return errors.New("invalid flag -test.v=" + arg)
+	}
+	f.on, f.json = on, false
+	return nil
+}
+
+func (f *chattyFlag) String() string {
+	if f.json {
+		return "test2json"
+	}
+	return strconv.FormatBool(f.on)
+}
+
+func (f *chattyFlag) Get() interface{} {
+	if f.json {
+		return "test2json"
+	}
+	return f.on
+}
+
+// prefix returns the prefix of framing lines.
+func (f *chattyFlag) prefix() string {
+	if f.json {
+		return string(marker)
+	}
+	return ""
+}
+
+// A chattyPrinter prints the verbose output of tests. It keeps track of
+// the test whose output was printed last, and precedes output from
+// another test by an "=== NAME" line, so that all of the output of
+// parallel tests can be attributed to the right test.
+type chattyPrinter struct {
+	w    io.Writer
+	json bool // print framing lines for cmd/test2json
+
+	mu       sync.Mutex // guards lastName and writes to w
+	lastName string     // name of the test whose output was printed last
+}
+
+func newChattyPrinter(w io.Writer) *chattyPrinter {
+	return &chattyPrinter{w: w, json: chatty.json}
+}
+
+// prefix returns the prefix of framing lines.
+// It may be called on a nil printer.
+func (p *chattyPrinter) prefix() string {
+	if p != nil && p.json {
+		return string(marker)
+	}
+	return ""
+}
+
+// Updatef prints a framing line, such as "=== RUN", about the named test,
+// which becomes the test that the following output is attributed to.
+func (p *chattyPrinter) Updatef(testName, format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastName = testName
+	fmt.Fprintf(p.w, p.prefix()+format, args...)
+}
+
+// Printf prints output of the named test.
+func (p *chattyPrinter) Printf(testName, format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lastName != testName {
+		fmt.Fprintf(p.w, "%s=== NAME  %s\n", p.prefix(), testName)
+		p.lastName = testName
+	}
+	fmt.Fprintf(p.w, format, args...)
+}
+
 // common holds the elements common between T and B and
 // captures common methods such as Errorf.
 type common struct {
@@ -289,12 +430,14 @@ type common struct {
 	skipped bool                // Test of benchmark has been skipped.
 	done    bool                // Test is finished and all subtests have completed.
 	helpers map[string]struct{} // functions to be skipped when writing file/line info
+	cleanup []func()            // optional functions to be called at the end of the test
 
-	chatty     bool   // A copy of the chatty flag.
-	finished   bool   // Test function has completed.
-	hasSub     int32  // written atomically
-	raceErrors int    // number of races detected during test
-	runner     string // function name of tRunner running the test
+	chatty     *chattyPrinter // Printer of verbose output, if the chatty flag is set.
+	bench      bool           // Whether the current test is a benchmark.
+	finished   bool           // Test function has completed.
+	hasSub     int32          // written atomically
+	raceErrors int            // number of races detected during test
+	runner     string         // function name of tRunner running the test
 
 	parent   *common
 	level    int       // Nesting depth of test or benchmark.
@@ -304,6 +447,11 @@ type common struct {
 	barrier  chan bool // To signal parallel subtests they may start.
 	signal   chan bool // To signal a test is done.
 	sub      []*T      // Queue of subtests to be run in parallel.
+
+	tempDirOnce sync.Once
+	tempDir     string
+	tempDirErr  error
+	tempDirSeq  int32
 }
 
 // Short reports whether the -test.short flag is set.
@@ -320,7 +468,7 @@ func CoverMode() string {
 
 // Verbose reports whether the -test.v flag is set.
 func Verbose() bool {
-	return *chatty
+	return chatty.on
 }
 
 // frameSkip searches, starting after skip frames, for the first caller frame
@@ -396,17 +544,25 @@ func (c *common) decorate(s string) string {
 }
 
 // flushToParent writes c.output to the parent after first writing the header
-// with the given format and arguments.
-func (c *common) flushToParent(format string, args ...interface{}) {
+// with the given format and arguments, which reports on the named test.
+func (c *common) flushToParent(testName, format string, args ...interface{}) {
 	p := c.parent
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	fmt.Fprintf(p.w, format, args...)
-
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	io.Copy(p.w, bytes.NewReader(c.output))
+
+	format += "%s"
+	args = append(args[:len(args):len(args)], c.output)
+	if c.chatty != nil && p.w == c.chatty.w {
+		// The parent writes to the chatty printer's writer:
+		// print through the printer, so that it knows which
+		// test the output is from.
+		c.chatty.Updatef(testName, format, args...)
+	} else {
+		fmt.Fprintf(p.w, c.chatty.prefix()+format, args...)
+	}
 	c.output = c.output[:0]
 }
 
@@ -424,10 +580,16 @@ func (w indenter) Write(b []byte) (n int, err error) {
 			end++
 		}
 		// An indent of 4 spaces will neatly align the dashes with the status
-		// indicator of the parent.
+		// indicator of the parent. The marker of framing lines stays
+		// at the start of the line.
 		const indent = "    "
+		line := b[:end]
+		if line[0] == marker {
+			w.c.output = append(w.c.output, marker)
+			line = line[1:]
+		}
 		w.c.output = append(w.c.output, indent...)
-		w.c.output = append(w.c.output, b[:end]...)
+		w.c.output = append(w.c.output, line...)
 		b = b[end:]
 	}
 	return
@@ -455,6 +617,10 @@ type TB interface {
 	Skipf(format string, args ...interface{})
 	Skipped() bool
 	Helper()
+	Attr(key, value string)
+	Cleanup(func())
+	Setenv(key, value string)
+	TempDir() string
 
 	// A private method to prevent users implementing the
 	// interface and so future additions to it will not
@@ -478,6 +644,7 @@ var _ TB = (*B)(nil)
 type T struct {
 	common
 	isParallel bool
+	isEnvSet   bool
 	context    *testContext // For running tests and subtests.
 }
 
@@ -557,7 +724,15 @@ func (c *common) FailNow() {
 func (c *common) log(s string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.output = append(c.output, c.decorate(s)...)
+	s = c.decorate(s)
+	if c.chatty != nil && !c.bench {
+		// Print the output of tests as it happens, rather than at the
+		// end of the test, so that it shows up next to the output the
+		// test prints itself.
+		c.chatty.Printf(c.name, "%s", s)
+		return
+	}
+	c.output = append(c.output, s...)
 }
 
 // Log formats its arguments using default formatting, analogous to Println,
@@ -573,6 +748,29 @@ func (c *common) Log(args ...interface{}) { c.log(fmt.Sprintln(args...)) }
 // depend on the value of the -test.v flag.
 func (c *common) Logf(format string, args ...interface{}) { c.log(fmt.Sprintf(format, args...)) }
 
+// Attr emits a test attribute, a key and value associated with the test,
+// for the tools that read the output of go test -json, which reports it
+// as an attr event. Attributes are printed only if the -test.v flag is set.
+// The meaning of the keys is left to those tools.
+//
+// The key must not be empty or contain whitespace, and the value must not
+// contain newlines or carriage returns; Attr fails the test if they do.
+func (c *common) Attr(key, value string) {
+	if key == "" || strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+		c.log(fmt.Sprintf("invalid attribute key %q", key))
+		c.Fail()
+		return
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		c.log(fmt.Sprintf("invalid attribute value %q: contains newline", value))
+		c.Fail()
+		return
+	}
+	if c.chatty != nil {
+		c.chatty.Updatef(c.name, "=== ATTR  %s %s %s\n", c.name, key, value)
+	}
+}
+
 // Error is equivalent to Log followed by Fail.
 func (c *common) Error(args ...interface{}) {
 	c.log(fmt.Sprintln(args...))
@@ -650,6 +848,121 @@ func (c *common) Helper() {
 	c.helpers[callerName(1)] = struct{}{}
 }
 
+// Cleanup registers a function to be called when the test and all its
+// subtests complete. Cleanup functions will be called in last added,
+// first called order.
+func (c *common) Cleanup(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cleanup = append(c.cleanup, f)
+}
+
+// TempDir returns a temporary directory for the test to use.
+// The directory is automatically removed by Cleanup when the test and
+// all its subtests complete.
+// Each subsequent call to t.TempDir returns a unique directory;
+// if the directory creation fails, TempDir terminates the test by calling Fatal.
+func (c *common) TempDir() string {
+	// Use a single parent directory for all the temporary directories
+	// created by a test, each numbered sequentially.
+	c.tempDirOnce.Do(func() {
+		c.Helper()
+
+		// ioutil.TempDir doesn't like path separators in its pattern,
+		// so mangle the name to accommodate subtests.
+		pattern := strings.Map(func(r rune) rune {
+			switch r {
+			case '/', '\\', ':', '*', '?':
+				return '_'
+			}
+			return r
+		}, c.Name())
+		c.tempDir, c.tempDirErr = ioutil.TempDir("", pattern)
+		if c.tempDirErr == nil {
+			c.Cleanup(func() {
+				if err := os.RemoveAll(c.tempDir); err != nil {
+					c.Errorf("TempDir RemoveAll cleanup: %v", err)
+				}
+			})
+		}
+	})
+	if c.tempDirErr != nil {
+		c.Fatalf("TempDir: %v", c.tempDirErr)
+	}
+	seq := atomic.AddInt32(&c.tempDirSeq, 1)
+	dir := fmt.Sprintf("%s%c%03d", c.tempDir, os.PathSeparator, seq)
+	if err := os.Mkdir(dir, 0777); err != nil {
+		c.Fatalf("TempDir: %v", err)
+	}
+	return dir
+}
+
+// Setenv calls os.Setenv(key, value) and uses Cleanup to
+// restore the environment variable to its original value
+// after the test.
+func (c *common) Setenv(key, value string) {
+	prevValue, ok := os.LookupEnv(key)
+
+	if err := os.Setenv(key, value); err != nil {
+		c.Fatalf("cannot set environment variable: %v", err)
+	}
+
+	if ok {
+		c.Cleanup(func() {
+			os.Setenv(key, prevValue)
+		})
+	} else {
+		c.Cleanup(func() {
+			os.Unsetenv(key)
+		})
+	}
+}
+
+// panicHandling is an argument to runCleanup.
+type panicHandling int
+
+const (
+	normalPanic panicHandling = iota
+	recoverAndReturnPanic
+)
+
+// runCleanup is called at the end of the test.
+// If ph is recoverAndReturnPanic, it will catch panics, and return the
+// recovered value if any.
+func (c *common) runCleanup(ph panicHandling) (panicVal interface{}) {
+	if ph == recoverAndReturnPanic {
+		defer func() {
+			panicVal = recover()
+		}()
+	}
+
+	// Make sure that if a cleanup function panics,
+	// we still run the remaining cleanup functions.
+	defer func() {
+		c.mu.Lock()
+		recur := len(c.cleanup) > 0
+		c.mu.Unlock()
+		if recur {
+			c.runCleanup(normalPanic)
+		}
+	}()
+
+	for {
+		var cleanup func()
+		c.mu.Lock()
+		if len(c.cleanup) > 0 {
+			last := len(c.cleanup) - 1
+			cleanup = c.cleanup[last]
+			c.cleanup = c.cleanup[:last]
+		}
+		c.mu.Unlock()
+		if cleanup == nil {
+			return nil
+		}
+		cleanup()
+	}
+}
+
 // callerName gives the function name (qualified with a package path)
 // for the caller after skip frames (where 0 means the current function).
 func callerName(skip int) string {
@@ -672,6 +985,9 @@ func (t *T) Parallel() {
 	if t.isParallel {
 		panic("testing: t.Parallel called multiple times")
 	}
+	if t.isEnvSet {
+		panic("testing: t.Parallel called after t.Setenv; cannot set environment variables in parallel tests")
+	}
 	t.isParallel = true
 
 	// We don't want to include the time we spend waiting for serial tests
@@ -683,34 +999,36 @@ func (t *T) Parallel() {
 	t.parent.sub = append(t.parent.sub, t)
 	t.raceErrors += race.Errors()
 
-	if t.chatty {
-		// Print directly to root's io.Writer so there is no delay.
-		root := t.parent
-		for ; root.parent != nil; root = root.parent {
-		}
-		root.mu.Lock()
-		fmt.Fprintf(root.w, "=== PAUSE %s\n", t.name)
-		root.mu.Unlock()
+	if t.chatty != nil {
+		t.chatty.Updatef(t.name, "=== PAUSE %s\n", t.name)
 	}
 
 	t.signal <- true   // Release calling test.
 	<-t.parent.barrier // Wait for the parent test to complete.
 	t.context.waitParallel()
 
-	if t.chatty {
-		// Print directly to root's io.Writer so there is no delay.
-		root := t.parent
-		for ; root.parent != nil; root = root.parent {
-		}
-		root.mu.Lock()
-		fmt.Fprintf(root.w, "=== CONT  %s\n", t.name)
-		root.mu.Unlock()
+	if t.chatty != nil {
+		t.chatty.Updatef(t.name, "=== CONT  %s\n", t.name)
 	}
 
 	t.start = time.Now()
 	t.raceErrors += -race.Errors()
 }
 
+// Setenv calls os.Setenv(key, value) and uses Cleanup to
+// restore the environment variable to its original value
+// after the test.
+//
+// Because Setenv affects the whole process, it cannot be used
+// in parallel tests.
+func (t *T) Setenv(key, value string) {
+	if t.isParallel {
+		panic("testing: t.Setenv called after t.Parallel; cannot set environment variables in parallel tests")
+	}
+	t.isEnvSet = true
+	t.common.Setenv(key, value)
+}
+
 // An internal type but exported because it is cross-package; part of the implementation
 // of the "go test" command.
 type InternalTest struct {
@@ -736,11 +1054,17 @@ func tRunner(t *T, fn func(t *T)) {
 		if !t.finished && err == nil {
 			err = fmt.Errorf("test executed panic(nil) or runtime.Goexit")
 		}
-		if err != nil {
+		doPanic := func(err interface{}) {
 			t.Fail()
+			if r := t.runCleanup(recoverAndReturnPanic); r != nil {
+				t.Logf("cleanup panicked with %v", r)
+			}
 			t.report()
 			panic(err)
 		}
+		if err != nil {
+			doPanic(err)
+		}
 
 		if len(t.sub) > 0 {
 			// Run parallel subtests.
@@ -752,6 +1076,14 @@ func tRunner(t *T, fn func(t *T)) {
 			for _, sub := range t.sub {
 				<-sub.signal
 			}
+			// The cleanup of a test with parallel subtests runs only
+			// once those subtests have finished.
+			cleanupStart := time.Now()
+			err := t.runCleanup(recoverAndReturnPanic)
+			t.duration += time.Since(cleanupStart)
+			if err != nil {
+				doPanic(err)
+			}
 			if !t.isParallel {
 				// Reacquire the count for sequential tests. See comment in Run.
 				t.context.waitParallel()
@@ -771,6 +1103,11 @@ func tRunner(t *T, fn func(t *T)) {
 		}
 		t.signal <- true
 	}()
+	defer func() {
+		if len(t.sub) == 0 {
+			t.runCleanup(normalPanic)
+		}
+	}()
 
 	t.start = time.Now()
 	t.raceErrors = -race.Errors()
@@ -807,14 +1144,8 @@ func (t *T) Run(name string, f func(t *T)) bool {
 	}
 	t.w = indenter{&t.common}
 
-	if t.chatty {
-		// Print directly to root's io.Writer so there is no delay.
-		root := t.parent
-		for ; root.parent != nil; root = root.parent {
-		}
-		root.mu.Lock()
-		fmt.Fprintf(root.w, "=== RUN   %s\n", t.name)
-		root.mu.Unlock()
+	if t.chatty != nil {
+		t.chatty.Updatef(t.name, "=== RUN   %s\n", t.name)
 	}
 	// Instead of reducing the running count of this test before calling the
 	// tRunner and increasing it afterwards, we rely on tRunner keeping the
@@ -823,6 +1154,11 @@ func (t *T) Run(name string, f func(t *T)) bool {
 	// may especially reduce surprises if *parallel == 1.
 	go tRunner(t, f)
 	<-t.signal
+	if t.chatty != nil && t.chatty.json {
+		// The following output, if any, is from the parent test.
+		// Say so, for cmd/test2json.
+		t.chatty.Updatef(t.parent.name, "=== NAME  %s\n", t.parent.name)
+	}
 	return !t.failed
 }
 
@@ -894,6 +1230,14 @@ func (f matchStringOnly) WriteProfileTo(string, io.Writer, int) error { return e
 func (f matchStringOnly) ImportPath() string                          { return "" }
 func (f matchStringOnly) StartTestLog(io.Writer)                      {}
 func (f matchStringOnly) StopTestLog() error                          { return errMain }
+func (f matchStringOnly) ReadCorpus(string, []reflect.Type) ([]corpusEntry, error) {
+	return nil, errMain
+}
+func (f matchStringOnly) CheckCorpus([]interface{}, []reflect.Type) error { return nil }
+func (f matchStringOnly) CoordinateFuzzing(string, io.Writer) error       { return errMain }
+func (f matchStringOnly) RunFuzzWorker(string, time.Duration, int64, []corpusEntry, []reflect.Type, string, string, io.Writer, func(corpusEntry) error, func() []byte) error {
+	return errMain
+}
 
 // Main is an internal function, part of the implementation of the "go test" command.
 // It was exported because it is cross-package and predates "internal" packages.
@@ -902,15 +1246,16 @@ func (f matchStringOnly) StopTestLog() error                          { return e
 // new functionality is added to the testing package.
 // Systems simulating "go test" should be updated to use MainStart.
 func Main(matchString func(pat, str string) (bool, error), tests []InternalTest, benchmarks []InternalBenchmark, examples []InternalExample) {
-	os.Exit(MainStart(matchStringOnly(matchString), tests, benchmarks, examples).Run())
+	os.Exit(MainStart(matchStringOnly(matchString), tests, benchmarks, nil, examples).Run())
 }
 
 // M is a type passed to a TestMain function to run the actual tests.
 type M struct {
-	deps       testDeps
-	tests      []InternalTest
-	benchmarks []InternalBenchmark
-	examples   []InternalExample
+	deps        testDeps
+	tests       []InternalTest
+	benchmarks  []InternalBenchmark
+	fuzzTargets []InternalFuzzTarget
+	examples    []InternalExample
 
 	timer     *time.Timer
 	afterOnce sync.Once
@@ -931,17 +1276,22 @@ type testDeps interface {
 	StopTestLog() error
 	WriteHeapProfile(io.Writer) error
 	WriteProfileTo(string, io.Writer, int) error
+	CoordinateFuzzing(string, io.Writer) error
+	RunFuzzWorker(string, time.Duration, int64, []corpusEntry, []reflect.Type, string, string, io.Writer, func(corpusEntry) error, func() []byte) error
+	ReadCorpus(string, []reflect.Type) ([]corpusEntry, error)
+	CheckCorpus([]interface{}, []reflect.Type) error
 }
 
 // MainStart is meant for use by tests generated by 'go test'.
 // It is not meant to be called directly and is not subject to the Go 1 compatibility document.
 // It may change signature from release to release.
-func MainStart(deps testDeps, tests []InternalTest, benchmarks []InternalBenchmark, examples []InternalExample) *M {
+func MainStart(deps testDeps, tests []InternalTest, benchmarks []InternalBenchmark, fuzzTargets []InternalFuzzTarget, examples []InternalExample) *M {
 	return &M{
-		deps:       deps,
-		tests:      tests,
-		benchmarks: benchmarks,
-		examples:   examples,
+		deps:        deps,
+		tests:       tests,
+		benchmarks:  benchmarks,
+		fuzzTargets: fuzzTargets,
+		examples:    examples,
 	}
 }
 
@@ -965,28 +1315,38 @@ func (m *M) Run() int {
 	}
 
 	if len(*matchList) != 0 {
-		listTests(m.deps.MatchString, m.tests, m.benchmarks, m.examples)
+		listTests(m.deps.MatchString, m.tests, m.benchmarks, m.fuzzTargets, m.examples)
 		return 0
 	}
 
 	parseCpuList()
 
+	if *fuzzWorkerDir != "" {
+		// A fuzzing worker runs only the fuzz target, leaving
+		// the tests and their output to the coordinator.
+		if !runFuzzing(m.deps, m.fuzzTargets) {
+			return 1
+		}
+		return 0
+	}
+
 	m.before()
 	defer m.after()
 	m.startAlarm()
 	haveExamples = len(m.examples) > 0
 	testRan, testOk := runTests(m.deps.MatchString, m.tests)
+	fuzzTargetsRan, fuzzTargetsOk := runFuzzTests(m.deps, m.fuzzTargets)
 	exampleRan, exampleOk := runExamples(m.deps.MatchString, m.examples)
 	m.stopAlarm()
-	if !testRan && !exampleRan && *matchBenchmarks == "" {
+	if !testRan && !fuzzTargetsRan && !exampleRan && *matchBenchmarks == "" && *matchFuzz == "" {
 		fmt.Fprintln(os.Stderr, "testing: warning: no tests to run")
 	}
-	if !testOk || !exampleOk || !runBenchmarks(m.deps.ImportPath(), m.deps.MatchString, m.benchmarks) || race.Errors() > 0 {
-		fmt.Println("FAIL")
+	if !testOk || !fuzzTargetsOk || !exampleOk || !runFuzzing(m.deps, m.fuzzTargets) || !runBenchmarks(m.deps.ImportPath(), m.deps.MatchString, m.benchmarks) || race.Errors() > 0 {
+		fmt.Println(chatty.prefix() + "FAIL")
 		return 1
 	}
 
-	fmt.Println("PASS")
+	fmt.Println(chatty.prefix() + "PASS")
 	return 0
 }
 
@@ -997,17 +1357,17 @@ func (t *T) report() {
 	dstr := fmtDuration(t.duration)
 	format := "--- %s: %s (%s)\n"
 	if t.Failed() {
-		t.flushToParent(format, "FAIL", t.name, dstr)
-	} else if t.chatty {
+		t.flushToParent(t.name, format, "FAIL", t.name, dstr)
+	} else if t.chatty != nil {
 		if t.Skipped() {
-			t.flushToParent(format, "SKIP", t.name, dstr)
+			t.flushToParent(t.name, format, "SKIP", t.name, dstr)
 		} else {
-			t.flushToParent(format, "PASS", t.name, dstr)
+			t.flushToParent(t.name, format, "PASS", t.name, dstr)
 		}
 	}
 }
 
-func listTests(matchString func(pat, str string) (bool, error), tests []InternalTest, benchmarks []InternalBenchmark, examples []InternalExample) {
+func listTests(matchString func(pat, str string) (bool, error), tests []InternalTest, benchmarks []InternalBenchmark, fuzzTargets []InternalFuzzTarget, examples []InternalExample) {
 	if _, err := matchString(*matchList, "non-empty"); err != nil {
 		fmt.Fprintf(os.Stderr, "testing: invalid regexp in -test.list (%q): %s\n", *matchList, err)
 		os.Exit(1)
@@ -1023,6 +1383,11 @@ func listTests(matchString func(pat, str string) (bool, error), tests []Internal
 			fmt.Println(bench.Name)
 		}
 	}
+	for _, fuzzTarget := range fuzzTargets {
+		if ok, _ := matchString(*matchList, fuzzTarget.Name); ok {
+			fmt.Println(fuzzTarget.Name)
+		}
+	}
 	for _, example := range examples {
 		if ok, _ := matchString(*matchList, example.Name); ok {
 			fmt.Println(example.Name)
@@ -1054,10 +1419,12 @@ func runTests(matchString func(pat, str string) (bool, error), tests []InternalT
 					signal:  make(chan bool),
 					barrier: make(chan bool),
 					w:       os.Stdout,
-					chatty:  *chatty,
 				},
 				context: ctx,
 			}
+			if Verbose() {
+				t.chatty = newChattyPrinter(t.w)
+			}
 			tRunner(t, func(t *T) {
 				for _, test := range tests {
 					t.Run(test.Name, test.F)
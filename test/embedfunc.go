@@ -0,0 +1,28 @@
+// errorcheck
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that misplaced //go:embed directives are rejected.
+
+package p
+
+import _ "embed"
+
+// Compiler directives are only recognized at the start of a line,
+// so the directive inside f is deliberately not indented.
+func f() {
+//go:embed x.txt // ERROR "go:embed cannot apply to var inside func"
+	var x string
+	_ = x
+}
+
+//go:embed x.txt // ERROR "misplaced go:embed directive"
+func g() {}
+
+//go:embed x.txt // ERROR "go:embed cannot apply to multiple vars"
+var a, b string
+
+//go:embed x.txt // ERROR "go:embed cannot apply to var with initializer"
+var c = "c"
@@ -0,0 +1,12 @@
+// errorcheck
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that //go:embed requires importing "embed".
+
+package p
+
+//go:embed x.txt // ERROR "go:embed only allowed in Go files that import .embed."
+var s string